@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/demo"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/features"
+)
+
+// demoFeatureGates are the feature gates the demo enables on top of the default ones. ApplyManifests stays disabled,
+// since the fake cluster doesn't support server-side apply.
+var demoFeatureGates = []string{
+	features.CostEstimation,
+	features.NamespaceHibernation,
+	features.EvictPods,
+	features.DeploymentTopology,
+	features.Applications,
+}
+
+// runDemo serves the API from a fake cluster holding the demo dataset, with its rollouts simulated, until a signal is
+// received. The certificates, the token and the configuration files the demo needs are written to its directory, and
+// the commands reaching the API with them are printed to w. Any arguments after "--" are passed to the server as is.
+func runDemo(args []string, stopCh chan os.Signal, ctx context.Context, w io.Writer) error {
+	var dir, port string
+	var rolloutInterval time.Duration
+	flagSet := flag.NewFlagSet("demo", flag.ExitOnError)
+	flagSet.StringVar(&dir, "dir", "", "directory the certificates, the token, the dataset and the price sheet of the demo are written to. The files already there are kept, so that they can be edited between demos. Defaults to a temporary directory removed on exit")
+	flagSet.StringVar(&port, "port", "8443", "server port")
+	flagSet.DurationVar(&rolloutInterval, "rollout-interval", 2*time.Second, "how often the deployments move a pod closer to their replicas and pod template. Set to 0 to keep their pods and status as they are")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if dir == "" {
+		tempDir, err := os.MkdirTemp("", "go-k8s-http-api-demo-")
+		if err != nil {
+			return fmt.Errorf("failed to create the demo directory: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+		dir = tempDir
+	} else if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create the demo directory: %w", err)
+	}
+	scheme, err := newScheme()
+	if err != nil {
+		return err
+	}
+	files := demo.NewFiles(dir)
+	if err := files.Write(scheme, time.Now()); err != nil {
+		return err
+	}
+	token, err := files.Token()
+	if err != nil {
+		return err
+	}
+
+	gates := make([]string, 0, len(demoFeatureGates))
+	for _, gate := range demoFeatureGates {
+		gates = append(gates, gate+"=true")
+	}
+	serverArgs := []string{"demo",
+		"--port", port,
+		"--server-cert", files.ServerCert,
+		"--cert-key", files.ServerKey,
+		"--ca-cert", files.CACert,
+		"--auth-chain", "client-cert,token",
+		"--token-auth-file", files.TokenFile,
+		"--admin-identities", demo.Identity,
+		"--feature-gates", strings.Join(gates, ","),
+		"--cost-price-sheet-file", files.PriceSheet,
+		"--mock",
+		"--mock-fixtures", files.Fixtures,
+		"--mock-rollout-interval", rolloutInterval.String(),
+	}
+	serverArgs = append(serverArgs, flagSet.Args()...)
+
+	printDemoUsage(w, dir, files, token, port)
+	return run(serverArgs, stopCh, ctx)
+}
+
+// printDemoUsage prints the commands reaching the API of the demo, as the demo identity
+func printDemoUsage(w io.Writer, dir string, files *demo.Files, token, port string) {
+	baseURL := "https://localhost:" + port
+	fmt.Fprintf(w, `Serving the demo dataset from a fake cluster at %[1]s, with the files of the demo in %[2]s.
+
+Authenticate with the token of the %[3]s identity:
+
+  export DEMO_TOKEN=%[4]s
+  curl --cacert %[5]s -H "Authorization: Bearer $DEMO_TOKEN" %[1]s/deployments
+
+or with its client certificate:
+
+  curl --cacert %[5]s --cert %[6]s --key %[7]s %[1]s/deployments
+
+Then try, e.g.:
+
+  /namespaces/shop/deployments                      the deployments of a namespace
+  /deployments/shop/checkout/health                 a rollout in progress, completed within seconds
+  /deployments/analytics/etl-worker/health          a crash looping deployment
+  /alerts/rollouts                                  the rollouts past their progress deadline
+  /insights/scheduling                              the pods no node fits
+  /apps, /topology/shop/web, /cost/payments, /images, /search?q=cart
+  /deployments/shop/web?watch=true&resourceVersion=<the version of the deployment>
+                                                    a watch, answered as soon as the deployment changes
+  curl -X PUT -d '{"replicas": 5}' .../deployments/shop/web/replicas
+                                                    a scaling, rolled out a pod at a time
+
+`, baseURL, dir, demo.Identity, token, files.CACert, files.ClientCert, files.ClientKey)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/demo"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/testenv"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestRunDemo(t *testing.T) {
+	dir := t.TempDir()
+	port, healthzPort := testenv.FreePort(t), testenv.FreePort(t)
+	var stdout bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runDemo([]string{
+			"--dir", dir,
+			"--port", port,
+			"--rollout-interval", "100ms",
+			"--",
+			"--bind-address", "127.0.0.1",
+			"--healthz-bind-address", "127.0.0.1",
+			"--healthz-port", healthzPort,
+			"--shutdown-drain-period", "0s",
+		}, make(chan os.Signal, 1), ctx, &stdout)
+	}()
+	files := demo.NewFiles(dir)
+	var token string
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("runDemo() error = %v", err)
+		}
+		// The commands reaching the API are printed along with the token
+		if !strings.Contains(stdout.String(), "Bearer $DEMO_TOKEN") || token == "" || !strings.Contains(stdout.String(), token) {
+			t.Errorf("stdout = %s, want the commands reaching the API with the token", stdout.String())
+		}
+	})
+
+	// The files of the demo are written before the server starts
+	err := wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, time.Minute, true, func(context.Context) (bool, error) {
+		var err error
+		token, err = files.Token()
+		return err == nil, nil
+	})
+	if err != nil {
+		t.Fatalf("The demo files weren't written: %v", err)
+	}
+	caPEM, err := os.ReadFile(files.CACert)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	httpClient := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS13}},
+	}
+	get := func(path string) (int, string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://localhost:"+port+path, nil)
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return 0, err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// The rollout in progress completes, once the server is ready
+	err = wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, time.Minute, true, func(context.Context) (bool, error) {
+		status, body := get("/deployments/shop/checkout?cache=false")
+		return status == http.StatusOK && strings.Contains(body, `"replicas":2,"readyReplicas":2,"updatedReplicas":2`), nil
+	})
+	if err != nil {
+		t.Fatalf("The rollout of shop/checkout didn't complete: %v", err)
+	}
+
+	tests := []struct {
+		path         string
+		expectedBody string
+	}{
+		{path: "/namespaces/payments/deployments", expectedBody: `{"name":"fraud-detector","namespace":"payments"}`},
+		{path: "/deployments/analytics/etl-worker/health", expectedBody: `"issues":{"CrashLoopBackOff":1}`},
+		{path: "/alerts/rollouts", expectedBody: `"reason":"ProgressDeadlineExceeded"`},
+		{path: "/insights/scheduling", expectedBody: `"cause":"InsufficientCPU"`},
+		{path: "/apps/shop/cart", expectedBody: `"statefulSets":[{"name":"cart-redis"`},
+		{path: "/topology/shop/web", expectedBody: `"hosts":["shop.example.com"]`},
+		{path: "/cost/payments", expectedBody: `"currency":"USD"`},
+		{path: "/admin/features", expectedBody: `"name":"Applications"`},
+	}
+	for _, tt := range tests {
+		status, body := get(tt.path)
+		if status != http.StatusOK {
+			t.Errorf("GET %s status = %d, want %d: %s", tt.path, status, http.StatusOK, body)
+		}
+		if !strings.Contains(body, tt.expectedBody) {
+			t.Errorf("GET %s body = %s, want it to contain %s", tt.path, body, tt.expectedBody)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "errors"
+
+// Exit codes of the server, by class of failure, so that a misconfiguration tells apart from an API server the server
+// can't work with, or from a server failing while running, e.g. in the restart reasons of the pods
+const (
+	// exitCodeFailure is the exit code of the failures of no particular class
+	exitCodeFailure = 1
+	// exitCodeUsage is the exit code of the invalid flags and configuration files
+	exitCodeUsage = 2
+	// exitCodeTLS is the exit code of the failures to load the serving certificate, its key or the client CA bundles
+	exitCodeTLS = 3
+	// exitCodeKubernetes is the exit code of the failures to load the configuration of the API server, or to set up
+	// the clients, the cache and the controllers with it
+	exitCodeKubernetes = 4
+	// exitCodeServer is the exit code of the servers and of the manager failing while running
+	exitCodeServer = 5
+)
+
+// exitError is an error with the exit code of its class of failure
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode returns the error with the given exit code, unless it already has one
+func withExitCode(code int, err error) error {
+	var exitErr *exitError
+	if err == nil || errors.As(err, &exitErr) {
+		return err
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code of the error, or exitCodeFailure when it has none
+func exitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return exitCodeFailure
+}
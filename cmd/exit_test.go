@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "unclassified", err: errors.New("boom"), expected: exitCodeFailure},
+		{name: "classified", err: withExitCode(exitCodeTLS, errors.New("no such file")), expected: exitCodeTLS},
+		{name: "wrapped", err: fmt.Errorf("startup: %w", withExitCode(exitCodeUsage, errors.New("invalid flag"))), expected: exitCodeUsage},
+		{name: "classified once", err: withExitCode(exitCodeKubernetes, withExitCode(exitCodeUsage, errors.New("invalid flag"))), expected: exitCodeUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.expected {
+				t.Errorf("exitCode() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+	if err := withExitCode(exitCodeUsage, nil); err != nil {
+		t.Errorf("withExitCode(nil) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/handlers"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/loadtest"
+	"k8s.io/klog/v2"
+)
+
+// serveLoadTest serves the deployments endpoints over plain HTTP, backed by a fake client holding a generated cluster
+// instead of a real one, until a signal is received. It is the target of the load profiles in hack/load, so that the
+// throughput of the endpoints is measured without the API server, TLS or authentication in the way.
+func serveLoadTest(args []string, stopCh <-chan os.Signal) error {
+	var address string
+	var cluster loadtest.Cluster
+	flagSet := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	flagSet.StringVar(&address, "address", "127.0.0.1:8080", "address to serve the endpoints on, over plain HTTP")
+	flagSet.IntVar(&cluster.Deployments, "deployments", 10000, "number of deployments of the fake cluster")
+	flagSet.IntVar(&cluster.Namespaces, "namespaces", 100, "number of namespaces the deployments are spread over")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	c, err := cluster.NewClient()
+	if err != nil {
+		return err
+	}
+	// The deployments are listed from their summaries, as they are from the informer cache
+	summaries, err := cluster.NewSummaries()
+	if err != nil {
+		return err
+	}
+	deploymentsHandler := &handlers.DeploymentsHandler{Client: c, Summaries: summaries}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /deployments", deploymentsHandler.ListDeployments)
+	mux.HandleFunc("GET /namespaces/{namespace}/deployments", deploymentsHandler.ListDeployments)
+	mux.HandleFunc("GET /deployments/{namespace}/{deployment}/replicas", deploymentsHandler.GetDeploymentReplicas)
+	mux.HandleFunc("PUT /deployments/{namespace}/{deployment}/replicas", deploymentsHandler.SetDeploymentReplicas)
+	server := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() {
+		klog.InfoS("Serving the fake cluster for load tests", "address", address, "deployments", cluster.Deployments, "namespaces", cluster.Namespaces)
+		errCh <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-stopCh:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
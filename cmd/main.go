@@ -2,265 +2,1662 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/admission"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiwarnings"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apps"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/audit"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/bounds"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/breaker"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/cachesync"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/cachetransform"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/capture"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/certexpiry"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/clientca"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/conntrack"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/cost"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/deadline"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/debug"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/events"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/features"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/freeze"
 	"github.com/moshevayner/go-k8s-http-api-interface/internal/handlers"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/healthhistory"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/hibernation"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/idempotency"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/imagepolicy"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/images"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/ipfilter"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/kubecredentials"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/lifecycle"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/manifests"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/mock"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/notify"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/opa"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/operations"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/policy"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/projection"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/quota"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/ratelimit"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/rbaccheck"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/reconnect"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/redaction"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/responsecache"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/rollouts"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/securityheaders"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/shedding"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/slo"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/snapshots"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/stats"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/timing"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"crypto/tls"
-	"crypto/x509"
 
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
 func main() {
+	// The manifests subcommand renders the manifests needed to run the server in-cluster, instead of running it
+	if len(os.Args) > 1 && os.Args[1] == "manifests" {
+		if err := renderManifests(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Set up a channel to listen for the interrupt signal
+	// Set up a channel to listen for the interrupt and termination signals (Kubernetes sends SIGTERM on pod deletion)
 	stopCh := make(chan os.Signal, 1)
-	signal.Notify(stopCh, os.Interrupt)
+	signal.Notify(stopCh, os.Interrupt, syscall.SIGTERM)
+
+	// The loadtest subcommand serves the endpoints from a generated fake cluster, as the target of the load profiles
+	if len(os.Args) > 1 && os.Args[1] == "loadtest" {
+		if err := serveLoadTest(os.Args[2:], stopCh); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Run the servers and manager
+	// The demo subcommand runs the server against a fake cluster holding a generated dataset, to explore the API with
+	if len(os.Args) > 1 && os.Args[1] == "demo" {
+		if err := runDemo(os.Args[2:], stopCh, ctx, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v", err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+
+	// Run the servers and manager until a signal is received and the graceful shutdown completes. The exit code tells
+	// the class of the failure, if any.
 	err := run(os.Args, stopCh, ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// renderManifests renders the in-cluster manifests to w. Any arguments after "--" are passed to the server as is.
+func renderManifests(args []string, w io.Writer) error {
+	cfg := manifests.Config{Gates: features.NewGates()}
+	flagSet := flag.NewFlagSet("manifests", flag.ExitOnError)
+	flagSet.StringVar(&cfg.Name, "name", "go-k8s-http-api", "name of the rendered objects")
+	flagSet.StringVar(&cfg.Namespace, "namespace", "default", "namespace to deploy the server to")
+	flagSet.StringVar(&cfg.Image, "image", "mosheshi/go-k8s-http-api-interface:latest", "image of the server")
+	flagSet.IntVar(&cfg.Replicas, "replicas", 1, "number of replicas. Requires --leader-elect when greater than 1")
+	flagSet.BoolVar(&cfg.LeaderElection, "leader-elect", false, "enable leader election")
+	flagSet.Var(cfg.Gates, "feature-gates", "comma separated list of Name=bool pairs to enable / disable endpoints. Only the permissions needed by the enabled endpoints are granted")
+	flagSet.StringVar(&cfg.CertManagerIssuer, "cert-manager-issuer", "", "name of the cert-manager issuer of the server certificate. If not specified, the <name>-certs secret has to be created separately")
+	flagSet.StringVar(&cfg.CertManagerIssuerKind, "cert-manager-issuer-kind", "Issuer", "kind of the cert-manager issuer, either Issuer or ClusterIssuer")
+	flagSet.BoolVar(&cfg.NetworkPolicy, "network-policy", true, "render a NetworkPolicy only allowing ingress traffic to the server ports")
+	if err := flagSet.Parse(args); err != nil {
+		return err
 	}
+	cfg.ExtraArgs = flagSet.Args()
+
+	return manifests.Render(w, cfg)
+}
 
-	// Wait for the interrupt signal
-	<-stopCh
-	klog.Info("Shutting down...")
+// Values of the --namespace-authorization flag
+const (
+	namespaceAuthorizationSubjectAccessReview = "subjectaccessreview"
+	namespaceAuthorizationNone                = "none"
+)
 
-	// Initiate a graceful shutdown
-	cancel()
+// controllerCacheSyncTimeout is how long the controllers wait for their informers to sync, matching the time the
+// startup probe of the Helm chart allows, so that an unreachable API server at startup doesn't fail the manager after
+// the default 2 minutes
+const controllerCacheSyncTimeout = 10 * time.Minute
 
+// managerOptions holds the configurable options for the controller-runtime manager
+type managerOptions struct {
+	leaderElection          bool
+	leaderElectionNamespace string
+	leaderElectionID        string
+	// cacheNamespaces restricts the cache to the given namespaces. When empty, all namespaces are cached.
+	cacheNamespaces []string
+	// cacheStripManagedFields and cacheStripLastApplied strip fields which are never served from cached objects
+	cacheStripManagedFields bool
+	cacheStripLastApplied   bool
+	// cacheServedOnly makes the cache fail reads for resources without a pre-registered informer,
+	// instead of lazily starting a new informer for every requested resource
+	cacheServedOnly bool
+	// httpClient is the HTTP client of the cache, of the client and of the REST mapper of the manager
+	httpClient *http.Client
+	// cluster is the fake cluster backing the manager in mock mode, instead of the API server
+	cluster *mock.Cluster
 }
 
-func setupManager() (ctrl.Manager, error) {
+// newScheme returns the scheme of the kinds read and written through the manager
+func newScheme() (*runtime.Scheme, error) {
 	scheme := runtime.NewScheme()
 	// Register the apps/v1 group of the Kubernetes API with the scheme
 	if err := appsv1.AddToScheme(scheme); err != nil {
 		return nil, fmt.Errorf("failed to add apps/v1 to scheme: %w", err)
 	}
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	// Register the core/v1 group as well, to read the pods of deployments (which aren't cached) from the API reader
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add core/v1 to scheme: %w", err)
+	}
+	// Register the autoscaling/v2 group as well, to read the HorizontalPodAutoscalers of deployments (which aren't cached)
+	// from the API reader
+	if err := autoscalingv2.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add autoscaling/v2 to scheme: %w", err)
+	}
+	// Register the networking.k8s.io/v1 group as well, to read the ingresses of the topology of deployments from the
+	// API reader
+	if err := networkingv1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add networking.k8s.io/v1 to scheme: %w", err)
+	}
+	// Register the policy/v1 group as well, to evict pods through the Eviction API
+	if err := policyv1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add policy/v1 to scheme: %w", err)
+	}
+	return scheme, nil
+}
+
+func setupManager(config *rest.Config, scheme *runtime.Scheme, opts managerOptions) (ctrl.Manager, error) {
+	cacheOpts := cache.Options{
+		HTTPClient:                  opts.httpClient,
+		DefaultTransform:            cachetransform.StripServerFields(opts.cacheStripManagedFields, opts.cacheStripLastApplied),
+		ReaderFailOnMissingInformer: opts.cacheServedOnly,
+	}
+	if len(opts.cacheNamespaces) > 0 {
+		// Only list and watch the selected namespaces, to speed up the cache warm-up on large clusters
+		cacheOpts.DefaultNamespaces = make(map[string]cache.Config, len(opts.cacheNamespaces))
+		for _, namespace := range opts.cacheNamespaces {
+			cacheOpts.DefaultNamespaces[namespace] = cache.Config{}
+		}
+	}
+	options := ctrl.Options{
 		Scheme:   scheme,
 		NewCache: cache.New,
-		Metrics:  metricsserver.Options{BindAddress: "0"},
-		Logger:   ctrl.Log.WithName("controller-runtime"),
-	})
+		Cache:    cacheOpts,
+		Client:   client.Options{HTTPClient: opts.httpClient},
+		// The discovery calls of the REST mapper share the HTTP client too, so that they're tracked as well
+		MapperProvider: func(config *rest.Config, httpClient *http.Client) (meta.RESTMapper, error) {
+			if opts.httpClient != nil {
+				httpClient = opts.httpClient
+			}
+			return apiutil.NewDynamicRESTMapper(config, httpClient)
+		},
+		// The names of the controllers are unique to the manager, yet they're registered globally, which would prevent
+		// run from setting up another manager in the same process, e.g. in the tests
+		Controller: ctrlconfig.Controller{CacheSyncTimeout: controllerCacheSyncTimeout, SkipNameValidation: ptr.To(true)},
+		Metrics:    metricsserver.Options{BindAddress: "0"},
+		Logger:     ctrl.Log.WithName("controller-runtime"),
+		// When leader election is enabled, only the leader serves mutating endpoints, while all replicas serve cached reads.
+		// Releasing the lease on shutdown lets another replica take over immediately during rolling upgrades.
+		LeaderElection:                opts.leaderElection,
+		LeaderElectionNamespace:       opts.leaderElectionNamespace,
+		LeaderElectionID:              opts.leaderElectionID,
+		LeaderElectionReleaseOnCancel: true,
+	}
+	if opts.cluster != nil {
+		// The cache, the clients and the REST mapper are backed by the fake cluster in mock mode
+		options.NewCache, options.NewClient, options.MapperProvider = opts.cluster.NewCache, opts.cluster.NewClient, opts.cluster.NewRESTMapper
+	}
+	mgr, err := ctrl.NewManager(config, options)
 	if err != nil {
 		return nil, err
 	}
+	if opts.cluster != nil {
+		return opts.cluster.Manager(mgr), nil
+	}
 	return mgr, nil
 }
 
-// loggingMiddleware returns a new http.HandlerFunc that wraps the provided handler
+// loadConfig loads the configuration of the API server: the kubeconfig files, along with their credentials, e.g. exec
+// credential plugins, or else the in-cluster config. It returns the context of the kubeconfig in use, if any.
+func loadConfig(kubeconfig, kubeContext string) (*rest.Config, string, error) {
+	// First, try to load the kubeconfig files, along with their credentials, e.g. exec credential plugins
+	klog.V(5).InfoS("Trying to load kubeconfig file", "path", kubeconfig, "context", kubeContext)
+	config, activeContext, err := kubecredentials.LoadConfig(kubeconfig, kubeContext)
+	if err != nil && kubeContext != "" {
+		// the context was explicitly selected, so falling back to the in-cluster config would target another cluster
+		return nil, "", fmt.Errorf("failed to load the kubeconfig context %q: %w", kubeContext, err)
+	}
+	if err == nil {
+		klog.InfoS("Using kubeconfig", "context", activeContext)
+		return config, activeContext, nil
+	}
+	// log the error as a warning, and try to get the in-cluster config
+	klog.Warningf("Error loading kubeconfig file: %v", err)
+	klog.V(5).Info("Trying to get in-cluster config")
+	config, err = rest.InClusterConfig()
+	if err != nil {
+		if err == rest.ErrNotInCluster {
+			// since kubeconfig failed to load and we're not in cluster, we can't continue
+			return nil, "", fmt.Errorf("kubeconfig failed to load and not running in cluster, cannot continue. Please provide a valid kubeconfig file or run in-cluster")
+		}
+		// we are running in-cluster, but there was an error getting the config (other than ErrNotInCluster)
+		return nil, "", fmt.Errorf("failed to get the in-cluster config: %w", err)
+	}
+	klog.InfoS("Using in-cluster config")
+	return config, "", nil
+}
+
+// loggingMiddleware returns a new http.HandlerFunc that wraps the provided handler.
+// It adds a logger with the request ID and the client's identity to the request context, so that every line logged
+// while serving the request can be correlated.
 func loggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		requestID := logging.RequestID(r)
+		w.Header().Set(logging.RequestIDHeader, requestID)
+		logger := klog.FromContext(r.Context()).WithValues("requestID", requestID, "user", auth.Identity(r))
+		r = r.WithContext(klog.NewContext(r.Context(), logger))
+
 		// Log the request
-		klog.V(5).Infof("Started %s %s", r.Method, r.URL.Path)
+		logger.V(5).Info("Started request", "method", r.Method, "path", r.URL.Path)
 
 		next.ServeHTTP(w, r)
 
 		// Log the response time
-		klog.V(5).Infof("Completed in %v", time.Since(start))
+		logger.V(5).Info("Completed request", "duration", time.Since(start))
+	}
+}
+
+// leaderOnlyMiddleware returns a new http.HandlerFunc that only calls the provided handler if this instance is the elected leader.
+// When leader election is disabled, the elected channel is closed right away, so all requests are served.
+func leaderOnlyMiddleware(elected <-chan struct{}, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-elected:
+			next.ServeHTTP(w, r)
+		default:
+			logger := klog.FromContext(r.Context())
+			logger.V(5).Info("Rejecting request, this instance is not the leader")
+			resp := handlers.NewAPIError(w, http.StatusServiceUnavailable, messages.New(messages.NotLeader))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			encErr := json.NewEncoder(w).Encode(resp)
+			if encErr != nil {
+				logger.Error(encErr, "Error encoding response")
+			}
+		}
 	}
 }
 
-func run(args []string, stopCh chan os.Signal, ctx context.Context) error {
-	// Get the user's home directory
-	homedir, err := os.UserHomeDir()
+// splitCommaSeparated splits a comma separated flag value into its non-empty, trimmed elements
+func splitCommaSeparated(value string) []string {
+	var elements []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// serverTimeouts holds the timeouts applied to the HTTP servers, so that slow clients (e.g. Slowloris attacks)
+// can't hold connections open indefinitely
+type serverTimeouts struct {
+	readHeader time.Duration
+	read       time.Duration
+	write      time.Duration
+	idle       time.Duration
+}
+
+// apply sets the timeouts on the given server
+func (t serverTimeouts) apply(server *http.Server) {
+	server.ReadHeaderTimeout = t.readHeader
+	server.ReadTimeout = t.read
+	server.WriteTimeout = t.write
+	server.IdleTimeout = t.idle
+}
+
+// listenUnix listens on a Unix domain socket at the given path, replacing any stale socket left behind by a previous run.
+// The socket is only accessible to the owner and group of the process.
+func listenUnix(path string) (net.Listener, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	listener, err := net.Listen("unix", path)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := os.Chmod(path, 0660); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// newHealthzMux returns a mux serving the given endpoints of the unauthenticated healthz server, each at /{name}.
+// Any other path returns a 404.
+func newHealthzMux(endpoints []string, available map[string]http.Handler) (*http.ServeMux, error) {
+	mux := http.NewServeMux()
+	for _, name := range endpoints {
+		handler, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown healthz endpoint %q", name)
+		}
+		mux.Handle("/"+name, loggingMiddleware(handler.ServeHTTP))
 	}
+	return mux, nil
+}
+
+// handleIfEnabled registers the handler for the given pattern only if its feature gate is enabled. The gate is then
+// re-evaluated on every request, so that the endpoint can be switched off at runtime.
+func handleIfEnabled(mux *http.ServeMux, gates *features.Gates, feature, pattern string, handler http.HandlerFunc) {
+	if !gates.Enabled(feature) {
+		klog.InfoS("Endpoint is disabled by feature gate", "feature", feature, "pattern", pattern)
+		return
+	}
+	mux.HandleFunc(pattern, loggingMiddleware(gates.Middleware(feature, handler)))
+}
+
+func run(args []string, stopCh chan os.Signal, ctx context.Context) (err error) {
+	// The errors which weren't classified where they're returned get the exit code of the phase of the startup they
+	// happened in: parsing the flags and configuration files, loading the TLS material, or setting up the clients
+	phase := exitCodeUsage
+	defer func() {
+		err = withExitCode(phase, err)
+	}()
 
 	// Parse command line flags
-	var port, kubeconfig, serverCert, certKey, caCert string
-	flagSet := flag.NewFlagSet(args[0], flag.ExitOnError)
+	var port, kubeconfig, kubeContext, serverCert, certKey, caCert, cacheNamespaces, adminAddress, adminIdentities, loggingFormat, rbacCheckMode, operationsConfigMap, namespaceAuthorization, tenantsFile, redactionPolicyFile, auditLogPath, auditRedactionRulesFile, auditForwardingFile, costPriceSheetFile, imageScanURL, opaDecisionURL string
+	var bindAddress, healthzBindAddress, healthzPort, healthzEndpoints, unixSocket string
+	var fieldManager, applyAllowedKinds, imagePolicyFile, admissionPlugins, gitOpsMode, argoCDInstanceLabel, appLabel, approvalsFile, changeFreezeFile, ipFilterFile, notificationsFile, rolloutAlertsWebhook, sloFile, sloAlertsWebhook, quotasFile, expensiveRoutes, storeBackend, storeNamespace string
+	var timeouts serverTimeouts
+	var enableHTTP2, unixSocketH2C bool
+	var authChain, unixSocketAuthChain string
+	var authConfig auth.Config
+	var guardConfig auth.GuardConfig
+	securityHeaders := securityheaders.Default()
+	var http2MaxConcurrentStreams uint
+	var enableDebugEndpoints, validateResponses, enableGatewayPolicies, opaFailOpen, recordChangeEvents, mockMode bool
+	var mockFixtures string
+	var mockRolloutInterval time.Duration
+	var slowRequestThreshold, drainPeriod, shutdownTimeout, idempotencyTTL, operationTTL, operationTimeout, rolloutStuckThreshold time.Duration
+	var mgrOpts managerOptions
+	var responseCacheMaxEntries, statsWindow, statsMaxSeries, maxInflightRequests, kubeAPIBurst, breakerFailures, auditMaxBodyBytes, captureSize, captureMaxBodyBytes, imageScanConcurrency, snapshotsPerDeployment int
+	var breakerCooldown, imageScanTTL, opaTimeout, kubeAPITimeout, healthzProbeInterval, healthzProbeTimeout, auditRetention, caReloadInterval, certExpiryThreshold, certExpiryCheckInterval, reconnectBackoff, reconnectMaxBackoff time.Duration
+	var healthzHistorySize, healthzFlapThreshold int
+	var kubeAPIRouteTimeouts string
+	var kubeAPIQPS float64
+	gates := features.NewGates()
+	responseCacheTTLs := responsecache.TTLs{}
+	flagSet := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	flagSet.StringVar(&port, "port", "8443", "server port")
-	flagSet.StringVar(&kubeconfig, "kubeconfig", filepath.Join(homedir, ".kube", "config"), "path to the kubeconfig file")
+	flagSet.StringVar(&bindAddress, "bind-address", "", "IP address to bind the main server to. If not specified, the server listens on all interfaces")
+	flagSet.StringVar(&healthzBindAddress, "healthz-bind-address", "", "IP address to bind the healthz server to. If not specified, the server listens on all interfaces")
+	flagSet.StringVar(&healthzPort, "healthz-port", "8080", "healthz server port")
+	flagSet.StringVar(&healthzEndpoints, "healthz-endpoints", "healthz,readyz,livez,startupz,version", "comma separated list of endpoints to serve on the unauthenticated healthz server, out of healthz, healthz/history, readyz, livez, startupz, version and metrics")
+	flagSet.DurationVar(&healthzProbeInterval, "healthz-probe-interval", 10*time.Second, "how often the API server health is probed in the background, /healthz serving the result of the latest probe rather than probing the API server on every request. Set to 0 to probe it on every /healthz request instead")
+	flagSet.DurationVar(&healthzProbeTimeout, "healthz-probe-timeout", 5*time.Second, "timeout of the background probes of the API server health")
+	flagSet.IntVar(&healthzHistorySize, "healthz-history-size", 60, "number of API server health probes kept in /healthz/history")
+	flagSet.IntVar(&healthzFlapThreshold, "healthz-flap-threshold", 4, "number of changes between healthy and unhealthy within /healthz/history from which the API server health is reported as flapping. Set to 0 to disable")
+	flagSet.StringVar(&unixSocket, "unix-socket", "", "optional path of a Unix domain socket to also serve the API on (without TLS), for consumption by sidecars sharing the pod")
+	flagSet.StringVar(&kubeconfig, "kubeconfig", "", "path to the kubeconfig file, which defaults to the files of $KUBECONFIG, or else ~/.kube/config, like kubectl")
+	flagSet.StringVar(&kubeContext, "context", "", "context of the kubeconfig to use, which defaults to its current context. It must exist in the kubeconfig")
+	flagSet.BoolVar(&mockMode, "mock", false, "serve the API from an in-memory fake cluster instead of a Kubernetes cluster, to develop the clients locally. The changes are lost on exit")
+	flagSet.StringVar(&mockFixtures, "mock-fixtures", "", "optional path of a directory of YAML and JSON files holding the objects the fake cluster of --mock is seeded with, e.g. the output of kubectl get -o yaml")
+	flagSet.DurationVar(&mockRolloutInterval, "mock-rollout-interval", 0, "how often the deployments of the fake cluster of --mock move a pod closer to their replicas and pod template, standing in for the deployment controller it lacks. Set to 0 to keep their pods and status as they are")
 	flagSet.StringVar(&serverCert, "server-cert", "", "path to the server certificate")
 	flagSet.StringVar(&certKey, "cert-key", "", "path to the certificate key")
-	flagSet.StringVar(&caCert, "ca-cert", "", "path to the CA certificate")
+	flagSet.StringVar(&caCert, "ca-cert", "", "comma separated paths of the CA bundles verifying the client certificates, e.g. both the old and the new CA during a rotation")
+	flagSet.DurationVar(&certExpiryThreshold, "cert-expiry-warning-threshold", 30*24*time.Hour, "how long before their expiry the serving certificate and the client CAs are logged as expiring, and reported as such by /healthz?verbose")
+	flagSet.DurationVar(&certExpiryCheckInterval, "cert-expiry-check-interval", time.Hour, "how often the expiry of the serving certificate and of the client CAs is checked")
+	flagSet.DurationVar(&caReloadInterval, "ca-reload-interval", time.Minute, "how often the CA bundles of --ca-cert are checked for changes, and reloaded without a restart. Set to 0 to disable")
+	flagSet.BoolVar(&unixSocketH2C, "unix-socket-h2c", false, "serve HTTP/2 over cleartext (h2c) on the Unix domain socket, e.g. for gRPC-gateway sidecars")
+	flagSet.BoolVar(&enableHTTP2, "http2", true, "enable HTTP/2 on the main TLS server")
+	flagSet.UintVar(&http2MaxConcurrentStreams, "http2-max-concurrent-streams", 250, "maximum number of concurrent HTTP/2 streams per connection")
+	flagSet.DurationVar(&timeouts.readHeader, "read-header-timeout", 10*time.Second, "maximum duration for reading the request headers")
+	flagSet.DurationVar(&timeouts.read, "read-timeout", 30*time.Second, "maximum duration for reading the entire request, including the body")
+	flagSet.DurationVar(&timeouts.write, "write-timeout", 60*time.Second, "maximum duration before timing out writes of the response")
+	flagSet.DurationVar(&timeouts.idle, "idle-timeout", 120*time.Second, "maximum amount of time to wait for the next request on a keep-alive connection")
+	flagSet.DurationVar(&slowRequestThreshold, "slow-request-threshold", time.Second, "log the requests slower than this threshold, with a breakdown of the time spent in cache reads, API server calls and encoding the response. Set to 0 to disable")
+	flagSet.DurationVar(&drainPeriod, "shutdown-drain-period", 5*time.Second, "how long to keep serving after /readyz starts failing on shutdown, before the servers are stopped")
+	flagSet.DurationVar(&shutdownTimeout, "shutdown-timeout", 5*time.Second, "how long the servers have to complete the requests in flight once they're stopped, past the drain period, before they're cut off")
+	flagSet.BoolVar(&mgrOpts.leaderElection, "leader-elect", false, "enable leader election, so that only the leader replica serves mutating endpoints")
+	flagSet.StringVar(&mgrOpts.leaderElectionNamespace, "leader-election-namespace", "", "namespace of the leader election lease (defaults to the namespace the pod runs in)")
+	flagSet.StringVar(&mgrOpts.leaderElectionID, "leader-election-id", "go-k8s-http-api-leader", "name of the leader election lease")
+	flagSet.StringVar(&cacheNamespaces, "cache-namespaces", "", "comma separated list of namespaces to cache. If not specified, all namespaces are cached")
+	flagSet.BoolVar(&mgrOpts.cacheStripManagedFields, "cache-strip-managed-fields", true, "strip managedFields from cached objects to reduce memory usage")
+	flagSet.BoolVar(&mgrOpts.cacheStripLastApplied, "cache-strip-last-applied", true, "strip the kubectl last-applied-configuration annotation from cached objects to reduce memory usage")
+	flagSet.BoolVar(&mgrOpts.cacheServedOnly, "cache-served-only", true, "only cache the resources served by the API, instead of starting an informer for any resource that is read")
+	flagSet.BoolVar(&enableDebugEndpoints, "enable-debug-endpoints", false, "serve the pprof, expvar and goroutine dump debug endpoints on the admin address")
+	flagSet.StringVar(&adminAddress, "admin-address", "127.0.0.1:6060", "loopback address to serve the debug endpoints on, when enabled")
+	flagSet.StringVar(&adminIdentities, "admin-identities", "", "comma separated list of identities (client certificate Common Names, or user names of the other authenticators) allowed to use the /admin endpoints")
+	flagSet.StringVar(&authChain, "auth-chain", auth.ClientCertificateAuthenticator, "comma separated, ordered list of the authenticators of the main (TLS) server, out of "+strings.Join(auth.Authenticators(), ", ")+". Client certificates are only required when client-cert is the only one")
+	flagSet.StringVar(&unixSocketAuthChain, "unix-socket-auth-chain", "", "comma separated, ordered list of the authenticators of the Unix domain socket. Its requests are left unauthenticated when empty")
+	authConfig.AddFlags(flagSet)
+	flagSet.IntVar(&guardConfig.Threshold, "auth-lockout-threshold", 10, "number of authentication failures with invalid credentials within --auth-lockout-window from which a source IP or API key is locked out. Set to 0 to disable the lockouts")
+	flagSet.DurationVar(&guardConfig.Window, "auth-lockout-window", 5*time.Minute, "sliding window the authentication failures are counted over")
+	flagSet.DurationVar(&guardConfig.Duration, "auth-lockout-duration", 15*time.Minute, "how long the source IPs and API keys are locked out, their requests getting a 429 Too Many Requests")
+	flagSet.IntVar(&guardConfig.EventsSize, "security-events-size", 1000, "number of security events (authentication failures, lockouts and suspicious successes) kept in memory for /admin/security/events")
+	flagSet.StringVar(&loggingFormat, "logging-format", logging.FormatText, "log format, either \"text\" or \"json\"")
+	flagSet.StringVar(&rbacCheckMode, "rbac-check", rbaccheck.ModeDegrade, "how to handle missing permissions at startup: \"fail\" to exit, \"degrade\" to disable the endpoints missing permissions, or \"off\" to skip the check")
+	flagSet.DurationVar(&idempotencyTTL, "idempotency-ttl", 24*time.Hour, "how long the responses to mutating requests carrying an Idempotency-Key header are kept for replaying to retries")
+	flagSet.DurationVar(&operationTTL, "operation-ttl", time.Hour, "how long completed async operations are kept")
+	flagSet.DurationVar(&operationTimeout, "operation-timeout", 10*time.Minute, "maximum duration of an async operation, after which it fails")
+	flagSet.StringVar(&operationsConfigMap, "operations-configmap", "", "optional namespace/name of a ConfigMap to persist the async operations in, so that they survive restarts and are visible to all replicas. Takes precedence over --store for the operations")
+	flagSet.StringVar(&storeBackend, "store", store.BackendMemory, "where the state owned by the server (e.g. async operations) is kept: \"memory\", or \"configmap\" / \"crd\" to have it survive restarts and be visible to all replicas")
+	flagSet.StringVar(&storeNamespace, "store-namespace", "default", "namespace of the ConfigMaps / Records holding the state, with the configmap and crd stores")
+	flagSet.StringVar(&namespaceAuthorization, "namespace-authorization", namespaceAuthorizationSubjectAccessReview, "how clients are authorized on the namespace scoped routes: \"subjectaccessreview\" to check the access of their identity with the cluster's RBAC, or \"none\"")
+	flagSet.StringVar(&tenantsFile, "tenants-file", "", "optional path of a YAML file mapping client identities to the namespaces they may access. If not specified, tenancy is disabled")
+	flagSet.StringVar(&redactionPolicyFile, "redaction-policy-file", "", "optional path of a YAML file configuring the sensitive fields (environment variable values, image pull secrets, annotations) redacted from the manifests and diffs served to non-privileged client identities. If not specified, responses aren't redacted")
+	flagSet.StringVar(&costPriceSheetFile, "cost-price-sheet-file", "", "path of a YAML file holding the prices (per CPU core and per GiB of memory, for a month) the costs of /cost are estimated with. Required by the CostEstimation feature gate")
+	flagSet.StringVar(&imageScanURL, "image-scan-url", "", "optional URL of the Trivy or Clair compatible scan report of an image, where {image} is replaced by the URL-escaped image, e.g. http://scanner/report?image={image}. If specified, the images of /images are enriched with their vulnerabilities")
+	flagSet.DurationVar(&imageScanTTL, "image-scan-ttl", time.Hour, "how long the scan results of the images are cached")
+	flagSet.IntVar(&imageScanConcurrency, "image-scan-concurrency", 4, "maximum number of scan reports fetched at once")
+	flagSet.IntVar(&snapshotsPerDeployment, "snapshots-per-deployment", snapshots.DefaultMaxPerDeployment, "maximum number of snapshots kept per deployment, beyond which the oldest ones are removed")
+	flagSet.StringVar(&auditLogPath, "audit-log-path", "", "optional path of the file the mutating requests are recorded to as JSON lines, or - for stdout. If not specified, the audit log is disabled")
+	flagSet.StringVar(&auditRedactionRulesFile, "audit-redaction-rules-file", "", "optional path of a YAML file listing the JSONPath redaction rules applied to the request and response bodies before they're written to the audit log")
+	flagSet.IntVar(&auditMaxBodyBytes, "audit-max-body-bytes", 64<<10, "maximum size of the request and response bodies recorded to the audit log, larger bodies are left out")
+	flagSet.IntVar(&captureSize, "capture-size", 0, "number of the latest requests, along with their responses, kept by the captures started with /admin/capture. Set to 0 to disable the captures")
+	flagSet.IntVar(&captureMaxBodyBytes, "capture-max-body-bytes", 64<<10, "maximum size of the request and response bodies recorded by the captures, larger bodies are left out")
+	flagSet.StringVar(&auditForwardingFile, "audit-forwarding-file", "", "optional path of a YAML file configuring the forwarding of the audit events to a SIEM, over syslog (RFC 5424 over TLS) and/or to an HTTPS collector such as a Splunk HTTP Event Collector")
+	flagSet.DurationVar(&auditRetention, "audit-retention", 0, "how long the audit events are also kept in the state store, to be queried with GET /audit. If 0, the events are only recorded to the audit log and /audit isn't served")
+	flagSet.BoolVar(&enableGatewayPolicies, "gateway-policies", false, "enforce the authorization rules, namespace allow-lists and rate limits of the APIGatewayPolicy custom resources. Requires the APIGatewayPolicy CRD to be installed")
+	flagSet.StringVar(&opaDecisionURL, "opa-decision-url", "", "optional URL of an Open Policy Agent decision (e.g. http://localhost:8181/v1/data/k8sapi/decision) the mutating requests must be allowed by before they are served")
+	flagSet.DurationVar(&opaTimeout, "opa-timeout", 2*time.Second, "timeout of the policy decisions of --opa-decision-url")
+	flagSet.BoolVar(&opaFailOpen, "opa-fail-open", false, "allow the mutating requests when the policy decision can't be evaluated, instead of rejecting them with a 503")
+	flagSet.StringVar(&fieldManager, "field-manager", handlers.DefaultFieldManager, "field manager of the server-side applies, which requests to /apply may override with ?fieldManager=")
+	flagSet.StringVar(&applyAllowedKinds, "apply-allowed-kinds", "Deployment.v1.apps", "comma separated list of the kinds which may be applied via /apply, in the Kind.version.group format (e.g. ConfigMap.v1 for the core group)")
+	flagSet.StringVar(&imagePolicyFile, "image-policy-file", "", "optional path of a YAML file holding the registry allow-list and deny-list, and the tag policies, the images of the changes made through the API must comply with. Enables the ImagePolicy admission plugin")
+	flagSet.StringVar(&admissionPlugins, "admission-plugins", admission.ReplicaBoundsPlugin, fmt.Sprintf("comma separated list of the admission plugins the changes made through the API are run through before they are written, in order, out of %s", strings.Join(admission.Plugins, ", ")))
+	flagSet.StringVar(&approvalsFile, "approvals-file", "", "optional path of a YAML file listing the operations (e.g. scaling above a number of replicas) which are held until a second identity approves them via POST /approvals/{id}/approve")
+	flagSet.Func("security-header", "Name=Value of a security header added to the responses, overriding its default, or disabling it when the value is empty. May be repeated. The defaults are "+securityHeaders.String(), securityHeaders.Set)
+	flagSet.StringVar(&ipFilterFile, "ip-filter-file", "", "optional path of a YAML file with the CIDRs the TLS server may be reached from, globally and per group of routes, and the trusted proxies whose X-Forwarded-For header is honored")
+	flagSet.StringVar(&changeFreezeFile, "change-freeze-file", "", "optional path of a YAML file listing the freeze windows (fixed periods, cron schedules, or the events of an iCalendar feed) during which the mutating requests are rejected")
+	flagSet.StringVar(&notificationsFile, "notifications-file", "", "optional path of a YAML file routing notifications of the changes made through the API (e.g. who scaled what from X to Y) to Slack or Microsoft Teams webhooks, by namespace")
+	flagSet.BoolVar(&recordChangeEvents, "record-change-events", true, "record an event on every object changed through the API (e.g. \"Scaled by go-k8s-http-api on behalf of CN=ci-bot from 3 to 7\"), so that kubectl describe shows who changed it")
+	flagSet.StringVar(&gitOpsMode, "gitops-mode", admission.GitOpsWarn, fmt.Sprintf("whether the GitOps admission plugin warns about (%s) or rejects (%s) the changes of the deployments managed by Argo CD or Flux, which they would revert", admission.GitOpsWarn, admission.GitOpsReject))
+	flagSet.StringVar(&appLabel, "app-label", apps.DefaultLabel, "label the /apps endpoints group the deployments, statefulsets, services and HorizontalPodAutoscalers of the same application by")
+	flagSet.StringVar(&argoCDInstanceLabel, "gitops-argocd-instance-label", "", "optional label of the deployments managed by Argo CD with the label based tracking, e.g. app.kubernetes.io/instance, for the GitOps admission plugin. Deployments tracked by annotation are detected regardless")
+	flagSet.DurationVar(&rolloutStuckThreshold, "rollout-stuck-threshold", 30*time.Minute, "how long a deployment rollout may make no progress before it's reported by /alerts/rollouts, regardless of its progressDeadlineSeconds. Set to 0 to only report rollouts exceeding their progress deadline")
+	flagSet.StringVar(&rolloutAlertsWebhook, "rollout-alerts-webhook", "", "optional URL to post a JSON notification to whenever a rollout alert fires or resolves")
+	flagSet.StringVar(&quotasFile, "quotas-file", "", "optional path of a YAML file listing the hourly or daily quotas of mutating operations of the client identities, e.g. 100 scale operations a day for a CI bot, beyond which the operations are rejected with a 429")
+	flagSet.StringVar(&sloFile, "slo-file", "", "optional path of a YAML file listing the availability and latency objectives of the routes, whose error budgets and burn rates are served by /admin/slo and exported as metrics")
+	flagSet.StringVar(&sloAlertsWebhook, "slo-alerts-webhook", "", "optional URL to post a JSON notification to whenever the error budget of an objective starts or stops burning fast")
+	flagSet.BoolVar(&validateResponses, "validate-responses", false, "validate response bodies against their JSON Schema and log mismatches, for use in test and debug environments")
+	flagSet.Var(responseCacheTTLs, "response-cache-ttls", "comma separated list of Name=duration pairs to cache the responses of read endpoints for, e.g. ListDeployments=5s,GetDeploymentHealth=10s. Cached responses are invalidated by the writes to their namespace")
+	flagSet.IntVar(&responseCacheMaxEntries, "response-cache-max-entries", 10000, "maximum number of responses held by the response cache")
+	flagSet.IntVar(&statsWindow, "stats-window", 1000, "number of most recent requests of every client identity and route that the latency percentiles of /admin/stats are computed over")
+	flagSet.Float64Var(&kubeAPIQPS, "kube-api-qps", 50, "maximum queries per second to the Kubernetes API server, shared by all the clients of the server")
+	flagSet.IntVar(&kubeAPIBurst, "kube-api-burst", 100, "maximum burst of queries to the Kubernetes API server above --kube-api-qps")
+	flagSet.DurationVar(&kubeAPITimeout, "kube-api-timeout", 30*time.Second, "deadline of every Kubernetes API server call made while serving a request, after which the request fails with a 504. Set to 0 to disable")
+	flagSet.StringVar(&kubeAPIRouteTimeouts, "kube-api-route-timeouts", "", "comma separated list of PATTERN=duration pairs overriding --kube-api-timeout for the routes with the given pattern, e.g. \"POST /apply=2m\". Set a route to 0 to disable its deadline")
+	flagSet.IntVar(&breakerFailures, "apiserver-breaker-failures", 5, "number of consecutive API server calls failing or timing out after which the circuit breaker opens: reads are served from the cache and mutating requests fail right away with a 503. Set to 0 to disable")
+	flagSet.DurationVar(&breakerCooldown, "apiserver-breaker-cooldown", 30*time.Second, "how long the circuit breaker stays open before letting a trial call through to the API server")
+	flagSet.DurationVar(&reconnectBackoff, "apiserver-reconnect-backoff", time.Second, "initial delay between the retries of the startup steps calling the API server while it's unreachable, doubled after every retry up to --apiserver-reconnect-max-backoff")
+	flagSet.DurationVar(&reconnectMaxBackoff, "apiserver-reconnect-max-backoff", 30*time.Second, "maximum delay between the retries of the startup steps calling the API server while it's unreachable")
+	flagSet.IntVar(&maxInflightRequests, "max-inflight-requests", 0, "maximum number of requests served at once, beyond which the requests are shed with a 429, the lowest priorities first: the --expensive-routes from half of the limit, the writes from 80%, and the reads at the limit. The probes are never shed. Set to 0 to disable")
+	flagSet.StringVar(&expensiveRoutes, "expensive-routes", strings.Join(shedding.DefaultExpensiveRoutes, ","), "comma separated list of the patterns of the routes fanning out to all the namespaces, which are shed first under overload (see --max-inflight-requests)")
+	flagSet.IntVar(&statsMaxSeries, "stats-max-series", 1000, "maximum number of client identity and route pairs tracked by /admin/stats, beyond which the requests of new identities are grouped under \"other\"")
+	flagSet.Var(gates, "feature-gates", "comma separated list of Name=bool pairs to enable / disable endpoints, e.g. SetDeploymentReplicas=false")
 	klog.InitFlags(flagSet)
 	defer klog.Flush()
-	err = flagSet.Parse(os.Args[1:])
-	if err != nil {
+	if err := flagSet.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	// Set up the log format, and have controller-runtime log through klog as well
+	if err := logging.Setup(loggingFormat, os.Stderr); err != nil {
+		return err
+	}
+	ctrl.SetLogger(klog.Background())
+
+	// The debug endpoints are unauthenticated, so they may only ever be served on a loopback interface
+	if enableDebugEndpoints {
+		if err := debug.ValidateLoopbackAddress(adminAddress); err != nil {
+			return fmt.Errorf("invalid --admin-address: %w", err)
+		}
+	}
+	if err := rbaccheck.ValidateMode(rbacCheckMode); err != nil {
 		return err
 	}
+	if err := store.ValidateBackend(storeBackend); err != nil {
+		return fmt.Errorf("invalid --store: %w", err)
+	}
+	if certExpiryCheckInterval <= 0 {
+		return fmt.Errorf("invalid --cert-expiry-check-interval %s, must be positive", certExpiryCheckInterval)
+	}
+	if shutdownTimeout <= 0 {
+		return fmt.Errorf("invalid --shutdown-timeout %s, must be positive", shutdownTimeout)
+	}
+	if reconnectBackoff <= 0 || reconnectMaxBackoff < reconnectBackoff {
+		return fmt.Errorf("invalid --apiserver-reconnect-backoff %s and --apiserver-reconnect-max-backoff %s, must be positive and in increasing order", reconnectBackoff, reconnectMaxBackoff)
+	}
+	if namespaceAuthorization != namespaceAuthorizationSubjectAccessReview && namespaceAuthorization != namespaceAuthorizationNone {
+		return fmt.Errorf("invalid --namespace-authorization %q, must be either %s or %s", namespaceAuthorization, namespaceAuthorizationSubjectAccessReview, namespaceAuthorizationNone)
+	}
+
+	// Load the tenants, mapping client identities to the namespaces they may access
+	var tenants *tenancy.Tenancy
+	if tenantsFile != "" {
+		tenants, err = tenancy.LoadFile(tenantsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Load the redaction policy of the full-detail responses served to non-privileged identities
+	var redactionPolicy *redaction.Policy
+	if redactionPolicyFile != "" {
+		redactionPolicy, err = redaction.LoadFile(redactionPolicyFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Load the price sheet the costs of the deployments are estimated with
+	var priceSheet *cost.PriceSheet
+	if gates.Enabled(features.CostEstimation) {
+		if costPriceSheetFile == "" {
+			return fmt.Errorf("the CostEstimation feature gate requires --cost-price-sheet-file")
+		}
+		priceSheet, err = cost.LoadFile(costPriceSheetFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Set up the audit log of the mutating requests, with their bodies redacted by the configured rules. The events are
+	// also kept in the state store once it's set up, when they're to be queried.
+	var auditSinks audit.MultiSink
+	var auditRules []audit.Rule
+	if auditLogPath != "" {
+		auditSink, err := audit.NewFileSink(auditLogPath)
+		if err != nil {
+			return err
+		}
+		auditSinks = append(auditSinks, auditSink)
+	}
+	// Forwarding to the SIEMs happens in the background, so that a slow collector never delays the API
+	var auditForwarders []*audit.Forwarder
+	if auditForwardingFile != "" {
+		auditForwarders, err = audit.LoadForwardingFile(auditForwardingFile)
+		if err != nil {
+			return err
+		}
+		for _, forwarder := range auditForwarders {
+			auditSinks = append(auditSinks, forwarder)
+		}
+	}
+	if captureSize < 0 || captureMaxBodyBytes <= 0 {
+		return fmt.Errorf("invalid --capture-size %d and --capture-max-body-bytes %d, must not be negative and positive respectively", captureSize, captureMaxBodyBytes)
+	}
+	if auditRetention < 0 {
+		return fmt.Errorf("--audit-retention must not be negative")
+	}
+	if auditRedactionRulesFile != "" {
+		if len(auditSinks) == 0 && auditRetention == 0 && captureSize == 0 {
+			return fmt.Errorf("--audit-redaction-rules-file requires --audit-log-path, --audit-forwarding-file, --audit-retention or --capture-size")
+		}
+		auditRules, err = audit.LoadRulesFile(auditRedactionRulesFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	allowedKinds, err := handlers.ParseKinds(splitCommaSeparated(applyAllowedKinds))
+	if err != nil {
+		return fmt.Errorf("invalid --apply-allowed-kinds: %w", err)
+	}
+	// The mutating requests are evaluated against the OPA policy decision, once allowed by tenancy and the gateway
+	// policies
+	var opaClient *opa.Client
+	if opaDecisionURL != "" {
+		opaClient = &opa.Client{URL: opaDecisionURL, Client: &http.Client{Timeout: opaTimeout}, FailOpen: opaFailOpen}
+	}
+	// The mutating requests are rejected during the change freezes, unless made by an override identity
+	var changeFreeze *freeze.Freeze
+	if changeFreezeFile != "" {
+		changeFreeze, err = freeze.LoadFile(changeFreezeFile)
+		if err != nil {
+			return err
+		}
+	}
+	// The requests are counted against the service level objectives, if any
+	var sloTracker *slo.Tracker
+	if sloFile != "" {
+		sloTracker, err = slo.LoadFile(sloFile)
+		if err != nil {
+			return err
+		}
+		if sloAlertsWebhook != "" {
+			sloTracker.Notifier = &slo.WebhookNotifier{URL: sloAlertsWebhook, Client: &http.Client{Timeout: 10 * time.Second}}
+		}
+	}
+	// The changes made through the API are notified to chat channels, routed by namespace
+	var notifier *notify.Notifier
+	if notificationsFile != "" {
+		notifier, err = notify.LoadFile(notificationsFile)
+		if err != nil {
+			return err
+		}
+	}
+	var imagePolicy *imagepolicy.Policy
+	if imagePolicyFile != "" {
+		imagePolicy, err = imagepolicy.LoadFile(imagePolicyFile)
+		if err != nil {
+			return err
+		}
+	}
+	admissionPluginNames := splitCommaSeparated(admissionPlugins)
+	if imagePolicy != nil && !slices.Contains(admissionPluginNames, admission.ImagePolicyPlugin) {
+		admissionPluginNames = append(admissionPluginNames, admission.ImagePolicyPlugin)
+	}
+
+	mgrOpts.cacheNamespaces = splitCommaSeparated(cacheNamespaces)
+	if mockFixtures != "" && !mockMode {
+		return fmt.Errorf("--mock-fixtures requires --mock")
+	}
+	if mockRolloutInterval < 0 {
+		return fmt.Errorf("--mock-rollout-interval must be greater than or equal to 0")
+	}
+	if mockRolloutInterval > 0 && !mockMode {
+		return fmt.Errorf("--mock-rollout-interval requires --mock")
+	}
+	if mockMode && (mgrOpts.leaderElection || enableGatewayPolicies) {
+		return fmt.Errorf("--mock doesn't support --leader-elect and --gateway-policies, which require a Kubernetes cluster")
+	}
 
 	// Load server's certificate and private key
+	phase = exitCodeTLS
 	cert, err := tls.LoadX509KeyPair(serverCert, certKey)
 	if err != nil {
-		klog.Fatalf("Error loading server certificate and private key: %v", err)
+		return fmt.Errorf("failed to load the server certificate and private key: %w", err)
 	}
 
-	// Parse the provided CA certificate
-	caCertPool := x509.NewCertPool()
-	parsedCaCert, err := os.ReadFile(caCert)
+	// Load the provided CA bundles, which are reloaded whenever they change
+	clientCAs, err := clientca.Load(splitCommaSeparated(caCert))
 	if err != nil {
-		klog.Fatalf("Error loading CA certificate: %v", err)
+		return fmt.Errorf("failed to load the CA certificates: %w", err)
 	}
-	caCertPool.AppendCertsFromPEM([]byte(parsedCaCert))
 
-	// Create a tls.Config with the server certificate and require client cert verification
+	// Create a tls.Config with the server certificate and client cert verification, the client certificates only being
+	// required when they're the only way to authenticate (see the authentication chains below)
 	tlsConfig := &tls.Config{
 		Certificates: []tls.Certificate{cert},
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		ClientCAs:    caCertPool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    clientCAs.CertPool(),
 		MinVersion:   tls.VersionTLS13,
 	}
+	// Every handshake verifies the client certificates with the CA bundles currently loaded
+	tlsConfig.GetConfigForClient = clientCAs.ConfigForClient(tlsConfig)
+	mux := http.NewServeMux()
+	// Every request to the API is recorded in the per-identity, per-route statistics served by /admin/stats
+	requestStats := stats.NewRecorder(statsWindow, statsMaxSeries)
+	apiHandler := requestStats.Middleware(mux)
+	// The requests to the route captured by an admin are recorded, sanitized, along with their responses
+	var captureRecorder *capture.Recorder
+	if captureSize > 0 {
+		captureRecorder = capture.NewRecorder(mux, captureSize, captureMaxBodyBytes, auditRules)
+		apiHandler = captureRecorder.Middleware(apiHandler)
+	}
+	if sloTracker != nil {
+		apiHandler = sloTracker.Middleware(apiHandler)
+	}
+	// Under overload, the requests of the lowest priorities are shed first, so that the probes and small reads stay
+	// responsive
+	if maxInflightRequests > 0 {
+		shedder, err := shedding.New(mux, maxInflightRequests, splitCommaSeparated(expensiveRoutes))
+		if err != nil {
+			return withExitCode(exitCodeUsage, err)
+		}
+		apiHandler = shedder.Middleware(apiHandler)
+	}
 	server := &http.Server{
-		Addr:      ":" + port,
-		Handler:   nil, // use http.DefaultServeMux
+		Addr:      net.JoinHostPort(bindAddress, port),
+		Handler:   apiwarnings.Middleware(timing.SlowRequests(slowRequestThreshold, apiHandler)),
 		TLSConfig: tlsConfig,
 	}
-	if err != nil {
-		klog.Fatalf("Error loading server certificate: %v", err)
+	timeouts.apply(server)
+	// The connections are counted by client certificate identity, capped along with the series of /admin/stats, and
+	// the failed TLS handshakes by reason, since the clients failing mutual TLS never reach the handlers
+	conntrack.NewTracker(statsMaxSeries).Instrument(server)
+	http2Server := &http2.Server{
+		MaxConcurrentStreams: uint32(http2MaxConcurrentStreams),
+		IdleTimeout:          timeouts.idle,
+	}
+	if enableHTTP2 {
+		// Explicitly configure HTTP/2, rather than relying on the defaults of net/http
+		if err := http2.ConfigureServer(server, http2Server); err != nil {
+			return fmt.Errorf("failed to configure HTTP/2: %w", err)
+		}
+	} else {
+		// A non-nil, empty TLSNextProto map disables HTTP/2
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
 	}
 
-	// use the current context in kubeconfig
-
+	phase = exitCodeKubernetes
 	var config *rest.Config
-
-	// First, try to load the kubeconfig file
-	klog.V(5).Infof("Trying to load kubeconfig file: %v", kubeconfig)
-	config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	var activeContext string
+	scheme, err := newScheme()
 	if err != nil {
-		// log the error as a warning, and try to get the in-cluster config
-		klog.Warningf("Error loading kubeconfig file: %v", err)
-		klog.V(5).Info("Trying to get in-cluster config")
-		config, err = rest.InClusterConfig()
-		if err != nil {
-			if err == rest.ErrNotInCluster {
-				// since kubeconfig failed to load and we're not in cluster, we can't continue
-				klog.Fatalf("kubeconfig failed to load and not running in cluster, cannot continue. Please provide a valid kubeconfig file or run in-cluster")
-			} else {
-				// we are running in-cluster, but there was an error getting the config (other than ErrNotInCluster)
-				klog.Fatalf("Error getting in-cluster config: %v", err)
+		return err
+	}
+
+	// In mock mode, the API is served from an in-memory fake cluster seeded with the fixtures, rather than from a cluster
+	var mockCluster *mock.Cluster
+	if mockMode {
+		var objects []client.Object
+		if mockFixtures != "" {
+			objects, err = mock.LoadFixtures(mockFixtures, scheme)
+			if err != nil {
+				return withExitCode(exitCodeUsage, fmt.Errorf("failed to load the fixtures of --mock-fixtures: %w", err))
 			}
-		} else {
-			klog.Info("Using in-cluster config")
 		}
+		mockCluster, err = mock.NewCluster(scheme, objects...)
+		if err != nil {
+			return withExitCode(exitCodeUsage, fmt.Errorf("failed to create the fake cluster: %w", err))
+		}
+		mgrOpts.cluster = mockCluster
+		config, activeContext = mockCluster.Config(), "mock"
+		klog.InfoS("Serving the API from a fake cluster", "fixtures", mockFixtures, "objects", len(objects))
+	}
+
+	if mockCluster == nil {
+		config, activeContext, err = loadConfig(kubeconfig, kubeContext)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Rate limit the requests to the API server with a single limiter shared by all the clients, which fails the requests
+	// that would outlive their context instead of queueing them, and records the throttling in the metrics
+	rateLimiter, err := ratelimit.New(float32(kubeAPIQPS), kubeAPIBurst)
+	if err != nil {
+		return withExitCode(exitCodeUsage, fmt.Errorf("invalid Kubernetes API rate limits: %w", err))
+	}
+	config.QPS, config.Burst, config.RateLimiter = float32(kubeAPIQPS), kubeAPIBurst, rateLimiter
+	// Bound every API server call made while serving a request with the deadline of its route
+	routeTimeouts, err := deadline.ParseRoutes(kubeAPIRouteTimeouts)
+	if err != nil {
+		return withExitCode(exitCodeUsage, fmt.Errorf("invalid --kube-api-route-timeouts: %w", err))
+	}
+	routeDeadlines := &deadline.Deadlines{Default: kubeAPITimeout, Routes: routeTimeouts}
+	// Forward the warnings of the API server (e.g. deprecation notices) to the clients whose requests triggered them
+	config.Wrap(apiwarnings.WrapTransport)
+
+	// Every client shares the same HTTP client, whose API server calls are tracked as a whole, so that the failures of
+	// the credentials (e.g. of an exec credential plugin) are reported by /readyz
+	credentials := kubecredentials.NewTracker(config)
+	klog.InfoS("Using the API server credentials", "source", credentials.Status().Source)
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return fmt.Errorf("failed to create the Kubernetes API client: %w", err)
 	}
+	httpClient.Transport = credentials.WrapTransport(httpClient.Transport)
+	// The connectivity to the API server is tracked out of the same calls. While it's unreachable, the startup steps
+	// calling it are retried with a backoff, and the informers reconnect on their own, rather than the server exiting.
+	connectivity := reconnect.NewTracker(reconnectBackoff, reconnectMaxBackoff)
+	httpClient.Transport = connectivity.WrapTransport(httpClient.Transport)
+	mgrOpts.httpClient = httpClient
 
 	// create the clientset
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, err := kubernetes.NewForConfigAndClient(config, httpClient)
 	if err != nil {
 		return err
 	}
+	authorizationClient, healthzClient := clientset.AuthorizationV1(), rest.Interface(clientset.RESTClient())
+	if mockCluster != nil {
+		// The fake cluster is always healthy, and allows every access
+		authorizationClient, healthzClient = mockCluster.AuthorizationClient(), mockCluster.HealthzClient()
+	}
+
+	// Verify the server has the permissions needed by the enabled endpoints, instead of discovering missing ones via runtime errors
+	if rbacCheckMode != rbaccheck.ModeOff {
+		requirements := rbaccheck.Requirements(gates, mgrOpts.cacheNamespaces)
+		if namespaceAuthorization == namespaceAuthorizationSubjectAccessReview {
+			requirements = append(requirements, rbaccheck.Requirement{
+				Permission: rbaccheck.Permission{Verb: "create", Group: "authorization.k8s.io", Resource: "subjectaccessreviews"},
+			})
+		}
+		for _, permission := range admission.Permissions(admissionPluginNames) {
+			requirements = append(requirements, rbaccheck.Requirement{Permission: permission})
+		}
+		var results []rbaccheck.Result
+		err := connectivity.Retry(ctx, "rbac-check", func(ctx context.Context) (err error) {
+			results, err = rbaccheck.Check(ctx, authorizationClient, requirements)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to verify the permissions: %w", err)
+		}
+		if err := rbaccheck.Enforce(klog.Background(), results, gates, rbacCheckMode); err != nil {
+			return fmt.Errorf("failed to verify the permissions: %w", err)
+		}
+	}
 
 	// Create a new manager to watch for changes to deployments
-	mgr, err := setupManager()
+	mgr, err := setupManager(config, scheme, mgrOpts)
+	if err != nil {
+		return fmt.Errorf("failed to set up the manager: %w", err)
+	}
+
+	// Register an informer for every served resource, and track its initial sync progress so that slow cold starts are visible.
+	// When --cache-served-only is set, these are the only resources the cache serves.
+	cacheSyncTracker := cachesync.NewTracker()
+	servedObjects := map[string]client.Object{
+		"deployments.apps": &appsv1.Deployment{},
+	}
+	for name, obj := range servedObjects {
+		var informer cache.Informer
+		err := connectivity.Retry(ctx, "informers", func(ctx context.Context) (err error) {
+			informer, err = mgr.GetCache().GetInformer(ctx, obj, cache.BlockUntilSynced(false))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get the %s informer: %w", name, err)
+		}
+		if err := cacheSyncTracker.Track(ctx, name, informer); err != nil {
+			return fmt.Errorf("failed to track the %s informer: %w", name, err)
+		}
+	}
+
+	// The number of objects of every served resource is counted up front, so that the sync progress of their informers
+	// is reported as a percentage. It doesn't hold the startup up, the progress staying 0 until counted.
+	go func() {
+		for name, obj := range servedObjects {
+			gvk, err := apiutil.GVKForObject(obj, mgr.GetScheme())
+			if err != nil {
+				klog.ErrorS(err, "Error getting the kind of a served resource", "resource", name)
+				continue
+			}
+			var count int64
+			err = connectivity.Retry(ctx, "count-objects", func(ctx context.Context) (err error) {
+				count, err = cachesync.CountObjects(ctx, mgr.GetAPIReader(), gvk, mgrOpts.cacheNamespaces)
+				return err
+			})
+			if err != nil {
+				klog.ErrorS(err, "Error counting the objects of a served resource", "resource", name)
+				continue
+			}
+			cacheSyncTracker.SetExpected(name, count)
+		}
+	}()
+
+	// The deployment lists read from the cache are served from summaries projected as the informer receives the
+	// deployments, rather than deep copying every cached deployment on each request
+	deploymentSummaries := projection.NewStore()
+	deploymentsInformer, err := mgr.GetCache().GetInformer(ctx, &appsv1.Deployment{}, cache.BlockUntilSynced(false))
 	if err != nil {
-		klog.Fatalf("Error setting up manager: %v", err)
+		return fmt.Errorf("failed to get the deployments informer: %w", err)
+	}
+	if err := deploymentSummaries.Track(deploymentsInformer); err != nil {
+		return fmt.Errorf("failed to track the deployments informer: %w", err)
 	}
 
 	// HealthzHandler is an HTTP handler for the healthz API.
-	healthzHandler := &handlers.HealthzHandler{Client: clientset.RESTClient()}
-	http.Handle("/healthz", healthzHandler)
+	// Its probes of the API server are kept in a short history, telling transient blips from sustained outages
+	healthHistory := healthhistory.New(healthzHistorySize, healthzFlapThreshold)
+	// The expiry of the serving certificate and of the client CAs is reported by /healthz?verbose, without failing it
+	certExpiry := certexpiry.New(cert.Leaf, clientCAs, certExpiryThreshold)
+	healthzHandler := &handlers.HealthzHandler{Client: healthzClient, History: healthHistory, ProbeTimeout: healthzProbeTimeout,
+		Checks: []handlers.ReadyzCheck{{Name: "certificates", Check: certExpiry.HealthzCheck}}}
+	healthzHistoryHandler := &handlers.HealthzHistoryHandler{History: healthHistory}
+	mux.Handle("/healthz", healthzHandler)
+	mux.Handle("/healthz/history", healthzHistoryHandler)
+
+	// ReadyzHandler reports whether the instance should receive traffic. It starts failing once shutdown begins.
+	// It also reports the per informer cache sync progress, and only passes once all the informers have synced.
+	// When the API server calls keep failing, the circuit breaker fails the next ones right away, and its state is
+	// reported by /readyz (without failing it, since reads keep being served from the cache)
+	var apiBreaker *breaker.Breaker
+	if breakerFailures > 0 {
+		apiBreaker = breaker.New(breakerFailures, breakerCooldown)
+	}
+	readyzHandler := &handlers.ReadyzHandler{
+		Checks: []handlers.ReadyzCheck{
+			{Name: "informers", Check: cacheSyncTracker.ReadyzCheck},
+		},
+	}
+	if apiBreaker != nil {
+		readyzHandler.Checks = append(readyzHandler.Checks, handlers.ReadyzCheck{Name: "apiserver", Check: apiBreaker.ReadyzCheck, Degraded: apiBreaker.Open})
+	}
+	// The outages of the API server are reported as degraded too, while the informers reconnect, and the failures of
+	// its credentials without failing /readyz either. The fake cluster of the mock mode is never called through them.
+	if mockCluster == nil {
+		readyzHandler.Checks = append(readyzHandler.Checks, handlers.ReadyzCheck{Name: "connectivity", Check: connectivity.ReadyzCheck, Degraded: connectivity.Degraded})
+		readyzHandler.Checks = append(readyzHandler.Checks, handlers.ReadyzCheck{Name: "credentials", Check: credentials.ReadyzCheck})
+	}
+	mux.Handle("/readyz", readyzHandler)
+
+	// StartupzHandler reports whether the instance has started, for the startup probe: once the informers have synced,
+	// along with their sync progress until then, and the TLS material was loaded
+	startupzHandler := &handlers.StartupzHandler{
+		Checks: []handlers.ReadyzCheck{
+			{Name: "informers", Check: cacheSyncTracker.ReadyzCheck},
+			{Name: "tls", Check: func() (bool, any) {
+				if cert.Leaf == nil {
+					return false, nil
+				}
+				return true, map[string]any{"subject": cert.Leaf.Subject.String(), "notAfter": cert.Leaf.NotAfter}
+			}},
+		},
+	}
+	mux.Handle("/startupz", startupzHandler)
+
+	// Response bodies are only validated against their JSON Schema when enabled, since it requires buffering every response
+	validateResponse := func(name string, next http.HandlerFunc) http.HandlerFunc {
+		if !validateResponses {
+			return next
+		}
+		return schema.ValidateResponse(name, next)
+	}
+
+	// The state owned by the server is read directly from the API server, since ConfigMaps and Records aren't cached
+	var storeClient client.Client
+	if mockCluster != nil {
+		storeClient = mockCluster.Client()
+	} else if storeClient, err = client.New(config, client.Options{Scheme: mgr.GetScheme(), HTTPClient: httpClient}); err != nil {
+		return fmt.Errorf("failed to create the client: %w", err)
+	}
+
+	// Long-running actions requested with ?async=true run in the background as operations, which clients poll.
+	// Operations are optionally persisted, so that they survive restarts and are visible to all replicas.
+	var operationsStore store.Store
+	if operationsConfigMap != "" {
+		namespace, name, found := strings.Cut(operationsConfigMap, "/")
+		if !found || namespace == "" || name == "" {
+			return withExitCode(exitCodeUsage, fmt.Errorf("invalid --operations-configmap %q, must be in the namespace/name format", operationsConfigMap))
+		}
+		operationsStore = &store.ConfigMap{Client: storeClient, Namespace: namespace, Name: name}
+	} else if storeBackend != store.BackendMemory {
+		operationsStore, err = store.New(storeBackend, storeClient, storeNamespace, "go-k8s-http-api-operations")
+		if err != nil {
+			return err
+		}
+	}
+	operationsManager := operations.NewManager(operationTTL, operationTimeout, operationsStore)
+	if err := connectivity.Retry(ctx, "restore-operations", operationsManager.Restore); err != nil {
+		return fmt.Errorf("failed to restore the operations: %w", err)
+	}
+
+	// Sensitive changes are held until a second identity approves them. Pending approvals are kept in the state store,
+	// so that any replica may serve their approval.
+	var approvalsManager *approvals.Manager
+	if approvalsFile != "" {
+		var approvalsStore store.Store = store.NewMemory()
+		if storeBackend != store.BackendMemory {
+			approvalsStore, err = store.New(storeBackend, storeClient, storeNamespace, "go-k8s-http-api-approvals")
+			if err != nil {
+				return err
+			}
+		}
+		approvalsManager, err = approvals.LoadFile(approvalsFile, approvalsStore)
+		if err != nil {
+			return withExitCode(exitCodeUsage, err)
+		}
+	}
+
+	// Snapshots of the deployment specs are kept in the state store, so that they survive restarts and are visible to all
+	// replicas
+	var snapshotsStore store.Store = store.NewMemory()
+	if storeBackend != store.BackendMemory {
+		snapshotsStore, err = store.New(storeBackend, storeClient, storeNamespace, "go-k8s-http-api-snapshots")
+		if err != nil {
+			return err
+		}
+	}
+	snapshotsManager := snapshots.New(snapshotsStore, snapshotsPerDeployment)
+
+	// API keys are kept in the state store, only the hashes of their secrets being stored
+	var apiKeysStore store.Store = store.NewMemory()
+	if storeBackend != store.BackendMemory {
+		apiKeysStore, err = store.New(storeBackend, storeClient, storeNamespace, "go-k8s-http-api-apikeys")
+		if err != nil {
+			return err
+		}
+	}
+	authConfig.APIKeys = auth.NewAPIKeys(apiKeysStore)
+
+	// The usage of the operation quotas is kept in the state store, so that it survives restarts and leader elections
+	var quotaManager *quota.Manager
+	if quotasFile != "" {
+		var quotasStore store.Store = store.NewMemory()
+		if storeBackend != store.BackendMemory {
+			quotasStore, err = store.New(storeBackend, storeClient, storeNamespace, "go-k8s-http-api-quotas")
+			if err != nil {
+				return err
+			}
+		}
+		quotaManager, err = quota.LoadFile(quotasFile, quotasStore)
+		if err != nil {
+			return withExitCode(exitCodeUsage, err)
+		}
+	}
+
+	// Audit events are kept in the state store for the retention, so that they can be queried
+	var auditStore *audit.StoreSink
+	if auditRetention > 0 {
+		var auditEventsStore store.Store = store.NewMemory()
+		if storeBackend != store.BackendMemory {
+			auditEventsStore, err = store.New(storeBackend, storeClient, storeNamespace, "go-k8s-http-api-audit")
+			if err != nil {
+				return err
+			}
+		}
+		auditStore = audit.NewStoreSink(auditEventsStore, auditRetention)
+		auditSinks = append(auditSinks, auditStore)
+	}
+	var auditLogger *audit.Logger
+	if len(auditSinks) > 0 {
+		auditLogger = audit.NewLogger(auditSinks, auditRules, auditMaxBodyBytes)
+	}
+	// Browser sessions are issued at the end of an OIDC login, once a redirect URL is registered with the issuer
+	if authConfig.Session.RedirectURL != "" {
+		authConfig.Sessions, err = auth.NewSessions(authConfig.OIDC, authConfig.Session, &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			return withExitCode(exitCodeUsage, fmt.Errorf("failed to set up the browser sessions: %w", err))
+		}
+	}
+
+	// Every listener authenticates its clients with its own chain of authenticators, adding their identity to the
+	// request context
+	mainAuthChain, err := auth.NewChain(splitCommaSeparated(authChain), authConfig)
+	if err != nil {
+		return withExitCode(exitCodeUsage, fmt.Errorf("failed to set up the authentication chain: %w", err))
+	}
+	unixAuthChain, err := auth.NewChain(splitCommaSeparated(unixSocketAuthChain), authConfig)
+	if err != nil {
+		return withExitCode(exitCodeUsage, fmt.Errorf("failed to set up the Unix socket authentication chain: %w", err))
+	}
+	// Both listeners share the authentication failures, so that the lockouts apply to either
+	authGuard := auth.NewGuard(guardConfig)
+	mainAuthChain.Guard, unixAuthChain.Guard = authGuard, authGuard
+	if mainAuthChain.RequiresClientCertificate() {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	server.Handler = mainAuthChain.Middleware(server.Handler)
+	// The client IPs are filtered before the authentication, so that the ones which aren't allowed can't even attempt it
+	if ipFilterFile != "" {
+		ipFilter, err := ipfilter.LoadFile(ipFilterFile)
+		if err != nil {
+			return withExitCode(exitCodeUsage, fmt.Errorf("failed to load the IP filter: %w", err))
+		}
+		server.Handler = ipFilter.Middleware(server.Handler)
+	}
+	// The security headers are added to every response, including the rejections of the IP filter and authentication
+	server.Handler = securityHeaders.Middleware(server.Handler)
+
+	// Authorizes the access of client identities to the namespace scoped routes
+	var namespaceAuthorizer auth.NamespaceAuthorizer
+	if namespaceAuthorization == namespaceAuthorizationSubjectAccessReview {
+		namespaceAuthorizer = &auth.SubjectAccessReviewAuthorizer{Client: authorizationClient, TTL: time.Minute}
+	}
+
+	// Responses to mutating requests carrying an Idempotency-Key header are stored, and replayed to client retries
+	idempotencyStore := idempotency.NewStore(idempotencyTTL)
+
+	// FeaturesHandler reports which endpoints are enabled in the current environment.
+	featuresHandler := &handlers.FeaturesHandler{Gates: gates}
+	mux.HandleFunc("GET /features", loggingMiddleware(validateResponse(schema.FeaturesResponse, featuresHandler.ServeHTTP)))
 
 	// DeploymentsHandler is an HTTP handler for the deployments API.
 	// This handler uses the manager's client to interact with the Kubernetes API, in order to take advantage of the cache.
+	// Reads can bypass the cache with ?cache=false, in which case the manager's API reader is used to read directly from the API server.
+	// The API reader is also used as a fallback while the cache hasn't synced yet.
+	// The clients of the handlers attribute the time spent in their calls to cache reads or API server calls, which is
+	// reported for the slow requests. Their API server calls are bounded by the deadline of the route of their request,
+	// and guarded by the circuit breaker, if enabled.
+	var apiClient client.Client = &deadline.Client{Client: mgr.GetClient()}
+	var apiReader client.Reader = &deadline.Reader{Reader: mgr.GetAPIReader()}
+	var apiServerUnavailable func() bool
+	if apiBreaker != nil {
+		apiClient = &breaker.Client{Client: apiClient, Breaker: apiBreaker}
+		apiReader = &breaker.Reader{Reader: apiReader, Breaker: apiBreaker}
+		apiServerUnavailable = apiBreaker.Open
+	}
+	timedClient := &timing.Client{Client: apiClient, Reads: timing.PhaseCache}
+	timedLiveReader := &timing.Reader{Reader: apiReader, Phase: timing.PhaseAPIServer}
+	// The admission plugins evaluate every change made through the API before it's written. The objects they depend on
+	// aren't cached, and are read directly from the API server.
+	admissionChain, err := admission.NewChain(admissionPluginNames, admission.Options{Reader: timedLiveReader, ImagePolicy: imagePolicy, GitOpsMode: gitOpsMode, ArgoCDInstanceLabel: argoCDInstanceLabel})
+	if err != nil {
+		return withExitCode(exitCodeUsage, fmt.Errorf("failed to set up the admission plugins: %w", err))
+	}
+	// The changes made through the API are recorded as events on the changed objects, along with the chat notifications
+	var changeEvents *events.Recorder
+	if recordChangeEvents {
+		changeEvents = &events.Recorder{Recorder: mgr.GetEventRecorderFor(fieldManager), Component: fieldManager}
+	}
 	deploymentsHandler := &handlers.DeploymentsHandler{
-		Client: mgr.GetClient(),
+		Client:               timedClient,
+		LiveReader:           timedLiveReader,
+		CacheSynced:          cacheSyncTracker.Synced,
+		CacheLastSync:        cacheSyncTracker.LastSync,
+		Summaries:            deploymentSummaries,
+		APIServerUnavailable: apiServerUnavailable,
+		Operations:           operationsManager,
+		FieldManager:         fieldManager,
+		// Watches are answered before the server's write timeout cuts their connection
+		MaxLongPollTimeout: max(timeouts.write-5*time.Second, time.Second),
+		Admission:          admissionChain,
+		Notifier:           notifier,
+		Events:             changeEvents,
+		Snapshots:          snapshotsManager,
+	}
+	// ApplyHandler server-side applies manifests of the allowed kinds. Unstructured objects aren't cached by the manager's
+	// client, so they are read directly from the API server.
+	applyHandler := &handlers.ApplyHandler{
+		Client:       timedClient,
+		LiveReader:   timedLiveReader,
+		FieldManager: fieldManager,
+		AllowedKinds: allowedKinds,
+		Admission:    admissionChain,
+		Notifier:     notifier,
+		Events:       changeEvents,
+	}
+	// HibernationHandler scales the workloads of namespaces to zero and back. Statefulsets aren't cached, so the workloads
+	// are listed directly from the API server.
+	hibernationHandler := &handlers.HibernationHandler{
+		Hibernator: &hibernation.Hibernator{Client: timedClient, Reader: timedLiveReader, Admission: admissionChain, Events: changeEvents},
+		Notifier:   notifier,
+	}
+	// AppsHandler groups the objects of the applications. Only deployments are cached, so the other kinds are listed
+	// directly from the API server.
+	if errs := validation.IsQualifiedName(appLabel); len(errs) > 0 {
+		return withExitCode(exitCodeUsage, fmt.Errorf("invalid --app-label %q: %s", appLabel, strings.Join(errs, ", ")))
+	}
+	appsHandler := &handlers.AppsHandler{Client: timedClient, LiveReader: timedLiveReader, Label: appLabel}
+	// InsightsHandler explains why pods are pending. Pods aren't cached, so they're listed directly from the API server.
+	insightsHandler := &handlers.InsightsHandler{LiveReader: timedLiveReader}
+	// PodsHandler evicts pods. Pods aren't cached, so they're read directly from the API server.
+	podsHandler := &handlers.PodsHandler{Client: timedClient, LiveReader: timedLiveReader, Notifier: notifier, Events: changeEvents}
+	// The rollout detector reports the deployments whose rollout is stuck. It runs on every replica so that all of them
+	// serve the alerts, while only the leader sends the webhook notifications.
+	rolloutDetector := &rollouts.Detector{Client: mgr.GetClient(), StuckAfter: rolloutStuckThreshold, Elected: mgr.Elected()}
+	if rolloutAlertsWebhook != "" {
+		rolloutDetector.Notifier = &rollouts.WebhookNotifier{URL: rolloutAlertsWebhook, Client: &http.Client{Timeout: 10 * time.Second}}
+	}
+	if gates.Enabled(features.RolloutAlerts) {
+		if err := rolloutDetector.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("failed to set up the rollout detector: %w", err)
+		}
+	}
+	alertsHandler := &handlers.AlertsHandler{Rollouts: rolloutDetector}
+	costHandler := &handlers.CostHandler{Client: mgr.GetClient(), PriceSheet: priceSheet}
+	imagesHandler := &handlers.ImagesHandler{Client: mgr.GetClient()}
+	if imageScanURL != "" {
+		imagesHandler.Scanner = &images.Scanner{
+			URL:         imageScanURL,
+			Client:      &http.Client{Timeout: 30 * time.Second},
+			TTL:         imageScanTTL,
+			Concurrency: imageScanConcurrency,
+		}
+	}
+	// The search matches the objects of every served resource, which are all cached
+	searchHandler := &handlers.SearchHandler{Client: timedClient}
+	for name, obj := range servedObjects {
+		gvk, err := apiutil.GVKForObject(obj, mgr.GetScheme())
+		if err != nil {
+			return fmt.Errorf("failed to get the kind of %s: %w", name, err)
+		}
+		list, err := mgr.GetScheme().New(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err != nil {
+			return fmt.Errorf("failed to get the list type of %s: %w", name, err)
+		}
+		searchHandler.Kinds = append(searchHandler.Kinds, handlers.SearchKind{Kind: gvk.Kind, List: list.(client.ObjectList)})
+	}
+	// The replica bounds enforcer scales deployments back within the bounds declared via the API. It only runs on the
+	// leader, and records an event on every deployment it scales.
+	if gates.Enabled(features.ReplicaBounds) {
+		boundsEnforcer := &bounds.Enforcer{Client: mgr.GetClient(), Recorder: mgr.GetEventRecorderFor(fieldManager)}
+		if err := boundsEnforcer.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("failed to set up the replica bounds enforcer: %w", err)
+		}
+	}
+	// The gateway policies are loaded from the APIGatewayPolicy custom resources by every replica, and reloaded whenever
+	// they change
+	var gatewayPolicies *policy.Engine
+	if enableGatewayPolicies {
+		gatewayPolicies = policy.NewEngine()
+		policyReconciler := &policy.Reconciler{Client: mgr.GetClient(), Engine: gatewayPolicies}
+		if err := policyReconciler.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("failed to set up the gateway policies: %w", err)
+		}
 	}
 
-	http.HandleFunc("/deployments", loggingMiddleware(deploymentsHandler.ListDeployments))
+	// LogLevelHandler allows admins to change the log verbosity at runtime, without a restart
+	logLevelHandler := &handlers.LogLevelHandler{Verbosity: flagSet.Lookup("v").Value}
+	admins := splitCommaSeparated(adminIdentities)
+	mux.HandleFunc("GET /admin/loglevel", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.LogLevel, logLevelHandler.GetLogLevel))))
+	statsHandler := &handlers.StatsHandler{Stats: requestStats}
+	mux.HandleFunc("GET /admin/stats", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.StatsResponse, statsHandler.GetStats))))
+	mux.HandleFunc("PUT /admin/loglevel", loggingMiddleware(auth.RequireIdentity(admins, idempotency.Middleware(idempotencyStore, validateResponse(schema.LogLevel, schema.ValidateRequest(schema.LogLevel, logLevelHandler.SetLogLevel))))))
+	// SecurityEventsHandler serves the feed of the authentication failures, lockouts and suspicious successes to admins
+	securityEventsHandler := &handlers.SecurityEventsHandler{Guard: authGuard}
+	mux.HandleFunc("GET /admin/security/events", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.SecurityEvents, securityEventsHandler.GetSecurityEvents))))
+	// CaptureHandler lets admins capture the requests to a route, along with their responses
+	if captureRecorder != nil {
+		captureHandler := &handlers.CaptureHandler{Recorder: captureRecorder}
+		mux.HandleFunc("GET /admin/capture", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.CaptureSession, captureHandler.GetCapture))))
+		mux.HandleFunc("PUT /admin/capture", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.CaptureSession, schema.ValidateRequest(schema.CaptureRequest, captureHandler.StartCapture)))))
+		mux.HandleFunc("DELETE /admin/capture", loggingMiddleware(auth.RequireIdentity(admins, captureHandler.StopCapture)))
+		mux.HandleFunc("GET /admin/capture/exchanges", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.CapturedExchanges, captureHandler.ListCapturedExchanges))))
+	}
+	// FeaturesHandler lets admins switch the endpoints of a feature gate off, and back on, at runtime
+	mux.HandleFunc("GET /admin/features", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.FeatureStatuses, featuresHandler.ListFeatures))))
+	mux.HandleFunc("PUT /admin/features/{name}", loggingMiddleware(auth.RequireIdentity(admins, idempotency.Middleware(idempotencyStore, validateResponse(schema.FeatureStatus, schema.ValidateRequest(schema.FeatureSwitch, featuresHandler.SwitchFeature))))))
+	// DashboardsHandler serves the Grafana dashboards of the metrics bundled with the server
+	dashboardsHandler := &handlers.DashboardsHandler{}
+	mux.HandleFunc("GET /admin/dashboards", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.DashboardsResponse, dashboardsHandler.ListDashboards))))
+	mux.HandleFunc("GET /admin/dashboards/{name}", loggingMiddleware(auth.RequireIdentity(admins, dashboardsHandler.GetDashboard)))
+	if sloTracker != nil {
+		// SLOHandler serves the error budgets and burn rates of the service level objectives
+		sloHandler := &handlers.SLOHandler{SLOs: sloTracker}
+		mux.HandleFunc("GET /admin/slo", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.SLOs, sloHandler.ListSLOs))))
+	}
+	if quotaManager != nil {
+		// QuotasHandler serves the usage of the operation quotas, the callers' own, or all of them to the admins
+		quotasHandler := &handlers.QuotasHandler{Quotas: quotaManager}
+		mux.HandleFunc("GET /quotas", loggingMiddleware(validateResponse(schema.QuotasResponse, quotasHandler.GetQuotas)))
+		mux.HandleFunc("GET /admin/quotas", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.QuotasResponse, quotasHandler.ListQuotaUsage))))
+	}
+	// AuditHandler lets admins query the audit events kept in the state store
+	if auditStore != nil {
+		auditHandler := &handlers.AuditHandler{Events: auditStore}
+		mux.HandleFunc("GET /audit", loggingMiddleware(auth.RequireIdentity(admins, validateResponse(schema.AuditEvents, auditHandler.ListAuditEvents))))
+	}
+	// APIKeysHandler lets every identity manage its own API keys, and the admins the keys of all the identities. It's
+	// only served when a listener authenticates API keys.
+	if mainAuthChain.Has(auth.APIKeyAuthenticator) || unixAuthChain.Has(auth.APIKeyAuthenticator) {
+		apiKeysHandler := &handlers.APIKeysHandler{Keys: authConfig.APIKeys, Admins: admins}
+		mux.HandleFunc("POST /admin/apikeys", loggingMiddleware(validateResponse(schema.APIKey, schema.ValidateRequest(schema.APIKeyRequest, apiKeysHandler.CreateAPIKey))))
+		mux.HandleFunc("GET /admin/apikeys", loggingMiddleware(validateResponse(schema.APIKeysResponse, apiKeysHandler.ListAPIKeys)))
+		mux.HandleFunc("GET /admin/apikeys/{id}", loggingMiddleware(validateResponse(schema.APIKey, apiKeysHandler.GetAPIKey)))
+		mux.HandleFunc("DELETE /admin/apikeys/{id}", loggingMiddleware(apiKeysHandler.DeleteAPIKey))
+	}
+	// The login flow of the browser sessions is served to unauthenticated clients, when a listener authenticates them
+	if mainAuthChain.Has(auth.SessionAuthenticator) || unixAuthChain.Has(auth.SessionAuthenticator) {
+		mux.HandleFunc("GET "+auth.LoginPath, loggingMiddleware(authConfig.Sessions.Login))
+		mux.HandleFunc("GET "+auth.CallbackPath, loggingMiddleware(authConfig.Sessions.Callback))
+		mux.HandleFunc("POST "+auth.LogoutPath, loggingMiddleware(authConfig.Sessions.Logout))
+	}
 
-	// This is a quick and dirty way to handle the two different methods for the /deployments/{namespace}/{deployment}/replicas endpoint
-	// As we add more handlers, we may want to use a router instead of a switch statement. This does not scale well and isn't really production-ready.
-	http.HandleFunc("/deployments/", loggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			deploymentsHandler.GetDeploymentReplicas(w, r)
-		case http.MethodPut:
-			deploymentsHandler.SetDeploymentReplicas(w, r)
-		default:
-			// return a 405 Method Not Allowed
-			w.WriteHeader(http.StatusMethodNotAllowed)
+	// Endpoints are only registered if enabled by their feature gate, so disabled endpoints return a 404.
+	// Requests with a method that does not match any registered pattern get a 405 Method Not Allowed from the mux.
+	// Request bodies are validated against their JSON Schema before reaching the handlers.
+	// With tenancy enabled, clients may only access the namespaces of their tenant, and lists are filtered accordingly.
+	// Gateway policies further restrict the requests of the clients they apply to, within the scope of their tenant.
+	// Mutating requests are rejected during the change freezes, and evaluated against the OPA policy decision otherwise.
+	// Mutating requests are recorded to the audit log, including the ones denied.
+	// The requests whose API server calls hit their deadline fail with a 504, which is what gets audited.
+	scoped := func(next http.HandlerFunc) http.HandlerFunc {
+		next = routeDeadlines.Middleware(next)
+		if redactionPolicy != nil {
+			next = redaction.Middleware(redactionPolicy, next)
+		}
+		if opaClient != nil {
+			next = opaClient.Middleware(next)
+		}
+		if changeFreeze != nil {
+			next = changeFreeze.Middleware(next)
+		}
+		if gatewayPolicies != nil {
+			next = policy.Middleware(gatewayPolicies, next)
+		}
+		if tenants != nil {
+			next = tenancy.Middleware(tenants, next)
 		}
-	}))
+		if auditLogger != nil {
+			next = auditLogger.Middleware(next)
+		}
+		return next
+	}
+	// Expensive reads may be cached for the TTL of their endpoint, and successful writes invalidate the cached responses
+	// they affect
+	responseCache := responsecache.New(responseCacheMaxEntries)
+	cached := func(feature string, next http.HandlerFunc) http.HandlerFunc {
+		return responseCache.Middleware(responseCacheTTLs[feature], next)
+	}
+	// Mutating requests fail right away while the circuit breaker is open, rather than waiting for the API server to time out.
+	// Idempotent replays are still served, since they don't reach the API server.
+	failFast := func(next http.HandlerFunc) http.HandlerFunc {
+		if apiBreaker == nil {
+			return next
+		}
+		return apiBreaker.Middleware(next)
+	}
+	// Operations requiring an approval are held before reaching the API server, and replayed once approved. Operations
+	// are counted against the quotas of their identity beforehand, so that the held ones count too, but the replays don't.
+	holdForApproval := func(operation string, next http.HandlerFunc) http.HandlerFunc {
+		if approvalsManager != nil {
+			next = approvalsManager.Middleware(operation, next)
+		}
+		if quotaManager != nil {
+			next = quotaManager.Middleware(operation, next)
+		}
+		return next
+	}
+	// The operations are scoped like the deployments they target
+	operationsHandler := &handlers.OperationsHandler{Operations: operationsManager}
+	mux.HandleFunc("GET /operations/{id}", loggingMiddleware(scoped(validateResponse(schema.Operation, operationsHandler.GetOperation))))
 
-	// Unauthenticated server setup
-	healthzServer := &http.Server{
-		Addr: ":8080", // Use a different port for unauthenticated server
-		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.URL.Path == "/healthz" {
-				// Serve /healthz requests
-				healthzHandler.ServeHTTP(w, r)
-			} else {
-				// Return a 404 for all other requests
-				// TODO in a future iteration, we may want to return a redirect to the authenticated server
-				w.WriteHeader(http.StatusNotFound)
-			}
-		}),
+	listDeployments := scoped(cached(features.ListDeployments, validateResponse(schema.DeploymentsResponse, deploymentsHandler.ListDeployments)))
+	getDeployment := scoped(validateResponse(schema.DeploymentResponse, deploymentsHandler.GetDeployment))
+	getDeploymentReplicas := scoped(cached(features.GetDeploymentReplicas, validateResponse(schema.ReplicasResponse, deploymentsHandler.GetDeploymentReplicas)))
+	setDeploymentReplicas := scoped(responseCache.BustOnWrite(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, holdForApproval(approvals.OperationSetDeploymentReplicas, failFast(validateResponse(schema.ReplicasResponse, schema.ValidateRequest(schema.ReplicasRequest, deploymentsHandler.SetDeploymentReplicas))))))))
+	// Scale plans always run in the background, so their responses are the operations to poll
+	scaleDeploymentInSteps := scoped(responseCache.BustOnWrite(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, holdForApproval(approvals.OperationScaleDeploymentInSteps, failFast(validateResponse(schema.Operation, schema.ValidateRequest(schema.ScalePlanRequest, deploymentsHandler.ScaleDeploymentInSteps))))))))
+	// Diffs accept either a manifest or a simplified spec, so their request bodies are validated by the handler itself
+	// Manifests are returned as YAML by default, so their responses aren't validated against a JSON Schema
+	getDeploymentManifest := scoped(cached(features.GetDeploymentManifest, deploymentsHandler.GetDeploymentManifest))
+	getDeploymentHealth := scoped(cached(features.GetDeploymentHealth, validateResponse(schema.HealthResponse, deploymentsHandler.GetDeploymentHealth)))
+	diffDeployment := scoped(validateResponse(schema.DiffResponse, deploymentsHandler.DiffDeployment))
+	getDeploymentBounds := scoped(cached(features.ReplicaBounds, validateResponse(schema.BoundsResponse, deploymentsHandler.GetDeploymentBounds)))
+	setDeploymentBounds := scoped(responseCache.BustOnWrite(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, holdForApproval(approvals.OperationSetDeploymentBounds, failFast(validateResponse(schema.BoundsResponse, schema.ValidateRequest(schema.BoundsRequest, deploymentsHandler.SetDeploymentBounds))))))))
+	deleteDeploymentBounds := scoped(responseCache.BustOnWrite(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, holdForApproval(approvals.OperationDeleteDeploymentBounds, failFast(deploymentsHandler.DeleteDeploymentBounds))))))
+	getDeploymentOwnership := scoped(cached(features.DeploymentOwnership, validateResponse(schema.OwnershipResponse, deploymentsHandler.GetDeploymentOwnership)))
+	setDeploymentOwnership := scoped(responseCache.BustOnWrite(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, holdForApproval(approvals.OperationSetDeploymentOwnership, failFast(validateResponse(schema.OwnershipResponse, schema.ValidateRequest(schema.OwnershipRequest, deploymentsHandler.SetDeploymentOwnership))))))))
+	getDeploymentManagers := scoped(validateResponse(schema.ManagersResponse, deploymentsHandler.GetDeploymentManagers))
+	getDeploymentTopology := scoped(cached(features.DeploymentTopology, validateResponse(schema.TopologyResponse, deploymentsHandler.GetDeploymentTopology)))
+	applyManifests := scoped(responseCache.BustOnWrite(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, holdForApproval(approvals.OperationApplyManifests, failFast(validateResponse(schema.ApplyResponse, applyHandler.Apply)))))))
+	handleIfEnabled(mux, gates, features.ListDeployments, "GET /deployments", listDeployments)
+	handleIfEnabled(mux, gates, features.GetDeployment, "GET /deployments/{namespace}/{deployment}", getDeployment)
+	handleIfEnabled(mux, gates, features.GetDeploymentReplicas, "GET /deployments/{namespace}/{deployment}/replicas", getDeploymentReplicas)
+	handleIfEnabled(mux, gates, features.SetDeploymentReplicas, "PUT /deployments/{namespace}/{deployment}/replicas", setDeploymentReplicas)
+	handleIfEnabled(mux, gates, features.SetDeploymentReplicas, "POST /deployments/{namespace}/{deployment}/scale-plan", scaleDeploymentInSteps)
+	handleIfEnabled(mux, gates, features.GetDeploymentManifest, "GET /deployments/{namespace}/{deployment}/manifest", getDeploymentManifest)
+	handleIfEnabled(mux, gates, features.GetDeploymentHealth, "GET /deployments/{namespace}/{deployment}/health", getDeploymentHealth)
+	handleIfEnabled(mux, gates, features.DiffDeployment, "POST /deployments/{namespace}/{deployment}/diff", diffDeployment)
+	handleIfEnabled(mux, gates, features.ReplicaBounds, "GET /deployments/{namespace}/{deployment}/bounds", getDeploymentBounds)
+	handleIfEnabled(mux, gates, features.ReplicaBounds, "PUT /deployments/{namespace}/{deployment}/bounds", setDeploymentBounds)
+	handleIfEnabled(mux, gates, features.ReplicaBounds, "DELETE /deployments/{namespace}/{deployment}/bounds", deleteDeploymentBounds)
+	handleIfEnabled(mux, gates, features.DeploymentOwnership, "GET /deployments/{namespace}/{deployment}/ownership", getDeploymentOwnership)
+	handleIfEnabled(mux, gates, features.DeploymentOwnership, "PUT /deployments/{namespace}/{deployment}/ownership", setDeploymentOwnership)
+	handleIfEnabled(mux, gates, features.DeploymentManagers, "GET /deployments/{namespace}/{deployment}/managers", getDeploymentManagers)
+	handleIfEnabled(mux, gates, features.DeploymentTopology, "GET /topology/{namespace}/{deployment}", getDeploymentTopology)
+	createDeploymentSnapshot := scoped(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, validateResponse(schema.Snapshot, deploymentsHandler.CreateDeploymentSnapshot))))
+	listDeploymentSnapshots := scoped(validateResponse(schema.SnapshotsResponse, deploymentsHandler.ListDeploymentSnapshots))
+	restoreDeploymentSnapshot := scoped(responseCache.BustOnWrite(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, holdForApproval(approvals.OperationRestoreDeploymentSnapshot, failFast(validateResponse(schema.RestoreResponse, deploymentsHandler.RestoreDeploymentSnapshot)))))))
+	handleIfEnabled(mux, gates, features.ApplyManifests, "POST /apply", applyManifests)
+	handleIfEnabled(mux, gates, features.DeploymentSnapshots, "POST /deployments/{namespace}/{deployment}/snapshot", createDeploymentSnapshot)
+	handleIfEnabled(mux, gates, features.DeploymentSnapshots, "GET /deployments/{namespace}/{deployment}/snapshots", listDeploymentSnapshots)
+	handleIfEnabled(mux, gates, features.DeploymentSnapshots, "POST /deployments/{namespace}/{deployment}/restore/{snapshot}", restoreDeploymentSnapshot)
+	handleIfEnabled(mux, gates, features.RolloutAlerts, "GET /alerts/rollouts", scoped(cached(features.RolloutAlerts, validateResponse(schema.RolloutAlerts, alertsHandler.ListRolloutAlerts))))
+	getNamespaceCost := scoped(cached(features.CostEstimation, validateResponse(schema.CostResponse, costHandler.GetNamespaceCost)))
+	getDeploymentCost := scoped(cached(features.CostEstimation, validateResponse(schema.CostResponse, costHandler.GetDeploymentCost)))
+	handleIfEnabled(mux, gates, features.CostEstimation, "GET /cost/{namespace}", getNamespaceCost)
+	listImages := scoped(cached(features.ImageInventory, validateResponse(schema.ImagesResponse, imagesHandler.ListImages)))
+	handleIfEnabled(mux, gates, features.ImageInventory, "GET /images", listImages)
+	getSchedulingInsights := scoped(cached(features.SchedulingInsights, validateResponse(schema.SchedulingInsights, insightsHandler.GetSchedulingInsights)))
+	handleIfEnabled(mux, gates, features.SchedulingInsights, "GET /insights/scheduling", getSchedulingInsights)
+	search := scoped(cached(features.Search, validateResponse(schema.SearchResponse, searchHandler.Search)))
+	handleIfEnabled(mux, gates, features.Search, "GET /search", search)
+	listApps := scoped(cached(features.Applications, validateResponse(schema.AppsResponse, appsHandler.ListApps)))
+	getApp := scoped(cached(features.Applications, validateResponse(schema.App, appsHandler.GetApp)))
+	handleIfEnabled(mux, gates, features.Applications, "GET /apps", listApps)
+	handleIfEnabled(mux, gates, features.Applications, "GET /apps/{namespace}/{app}", getApp)
+	handleIfEnabled(mux, gates, features.CostEstimation, "GET /cost/{namespace}/{deployment}", getDeploymentCost)
+
+	if approvalsManager != nil {
+		approvalsHandler := &handlers.ApprovalsHandler{Approvals: approvalsManager}
+		mux.HandleFunc("GET /approvals", loggingMiddleware(scoped(validateResponse(schema.ApprovalsResponse, approvalsHandler.ListApprovals))))
+		mux.HandleFunc("GET /approvals/{id}", loggingMiddleware(scoped(validateResponse(schema.Approval, approvalsHandler.GetApproval))))
+		mux.HandleFunc("POST /approvals/{id}/approve", loggingMiddleware(scoped(leaderOnlyMiddleware(mgr.Elected(), failFast(validateResponse(schema.Approval, approvalsHandler.Approve))))))
 	}
 
-	// Start the controller-manager in a separate goroutine
-	go func() {
-		if err := mgr.Start(ctx); err != nil {
-			klog.Fatalf("Problem running manager: %v", err)
+	// Namespace scoped routes serve the same endpoints, restricted to the namespace of the path.
+	// Unless disabled, clients may only access the namespaces their identity is authorized for, on the resources the
+	// handler of the route acts on.
+	deployments := []auth.Resource{auth.Deployments}
+	pods := []auth.Resource{{Resource: "pods"}}
+	evictions := []auth.Resource{{Resource: "pods", Subresource: "eviction"}}
+	// The hibernated workloads are patched as a whole, since their replicas are recorded in an annotation
+	workloads := []auth.Resource{auth.Deployments, {Group: "apps", Resource: "statefulsets"}}
+	applications := []auth.Resource{auth.Deployments, {Group: "apps", Resource: "statefulsets"}, {Resource: "services"},
+		{Group: "autoscaling", Resource: "horizontalpodautoscalers"}}
+	namespaceAccess := func(verb string, resources []auth.Resource, next http.HandlerFunc) http.HandlerFunc {
+		if namespaceAuthorizer == nil {
+			return next
 		}
-	}()
+		return auth.RequireNamespaceAccess(namespaceAuthorizer, verb, resources, next)
+	}
+	handleIfEnabled(mux, gates, features.ListDeployments, "GET /namespaces/{namespace}/deployments", namespaceAccess("list", deployments, listDeployments))
+	handleIfEnabled(mux, gates, features.GetDeployment, "GET /namespaces/{namespace}/deployments/{deployment}", namespaceAccess("get", deployments, getDeployment))
+	handleIfEnabled(mux, gates, features.GetDeploymentReplicas, "GET /namespaces/{namespace}/deployments/{deployment}/replicas", namespaceAccess("get", deployments, getDeploymentReplicas))
+	handleIfEnabled(mux, gates, features.SetDeploymentReplicas, "PUT /namespaces/{namespace}/deployments/{deployment}/replicas", namespaceAccess("patch", deployments, setDeploymentReplicas))
+	handleIfEnabled(mux, gates, features.SetDeploymentReplicas, "POST /namespaces/{namespace}/deployments/{deployment}/scale-plan", namespaceAccess("patch", deployments, scaleDeploymentInSteps))
+	handleIfEnabled(mux, gates, features.GetDeploymentManifest, "GET /namespaces/{namespace}/deployments/{deployment}/manifest", namespaceAccess("get", deployments, getDeploymentManifest))
+	handleIfEnabled(mux, gates, features.GetDeploymentHealth, "GET /namespaces/{namespace}/deployments/{deployment}/health", namespaceAccess("get", deployments, getDeploymentHealth))
+	handleIfEnabled(mux, gates, features.DiffDeployment, "POST /namespaces/{namespace}/deployments/{deployment}/diff", namespaceAccess("get", deployments, diffDeployment))
+	handleIfEnabled(mux, gates, features.ReplicaBounds, "GET /namespaces/{namespace}/deployments/{deployment}/bounds", namespaceAccess("get", deployments, getDeploymentBounds))
+	handleIfEnabled(mux, gates, features.ReplicaBounds, "PUT /namespaces/{namespace}/deployments/{deployment}/bounds", namespaceAccess("patch", deployments, setDeploymentBounds))
+	handleIfEnabled(mux, gates, features.ReplicaBounds, "DELETE /namespaces/{namespace}/deployments/{deployment}/bounds", namespaceAccess("patch", deployments, deleteDeploymentBounds))
+	handleIfEnabled(mux, gates, features.DeploymentOwnership, "GET /namespaces/{namespace}/deployments/{deployment}/ownership", namespaceAccess("get", deployments, getDeploymentOwnership))
+	handleIfEnabled(mux, gates, features.DeploymentOwnership, "PUT /namespaces/{namespace}/deployments/{deployment}/ownership", namespaceAccess("patch", deployments, setDeploymentOwnership))
+	handleIfEnabled(mux, gates, features.DeploymentManagers, "GET /namespaces/{namespace}/deployments/{deployment}/managers", namespaceAccess("get", deployments, getDeploymentManagers))
+	handleIfEnabled(mux, gates, features.DeploymentTopology, "GET /namespaces/{namespace}/deployments/{deployment}/topology", namespaceAccess("get", deployments, getDeploymentTopology))
+	handleIfEnabled(mux, gates, features.ApplyManifests, "POST /namespaces/{namespace}/apply", namespaceAccess("patch", deployments, applyManifests))
+	handleIfEnabled(mux, gates, features.DeploymentSnapshots, "POST /namespaces/{namespace}/deployments/{deployment}/snapshot", namespaceAccess("patch", deployments, createDeploymentSnapshot))
+	handleIfEnabled(mux, gates, features.DeploymentSnapshots, "GET /namespaces/{namespace}/deployments/{deployment}/snapshots", namespaceAccess("get", deployments, listDeploymentSnapshots))
+	handleIfEnabled(mux, gates, features.DeploymentSnapshots, "POST /namespaces/{namespace}/deployments/{deployment}/restore/{snapshot}", namespaceAccess("patch", deployments, restoreDeploymentSnapshot))
+	// Hibernation acts on a whole namespace, so it's only served by namespace scoped routes
+	hibernateNamespace := scoped(responseCache.BustOnWrite(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, holdForApproval(approvals.OperationHibernateNamespace, failFast(validateResponse(schema.HibernationResponse, hibernationHandler.Hibernate)))))))
+	wakeNamespace := scoped(responseCache.BustOnWrite(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, holdForApproval(approvals.OperationWakeNamespace, failFast(validateResponse(schema.HibernationResponse, hibernationHandler.Wake)))))))
+	handleIfEnabled(mux, gates, features.NamespaceHibernation, "POST /namespaces/{namespace}/hibernate", namespaceAccess("patch", workloads, hibernateNamespace))
+	handleIfEnabled(mux, gates, features.NamespaceHibernation, "POST /namespaces/{namespace}/wake", namespaceAccess("patch", workloads, wakeNamespace))
+	// Pod evictions honor the PodDisruptionBudgets: the blocked ones get a 429, to be retried after its Retry-After
+	evictPod := scoped(responseCache.BustOnWrite(leaderOnlyMiddleware(mgr.Elected(), idempotency.Middleware(idempotencyStore, holdForApproval(approvals.OperationEvictPod, failFast(validateResponse(schema.EvictionResponse, podsHandler.EvictPod)))))))
+	handleIfEnabled(mux, gates, features.EvictPods, "POST /pods/{namespace}/{pod}/evict", evictPod)
+	handleIfEnabled(mux, gates, features.EvictPods, "POST /namespaces/{namespace}/pods/{pod}/evict", namespaceAccess("create", evictions, evictPod))
+	handleIfEnabled(mux, gates, features.CostEstimation, "GET /namespaces/{namespace}/cost", namespaceAccess("list", deployments, getNamespaceCost))
+	handleIfEnabled(mux, gates, features.ImageInventory, "GET /namespaces/{namespace}/images", namespaceAccess("list", deployments, listImages))
+	handleIfEnabled(mux, gates, features.SchedulingInsights, "GET /namespaces/{namespace}/insights/scheduling", namespaceAccess("list", pods, getSchedulingInsights))
+	handleIfEnabled(mux, gates, features.Search, "GET /namespaces/{namespace}/search", namespaceAccess("list", deployments, search))
+	handleIfEnabled(mux, gates, features.Applications, "GET /namespaces/{namespace}/apps", namespaceAccess("list", applications, listApps))
+	handleIfEnabled(mux, gates, features.Applications, "GET /namespaces/{namespace}/apps/{app}", namespaceAccess("get", applications, getApp))
+	handleIfEnabled(mux, gates, features.CostEstimation, "GET /namespaces/{namespace}/deployments/{deployment}/cost", namespaceAccess("get", deployments, getDeploymentCost))
+	// The versioned API serves the same routes under /v1, with the list responses wrapped in an envelope
+	mux.Handle(apiversion.Prefix+"/", apiversion.Handler(mux))
 
-	// Start the main server in a separate goroutine
-	go func() {
-		klog.Info("Starting main server...")
-		klog.V(5).Infof("TLS port: %s", port)
-		defer klog.Flush()
+	// Unauthenticated server setup. The metrics are served in the OpenMetrics format when Prometheus asks for it, which
+	// is the only format exposing the exemplars of the histograms.
+	healthzMux, err := newHealthzMux(splitCommaSeparated(healthzEndpoints), map[string]http.Handler{
+		"healthz":         healthzHandler,
+		"healthz/history": healthzHistoryHandler,
+		"readyz":          readyzHandler,
+		"livez":           &handlers.LivezHandler{},
+		"startupz":        startupzHandler,
+		"version":         &handlers.VersionHandler{KubeContext: activeContext},
+		"metrics":         promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	})
+	if err != nil {
+		return withExitCode(exitCodeUsage, fmt.Errorf("failed to set up the healthz server: %w", err))
+	}
+	healthzServer := &http.Server{
+		Addr:    net.JoinHostPort(healthzBindAddress, healthzPort), // Use a different port for unauthenticated server
+		Handler: securityHeaders.Middleware(healthzMux),
+	}
+	timeouts.apply(healthzServer)
 
-		if err := server.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
-			klog.Fatalf("Error starting main server: %v", err)
+	// Admin server setup, serving the runtime debug endpoints on a loopback-only listener
+	var adminServer *http.Server
+	if enableDebugEndpoints {
+		// Only the header timeout is applied, since profiles and traces are streamed for as long as requested
+		adminServer = &http.Server{
+			Addr:              adminAddress,
+			Handler:           debug.NewMux(),
+			ReadHeaderTimeout: timeouts.readHeader,
 		}
-	}()
+	}
 
-	// Start the unauthenticated server for the healthz API in a separate goroutine
-	go func() {
-		klog.Info("Starting healthz server...")
-		klog.V(5).Info("healthz port: 8080")
-		defer klog.Flush()
+	// The Unix domain socket is only accessible from within the pod, and its file permissions restrict access further, so
+	// TLS is not used
+	var unixServer *http.Server
+	var unixListener net.Listener
+	if unixSocket != "" {
+		unixListener, err = listenUnix(unixSocket)
+		if err != nil {
+			return withExitCode(exitCodeServer, fmt.Errorf("failed to listen on the Unix socket %s: %w", unixSocket, err))
+		}
+		unixServer = &http.Server{Handler: unixAuthChain.Middleware(apiwarnings.Middleware(timing.SlowRequests(slowRequestThreshold, apiHandler)))}
+		if unixSocketH2C {
+			unixServer.Handler = h2c.NewHandler(unixServer.Handler, http2Server)
+		}
+		timeouts.apply(unixServer)
+	}
 
-		err := healthzServer.ListenAndServe()
-		if err != nil && err != http.ErrServerClosed {
-			klog.Fatalf("Error starting healthz server: %v", err)
+	// The server stops on the interrupt / termination signal, or once the parent context is cancelled
+	phase = exitCodeServer
+	stopCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go func() {
+		select {
+		case sig := <-stopCh:
+			klog.InfoS("Received signal, shutting down...", "signal", sig)
+			stop()
+		case <-stopCtx.Done():
 		}
 	}()
 
-	// Shutdown logic
-	go func() {
-		<-ctx.Done() // Wait for shutdown signal
+	// The components are started in order, and stopped in the reverse order: the manager first, so that it's only
+	// stopped after the servers are done serving from its cache, along with its background loops and the servers of the
+	// probes and debug endpoints, which report the sync progress of the cache. The API listeners are only started once
+	// the cache has synced. The first failure of a component, e.g. a server failing to listen, shuts the server down.
+	lc := lifecycle.New(ctx)
+	// The manager only fails on errors it can't recover from, such as the leader losing its lease, while the outages of
+	// the API server are ridden out by the informers
+	lc.Go("manager", mgr.Start)
+	lc.Go("cache sync progress", lifecycle.Loop(func(ctx context.Context) { cacheSyncTracker.LogProgress(ctx, 10*time.Second) }))
+	if healthzProbeInterval > 0 {
+		lc.Go("healthz probes", lifecycle.Loop(func(ctx context.Context) { healthzHandler.RunProbes(ctx, healthzProbeInterval) }))
+	}
+	if changeFreeze != nil {
+		lc.Go("change freeze", lifecycle.Loop(changeFreeze.Run))
+	}
+	if notifier != nil {
+		lc.Go("notifier", lifecycle.Loop(notifier.Run))
+	}
+	if auditStore != nil {
+		lc.Go("audit store", lifecycle.Loop(auditStore.Run))
+	}
+	if sloTracker != nil {
+		lc.Go("SLO tracker", lifecycle.Loop(sloTracker.Run))
+	}
+	if quotaManager != nil {
+		lc.Go("quota manager", lifecycle.Loop(quotaManager.Run))
+	}
+	if caReloadInterval > 0 {
+		lc.Go("client CA reloads", lifecycle.Loop(func(ctx context.Context) { clientCAs.Run(ctx, caReloadInterval) }))
+	}
+	lc.Go("certificate expiry checks", lifecycle.Loop(func(ctx context.Context) { certExpiry.Run(ctx, certExpiryCheckInterval) }))
+	// The fake cluster has no deployment controller, so its rollouts are simulated instead
+	if mockCluster != nil && mockRolloutInterval > 0 {
+		lc.Go("mock rollouts", lifecycle.Loop(func(ctx context.Context) { mockCluster.Rollouts().Run(ctx, mockRolloutInterval) }))
+	}
+	// The audit events still queued are forwarded to the SIEMs once the servers stopped
+	for _, forwarder := range auditForwarders {
+		lc.Go("audit forwarder", lifecycle.Loop(forwarder.Run))
+	}
 
-		// Create a new context for shutdown
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer shutdownCancel()
+	klog.InfoS("Starting healthz server...")
+	klog.V(5).InfoS("healthz address", "address", healthzServer.Addr)
+	lc.Go("healthz server", lifecycle.Server("healthz", healthzServer, healthzServer.ListenAndServe, shutdownTimeout))
+	if adminServer != nil {
+		klog.InfoS("Starting admin server...")
+		klog.V(5).InfoS("admin address", "address", adminAddress)
+		lc.Go("admin server", lifecycle.Server("admin", adminServer, adminServer.ListenAndServe, shutdownTimeout))
+	}
 
-		// Shutdown the main server
-		if err := server.Shutdown(shutdownCtx); err != nil {
-			klog.Errorf("Error shutting down main server: %v", err)
+	if err := lc.WaitFor(stopCtx, "cache sync", mgr.GetCache().WaitForCacheSync); err != nil {
+		klog.V(2).InfoS("Not starting the API listeners", "reason", err.Error())
+	} else {
+		klog.InfoS("Starting main server...")
+		klog.V(5).InfoS("TLS address", "address", server.Addr)
+		lc.Go("main server", lifecycle.Server("main", server, func() error { return server.ListenAndServeTLS("", "") }, shutdownTimeout))
+		if unixServer != nil {
+			klog.InfoS("Starting Unix socket server...", "path", unixSocket)
+			lc.Go("Unix socket server", lifecycle.Server("unix", unixServer, func() error { return unixServer.Serve(unixListener) }, shutdownTimeout))
 		}
+	}
 
-		// Shutdown the healthz server
-		if err := healthzServer.Shutdown(shutdownCtx); err != nil {
-			klog.Errorf("Error shutting down healthz server: %v", err)
+	// Wait for the interrupt / termination signal, for the parent context to be cancelled, or for a component to fail
+	select {
+	case <-stopCtx.Done():
+		if ctx.Err() != nil {
+			klog.InfoS("Context cancelled, shutting down...")
 		}
-	}()
+	case <-lc.Failed():
+		klog.InfoS("A component failed, shutting down...")
+	}
+
+	// Flip readiness first, so that Kubernetes stops routing new connections to this instance
+	shutdownStart := time.Now()
+	readyzHandler.SetDraining()
+	klog.InfoS("Readiness set to failing, waiting for connections to drain", "drainPeriod", drainPeriod)
+	time.Sleep(drainPeriod)
+
+	// Then stop the servers, each reporting the requests it cut off past the shutdown timeout, the background loops and
+	// finally the manager, which returns the first failure, if any
+	failure := lc.Stop()
+	klog.InfoS("Shutdown complete", "duration", time.Since(shutdownStart), "drainPeriod", drainPeriod, "shutdownTimeout", shutdownTimeout)
 
-	return nil
+	return failure
 }
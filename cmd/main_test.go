@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/testenv"
+)
+
+// TestRun exercises the full HTTP stack of the server (TLS, authentication, routing, handlers) against a real API server
+func TestRun(t *testing.T) {
+	env := testenv.Start(t)
+	certs := testenv.NewCertificates(t)
+	port, healthzPort := testenv.FreePort(t), testenv.FreePort(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- run([]string{
+			"go-k8s-http-api",
+			"--kubeconfig", env.Kubeconfig,
+			"--server-cert", certs.ServerCert,
+			"--cert-key", certs.ServerKey,
+			"--ca-cert", certs.CACert,
+			"--bind-address", "127.0.0.1",
+			"--port", port,
+			"--healthz-bind-address", "127.0.0.1",
+			"--healthz-port", healthzPort,
+			"--shutdown-drain-period", "0s",
+			"--admin-identities", "admin",
+		}, make(chan os.Signal, 1), ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("run() error = %v", err)
+		}
+	})
+
+	err := wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, time.Minute, true, func(ctx context.Context) (bool, error) {
+		resp, err := http.Get("http://127.0.0.1:" + healthzPort + "/readyz")
+		if err != nil {
+			return false, nil
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK, nil
+	})
+	if err != nil {
+		t.Fatalf("The server didn't get ready: %v", err)
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(int32(2)),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "foo"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "foo", Image: "nginx"}}},
+			},
+		},
+	}
+	if err := env.Client.Create(ctx, deployment); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	baseURL := "https://localhost:" + port
+	alice, admin := certs.Client(t, "alice"), certs.Client(t, "admin")
+	tests := []struct {
+		name           string
+		client         *http.Client
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "scale", client: alice, method: http.MethodPut, path: "/deployments/default/foo/replicas", body: `{"replicas": 3}`, expectedStatus: http.StatusOK, expectedBody: `"replicas":3`},
+		{name: "read after write", client: alice, method: http.MethodGet, path: "/deployments/default/foo/replicas?cache=false", expectedStatus: http.StatusOK, expectedBody: `"replicas":3`},
+		{name: "namespace scoped list", client: alice, method: http.MethodGet, path: "/namespaces/default/deployments", expectedStatus: http.StatusOK, expectedBody: `"name":"foo"`},
+		{name: "invalid name", client: alice, method: http.MethodGet, path: "/deployments/default/Foo/replicas", expectedStatus: http.StatusBadRequest},
+		{name: "unknown route", client: alice, method: http.MethodGet, path: "/statefulsets", expectedStatus: http.StatusNotFound},
+		{name: "admin endpoint as a non admin", client: alice, method: http.MethodGet, path: "/admin/stats", expectedStatus: http.StatusForbidden},
+		{name: "admin endpoint as an admin", client: admin, method: http.MethodGet, path: "/admin/stats", expectedStatus: http.StatusOK, expectedBody: `"identity":"alice"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(ctx, tt.method, baseURL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			resp, err := tt.client.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s error = %v", tt.method, tt.path, err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("%s %s status = %d, want %d: %s", tt.method, tt.path, resp.StatusCode, tt.expectedStatus, body)
+			}
+			if !strings.Contains(string(body), tt.expectedBody) {
+				t.Errorf("%s %s body = %s, want it to contain %s", tt.method, tt.path, body, tt.expectedBody)
+			}
+		})
+	}
+}
+
+// TestRun_Mock exercises the full HTTP stack of the server against the fake cluster of the mock mode, seeded with fixtures
+func TestRun_Mock(t *testing.T) {
+	certs := testenv.NewCertificates(t)
+	port, healthzPort := testenv.FreePort(t), testenv.FreePort(t)
+	fixtures := t.TempDir()
+	err := os.WriteFile(filepath.Join(fixtures, "deployments.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: foo
+  template:
+    metadata:
+      labels:
+        app: foo
+    spec:
+      containers:
+      - name: foo
+        image: nginx
+`), 0o600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- run([]string{
+			"go-k8s-http-api",
+			"--mock",
+			"--mock-fixtures", fixtures,
+			"--server-cert", certs.ServerCert,
+			"--cert-key", certs.ServerKey,
+			"--ca-cert", certs.CACert,
+			"--bind-address", "127.0.0.1",
+			"--port", port,
+			"--healthz-bind-address", "127.0.0.1",
+			"--healthz-port", healthzPort,
+			"--shutdown-drain-period", "0s",
+		}, make(chan os.Signal, 1), ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("run() error = %v", err)
+		}
+	})
+
+	baseURL := "https://localhost:" + port
+	alice := certs.Client(t, "alice")
+	// The main server starts listening once ready
+	err = wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, time.Minute, true, func(ctx context.Context) (bool, error) {
+		resp, err := alice.Get(baseURL + "/deployments")
+		if err != nil {
+			return false, nil
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK, nil
+	})
+	if err != nil {
+		t.Fatalf("The server didn't get ready: %v", err)
+	}
+	tests := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{method: http.MethodGet, path: "/deployments", expectedStatus: http.StatusOK, expectedBody: `{"name":"foo","namespace":"default"}`},
+		{method: http.MethodPut, path: "/deployments/default/foo/replicas", body: `{"replicas": 3}`, expectedStatus: http.StatusOK, expectedBody: `"replicas":3`},
+		{method: http.MethodGet, path: "/deployments/default/foo/replicas?cache=false", expectedStatus: http.StatusOK, expectedBody: `"replicas":3`},
+		{method: http.MethodGet, path: "/deployments/default/bar/replicas", expectedStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequestWithContext(ctx, tt.method, baseURL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		resp, err := alice.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s error = %v", tt.method, tt.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.expectedStatus {
+			t.Errorf("%s %s status = %d, want %d: %s", tt.method, tt.path, resp.StatusCode, tt.expectedStatus, body)
+		}
+		if !strings.Contains(string(body), tt.expectedBody) {
+			t.Errorf("%s %s body = %s, want it to contain %s", tt.method, tt.path, body, tt.expectedBody)
+		}
+	}
+}
+
+// TestRun_StartupFailures checks that run returns the startup failures with the exit code of their class, instead of
+// exiting the process
+func TestRun_StartupFailures(t *testing.T) {
+	// Out of the cluster, so that the kubeconfig failing to load doesn't fall back to the in-cluster configuration
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	certs := testenv.NewCertificates(t)
+	dir := t.TempDir()
+	invalidCA := filepath.Join(dir, "invalid-ca.crt")
+	if err := os.WriteFile(invalidCA, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	// An API server rejecting the credentials of the server
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Unauthorized","code":401}`)
+	}))
+	defer apiServer.Close()
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	err := os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: `+apiServer.URL+`
+users:
+- name: test
+  user:
+    token: expired
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+`), 0o600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	tlsArgs := []string{"--server-cert", certs.ServerCert, "--cert-key", certs.ServerKey, "--ca-cert", certs.CACert}
+
+	tests := []struct {
+		name             string
+		args             []string
+		expectedExitCode int
+		expectedError    string
+	}{
+		{name: "unknown flag", args: []string{"--no-such-flag"}, expectedExitCode: exitCodeUsage, expectedError: "flag provided but not defined"},
+		{name: "invalid flag", args: []string{"--namespace-authorization", "rbac"}, expectedExitCode: exitCodeUsage, expectedError: "invalid --namespace-authorization"},
+		{name: "missing server certificate", args: []string{"--server-cert", filepath.Join(dir, "missing.crt"), "--cert-key", certs.ServerKey},
+			expectedExitCode: exitCodeTLS, expectedError: "failed to load the server certificate"},
+		{name: "invalid CA bundle", args: []string{"--server-cert", certs.ServerCert, "--cert-key", certs.ServerKey, "--ca-cert", invalidCA},
+			expectedExitCode: exitCodeTLS, expectedError: "failed to load the CA certificates"},
+		{name: "unknown kubeconfig context", args: append([]string{"--kubeconfig", kubeconfig, "--context", "prod"}, tlsArgs...),
+			expectedExitCode: exitCodeKubernetes, expectedError: `context "prod" not found`},
+		{name: "no kubeconfig out of the cluster", args: append([]string{"--kubeconfig", filepath.Join(dir, "missing")}, tlsArgs...),
+			expectedExitCode: exitCodeKubernetes, expectedError: "not running in cluster"},
+		{name: "rejected credentials", args: append([]string{"--kubeconfig", kubeconfig}, tlsArgs...),
+			expectedExitCode: exitCodeKubernetes, expectedError: "failed to verify the permissions"},
+		{name: "invalid configuration past the TLS material", args: append([]string{"--kubeconfig", kubeconfig, "--kube-api-route-timeouts", "GET /deployments"}, tlsArgs...),
+			expectedExitCode: exitCodeUsage, expectedError: "invalid --kube-api-route-timeouts"},
+		{name: "fixtures without the mock mode", args: []string{"--mock-fixtures", dir}, expectedExitCode: exitCodeUsage, expectedError: "--mock-fixtures requires --mock"},
+		{name: "invalid fixtures", args: append([]string{"--mock", "--mock-fixtures", filepath.Join(dir, "missing")}, tlsArgs...),
+			expectedExitCode: exitCodeUsage, expectedError: "failed to load the fixtures"},
+		{name: "rollouts without the mock mode", args: []string{"--mock-rollout-interval", "1s"}, expectedExitCode: exitCodeUsage, expectedError: "--mock-rollout-interval requires --mock"},
+		{name: "negative rollout interval", args: []string{"--mock", "--mock-rollout-interval", "-1s"}, expectedExitCode: exitCodeUsage, expectedError: "--mock-rollout-interval must be greater than or equal to 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := run(append([]string{"go-k8s-http-api"}, tt.args...), make(chan os.Signal, 1), context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Fatalf("run() error = %v, want it to contain %q", err, tt.expectedError)
+			}
+			if got := exitCode(err); got != tt.expectedExitCode {
+				t.Errorf("exitCode() = %d, want %d", got, tt.expectedExitCode)
+			}
+		})
+	}
+
+	// Asking for the usage isn't a failure
+	if err := run([]string{"go-k8s-http-api", "-h"}, make(chan os.Signal, 1), context.Background()); err != nil {
+		t.Errorf("run(-h) error = %v, want nil", err)
+	}
+}
+
+// TestRun_ServerFailure checks that a server failing to start shuts the others down, and is returned with the exit
+// code of the failures while running
+func TestRun_ServerFailure(t *testing.T) {
+	env := testenv.Start(t)
+	certs := testenv.NewCertificates(t)
+	// The port of the main server is taken
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	err = run([]string{
+		"go-k8s-http-api",
+		"--kubeconfig", env.Kubeconfig,
+		"--server-cert", certs.ServerCert,
+		"--cert-key", certs.ServerKey,
+		"--ca-cert", certs.CACert,
+		"--bind-address", "127.0.0.1",
+		"--port", port,
+		"--healthz-bind-address", "127.0.0.1",
+		"--healthz-port", testenv.FreePort(t),
+		"--shutdown-drain-period", "0s",
+	}, make(chan os.Signal, 1), context.Background())
+	if err == nil || !strings.Contains(err.Error(), "the main server failed") {
+		t.Fatalf("run() error = %v, want the failure of the main server", err)
+	}
+	if got := exitCode(err); got != exitCodeServer {
+		t.Errorf("exitCode() = %d, want %d", got, exitCodeServer)
+	}
+}
+
+func TestNewScheme_OperationsStore(t *testing.T) {
+	scheme, err := newScheme()
+	if err != nil {
+		t.Fatalf("newScheme() error = %v", err)
+	}
+
+	// The operations set with --operations-configmap are persisted in a ConfigMap through a client of the scheme of the
+	// manager
+	operationsStore := &store.ConfigMap{
+		Client:    fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Namespace: "default",
+		Name:      "operations",
+	}
+	ctx := context.Background()
+	if err := operationsStore.Put(ctx, "foo", []byte(`{"id":"foo"}`)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	value, found, err := operationsStore.Get(ctx, "foo")
+	if err != nil || !found || string(value) != `{"id":"foo"}` {
+		t.Errorf("Get() = %s, %v, %v, want the operation put", value, found, err)
+	}
+}
@@ -0,0 +1,79 @@
+// Load profile of the list and scale endpoints, run against the loadtest subcommand of the server:
+//
+//   go run ./cmd loadtest --deployments 10000
+//   k6 run hack/load/k6.js
+//
+// BASE_URL, DEPLOYMENTS and NAMESPACES must match the server (defaults: http://127.0.0.1:8080, 10000 and 100).
+import http from 'k6/http';
+import { check } from 'k6';
+
+const baseURL = __ENV.BASE_URL || 'http://127.0.0.1:8080';
+const deployments = parseInt(__ENV.DEPLOYMENTS || '10000');
+const namespaces = parseInt(__ENV.NAMESPACES || '100');
+
+export const options = {
+  scenarios: {
+    list: {
+      executor: 'constant-arrival-rate',
+      exec: 'list',
+      rate: 5,
+      timeUnit: '1s',
+      duration: '30s',
+      preAllocatedVUs: 10,
+    },
+    listNamespace: {
+      executor: 'constant-arrival-rate',
+      exec: 'listNamespace',
+      rate: 100,
+      timeUnit: '1s',
+      duration: '30s',
+      preAllocatedVUs: 20,
+    },
+    replicas: {
+      executor: 'constant-arrival-rate',
+      exec: 'replicas',
+      rate: 500,
+      timeUnit: '1s',
+      duration: '30s',
+      preAllocatedVUs: 50,
+    },
+    scale: {
+      executor: 'constant-arrival-rate',
+      exec: 'scale',
+      rate: 50,
+      timeUnit: '1s',
+      duration: '30s',
+      preAllocatedVUs: 20,
+    },
+  },
+  thresholds: {
+    http_req_failed: ['rate<0.01'],
+    'http_req_duration{scenario:replicas}': ['p(99)<50'],
+    'http_req_duration{scenario:scale}': ['p(99)<100'],
+  },
+};
+
+// deployment returns the path of a random deployment, named the same way as internal/loadtest does
+function deployment() {
+  const i = Math.floor(Math.random() * deployments);
+  return `/deployments/loadtest-${i % namespaces}/deployment-${i}`;
+}
+
+export function list() {
+  check(http.get(`${baseURL}/deployments`), { 'status is 200': (r) => r.status === 200 });
+}
+
+export function listNamespace() {
+  const namespace = Math.floor(Math.random() * namespaces);
+  check(http.get(`${baseURL}/namespaces/loadtest-${namespace}/deployments`), { 'status is 200': (r) => r.status === 200 });
+}
+
+export function replicas() {
+  check(http.get(`${baseURL}${deployment()}/replicas`), { 'status is 200': (r) => r.status === 200 });
+}
+
+export function scale() {
+  const body = JSON.stringify({ replicas: Math.floor(Math.random() * 10) });
+  const r = http.put(`${baseURL}${deployment()}/replicas`, body, { headers: { 'Content-Type': 'application/json' } });
+  check(r, { 'status is 200': (r) => r.status === 200 });
+}
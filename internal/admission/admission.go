@@ -0,0 +1,161 @@
+// Package admission runs the changes made through the API through a chain of plugins before they are written, in the
+// spirit of the admission controllers of the API server: the mutators may adjust the changed objects, and the
+// validators may then reject them, while the warners flag the admitted changes the client should know about. Plugins
+// are registered by name, and each of them is independently testable, so that handlers don't have to inline their own
+// checks.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Request is a change made through the API
+type Request struct {
+	// Object is the object as it will be written. For server-side applies, it's the applied configuration, which may
+	// only hold the fields managed by the client.
+	Object *unstructured.Unstructured
+	// OldObject is the live object, or nil if the change creates it
+	OldObject *unstructured.Unstructured
+	// Identity is the identity of the client making the change
+	Identity string
+	// Warnings are the warnings of the plugins about the admitted change, set by Admit
+	Warnings []string
+}
+
+// Violation is a reason a change is rejected
+type Violation struct {
+	// Plugin is the name of the plugin rejecting the change
+	Plugin string `json:"plugin"`
+	// Object is the rejected object, as Kind namespace/name
+	Object string `json:"object,omitempty"`
+	// Field is the path of the rejected field, if any
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error is returned for the changes rejected by the validators
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Mutator adjusts the changed objects in place, before they are validated
+type Mutator interface {
+	Mutate(ctx context.Context, req *Request) error
+}
+
+// Validator returns the reasons a change is rejected, or none if it's admitted. Errors are reserved to the failures to
+// evaluate the change, e.g. when reading the objects it depends on.
+type Validator interface {
+	Validate(ctx context.Context, req *Request) ([]Violation, error)
+}
+
+// Warner returns warnings about a change, which is admitted nonetheless, e.g. for the client to report them to its
+// user
+type Warner interface {
+	Warn(ctx context.Context, req *Request) ([]string, error)
+}
+
+// registeredPlugin is a plugin of the chain, along with its name
+type registeredPlugin struct {
+	name   string
+	plugin any
+}
+
+// Chain runs the changes through its plugins, in order of registration
+type Chain struct {
+	plugins []registeredPlugin
+}
+
+// Register adds the plugin to the chain, which must implement at least one of Mutator, Validator and Warner
+func (c *Chain) Register(name string, plugin any) error {
+	_, mutates := plugin.(Mutator)
+	_, validates := plugin.(Validator)
+	_, warns := plugin.(Warner)
+	if !mutates && !validates && !warns {
+		return fmt.Errorf("admission plugin %s is neither a mutator, a validator nor a warner", name)
+	}
+	c.plugins = append(c.plugins, registeredPlugin{name: name, plugin: plugin})
+	return nil
+}
+
+// Names returns the names of the plugins of the chain, in order of registration
+func (c *Chain) Names() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.plugins))
+	for _, p := range c.plugins {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// Admit runs the change through the mutators, then through the validators, and finally through the warners of
+// admitted changes, whose warnings are set on the request. It returns an *Error holding the violations of every
+// validator if the change is rejected, or the error of the first plugin failing to evaluate it. A nil Chain admits
+// every change.
+func (c *Chain) Admit(ctx context.Context, req *Request) error {
+	if c == nil {
+		return nil
+	}
+	for _, p := range c.plugins {
+		if mutator, ok := p.plugin.(Mutator); ok {
+			if err := mutator.Mutate(ctx, req); err != nil {
+				return fmt.Errorf("admission plugin %s: %w", p.name, err)
+			}
+		}
+	}
+
+	var violations []Violation
+	for _, p := range c.plugins {
+		validator, ok := p.plugin.(Validator)
+		if !ok {
+			continue
+		}
+		pluginViolations, err := validator.Validate(ctx, req)
+		if err != nil {
+			return fmt.Errorf("admission plugin %s: %w", p.name, err)
+		}
+		for _, v := range pluginViolations {
+			v.Plugin = p.name
+			if v.Object == "" {
+				v.Object = describe(req.Object)
+			}
+			violations = append(violations, v)
+		}
+	}
+	if len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+
+	for _, p := range c.plugins {
+		warner, ok := p.plugin.(Warner)
+		if !ok {
+			continue
+		}
+		warnings, err := warner.Warn(ctx, req)
+		if err != nil {
+			return fmt.Errorf("admission plugin %s: %w", p.name, err)
+		}
+		req.Warnings = append(req.Warnings, warnings...)
+	}
+	return nil
+}
+
+// describe returns the object as Kind namespace/name
+func describe(obj *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s %s", obj.GetKind(), path.Join(obj.GetNamespace(), obj.GetName()))
+}
@@ -0,0 +1,152 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// labelMutator sets a label on the changed objects
+type labelMutator struct{}
+
+func (labelMutator) Mutate(_ context.Context, req *Request) error {
+	req.Object.SetLabels(map[string]string{"team": "a"})
+	return nil
+}
+
+// labelValidator rejects the objects without a team label
+type labelValidator struct {
+	err error
+}
+
+func (v labelValidator) Validate(_ context.Context, req *Request) ([]Violation, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	if req.Object.GetLabels()["team"] == "" {
+		return []Violation{{Field: "metadata.labels", Message: "team label is required"}}, nil
+	}
+	return nil, nil
+}
+
+// teamWarner warns about the objects of the team a
+type teamWarner struct{}
+
+func (teamWarner) Warn(_ context.Context, req *Request) ([]string, error) {
+	if req.Object.GetLabels()["team"] == "a" {
+		return []string{"team a is frozen"}, nil
+	}
+	return nil, nil
+}
+
+func newRequest() *Request {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("apps/v1")
+	obj.SetKind("Deployment")
+	obj.SetNamespace("foo")
+	obj.SetName("bar")
+	return &Request{Object: obj}
+}
+
+func TestChain_Admit(t *testing.T) {
+	evaluationError := errors.New("boom")
+	tests := []struct {
+		name               string
+		plugins            map[string]any
+		order              []string
+		expectedViolations []Violation
+		expectedError      error
+	}{
+		{"Test Admit Mutates Before Validating", map[string]any{"Validator": labelValidator{}, "Mutator": labelMutator{}}, []string{"Validator", "Mutator"}, nil, nil},
+		{"Test Admit Rejects", map[string]any{"Validator": labelValidator{}}, []string{"Validator"},
+			[]Violation{{Plugin: "Validator", Object: "Deployment foo/bar", Field: "metadata.labels", Message: "team label is required"}}, nil},
+		{"Test Admit Fails", map[string]any{"Validator": labelValidator{err: evaluationError}}, []string{"Validator"}, nil, evaluationError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &Chain{}
+			for _, name := range tt.order {
+				if err := chain.Register(name, tt.plugins[name]); err != nil {
+					t.Fatalf("Register() error = %v", err)
+				}
+			}
+			err := chain.Admit(context.Background(), newRequest())
+			var rejected *Error
+			switch {
+			case tt.expectedError != nil:
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("Admit() error = %v, want %v", err, tt.expectedError)
+				}
+			case tt.expectedViolations != nil:
+				if !errors.As(err, &rejected) || !reflect.DeepEqual(rejected.Violations, tt.expectedViolations) {
+					t.Errorf("Admit() error = %#v, want violations %+v", err, tt.expectedViolations)
+				}
+			case err != nil:
+				t.Errorf("Admit() error = %v, want the change admitted", err)
+			}
+		})
+	}
+}
+
+func TestChain_Admit_Warnings(t *testing.T) {
+	chain := &Chain{}
+	for name, plugin := range map[string]any{"Mutator": labelMutator{}, "Warner": teamWarner{}} {
+		if err := chain.Register(name, plugin); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+	req := newRequest()
+	if err := chain.Admit(context.Background(), req); err != nil {
+		t.Fatalf("Admit() error = %v", err)
+	}
+	if expected := []string{"team a is frozen"}; !reflect.DeepEqual(req.Warnings, expected) {
+		t.Errorf("Admit() warnings = %q, want %q", req.Warnings, expected)
+	}
+
+	// Rejected changes aren't warned about
+	if err := chain.Register("Validator", labelValidator{err: errors.New("boom")}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	req = newRequest()
+	if err := chain.Admit(context.Background(), req); err == nil || req.Warnings != nil {
+		t.Errorf("Admit() = %v, warnings %q, want an error and no warnings", err, req.Warnings)
+	}
+}
+
+func TestChain_Register(t *testing.T) {
+	chain := &Chain{}
+	if err := chain.Register("Invalid", struct{}{}); err == nil {
+		t.Error("Register() error = nil, want an error for a plugin which is neither a mutator, a validator nor a warner")
+	}
+	var nilChain *Chain
+	if err := nilChain.Admit(context.Background(), newRequest()); err != nil {
+		t.Errorf("Admit() error = %v, want a nil chain to admit every change", err)
+	}
+}
+
+func TestNewChain(t *testing.T) {
+	chain, err := NewChain([]string{ReplicaBoundsPlugin, ResourceQuotaPlugin}, Options{})
+	if err != nil {
+		t.Fatalf("NewChain() error = %v", err)
+	}
+	if names := chain.Names(); !reflect.DeepEqual(names, []string{ReplicaBoundsPlugin, ResourceQuotaPlugin}) {
+		t.Errorf("Names() = %v, want the plugins in order", names)
+	}
+	for _, names := range [][]string{{"Unknown"}, {ImagePolicyPlugin}, {ReplicaBoundsPlugin, ReplicaBoundsPlugin}} {
+		if _, err := NewChain(names, Options{}); err == nil {
+			t.Errorf("NewChain(%v) error = nil, want an error", names)
+		}
+	}
+}
+
+func TestNewChain_GitOpsMode(t *testing.T) {
+	if _, err := NewChain([]string{GitOpsPlugin}, Options{GitOpsMode: GitOpsReject}); err != nil {
+		t.Errorf("NewChain() error = %v", err)
+	}
+	if _, err := NewChain([]string{GitOpsPlugin}, Options{GitOpsMode: "ignore"}); err == nil {
+		t.Error("NewChain() error = nil, want an error for an invalid mode")
+	}
+}
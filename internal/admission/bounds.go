@@ -0,0 +1,36 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/bounds"
+)
+
+// ReplicaBounds rejects the replica changes outside of the bounds declared in the annotations of deployments, which
+// the replica bounds controller would revert right away. Changes to the bounds themselves are admitted, and enforced
+// by the controller. Invalid bounds are reported by the controller as well.
+type ReplicaBounds struct{}
+
+// Validate implements Validator
+func (ReplicaBounds) Validate(_ context.Context, req *Request) ([]Violation, error) {
+	d, old, changed, err := replicasChange(req)
+	if err != nil || !changed {
+		return nil, err
+	}
+	// Applied configurations may leave out the annotations managed by other clients, which are kept
+	annotations := map[string]string{}
+	if old != nil {
+		maps.Copy(annotations, old.Annotations)
+	}
+	maps.Copy(annotations, d.Annotations)
+	b, err := bounds.FromAnnotations(annotations)
+	if err != nil || b.Clamp(*d.Spec.Replicas) == *d.Spec.Replicas {
+		return nil, nil
+	}
+	return []Violation{{
+		Field:   "spec.replicas",
+		Message: fmt.Sprintf("Replicas must be within the bounds %s of deployment %s in namespace %s", b.String(), d.Name, d.Namespace),
+	}}, nil
+}
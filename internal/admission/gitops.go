@@ -0,0 +1,102 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Annotations and labels GitOps tools track the objects they manage with
+const (
+	// ArgoCDTrackingIDAnnotation is set by Argo CD with the annotation based tracking, as app:group/kind:namespace/name
+	ArgoCDTrackingIDAnnotation = "argocd.argoproj.io/tracking-id"
+	FluxKustomizationNameLabel = "kustomize.toolkit.fluxcd.io/name"
+	FluxKustomizationNSLabel   = "kustomize.toolkit.fluxcd.io/namespace"
+	FluxHelmReleaseNameLabel   = "helm.toolkit.fluxcd.io/name"
+	FluxHelmReleaseNSLabel     = "helm.toolkit.fluxcd.io/namespace"
+	// FluxReconcileAnnotation disables the reconciliation of the object by Flux when set to disabled
+	FluxReconcileAnnotation = "kustomize.toolkit.fluxcd.io/reconcile"
+	// FluxSSAAnnotation keeps Flux from overriding the changes made to the object when set to Ignore or IfNotPresent
+	FluxSSAAnnotation = "kustomize.toolkit.fluxcd.io/ssa"
+)
+
+// Modes of the GitOps plugin
+const (
+	GitOpsWarn   = "warn"
+	GitOpsReject = "reject"
+)
+
+// GitOps flags the changes of the objects managed by a GitOps tool (Argo CD or Flux), which are reverted on its next
+// sync, with a warning or a violation depending on its mode. Objects whose changes Flux was told to ignore aren't
+// flagged.
+type GitOps struct {
+	// Reject rejects the changes rather than warning about them
+	Reject bool
+	// ArgoCDInstanceLabel is the label of the objects managed by Argo CD with the label based tracking (its default
+	// before Argo CD 3), e.g. app.kubernetes.io/instance. The label isn't considered when empty, since Helm sets the
+	// app.kubernetes.io/instance label as well.
+	ArgoCDInstanceLabel string
+}
+
+// Validate rejects the changes of the objects managed by a GitOps tool, in reject mode
+func (p *GitOps) Validate(_ context.Context, req *Request) ([]Violation, error) {
+	if !p.Reject {
+		return nil, nil
+	}
+	if message := p.message(req); message != "" {
+		return []Violation{{Message: message}}, nil
+	}
+	return nil, nil
+}
+
+// Warn warns about the changes of the objects managed by a GitOps tool, in warn mode
+func (p *GitOps) Warn(_ context.Context, req *Request) ([]string, error) {
+	if p.Reject {
+		return nil, nil
+	}
+	if message := p.message(req); message != "" {
+		return []string{message}, nil
+	}
+	return nil, nil
+}
+
+// message returns why the change will be reverted, or an empty string if it won't be
+func (p *GitOps) message(req *Request) string {
+	manager := p.managedBy(req.OldObject)
+	if manager == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s is managed by %s, which may revert this change on its next sync unless it's also made in Git",
+		describe(req.OldObject), manager)
+}
+
+// managedBy returns the GitOps tool managing the live object, or an empty string if it isn't managed by any
+func (p *GitOps) managedBy(obj *unstructured.Unstructured) string {
+	if obj == nil {
+		return ""
+	}
+	annotations, labels := obj.GetAnnotations(), obj.GetLabels()
+	if trackingID := annotations[ArgoCDTrackingIDAnnotation]; trackingID != "" {
+		app, _, _ := strings.Cut(trackingID, ":")
+		return fmt.Sprintf("the Argo CD application %s", app)
+	}
+	if p.ArgoCDInstanceLabel != "" && labels[p.ArgoCDInstanceLabel] != "" {
+		return fmt.Sprintf("the Argo CD application %s", labels[p.ArgoCDInstanceLabel])
+	}
+	switch annotations[FluxSSAAnnotation] {
+	case "Ignore", "IfNotPresent":
+		return ""
+	}
+	if annotations[FluxReconcileAnnotation] == "disabled" {
+		return ""
+	}
+	if name := labels[FluxKustomizationNameLabel]; name != "" {
+		return fmt.Sprintf("the Flux Kustomization %s/%s", labels[FluxKustomizationNSLabel], name)
+	}
+	if name := labels[FluxHelmReleaseNameLabel]; name != "" {
+		return fmt.Sprintf("the Flux HelmRelease %s/%s", labels[FluxHelmReleaseNSLabel], name)
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HorizontalPodAutoscaler rejects the replica changes of the deployments scaled by a HorizontalPodAutoscaler, which
+// would override them right away
+type HorizontalPodAutoscaler struct {
+	Reader client.Reader
+}
+
+// Validate implements Validator
+func (p *HorizontalPodAutoscaler) Validate(ctx context.Context, req *Request) ([]Violation, error) {
+	d, _, changed, err := replicasChange(req)
+	if err != nil || !changed {
+		return nil, err
+	}
+	var hpas autoscalingv2.HorizontalPodAutoscalerList
+	if err := p.Reader.List(ctx, &hpas, client.InNamespace(d.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list the HorizontalPodAutoscalers of namespace %s: %w", d.Namespace, err)
+	}
+	var violations []Violation
+	for _, hpa := range hpas.Items {
+		target := hpa.Spec.ScaleTargetRef
+		gv, err := schema.ParseGroupVersion(target.APIVersion)
+		if err != nil || gv.Group != appsv1.GroupName || target.Kind != "Deployment" || target.Name != d.Name {
+			continue
+		}
+		violations = append(violations, Violation{
+			Field: "spec.replicas",
+			Message: fmt.Sprintf("Deployment %s in namespace %s is scaled by HorizontalPodAutoscaler %s, change its minReplicas and maxReplicas instead",
+				d.Name, d.Namespace, hpa.Name),
+		})
+	}
+	return violations, nil
+}
@@ -0,0 +1,34 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/imagepolicy"
+)
+
+// ImagePolicy rejects the images of the changed objects which don't comply with the image policy. The images left
+// unchanged are admitted, so that objects deployed before the policy was introduced can still be scaled.
+type ImagePolicy struct {
+	Policy *imagepolicy.Policy
+}
+
+// Validate implements Validator
+func (p *ImagePolicy) Validate(_ context.Context, req *Request) ([]Violation, error) {
+	previous := map[string]string{}
+	if req.OldObject != nil {
+		for _, c := range imagepolicy.Containers(req.OldObject) {
+			previous[c.Name] = c.Image
+		}
+	}
+	var violations []Violation
+	for _, c := range imagepolicy.Containers(req.Object) {
+		if image, ok := previous[c.Name]; ok && image == c.Image {
+			continue
+		}
+		for _, reason := range p.Policy.Check(c.Image) {
+			violations = append(violations, Violation{Message: fmt.Sprintf("image %s of container %s: %s", c.Image, c.Name, reason)})
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,122 @@
+package admission
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/imagepolicy"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/rbaccheck"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names of the admission plugins, enabled via the --admission-plugins flag
+const (
+	ReplicaBoundsPlugin           = "ReplicaBounds"
+	HorizontalPodAutoscalerPlugin = "HorizontalPodAutoscaler"
+	ResourceQuotaPlugin           = "ResourceQuota"
+	ImagePolicyPlugin             = "ImagePolicy"
+	GitOpsPlugin                  = "GitOps"
+)
+
+// Plugins are the names of the available plugins
+var Plugins = []string{ReplicaBoundsPlugin, HorizontalPodAutoscalerPlugin, ResourceQuotaPlugin, ImagePolicyPlugin, GitOpsPlugin}
+
+// Options are the dependencies of the plugins
+type Options struct {
+	// Reader reads the HorizontalPodAutoscalers and ResourceQuotas, which aren't cached
+	Reader client.Reader
+	// ImagePolicy is the policy of the ImagePolicy plugin
+	ImagePolicy *imagepolicy.Policy
+	// GitOpsMode is the mode of the GitOps plugin, warn or reject. Defaults to warn.
+	GitOpsMode string
+	// ArgoCDInstanceLabel is the label of the objects managed by Argo CD with the label based tracking, if any
+	ArgoCDInstanceLabel string
+}
+
+// NewChain returns a chain of the named plugins, in the given order
+func NewChain(names []string, opts Options) (*Chain, error) {
+	chain := &Chain{}
+	for _, name := range names {
+		var plugin any
+		switch name {
+		case ReplicaBoundsPlugin:
+			plugin = ReplicaBounds{}
+		case HorizontalPodAutoscalerPlugin:
+			plugin = &HorizontalPodAutoscaler{Reader: opts.Reader}
+		case ResourceQuotaPlugin:
+			plugin = &ResourceQuota{Reader: opts.Reader}
+		case ImagePolicyPlugin:
+			if opts.ImagePolicy == nil {
+				return nil, fmt.Errorf("admission plugin %s requires an image policy", name)
+			}
+			plugin = &ImagePolicy{Policy: opts.ImagePolicy}
+		case GitOpsPlugin:
+			if opts.GitOpsMode != "" && opts.GitOpsMode != GitOpsWarn && opts.GitOpsMode != GitOpsReject {
+				return nil, fmt.Errorf("invalid mode %q of admission plugin %s, must be %s or %s", opts.GitOpsMode, name, GitOpsWarn, GitOpsReject)
+			}
+			plugin = &GitOps{Reject: opts.GitOpsMode == GitOpsReject, ArgoCDInstanceLabel: opts.ArgoCDInstanceLabel}
+		default:
+			return nil, fmt.Errorf("unknown admission plugin %q, must be one of %v", name, Plugins)
+		}
+		if slices.Contains(chain.Names(), name) {
+			return nil, fmt.Errorf("admission plugin %s is enabled more than once", name)
+		}
+		if err := chain.Register(name, plugin); err != nil {
+			return nil, err
+		}
+	}
+	return chain, nil
+}
+
+// Permissions returns the permissions needed by the named plugins, on top of the ones of the endpoints
+func Permissions(names []string) []rbaccheck.Permission {
+	var permissions []rbaccheck.Permission
+	for _, name := range names {
+		switch name {
+		case HorizontalPodAutoscalerPlugin:
+			permissions = append(permissions, rbaccheck.Permission{Verb: "list", Group: "autoscaling", Resource: "horizontalpodautoscalers"})
+		case ResourceQuotaPlugin:
+			permissions = append(permissions, rbaccheck.Permission{Verb: "list", Resource: "resourcequotas"})
+		}
+	}
+	return permissions
+}
+
+// deployment returns the typed form of the unstructured object, or nil if it isn't a deployment
+func deployment(obj *unstructured.Unstructured) (*appsv1.Deployment, error) {
+	if obj == nil || obj.GroupVersionKind().GroupKind() != appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind() {
+		return nil, nil
+	}
+	d := &appsv1.Deployment{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, d); err != nil {
+		return nil, fmt.Errorf("failed to convert deployment %s: %w", obj.GetName(), err)
+	}
+	return d, nil
+}
+
+// replicasChange returns the new replicas of the deployment, and whether the change sets them to a different value
+func replicasChange(req *Request) (*appsv1.Deployment, *appsv1.Deployment, bool, error) {
+	d, err := deployment(req.Object)
+	if err != nil || d == nil || d.Spec.Replicas == nil {
+		return nil, nil, false, err
+	}
+	old, err := deployment(req.OldObject)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return d, old, old == nil || oldReplicas(old) != *d.Spec.Replicas, nil
+}
+
+// oldReplicas returns the replicas of the live deployment, which defaults to 1
+func oldReplicas(old *appsv1.Deployment) int32 {
+	if old == nil {
+		return 0
+	}
+	if old.Spec.Replicas == nil {
+		return 1
+	}
+	return *old.Spec.Replicas
+}
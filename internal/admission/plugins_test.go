@@ -0,0 +1,245 @@
+package admission
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/bounds"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/imagepolicy"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newDeployment returns a deployment with the given replicas, annotations and image, in its unstructured form
+func newDeployment(t *testing.T, replicas *int32, annotations map[string]string, image string) *unstructured.Unstructured {
+	t.Helper()
+	d := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo", Annotations: annotations},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name:  "app",
+				Image: image,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("256Mi")},
+				},
+			}}}},
+		},
+	}
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(d)
+	if err != nil {
+		t.Fatalf("failed to convert deployment: %v", err)
+	}
+	return &unstructured.Unstructured{Object: object}
+}
+
+func messages(violations []Violation) []string {
+	var result []string
+	for _, v := range violations {
+		result = append(result, v.Message)
+	}
+	return result
+}
+
+func TestReplicaBounds_Validate(t *testing.T) {
+	withBounds := map[string]string{bounds.MinReplicasAnnotation: "2", bounds.MaxReplicasAnnotation: "5"}
+	tests := []struct {
+		name      string
+		object    *unstructured.Unstructured
+		oldObject *unstructured.Unstructured
+		expected  []string
+	}{
+		{"Test ReplicaBounds Within", newDeployment(t, ptr.To(int32(4)), withBounds, "app:v1"), newDeployment(t, ptr.To(int32(3)), withBounds, "app:v1"), nil},
+		{"Test ReplicaBounds Outside", newDeployment(t, ptr.To(int32(6)), withBounds, "app:v1"), newDeployment(t, ptr.To(int32(3)), withBounds, "app:v1"),
+			[]string{"Replicas must be within the bounds [2, 5] of deployment bar in namespace foo"}},
+		{"Test ReplicaBounds Outside Of Live Bounds", newDeployment(t, ptr.To(int32(6)), nil, "app:v1"), newDeployment(t, ptr.To(int32(3)), withBounds, "app:v1"),
+			[]string{"Replicas must be within the bounds [2, 5] of deployment bar in namespace foo"}},
+		{"Test ReplicaBounds Unchanged Replicas", newDeployment(t, ptr.To(int32(6)), withBounds, "app:v1"), newDeployment(t, ptr.To(int32(6)), nil, "app:v1"), nil},
+		{"Test ReplicaBounds Replicas Left Out", newDeployment(t, nil, withBounds, "app:v1"), newDeployment(t, ptr.To(int32(6)), nil, "app:v1"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := ReplicaBounds{}.Validate(context.Background(), &Request{Object: tt.object, OldObject: tt.oldObject})
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := messages(violations); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Validate() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHorizontalPodAutoscaler_Validate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = autoscalingv2.AddToScheme(scheme)
+	hpa := func(name, kind, target string) *autoscalingv2.HorizontalPodAutoscaler {
+		return &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "foo"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: kind, Name: target},
+				MaxReplicas:    10,
+			},
+		}
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hpa("bar", "Deployment", "bar"), hpa("baz", "Deployment", "baz"), hpa("qux", "StatefulSet", "bar")).Build()
+	p := &HorizontalPodAutoscaler{Reader: reader}
+
+	violations, err := p.Validate(context.Background(), &Request{Object: newDeployment(t, ptr.To(int32(4)), nil, "app:v1"), OldObject: newDeployment(t, ptr.To(int32(3)), nil, "app:v1")})
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	expected := []string{"Deployment bar in namespace foo is scaled by HorizontalPodAutoscaler bar, change its minReplicas and maxReplicas instead"}
+	if got := messages(violations); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Validate() = %q, want %q", got, expected)
+	}
+
+	violations, err = p.Validate(context.Background(), &Request{Object: newDeployment(t, ptr.To(int32(3)), nil, "app:v2"), OldObject: newDeployment(t, ptr.To(int32(3)), nil, "app:v1")})
+	if err != nil || violations != nil {
+		t.Errorf("Validate() = %v, %v, want changes leaving the replicas unchanged admitted", violations, err)
+	}
+}
+
+func TestResourceQuota_Validate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "foo"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4"), corev1.ResourcePods: resource.MustParse("10")},
+			Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("3"), corev1.ResourcePods: resource.MustParse("6")},
+		},
+	}
+	p := &ResourceQuota{Reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(quota).Build()}
+
+	tests := []struct {
+		name     string
+		replicas int32
+		expected []string
+	}{
+		{"Test ResourceQuota Within", 5, nil},
+		{"Test ResourceQuota Exceeded", 6, []string{"3 additional pods would exceed the requests.cpu quota of ResourceQuota compute: 1500m requested on top of 3 used, out of 4"}},
+		{"Test ResourceQuota Scale Down", 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Request{Object: newDeployment(t, ptr.To(tt.replicas), nil, "app:v1"), OldObject: newDeployment(t, ptr.To(int32(3)), nil, "app:v1")}
+			violations, err := p.Validate(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := messages(violations); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Validate() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPodResources(t *testing.T) {
+	spec := &corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			{Name: "proxy", RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways), Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			}},
+			{Name: "migrate", Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			}},
+		},
+		Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("1Gi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+		}}},
+	}
+	resources := podResources(spec)
+	for name, expected := range map[corev1.ResourceName]string{
+		corev1.ResourceRequestsCPU:    "2100m",
+		corev1.ResourceRequestsMemory: "1Gi",
+		corev1.ResourceLimitsMemory:   "2Gi",
+		corev1.ResourcePods:           "1",
+	} {
+		quantity := resources[name]
+		if quantity.Cmp(resource.MustParse(expected)) != 0 {
+			t.Errorf("podResources()[%s] = %s, want %s", name, quantity.String(), expected)
+		}
+	}
+}
+
+func TestImagePolicy_Validate(t *testing.T) {
+	policy, err := imagepolicy.New(imagepolicy.Config{DenyLatest: true})
+	if err != nil {
+		t.Fatalf("imagepolicy.New() error = %v", err)
+	}
+	p := &ImagePolicy{Policy: policy}
+
+	violations, err := p.Validate(context.Background(), &Request{Object: newDeployment(t, ptr.To(int32(3)), nil, "app"), OldObject: newDeployment(t, ptr.To(int32(3)), nil, "app:v1")})
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	expected := []string{"image app of container app: the latest tag is not allowed, images must be pinned to a version"}
+	if got := messages(violations); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Validate() = %q, want %q", got, expected)
+	}
+
+	// Images deployed before the policy was introduced are admitted as long as they are left unchanged
+	violations, err = p.Validate(context.Background(), &Request{Object: newDeployment(t, ptr.To(int32(5)), nil, "app"), OldObject: newDeployment(t, ptr.To(int32(3)), nil, "app")})
+	if err != nil || violations != nil {
+		t.Errorf("Validate() = %v, %v, want unchanged images admitted", violations, err)
+	}
+}
+
+func TestGitOps(t *testing.T) {
+	withLabels := func(labels map[string]string, annotations map[string]string) *unstructured.Unstructured {
+		obj := newDeployment(t, ptr.To(int32(3)), annotations, "app:v1")
+		obj.SetLabels(labels)
+		return obj
+	}
+	fluxLabels := map[string]string{FluxKustomizationNameLabel: "apps", FluxKustomizationNSLabel: "flux-system"}
+	tests := []struct {
+		name      string
+		plugin    *GitOps
+		oldObject *unstructured.Unstructured
+		expected  []string
+	}{
+		{"Test GitOps Unmanaged", &GitOps{}, withLabels(nil, nil), nil},
+		{"Test GitOps Created", &GitOps{}, nil, nil},
+		{"Test GitOps Argo CD Tracking ID", &GitOps{}, withLabels(nil, map[string]string{ArgoCDTrackingIDAnnotation: "shop:apps/Deployment:foo/bar"}),
+			[]string{"Deployment foo/bar is managed by the Argo CD application shop, which may revert this change on its next sync unless it's also made in Git"}},
+		{"Test GitOps Argo CD Instance Label", &GitOps{ArgoCDInstanceLabel: "app.kubernetes.io/instance"}, withLabels(map[string]string{"app.kubernetes.io/instance": "shop"}, nil),
+			[]string{"Deployment foo/bar is managed by the Argo CD application shop, which may revert this change on its next sync unless it's also made in Git"}},
+		{"Test GitOps Argo CD Instance Label Disabled", &GitOps{}, withLabels(map[string]string{"app.kubernetes.io/instance": "shop"}, nil), nil},
+		{"Test GitOps Flux Kustomization", &GitOps{}, withLabels(fluxLabels, nil),
+			[]string{"Deployment foo/bar is managed by the Flux Kustomization flux-system/apps, which may revert this change on its next sync unless it's also made in Git"}},
+		{"Test GitOps Flux HelmRelease", &GitOps{}, withLabels(map[string]string{FluxHelmReleaseNameLabel: "shop", FluxHelmReleaseNSLabel: "foo"}, nil),
+			[]string{"Deployment foo/bar is managed by the Flux HelmRelease foo/shop, which may revert this change on its next sync unless it's also made in Git"}},
+		{"Test GitOps Flux Ignored", &GitOps{}, withLabels(fluxLabels, map[string]string{FluxSSAAnnotation: "Ignore"}), nil},
+		{"Test GitOps Flux Reconcile Disabled", &GitOps{}, withLabels(fluxLabels, map[string]string{FluxReconcileAnnotation: "disabled"}), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Request{Object: newDeployment(t, ptr.To(int32(5)), nil, "app:v1"), OldObject: tt.oldObject}
+			warnings, err := tt.plugin.Warn(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Warn() error = %v", err)
+			}
+			if !reflect.DeepEqual(warnings, tt.expected) {
+				t.Errorf("Warn() = %q, want %q", warnings, tt.expected)
+			}
+			violations, err := (&GitOps{Reject: true, ArgoCDInstanceLabel: tt.plugin.ArgoCDInstanceLabel}).Validate(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := messages(violations); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Validate() = %q, want %q in reject mode", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResourceQuota rejects the replica changes of the deployments whose additional pods would exceed the ResourceQuotas
+// of their namespace, which would otherwise only surface as pods failing to be created. The surge pods of rolling
+// updates aren't accounted for.
+type ResourceQuota struct {
+	Reader client.Reader
+}
+
+// Validate implements Validator
+func (p *ResourceQuota) Validate(ctx context.Context, req *Request) ([]Violation, error) {
+	d, old, changed, err := replicasChange(req)
+	if err != nil || !changed {
+		return nil, err
+	}
+	additional := int64(*d.Spec.Replicas - oldReplicas(old))
+	if additional <= 0 {
+		return nil, nil
+	}
+	// Applied configurations may leave out the pod template, which is then unchanged
+	spec := &d.Spec.Template.Spec
+	if len(spec.Containers) == 0 && old != nil {
+		spec = &old.Spec.Template.Spec
+	}
+	requested := podResources(spec)
+
+	var quotas corev1.ResourceQuotaList
+	if err := p.Reader.List(ctx, &quotas, client.InNamespace(d.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list the ResourceQuotas of namespace %s: %w", d.Namespace, err)
+	}
+	var violations []Violation
+	for _, quota := range quotas.Items {
+		hard := quota.Status.Hard
+		if hard == nil {
+			hard = quota.Spec.Hard
+		}
+		names := make([]corev1.ResourceName, 0, len(hard))
+		for name := range hard {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			perPod, ok := requested[name]
+			if !ok || perPod.IsZero() {
+				continue
+			}
+			limit, used := hard[name], quota.Status.Used[name]
+			extra := resource.NewMilliQuantity(perPod.MilliValue()*additional, perPod.Format)
+			total := extra.DeepCopy()
+			total.Add(used)
+			if total.Cmp(limit) > 0 {
+				violations = append(violations, Violation{
+					Field: "spec.replicas",
+					Message: fmt.Sprintf("%d additional pods would exceed the %s quota of ResourceQuota %s: %s requested on top of %s used, out of %s",
+						additional, name, quota.Name, extra.String(), used.String(), limit.String()),
+				})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// podResources returns the resources of a pod accounted by ResourceQuotas: the containers and sidecars (restartable
+// init containers) run together, while the other init containers run one at a time before them
+func podResources(spec *corev1.PodSpec) corev1.ResourceList {
+	requests := podTotal(spec, func(c *corev1.Container) corev1.ResourceList { return c.Resources.Requests })
+	limits := podTotal(spec, func(c *corev1.Container) corev1.ResourceList { return c.Resources.Limits })
+	return corev1.ResourceList{
+		corev1.ResourcePods:           *resource.NewQuantity(1, resource.DecimalSI),
+		corev1.ResourceCPU:            requests[corev1.ResourceCPU],
+		corev1.ResourceMemory:         requests[corev1.ResourceMemory],
+		corev1.ResourceRequestsCPU:    requests[corev1.ResourceCPU],
+		corev1.ResourceRequestsMemory: requests[corev1.ResourceMemory],
+		corev1.ResourceLimitsCPU:      limits[corev1.ResourceCPU],
+		corev1.ResourceLimitsMemory:   limits[corev1.ResourceMemory],
+	}
+}
+
+// podTotal returns the CPU and memory of a pod, out of the resources of its containers
+func podTotal(spec *corev1.PodSpec, resources func(*corev1.Container) corev1.ResourceList) corev1.ResourceList {
+	total, sidecars := corev1.ResourceList{}, corev1.ResourceList{}
+	for i := range spec.InitContainers {
+		container := &spec.InitContainers[i]
+		if container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			addResources(sidecars, resources(container))
+			continue
+		}
+		// Init containers run after the sidecars declared before them were started
+		running := sidecars.DeepCopy()
+		addResources(running, resources(container))
+		maxResources(total, running)
+	}
+	running := sidecars.DeepCopy()
+	for i := range spec.Containers {
+		addResources(running, resources(&spec.Containers[i]))
+	}
+	maxResources(total, running)
+	addResources(total, spec.Overhead)
+	return total
+}
+
+// addResources adds the CPU and memory of the other list to the list
+func addResources(list, other corev1.ResourceList) {
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if quantity, ok := other[name]; ok {
+			sum := list[name]
+			sum.Add(quantity)
+			list[name] = sum
+		}
+	}
+}
+
+// maxResources sets the CPU and memory of the list to the maximum of the list and the other one
+func maxResources(list, other corev1.ResourceList) {
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if quantity, ok := other[name]; ok {
+			if current, ok := list[name]; !ok || quantity.Cmp(current) > 0 {
+				list[name] = quantity.DeepCopy()
+			}
+		}
+	}
+}
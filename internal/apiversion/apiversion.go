@@ -0,0 +1,55 @@
+// Package apiversion serves the versioned API under /v1. The versioned routes are the same as the unversioned ones, and
+// are dispatched to them with the version stripped from the path, so that the policies, audit log and per route
+// settings keyed by path or pattern apply alike. The version is carried in the context of the request instead, for the
+// handlers whose responses differ between versions (e.g. the list responses, which are wrapped in an envelope).
+package apiversion
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// V1 is the current version of the API
+const V1 = "v1"
+
+// Prefix is the path prefix of the routes of the current version of the API
+const Prefix = "/" + V1
+
+type versionKey struct{}
+
+// WithVersion returns a copy of the context of a request made to the given version of the API
+func WithVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+// Version returns the version of the API the request of the context was made to, or an empty string for the
+// unversioned routes
+func Version(ctx context.Context) string {
+	version, _ := ctx.Value(versionKey{}).(string)
+	return version
+}
+
+// Handler returns a new http.Handler serving the requests made to the Prefix of the current version with the routes of
+// the provided mux, where it's expected to be registered as Prefix + "/". The pattern of the matched route is set on
+// the original request, as the mux does, so that the requests are recorded under their route.
+func Handler(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, Prefix)
+		// The version can't be repeated
+		if path == r.URL.Path || path == Prefix || strings.HasPrefix(path, Prefix+"/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		r2 := r.WithContext(WithVersion(r.Context(), V1))
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = path
+		r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, Prefix)
+		r2.RequestURI = r2.URL.RequestURI()
+		mux.ServeHTTP(w, r2)
+		r.Pattern = r2.Pattern
+	})
+}
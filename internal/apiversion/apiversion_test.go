@@ -0,0 +1,54 @@
+package apiversion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /deployments/{namespace}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(Version(r.Context()) + " " + r.URL.Path + " " + r.PathValue("namespace")))
+	})
+	mux.Handle(Prefix+"/", Handler(mux))
+
+	tests := []struct {
+		name            string
+		path            string
+		expectedStatus  int
+		expectedBody    string
+		expectedPattern string
+	}{
+		{"Test Versioned", "/v1/deployments/foo", http.StatusOK, "v1 /deployments/foo foo", "GET /deployments/{namespace}"},
+		{"Test Unversioned", "/deployments/foo", http.StatusOK, " /deployments/foo foo", "GET /deployments/{namespace}"},
+		{"Test Unknown Route", "/v1/statefulsets/foo", http.StatusNotFound, "404 page not found\n", ""},
+		{"Test Repeated Version", "/v1/v1/deployments/foo", http.StatusNotFound, "404 page not found\n", "/v1/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tt.path, nil)
+			mux.ServeHTTP(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if w.Body.String() != tt.expectedBody {
+				t.Errorf("response body = %q, want %q", w.Body.String(), tt.expectedBody)
+			}
+			if r.Pattern != tt.expectedPattern {
+				t.Errorf("pattern = %q, want %q", r.Pattern, tt.expectedPattern)
+			}
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	if version := Version(context.Background()); version != "" {
+		t.Errorf("Version() = %q, want no version", version)
+	}
+	if version := Version(WithVersion(context.Background(), V1)); version != V1 {
+		t.Errorf("Version() = %q, want %q", version, V1)
+	}
+}
@@ -0,0 +1,118 @@
+// Package apiwarnings forwards the warnings returned by the API server, e.g. deprecation notices or the warnings of
+// validating admission policies, to the clients of the API. The warnings of the calls made while serving a request
+// are collected from the Warning headers of their responses, and added as Warning headers to the response of the
+// request, so that automation learns about the deprecated fields it's sending.
+package apiwarnings
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/klog/v2"
+)
+
+// collector holds the warnings of the API server calls made while serving a request
+type collector struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+type collectorKey struct{}
+
+// WithCollector returns a copy of the context collecting the warnings of the API server calls made with it
+func WithCollector(ctx context.Context) context.Context {
+	return context.WithValue(ctx, collectorKey{}, &collector{})
+}
+
+// Collected returns the warnings of the API server calls made with the context so far, in order. It returns nil when
+// the context doesn't collect warnings.
+func Collected(ctx context.Context) []string {
+	c, _ := ctx.Value(collectorKey{}).(*collector)
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.warnings)
+}
+
+// WrapTransport wraps the transport of the clients of the API server, collecting the warnings of the responses to the
+// requests whose context collects them. It's meant to be passed to rest.Config.Wrap.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &transport{next: rt}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	c, _ := req.Context().Value(collectorKey{}).(*collector)
+	if c == nil {
+		return resp, nil
+	}
+	headers, errs := utilnet.ParseWarningHeaders(resp.Header.Values("Warning"))
+	for _, err := range errs {
+		klog.FromContext(req.Context()).V(4).Info("Ignoring invalid warning header of the API server", "err", err)
+	}
+	if len(headers) > 0 {
+		c.mu.Lock()
+		for _, header := range headers {
+			c.warnings = append(c.warnings, header.Text)
+		}
+		c.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// Middleware returns a new http.Handler collecting the warnings of the API server calls made by the provided handler,
+// and adding them as Warning headers to its response. The warnings of the calls made once the response headers are
+// written (e.g. by watches) can't be forwarded, and are dropped.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithCollector(r.Context())
+		next.ServeHTTP(&writer{ResponseWriter: w, ctx: ctx}, r.WithContext(ctx))
+	})
+}
+
+// writer adds the collected warnings to the response headers right before they are written
+type writer struct {
+	http.ResponseWriter
+	ctx         context.Context
+	wroteHeader bool
+}
+
+func (w *writer) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		header := w.Header()
+		for _, warning := range Collected(w.ctx) {
+			value := "299 - " + strconv.Quote(warning)
+			// The handlers may have already added the same warning, e.g. along with the ones of the admission plugins
+			if !slices.Contains(header.Values("Warning"), value) {
+				header.Add("Warning", value)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so that http.ResponseController can reach it
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
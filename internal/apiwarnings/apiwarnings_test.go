@@ -0,0 +1,72 @@
+package apiwarnings
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWrapTransport(t *testing.T) {
+	apiserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Warning", `299 - "spec.template.spec.nodeSelector[beta.kubernetes.io/os]: deprecated since v1.14"`)
+		w.Header().Add("Warning", `299 - "unknown field \"spec.replica\""`)
+		w.Header().Add("Warning", `not a warning`)
+	}))
+	defer apiserver.Close()
+	client := &http.Client{Transport: WrapTransport(http.DefaultTransport)}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected []string
+	}{
+		{"Test Collected", WithCollector(context.Background()), []string{
+			"spec.template.spec.nodeSelector[beta.kubernetes.io/os]: deprecated since v1.14",
+			`unknown field "spec.replica"`,
+		}},
+		{"Test Not Collected", context.Background(), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequestWithContext(tt.ctx, "GET", apiserver.URL, nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			_ = resp.Body.Close()
+			if warnings := Collected(tt.ctx); !reflect.DeepEqual(warnings, tt.expected) {
+				t.Errorf("Collected() = %q, want %q", warnings, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	apiserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Warning", `299 - "apps/v1beta1 Deployment is deprecated"`)
+	}))
+	defer apiserver.Close()
+	client := &http.Client{Transport: WrapTransport(http.DefaultTransport)}
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Warning", `299 - "replicas above 10 cost extra"`)
+		for range 2 {
+			req, _ := http.NewRequestWithContext(r.Context(), "GET", apiserver.URL, nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			_ = resp.Body.Close()
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/deployments", nil))
+	expected := []string{`299 - "replicas above 10 cost extra"`, `299 - "apps/v1beta1 Deployment is deprecated"`}
+	if warnings := w.Header().Values("Warning"); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Warning headers = %q, want %q", warnings, expected)
+	}
+}
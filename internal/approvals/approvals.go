@@ -0,0 +1,432 @@
+// Package approvals holds the sensitive changes requested through the API (e.g. scaling a deployment above a
+// threshold) until a second identity approves them, as a two-person rule. The pending changes are kept in the state
+// store, and expire unless they are approved in time.
+package approvals
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Operations which may require an approval, named after their handlers
+const (
+	OperationSetDeploymentReplicas  = "SetDeploymentReplicas"
+	OperationSetDeploymentBounds    = "SetDeploymentBounds"
+	OperationDeleteDeploymentBounds = "DeleteDeploymentBounds"
+	OperationSetDeploymentOwnership = "SetDeploymentOwnership"
+	OperationApplyManifests         = "ApplyManifests"
+	// OperationRestoreDeploymentSnapshot replaces the whole spec of a deployment
+	OperationRestoreDeploymentSnapshot = "RestoreDeploymentSnapshot"
+	// OperationHibernateNamespace and OperationWakeNamespace scale all the workloads of a namespace
+	OperationHibernateNamespace = "HibernateNamespace"
+	OperationWakeNamespace      = "WakeNamespace"
+	// OperationScaleDeploymentInSteps scales a deployment following a scale plan
+	OperationScaleDeploymentInSteps = "ScaleDeploymentInSteps"
+	// OperationEvictPod evicts a pod, within the limits of its PodDisruptionBudgets
+	OperationEvictPod = "EvictPod"
+)
+
+// Operations are the names of the operations which may require an approval
+var Operations = []string{OperationSetDeploymentReplicas, OperationSetDeploymentBounds, OperationDeleteDeploymentBounds,
+	OperationSetDeploymentOwnership, OperationApplyManifests, OperationRestoreDeploymentSnapshot, OperationHibernateNamespace, OperationWakeNamespace,
+	OperationScaleDeploymentInSteps, OperationEvictPod}
+
+// defaultTTL is how long the changes wait for their approval, unless configured otherwise
+const defaultTTL = time.Hour
+
+// Statuses of the approvals
+const (
+	StatusPending  = "pending"
+	StatusExecuted = "executed"
+	StatusExpired  = "expired"
+)
+
+// Errors of Approve
+var (
+	ErrNotFound   = errors.New("approval not found")
+	ErrForbidden  = errors.New("identity may not approve this change")
+	ErrNotPending = errors.New("approval is not pending")
+	ErrExpired    = errors.New("approval has expired")
+)
+
+// RuleConfig is an operation requiring an approval
+type RuleConfig struct {
+	// Operation is the name of the operation, out of Operations
+	Operation string `json:"operation"`
+	// ReplicasAbove only requires an approval for the SetDeploymentReplicas and ScaleDeploymentInSteps requests setting
+	// more replicas
+	ReplicasAbove *int32 `json:"replicasAbove,omitempty"`
+}
+
+// Config is the approvals configuration file
+type Config struct {
+	// TTL is how long the changes wait for their approval, e.g. 30m. Defaults to 1h.
+	TTL string `json:"ttl,omitempty"`
+	// Approvers are the identities which may approve the changes. Any identity but the requester may approve them when
+	// empty.
+	Approvers []string     `json:"approvers,omitempty"`
+	Rules     []RuleConfig `json:"rules"`
+}
+
+// Result is the response of an executed change
+type Result struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Approval is a change waiting for, or executed after, its approval
+type Approval struct {
+	ID        string `json:"id"`
+	Operation string `json:"operation"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Query     string `json:"query,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// Body is the body of the request, as sent
+	Body        string     `json:"body,omitempty"`
+	RequestedBy string     `json:"requestedBy"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	Status      string     `json:"status"`
+	ApprovedBy  string     `json:"approvedBy,omitempty"`
+	ApprovedAt  *time.Time `json:"approvedAt,omitempty"`
+	Result      *Result    `json:"result,omitempty"`
+}
+
+// record is the persisted form of an approval, along with what's needed to replay its request
+type record struct {
+	Approval
+	PathValues  map[string]string `json:"pathValues,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	// AllNamespaces and Namespaces are the tenancy scope of the requester, if any
+	Scoped        bool     `json:"scoped,omitempty"`
+	AllNamespaces bool     `json:"allNamespaces,omitempty"`
+	Namespaces    []string `json:"namespaces,omitempty"`
+}
+
+// Manager holds the changes requiring an approval, and executes them once approved
+type Manager struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	approvers []string
+	rules     map[string]RuleConfig
+	approvals store.Typed[record]
+	handlers  map[string]http.HandlerFunc
+	now       func() time.Time
+}
+
+// New returns a new Manager keeping the approvals in the given store
+func New(config Config, s store.Store) (*Manager, error) {
+	m := &Manager{
+		ttl:       defaultTTL,
+		approvers: config.Approvers,
+		rules:     map[string]RuleConfig{},
+		approvals: store.Typed[record]{Store: s},
+		handlers:  map[string]http.HandlerFunc{},
+		now:       time.Now,
+	}
+	if config.TTL != "" {
+		ttl, err := time.ParseDuration(config.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid ttl %q, must be a positive duration", config.TTL)
+		}
+		m.ttl = ttl
+	}
+	for _, rule := range config.Rules {
+		if !slices.Contains(Operations, rule.Operation) {
+			return nil, fmt.Errorf("unknown operation %q, must be one of %v", rule.Operation, Operations)
+		}
+		if rule.ReplicasAbove != nil && rule.Operation != OperationSetDeploymentReplicas && rule.Operation != OperationScaleDeploymentInSteps {
+			return nil, fmt.Errorf("replicasAbove only applies to the %s and %s operations", OperationSetDeploymentReplicas, OperationScaleDeploymentInSteps)
+		}
+		if _, ok := m.rules[rule.Operation]; ok {
+			return nil, fmt.Errorf("operation %s has more than one rule", rule.Operation)
+		}
+		m.rules[rule.Operation] = rule
+	}
+	return m, nil
+}
+
+// LoadFile returns a new Manager from the given YAML or JSON configuration file
+func LoadFile(path string, s store.Store) (*Manager, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approvals file: %w", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse approvals file %s: %w", path, err)
+	}
+	return New(config, s)
+}
+
+// Middleware returns a new http.HandlerFunc which holds the requests of the operation requiring an approval, and
+// passes the other ones to the provided handler. Held requests get a 202 Accepted with their pending approval, which
+// is also pointed at by the Location header. The provided handler serves the held requests once approved.
+func (m *Manager) Middleware(operation string, next http.HandlerFunc) http.HandlerFunc {
+	rule, ok := m.rules[operation]
+	if !ok {
+		return next
+	}
+	m.handlers[operation] = next
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := klog.FromContext(r.Context())
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeMessage(w, logger, http.StatusBadRequest, fmt.Sprintf("Error reading request body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if !rule.matches(body) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := m.now()
+		rec := record{
+			Approval: Approval{
+				ID:          uuid.NewString(),
+				Operation:   operation,
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				Query:       r.URL.RawQuery,
+				Namespace:   r.PathValue("namespace"),
+				Body:        string(body),
+				RequestedBy: auth.Identity(r),
+				RequestedAt: now,
+				ExpiresAt:   now.Add(m.ttl),
+				Status:      StatusPending,
+			},
+			PathValues:  map[string]string{},
+			ContentType: r.Header.Get("Content-Type"),
+		}
+		for _, name := range []string{"namespace", "deployment", "snapshot"} {
+			if value := r.PathValue(name); value != "" {
+				rec.PathValues[name] = value
+			}
+		}
+		if scope, ok := tenancy.ScopeFrom(r.Context()); ok {
+			rec.Scoped, rec.AllNamespaces = true, scope.All
+			for namespace := range scope.Namespaces {
+				rec.Namespaces = append(rec.Namespaces, namespace)
+			}
+			slices.Sort(rec.Namespaces)
+		}
+		m.purgeExpired(r.Context())
+		if err := m.approvals.Put(r.Context(), rec.ID, rec); err != nil {
+			logger.Error(err, "Error saving approval")
+			writeMessage(w, logger, http.StatusInternalServerError, "Error saving approval")
+			return
+		}
+		logger.Info("Change held for approval", "approval", rec.ID, "operation", operation, "identity", rec.RequestedBy)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/approvals/"+rec.ID)
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(rec.Approval); err != nil {
+			logger.Error(err, "Error encoding response")
+		}
+	}
+}
+
+// matches returns whether the request with the given body requires an approval. Bodies which can't be parsed are
+// passed through, for the handler to reject them.
+func (r RuleConfig) matches(body []byte) bool {
+	if r.ReplicasAbove == nil {
+		return true
+	}
+	var replicas struct {
+		Replicas *int32 `json:"replicas"`
+	}
+	if err := json.Unmarshal(body, &replicas); err != nil || replicas.Replicas == nil {
+		return false
+	}
+	return *replicas.Replicas > *r.ReplicasAbove
+}
+
+// Get returns the approval with the given ID
+func (m *Manager) Get(ctx context.Context, id string) (Approval, bool, error) {
+	rec, found, err := m.approvals.Get(ctx, id)
+	if err != nil || !found {
+		return Approval{}, false, err
+	}
+	return m.withStatus(rec).Approval, true, nil
+}
+
+// List returns the approvals visible in the given scope, i.e. the ones of the namespaces it allows, or all of them
+// if nil, sorted by request time
+func (m *Manager) List(ctx context.Context, scope *tenancy.Scope) ([]Approval, error) {
+	records, err := m.approvals.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	approvals := make([]Approval, 0, len(records))
+	for _, rec := range records {
+		if scope != nil && !scope.All && (rec.Namespace == "" || !scope.Allows(rec.Namespace)) {
+			continue
+		}
+		approvals = append(approvals, m.withStatus(rec).Approval)
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].RequestedAt.Before(approvals[j].RequestedAt) })
+	return approvals, nil
+}
+
+// Approve approves the pending change with the given ID on behalf of the identity of the request, and executes it.
+// The change is executed with the tenancy scope of its requester, while the request authenticates the approver. The
+// approver must be allowed to access the namespace of the change, within the scope of the request.
+func (m *Manager) Approve(r *http.Request, id string) (Approval, error) {
+	ctx := r.Context()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec, found, err := m.approvals.Get(ctx, id)
+	if err != nil {
+		return Approval{}, err
+	}
+	if !found {
+		return Approval{}, ErrNotFound
+	}
+	rec = m.withStatus(rec)
+	identity := auth.Identity(r)
+	scope, scoped := tenancy.ScopeFrom(ctx)
+	switch {
+	case identity == "" || identity == rec.RequestedBy:
+		return Approval{}, ErrForbidden
+	case len(m.approvers) > 0 && !slices.Contains(m.approvers, identity):
+		return Approval{}, ErrForbidden
+	case scoped && !scope.All && (rec.Namespace == "" || !scope.Allows(rec.Namespace)):
+		return Approval{}, ErrForbidden
+	case rec.Status == StatusExpired:
+		return Approval{}, ErrExpired
+	case rec.Status != StatusPending:
+		return Approval{}, ErrNotPending
+	}
+
+	handler, ok := m.handlers[rec.Operation]
+	if !ok {
+		return Approval{}, fmt.Errorf("operation %s isn't served", rec.Operation)
+	}
+	result := m.execute(r, rec, handler)
+	now := m.now()
+	rec.Status, rec.ApprovedBy, rec.ApprovedAt, rec.Result = StatusExecuted, identity, &now, result
+	if err := m.approvals.Put(ctx, rec.ID, rec); err != nil {
+		klog.FromContext(ctx).Error(err, "Error saving approval", "approval", rec.ID)
+	}
+	klog.FromContext(ctx).Info("Approved change executed", "approval", rec.ID, "operation", rec.Operation, "status", result.Status)
+	return rec.Approval, nil
+}
+
+// execute replays the request of the approval through the handler of its operation, and returns its response
+func (m *Manager) execute(r *http.Request, rec record, handler http.HandlerFunc) *Result {
+	ctx := r.Context()
+	if rec.Scoped {
+		scope := tenancy.Scope{All: rec.AllNamespaces, Namespaces: map[string]bool{}}
+		for _, namespace := range rec.Namespaces {
+			scope.Namespaces[namespace] = true
+		}
+		ctx = tenancy.WithScope(ctx, scope)
+	}
+	target := rec.Path
+	if rec.Query != "" {
+		target += "?" + rec.Query
+	}
+	replay, err := http.NewRequestWithContext(ctx, rec.Method, target, bytes.NewReader([]byte(rec.Body)))
+	if err != nil {
+		return &Result{Status: http.StatusInternalServerError}
+	}
+	replay.TLS = r.TLS
+	replay.RemoteAddr = r.RemoteAddr
+	if rec.ContentType != "" {
+		replay.Header.Set("Content-Type", rec.ContentType)
+	}
+	for name, value := range rec.PathValues {
+		replay.SetPathValue(name, value)
+	}
+
+	rw := &recorder{header: http.Header{}}
+	handler.ServeHTTP(rw, replay)
+	result := &Result{Status: rw.status}
+	if result.Status == 0 {
+		result.Status = http.StatusOK
+	}
+	if body := bytes.TrimSpace(rw.body.Bytes()); json.Valid(body) {
+		result.Body = body
+	}
+	return result
+}
+
+// withStatus returns the record with its pending status replaced by expired once it's past its expiry
+func (m *Manager) withStatus(rec record) record {
+	if rec.Status == StatusPending && m.now().After(rec.ExpiresAt) {
+		rec.Status = StatusExpired
+	}
+	return rec
+}
+
+// purgeExpired removes the approvals which expired, or were executed, more than a TTL ago. They are kept for a TTL
+// so that requesters can still see what became of their changes.
+func (m *Manager) purgeExpired(ctx context.Context) {
+	records, err := m.approvals.List(ctx)
+	if err != nil {
+		klog.FromContext(ctx).Error(err, "Error listing approvals")
+		return
+	}
+	cutoff := m.now().Add(-m.ttl)
+	for id, rec := range records {
+		done := rec.ExpiresAt
+		if rec.ApprovedAt != nil {
+			done = *rec.ApprovedAt
+		}
+		if done.Before(cutoff) {
+			if err := m.approvals.Delete(ctx, id); err != nil {
+				klog.FromContext(ctx).Error(err, "Error deleting approval", "approval", id)
+			}
+		}
+	}
+}
+
+// recorder records the response of a replayed request
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header { return r.header }
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func writeMessage(w http.ResponseWriter, logger klog.Logger, status int, message string) {
+	if encErr := problem.Write(w, status, message); encErr != nil {
+		logger.Error(encErr, "Error encoding response")
+	}
+}
@@ -0,0 +1,267 @@
+package approvals
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	"k8s.io/utils/ptr"
+)
+
+// newRequest returns a new request to the replicas of deployment foo/bar, authenticated as the given identity
+func newRequest(identity, body string) *http.Request {
+	r := httptest.NewRequest("PUT", "/deployments/foo/bar/replicas", strings.NewReader(body))
+	r.SetPathValue("namespace", "foo")
+	r.SetPathValue("deployment", "bar")
+	if identity != "" {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: identity}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+	return r
+}
+
+func newManager(t *testing.T, config Config) *Manager {
+	t.Helper()
+	m, err := New(config, store.NewMemory())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m
+}
+
+// replicasHandler records the replicas it's called with
+type replicasHandler struct {
+	calls []string
+}
+
+func (h *replicasHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	h.calls = append(h.calls, r.PathValue("namespace")+"/"+r.PathValue("deployment")+" "+string(body))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"Test Unknown Operation", Config{Rules: []RuleConfig{{Operation: "DeleteDeployment"}}}},
+		{"Test ReplicasAbove Other Operation", Config{Rules: []RuleConfig{{Operation: OperationApplyManifests, ReplicasAbove: ptr.To(int32(3))}}}},
+		{"Test Duplicate Operation", Config{Rules: []RuleConfig{{Operation: OperationApplyManifests}, {Operation: OperationApplyManifests}}}},
+		{"Test Invalid TTL", Config{TTL: "soon"}},
+		{"Test Negative TTL", Config{TTL: "-1h"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config, store.NewMemory()); err == nil {
+				t.Errorf("New() expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "approvals.yaml")
+	config := "ttl: 30m\napprovers: [alice, bob]\nrules:\n  - operation: SetDeploymentReplicas\n    replicasAbove: 10\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadFile(path, store.NewMemory())
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if m.ttl != 30*time.Minute || len(m.approvers) != 2 || *m.rules[OperationSetDeploymentReplicas].ReplicasAbove != 10 {
+		t.Errorf("LoadFile() = %+v", m)
+	}
+
+	if err := os.WriteFile(path, []byte("rules:\n  - operation: SetDeploymentReplicas\n    above: 10\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path, store.NewMemory()); err == nil {
+		t.Errorf("LoadFile() expected an error for an unknown field")
+	}
+}
+
+func TestManager_Middleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     RuleConfig
+		body     string
+		expected int
+	}{
+		{"Test Unconditional", RuleConfig{Operation: OperationSetDeploymentReplicas}, `{"replicas": 1}`, http.StatusAccepted},
+		{"Test Above Threshold", RuleConfig{Operation: OperationSetDeploymentReplicas, ReplicasAbove: ptr.To(int32(10))}, `{"replicas": 11}`, http.StatusAccepted},
+		{"Test At Threshold", RuleConfig{Operation: OperationSetDeploymentReplicas, ReplicasAbove: ptr.To(int32(10))}, `{"replicas": 10}`, http.StatusOK},
+		{"Test Invalid Body", RuleConfig{Operation: OperationSetDeploymentReplicas, ReplicasAbove: ptr.To(int32(10))}, `replicas`, http.StatusOK},
+		{"Test Other Operation", RuleConfig{Operation: OperationApplyManifests}, `{"replicas": 11}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newManager(t, Config{Rules: []RuleConfig{tt.rule}})
+			next := &replicasHandler{}
+			w := httptest.NewRecorder()
+			m.Middleware(OperationSetDeploymentReplicas, next.ServeHTTP)(w, newRequest("alice", tt.body))
+			if w.Code != tt.expected {
+				t.Fatalf("Middleware() status code = %v, want %v", w.Code, tt.expected)
+			}
+			if tt.expected == http.StatusOK {
+				if len(next.calls) != 1 || !strings.HasSuffix(next.calls[0], tt.body) {
+					t.Errorf("Middleware() expected the request to be passed through with its body, got %v", next.calls)
+				}
+				return
+			}
+			if len(next.calls) != 0 {
+				t.Errorf("Middleware() expected the request to be held, got %v", next.calls)
+			}
+			var approval Approval
+			if err := json.NewDecoder(w.Body).Decode(&approval); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if w.Header().Get("Location") != "/approvals/"+approval.ID || approval.Status != StatusPending ||
+				approval.RequestedBy != "alice" || approval.Namespace != "foo" || approval.Body != tt.body {
+				t.Errorf("Middleware() = %+v, Location %v", approval, w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestManager_Approve(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		approver string
+		scope    *tenancy.Scope
+		after    time.Duration
+		expected error
+	}{
+		{"Test Approve", Config{}, "bob", nil, 0, nil},
+		{"Test Approve By Approver", Config{Approvers: []string{"bob"}}, "bob", nil, 0, nil},
+		{"Test Approve By Requester", Config{}, "alice", nil, 0, ErrForbidden},
+		{"Test Approve Anonymous", Config{}, "", nil, 0, ErrForbidden},
+		{"Test Approve By Non Approver", Config{Approvers: []string{"carol"}}, "bob", nil, 0, ErrForbidden},
+		{"Test Approve Outside Scope", Config{}, "bob", &tenancy.Scope{Namespaces: map[string]bool{"baz": true}}, 0, ErrForbidden},
+		{"Test Approve Within Scope", Config{}, "bob", &tenancy.Scope{Namespaces: map[string]bool{"foo": true}}, 0, nil},
+		{"Test Approve Expired", Config{TTL: "1m"}, "bob", nil, 2 * time.Minute, ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Rules = []RuleConfig{{Operation: OperationSetDeploymentReplicas}}
+			m := newManager(t, tt.config)
+			now := time.Now()
+			m.now = func() time.Time { return now }
+			next := &replicasHandler{}
+			handler := m.Middleware(OperationSetDeploymentReplicas, next.ServeHTTP)
+
+			w := httptest.NewRecorder()
+			handler(w, newRequest("alice", `{"replicas": 20}`))
+			var pending Approval
+			if err := json.NewDecoder(w.Body).Decode(&pending); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			now = now.Add(tt.after)
+			r := newRequest(tt.approver, "")
+			if tt.scope != nil {
+				r = r.WithContext(tenancy.WithScope(r.Context(), *tt.scope))
+			}
+			approval, err := m.Approve(r, pending.ID)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("Approve() error = %v, want %v", err, tt.expected)
+			}
+			if tt.expected != nil {
+				if len(next.calls) != 0 {
+					t.Errorf("Approve() expected the change not to be executed, got %v", next.calls)
+				}
+				return
+			}
+			if len(next.calls) != 1 || next.calls[0] != `foo/bar {"replicas": 20}` {
+				t.Errorf("Approve() expected the change to be executed once, got %v", next.calls)
+			}
+			if approval.Status != StatusExecuted || approval.ApprovedBy != tt.approver || approval.Result == nil ||
+				approval.Result.Status != http.StatusOK || string(approval.Result.Body) != `{"replicas": 20}` {
+				t.Errorf("Approve() = %+v", approval)
+			}
+
+			// Approvals are only executed once
+			if _, err := m.Approve(newRequest("carol", ""), pending.ID); !errors.Is(err, ErrNotPending) && !errors.Is(err, ErrForbidden) {
+				t.Errorf("Approve() error = %v, want %v", err, ErrNotPending)
+			}
+			if len(next.calls) != 1 {
+				t.Errorf("Approve() expected the change to be executed once, got %v", next.calls)
+			}
+		})
+	}
+
+	m := newManager(t, Config{})
+	if _, err := m.Approve(newRequest("bob", ""), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Approve() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestManager_ApproveRestoresScope(t *testing.T) {
+	m := newManager(t, Config{Rules: []RuleConfig{{Operation: OperationSetDeploymentReplicas}}})
+	var executedScope tenancy.Scope
+	handler := m.Middleware(OperationSetDeploymentReplicas, func(w http.ResponseWriter, r *http.Request) {
+		executedScope, _ = tenancy.ScopeFrom(r.Context())
+	})
+
+	r := newRequest("alice", `{"replicas": 20}`)
+	r = r.WithContext(tenancy.WithScope(r.Context(), tenancy.Scope{Namespaces: map[string]bool{"foo": true}}))
+	w := httptest.NewRecorder()
+	handler(w, r)
+	var pending Approval
+	if err := json.NewDecoder(w.Body).Decode(&pending); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	r = newRequest("bob", "")
+	r = r.WithContext(tenancy.WithScope(r.Context(), tenancy.Scope{All: true}))
+	if _, err := m.Approve(r, pending.ID); err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	if executedScope.All || !executedScope.Allows("foo") || executedScope.Allows("baz") {
+		t.Errorf("Approve() expected the change to run in the scope of its requester, got %+v", executedScope)
+	}
+}
+
+func TestManager_List(t *testing.T) {
+	m := newManager(t, Config{TTL: "1m", Rules: []RuleConfig{{Operation: OperationSetDeploymentReplicas}}})
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	handler := m.Middleware(OperationSetDeploymentReplicas, (&replicasHandler{}).ServeHTTP)
+	handler(httptest.NewRecorder(), newRequest("alice", `{"replicas": 20}`))
+
+	list, err := m.List(context.Background(), &tenancy.Scope{Namespaces: map[string]bool{"baz": true}})
+	if err != nil || len(list) != 0 {
+		t.Errorf("List() = %v, %v, want no approvals outside of the scope", list, err)
+	}
+	list, err = m.List(context.Background(), nil)
+	if err != nil || len(list) != 1 || list[0].Status != StatusPending {
+		t.Fatalf("List() = %v, %v, want 1 pending approval", list, err)
+	}
+
+	// Expired approvals are reported as such, then purged once past their TTL
+	now = now.Add(90 * time.Second)
+	if list, _ = m.List(context.Background(), nil); len(list) != 1 || list[0].Status != StatusExpired {
+		t.Errorf("List() = %v, want 1 expired approval", list)
+	}
+	now = now.Add(time.Minute)
+	handler(httptest.NewRecorder(), newRequest("alice", `{"replicas": 30}`))
+	if list, _ = m.List(context.Background(), nil); len(list) != 1 || list[0].Body != `{"replicas": 30}` {
+		t.Errorf("List() = %v, want the expired approval to be purged", list)
+	}
+}
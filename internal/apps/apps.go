@@ -0,0 +1,135 @@
+// Package apps groups the workloads, services and autoscalers of a namespace into logical applications, keyed on the
+// value of a label such as app.kubernetes.io/name.
+package apps
+
+import (
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultLabel is the label the applications are keyed on by default, as recommended by Kubernetes
+const DefaultLabel = "app.kubernetes.io/name"
+
+// Workload is a deployment or a statefulset of an application
+type Workload struct {
+	Name          string `json:"name"`
+	Replicas      int32  `json:"replicas"`
+	ReadyReplicas int32  `json:"readyReplicas"`
+}
+
+// Service is a service of an application
+type Service struct {
+	Name string `json:"name"`
+	// Type is the type of the service, e.g. ClusterIP or LoadBalancer
+	Type string `json:"type"`
+}
+
+// Autoscaler is a HorizontalPodAutoscaler of an application
+type Autoscaler struct {
+	Name string `json:"name"`
+	// Target is the workload scaled by the autoscaler, as Kind/name
+	Target          string `json:"target"`
+	MinReplicas     int32  `json:"minReplicas"`
+	MaxReplicas     int32  `json:"maxReplicas"`
+	CurrentReplicas int32  `json:"currentReplicas"`
+}
+
+// App is a logical application, made of the objects of a namespace sharing the same value of the application label
+type App struct {
+	Name         string       `json:"name"`
+	Namespace    string       `json:"namespace"`
+	Deployments  []Workload   `json:"deployments"`
+	StatefulSets []Workload   `json:"statefulSets"`
+	Services     []Service    `json:"services"`
+	Autoscalers  []Autoscaler `json:"autoscalers"`
+}
+
+// Objects are the objects to group into applications
+type Objects struct {
+	Deployments  []appsv1.Deployment
+	StatefulSets []appsv1.StatefulSet
+	Services     []corev1.Service
+	Autoscalers  []autoscalingv2.HorizontalPodAutoscaler
+}
+
+// Group groups the objects into applications by the value of the given label, sorted by namespace and name. Objects
+// without the label aren't part of any application, except the autoscalers, which belong to the application of the
+// workload they scale when they aren't labeled themselves.
+func Group(label string, objects Objects) []App {
+	type key struct{ namespace, name string }
+	byKey := map[key]*App{}
+	app := func(namespace, name string) *App {
+		k := key{namespace, name}
+		if byKey[k] == nil {
+			byKey[k] = &App{Name: name, Namespace: namespace, Deployments: []Workload{}, StatefulSets: []Workload{},
+				Services: []Service{}, Autoscalers: []Autoscaler{}}
+		}
+		return byKey[k]
+	}
+	// workloads maps the workloads to their application, for the autoscalers scaling them
+	workloads := map[key]string{}
+
+	for _, d := range objects.Deployments {
+		name, ok := d.Labels[label]
+		if !ok {
+			continue
+		}
+		a := app(d.Namespace, name)
+		a.Deployments = append(a.Deployments, Workload{Name: d.Name, Replicas: replicas(d.Spec.Replicas), ReadyReplicas: d.Status.ReadyReplicas})
+		workloads[key{d.Namespace, "Deployment/" + d.Name}] = name
+	}
+	for _, s := range objects.StatefulSets {
+		name, ok := s.Labels[label]
+		if !ok {
+			continue
+		}
+		a := app(s.Namespace, name)
+		a.StatefulSets = append(a.StatefulSets, Workload{Name: s.Name, Replicas: replicas(s.Spec.Replicas), ReadyReplicas: s.Status.ReadyReplicas})
+		workloads[key{s.Namespace, "StatefulSet/" + s.Name}] = name
+	}
+	for _, s := range objects.Services {
+		if name, ok := s.Labels[label]; ok {
+			a := app(s.Namespace, name)
+			a.Services = append(a.Services, Service{Name: s.Name, Type: string(s.Spec.Type)})
+		}
+	}
+	for _, hpa := range objects.Autoscalers {
+		target := hpa.Spec.ScaleTargetRef.Kind + "/" + hpa.Spec.ScaleTargetRef.Name
+		name, ok := hpa.Labels[label]
+		if !ok {
+			if name, ok = workloads[key{hpa.Namespace, target}]; !ok {
+				continue
+			}
+		}
+		a := app(hpa.Namespace, name)
+		a.Autoscalers = append(a.Autoscalers, Autoscaler{Name: hpa.Name, Target: target, MinReplicas: replicas(hpa.Spec.MinReplicas),
+			MaxReplicas: hpa.Spec.MaxReplicas, CurrentReplicas: hpa.Status.CurrentReplicas})
+	}
+
+	apps := make([]App, 0, len(byKey))
+	for _, a := range byKey {
+		sort.Slice(a.Deployments, func(i, j int) bool { return a.Deployments[i].Name < a.Deployments[j].Name })
+		sort.Slice(a.StatefulSets, func(i, j int) bool { return a.StatefulSets[i].Name < a.StatefulSets[j].Name })
+		sort.Slice(a.Services, func(i, j int) bool { return a.Services[i].Name < a.Services[j].Name })
+		sort.Slice(a.Autoscalers, func(i, j int) bool { return a.Autoscalers[i].Name < a.Autoscalers[j].Name })
+		apps = append(apps, *a)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].Namespace != apps[j].Namespace {
+			return apps[i].Namespace < apps[j].Namespace
+		}
+		return apps[i].Name < apps[j].Name
+	})
+	return apps
+}
+
+// replicas returns the replicas, which default to 1 when unset
+func replicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
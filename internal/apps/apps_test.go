@@ -0,0 +1,72 @@
+package apps
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestGroup(t *testing.T) {
+	meta := func(namespace, name, app string) metav1.ObjectMeta {
+		m := metav1.ObjectMeta{Namespace: namespace, Name: name}
+		if app != "" {
+			m.Labels = map[string]string{DefaultLabel: app}
+		}
+		return m
+	}
+	hpa := func(namespace, name, app, kind, target string) autoscalingv2.HorizontalPodAutoscaler {
+		return autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: meta(namespace, name, app),
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: kind, Name: target},
+				MinReplicas:    ptr.To[int32](2),
+				MaxReplicas:    10,
+			},
+			Status: autoscalingv2.HorizontalPodAutoscalerStatus{CurrentReplicas: 3},
+		}
+	}
+
+	apps := Group(DefaultLabel, Objects{
+		Deployments: []appsv1.Deployment{
+			{ObjectMeta: meta("team-a", "shop-web", "shop"), Spec: appsv1.DeploymentSpec{Replicas: ptr.To[int32](3)}, Status: appsv1.DeploymentStatus{ReadyReplicas: 2}},
+			{ObjectMeta: meta("team-a", "shop-api", "shop")},
+			// The same application name in another namespace is another application
+			{ObjectMeta: meta("team-b", "shop", "shop")},
+			// Not part of any application
+			{ObjectMeta: meta("team-a", "debug", "")},
+		},
+		StatefulSets: []appsv1.StatefulSet{
+			{ObjectMeta: meta("team-a", "shop-db", "shop"), Spec: appsv1.StatefulSetSpec{Replicas: ptr.To[int32](1)}, Status: appsv1.StatefulSetStatus{ReadyReplicas: 1}},
+		},
+		Services: []corev1.Service{
+			{ObjectMeta: meta("team-a", "shop", "shop"), Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}},
+			{ObjectMeta: meta("team-a", "other", "")},
+		},
+		Autoscalers: []autoscalingv2.HorizontalPodAutoscaler{
+			// Autoscalers without the label belong to the application of the workload they scale
+			hpa("team-a", "shop-web", "", "Deployment", "shop-web"),
+			hpa("team-a", "debug", "", "Deployment", "debug"),
+		},
+	})
+	expected := []App{
+		{
+			Name: "shop", Namespace: "team-a",
+			Deployments:  []Workload{{Name: "shop-api", Replicas: 1}, {Name: "shop-web", Replicas: 3, ReadyReplicas: 2}},
+			StatefulSets: []Workload{{Name: "shop-db", Replicas: 1, ReadyReplicas: 1}},
+			Services:     []Service{{Name: "shop", Type: "LoadBalancer"}},
+			Autoscalers:  []Autoscaler{{Name: "shop-web", Target: "Deployment/shop-web", MinReplicas: 2, MaxReplicas: 10, CurrentReplicas: 3}},
+		},
+		{
+			Name: "shop", Namespace: "team-b",
+			Deployments: []Workload{{Name: "shop", Replicas: 1}}, StatefulSets: []Workload{}, Services: []Service{}, Autoscalers: []Autoscaler{},
+		},
+	}
+	if !reflect.DeepEqual(apps, expected) {
+		t.Errorf("Group() = %+v, want %+v", apps, expected)
+	}
+}
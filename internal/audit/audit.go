@@ -0,0 +1,196 @@
+// Package audit records the mutating requests served by the API (who changed what, and the outcome) to an audit sink.
+// The request and response bodies are recorded along with them, once the configured redaction rules are applied, so
+// that secrets never land in the audit storage.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Event is the audit record of a mutating request
+type Event struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"requestID,omitempty"`
+	Identity  string    `json:"identity"`
+	// ClientCA is the client CA which verified the client certificate of the request, if any
+	ClientCA  string `json:"clientCA,omitempty"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Status    int    `json:"status"`
+	Duration  string `json:"duration"`
+	// RequestBody and ResponseBody are the redacted bodies, when they are JSON (or YAML for the requests)
+	RequestBody  json.RawMessage `json:"requestBody,omitempty"`
+	ResponseBody json.RawMessage `json:"responseBody,omitempty"`
+	// RequestBodyOmitted and ResponseBodyOmitted are the reasons the bodies were left out of the event, e.g. when they
+	// can't be parsed for redaction
+	RequestBodyOmitted  string `json:"requestBodyOmitted,omitempty"`
+	ResponseBodyOmitted string `json:"responseBodyOmitted,omitempty"`
+}
+
+// Sink is where the audit events are written to
+type Sink interface {
+	Write(ctx context.Context, event *Event) error
+}
+
+// FileSink writes the audit events to a file as JSON lines
+type FileSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewFileSink returns a FileSink appending to the file at the given path, which is created if it doesn't exist, or
+// writing to stdout if the path is "-"
+func NewFileSink(path string) (*FileSink, error) {
+	if path == "-" {
+		return &FileSink{w: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &FileSink{w: f}, nil
+}
+
+// Write writes the event as a single line
+func (s *FileSink) Write(_ context.Context, event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Logger records the mutating requests to its sink
+type Logger struct {
+	sink         Sink
+	rules        []Rule
+	maxBodyBytes int
+}
+
+// NewLogger returns a new Logger writing to the sink, which redacts the bodies with the given rules, and leaves out the
+// bodies larger than maxBodyBytes
+func NewLogger(sink Sink, rules []Rule, maxBodyBytes int) *Logger {
+	return &Logger{sink: sink, rules: rules, maxBodyBytes: maxBodyBytes}
+}
+
+// recorder passes the response through, while keeping a copy of its status code and of the start of its body
+type recorder struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if room := r.limit - r.body.Len(); len(b) > room {
+		r.truncated = true
+		r.body.Write(b[:max(room, 0)])
+	} else {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Middleware returns a new http.HandlerFunc which records the mutating requests to the provided handler, once they
+// are served. Safe requests (GET, HEAD and OPTIONS) aren't recorded.
+func (l *Logger) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		logger := klog.FromContext(r.Context())
+
+		// The start of the body is read up front, and served again to the handler along with the rest of it
+		var requestBody []byte
+		var requestErr error
+		if r.Body != nil && r.Body != http.NoBody {
+			requestBody, requestErr = io.ReadAll(io.LimitReader(r.Body, int64(l.maxBodyBytes)+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(requestBody), r.Body), r.Body}
+		}
+
+		rec := &recorder{ResponseWriter: w, limit: l.maxBodyBytes}
+		next.ServeHTTP(rec, r)
+
+		event := &Event{
+			Time:      start.UTC(),
+			RequestID: w.Header().Get(logging.RequestIDHeader),
+			Identity:  auth.Identity(r),
+			ClientCA:  auth.ClientCA(r),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Namespace: r.PathValue("namespace"),
+			Name:      r.PathValue("deployment"),
+			Status:    rec.status,
+			Duration:  time.Since(start).String(),
+		}
+		if requestErr != nil {
+			event.RequestBodyOmitted = fmt.Sprintf("failed to read the body: %v", requestErr)
+		} else {
+			event.RequestBody, event.RequestBodyOmitted = l.body(requestBody, len(requestBody) > l.maxBodyBytes, BodyRequest)
+		}
+		event.ResponseBody, event.ResponseBodyOmitted = l.body(rec.body.Bytes(), rec.truncated, BodyResponse)
+		if err := l.sink.Write(r.Context(), event); err != nil {
+			logger.Error(err, "Error writing audit event")
+		}
+	}
+}
+
+// body returns the redacted body, or the reason it's omitted. Bodies which can't be parsed are omitted as a whole,
+// since they can't be redacted.
+func (l *Logger) body(raw []byte, truncated bool, target string) (json.RawMessage, string) {
+	switch {
+	case len(bytes.TrimSpace(raw)) == 0:
+		return nil, ""
+	case truncated:
+		return nil, fmt.Sprintf("body larger than %d bytes", l.maxBodyBytes)
+	}
+	var document any
+	if err := json.Unmarshal(raw, &document); err != nil {
+		// Requests may also be sent as YAML, e.g. the manifests to apply
+		converted, yamlErr := yaml.YAMLToJSON(raw)
+		if yamlErr != nil || json.Unmarshal(converted, &document) != nil {
+			return nil, "body isn't a JSON or YAML document"
+		}
+	}
+	Redact(l.rules, document, target)
+	redacted, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Sprintf("failed to encode the body: %v", err)
+	}
+	return redacted, ""
+}
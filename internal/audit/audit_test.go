@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+)
+
+type memorySink struct {
+	events []*Event
+}
+
+func (s *memorySink) Write(_ context.Context, event *Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+const testRules = `
+rules:
+- jsonPath: $..env[*].value
+- jsonPath: $.token
+  bodies: [response]
+`
+
+func newRules(t *testing.T) []Rule {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(testRules), 0o600); err != nil {
+		t.Fatalf("failed to write audit redaction rules file: %v", err)
+	}
+	rules, err := LoadRulesFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return rules
+}
+
+func TestLogger_Middleware(t *testing.T) {
+	tests := []struct {
+		name                 string
+		method               string
+		requestBody          string
+		responseBody         string
+		maxBodyBytes         int
+		expectedEvent        bool
+		expectedRequestBody  string
+		expectedResponseBody string
+		expectedOmitted      bool
+	}{
+		{"Test Middleware Redacts Bodies", "POST", `{"env":[{"name":"API_KEY","value":"s3cr3t"}],"token":"t0k3n"}`, `{"env":[{"name":"API_KEY","value":"s3cr3t"}],"token":"t0k3n"}`, 1024, true,
+			`{"env":[{"name":"API_KEY","value":"REDACTED"}],"token":"t0k3n"}`, `{"env":[{"name":"API_KEY","value":"REDACTED"}],"token":"REDACTED"}`, false},
+		{"Test Middleware Redacts YAML Request Body", "POST", "env:\n- name: API_KEY\n  value: s3cr3t\n", "", 1024, true,
+			`{"env":[{"name":"API_KEY","value":"REDACTED"}]}`, "", false},
+		{"Test Middleware Omits Large Bodies", "PUT", `{"replicas":3}`, `{"replicas":3,"padding":"xxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"}`, 20, true, `{"replicas":3}`, "", true},
+		{"Test Middleware Omits Large Request Bodies", "POST", `{"replicas":3,"padding":"xxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"}`, `{"replicas":3}`, 20, true, "", `{"replicas":3}`, false},
+		{"Test Middleware Omits Unparseable Bodies", "PUT", `{"replicas":3}`, "replicas: [", 1024, true, `{"replicas":3}`, "", true},
+		{"Test Middleware Skips Reads", "GET", "", `{"replicas":3}`, 1024, false, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &memorySink{}
+			handler := NewLogger(sink, newRules(t), tt.maxBodyBytes).Middleware(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tt.requestBody {
+					t.Errorf("handler read body %q, want %q", body, tt.requestBody)
+				}
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte(tt.responseBody))
+			})
+			mux := http.NewServeMux()
+			mux.HandleFunc("/deployments/{namespace}/{deployment}", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(logging.RequestIDHeader, "abc")
+				handler(w, r)
+			})
+
+			r := httptest.NewRequest(tt.method, "/deployments/foo/bar", strings.NewReader(tt.requestBody))
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: "team-a-portal"}}
+			ca := &x509.Certificate{Subject: pkix.Name{CommonName: "Corp Root CA 2024"}}
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, ca}}}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Body.String() != tt.responseBody {
+				t.Errorf("response body = %q, want %q", w.Body.String(), tt.responseBody)
+			}
+			if !tt.expectedEvent {
+				if len(sink.events) != 0 {
+					t.Errorf("events = %d, want none", len(sink.events))
+				}
+				return
+			}
+			if len(sink.events) != 1 {
+				t.Fatalf("events = %d, want 1", len(sink.events))
+			}
+			event := sink.events[0]
+			if event.Identity != "team-a-portal" || event.ClientCA != "Corp Root CA 2024" || event.RequestID != "abc" || event.Method != tt.method || event.Namespace != "foo" || event.Name != "bar" || event.Status != http.StatusAccepted {
+				t.Errorf("event = %+v, want the request and its outcome", event)
+			}
+			if string(event.RequestBody) != tt.expectedRequestBody {
+				t.Errorf("request body = %s, want %s", event.RequestBody, tt.expectedRequestBody)
+			}
+			if tt.expectedRequestBody == "" && tt.requestBody != "" && event.RequestBodyOmitted == "" {
+				t.Errorf("request body = %s, want it omitted", event.RequestBody)
+			}
+			if string(event.ResponseBody) != tt.expectedResponseBody {
+				t.Errorf("response body = %s, want %s", event.ResponseBody, tt.expectedResponseBody)
+			}
+			if omitted := event.ResponseBodyOmitted != ""; omitted != tt.expectedOmitted {
+				t.Errorf("response body omitted = %q, want omitted %v", event.ResponseBodyOmitted, tt.expectedOmitted)
+			}
+		})
+	}
+}
+
+func TestFileSink_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink() error = %v", err)
+	}
+	for _, name := range []string{"foo", "bar"} {
+		if err := sink.Write(context.Background(), &Event{Method: "PUT", Name: name}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("failed to decode audit event %q: %v", scanner.Text(), err)
+		}
+		names = append(names, event.Name)
+	}
+	if strings.Join(names, ",") != "foo,bar" {
+		t.Errorf("events = %v, want foo,bar", names)
+	}
+}
+
+func TestNewRules_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config RulesConfig
+	}{
+		{"Test NewRules Invalid JSONPath", RulesConfig{Rules: []RuleConfig{{JSONPath: "spec.token"}}}},
+		{"Test NewRules Invalid Body", RulesConfig{Rules: []RuleConfig{{JSONPath: "$.token", Bodies: []string{"headers"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRules(tt.config); err == nil {
+				t.Errorf("NewRules() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package audit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/yaml"
+)
+
+// Defaults of the forwarding configuration
+const (
+	defaultQueueSize     = 10000
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5 * time.Second
+	defaultMaxRetries    = 5
+	// maxRetryDelay caps the exponential backoff between the deliveries of a batch
+	maxRetryDelay = time.Minute
+	// drainTimeout is how long the queued events are still delivered for once the forwarder is stopped
+	drainTimeout = 5 * time.Second
+)
+
+// Names of the sinks the events are forwarded to
+const (
+	SinkSyslog = "syslog"
+	SinkHTTPS  = "https"
+)
+
+// Reasons the forwarded events are dropped for
+const (
+	DropQueueFull      = "queue_full"
+	DropDeliveryFailed = "delivery_failed"
+)
+
+var (
+	forwardedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_audit_forwarded_events_total",
+		Help: "Number of audit events delivered to the SIEM, by sink",
+	}, []string{"sink"})
+	droppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_audit_dropped_events_total",
+		Help: "Number of audit events which couldn't be delivered to the SIEM, by sink and reason",
+	}, []string{"sink", "reason"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(forwardedTotal, droppedTotal)
+}
+
+// TLSConfig is the TLS configuration of the connections to a SIEM
+type TLSConfig struct {
+	// CAFile is the path of the PEM bundle the certificate of the SIEM is verified with. Defaults to the system roots.
+	CAFile string `json:"caFile,omitempty"`
+	// CertFile and KeyFile are the paths of the client certificate and key, when the SIEM requires mutual TLS
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// ServerName overrides the name the certificate of the SIEM is verified against, which defaults to its host
+	ServerName string `json:"serverName,omitempty"`
+}
+
+// load returns the tls.Config of the configuration
+func (c TLSConfig) load() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: c.ServerName}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in CA file %s", c.CAFile)
+		}
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// BatchConfig is how the events are batched and retried on their way to a SIEM
+type BatchConfig struct {
+	// BatchSize is the maximum number of events delivered at once. Defaults to 100.
+	BatchSize int `json:"batchSize,omitempty"`
+	// FlushInterval is how long the events may wait for their batch to fill up. Defaults to 5s.
+	FlushInterval string `json:"flushInterval,omitempty"`
+	// MaxRetries is how many times the delivery of a batch is retried, with an exponential backoff, before its events
+	// are dropped. Defaults to 5.
+	MaxRetries *int `json:"maxRetries,omitempty"`
+}
+
+// ForwardingConfig is the configuration file of the forwarding of the audit events to SIEMs, e.g. Splunk or Sentinel
+type ForwardingConfig struct {
+	// QueueSize is how many events of each sink may wait to be delivered before new ones are dropped. Defaults to 10000.
+	QueueSize int           `json:"queueSize,omitempty"`
+	Syslog    *SyslogConfig `json:"syslog,omitempty"`
+	HTTPS     *HTTPSConfig  `json:"https,omitempty"`
+}
+
+// transport delivers batches of events to a SIEM
+type transport interface {
+	send(ctx context.Context, events []*Event) error
+	close()
+}
+
+// permanentError is a delivery error which retrying won't fix, e.g. a rejected token
+type permanentError struct {
+	error
+}
+
+// Forwarder is a Sink queuing the events to be delivered to a SIEM in the background, in batches, so that a slow or
+// unavailable SIEM never delays the API
+type Forwarder struct {
+	name          string
+	transport     transport
+	queue         chan *Event
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	retryDelay    time.Duration
+}
+
+// newForwarder returns a new Forwarder delivering to the transport, batched as configured
+func newForwarder(name string, t transport, queueSize int, config BatchConfig) (*Forwarder, error) {
+	f := &Forwarder{
+		name:          name,
+		transport:     t,
+		queue:         make(chan *Event, queueSize),
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		maxRetries:    defaultMaxRetries,
+		retryDelay:    time.Second,
+	}
+	if config.BatchSize < 0 {
+		return nil, fmt.Errorf("invalid batch size %d of the %s sink, must be positive", config.BatchSize, name)
+	} else if config.BatchSize > 0 {
+		f.batchSize = config.BatchSize
+	}
+	if config.FlushInterval != "" {
+		interval, err := time.ParseDuration(config.FlushInterval)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid flush interval %q of the %s sink, must be a positive duration", config.FlushInterval, name)
+		}
+		f.flushInterval = interval
+	}
+	if config.MaxRetries != nil {
+		if *config.MaxRetries < 0 {
+			return nil, fmt.Errorf("invalid max retries %d of the %s sink, must not be negative", *config.MaxRetries, name)
+		}
+		f.maxRetries = *config.MaxRetries
+	}
+	return f, nil
+}
+
+// NewForwarders returns the Forwarders of the sinks of the given configuration
+func NewForwarders(config ForwardingConfig) ([]*Forwarder, error) {
+	queueSize := defaultQueueSize
+	if config.QueueSize < 0 {
+		return nil, fmt.Errorf("invalid queue size %d, must be positive", config.QueueSize)
+	} else if config.QueueSize > 0 {
+		queueSize = config.QueueSize
+	}
+	var forwarders []*Forwarder
+	if config.Syslog != nil {
+		t, err := newSyslogTransport(*config.Syslog)
+		if err != nil {
+			return nil, err
+		}
+		f, err := newForwarder(SinkSyslog, t, queueSize, config.Syslog.BatchConfig)
+		if err != nil {
+			return nil, err
+		}
+		forwarders = append(forwarders, f)
+	}
+	if config.HTTPS != nil {
+		t, err := newHTTPSTransport(*config.HTTPS)
+		if err != nil {
+			return nil, err
+		}
+		f, err := newForwarder(SinkHTTPS, t, queueSize, config.HTTPS.BatchConfig)
+		if err != nil {
+			return nil, err
+		}
+		forwarders = append(forwarders, f)
+	}
+	if len(forwarders) == 0 {
+		return nil, errors.New("at least one of the syslog and https sinks must be configured")
+	}
+	return forwarders, nil
+}
+
+// LoadForwardingFile returns the Forwarders of the sinks of the YAML configuration file at the given path
+func LoadForwardingFile(path string) ([]*Forwarder, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit forwarding file: %w", err)
+	}
+	var config ForwardingConfig
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse audit forwarding file %s: %w", path, err)
+	}
+	return NewForwarders(config)
+}
+
+// Write queues the event, without waiting for it to be delivered. Events are dropped when the queue is full.
+func (f *Forwarder) Write(_ context.Context, event *Event) error {
+	select {
+	case f.queue <- event:
+		return nil
+	default:
+		droppedTotal.WithLabelValues(f.name, DropQueueFull).Inc()
+		return fmt.Errorf("audit event dropped, too many events are waiting to be forwarded to %s", f.name)
+	}
+}
+
+// Run delivers the queued events in batches until the context is cancelled, and then the events still queued, for a
+// few seconds
+func (f *Forwarder) Run(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithValues("sink", f.name)
+	defer f.transport.close()
+	ticker := time.NewTicker(f.flushInterval)
+	defer ticker.Stop()
+	batch := make([]*Event, 0, f.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), drainTimeout)
+			defer cancel()
+			for {
+				select {
+				case event := <-f.queue:
+					batch = append(batch, event)
+					if len(batch) < f.batchSize {
+						continue
+					}
+					f.deliver(drainCtx, logger, batch)
+					batch = batch[:0]
+				default:
+					f.deliver(drainCtx, logger, batch)
+					return
+				}
+			}
+		case event := <-f.queue:
+			batch = append(batch, event)
+			if len(batch) < f.batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		f.deliver(ctx, logger, batch)
+		batch = batch[:0]
+	}
+}
+
+// deliver sends the batch, retrying with an exponential backoff, and drops it once the retries are exhausted
+func (f *Forwarder) deliver(ctx context.Context, logger klog.Logger, batch []*Event) {
+	if len(batch) == 0 {
+		return
+	}
+	delay := f.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := f.transport.send(ctx, batch)
+		if err == nil {
+			forwardedTotal.WithLabelValues(f.name).Add(float64(len(batch)))
+			return
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) || attempt >= f.maxRetries || ctx.Err() != nil {
+			logger.Error(err, "Error forwarding audit events, dropping them", "events", len(batch), "attempts", attempt+1)
+			droppedTotal.WithLabelValues(f.name, DropDeliveryFailed).Add(float64(len(batch)))
+			return
+		}
+		logger.V(2).Info("Error forwarding audit events, retrying", "err", err, "events", len(batch), "delay", delay)
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
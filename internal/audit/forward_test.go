@@ -0,0 +1,284 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeCAFile writes the certificate of the test server to a CA file
+func writeCAFile(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, encoded, 0o600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+	return path
+}
+
+func newTestEvents(n int) []*Event {
+	events := make([]*Event, 0, n)
+	for i := range n {
+		events = append(events, &Event{
+			Time:     time.Date(2024, 6, 1, 12, 0, i, 0, time.UTC),
+			Identity: "ci-bot",
+			Method:   "PUT",
+			Path:     "/deployments/default/web/replicas",
+			Status:   200 + 200*(i%2),
+		})
+	}
+	return events
+}
+
+func TestForwarder_HTTPS(t *testing.T) {
+	tests := []struct {
+		name string
+		// statuses are the statuses of the successive responses of the collector, the last one being repeated
+		statuses          []int
+		format            string
+		expectedRequests  int
+		expectedDelivered bool
+	}{
+		{name: "json", statuses: []int{http.StatusOK}, format: FormatJSON, expectedRequests: 2, expectedDelivered: true},
+		{name: "splunk", statuses: []int{http.StatusOK}, format: FormatSplunk, expectedRequests: 2, expectedDelivered: true},
+		{name: "retried", statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, expectedRequests: 4, expectedDelivered: true},
+		{name: "retries exhausted", statuses: []int{http.StatusBadGateway}, expectedRequests: 12},
+		{name: "rejected", statuses: []int{http.StatusForbidden}, expectedRequests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests int
+			var bodies []string
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.Header.Get("Authorization") != "Splunk t0k3n" {
+					t.Errorf("Authorization = %q, want the content of the authorization file", r.Header.Get("Authorization"))
+				}
+				body, _ := io.ReadAll(r.Body)
+				status := tt.statuses[min(requests, len(tt.statuses)-1)]
+				requests++
+				if status == http.StatusOK {
+					bodies = append(bodies, string(body))
+				}
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+			authorizationFile := filepath.Join(t.TempDir(), "authorization")
+			if err := os.WriteFile(authorizationFile, []byte("Splunk t0k3n\n"), 0o600); err != nil {
+				t.Fatalf("failed to write authorization file: %v", err)
+			}
+
+			forwarders, err := NewForwarders(ForwardingConfig{HTTPS: &HTTPSConfig{
+				URL:               server.URL + "/services/collector/event",
+				Format:            tt.format,
+				AuthorizationFile: authorizationFile,
+				TLS:               TLSConfig{CAFile: writeCAFile(t, server)},
+				BatchConfig:       BatchConfig{BatchSize: 2, FlushInterval: "50ms"},
+			}})
+			if err != nil {
+				t.Fatalf("NewForwarders() error = %v", err)
+			}
+			f := forwarders[0]
+			f.retryDelay = time.Millisecond
+			for _, event := range newTestEvents(3) {
+				if err := f.Write(context.Background(), event); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				f.Run(ctx)
+				close(done)
+			}()
+			// The first batch is full right away, while the last event waits for the flush interval
+			time.Sleep(500 * time.Millisecond)
+			cancel()
+			<-done
+
+			mu.Lock()
+			defer mu.Unlock()
+			if requests != tt.expectedRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.expectedRequests)
+			}
+			if !tt.expectedDelivered {
+				return
+			}
+			var delivered int
+			for _, body := range bodies {
+				if tt.format == FormatSplunk {
+					decoder := json.NewDecoder(strings.NewReader(body))
+					for decoder.More() {
+						var envelope splunkEvent
+						if err := decoder.Decode(&envelope); err != nil {
+							t.Fatalf("error decoding Splunk event: %v", err)
+						}
+						if envelope.Event == nil || envelope.Event.Identity != "ci-bot" || envelope.Time != float64(envelope.Event.Time.Unix()) {
+							t.Errorf("Splunk event = %+v, want the envelope of an audit event", envelope)
+						}
+						delivered++
+					}
+					continue
+				}
+				var events []Event
+				if err := json.Unmarshal([]byte(body), &events); err != nil {
+					t.Fatalf("error decoding batch: %v", err)
+				}
+				delivered += len(events)
+			}
+			if delivered != 3 {
+				t.Errorf("delivered events = %d, want 3", delivered)
+			}
+		})
+	}
+}
+
+func TestForwarder_Syslog(t *testing.T) {
+	// The listener serves the certificate of an httptest server, which is valid for 127.0.0.1
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	caFile := writeCAFile(t, server)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: server.TLS.Certificates})
+	server.Close()
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	messages := make(chan string, 3)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			// Every message is prefixed with its length, as per RFC 5425
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(length))
+			if err != nil {
+				t.Errorf("invalid message length %q", length)
+				return
+			}
+			message := make([]byte, n)
+			if _, err := io.ReadFull(reader, message); err != nil {
+				return
+			}
+			messages <- string(message)
+		}
+	}()
+
+	forwarders, err := NewForwarders(ForwardingConfig{Syslog: &SyslogConfig{
+		Address:     listener.Addr().String(),
+		TLS:         TLSConfig{CAFile: caFile},
+		BatchConfig: BatchConfig{BatchSize: 3},
+	}})
+	if err != nil {
+		t.Fatalf("NewForwarders() error = %v", err)
+	}
+	f := forwarders[0]
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.Run(ctx)
+	for _, event := range newTestEvents(3) {
+		if err := f.Write(context.Background(), event); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	for i, expectedPrefix := range []string{"<134>1 2024-06-01T12:00:00.000000Z ", "<133>1 2024-06-01T12:00:01.000000Z ", "<134>1 2024-06-01T12:00:02.000000Z "} {
+		select {
+		case message := <-messages:
+			expectedPrefix += hostname + " go-k8s-http-api " + strconv.Itoa(os.Getpid()) + " audit - {"
+			if !strings.HasPrefix(message, expectedPrefix) {
+				t.Errorf("message %d = %q, want the prefix %q", i, message, expectedPrefix)
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(message[strings.Index(message, "{"):]), &event); err != nil || event.Identity != "ci-bot" {
+				t.Errorf("message %d holds %+v (%v), want the JSON audit event", i, event, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message %d not received", i)
+		}
+	}
+}
+
+func TestForwarder_QueueFull(t *testing.T) {
+	forwarders, err := NewForwarders(ForwardingConfig{QueueSize: 1, HTTPS: &HTTPSConfig{URL: "https://splunk.example.com:8088/services/collector/event"}})
+	if err != nil {
+		t.Fatalf("NewForwarders() error = %v", err)
+	}
+	events := newTestEvents(2)
+	if err := forwarders[0].Write(context.Background(), events[0]); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := forwarders[0].Write(context.Background(), events[1]); err == nil {
+		t.Error("Write() error = nil, want an error once the queue is full")
+	}
+}
+
+func TestLoadForwardingFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expectedSinks int
+		expectedError bool
+	}{
+		{
+			name: "both sinks",
+			config: `
+queueSize: 500
+syslog:
+  address: siem.example.com:6514
+  appName: k8s-api-proxy
+https:
+  url: https://splunk.example.com:8088/services/collector/event
+  format: splunk
+  batchSize: 50
+  flushInterval: 2s
+  maxRetries: 0
+`,
+			expectedSinks: 2,
+		},
+		{name: "no sink", config: "queueSize: 500\n", expectedError: true},
+		{name: "plain HTTP", config: "https:\n  url: http://splunk.example.com:8088/services/collector/event\n", expectedError: true},
+		{name: "unknown format", config: "https:\n  url: https://splunk.example.com\n  format: cef\n", expectedError: true},
+		{name: "syslog address without port", config: "syslog:\n  address: siem.example.com\n", expectedError: true},
+		{name: "invalid flush interval", config: "syslog:\n  address: siem.example.com:6514\n  flushInterval: soon\n", expectedError: true},
+		{name: "missing CA file", config: "syslog:\n  address: siem.example.com:6514\n  tls:\n    caFile: /nonexistent/ca.pem\n", expectedError: true},
+		{name: "unknown field", config: "syslog:\n  address: siem.example.com:6514\n  protocol: udp\n", expectedError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "forwarding.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatalf("failed to write forwarding file: %v", err)
+			}
+			forwarders, err := LoadForwardingFile(path)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("LoadForwardingFile() error = %v, want an error: %v", err, tt.expectedError)
+			}
+			if len(forwarders) != tt.expectedSinks {
+				t.Errorf("LoadForwardingFile() = %d sinks, want %d", len(forwarders), tt.expectedSinks)
+			}
+		})
+	}
+}
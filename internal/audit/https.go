@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Formats of the bodies posted to the HTTPS collectors
+const (
+	// FormatJSON posts the batches as JSON arrays of events, e.g. to the Azure Monitor logs ingestion API of Sentinel
+	FormatJSON = "json"
+	// FormatSplunk posts the batches as Splunk HTTP Event Collector events, one JSON object per event
+	FormatSplunk = "splunk"
+)
+
+// HTTPSConfig is a generic HTTPS collector the events are posted to in batches, e.g. a Splunk HTTP Event Collector
+type HTTPSConfig struct {
+	URL string `json:"url"`
+	// Format is the format of the bodies, json or splunk. Defaults to json.
+	Format string `json:"format,omitempty"`
+	// AuthorizationFile is the path of the file holding the value of the Authorization header of the requests, e.g.
+	// "Splunk <token>" or "Bearer <token>"
+	AuthorizationFile string    `json:"authorizationFile,omitempty"`
+	TLS               TLSConfig `json:"tls,omitempty"`
+	BatchConfig
+}
+
+// httpsTransport posts the events to an HTTPS collector
+type httpsTransport struct {
+	url           string
+	format        string
+	authorization string
+	hostname      string
+	client        *http.Client
+}
+
+func newHTTPSTransport(config HTTPSConfig) (*httpsTransport, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q of the https sink, must be an https:// URL", config.URL)
+	}
+	t := &httpsTransport{url: config.URL, format: config.Format}
+	switch t.format {
+	case "":
+		t.format = FormatJSON
+	case FormatJSON, FormatSplunk:
+	default:
+		return nil, fmt.Errorf("invalid format %q of the https sink, must be %s or %s", config.Format, FormatJSON, FormatSplunk)
+	}
+	if config.AuthorizationFile != "" {
+		raw, err := os.ReadFile(config.AuthorizationFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read authorization file of the https sink: %w", err)
+		}
+		t.authorization = strings.TrimSpace(string(raw))
+	}
+	tlsConfig, err := config.TLS.load()
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration of the https sink: %w", err)
+	}
+	if t.hostname, err = os.Hostname(); err != nil {
+		t.hostname = ""
+	}
+	t.client = &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	return t, nil
+}
+
+// splunkEvent is the envelope of an event posted to a Splunk HTTP Event Collector
+type splunkEvent struct {
+	// Time is the time of the event in seconds since the epoch, with a millisecond precision
+	Time       float64 `json:"time"`
+	Host       string  `json:"host,omitempty"`
+	Source     string  `json:"source"`
+	SourceType string  `json:"sourcetype"`
+	Event      *Event  `json:"event"`
+}
+
+// body returns the body posted for the events, in the format of the collector
+func (t *httpsTransport) body(events []*Event) ([]byte, error) {
+	if t.format == FormatJSON {
+		return json.Marshal(events)
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, event := range events {
+		envelope := splunkEvent{
+			Time:       float64(event.Time.UnixMilli()) / 1000,
+			Host:       t.hostname,
+			Source:     defaultAppName,
+			SourceType: "_json",
+			Event:      event,
+		}
+		if err := encoder.Encode(envelope); err != nil {
+			return nil, err
+		}
+	}
+	return body.Bytes(), nil
+}
+
+// send posts the events in a single request. The 429 and 5xx responses are retried, but not the other errors.
+func (t *httpsTransport) send(ctx context.Context, events []*Event) error {
+	body, err := t.body(events)
+	if err != nil {
+		return permanentError{fmt.Errorf("failed to encode audit events: %w", err)}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{fmt.Errorf("failed to create collector request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.authorization != "" {
+		req.Header.Set("Authorization", t.authorization)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call collector: %w", err)
+	}
+	defer resp.Body.Close()
+	// The body is drained, so that the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	default:
+		return permanentError{fmt.Errorf("collector returned status %d", resp.StatusCode)}
+	}
+}
+
+func (t *httpsTransport) close() {
+	t.client.CloseIdleConnections()
+}
@@ -0,0 +1,180 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// stepKind is the kind of a step of a JSONPath expression
+type stepKind int
+
+const (
+	// stepChild selects the member of an object with the given name
+	stepChild stepKind = iota
+	// stepIndex selects the element of an array at the given index, counting from the end when negative
+	stepIndex
+	// stepWildcard selects all the members of an object, or all the elements of an array
+	stepWildcard
+	// stepDescendant selects the members with the given name of the object and of all its descendants
+	stepDescendant
+)
+
+type step struct {
+	kind  stepKind
+	name  string
+	index int
+}
+
+// Path is a parsed JSONPath expression. The supported subset is the root ($), followed by any of: child members
+// (.name or ['name']), array indexes ([0], [-1]), wildcards (.* or [*]) and recursive descent (..name).
+type Path struct {
+	expression string
+	steps      []step
+}
+
+// ParsePath parses the JSONPath expression
+func ParsePath(expression string) (*Path, error) {
+	rest, found := strings.CutPrefix(expression, "$")
+	if !found {
+		return nil, fmt.Errorf("invalid JSONPath %q, must start with $", expression)
+	}
+	p := &Path{expression: expression}
+	for rest != "" {
+		var s step
+		var err error
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			s.kind = stepDescendant
+			s.name, rest = cutName(rest[2:])
+			if s.name == "" || s.name == "*" {
+				err = fmt.Errorf("recursive descent must be followed by a member name")
+			}
+		case strings.HasPrefix(rest, "."):
+			s.name, rest = cutName(rest[1:])
+			if s.name == "*" {
+				s.kind = stepWildcard
+			} else if s.name == "" {
+				err = fmt.Errorf("empty member name")
+			}
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				err = fmt.Errorf("unterminated bracket")
+				break
+			}
+			s, err = parseBracket(rest[1:end])
+			rest = rest[end+1:]
+		default:
+			err = fmt.Errorf("unexpected %q", rest)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSONPath %q: %w", expression, err)
+		}
+		p.steps = append(p.steps, s)
+	}
+	if len(p.steps) == 0 {
+		return nil, fmt.Errorf("invalid JSONPath %q, must select a member of the document", expression)
+	}
+	return p, nil
+}
+
+// cutName returns the member name at the start of the expression, up to the next step
+func cutName(expression string) (string, string) {
+	end := strings.IndexAny(expression, ".[")
+	if end == -1 {
+		return expression, ""
+	}
+	return expression[:end], expression[end:]
+}
+
+// parseBracket parses the content of a bracketed step: *, an index, or a quoted member name
+func parseBracket(content string) (step, error) {
+	content = strings.TrimSpace(content)
+	switch {
+	case content == "*":
+		return step{kind: stepWildcard}, nil
+	case len(content) >= 2 && (content[0] == '\'' || content[0] == '"') && content[len(content)-1] == content[0]:
+		return step{kind: stepChild, name: content[1 : len(content)-1]}, nil
+	}
+	index, err := strconv.Atoi(content)
+	if err != nil {
+		return step{}, fmt.Errorf("invalid bracket %q, must be *, an index or a quoted name", content)
+	}
+	return step{kind: stepIndex, index: index}, nil
+}
+
+// String returns the JSONPath expression
+func (p *Path) String() string {
+	return p.expression
+}
+
+// Replace replaces the values selected by the path in the decoded JSON document with the given value, in place, and
+// returns the number of replaced values
+func (p *Path) Replace(document any, value any) int {
+	return replace(document, p.steps, value)
+}
+
+func replace(node any, steps []step, value any) int {
+	s, rest := steps[0], steps[1:]
+	replaced := 0
+	if s.kind == stepDescendant {
+		// Apply the remaining steps to the named member of the node, and look for it in all the descendants as well
+		replaced += replace(node, append([]step{{kind: stepChild, name: s.name}}, rest...), value)
+		forEachChild(node, func(child any, _ func(any)) {
+			replaced += replace(child, steps, value)
+		})
+		return replaced
+	}
+	forEachMatch(node, s, func(child any, set func(any)) {
+		if len(rest) == 0 {
+			set(value)
+			replaced++
+			return
+		}
+		replaced += replace(child, rest, value)
+	})
+	return replaced
+}
+
+// forEachMatch calls fn with the children of the node selected by the step, and a function replacing them
+func forEachMatch(node any, s step, fn func(child any, set func(any))) {
+	switch n := node.(type) {
+	case map[string]any:
+		switch s.kind {
+		case stepChild:
+			if child, ok := n[s.name]; ok {
+				fn(child, func(v any) { n[s.name] = v })
+			}
+		case stepWildcard:
+			forEachChild(n, fn)
+		}
+	case []any:
+		switch s.kind {
+		case stepIndex:
+			i := s.index
+			if i < 0 {
+				i += len(n)
+			}
+			if i >= 0 && i < len(n) {
+				fn(n[i], func(v any) { n[i] = v })
+			}
+		case stepWildcard:
+			forEachChild(n, fn)
+		}
+	}
+}
+
+// forEachChild calls fn with every member of an object or element of an array, and a function replacing it
+func forEachChild(node any, fn func(child any, set func(any))) {
+	switch n := node.(type) {
+	case map[string]any:
+		for key, child := range n {
+			fn(child, func(v any) { n[key] = v })
+		}
+	case []any:
+		for i, child := range n {
+			fn(child, func(v any) { n[i] = v })
+		}
+	}
+}
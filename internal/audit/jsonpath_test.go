@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testDocument = `{
+	"metadata": {"name": "bar", "annotations": {"token": "s3cr3t"}},
+	"spec": {"containers": [
+		{"name": "app", "env": [{"name": "API_KEY", "value": "s3cr3t"}, {"name": "MODE", "value": "fast"}]},
+		{"name": "sidecar", "env": [{"name": "PASSWORD", "value": "hunter2"}]}
+	]},
+	"password": "root"
+}`
+
+func TestPath_Replace(t *testing.T) {
+	tests := []struct {
+		name             string
+		expression       string
+		expectedReplaced int
+		expectedDocument string
+	}{
+		{"Test Replace Child", "$.metadata.annotations.token", 1, `{"metadata":{"name":"bar","annotations":{"token":"X"}},"spec":{"containers":[{"name":"app","env":[{"name":"API_KEY","value":"s3cr3t"},{"name":"MODE","value":"fast"}]},{"name":"sidecar","env":[{"name":"PASSWORD","value":"hunter2"}]}]},"password":"root"}`},
+		{"Test Replace Bracket Name", "$['metadata']['name']", 1, `{"metadata":{"name":"X","annotations":{"token":"s3cr3t"}},"spec":{"containers":[{"name":"app","env":[{"name":"API_KEY","value":"s3cr3t"},{"name":"MODE","value":"fast"}]},{"name":"sidecar","env":[{"name":"PASSWORD","value":"hunter2"}]}]},"password":"root"}`},
+		{"Test Replace Wildcards", "$.spec.containers[*].env[*].value", 3, `{"metadata":{"name":"bar","annotations":{"token":"s3cr3t"}},"spec":{"containers":[{"name":"app","env":[{"name":"API_KEY","value":"X"},{"name":"MODE","value":"X"}]},{"name":"sidecar","env":[{"name":"PASSWORD","value":"X"}]}]},"password":"root"}`},
+		{"Test Replace Negative Index", "$.spec.containers[-1].env[0].value", 1, `{"metadata":{"name":"bar","annotations":{"token":"s3cr3t"}},"spec":{"containers":[{"name":"app","env":[{"name":"API_KEY","value":"s3cr3t"},{"name":"MODE","value":"fast"}]},{"name":"sidecar","env":[{"name":"PASSWORD","value":"X"}]}]},"password":"root"}`},
+		{"Test Replace Object Wildcard", "$.metadata.annotations.*", 1, `{"metadata":{"name":"bar","annotations":{"token":"X"}},"spec":{"containers":[{"name":"app","env":[{"name":"API_KEY","value":"s3cr3t"},{"name":"MODE","value":"fast"}]},{"name":"sidecar","env":[{"name":"PASSWORD","value":"hunter2"}]}]},"password":"root"}`},
+		{"Test Replace Recursive Descent", "$..token", 1, `{"metadata":{"name":"bar","annotations":{"token":"X"}},"spec":{"containers":[{"name":"app","env":[{"name":"API_KEY","value":"s3cr3t"},{"name":"MODE","value":"fast"}]},{"name":"sidecar","env":[{"name":"PASSWORD","value":"hunter2"}]}]},"password":"root"}`},
+		{"Test Replace Recursive Descent Nested", "$..env[*].value", 3, `{"metadata":{"name":"bar","annotations":{"token":"s3cr3t"}},"spec":{"containers":[{"name":"app","env":[{"name":"API_KEY","value":"X"},{"name":"MODE","value":"X"}]},{"name":"sidecar","env":[{"name":"PASSWORD","value":"X"}]}]},"password":"root"}`},
+		{"Test Replace No Match", "$.spec.replicas", 0, `{"metadata":{"name":"bar","annotations":{"token":"s3cr3t"}},"spec":{"containers":[{"name":"app","env":[{"name":"API_KEY","value":"s3cr3t"},{"name":"MODE","value":"fast"}]},{"name":"sidecar","env":[{"name":"PASSWORD","value":"hunter2"}]}]},"password":"root"}`},
+		{"Test Replace Out Of Range", "$.spec.containers[5].name", 0, `{"metadata":{"name":"bar","annotations":{"token":"s3cr3t"}},"spec":{"containers":[{"name":"app","env":[{"name":"API_KEY","value":"s3cr3t"},{"name":"MODE","value":"fast"}]},{"name":"sidecar","env":[{"name":"PASSWORD","value":"hunter2"}]}]},"password":"root"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePath(tt.expression)
+			if err != nil {
+				t.Fatalf("ParsePath() error = %v", err)
+			}
+			var document, expected any
+			if err := json.Unmarshal([]byte(testDocument), &document); err != nil {
+				t.Fatalf("failed to decode the document: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tt.expectedDocument), &expected); err != nil {
+				t.Fatalf("failed to decode the expected document: %v", err)
+			}
+			if replaced := p.Replace(document, "X"); replaced != tt.expectedReplaced {
+				t.Errorf("Replace() = %d, want %d", replaced, tt.expectedReplaced)
+			}
+			got, _ := json.Marshal(document)
+			want, _ := json.Marshal(expected)
+			if string(got) != string(want) {
+				t.Errorf("document = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestParsePath_Invalid(t *testing.T) {
+	for _, expression := range []string{"", "spec.replicas", "$", "$.", "$..", "$..*", "$[0", "$[abc]", "$spec"} {
+		if _, err := ParsePath(expression); err == nil {
+			t.Errorf("ParsePath(%q) error = nil, want an error", expression)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Redacted is the value the redacted values of the bodies are replaced with
+const Redacted = "REDACTED"
+
+// Bodies the redaction rules apply to
+const (
+	BodyRequest  = "request"
+	BodyResponse = "response"
+)
+
+// RuleConfig is the configuration of a redaction rule
+type RuleConfig struct {
+	// JSONPath selects the values to redact, e.g. $.spec.template.spec.containers[*].env[*].value
+	JSONPath string `json:"jsonPath"`
+	// Bodies are the bodies the rule applies to, out of request and response. It applies to both when empty.
+	Bodies []string `json:"bodies,omitempty"`
+}
+
+// RulesConfig is the redaction rules configuration file
+type RulesConfig struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+// Rule redacts the values selected by its JSONPath from the bodies it applies to
+type Rule struct {
+	path   *Path
+	bodies []string
+}
+
+// appliesTo returns whether the rule applies to the given body
+func (r Rule) appliesTo(body string) bool {
+	return len(r.bodies) == 0 || slices.Contains(r.bodies, body)
+}
+
+// NewRules returns the redaction rules of the given configuration
+func NewRules(config RulesConfig) ([]Rule, error) {
+	rules := make([]Rule, 0, len(config.Rules))
+	for _, rc := range config.Rules {
+		path, err := ParsePath(rc.JSONPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, body := range rc.Bodies {
+			if body != BodyRequest && body != BodyResponse {
+				return nil, fmt.Errorf("invalid body %q of rule %s, must be either %s or %s", body, rc.JSONPath, BodyRequest, BodyResponse)
+			}
+		}
+		rules = append(rules, Rule{path: path, bodies: rc.Bodies})
+	}
+	return rules, nil
+}
+
+// Redact replaces the values of the parsed JSON document selected by the rules applying to the given body, out of
+// BodyRequest and BodyResponse
+func Redact(rules []Rule, document any, body string) {
+	for _, rule := range rules {
+		if rule.appliesTo(body) {
+			rule.path.Replace(document, Redacted)
+		}
+	}
+}
+
+// LoadRulesFile returns the redaction rules of the given YAML or JSON configuration file
+func LoadRulesFile(path string) ([]Rule, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit redaction rules file: %w", err)
+	}
+	var config RulesConfig
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse audit redaction rules file %s: %w", path, err)
+	}
+	return NewRules(config)
+}
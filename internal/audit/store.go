@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	"k8s.io/klog/v2"
+)
+
+// Outcomes of the audited requests, which the events may be filtered by
+const (
+	// OutcomeSuccess is the outcome of the requests served with a status below 400
+	OutcomeSuccess = "success"
+	// OutcomeFailure is the outcome of the requests which were denied or failed, i.e. served with a status of 400 or more
+	OutcomeFailure = "failure"
+)
+
+// keyTimeFormat is the time format of the keys of the stored events, which sort in the order of the events. The keys
+// are valid ConfigMap keys.
+const keyTimeFormat = "20060102-150405.000000000"
+
+// pruneInterval is how often the events older than the retention are removed from the store
+const pruneInterval = time.Hour
+
+// ErrInvalidContinue is returned for the continue tokens which weren't returned by a previous query
+var ErrInvalidContinue = errors.New("invalid continue token")
+
+// MultiSink writes the audit events to all of its sinks, e.g. to a file and to the state store
+type MultiSink []Sink
+
+// Write writes the event to every sink, even when some of them fail
+func (s MultiSink) Write(ctx context.Context, event *Event) error {
+	var errs []error
+	for _, sink := range s {
+		if err := sink.Write(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Query filters and paginates the audit events kept in a StoreSink. The zero values match all the events.
+type Query struct {
+	Identity  string
+	Namespace string
+	// Resource is the resource of the request path, see Resource
+	Resource string
+	// Verb is the HTTP method of the request, matched case-insensitively
+	Verb string
+	// Outcome is either OutcomeSuccess or OutcomeFailure
+	Outcome string
+	// Since and Until bound the time of the events, Since being inclusive and Until exclusive
+	Since, Until time.Time
+	// Limit is the maximum number of events returned, all of them being returned when it isn't positive
+	Limit int
+	// Continue is the token returned along with the previous page of events
+	Continue string
+}
+
+// matches returns whether the event matches the filters of the query
+func (q Query) matches(event *Event) bool {
+	switch {
+	case q.Identity != "" && event.Identity != q.Identity,
+		q.Namespace != "" && event.Namespace != q.Namespace,
+		q.Resource != "" && Resource(event.Path) != q.Resource,
+		q.Verb != "" && !strings.EqualFold(event.Method, q.Verb),
+		q.Outcome == OutcomeSuccess && event.Status >= 400,
+		q.Outcome == OutcomeFailure && event.Status < 400,
+		!q.Since.IsZero() && event.Time.Before(q.Since),
+		!q.Until.IsZero() && !event.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// Resource returns the resource of a request path, i.e. its first segment once the /v1 prefix of the versioned API and
+// the /namespaces/{namespace} prefix of the namespaced routes are stripped, e.g. deployments, pods or apply
+func Resource(requestPath string) string {
+	if trimmed := strings.TrimPrefix(requestPath, apiversion.Prefix); strings.HasPrefix(trimmed, "/") {
+		requestPath = trimmed
+	}
+	segments := strings.Split(strings.Trim(requestPath, "/"), "/")
+	if segments[0] == "namespaces" && len(segments) > 2 {
+		return segments[2]
+	}
+	return segments[0]
+}
+
+// StoreSink keeps the audit events in the state store, so that they can be queried, for the duration of the retention
+type StoreSink struct {
+	events    store.Typed[Event]
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewStoreSink returns a new StoreSink keeping the events in the given store for the given retention
+func NewStoreSink(s store.Store, retention time.Duration) *StoreSink {
+	return &StoreSink{events: store.Typed[Event]{Store: s}, retention: retention, now: time.Now}
+}
+
+// Write saves the event under a key sorting in the order of the events
+func (s *StoreSink) Write(ctx context.Context, event *Event) error {
+	key := event.Time.UTC().Format(keyTimeFormat) + "-" + uuid.NewString()
+	if err := s.events.Put(ctx, key, *event); err != nil {
+		return fmt.Errorf("failed to save audit event: %w", err)
+	}
+	return nil
+}
+
+// Query returns the events matching the query, newest first, along with the continue token of the next page, which is
+// empty on the last page
+func (s *StoreSink) Query(ctx context.Context, q Query) ([]Event, string, error) {
+	if q.Continue != "" {
+		if len(q.Continue) <= len(keyTimeFormat) {
+			return nil, "", ErrInvalidContinue
+		} else if _, err := time.Parse(keyTimeFormat, q.Continue[:len(keyTimeFormat)]); err != nil {
+			return nil, "", ErrInvalidContinue
+		}
+	}
+	all, err := s.events.List(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list audit events: %w", err)
+	}
+	keys := make([]string, 0, len(all))
+	for key, event := range all {
+		// The pages hold the events stored before the last event of the previous page
+		if (q.Continue == "" || key < q.Continue) && q.matches(&event) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	var next string
+	if q.Limit > 0 && len(keys) > q.Limit {
+		keys = keys[:q.Limit]
+		next = keys[len(keys)-1]
+	}
+	events := make([]Event, 0, len(keys))
+	for _, key := range keys {
+		events = append(events, all[key])
+	}
+	return events, next, nil
+}
+
+// Run removes the events older than the retention periodically, until the context is cancelled
+func (s *StoreSink) Run(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.prune(ctx); err != nil {
+			logger.Error(err, "Error removing the expired audit events")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune removes the events older than the retention
+func (s *StoreSink) prune(ctx context.Context) error {
+	all, err := s.events.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list audit events: %w", err)
+	}
+	cutoff := s.now().Add(-s.retention)
+	for key, event := range all {
+		if event.Time.Before(cutoff) {
+			if err := s.events.Delete(ctx, key); err != nil {
+				return fmt.Errorf("failed to remove audit event %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
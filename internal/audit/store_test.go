@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+)
+
+func newTestStoreSink(t *testing.T, start time.Time) *StoreSink {
+	t.Helper()
+	s := NewStoreSink(store.NewMemory(), time.Hour)
+	events := []Event{
+		{Identity: "alice", Method: "PUT", Path: "/deployments/default/web/replicas", Namespace: "default", Name: "web", Status: 200},
+		{Identity: "bob", Method: "POST", Path: "/v1/namespaces/default/pods/web-1/evict", Namespace: "default", Status: 429},
+		{Identity: "alice", Method: "POST", Path: "/apply", Status: 201},
+		{Identity: "alice", Method: "PUT", Path: "/v1/namespaces/prod/deployments/api/replicas", Namespace: "prod", Name: "api", Status: 403},
+	}
+	for i := range events {
+		events[i].Time = start.Add(time.Duration(i) * time.Minute)
+		if err := s.Write(context.Background(), &events[i]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	return s
+}
+
+func TestStoreSink_Query(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStoreSink(t, start)
+	tests := []struct {
+		name          string
+		query         Query
+		expectedPaths []string
+	}{
+		{"Test All Newest First", Query{}, []string{"/v1/namespaces/prod/deployments/api/replicas", "/apply", "/v1/namespaces/default/pods/web-1/evict", "/deployments/default/web/replicas"}},
+		{"Test Identity", Query{Identity: "bob"}, []string{"/v1/namespaces/default/pods/web-1/evict"}},
+		{"Test Namespace", Query{Namespace: "default"}, []string{"/v1/namespaces/default/pods/web-1/evict", "/deployments/default/web/replicas"}},
+		{"Test Resource", Query{Resource: "deployments"}, []string{"/v1/namespaces/prod/deployments/api/replicas", "/deployments/default/web/replicas"}},
+		{"Test Verb", Query{Verb: "post"}, []string{"/apply", "/v1/namespaces/default/pods/web-1/evict"}},
+		{"Test Success", Query{Outcome: OutcomeSuccess}, []string{"/apply", "/deployments/default/web/replicas"}},
+		{"Test Failure", Query{Outcome: OutcomeFailure, Identity: "alice"}, []string{"/v1/namespaces/prod/deployments/api/replicas"}},
+		{"Test Time Range", Query{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, []string{"/apply", "/v1/namespaces/default/pods/web-1/evict"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, next, err := s.Query(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if next != "" {
+				t.Errorf("Query() continue = %q, want none", next)
+			}
+			if len(events) != len(tt.expectedPaths) {
+				t.Fatalf("Query() = %+v, want %v", events, tt.expectedPaths)
+			}
+			for i, event := range events {
+				if event.Path != tt.expectedPaths[i] {
+					t.Errorf("Query()[%d].path = %v, want %v", i, event.Path, tt.expectedPaths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStoreSink_Query_Pagination(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStoreSink(t, start)
+	var paths []string
+	q := Query{Identity: "alice", Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatalf("Query() still paginating after %d pages", pages)
+		}
+		events, next, err := s.Query(context.Background(), q)
+		if err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		for _, event := range events {
+			paths = append(paths, event.Path)
+		}
+		if next == "" {
+			break
+		}
+		q.Continue = next
+	}
+	expected := []string{"/v1/namespaces/prod/deployments/api/replicas", "/apply", "/deployments/default/web/replicas"}
+	if len(paths) != len(expected) {
+		t.Fatalf("paginated paths = %v, want %v", paths, expected)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Errorf("paginated paths[%d] = %v, want %v", i, paths[i], expected[i])
+		}
+	}
+
+	if _, _, err := s.Query(context.Background(), Query{Continue: "page-2"}); !errors.Is(err, ErrInvalidContinue) {
+		t.Errorf("Query() error = %v, want %v", err, ErrInvalidContinue)
+	}
+}
+
+func TestStoreSink_Prune(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStoreSink(t, start)
+	s.now = func() time.Time { return start.Add(time.Hour + 90*time.Second) }
+	if err := s.prune(context.Background()); err != nil {
+		t.Fatalf("prune() error = %v", err)
+	}
+	events, _, err := s.Query(context.Background(), Query{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(events) != 2 || events[1].Path != "/apply" {
+		t.Errorf("Query() after prune = %+v, want the 2 events of the last hour", events)
+	}
+}
+
+func TestResource(t *testing.T) {
+	tests := map[string]string{
+		"/deployments/default/web/replicas":            "deployments",
+		"/v1/deployments/default/web/replicas":         "deployments",
+		"/namespaces/default/deployments/web/replicas": "deployments",
+		"/v1/namespaces/default/pods/web-1/evict":      "pods",
+		"/namespaces/default/hibernate":                "hibernate",
+		"/apply":                                       "apply",
+		"/v1/admin/loglevel":                           "admin",
+		"/v1beta1/deployments":                         "v1beta1",
+	}
+	for path, expected := range tests {
+		if got := Resource(path); got != expected {
+			t.Errorf("Resource(%q) = %v, want %v", path, got, expected)
+		}
+	}
+}
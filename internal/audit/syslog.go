@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultAppName is the APP-NAME of the syslog messages, unless configured otherwise
+const defaultAppName = "go-k8s-http-api"
+
+// Syslog facility and severities of the messages
+const (
+	facilityLocal0 = 16
+	severityNotice = 5
+	severityInfo   = 6
+)
+
+// writeTimeout is how long a batch of messages may take to be written to the syslog collector
+const writeTimeout = 10 * time.Second
+
+// SyslogConfig is a syslog collector receiving the events as RFC 5424 messages over TLS (RFC 5425)
+type SyslogConfig struct {
+	// Address is the host:port of the collector, usually on port 6514
+	Address string    `json:"address"`
+	TLS     TLSConfig `json:"tls,omitempty"`
+	// AppName is the APP-NAME of the messages. Defaults to go-k8s-http-api.
+	AppName string `json:"appName,omitempty"`
+	BatchConfig
+}
+
+// syslogTransport writes the events to a syslog collector over a TLS connection, which is dialed on the first batch
+// and again after an error
+type syslogTransport struct {
+	address  string
+	tls      *tls.Config
+	hostname string
+	appName  string
+	procID   string
+	conn     net.Conn
+}
+
+func newSyslogTransport(config SyslogConfig) (*syslogTransport, error) {
+	if _, _, err := net.SplitHostPort(config.Address); err != nil {
+		return nil, fmt.Errorf("invalid syslog address %q, must be host:port: %w", config.Address, err)
+	}
+	tlsConfig, err := config.TLS.load()
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration of the syslog sink: %w", err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+	t := &syslogTransport{address: config.Address, tls: tlsConfig, hostname: hostname, appName: config.AppName, procID: strconv.Itoa(os.Getpid())}
+	if t.appName == "" {
+		t.appName = defaultAppName
+	}
+	return t, nil
+}
+
+// send writes the events as octet-counted RFC 5424 messages, with the JSON event as the message
+func (t *syslogTransport) send(ctx context.Context, events []*Event) error {
+	var frames bytes.Buffer
+	for _, event := range events {
+		message, err := t.message(event)
+		if err != nil {
+			return permanentError{err}
+		}
+		frames.WriteString(strconv.Itoa(len(message)))
+		frames.WriteByte(' ')
+		frames.Write(message)
+	}
+	if t.conn == nil {
+		dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: writeTimeout}, Config: t.tls}
+		conn, err := dialer.DialContext(ctx, "tcp", t.address)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog collector: %w", err)
+		}
+		t.conn = conn
+	}
+	if err := t.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		t.close()
+		return err
+	}
+	if _, err := t.conn.Write(frames.Bytes()); err != nil {
+		t.close()
+		return fmt.Errorf("failed to write to syslog collector: %w", err)
+	}
+	return nil
+}
+
+// message returns the RFC 5424 message of the event: the requests which were denied or failed are notices, and the
+// others informational
+func (t *syslogTransport) message(event *Event) ([]byte, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit event: %w", err)
+	}
+	severity := severityInfo
+	if event.Status >= 400 {
+		severity = severityNotice
+	}
+	header := fmt.Sprintf("<%d>1 %s %s %s %s audit - ", facilityLocal0*8+severity,
+		event.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), t.hostname, t.appName, t.procID)
+	return append([]byte(header), body...), nil
+}
+
+func (t *syslogTransport) close() {
+	if t.conn != nil {
+		// The connection is discarded either way
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+)
+
+// apiKeyPrefix is the prefix of the API keys, which tells them from the other bearer tokens, e.g. in secret scanners
+const apiKeyPrefix = "gka_"
+
+// APIKeyHeader is the header the API keys may be passed in, rather than as a bearer token
+const APIKeyHeader = "X-API-Key"
+
+// Scopes of the API keys
+const (
+	// ScopeRead allows the GET, HEAD and OPTIONS requests
+	ScopeRead = "read"
+	// ScopeWrite allows the requests of the other methods
+	ScopeWrite = "write"
+)
+
+// Errors of the management of the API keys
+var (
+	// ErrAPIKeyNotFound is returned for the API keys which don't exist, or belong to another identity
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidAPIKey is wrapped by the errors of the invalid scopes and expiries of the created API keys
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// APIKey describes an API key, without its secret
+type APIKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Owner is the identity which created the key, on behalf of which its requests are made
+	Owner string `json:"owner"`
+	// Scopes restrict the requests of the key, out of read and write
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// storedAPIKey is an API key as kept in the state store, with the hash of its secret rather than the secret itself
+type storedAPIKey struct {
+	APIKey
+	// Groups are the groups of the owner when the key was created
+	Groups []string `json:"groups,omitempty"`
+	// Hash is the hex encoded SHA-256 hash of the secret of the key
+	Hash string `json:"hash"`
+}
+
+// APIKeys manages the API keys, kept in the state store, and authenticates their requests. Only the hashes of the
+// secrets are kept, the secret of a key being returned once, on creation.
+type APIKeys struct {
+	keys store.Typed[storedAPIKey]
+	now  func() time.Time
+}
+
+// NewAPIKeys returns a new APIKeys keeping the keys in the given store
+func NewAPIKeys(s store.Store) *APIKeys {
+	return &APIKeys{keys: store.Typed[storedAPIKey]{Store: s}, now: time.Now}
+}
+
+// Create creates an API key on behalf of the given principal, restricted to the given scopes and expiring at the given
+// time, if any. It returns the key along with its secret, which isn't kept.
+func (k *APIKeys) Create(ctx context.Context, owner Principal, name string, scopes []string, expiresAt *time.Time) (APIKey, string, error) {
+	if len(scopes) == 0 {
+		return APIKey{}, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKey)
+	}
+	for _, scope := range scopes {
+		if scope != ScopeRead && scope != ScopeWrite {
+			return APIKey{}, "", fmt.Errorf("%w: scope %q must be %s or %s", ErrInvalidAPIKey, scope, ScopeRead, ScopeWrite)
+		}
+	}
+	now := k.now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return APIKey{}, "", fmt.Errorf("%w: the expiry must be in the future", ErrInvalidAPIKey)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return APIKey{}, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+	id := strings.ReplaceAll(uuid.NewString(), "-", "")
+	key := storedAPIKey{
+		APIKey: APIKey{ID: id, Name: name, Owner: owner.Name, Scopes: scopes, CreatedAt: now, ExpiresAt: expiresAt},
+		Groups: owner.Groups,
+		Hash:   hashSecret(encodedSecret),
+	}
+	if err := k.keys.Put(ctx, id, key); err != nil {
+		return APIKey{}, "", fmt.Errorf("failed to store API key: %w", err)
+	}
+	return key.APIKey, apiKeyPrefix + id + "_" + encodedSecret, nil
+}
+
+// List returns the API keys of the given owner, or all of them when empty, oldest first
+func (k *APIKeys) List(ctx context.Context, owner string) ([]APIKey, error) {
+	stored, err := k.keys.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]APIKey, 0, len(stored))
+	for _, key := range stored {
+		if owner == "" || key.Owner == owner {
+			keys = append(keys, key.APIKey)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+// Get returns the API key of the given ID, if it belongs to the given owner, or to anyone when empty
+func (k *APIKeys) Get(ctx context.Context, owner, id string) (APIKey, error) {
+	key, found, err := k.keys.Get(ctx, id)
+	if err != nil {
+		return APIKey{}, err
+	}
+	if !found || (owner != "" && key.Owner != owner) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return key.APIKey, nil
+}
+
+// Delete revokes the API key of the given ID, if it belongs to the given owner, or to anyone when empty
+func (k *APIKeys) Delete(ctx context.Context, owner, id string) error {
+	if _, err := k.Get(ctx, owner, id); err != nil {
+		return err
+	}
+	return k.keys.Delete(ctx, id)
+}
+
+// Authenticate implements Authenticator, for the API keys passed as a bearer token or in the X-API-Key header. The
+// requests outside of the scopes of their key are forbidden.
+func (k *APIKeys) Authenticate(r *http.Request) (Principal, bool, error) {
+	token := r.Header.Get(APIKeyHeader)
+	if token == "" {
+		bearer, ok := bearerToken(r)
+		if !ok || !strings.HasPrefix(bearer, apiKeyPrefix) {
+			return Principal{}, false, nil
+		}
+		token = bearer
+	}
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, apiKeyPrefix), "_")
+	if _, err := hex.DecodeString(id); !ok || err != nil || len(id) != 32 || !strings.HasPrefix(token, apiKeyPrefix) {
+		return Principal{}, false, errors.New("malformed API key")
+	}
+	key, found, err := k.keys.Get(r.Context(), id)
+	if err != nil {
+		return Principal{}, false, fmt.Errorf("failed to get API key: %w", err)
+	}
+	if !found {
+		return Principal{}, false, errors.New("unknown API key")
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.Hash)) != 1 {
+		return Principal{}, false, &CredentialError{Credential: id, Err: fmt.Errorf("wrong secret for API key %s", id)}
+	}
+	if key.ExpiresAt != nil && !k.now().Before(*key.ExpiresAt) {
+		return Principal{}, false, &CredentialError{Credential: id, Err: fmt.Errorf("API key %s expired", id)}
+	}
+	scope := ScopeWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+		scope = ScopeRead
+	}
+	if !slices.Contains(key.Scopes, scope) {
+		return Principal{}, false, &IdentityError{Identity: key.Owner, Err: fmt.Errorf("%w: API key %s lacks the %s scope", ErrForbidden, id, scope)}
+	}
+	return Principal{Name: key.Owner, Groups: key.Groups, Credential: id}, true, nil
+}
+
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+)
+
+func TestAPIKeys_Create(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	tests := []struct {
+		name        string
+		scopes      []string
+		expiresAt   *time.Time
+		expectedErr string
+	}{
+		{name: "read only", scopes: []string{ScopeRead}},
+		{name: "read write expiring", scopes: []string{ScopeRead, ScopeWrite}, expiresAt: &future},
+		{name: "no scopes", expectedErr: "at least one scope is required"},
+		{name: "unknown scope", scopes: []string{"admin"}, expectedErr: `scope "admin" must be read or write`},
+		{name: "expired", scopes: []string{ScopeRead}, expiresAt: &past, expectedErr: "the expiry must be in the future"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := NewAPIKeys(store.NewMemory())
+			keys.now = func() time.Time { return now }
+			key, secret, err := keys.Create(context.Background(), Principal{Name: "alice"}, "ci", tt.scopes, tt.expiresAt)
+			if tt.expectedErr != "" {
+				if !errors.Is(err, ErrInvalidAPIKey) || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Create() error = %v, want %v", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if !strings.HasPrefix(secret, apiKeyPrefix+key.ID+"_") {
+				t.Errorf("Create() secret = %v, want the ID of the key", secret)
+			}
+			if key.Owner != "alice" || !key.CreatedAt.Equal(now) {
+				t.Errorf("Create() = %+v, want owned by alice, created now", key)
+			}
+
+			// Only the hash of the secret is kept
+			stored, _, _ := keys.keys.Get(context.Background(), key.ID)
+			if stored.Hash == "" || strings.Contains(stored.Hash, secret[len(apiKeyPrefix+key.ID+"_"):]) {
+				t.Errorf("stored hash = %v, want the hash of the secret", stored.Hash)
+			}
+		})
+	}
+}
+
+func TestAPIKeys_Authenticate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	expiry := now.Add(time.Hour)
+	keys := NewAPIKeys(store.NewMemory())
+	keys.now = func() time.Time { return now }
+	owner := Principal{Name: "alice", Groups: []string{"deployers"}}
+	_, readOnly, err := keys.Create(context.Background(), owner, "dashboard", []string{ScopeRead}, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	_, readWrite, err := keys.Create(context.Background(), owner, "ci", []string{ScopeRead, ScopeWrite}, &expiry)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	revokedKey, revoked, err := keys.Create(context.Background(), owner, "old", []string{ScopeRead}, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := keys.Delete(context.Background(), "alice", revokedKey.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		header        string
+		value         string
+		after         time.Duration
+		expectedOK    bool
+		expectedError error
+	}{
+		{name: "header", method: "GET", header: APIKeyHeader, value: readOnly, expectedOK: true},
+		{name: "bearer", method: "POST", header: "Authorization", value: "Bearer " + readWrite, expectedOK: true},
+		{name: "other bearer token", method: "GET", header: "Authorization", value: "Bearer s3cr3t"},
+		{name: "no credentials", method: "GET"},
+		{name: "missing scope", method: "PATCH", header: APIKeyHeader, value: readOnly, expectedError: ErrForbidden},
+		{name: "wrong secret", method: "GET", header: APIKeyHeader, value: readOnly[:len(readOnly)-4] + "AAAA", expectedError: errors.New("wrong secret")},
+		{name: "malformed", method: "GET", header: APIKeyHeader, value: "gka_../../foo_bar", expectedError: errors.New("malformed API key")},
+		{name: "revoked", method: "GET", header: APIKeyHeader, value: revoked, expectedError: errors.New("unknown API key")},
+		{name: "expired", method: "GET", header: APIKeyHeader, value: readWrite, after: 2 * time.Hour, expectedError: errors.New("expired")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys.now = func() time.Time { return now.Add(tt.after) }
+			r := httptest.NewRequest(tt.method, "/deployments", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			principal, ok, err := keys.Authenticate(r)
+			switch {
+			case tt.expectedError == nil && err != nil:
+				t.Fatalf("Authenticate() error = %v", err)
+			case errors.Is(tt.expectedError, ErrForbidden) && !errors.Is(err, ErrForbidden):
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.expectedError)
+			case tt.expectedError != nil && (err == nil || !strings.Contains(err.Error(), tt.expectedError.Error())):
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.expectedError)
+			}
+			if ok != tt.expectedOK {
+				t.Fatalf("Authenticate() ok = %v, want %v", ok, tt.expectedOK)
+			}
+			if ok && (principal.Name != "alice" || !slices.Equal(principal.Groups, owner.Groups)) {
+				t.Errorf("Authenticate() principal = %+v, want %+v", principal, owner)
+			}
+		})
+	}
+}
+
+func TestAPIKeys_Ownership(t *testing.T) {
+	keys := NewAPIKeys(store.NewMemory())
+	alice, _, err := keys.Create(context.Background(), Principal{Name: "alice"}, "ci", []string{ScopeRead}, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := keys.Create(context.Background(), Principal{Name: "bob"}, "ci", []string{ScopeRead}, nil); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Every owner only sees its own keys, while the empty owner sees them all
+	for owner, expected := range map[string]int{"alice": 1, "bob": 1, "carol": 0, "": 2} {
+		list, err := keys.List(context.Background(), owner)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(list) != expected {
+			t.Errorf("List(%q) = %v keys, want %v", owner, len(list), expected)
+		}
+	}
+	if _, err := keys.Get(context.Background(), "bob", alice.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Get() of another owner's key error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+	if err := keys.Delete(context.Background(), "bob", alice.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Delete() of another owner's key error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+	if _, err := keys.Get(context.Background(), "", alice.ID); err != nil {
+		t.Errorf("Get() by an admin error = %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/clientca"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"k8s.io/klog/v2"
+)
+
+// Principal is the identity of an authenticated client, as contributed to the request context by the authenticator
+// of the chain that authenticated it
+type Principal struct {
+	// Name is the user name of the client, e.g. the Common Name of its client certificate
+	Name string
+	// Groups are the groups of the client, e.g. the Organizations of its client certificate
+	Groups []string
+	// Method is the name of the authenticator that authenticated the client, e.g. client-cert
+	Method string
+	// Credential is the credential the client authenticated with, when its failures are tracked apart from the
+	// identity, e.g. the ID of an API key
+	Credential string
+}
+
+// principalKey is the context key of the Principal of a request
+type principalKey struct{}
+
+// WithPrincipal returns a copy of the context holding the given Principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the Principal held by the context, if any
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// Identity returns the identity of the client that sent the request: the name of the Principal contributed by the
+// authentication chain, or else the Common Name of its verified client certificate. It returns an empty string if the
+// request wasn't authenticated.
+func Identity(r *http.Request) string {
+	if principal, ok := PrincipalFrom(r.Context()); ok {
+		return principal.Name
+	}
+	if principal, ok := clientCertificatePrincipal(r); ok {
+		return principal.Name
+	}
+	return ""
+}
+
+// ClientCA returns the name of the client CA which verified the client certificate of the request, i.e. of the root of
+// its verified chain, e.g. to tell the clients of the old and new CA apart during a rotation. It returns an empty string
+// if the request has no verified client certificate.
+func ClientCA(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	chain := r.TLS.VerifiedChains[0]
+	return clientca.Name(chain[len(chain)-1])
+}
+
+// RequireIdentity returns a new http.HandlerFunc that only calls the provided handler if the client's identity is one
+// of the allowed identities, and returns a 403 Forbidden otherwise
+func RequireIdentity(allowed []string, next http.HandlerFunc) http.HandlerFunc {
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, identity := range allowed {
+		allowedSet[identity] = true
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity := Identity(r)
+		if identity != "" && allowedSet[identity] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logger := klog.FromContext(r.Context())
+		logger.Info("Forbidden, identity is not allowed", "identity", identity)
+		writeMessage(w, logger, http.StatusForbidden, "Forbidden")
+	}
+}
+
+// WriteForbidden returns a 403 Forbidden
+func WriteForbidden(w http.ResponseWriter, logger klog.Logger) {
+	writeMessage(w, logger, http.StatusForbidden, "Forbidden")
+}
+
+func writeMessage(w http.ResponseWriter, logger klog.Logger, status int, message string) {
+	if encErr := problem.Write(w, status, message); encErr != nil {
+		logger.Error(encErr, "Error encoding response")
+	}
+}
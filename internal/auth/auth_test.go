@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTLSRequest creates a new http.Request authenticated with a client certificate with the given Common Name
+func newTLSRequest(commonName string) *http.Request {
+	r := httptest.NewRequest("GET", "/admin/loglevel", nil)
+	if commonName != "" {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+	return r
+}
+
+func TestRequireIdentity(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowed        []string
+		commonName     string
+		expectedStatus int
+	}{
+		{"Test RequireIdentity Allowed", []string{"admin", "ci-bot"}, "ci-bot", http.StatusOK},
+		{"Test RequireIdentity Not Allowed", []string{"admin"}, "ci-bot", http.StatusForbidden},
+		{"Test RequireIdentity No Client Certificate", []string{"admin"}, "", http.StatusForbidden},
+		{"Test RequireIdentity No Allowed Identities", nil, "admin", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := RequireIdentity(tt.allowed, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+			h(w, newTLSRequest(tt.commonName))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("RequireIdentity() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestClientCA(t *testing.T) {
+	leaf := &x509.Certificate{Subject: pkix.Name{CommonName: "ci-bot"}}
+	intermediate := &x509.Certificate{Subject: pkix.Name{CommonName: "Corp Issuing CA"}}
+	tests := []struct {
+		name     string
+		state    *tls.ConnectionState
+		expected string
+	}{
+		{"Test ClientCA Root Of The Chain", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf, intermediate, {Subject: pkix.Name{CommonName: "Corp Root CA 2024"}}}}}, "Corp Root CA 2024"},
+		{"Test ClientCA Without Common Name", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf, {Subject: pkix.Name{Organization: []string{"Corp"}}}}}}, "O=Corp"},
+		{"Test ClientCA No Client Certificate", &tls.ConnectionState{}, ""},
+		{"Test ClientCA No TLS", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/deployments", nil)
+			r.TLS = tt.state
+			if got := ClientCA(r); got != tt.expected {
+				t.Errorf("ClientCA() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Authenticator authenticates the client of a request. It returns false when the request doesn't carry its kind of
+// credentials, so that the next authenticator of the chain gets a chance, and an error when it carries invalid ones.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, bool, error)
+}
+
+// ErrForbidden is wrapped by the errors of the authenticators recognizing valid credentials which don't allow the
+// request, e.g. an API key lacking the write scope, which get a 403 Forbidden rather than a 401 Unauthorized
+var ErrForbidden = errors.New("forbidden")
+
+// Names of the authenticators
+const (
+	ClientCertificateAuthenticator = "client-cert"
+	TokenAuthenticator             = "token"
+	OIDCAuthenticator              = "oidc"
+	RequestHeaderAuthenticator     = "request-header"
+	APIKeyAuthenticator            = "apikey"
+	SessionAuthenticator           = "session"
+)
+
+// Config holds the settings of every authenticator, only the ones of the authenticators of a chain being required
+type Config struct {
+	// TokenFile is the path of the static token file of the token authenticator
+	TokenFile string
+	// OIDC holds the settings of the oidc authenticator
+	OIDC OIDCConfig
+	// RequestHeader holds the settings of the request-header authenticator
+	RequestHeader RequestHeader
+	// APIKeys holds the API keys of the apikey authenticator
+	APIKeys *APIKeys
+	// Session holds the settings of the session authenticator, on top of the OIDC ones
+	Session SessionConfig
+	// Sessions holds the browser sessions of the session authenticator
+	Sessions *Sessions
+}
+
+// AddFlags registers the flags of the settings of every authenticator on the given flag set
+func (c *Config) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&c.TokenFile, "token-auth-file", "", "path of the static token file of the token authenticator, with one token,user,uid,\"group1,group2\" line per token")
+	flagSet.StringVar(&c.OIDC.IssuerURL, "oidc-issuer-url", "", "URL of the OpenID Connect issuer of the ID tokens accepted by the oidc authenticator")
+	flagSet.StringVar(&c.OIDC.ClientID, "oidc-client-id", "", "client ID the ID tokens accepted by the oidc authenticator must be issued for")
+	flagSet.StringVar(&c.OIDC.UsernameClaim, "oidc-username-claim", "sub", "claim of the ID tokens holding the user name")
+	flagSet.StringVar(&c.OIDC.UsernamePrefix, "oidc-username-prefix", "oidc:", "prefix of the user names of the ID tokens, so that they don't clash with the client certificate Common Names")
+	flagSet.StringVar(&c.OIDC.GroupsClaim, "oidc-groups-claim", "groups", "claim of the ID tokens holding the groups")
+	flagSet.StringVar(&c.Session.ClientSecretFile, "oidc-client-secret-file", "", "path of the file holding the OIDC client secret, with which the session authenticator exchanges the authorization codes")
+	flagSet.StringVar(&c.Session.RedirectURL, "oidc-redirect-url", "", "URL of the "+CallbackPath+" endpoint, as registered with the OIDC issuer, e.g. https://api.example.com"+CallbackPath+". Required by the session authenticator")
+	c.Session.Scopes = []string{"openid", "email", "profile"}
+	flagSet.Func("oidc-scopes", "comma separated list of the scopes the session authenticator requests from the OIDC issuer (default openid,email,profile)", func(value string) error {
+		c.Session.Scopes = nil
+		for _, scope := range strings.Split(value, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				c.Session.Scopes = append(c.Session.Scopes, scope)
+			}
+		}
+		return nil
+	})
+	flagSet.StringVar(&c.Session.KeyFile, "session-key-file", "", "path of the file holding the base64 encoded 32 byte key encrypting the session cookies, e.g. generated by openssl rand -base64 32. A random key is generated when empty, so that the sessions don't survive restarts, nor are shared by the replicas")
+	flagSet.DurationVar(&c.Session.TTL, "session-ttl", 8*time.Hour, "how long the browser sessions of the session authenticator last, from the login")
+	flagSet.Func("requestheader-allowed-names", "comma separated list of the client certificate Common Names of the authenticating proxies trusted by the request-header authenticator. Required by the request-header authenticator, since any client with a verified certificate could otherwise impersonate anyone", func(value string) error {
+		c.RequestHeader.AllowedNames = nil
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				c.RequestHeader.AllowedNames = append(c.RequestHeader.AllowedNames, name)
+			}
+		}
+		return nil
+	})
+	flagSet.StringVar(&c.RequestHeader.UsernameHeader, "requestheader-username-header", "X-Remote-User", "header holding the user name set by the authenticating proxies")
+	flagSet.StringVar(&c.RequestHeader.GroupHeader, "requestheader-group-header", "X-Remote-Group", "header holding the groups set by the authenticating proxies")
+}
+
+// constructors are the constructors of the authenticators, by name. New schemes only need to register theirs.
+var constructors = map[string]func(cfg Config) (Authenticator, error){
+	ClientCertificateAuthenticator: func(Config) (Authenticator, error) {
+		return ClientCertificate{}, nil
+	},
+	TokenAuthenticator: func(cfg Config) (Authenticator, error) {
+		if cfg.TokenFile == "" {
+			return nil, fmt.Errorf("the %s authenticator requires a token file", TokenAuthenticator)
+		}
+		return LoadTokenFile(cfg.TokenFile)
+	},
+	OIDCAuthenticator: func(cfg Config) (Authenticator, error) {
+		if cfg.OIDC.IssuerURL == "" || cfg.OIDC.ClientID == "" {
+			return nil, fmt.Errorf("the %s authenticator requires an issuer URL and a client ID", OIDCAuthenticator)
+		}
+		return NewOIDC(cfg.OIDC, &http.Client{Timeout: 10 * time.Second}), nil
+	},
+	RequestHeaderAuthenticator: func(cfg Config) (Authenticator, error) {
+		if len(cfg.RequestHeader.AllowedNames) == 0 {
+			return nil, fmt.Errorf("the %s authenticator requires the allowed names of the authenticating proxies", RequestHeaderAuthenticator)
+		}
+		header := cfg.RequestHeader
+		return &header, nil
+	},
+	APIKeyAuthenticator: func(cfg Config) (Authenticator, error) {
+		if cfg.APIKeys == nil {
+			return nil, fmt.Errorf("the %s authenticator requires an API key store", APIKeyAuthenticator)
+		}
+		return cfg.APIKeys, nil
+	},
+	SessionAuthenticator: func(cfg Config) (Authenticator, error) {
+		if cfg.Sessions == nil {
+			return nil, fmt.Errorf("the %s authenticator requires an OIDC redirect URL", SessionAuthenticator)
+		}
+		return cfg.Sessions, nil
+	},
+}
+
+// Authenticators returns the names of the available authenticators, sorted
+func Authenticators() []string {
+	names := make([]string, 0, len(constructors))
+	for name := range constructors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// namedAuthenticator is an authenticator of a chain, along with its name
+type namedAuthenticator struct {
+	name string
+	Authenticator
+}
+
+// Chain is an ordered list of authenticators, the first one recognizing the credentials of a request authenticating
+// its client. An empty chain leaves the requests unauthenticated.
+type Chain struct {
+	authenticators []namedAuthenticator
+	// public are the paths served to unauthenticated clients, e.g. the login endpoints
+	public []string
+
+	// Guard records the authentication failures, and locks out the source IPs and identities failing too often, if set
+	Guard *Guard
+}
+
+// publicPaths is implemented by the authenticators serving endpoints to unauthenticated clients, e.g. a login
+type publicPaths interface {
+	PublicPaths() []string
+}
+
+// NewChain returns the chain of the given authenticators, in order, configured from cfg
+func NewChain(names []string, cfg Config) (*Chain, error) {
+	chain := &Chain{}
+	for _, name := range names {
+		constructor, ok := constructors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown authenticator %q, must be one of %s", name, strings.Join(Authenticators(), ", "))
+		}
+		authenticator, err := constructor(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up the %s authenticator: %w", name, err)
+		}
+		chain.authenticators = append(chain.authenticators, namedAuthenticator{name: name, Authenticator: authenticator})
+		if authenticator, ok := authenticator.(publicPaths); ok {
+			chain.public = append(chain.public, authenticator.PublicPaths()...)
+		}
+	}
+	return chain, nil
+}
+
+// Has returns whether the chain includes the authenticator of the given name
+func (c *Chain) Has(name string) bool {
+	return slices.ContainsFunc(c.authenticators, func(a namedAuthenticator) bool { return a.name == name })
+}
+
+// RequiresClientCertificate returns whether every request must present a client certificate, i.e. whether the chain
+// only authenticates client certificates
+func (c *Chain) RequiresClientCertificate() bool {
+	return len(c.authenticators) == 1 && c.Has(ClientCertificateAuthenticator)
+}
+
+// Middleware returns a new http.Handler that authenticates the client of every request with the chain, and adds its
+// Principal to the request context before calling the provided handler. It returns a 401 Unauthorized for the requests
+// carrying invalid credentials, or none of the ones recognized by the chain, and a 403 Forbidden for the requests their
+// credentials don't allow. The public paths of the authenticators, e.g. the login endpoints, are left unauthenticated.
+// With a Guard, the requests of the locked out source IPs and credentials get a 429 Too Many Requests.
+func (c *Chain) Middleware(next http.Handler) http.Handler {
+	if len(c.authenticators) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(c.public, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logger := klog.FromContext(r.Context())
+		source := sourceIP(r)
+		if c.lockedOut(w, logger, source, "") {
+			return
+		}
+		for _, authenticator := range c.authenticators {
+			principal, ok, err := authenticator.Authenticate(r)
+			if errors.Is(err, ErrForbidden) {
+				logger.Info("Forbidden, the credentials don't allow the request", "authenticator", authenticator.name, "err", err)
+				c.failure(source, failedIdentity(err), failedCredential(err), authenticator.name, FailureForbidden, err)
+				writeMessage(w, logger, http.StatusForbidden, "Forbidden")
+				return
+			}
+			if err != nil {
+				logger.Info("Unauthorized, invalid credentials", "authenticator", authenticator.name, "err", err)
+				c.failure(source, failedIdentity(err), failedCredential(err), authenticator.name, FailureInvalidCredentials, err)
+				c.writeUnauthorized(w, logger)
+				return
+			}
+			if ok {
+				if c.lockedOut(w, logger, "", principal.Credential) {
+					return
+				}
+				if c.Guard != nil {
+					c.Guard.Success(source, principal.Name, principal.Credential, authenticator.name)
+				}
+				principal.Method = authenticator.name
+				next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+				return
+			}
+		}
+		if carriesCredentials(r) {
+			logger.Info("Unauthorized, unrecognized credentials")
+			c.failure(source, "", "", "", FailureInvalidCredentials, errors.New("credentials not recognized by any authenticator"))
+		} else {
+			logger.Info("Unauthorized, no credentials")
+			c.failure(source, "", "", "", FailureNoCredentials, nil)
+		}
+		c.writeUnauthorized(w, logger)
+	})
+}
+
+func (c *Chain) failure(source, identity, credential, authenticator, reason string, err error) {
+	if c.Guard != nil {
+		c.Guard.Failure(source, identity, credential, authenticator, reason, err)
+	}
+}
+
+// lockedOut returns a 429 Too Many Requests and true if the given source IP or credential is locked out
+func (c *Chain) lockedOut(w http.ResponseWriter, logger klog.Logger, source, credential string) bool {
+	if c.Guard == nil {
+		return false
+	}
+	kind, remaining, locked := c.Guard.LockedOut(source, credential)
+	if !locked {
+		return false
+	}
+	logger.Info("Too many requests, locked out after too many authentication failures", "kind", kind, "source", source, "credential", credential, "remaining", remaining)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	writeMessage(w, logger, http.StatusTooManyRequests, "Too many authentication failures, retry later")
+	return true
+}
+
+func (c *Chain) writeUnauthorized(w http.ResponseWriter, logger klog.Logger) {
+	if c.Has(TokenAuthenticator) || c.Has(OIDCAuthenticator) || c.Has(APIKeyAuthenticator) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="go-k8s-http-api"`)
+	}
+	writeMessage(w, logger, http.StatusUnauthorized, "Unauthorized")
+}
+
+// ClientCertificate authenticates the clients by their verified client certificate, named after its Common Name and
+// part of the groups of its Organizations, like Kubernetes does
+type ClientCertificate struct{}
+
+// Authenticate implements Authenticator
+func (ClientCertificate) Authenticate(r *http.Request) (Principal, bool, error) {
+	principal, ok := clientCertificatePrincipal(r)
+	return principal, ok, nil
+}
+
+func clientCertificatePrincipal(r *http.Request) (Principal, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return Principal{}, false
+	}
+	subject := r.TLS.VerifiedChains[0][0].Subject
+	if subject.CommonName == "" {
+		return Principal{}, false
+	}
+	return Principal{Name: subject.CommonName, Groups: subject.Organization}, true
+}
+
+// RequestHeader authenticates the clients from the headers set by an authenticating proxy, e.g. X-Remote-User. The
+// headers are only trusted from a proxy authenticated by its verified client certificate, so that the clients can't
+// set them themselves.
+type RequestHeader struct {
+	// AllowedNames are the Common Names of the client certificates of the proxies. No proxy is trusted when empty, since
+	// the verified client certificates of the clients would be as well.
+	AllowedNames []string
+	// UsernameHeader is the header holding the user name, X-Remote-User if empty
+	UsernameHeader string
+	// GroupHeader is the header holding the groups, one per value, X-Remote-Group if empty
+	GroupHeader string
+}
+
+// Authenticate implements Authenticator
+func (h *RequestHeader) Authenticate(r *http.Request) (Principal, bool, error) {
+	usernameHeader, groupHeader := h.UsernameHeader, h.GroupHeader
+	if usernameHeader == "" {
+		usernameHeader = "X-Remote-User"
+	}
+	if groupHeader == "" {
+		groupHeader = "X-Remote-Group"
+	}
+	username := r.Header.Get(usernameHeader)
+	if username == "" {
+		return Principal{}, false, nil
+	}
+	proxy, ok := clientCertificatePrincipal(r)
+	if !ok {
+		return Principal{}, false, fmt.Errorf("the %s header is only trusted from an authenticating proxy", usernameHeader)
+	}
+	if !slices.Contains(h.AllowedNames, proxy.Name) {
+		return Principal{}, false, fmt.Errorf("%s is not an allowed authenticating proxy", proxy.Name)
+	}
+	return Principal{Name: username, Groups: r.Header.Values(groupHeader)}, true, nil
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewChain(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "tokens.csv")
+	if err := os.WriteFile(tokenFile, []byte("s3cr3t,ci-bot,1\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	tests := []struct {
+		name        string
+		names       []string
+		cfg         Config
+		expectedErr string
+	}{
+		{name: "empty chain"},
+		{name: "all the authenticators", names: []string{"request-header", "client-cert", "token", "oidc"}, cfg: Config{
+			TokenFile:     tokenFile,
+			OIDC:          OIDCConfig{IssuerURL: "https://issuer.example.com", ClientID: "k8s-api-proxy"},
+			RequestHeader: RequestHeader{AllowedNames: []string{"front-proxy"}},
+		}},
+		{name: "unknown authenticator", names: []string{"basic"}, expectedErr: `unknown authenticator "basic"`},
+		{name: "token without token file", names: []string{"token"}, expectedErr: "requires a token file"},
+		{name: "missing token file", names: []string{"token"}, cfg: Config{TokenFile: filepath.Join(t.TempDir(), "missing.csv")}, expectedErr: "failed to open token file"},
+		{name: "request-header without allowed names", names: []string{"request-header", "client-cert"}, expectedErr: "requires the allowed names"},
+		{name: "oidc without issuer", names: []string{"oidc"}, cfg: Config{OIDC: OIDCConfig{ClientID: "k8s-api-proxy"}}, expectedErr: "requires an issuer URL and a client ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := NewChain(tt.names, tt.cfg)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("NewChain() error = %v, want %v", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewChain() error = %v", err)
+			}
+			for _, name := range tt.names {
+				if !chain.Has(name) {
+					t.Errorf("Has(%v) = false, want true", name)
+				}
+			}
+		})
+	}
+}
+
+func TestChain_Middleware(t *testing.T) {
+	tokens, err := ParseTokens(strings.NewReader("s3cr3t,ci-bot,1,\"deployers,viewers\"\n"))
+	if err != nil {
+		t.Fatalf("ParseTokens() error = %v", err)
+	}
+	chain := &Chain{authenticators: []namedAuthenticator{
+		{name: RequestHeaderAuthenticator, Authenticator: &RequestHeader{AllowedNames: []string{"front-proxy"}}},
+		{name: ClientCertificateAuthenticator, Authenticator: ClientCertificate{}},
+		{name: TokenAuthenticator, Authenticator: tokens},
+	}}
+
+	tests := []struct {
+		name              string
+		commonName        string
+		headers           map[string]string
+		expectedStatus    int
+		expectedPrincipal Principal
+	}{
+		{
+			name:              "client certificate",
+			commonName:        "alice",
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: Principal{Name: "alice", Method: ClientCertificateAuthenticator},
+		},
+		{
+			name:              "bearer token",
+			headers:           map[string]string{"Authorization": "Bearer s3cr3t"},
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: Principal{Name: "ci-bot", Groups: []string{"deployers", "viewers"}, Method: TokenAuthenticator},
+		},
+		{
+			name:              "authenticating proxy",
+			commonName:        "front-proxy",
+			headers:           map[string]string{"X-Remote-User": "bob", "X-Remote-Group": "admins"},
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: Principal{Name: "bob", Groups: []string{"admins"}, Method: RequestHeaderAuthenticator},
+		},
+		{
+			name:           "untrusted proxy",
+			commonName:     "alice",
+			headers:        map[string]string{"X-Remote-User": "bob"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "proxy header without client certificate",
+			headers:        map[string]string{"X-Remote-User": "bob"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown token",
+			headers:        map[string]string{"Authorization": "Bearer guess"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "no credentials",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var principal Principal
+			h := chain.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, _ = PrincipalFrom(r.Context())
+				if identity := Identity(r); identity != principal.Name {
+					t.Errorf("Identity() = %v, want %v", identity, principal.Name)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			r := newTLSRequest(tt.commonName)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus == http.StatusUnauthorized {
+				if header := w.Header().Get("WWW-Authenticate"); !strings.HasPrefix(header, "Bearer") {
+					t.Errorf("WWW-Authenticate = %v, want a Bearer challenge", header)
+				}
+				return
+			}
+			if principal.Name != tt.expectedPrincipal.Name || principal.Method != tt.expectedPrincipal.Method || !slices.Equal(principal.Groups, tt.expectedPrincipal.Groups) {
+				t.Errorf("principal = %+v, want %+v", principal, tt.expectedPrincipal)
+			}
+		})
+	}
+}
+
+func TestRequestHeader_Authenticate(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowedNames []string
+		commonName   string
+		expectedOK   bool
+	}{
+		{"Test Allowed Proxy", []string{"front-proxy"}, "front-proxy", true},
+		{"Test Client Certificate", []string{"front-proxy"}, "alice", false},
+		{"Test Client Certificate Without Allowed Names", nil, "alice", false},
+		{"Test Proxy Without Allowed Names", nil, "front-proxy", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTLSRequest(tt.commonName)
+			r.Header.Set("X-Remote-User", "admin")
+			r.Header.Add("X-Remote-Group", "system:masters")
+			principal, ok, err := (&RequestHeader{AllowedNames: tt.allowedNames}).Authenticate(r)
+			if ok != tt.expectedOK || (err == nil) != tt.expectedOK {
+				t.Fatalf("Authenticate() = %+v, %v, %v, want ok %v", principal, ok, err, tt.expectedOK)
+			}
+			if ok && (principal.Name != "admin" || !slices.Equal(principal.Groups, []string{"system:masters"})) {
+				t.Errorf("Authenticate() principal = %+v, want the user and groups of the headers", principal)
+			}
+		})
+	}
+}
+
+func TestChain_Middleware_Empty(t *testing.T) {
+	// An empty chain leaves the requests unauthenticated
+	h := (&Chain{}).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if identity := Identity(r); identity != "" {
+			t.Errorf("Identity() = %v, want none", identity)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newTLSRequest(""))
+	if w.Code != http.StatusOK {
+		t.Errorf("status code = %v, want %v", w.Code, http.StatusOK)
+	}
+}
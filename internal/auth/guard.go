@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/ipfilter"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	failuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_auth_failures_total",
+		Help: "Number of requests which failed authentication, by authenticator and reason.",
+	}, []string{"authenticator", "reason"})
+	lockoutsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_auth_lockouts_total",
+		Help: "Number of lockouts of source IPs and credentials after too many authentication failures, by kind.",
+	}, []string{"kind"})
+	lockedOutRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_auth_locked_out_requests_total",
+		Help: "Number of requests rejected because their source IP or credential was locked out, by kind.",
+	}, []string{"kind"})
+	suspiciousSuccessesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_auth_suspicious_successes_total",
+		Help: "Number of successful authentications of source IPs or credentials which recently failed authentication repeatedly.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(failuresTotal, lockoutsTotal, lockedOutRequestsTotal, suspiciousSuccessesTotal)
+}
+
+// Reasons of the authentication failures
+const (
+	// FailureInvalidCredentials is the reason of the requests carrying invalid credentials, the only failures counting
+	// towards a lockout
+	FailureInvalidCredentials = "invalid_credentials"
+	// FailureNoCredentials is the reason of the requests carrying no credentials at all, e.g. the first request of a
+	// client waiting for the WWW-Authenticate challenge
+	FailureNoCredentials = "no_credentials"
+	// FailureForbidden is the reason of the requests whose valid credentials don't allow them
+	FailureForbidden = "forbidden"
+)
+
+// Kinds of the lockouts
+const (
+	LockoutSource     = "source"
+	LockoutCredential = "credential"
+)
+
+// Types of the security events
+const (
+	// EventAuthFailure is a request which carried invalid credentials, or credentials which don't allow it
+	EventAuthFailure = "auth_failure"
+	// EventLockout is a source IP or a credential locked out after too many failures
+	EventLockout = "lockout"
+	// EventSuspiciousSuccess is the successful authentication of a source IP or credential which recently failed
+	// authentication repeatedly, e.g. a guessed credential
+	EventSuspiciousSuccess = "suspicious_success"
+)
+
+// SecurityEvent is an event of the security events feed
+type SecurityEvent struct {
+	// ID increases with every event, so that the feed can be polled for the events after the latest one seen
+	ID   int64     `json:"id"`
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	// Source is the IP address the request came from, empty for the Unix domain socket
+	Source string `json:"source,omitempty"`
+	// Identity is the identity the request authenticated as, when known
+	Identity string `json:"identity,omitempty"`
+	// Credential is the credential the request authenticated with, or attempted to, when its failures are tracked,
+	// e.g. the ID of an API key
+	Credential    string `json:"credential,omitempty"`
+	Authenticator string `json:"authenticator,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	// Error is the reason the authenticator rejected the credentials
+	Error string `json:"error,omitempty"`
+	// Failures is the number of failures within the window, for the lockouts and the suspicious successes
+	Failures int `json:"failures,omitempty"`
+	// LockedUntil is the end of a lockout
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"`
+}
+
+// IdentityError is an authentication error of credentials which proved the identity they authenticate as, but don't
+// allow the request, e.g. an API key lacking a scope, so that the failures name the identity
+type IdentityError struct {
+	Identity string
+	Err      error
+}
+
+func (e *IdentityError) Error() string { return e.Err.Error() }
+
+func (e *IdentityError) Unwrap() error { return e.Err }
+
+// CredentialError is an authentication error of a known credential whose secret wasn't proven, e.g. an API key with a
+// wrong secret, so that the failures are tracked for the credential as well. They're never charged to the identity the
+// credential belongs to, since anyone may send it, which would lock its owner out of every authenticator.
+type CredentialError struct {
+	Credential string
+	Err        error
+}
+
+func (e *CredentialError) Error() string { return e.Err.Error() }
+
+func (e *CredentialError) Unwrap() error { return e.Err }
+
+// GuardConfig holds the settings of a Guard
+type GuardConfig struct {
+	// Threshold is the number of failures within the window from which a source IP or credential is locked out. Lockouts
+	// are disabled when 0.
+	Threshold int
+	// Window is the sliding window the failures are counted over
+	Window time.Duration
+	// Duration is how long a lockout lasts
+	Duration time.Duration
+	// EventsSize is the number of events kept in the security events feed
+	EventsSize int
+}
+
+// maxTrackedFailures is the maximum number of failures kept per source IP or credential, on top of the lockout threshold
+const maxTrackedFailures = 100
+
+// tracker tracks the recent failures of a source IP or credential
+type tracker struct {
+	failures    []time.Time
+	lockedUntil time.Time
+	// flaggedAt is the time of the latest suspicious success, reported at most once per window
+	flaggedAt time.Time
+}
+
+// Guard tracks the authentication failures per source IP and per credential, locks out the ones failing too often with a
+// 429 Too Many Requests, and keeps a feed of the security events in memory
+type Guard struct {
+	config GuardConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	trackers  map[string]*tracker
+	prunedAt  time.Time
+	events    []SecurityEvent
+	lastEvent int64
+}
+
+// NewGuard returns a new Guard
+func NewGuard(config GuardConfig) *Guard {
+	return &Guard{config: config, now: time.Now, trackers: map[string]*tracker{}}
+}
+
+// LockedOut returns whether the given source IP or credential is locked out, along with the kind of the lockout and
+// how long it remains. Empty ones are ignored.
+func (g *Guard) LockedOut(source, credential string) (string, time.Duration, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	for _, k := range trackerKeys(source, credential) {
+		if t, ok := g.trackers[k.key]; ok && now.Before(t.lockedUntil) {
+			lockedOutRequestsTotal.WithLabelValues(k.kind).Inc()
+			return k.kind, t.lockedUntil.Sub(now), true
+		}
+	}
+	return "", 0, false
+}
+
+// Failure records an authentication failure of the given source IP, and of the given identity and credential, if known.
+// Only the invalid credentials count towards a lockout, of the source IP and the credential, and the requests without
+// credentials aren't added to the feed.
+func (g *Guard) Failure(source, identity, credential, authenticator, reason string, err error) {
+	failuresTotal.WithLabelValues(authenticator, reason).Inc()
+	if reason == FailureNoCredentials {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	event := SecurityEvent{Time: now, Type: EventAuthFailure, Source: source, Identity: identity, Credential: credential, Authenticator: authenticator, Reason: reason}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	g.record(event)
+	if reason != FailureInvalidCredentials {
+		return
+	}
+
+	g.prune(now)
+	for _, k := range trackerKeys(source, credential) {
+		t := g.trackers[k.key]
+		if t == nil {
+			t = &tracker{}
+			g.trackers[k.key] = t
+		}
+		t.failures = append(g.recent(t, now), now)
+		if limit := max(g.config.Threshold, maxTrackedFailures); len(t.failures) > limit {
+			t.failures = t.failures[len(t.failures)-limit:]
+		}
+		if g.config.Threshold > 0 && len(t.failures) >= g.config.Threshold && !now.Before(t.lockedUntil) {
+			t.lockedUntil = now.Add(g.config.Duration)
+			lockoutsTotal.WithLabelValues(k.kind).Inc()
+			lockedUntil := t.lockedUntil
+			lockout := SecurityEvent{Time: now, Type: EventLockout, Failures: len(t.failures), LockedUntil: &lockedUntil}
+			if k.kind == LockoutSource {
+				lockout.Source = source
+			} else {
+				lockout.Credential = credential
+			}
+			g.record(lockout)
+		}
+	}
+}
+
+// Success records the successful authentication of the given source IP as the given identity, with the given credential
+// if tracked. It's reported as suspicious when the source IP or the credential recently failed at least half the lockout
+// threshold times.
+func (g *Guard) Success(source, identity, credential, authenticator string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	threshold := max(g.config.Threshold/2, 1)
+	for _, k := range trackerKeys(source, credential) {
+		t, ok := g.trackers[k.key]
+		if !ok {
+			continue
+		}
+		failures := len(g.recent(t, now))
+		if failures < threshold || now.Sub(t.flaggedAt) < g.config.Window {
+			continue
+		}
+		t.flaggedAt = now
+		suspiciousSuccessesTotal.Inc()
+		g.record(SecurityEvent{Time: now, Type: EventSuspiciousSuccess, Source: source, Identity: identity, Credential: credential, Authenticator: authenticator, Failures: failures})
+		return
+	}
+}
+
+// Events returns the events of the feed after the given ID, oldest first, of the given type if not empty
+func (g *Guard) Events(after int64, eventType string) []SecurityEvent {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	events := []SecurityEvent{}
+	for _, event := range g.events {
+		if event.ID > after && (eventType == "" || event.Type == eventType) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// record adds an event to the feed, evicting the oldest one once full. It must be called with the lock held.
+func (g *Guard) record(event SecurityEvent) {
+	if g.config.EventsSize <= 0 {
+		return
+	}
+	g.lastEvent++
+	event.ID = g.lastEvent
+	if len(g.events) == g.config.EventsSize {
+		copy(g.events, g.events[1:])
+		g.events = g.events[:len(g.events)-1]
+	}
+	g.events = append(g.events, event)
+}
+
+// recent returns the failures of the tracker within the window
+func (g *Guard) recent(t *tracker, now time.Time) []time.Time {
+	i := 0
+	for i < len(t.failures) && now.Sub(t.failures[i]) >= g.config.Window {
+		i++
+	}
+	return t.failures[i:]
+}
+
+// prune forgets the trackers without failures within the window nor a running lockout, at most once per window, so
+// that the memory doesn't grow with every source IP ever seen. It must be called with the lock held.
+func (g *Guard) prune(now time.Time) {
+	if now.Sub(g.prunedAt) < g.config.Window {
+		return
+	}
+	g.prunedAt = now
+	for key, t := range g.trackers {
+		if len(g.recent(t, now)) == 0 && !now.Before(t.lockedUntil) {
+			delete(g.trackers, key)
+		}
+	}
+}
+
+// trackerKey is the key of the tracker of a source IP or credential, along with the kind of its lockouts
+type trackerKey struct {
+	kind string
+	key  string
+}
+
+// trackerKeys returns the keys of the trackers of the given source IP and credential, the empty ones being left out
+func trackerKeys(source, credential string) []trackerKey {
+	var keys []trackerKey
+	if source != "" {
+		keys = append(keys, trackerKey{kind: LockoutSource, key: "source:" + source})
+	}
+	if credential != "" {
+		keys = append(keys, trackerKey{kind: LockoutCredential, key: "credential:" + credential})
+	}
+	return keys
+}
+
+// sourceIP returns the IP address the request came from: the client IP resolved by the IP filter behind trusted
+// proxies, or else the peer address of the connection. It's empty for the Unix domain socket.
+func sourceIP(r *http.Request) string {
+	if addr, ok := ipfilter.ClientIPFrom(r.Context()); ok {
+		return addr.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// failedIdentity returns the identity the failed credentials proved, if known
+func failedIdentity(err error) string {
+	var identityErr *IdentityError
+	if errors.As(err, &identityErr) {
+		return identityErr.Identity
+	}
+	return ""
+}
+
+// failedCredential returns the known credential the request failed with, if any
+func failedCredential(err error) string {
+	var credentialErr *CredentialError
+	if errors.As(err, &credentialErr) {
+		return credentialErr.Credential
+	}
+	return ""
+}
+
+// carriesCredentials returns whether the request carries a bearer token or an API key, so that the ones none of the
+// chain recognized, e.g. guessed tokens, are told from the requests without any. Stale session cookies, e.g. after a
+// restart, aren't counted, since browsers keep sending them until the next login.
+func carriesCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get(APIKeyHeader) != ""
+}
@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+)
+
+func TestGuard(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	type attempt struct {
+		// at is the time of the attempt, from the start
+		at         time.Duration
+		source     string
+		credential string
+		reason     string
+		// success is a successful authentication, rather than a failure
+		success bool
+	}
+	tests := []struct {
+		name           string
+		attempts       []attempt
+		source         string
+		credential     string
+		expectedLocked string
+		expectedEvents []string
+	}{
+		{
+			name:           "under the threshold",
+			attempts:       []attempt{{0, "203.0.113.7", "", FailureInvalidCredentials, false}, {time.Second, "203.0.113.7", "", FailureInvalidCredentials, false}},
+			source:         "203.0.113.7",
+			expectedEvents: []string{EventAuthFailure, EventAuthFailure},
+		},
+		{
+			name: "source locked out",
+			attempts: []attempt{
+				{0, "203.0.113.7", "", FailureInvalidCredentials, false},
+				{time.Second, "203.0.113.7", "", FailureInvalidCredentials, false},
+				{2 * time.Second, "203.0.113.7", "", FailureInvalidCredentials, false},
+			},
+			source:         "203.0.113.7",
+			expectedLocked: LockoutSource,
+			expectedEvents: []string{EventAuthFailure, EventAuthFailure, EventAuthFailure, EventLockout},
+		},
+		{
+			name: "credential locked out across sources",
+			attempts: []attempt{
+				{0, "203.0.113.7", "key-a", FailureInvalidCredentials, false},
+				{time.Second, "203.0.113.8", "key-a", FailureInvalidCredentials, false},
+				{2 * time.Second, "203.0.113.9", "key-a", FailureInvalidCredentials, false},
+			},
+			source:         "198.51.100.1",
+			credential:     "key-a",
+			expectedLocked: LockoutCredential,
+			expectedEvents: []string{EventAuthFailure, EventAuthFailure, EventAuthFailure, EventLockout},
+		},
+		{
+			name: "failures out of the window",
+			attempts: []attempt{
+				{0, "203.0.113.7", "", FailureInvalidCredentials, false},
+				{time.Second, "203.0.113.7", "", FailureInvalidCredentials, false},
+				{2 * time.Minute, "203.0.113.7", "", FailureInvalidCredentials, false},
+			},
+			source:         "203.0.113.7",
+			expectedEvents: []string{EventAuthFailure, EventAuthFailure, EventAuthFailure},
+		},
+		{
+			name: "lockout over",
+			attempts: []attempt{
+				{0, "203.0.113.7", "", FailureInvalidCredentials, false},
+				{time.Second, "203.0.113.7", "", FailureInvalidCredentials, false},
+				{2 * time.Second, "203.0.113.7", "", FailureInvalidCredentials, false},
+				{10 * time.Minute, "198.51.100.1", "", FailureNoCredentials, false},
+			},
+			source:         "203.0.113.7",
+			expectedEvents: []string{EventAuthFailure, EventAuthFailure, EventAuthFailure, EventLockout},
+		},
+		{
+			name: "forbidden and missing credentials don't count",
+			attempts: []attempt{
+				{0, "203.0.113.7", "key-a", FailureForbidden, false},
+				{time.Second, "203.0.113.7", "key-a", FailureForbidden, false},
+				{2 * time.Second, "203.0.113.7", "", FailureNoCredentials, false},
+				{3 * time.Second, "203.0.113.7", "", FailureNoCredentials, false},
+			},
+			source:         "203.0.113.7",
+			credential:     "key-a",
+			expectedEvents: []string{EventAuthFailure, EventAuthFailure},
+		},
+		{
+			name: "suspicious success",
+			attempts: []attempt{
+				{0, "203.0.113.7", "", FailureInvalidCredentials, false},
+				{time.Second, "203.0.113.7", "key-b", "", true},
+				{2 * time.Second, "203.0.113.7", "key-b", "", true},
+			},
+			source:         "203.0.113.7",
+			expectedEvents: []string{EventAuthFailure, EventSuspiciousSuccess},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGuard(GuardConfig{Threshold: 3, Window: time.Minute, Duration: 5 * time.Minute, EventsSize: 10})
+			now := start
+			g.now = func() time.Time { return now }
+			for _, a := range tt.attempts {
+				now = start.Add(a.at)
+				if a.success {
+					g.Success(a.source, "alice", a.credential, APIKeyAuthenticator)
+				} else {
+					g.Failure(a.source, "", a.credential, APIKeyAuthenticator, a.reason, errors.New("wrong secret"))
+				}
+			}
+
+			kind, _, locked := g.LockedOut(tt.source, tt.credential)
+			if kind != tt.expectedLocked || locked != (tt.expectedLocked != "") {
+				t.Errorf("LockedOut() = %v, %v, want %v", kind, locked, tt.expectedLocked)
+			}
+			events := g.Events(0, "")
+			if len(events) != len(tt.expectedEvents) {
+				t.Fatalf("Events() = %+v, want %v", events, tt.expectedEvents)
+			}
+			for i, event := range events {
+				if event.Type != tt.expectedEvents[i] || event.ID != int64(i+1) {
+					t.Errorf("Events()[%d] = %+v, want a %v event of ID %d", i, event, tt.expectedEvents[i], i+1)
+				}
+			}
+			if after := g.Events(1, ""); len(after) != max(len(events)-1, 0) {
+				t.Errorf("Events(1) = %+v, want the events after the first one", after)
+			}
+		})
+	}
+}
+
+func TestChain_Guard(t *testing.T) {
+	tokens, err := ParseTokens(strings.NewReader("s3cr3t,alice,1000\n"))
+	if err != nil {
+		t.Fatalf("ParseTokens() error = %v", err)
+	}
+	chain := &Chain{
+		authenticators: []namedAuthenticator{{name: TokenAuthenticator, Authenticator: tokens}},
+		Guard:          NewGuard(GuardConfig{Threshold: 2, Window: time.Minute, Duration: time.Minute, EventsSize: 10}),
+	}
+	handler := chain.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		token          string
+		expectedStatus int
+	}{
+		{"Test No Credentials", "203.0.113.7:1234", "", http.StatusUnauthorized},
+		{"Test Valid Token", "203.0.113.7:1234", "s3cr3t", http.StatusOK},
+		{"Test Guessed Token", "203.0.113.7:1234", "guess1", http.StatusUnauthorized},
+		{"Test Guessed Token Again", "203.0.113.7:1234", "guess2", http.StatusUnauthorized},
+		{"Test Locked Out", "203.0.113.7:1234", "s3cr3t", http.StatusTooManyRequests},
+		{"Test Other Source", "198.51.100.1:1234", "s3cr3t", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/deployments", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "60" {
+				t.Errorf("Retry-After = %v, want 60", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestChain_Guard_APIKeys(t *testing.T) {
+	keys := NewAPIKeys(store.NewMemory())
+	alice := Principal{Name: "alice"}
+	_, guessed, err := keys.Create(context.Background(), alice, "ci", []string{ScopeRead}, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	_, other, err := keys.Create(context.Background(), alice, "dashboard", []string{ScopeRead}, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	tokens, err := ParseTokens(strings.NewReader("s3cr3t,alice,1000\n"))
+	if err != nil {
+		t.Fatalf("ParseTokens() error = %v", err)
+	}
+	chain := &Chain{
+		authenticators: []namedAuthenticator{{name: APIKeyAuthenticator, Authenticator: keys}, {name: TokenAuthenticator, Authenticator: tokens}},
+		Guard:          NewGuard(GuardConfig{Threshold: 2, Window: time.Minute, Duration: time.Minute, EventsSize: 10}),
+	}
+	handler := chain.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	wrongSecret := guessed[:len(guessed)-4] + "AAAA"
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		method         string
+		credential     string
+		expectedStatus int
+	}{
+		{"Test Wrong Secret", "203.0.113.7:1234", "GET", wrongSecret, http.StatusUnauthorized},
+		{"Test Wrong Secret From Another Source", "203.0.113.8:1234", "GET", wrongSecret, http.StatusUnauthorized},
+		{"Test Key Locked Out", "198.51.100.1:1234", "GET", guessed, http.StatusTooManyRequests},
+		{"Test Other Key Of The Owner", "198.51.100.1:1234", "GET", other, http.StatusOK},
+		{"Test Token Of The Owner", "198.51.100.1:1234", "GET", "s3cr3t", http.StatusOK},
+		{"Test Missing Scope", "192.0.2.1:1234", "PATCH", other, http.StatusForbidden},
+		{"Test Missing Scope Again", "192.0.2.1:1234", "PATCH", other, http.StatusForbidden},
+		{"Test Missing Scope Doesn't Lock Out", "192.0.2.1:1234", "GET", other, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/deployments", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("Authorization", "Bearer "+tt.credential)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+		})
+	}
+
+	// The failures with a wrong secret name the key rather than its owner
+	for _, event := range chain.Guard.Events(0, EventAuthFailure) {
+		if event.Reason == FailureInvalidCredentials && (event.Identity != "" || event.Credential == "") {
+			t.Errorf("event = %+v, want the key without its owner", event)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/klog/v2"
+)
+
+// Resource is a resource of the Kubernetes API, as authorized by RBAC
+type Resource struct {
+	Group       string
+	Resource    string
+	Subresource string
+}
+
+// String returns the resource in the resource[/subresource].group format
+func (r Resource) String() string {
+	name := r.Resource
+	if r.Subresource != "" {
+		name += "/" + r.Subresource
+	}
+	if r.Group != "" {
+		name += "." + r.Group
+	}
+	return name
+}
+
+// Deployments is the resource of the deployments, which most of the namespace scoped routes act on
+var Deployments = Resource{Group: "apps", Resource: "deployments"}
+
+// NamespaceAuthorizer decides whether an identity may perform a verb on a resource in a namespace
+type NamespaceAuthorizer interface {
+	Authorize(ctx context.Context, identity, verb string, resource Resource, namespace string) (bool, error)
+}
+
+// decision is a cached authorization decision
+type decision struct {
+	allowed   bool
+	expiresAt time.Time
+}
+
+// SubjectAccessReviewAuthorizer authorizes identities via SubjectAccessReviews, so that the access of every client
+// certificate Common Name (as a Kubernetes user) is managed with the cluster's RBAC.
+// Decisions are cached for the given TTL, to avoid a round trip to the API server on every request.
+type SubjectAccessReviewAuthorizer struct {
+	Client authorizationv1client.SubjectAccessReviewsGetter
+	TTL    time.Duration
+
+	mu        sync.Mutex
+	decisions map[string]decision
+	lastPrune time.Time
+}
+
+// Authorize runs a SubjectAccessReview for the identity, unless a decision is cached for it. The groups of the Principal
+// of the context are reviewed along with it, e.g. the groups of an OIDC ID token.
+func (a *SubjectAccessReviewAuthorizer) Authorize(ctx context.Context, identity, verb string, resource Resource, namespace string) (bool, error) {
+	var groups []string
+	if principal, ok := PrincipalFrom(ctx); ok && principal.Name == identity {
+		groups = principal.Groups
+	}
+	key := identity + "/" + strings.Join(groups, ",") + "/" + verb + "/" + resource.String() + "/" + namespace
+	a.mu.Lock()
+	if cached, ok := a.decisions[key]; ok && time.Now().Before(cached.expiresAt) {
+		a.mu.Unlock()
+		return cached.allowed, nil
+	}
+	a.mu.Unlock()
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   identity,
+			Groups: groups,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        verb,
+				Group:       resource.Group,
+				Resource:    resource.Resource,
+				Subresource: resource.Subresource,
+			},
+		},
+	}
+	response, err := a.Client.SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to review the access of %s: %w", identity, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.decisions == nil {
+		a.decisions = map[string]decision{}
+	}
+	now := time.Now()
+	a.pruneExpired(now)
+	a.decisions[key] = decision{allowed: response.Status.Allowed, expiresAt: now.Add(a.TTL)}
+	return response.Status.Allowed, nil
+}
+
+// pruneExpired removes the expired decisions, at most once per TTL, so that the decisions of the identities which
+// stopped sending requests don't pile up. Must be called with the lock held.
+func (a *SubjectAccessReviewAuthorizer) pruneExpired(now time.Time) {
+	if now.Sub(a.lastPrune) < a.TTL {
+		return
+	}
+	a.lastPrune = now
+	for key, cached := range a.decisions {
+		if !now.Before(cached.expiresAt) {
+			delete(a.decisions, key)
+		}
+	}
+}
+
+// RequireNamespaceAccess returns a new http.HandlerFunc that only calls the provided handler if the client's identity
+// may perform the given verb on every one of the given resources in the namespace of the request path, and returns a
+// 403 Forbidden otherwise
+func RequireNamespaceAccess(authorizer NamespaceAuthorizer, verb string, resources []Resource, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity := Identity(r)
+		namespace := r.PathValue("namespace")
+		logger := klog.FromContext(r.Context())
+
+		if identity == "" || namespace == "" || len(resources) == 0 {
+			logger.Info("Forbidden, identity may not access the namespace", "identity", identity, "namespace", namespace, "verb", verb)
+			writeMessage(w, logger, http.StatusForbidden, "Forbidden")
+			return
+		}
+		for _, resource := range resources {
+			allowed, err := authorizer.Authorize(r.Context(), identity, verb, resource, namespace)
+			if err != nil {
+				logger.Error(err, "Error authorizing request", "namespace", namespace, "verb", verb, "resource", resource)
+				writeMessage(w, logger, http.StatusInternalServerError, "Error authorizing request")
+				return
+			}
+			if !allowed {
+				logger.Info("Forbidden, identity may not access the namespace", "identity", identity, "namespace", namespace, "verb", verb, "resource", resource)
+				writeMessage(w, logger, http.StatusForbidden, "Forbidden")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeAuthorizer allows the identities listed for a namespace, on the deployments and on the listed resources
+type fakeAuthorizer struct {
+	allowed   map[string][]string
+	resources []Resource
+	err       error
+}
+
+func (a *fakeAuthorizer) Authorize(_ context.Context, identity, _ string, resource Resource, namespace string) (bool, error) {
+	if resource != Deployments && !slices.Contains(a.resources, resource) {
+		return false, a.err
+	}
+	for _, allowed := range a.allowed[namespace] {
+		if allowed == identity {
+			return true, a.err
+		}
+	}
+	return false, a.err
+}
+
+func TestRequireNamespaceAccess(t *testing.T) {
+	authorizer := &fakeAuthorizer{allowed: map[string][]string{"team-a": {"alice"}}}
+	statefulSets := Resource{Group: "apps", Resource: "statefulsets"}
+	tests := []struct {
+		name           string
+		authorizer     NamespaceAuthorizer
+		resources      []Resource
+		commonName     string
+		namespace      string
+		expectedStatus int
+	}{
+		{"Test RequireNamespaceAccess Allowed", authorizer, []Resource{Deployments}, "alice", "team-a", http.StatusOK},
+		{"Test RequireNamespaceAccess Other Namespace", authorizer, []Resource{Deployments}, "alice", "team-b", http.StatusForbidden},
+		{"Test RequireNamespaceAccess Other Identity", authorizer, []Resource{Deployments}, "bob", "team-a", http.StatusForbidden},
+		{"Test RequireNamespaceAccess No Client Certificate", authorizer, []Resource{Deployments}, "", "team-a", http.StatusForbidden},
+		{"Test RequireNamespaceAccess Error", &fakeAuthorizer{err: errors.New("boom")}, []Resource{Deployments}, "alice", "team-a", http.StatusInternalServerError},
+		{"Test RequireNamespaceAccess Other Resource", authorizer, []Resource{statefulSets}, "alice", "team-a", http.StatusForbidden},
+		{"Test RequireNamespaceAccess Some Resources", authorizer, []Resource{Deployments, statefulSets}, "alice", "team-a", http.StatusForbidden},
+		{"Test RequireNamespaceAccess All Resources", &fakeAuthorizer{allowed: authorizer.allowed, resources: []Resource{statefulSets}},
+			[]Resource{Deployments, statefulSets}, "alice", "team-a", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /namespaces/{namespace}/deployments", RequireNamespaceAccess(tt.authorizer, "list", tt.resources, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			r := newTLSRequest(tt.commonName)
+			r.URL.Path = "/namespaces/" + tt.namespace + "/deployments"
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("RequireNamespaceAccess() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestSubjectAccessReviewAuthorizer(t *testing.T) {
+	reviews := 0
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == "alice" && attributes.Namespace == "team-a" &&
+			(attributes.Verb == "list" && attributes.Group == "apps" && attributes.Resource == "deployments" ||
+				attributes.Verb == "create" && attributes.Resource == "pods" && attributes.Subresource == "eviction")
+		return true, review, nil
+	})
+	authorizer := &SubjectAccessReviewAuthorizer{Client: client.AuthorizationV1(), TTL: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		allowed, err := authorizer.Authorize(context.Background(), "alice", "list", Deployments, "team-a")
+		if err != nil || !allowed {
+			t.Errorf("expected alice to be allowed, got %v, %v", allowed, err)
+		}
+	}
+	if reviews != 1 {
+		t.Errorf("expected the decision to be cached, got %d reviews", reviews)
+	}
+
+	allowed, err := authorizer.Authorize(context.Background(), "alice", "patch", Deployments, "team-a")
+	if err != nil || allowed {
+		t.Errorf("expected alice not to be allowed to patch, got %v, %v", allowed, err)
+	}
+
+	// The resource is reviewed as is, rather than as the deployments
+	evictions := Resource{Resource: "pods", Subresource: "eviction"}
+	allowed, err = authorizer.Authorize(context.Background(), "alice", "create", evictions, "team-a")
+	if err != nil || !allowed {
+		t.Errorf("expected alice to be allowed to evict pods, got %v, %v", allowed, err)
+	}
+	allowed, err = authorizer.Authorize(context.Background(), "alice", "list", evictions, "team-a")
+	if err != nil || allowed {
+		t.Errorf("expected alice not to be allowed to list evictions, got %v, %v", allowed, err)
+	}
+}
+
+func TestSubjectAccessReviewAuthorizer_Prune(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, action.(k8stesting.CreateAction).GetObject(), nil
+	})
+	authorizer := &SubjectAccessReviewAuthorizer{Client: client.AuthorizationV1(), TTL: time.Millisecond}
+	for _, identity := range []string{"alice", "bob", "carol"} {
+		if _, err := authorizer.Authorize(context.Background(), identity, "list", Deployments, "team-a"); err != nil {
+			t.Fatalf("Authorize() error = %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// The expired decisions are removed as new ones are cached
+	authorizer.mu.Lock()
+	defer authorizer.mu.Unlock()
+	if len(authorizer.decisions) != 1 {
+		t.Errorf("expected only the last decision to be kept, got %d decisions", len(authorizer.decisions))
+	}
+}
@@ -0,0 +1,341 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval is the minimum interval between two fetches of the signing keys of the issuer, which are fetched
+// again on a token signed by an unknown key, e.g. after a key rotation
+const jwksRefreshInterval = time.Minute
+
+// clockSkew is the clock skew tolerated on the expiry and not before times of the tokens
+const clockSkew = 30 * time.Second
+
+// OIDCConfig holds the settings of the OIDC authenticator
+type OIDCConfig struct {
+	// IssuerURL is the URL of the OpenID Connect issuer, which must match the iss claim of the ID tokens
+	IssuerURL string
+	// ClientID is the client ID the ID tokens must be issued for, i.e. their audience
+	ClientID string
+	// UsernameClaim is the claim holding the user name, sub if empty. The email claim is only trusted when verified.
+	UsernameClaim string
+	// UsernamePrefix is prepended to the user names, so that they don't clash with the ones of the other
+	// authenticators, e.g. oidc:
+	UsernamePrefix string
+	// GroupsClaim is the claim holding the groups, groups if empty
+	GroupsClaim string
+}
+
+// OIDC authenticates the clients by an ID token of an OpenID Connect issuer, passed as a bearer token. The signing keys
+// of the issuer are discovered from its /.well-known/openid-configuration.
+type OIDC struct {
+	config OIDCConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// discovery holds the endpoints of the issuer, out of its /.well-known/openid-configuration
+type discovery struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// errNotIDToken is returned for the tokens which aren't JSON Web Tokens of the issuer
+var errNotIDToken = errors.New("not an ID token of the issuer")
+
+// NewOIDC returns a new OIDC authenticator, fetching the discovery document and the signing keys of the issuer with the
+// given client
+func NewOIDC(config OIDCConfig, client *http.Client) *OIDC {
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "sub"
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	config.IssuerURL = strings.TrimSuffix(config.IssuerURL, "/")
+	return &OIDC{config: config, client: client, now: time.Now}
+}
+
+// jwtHeader is the header of a JSON Web Token
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Authenticate implements Authenticator. The bearer tokens that aren't JSON Web Tokens of the issuer are left to the
+// next authenticators.
+func (o *OIDC) Authenticate(r *http.Request) (Principal, bool, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return Principal{}, false, nil
+	}
+	principal, _, err := o.Verify(r.Context(), token)
+	if errors.Is(err, errNotIDToken) {
+		return Principal{}, false, nil
+	}
+	if err != nil {
+		return Principal{}, false, err
+	}
+	return principal, true, nil
+}
+
+// Verify verifies the signature, the audience and the validity period of an ID token of the issuer, and returns its
+// Principal along with its claims
+func (o *OIDC) Verify(ctx context.Context, token string) (Principal, map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, nil, errNotIDToken
+	}
+	var header jwtHeader
+	var claims map[string]any
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, nil, errNotIDToken
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, nil, errNotIDToken
+	}
+	if issuer, _ := claims["iss"].(string); strings.TrimSuffix(issuer, "/") != o.config.IssuerURL {
+		return Principal{}, nil, errNotIDToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, nil, fmt.Errorf("malformed ID token signature: %w", err)
+	}
+	key, err := o.key(ctx, header.KeyID)
+	if err != nil {
+		return Principal{}, nil, err
+	}
+	if err := verifySignature(header.Algorithm, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Principal{}, nil, err
+	}
+	if err := o.validate(claims); err != nil {
+		return Principal{}, nil, err
+	}
+
+	username, _ := claims[o.config.UsernameClaim].(string)
+	if username == "" {
+		return Principal{}, nil, fmt.Errorf("the ID token has no %s claim", o.config.UsernameClaim)
+	}
+	if o.config.UsernameClaim == "email" {
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return Principal{}, nil, errors.New("the email of the ID token isn't verified")
+		}
+	}
+	principal := Principal{Name: o.config.UsernamePrefix + username}
+	switch groups := claims[o.config.GroupsClaim].(type) {
+	case string:
+		principal.Groups = []string{groups}
+	case []any:
+		for _, group := range groups {
+			if group, ok := group.(string); ok {
+				principal.Groups = append(principal.Groups, group)
+			}
+		}
+	}
+	return principal, claims, nil
+}
+
+// validate checks the audience and the validity period of the ID token
+func (o *OIDC) validate(claims map[string]any) error {
+	var audience []string
+	switch aud := claims["aud"].(type) {
+	case string:
+		audience = []string{aud}
+	case []any:
+		for _, a := range aud {
+			if a, ok := a.(string); ok {
+				audience = append(audience, a)
+			}
+		}
+	}
+	if !slices.Contains(audience, o.config.ClientID) {
+		return errors.New("the ID token wasn't issued for this client")
+	}
+
+	now := o.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("the ID token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return errors.New("the ID token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("the ID token isn't valid yet")
+	}
+	return nil
+}
+
+// key returns the signing key of the given ID, fetching the keys of the issuer when unknown
+func (o *OIDC) key(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if key, ok := o.keys[keyID]; ok {
+		return key, nil
+	}
+	if !o.fetchedAt.IsZero() && o.now().Sub(o.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+	keys, err := o.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	o.keys, o.fetchedAt = keys, o.now()
+	if key, ok := o.keys[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", keyID)
+}
+
+// Endpoints returns the endpoints of the issuer, discovered on first use
+func (o *OIDC) Endpoints(ctx context.Context) (authorization, token string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	d, err := o.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return d.AuthorizationEndpoint, d.TokenEndpoint, nil
+}
+
+// discover returns the discovery document of the issuer, fetched on first use. It must be called with the lock held.
+func (o *OIDC) discover(ctx context.Context) (*discovery, error) {
+	if o.discovery != nil {
+		return o.discovery, nil
+	}
+	d := &discovery{}
+	if err := o.get(ctx, o.config.IssuerURL+"/.well-known/openid-configuration", d); err != nil {
+		return nil, fmt.Errorf("failed to discover the OIDC issuer: %w", err)
+	}
+	o.discovery = d
+	return d, nil
+}
+
+// fetchKeys fetches the signing keys of the issuer, by their ID. It must be called with the lock held.
+func (o *OIDC) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	discovery, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+			Curve   string `json:"crv"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := o.get(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch the signing keys of the OIDC issuer: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.KeyType {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if curves[jwk.Curve] == nil || errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.KeyID] = &ecdsa.PublicKey{Curve: curves[jwk.Curve], X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+func (o *OIDC) get(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// verifySignature verifies the signature of a JSON Web Token with the given algorithm, out of the RS and ES ones
+func verifySignature(algorithm string, key crypto.PublicKey, signed string, signature []byte) error {
+	hashes := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+	hash, ok := hashes[strings.TrimLeft(algorithm, "RSE")]
+	if !ok || len(algorithm) != 5 {
+		return fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(algorithm, "RS") {
+			return fmt.Errorf("signing algorithm %q doesn't match an RSA key", algorithm)
+		}
+		if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+			return errors.New("invalid ID token signature")
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(algorithm, "ES") || len(signature) != 2*size {
+			return fmt.Errorf("signing algorithm %q doesn't match an ECDSA key", algorithm)
+		}
+		rs, ss := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, rs, ss) {
+			return errors.New("invalid ID token signature")
+		}
+	default:
+		return errors.New("unsupported signing key")
+	}
+	return nil
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a JSON Web Token
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signToken returns a JSON Web Token of the given claims, signed with the given key of the given ID
+func signToken(t *testing.T, key crypto.Signer, keyID string, claims map[string]any) string {
+	t.Helper()
+	algorithm := "RS256"
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		algorithm = "ES256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": algorithm, "kid": keyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("SignPKCS1v15() error = %v", err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// newIssuer starts an OpenID Connect issuer serving the given keys, returning its mux so that tests can add endpoints
+func newIssuer(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) (*httptest.Server, *http.ServeMux, *int) {
+	t.Helper()
+	fetches := 0
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"jwks_uri":               server.URL + "/keys",
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+		}})
+	})
+	return server, mux, &fetches
+}
+
+func TestOIDC_Authenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	issuer, _, fetches := newIssuer(t, rsaKey, ecKey)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":    issuer.URL,
+			"aud":    "k8s-api-proxy",
+			"sub":    "alice",
+			"groups": []string{"deployers"},
+			"exp":    now.Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name              string
+		usernameClaim     string
+		token             string
+		expectedOK        bool
+		expectedErr       string
+		expectedPrincipal Principal
+	}{
+		{
+			name:              "RSA signed",
+			token:             signToken(t, rsaKey, "rsa", claims(nil)),
+			expectedOK:        true,
+			expectedPrincipal: Principal{Name: "oidc:alice", Groups: []string{"deployers"}},
+		},
+		{
+			name:              "ECDSA signed, audience list",
+			token:             signToken(t, ecKey, "ec", claims(map[string]any{"aud": []string{"other", "k8s-api-proxy"}})),
+			expectedOK:        true,
+			expectedPrincipal: Principal{Name: "oidc:alice", Groups: []string{"deployers"}},
+		},
+		{
+			name:              "verified email",
+			usernameClaim:     "email",
+			token:             signToken(t, rsaKey, "rsa", claims(map[string]any{"email": "alice@example.com", "email_verified": true})),
+			expectedOK:        true,
+			expectedPrincipal: Principal{Name: "oidc:alice@example.com", Groups: []string{"deployers"}},
+		},
+		{
+			name:          "unverified email",
+			usernameClaim: "email",
+			token:         signToken(t, rsaKey, "rsa", claims(map[string]any{"email": "alice@example.com"})),
+			expectedErr:   "isn't verified",
+		},
+		{name: "not a JWT", token: "s3cr3t"},
+		{name: "other issuer", token: signToken(t, rsaKey, "rsa", claims(map[string]any{"iss": "https://other.example.com"}))},
+		{name: "other audience", token: signToken(t, rsaKey, "rsa", claims(map[string]any{"aud": "other"})), expectedErr: "wasn't issued for this client"},
+		{name: "expired", token: signToken(t, rsaKey, "rsa", claims(map[string]any{"exp": now.Add(-time.Hour).Unix()})), expectedErr: "expired"},
+		{name: "not valid yet", token: signToken(t, rsaKey, "rsa", claims(map[string]any{"nbf": now.Add(time.Hour).Unix()})), expectedErr: "isn't valid yet"},
+		{name: "forged signature", token: signToken(t, otherKey, "ec", claims(nil)), expectedErr: "invalid ID token signature"},
+		{name: "unknown key", token: signToken(t, rsaKey, "rotated", claims(nil)), expectedErr: `unknown signing key "rotated"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewOIDC(OIDCConfig{IssuerURL: issuer.URL, ClientID: "k8s-api-proxy", UsernameClaim: tt.usernameClaim, UsernamePrefix: "oidc:"}, issuer.Client())
+			o.now = func() time.Time { return now }
+			r := httptest.NewRequest("GET", "/deployments", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+
+			principal, ok, err := o.Authenticate(r)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if ok != tt.expectedOK {
+				t.Fatalf("Authenticate() ok = %v, want %v", ok, tt.expectedOK)
+			}
+			if principal.Name != tt.expectedPrincipal.Name || !slices.Equal(principal.Groups, tt.expectedPrincipal.Groups) {
+				t.Errorf("Authenticate() principal = %+v, want %+v", principal, tt.expectedPrincipal)
+			}
+		})
+	}
+
+	// The signing keys are cached, and only fetched again on an unknown key once the refresh interval elapsed
+	o := NewOIDC(OIDCConfig{IssuerURL: issuer.URL, ClientID: "k8s-api-proxy"}, issuer.Client())
+	o.now = func() time.Time { return now }
+	*fetches = 0
+	for _, keyID := range []string{"rsa", "rsa", "rotated", "rotated"} {
+		r := httptest.NewRequest("GET", "/deployments", nil)
+		r.Header.Set("Authorization", "Bearer "+signToken(t, rsaKey, keyID, claims(nil)))
+		_, _, _ = o.Authenticate(r)
+	}
+	if *fetches != 1 {
+		t.Errorf("signing key fetches = %v, want 1", *fetches)
+	}
+}
@@ -0,0 +1,361 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Paths of the endpoints of the browser login flow, which are served to unauthenticated clients
+const (
+	LoginPath    = "/auth/login"
+	CallbackPath = "/auth/callback"
+	LogoutPath   = "/auth/logout"
+)
+
+// Names of the cookies of the browser sessions
+const (
+	sessionCookieName = "go-k8s-http-api-session"
+	loginCookieName   = "go-k8s-http-api-login"
+)
+
+// loginTTL is how long a browser has to complete a login at the issuer
+const loginTTL = 10 * time.Minute
+
+// maxCookieSize is the size from which the browsers may drop a cookie
+const maxCookieSize = 4000
+
+// SessionConfig holds the settings of the session authenticator, on top of the ones of the OIDC issuer
+type SessionConfig struct {
+	// ClientSecretFile is the path of the file holding the client secret, authenticating the exchange of the
+	// authorization codes at the issuer
+	ClientSecretFile string
+	// RedirectURL is the URL of the callback endpoint, as registered with the issuer, e.g.
+	// https://api.example.com/auth/callback
+	RedirectURL string
+	// Scopes are the scopes requested from the issuer, openid being always requested
+	Scopes []string
+	// KeyFile is the path of the file holding the base64 encoded 32 byte key encrypting the cookies. A random key is
+	// generated when empty, so that the sessions don't survive restarts, nor are shared by the replicas.
+	KeyFile string
+	// TTL is how long a session lasts, from the login
+	TTL time.Duration
+}
+
+// session is the content of the session cookie
+type session struct {
+	Name    string   `json:"n"`
+	Groups  []string `json:"g,omitempty"`
+	Expires int64    `json:"e"`
+}
+
+// loginState is the content of the login cookie, tying the callback to the browser that started the login
+type loginState struct {
+	State      string `json:"s"`
+	Nonce      string `json:"n"`
+	Verifier   string `json:"v"`
+	RedirectTo string `json:"r"`
+	Expires    int64  `json:"e"`
+}
+
+// Sessions authenticates browsers by an encrypted session cookie, issued at the end of an OpenID Connect authorization
+// code flow, so that a browser UI can use the API without handling tokens in JavaScript. The session cookie is
+// HttpOnly and SameSite=Strict, so that neither scripts nor other sites can use it.
+type Sessions struct {
+	oidc         *OIDC
+	clientID     string
+	clientSecret string
+	config       SessionConfig
+	aead         cipher.AEAD
+	client       *http.Client
+	now          func() time.Time
+}
+
+// NewSessions returns a new Sessions logging in at the given issuer with the given client
+func NewSessions(oidcConfig OIDCConfig, config SessionConfig, client *http.Client) (*Sessions, error) {
+	if oidcConfig.IssuerURL == "" || oidcConfig.ClientID == "" || config.ClientSecretFile == "" {
+		return nil, errors.New("an OIDC issuer URL, client ID and client secret are required")
+	}
+	if u, err := url.Parse(config.RedirectURL); err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("invalid redirect URL %q", config.RedirectURL)
+	}
+	if config.TTL <= 0 {
+		return nil, errors.New("the session TTL must be positive")
+	}
+	secret, err := os.ReadFile(config.ClientSecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the client secret: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if config.KeyFile != "" {
+		encoded, err := os.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the session key: %w", err)
+		}
+		if key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded))); err != nil || len(key) != 32 {
+			return nil, errors.New("the session key must be a base64 encoded 32 byte key")
+		}
+	} else if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate the session key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sessions{
+		oidc:         NewOIDC(oidcConfig, client),
+		clientID:     oidcConfig.ClientID,
+		clientSecret: strings.TrimSpace(string(secret)),
+		config:       config,
+		aead:         aead,
+		client:       client,
+		now:          time.Now,
+	}, nil
+}
+
+// PublicPaths returns the paths of the login flow, which the chain serves to unauthenticated clients
+func (s *Sessions) PublicPaths() []string {
+	return []string{LoginPath, CallbackPath, LogoutPath}
+}
+
+// Authenticate implements Authenticator, for the requests carrying a session cookie. The cookies which are expired, or
+// were encrypted with another key, e.g. before a restart, are left to the next authenticators.
+func (s *Sessions) Authenticate(r *http.Request) (Principal, bool, error) {
+	var sess session
+	if !s.open(r, sessionCookieName, &sess) || s.now().Unix() >= sess.Expires {
+		return Principal{}, false, nil
+	}
+	return Principal{Name: sess.Name, Groups: sess.Groups}, true, nil
+}
+
+// Login handles the "/auth/login" endpoint. It redirects the browser to the issuer to log in, and back to the local
+// path of the redirect query parameter once logged in, / by default.
+func (s *Sessions) Login(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+	redirectTo := r.URL.Query().Get("redirect")
+	if !strings.HasPrefix(redirectTo, "/") || strings.HasPrefix(redirectTo, "//") || strings.HasPrefix(redirectTo, "/\\") {
+		redirectTo = "/"
+	}
+	authorizationEndpoint, _, err := s.oidc.Endpoints(r.Context())
+	if err != nil {
+		logger.Error(err, "Error discovering the OIDC issuer")
+		writeMessage(w, logger, http.StatusBadGateway, "Failed to discover the OIDC issuer")
+		return
+	}
+	authorizationURL, err := url.Parse(authorizationEndpoint)
+	if err != nil || authorizationEndpoint == "" {
+		logger.Error(err, "Invalid authorization endpoint of the OIDC issuer", "endpoint", authorizationEndpoint)
+		writeMessage(w, logger, http.StatusBadGateway, "Failed to discover the OIDC issuer")
+		return
+	}
+
+	login := loginState{
+		State:      randomString(),
+		Nonce:      randomString(),
+		Verifier:   randomString(),
+		RedirectTo: redirectTo,
+		Expires:    s.now().Add(loginTTL).Unix(),
+	}
+	cookie, err := s.seal(loginCookieName, login)
+	if err != nil {
+		logger.Error(err, "Error sealing the login cookie")
+		writeMessage(w, logger, http.StatusInternalServerError, "Failed to start the login")
+		return
+	}
+	cookie.Path, cookie.MaxAge, cookie.SameSite = CallbackPath, int(loginTTL.Seconds()), http.SameSiteLaxMode
+	http.SetCookie(w, cookie)
+
+	challenge := sha256.Sum256([]byte(login.Verifier))
+	query := authorizationURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", s.clientID)
+	query.Set("redirect_uri", s.config.RedirectURL)
+	query.Set("scope", strings.Join(s.scopes(), " "))
+	query.Set("state", login.State)
+	query.Set("nonce", login.Nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authorizationURL.RawQuery = query.Encode()
+	http.Redirect(w, r, authorizationURL.String(), http.StatusFound)
+}
+
+// Callback handles the "/auth/callback" endpoint the issuer redirects the browser to. It exchanges the authorization
+// code for an ID token, and sets the session cookie of its principal before redirecting the browser to where the
+// login started.
+func (s *Sessions) Callback(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+	var login loginState
+	if !s.open(r, loginCookieName, &login) || s.now().Unix() >= login.Expires {
+		logger.Info("Login callback without a pending login")
+		writeMessage(w, logger, http.StatusBadRequest, "No pending login, start over from "+LoginPath)
+		return
+	}
+	http.SetCookie(w, s.clearCookie(loginCookieName, CallbackPath))
+
+	query := r.URL.Query()
+	if loginErr := query.Get("error"); loginErr != "" {
+		logger.Info("Login failed at the OIDC issuer", "error", loginErr, "description", query.Get("error_description"))
+		writeMessage(w, logger, http.StatusUnauthorized, "Login failed: "+loginErr)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(login.State)) != 1 {
+		logger.Info("Login callback with a mismatched state")
+		writeMessage(w, logger, http.StatusBadRequest, "Invalid login state, start over from "+LoginPath)
+		return
+	}
+
+	idToken, err := s.exchange(r, query.Get("code"), login.Verifier)
+	if err != nil {
+		logger.Error(err, "Error exchanging the authorization code")
+		writeMessage(w, logger, http.StatusBadGateway, "Failed to exchange the authorization code")
+		return
+	}
+	principal, claims, err := s.oidc.Verify(r.Context(), idToken)
+	if err == nil {
+		if nonce, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(nonce), []byte(login.Nonce)) != 1 {
+			err = errors.New("the nonce of the ID token doesn't match the login")
+		}
+	}
+	if err != nil {
+		logger.Info("Login failed, invalid ID token", "err", err)
+		writeMessage(w, logger, http.StatusUnauthorized, "Login failed: invalid ID token")
+		return
+	}
+
+	cookie, err := s.seal(sessionCookieName, session{Name: principal.Name, Groups: principal.Groups, Expires: s.now().Add(s.config.TTL).Unix()})
+	if err == nil && len(cookie.Value) > maxCookieSize {
+		err = fmt.Errorf("the session cookie is %d bytes long, e.g. too many groups", len(cookie.Value))
+	}
+	if err != nil {
+		logger.Error(err, "Error sealing the session cookie", "user", principal.Name)
+		writeMessage(w, logger, http.StatusInternalServerError, "Failed to start the session")
+		return
+	}
+	cookie.Path, cookie.MaxAge, cookie.SameSite = "/", int(s.config.TTL.Seconds()), http.SameSiteStrictMode
+	http.SetCookie(w, cookie)
+	logger.Info("Logged in", "user", principal.Name)
+	http.Redirect(w, r, login.RedirectTo, http.StatusSeeOther)
+}
+
+// Logout handles the "/auth/logout" endpoint. It clears the session cookie of the browser.
+func (s *Sessions) Logout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, s.clearCookie(sessionCookieName, "/"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// exchange exchanges an authorization code for an ID token at the token endpoint of the issuer
+func (s *Sessions) exchange(r *http.Request, code, verifier string) (string, error) {
+	_, tokenEndpoint, err := s.oidc.Endpoints(r.Context())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(s.clientID), url.QueryEscape(s.clientSecret))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil && resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("malformed token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from the token endpoint: %s", resp.StatusCode, token.Error)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("the token response has no ID token")
+	}
+	return token.IDToken, nil
+}
+
+func (s *Sessions) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range s.config.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// seal returns a cookie of the given name holding v, encrypted and authenticated, the name being authenticated along
+// with it so that a cookie can't pass for another one
+func (s *Sessions) seal(name string, v any) (*http.Cookie, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := s.aead.Seal(nonce, nonce, plaintext, []byte(name))
+	return &http.Cookie{
+		Name:     name,
+		Value:    base64.RawURLEncoding.EncodeToString(sealed),
+		Secure:   strings.HasPrefix(s.config.RedirectURL, "https://"),
+		HttpOnly: true,
+	}, nil
+}
+
+// open decrypts the cookie of the given name of the request into v, and returns whether it was present and valid
+func (s *Sessions) open(r *http.Request, name string, v any) bool {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return false
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return false
+	}
+	plaintext, err := s.aead.Open(nil, sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():], []byte(name))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(plaintext, v) == nil
+}
+
+func (s *Sessions) clearCookie(name, path string) *http.Cookie {
+	return &http.Cookie{Name: name, Path: path, MaxAge: -1, Secure: strings.HasPrefix(s.config.RedirectURL, "https://"), HttpOnly: true}
+}
+
+// randomString returns a random base64url encoded 32 byte string
+func randomString() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testIssuerLogin is the state of a login at the test issuer: the code challenge and the nonce of the authorization
+// request, from which its token endpoint issues an ID token for the authorization code the-code
+type testIssuerLogin struct {
+	challenge string
+	nonce     string
+}
+
+// newSessions returns a Sessions logging in at a test issuer
+func newSessions(t *testing.T, login *testIssuerLogin) *Sessions {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	issuer, mux, _ := newIssuer(t, rsaKey, ecKey)
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if id != "dashboard" || secret != "s3cr3t" || r.PostFormValue("code") != "the-code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != login.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signToken(t, rsaKey, "rsa", map[string]any{
+			"iss":    issuer.URL,
+			"aud":    "dashboard",
+			"sub":    "alice",
+			"groups": []string{"deployers"},
+			"nonce":  login.nonce,
+			"exp":    time.Now().Add(time.Hour).Unix(),
+		})})
+	})
+
+	secretFile := filepath.Join(t.TempDir(), "client-secret")
+	if err := os.WriteFile(secretFile, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	s, err := NewSessions(
+		OIDCConfig{IssuerURL: issuer.URL, ClientID: "dashboard", UsernamePrefix: "oidc:"},
+		SessionConfig{ClientSecretFile: secretFile, RedirectURL: "https://api.example.com" + CallbackPath, Scopes: []string{"email"}, TTL: time.Hour},
+		issuer.Client(),
+	)
+	if err != nil {
+		t.Fatalf("NewSessions() error = %v", err)
+	}
+	return s
+}
+
+// startLogin starts a login redirecting to the given path, and returns the login cookie along with the query of the
+// authorization request
+func startLogin(t *testing.T, s *Sessions, redirect string) (*http.Cookie, url.Values) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.Login(w, httptest.NewRequest("GET", LoginPath+"?redirect="+url.QueryEscape(redirect), nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("Login() status code = %v, want %v", w.Code, http.StatusFound)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Login() location error = %v", err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != loginCookieName {
+		t.Fatalf("Login() cookies = %v, want the login cookie", cookies)
+	}
+	return cookies[0], location.Query()
+}
+
+func TestSessions_Login(t *testing.T) {
+	s := newSessions(t, &testIssuerLogin{})
+	tests := []struct {
+		name               string
+		redirect           string
+		expectedRedirectTo string
+	}{
+		{"Test Local Path", "/deployments?namespace=default", "/deployments?namespace=default"},
+		{"Test No Redirect", "", "/"},
+		{"Test Other Host", "https://evil.example.com", "/"},
+		{"Test Protocol Relative", "//evil.example.com", "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookie, query := startLogin(t, s, tt.redirect)
+			expected := map[string]string{
+				"response_type":         "code",
+				"client_id":             "dashboard",
+				"redirect_uri":          "https://api.example.com" + CallbackPath,
+				"scope":                 "openid email",
+				"code_challenge_method": "S256",
+			}
+			for param, value := range expected {
+				if query.Get(param) != value {
+					t.Errorf("authorization request %s = %q, want %q", param, query.Get(param), value)
+				}
+			}
+			if !cookie.HttpOnly || !cookie.Secure || cookie.Path != CallbackPath {
+				t.Errorf("login cookie = %+v, want an HttpOnly and Secure cookie of path %s", cookie, CallbackPath)
+			}
+			r := httptest.NewRequest("GET", CallbackPath, nil)
+			r.AddCookie(cookie)
+			var login loginState
+			if !s.open(r, loginCookieName, &login) {
+				t.Fatal("login cookie can't be opened")
+			}
+			if login.RedirectTo != tt.expectedRedirectTo || login.State != query.Get("state") || login.Nonce != query.Get("nonce") {
+				t.Errorf("login state = %+v, want redirect to %v and the state and nonce of the authorization request", login, tt.expectedRedirectTo)
+			}
+		})
+	}
+}
+
+func TestSessions_Callback(t *testing.T) {
+	login := &testIssuerLogin{}
+	s := newSessions(t, login)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	tests := []struct {
+		name           string
+		noLoginCookie  bool
+		state          string
+		code           string
+		nonce          string
+		issuerError    string
+		expectedStatus int
+	}{
+		{name: "Test Logged In", code: "the-code", expectedStatus: http.StatusSeeOther},
+		{name: "Test No Pending Login", noLoginCookie: true, code: "the-code", expectedStatus: http.StatusBadRequest},
+		{name: "Test Mismatched State", state: "forged", code: "the-code", expectedStatus: http.StatusBadRequest},
+		{name: "Test Issuer Error", issuerError: "access_denied", expectedStatus: http.StatusUnauthorized},
+		{name: "Test Invalid Code", code: "other-code", expectedStatus: http.StatusBadGateway},
+		{name: "Test Replayed ID Token", code: "the-code", nonce: "other-nonce", expectedStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookie, query := startLogin(t, s, "/deployments")
+			login.challenge, login.nonce = query.Get("code_challenge"), query.Get("nonce")
+			if tt.nonce != "" {
+				login.nonce = tt.nonce
+			}
+			state := query.Get("state")
+			if tt.state != "" {
+				state = tt.state
+			}
+			callback := url.Values{"state": {state}, "code": {tt.code}}
+			if tt.issuerError != "" {
+				callback = url.Values{"state": {state}, "error": {tt.issuerError}}
+			}
+			r := httptest.NewRequest("GET", CallbackPath+"?"+callback.Encode(), nil)
+			if !tt.noLoginCookie {
+				r.AddCookie(cookie)
+			}
+
+			w := httptest.NewRecorder()
+			s.Callback(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("status code = %v, want %v, body %v", w.Code, tt.expectedStatus, w.Body.String())
+			}
+			var sessionCookie *http.Cookie
+			for _, c := range w.Result().Cookies() {
+				if c.Name == sessionCookieName {
+					sessionCookie = c
+				}
+			}
+			if tt.expectedStatus != http.StatusSeeOther {
+				if sessionCookie != nil {
+					t.Errorf("session cookie = %v, want none", sessionCookie)
+				}
+				return
+			}
+			if location := w.Header().Get("Location"); location != "/deployments" {
+				t.Errorf("location = %v, want /deployments", location)
+			}
+			if sessionCookie == nil || !sessionCookie.HttpOnly || !sessionCookie.Secure || sessionCookie.SameSite != http.SameSiteStrictMode {
+				t.Fatalf("session cookie = %+v, want an HttpOnly, Secure and SameSite=Strict cookie", sessionCookie)
+			}
+
+			// The session cookie authenticates the requests until it expires
+			r = httptest.NewRequest("GET", "/deployments", nil)
+			r.AddCookie(sessionCookie)
+			principal, ok, err := s.Authenticate(r)
+			if err != nil || !ok || principal.Name != "oidc:alice" || !slices.Equal(principal.Groups, []string{"deployers"}) {
+				t.Errorf("Authenticate() = %+v, %v, %v, want oidc:alice of deployers", principal, ok, err)
+			}
+			s.now = func() time.Time { return now.Add(time.Hour) }
+			defer func() { s.now = func() time.Time { return now } }()
+			if _, ok, err := s.Authenticate(r); ok || err != nil {
+				t.Errorf("Authenticate() of an expired session = %v, %v, want false, nil", ok, err)
+			}
+		})
+	}
+}
+
+func TestSessions_Authenticate(t *testing.T) {
+	s := newSessions(t, &testIssuerLogin{})
+	other := newSessions(t, &testIssuerLogin{})
+	sealed := func(s *Sessions, name string) string {
+		cookie, err := s.seal(name, session{Name: "oidc:alice", Expires: time.Now().Add(time.Hour).Unix()})
+		if err != nil {
+			t.Fatalf("seal() error = %v", err)
+		}
+		return cookie.Value
+	}
+	valid := sealed(s, sessionCookieName)
+	tampered, _ := base64.RawURLEncoding.DecodeString(valid)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name       string
+		cookie     string
+		expectedOK bool
+	}{
+		{"Test Valid", valid, true},
+		{"Test No Cookie", "", false},
+		{"Test Tampered", base64.RawURLEncoding.EncodeToString(tampered), false},
+		{"Test Other Key", sealed(other, sessionCookieName), false},
+		{"Test Login Cookie", sealed(s, loginCookieName), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/deployments", nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: tt.cookie})
+			}
+			_, ok, err := s.Authenticate(r)
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if ok != tt.expectedOK {
+				t.Errorf("Authenticate() ok = %v, want %v", ok, tt.expectedOK)
+			}
+		})
+	}
+
+	// The login endpoints are served to unauthenticated clients by the chains of the session authenticator
+	chain, err := NewChain([]string{SessionAuthenticator}, Config{Sessions: s})
+	if err != nil {
+		t.Fatalf("NewChain() error = %v", err)
+	}
+	handler := chain.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+	for path, expectedStatus := range map[string]int{LoginPath: http.StatusOK, LogoutPath: http.StatusOK, "/deployments": http.StatusUnauthorized} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != expectedStatus {
+			t.Errorf("%s status code = %v, want %v", path, w.Code, expectedStatus)
+		}
+	}
+}
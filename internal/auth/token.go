@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Tokens authenticates the clients by a static bearer token, out of a token file in the format of the Kubernetes
+// static token files: one token,user,uid,"group1,group2" line per token, the groups being optional
+type Tokens struct {
+	// principals are the principals of the tokens, by the SHA-256 hash of the token, so that the lookup doesn't leak
+	// the tokens through its timing
+	principals map[[sha256.Size]byte]Principal
+}
+
+// LoadTokenFile loads the tokens of the token file at the given path
+func LoadTokenFile(path string) (*Tokens, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token file: %w", err)
+	}
+	defer f.Close()
+	return ParseTokens(f)
+}
+
+// ParseTokens parses the tokens of a token file
+func ParseTokens(r io.Reader) (*Tokens, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	tokens := &Tokens{principals: map[[sha256.Size]byte]Principal{}}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return tokens, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse token file: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) < 2 || record[0] == "" || record[1] == "" {
+			return nil, fmt.Errorf("line %d of the token file must be token,user[,uid[,groups]]", line)
+		}
+		principal := Principal{Name: record[1]}
+		if len(record) > 3 && record[3] != "" {
+			principal.Groups = strings.Split(record[3], ",")
+		}
+		key := sha256.Sum256([]byte(record[0]))
+		if _, ok := tokens.principals[key]; ok {
+			return nil, fmt.Errorf("line %d of the token file has a duplicate token", line)
+		}
+		tokens.principals[key] = principal
+	}
+}
+
+// Authenticate implements Authenticator. The tokens that aren't in the file are left to the next authenticators, e.g.
+// the OIDC one.
+func (t *Tokens) Authenticate(r *http.Request) (Principal, bool, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return Principal{}, false, nil
+	}
+	principal, ok := t.principals[sha256.Sum256([]byte(token))]
+	return principal, ok, nil
+}
+
+// bearerToken returns the bearer token of the Authorization header of the request, if any
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		expectedErr string
+	}{
+		{name: "valid", file: "# CI tokens\ns3cr3t,ci-bot,1,\"deployers,viewers\"\nt0ken,alice,2\n"},
+		{name: "missing user", file: "s3cr3t\n", expectedErr: "line 1 of the token file must be token,user[,uid[,groups]]"},
+		{name: "duplicate token", file: "s3cr3t,ci-bot,1\ns3cr3t,alice,2\n", expectedErr: "line 2 of the token file has a duplicate token"},
+		{name: "malformed", file: "s3cr3t,\"ci-bot\n", expectedErr: "failed to parse token file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTokens(strings.NewReader(tt.file))
+			if tt.expectedErr == "" && err != nil {
+				t.Errorf("ParseTokens() error = %v", err)
+			}
+			if tt.expectedErr != "" && (err == nil || !strings.Contains(err.Error(), tt.expectedErr)) {
+				t.Errorf("ParseTokens() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package bounds
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Annotations declaring the replica bounds of a deployment
+const (
+	MinReplicasAnnotation = "go-k8s-http-api.io/min-replicas"
+	MaxReplicasAnnotation = "go-k8s-http-api.io/max-replicas"
+)
+
+// Bounds are the minimum and / or maximum number of replicas of a deployment. A nil bound is unbounded.
+type Bounds struct {
+	Min *int32 `json:"min,omitempty"`
+	Max *int32 `json:"max,omitempty"`
+}
+
+// IsZero returns whether neither bound is set
+func (b Bounds) IsZero() bool {
+	return b.Min == nil && b.Max == nil
+}
+
+// Validate validates the Bounds object and returns an error if it is invalid
+func (b Bounds) Validate() error {
+	if b.Min != nil && *b.Min < 0 {
+		return fmt.Errorf("min must be greater than or equal to 0")
+	}
+	if b.Max != nil && *b.Max < 0 {
+		return fmt.Errorf("max must be greater than or equal to 0")
+	}
+	if b.Min != nil && b.Max != nil && *b.Min > *b.Max {
+		return fmt.Errorf("min (%d) must be less than or equal to max (%d)", *b.Min, *b.Max)
+	}
+	return nil
+}
+
+// Clamp returns the given number of replicas brought back within the bounds
+func (b Bounds) Clamp(replicas int32) int32 {
+	if b.Min != nil && replicas < *b.Min {
+		return *b.Min
+	}
+	if b.Max != nil && replicas > *b.Max {
+		return *b.Max
+	}
+	return replicas
+}
+
+// String returns the bounds in the [min, max] interval notation, e.g. [2, 10] or [2, ∞)
+func (b Bounds) String() string {
+	lower, upper := "0", "∞)"
+	if b.Min != nil {
+		lower = strconv.Itoa(int(*b.Min))
+	}
+	if b.Max != nil {
+		upper = strconv.Itoa(int(*b.Max)) + "]"
+	}
+	return "[" + lower + ", " + upper
+}
+
+// FromAnnotations parses the bounds declared in the given annotations
+func FromAnnotations(annotations map[string]string) (Bounds, error) {
+	var b Bounds
+	for name, bound := range map[string]**int32{MinReplicasAnnotation: &b.Min, MaxReplicasAnnotation: &b.Max} {
+		value, ok := annotations[name]
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return Bounds{}, fmt.Errorf("invalid %s annotation %q: %w", name, value, err)
+		}
+		replicas := int32(parsed)
+		*bound = &replicas
+	}
+	return b, b.Validate()
+}
+
+// SetAnnotations declares the bounds in the given annotations, removing the annotations of the unset bounds, and
+// returns the updated annotations
+func SetAnnotations(annotations map[string]string, b Bounds) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for name, bound := range map[string]*int32{MinReplicasAnnotation: b.Min, MaxReplicasAnnotation: b.Max} {
+		if bound == nil {
+			delete(annotations, name)
+		} else {
+			annotations[name] = strconv.Itoa(int(*bound))
+		}
+	}
+	return annotations
+}
+
+// HasAnnotations returns whether any bound is declared in the given annotations
+func HasAnnotations(annotations map[string]string) bool {
+	_, hasMin := annotations[MinReplicasAnnotation]
+	_, hasMax := annotations[MaxReplicasAnnotation]
+	return hasMin || hasMax
+}
@@ -0,0 +1,94 @@
+package bounds
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/utils/ptr"
+)
+
+func TestFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		expected      Bounds
+		expectedError bool
+	}{
+		{
+			name: "no bounds",
+		},
+		{
+			name:        "min and max",
+			annotations: map[string]string{MinReplicasAnnotation: "2", MaxReplicasAnnotation: "10"},
+			expected:    Bounds{Min: ptr.To(int32(2)), Max: ptr.To(int32(10))},
+		},
+		{
+			name:        "min only",
+			annotations: map[string]string{MinReplicasAnnotation: "1", "foo": "bar"},
+			expected:    Bounds{Min: ptr.To(int32(1))},
+		},
+		{
+			name:          "not a number",
+			annotations:   map[string]string{MaxReplicasAnnotation: "ten"},
+			expectedError: true,
+		},
+		{
+			name:          "min greater than max",
+			annotations:   map[string]string{MinReplicasAnnotation: "5", MaxReplicasAnnotation: "3"},
+			expectedError: true,
+		},
+		{
+			name:          "negative",
+			annotations:   map[string]string{MinReplicasAnnotation: "-1"},
+			expectedError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := FromAnnotations(tt.annotations)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("FromAnnotations() error = %v, expectedError %v", err, tt.expectedError)
+			}
+			if !tt.expectedError && !reflect.DeepEqual(b, tt.expected) {
+				t.Errorf("FromAnnotations() = %v, want %v", b, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSetAnnotations(t *testing.T) {
+	annotations := SetAnnotations(map[string]string{"foo": "bar", MaxReplicasAnnotation: "10"}, Bounds{Min: ptr.To(int32(2))})
+	expected := map[string]string{"foo": "bar", MinReplicasAnnotation: "2"}
+	if !reflect.DeepEqual(annotations, expected) {
+		t.Errorf("SetAnnotations() = %v, want %v", annotations, expected)
+	}
+	if b, err := FromAnnotations(annotations); err != nil || *b.Min != 2 || b.Max != nil {
+		t.Errorf("FromAnnotations() = %v, %v, want the set bounds", b, err)
+	}
+}
+
+func TestBounds_Clamp(t *testing.T) {
+	tests := []struct {
+		name     string
+		bounds   Bounds
+		replicas int32
+		expected int32
+		str      string
+	}{
+		{"below min", Bounds{Min: ptr.To(int32(2)), Max: ptr.To(int32(10))}, 0, 2, "[2, 10]"},
+		{"above max", Bounds{Min: ptr.To(int32(2)), Max: ptr.To(int32(10))}, 20, 10, "[2, 10]"},
+		{"within bounds", Bounds{Min: ptr.To(int32(2)), Max: ptr.To(int32(10))}, 5, 5, "[2, 10]"},
+		{"unbounded max", Bounds{Min: ptr.To(int32(2))}, 50, 50, "[2, ∞)"},
+		{"unbounded min", Bounds{Max: ptr.To(int32(3))}, 0, 0, "[0, 3]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.bounds.Clamp(tt.replicas); got != tt.expected {
+				t.Errorf("Clamp(%d) = %d, want %d", tt.replicas, got, tt.expected)
+			}
+			if got := tt.bounds.String(); got != tt.str {
+				t.Errorf("String() = %q, want %q", got, tt.str)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package bounds
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Reasons of the events recorded on deployments
+const (
+	// ReasonReplicasOutOfBounds is recorded when the replicas of a deployment are scaled back within its bounds
+	ReasonReplicasOutOfBounds = "ReplicasOutOfBounds"
+	// ReasonInvalidReplicaBounds is recorded when the bounds annotations of a deployment can't be parsed
+	ReasonInvalidReplicaBounds = "InvalidReplicaBounds"
+)
+
+// Enforcer is a controller scaling deployments back within their declared replica bounds, e.g. after someone scaled
+// them manually. It only runs on the leader, since it mutates deployments.
+type Enforcer struct {
+	Client   client.Client
+	Recorder record.EventRecorder
+}
+
+// SetupWithManager registers the enforcer as a controller of the deployments declaring bounds on the manager
+func (e *Enforcer) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("replica-bounds").
+		For(&appsv1.Deployment{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return HasAnnotations(obj.GetAnnotations())
+		}))).
+		Complete(e)
+}
+
+// Reconcile scales the deployment back within its bounds, if needed
+func (e *Enforcer) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	logger := klog.FromContext(ctx).WithValues("deployment", req.NamespacedName)
+
+	d := &appsv1.Deployment{}
+	if err := e.Client.Get(ctx, req.NamespacedName, d); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	b, err := FromAnnotations(d.Annotations)
+	if err != nil {
+		// Retrying won't help until the annotations are fixed, which triggers a new reconciliation
+		logger.Error(err, "Invalid replica bounds")
+		e.Recorder.Event(d, corev1.EventTypeWarning, ReasonInvalidReplicaBounds, err.Error())
+		return reconcile.Result{}, nil
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	clamped := b.Clamp(replicas)
+	if clamped == replicas {
+		return reconcile.Result{}, nil
+	}
+
+	patch := client.MergeFromWithOptions(d.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	d.Spec.Replicas = &clamped
+	if err := e.Client.Patch(ctx, d, patch); err != nil {
+		if apierrors.IsConflict(err) {
+			// The deployment changed in the meantime, so it's reconciled again with its latest version
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	logger.Info("Scaled deployment back within its replica bounds", "from", replicas, "to", clamped, "bounds", b.String())
+	e.Recorder.Eventf(d, corev1.EventTypeWarning, ReasonReplicasOutOfBounds,
+		"Scaled from %d to %d replicas to stay within the declared bounds %s", replicas, clamped, b.String())
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,101 @@
+package bounds
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestEnforcer_Reconcile(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+
+	tests := []struct {
+		name             string
+		annotations      map[string]string
+		replicas         *int32
+		expectedReplicas int32
+		expectedEvent    string
+	}{
+		{
+			name:             "scaled to 0 below min",
+			annotations:      map[string]string{MinReplicasAnnotation: "2", MaxReplicasAnnotation: "10"},
+			replicas:         ptr.To(int32(0)),
+			expectedReplicas: 2,
+			expectedEvent:    "Warning ReplicasOutOfBounds Scaled from 0 to 2 replicas to stay within the declared bounds [2, 10]",
+		},
+		{
+			name:             "scaled above max",
+			annotations:      map[string]string{MaxReplicasAnnotation: "5"},
+			replicas:         ptr.To(int32(8)),
+			expectedReplicas: 5,
+			expectedEvent:    "Warning ReplicasOutOfBounds Scaled from 8 to 5 replicas to stay within the declared bounds [0, 5]",
+		},
+		{
+			name:             "defaulted replicas below min",
+			annotations:      map[string]string{MinReplicasAnnotation: "3"},
+			expectedReplicas: 3,
+			expectedEvent:    "Warning ReplicasOutOfBounds Scaled from 1 to 3 replicas to stay within the declared bounds [3, ∞)",
+		},
+		{
+			name:             "within bounds",
+			annotations:      map[string]string{MinReplicasAnnotation: "2", MaxReplicasAnnotation: "10"},
+			replicas:         ptr.To(int32(4)),
+			expectedReplicas: 4,
+		},
+		{
+			name:             "invalid bounds",
+			annotations:      map[string]string{MinReplicasAnnotation: "two"},
+			replicas:         ptr.To(int32(0)),
+			expectedReplicas: 0,
+			expectedEvent:    `Warning InvalidReplicaBounds invalid go-k8s-http-api.io/min-replicas annotation "two": strconv.ParseInt: parsing "two": invalid syntax`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Annotations: tt.annotations},
+				Spec:       appsv1.DeploymentSpec{Replicas: tt.replicas},
+			}
+			c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(d).Build()
+			recorder := record.NewFakeRecorder(10)
+			e := &Enforcer{Client: c, Recorder: recorder}
+
+			name := types.NamespacedName{Namespace: "default", Name: "foo"}
+			if _, err := e.Reconcile(context.Background(), reconcile.Request{NamespacedName: name}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			got := &appsv1.Deployment{}
+			if err := c.Get(context.Background(), name, got); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			replicas := int32(1)
+			if got.Spec.Replicas != nil {
+				replicas = *got.Spec.Replicas
+			}
+			if replicas != tt.expectedReplicas {
+				t.Errorf("replicas = %d, want %d", replicas, tt.expectedReplicas)
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if event != tt.expectedEvent {
+					t.Errorf("event = %q, want %q", event, tt.expectedEvent)
+				}
+			default:
+				if tt.expectedEvent != "" {
+					t.Errorf("expected event %q, got none", tt.expectedEvent)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+// State is the state of a circuit breaker
+type State string
+
+// States of a circuit breaker
+const (
+	// StateClosed lets all the calls through
+	StateClosed State = "closed"
+	// StateOpen fails the calls right away, until the cooldown elapses
+	StateOpen State = "open"
+	// StateHalfOpen lets a single trial call through, which closes the breaker if it succeeds, or opens it again if it fails
+	StateHalfOpen State = "half-open"
+)
+
+// ErrOpen is returned for the calls failed right away because the breaker is open
+var ErrOpen = errors.New("the API server is unavailable, circuit breaker is open")
+
+// Status is the state of a breaker, as reported in /readyz
+type Status struct {
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+}
+
+// Breaker is a circuit breaker for the calls to the API server. It opens after a number of consecutive calls fail or
+// time out, failing the subsequent calls right away instead of letting every one of them hit the full timeout. Once the
+// cooldown elapses, a single trial call is let through to find out whether the API server is back.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	openedAt  time.Time
+	lastError string
+	probing   bool
+}
+
+// New returns a new closed Breaker, opening after the given number of consecutive failures for the given cooldown
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown, now: time.Now, state: StateClosed}
+}
+
+// Allow returns ErrOpen if the call must fail right away, or nil if it may go through, in which case its result must
+// be reported with Done
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrOpen
+		}
+		b.state, b.probing = StateHalfOpen, true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+// Done reports the result of a call let through by Allow. Calls which got any answer from the API server, including
+// errors such as not found or conflicts, count as successes. Cancelled calls aren't counted either way.
+func (b *Breaker) Done(ctx context.Context, err error) {
+	if errors.Is(err, context.Canceled) {
+		b.mu.Lock()
+		b.probing = false
+		b.mu.Unlock()
+		return
+	}
+
+	logger := klog.FromContext(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+
+	if !IsUnavailable(err) {
+		if b.state != StateClosed {
+			logger.Info("API server is reachable again, closing the circuit breaker", "openFor", b.now().Sub(b.openedAt))
+		}
+		b.state, b.failures, b.lastError = StateClosed, 0, ""
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	if b.state == StateHalfOpen || (b.state == StateClosed && b.failures >= b.threshold) {
+		if b.state == StateClosed {
+			logger.Info("API server calls keep failing, opening the circuit breaker", "consecutiveFailures", b.failures, "err", err.Error())
+		}
+		b.state, b.openedAt = StateOpen, b.now()
+	}
+}
+
+// Status returns the current state of the breaker
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{State: b.state, ConsecutiveFailures: b.failures, LastError: b.lastError}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// Open returns whether the breaker currently fails the calls right away, i.e. whether the API server is unavailable
+func (b *Breaker) Open() bool {
+	return b.Status().State != StateClosed
+}
+
+// ReadyzCheck reports the state of the breaker. The instance stays ready while the breaker is open, since reads are
+// still served from the cache.
+func (b *Breaker) ReadyzCheck() (bool, any) {
+	return true, b.Status()
+}
+
+// Middleware fails the requests with a 503 Service Unavailable right away while the breaker is open, instead of
+// letting them wait for the API server calls to time out. It is meant for the mutating endpoints, which can't be
+// served from the cache.
+func (b *Breaker) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := b.Status()
+		if status.State != StateOpen || b.now().Sub(*status.OpenedAt) >= b.cooldown {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logger := klog.FromContext(r.Context())
+		logger.V(5).Info("Rejecting request, the circuit breaker is open")
+		w.Header().Set("Retry-After", retryAfter(b.cooldown-b.now().Sub(*status.OpenedAt)))
+		if err := problem.Write(w, http.StatusServiceUnavailable, "The API server is unavailable, try again later"); err != nil {
+			logger.Error(err, "Error encoding response")
+		}
+	}
+}
+
+// IsUnavailable returns whether the error means the API server couldn't serve the call: it timed out, couldn't be
+// reached, or answered with a server error
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, ErrOpen) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) ||
+		apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) || apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) || apierrors.IsUnexpectedServerError(err)
+}
+
+// retryAfter returns the Retry-After header value for the given duration, in whole seconds of at least 1
+func retryAfter(d time.Duration) string {
+	seconds := int((d + time.Second - 1) / time.Second)
+	return strconv.Itoa(max(seconds, 1))
+}
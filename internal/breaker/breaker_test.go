@@ -0,0 +1,129 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsUnavailable(t *testing.T) {
+	resource := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "no error", err: nil, want: false},
+		{name: "not found", err: apierrors.NewNotFound(resource, "foo"), want: false},
+		{name: "conflict", err: apierrors.NewConflict(resource, "foo", errors.New("conflict")), want: false},
+		{name: "deadline exceeded", err: fmt.Errorf("get: %w", context.DeadlineExceeded), want: true},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "server timeout", err: apierrors.NewServerTimeout(resource, "get", 1), want: true},
+		{name: "service unavailable", err: apierrors.NewServiceUnavailable("unavailable"), want: true},
+		{name: "internal error", err: apierrors.NewInternalError(errors.New("boom")), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUnavailable(tt.err); got != tt.want {
+				t.Errorf("IsUnavailable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := New(2, 30*time.Second)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+	unavailable := apierrors.NewServiceUnavailable("unavailable")
+
+	fail := func() error { return unavailable }
+	succeed := func() error { return nil }
+
+	// Failures below the threshold, and failures interrupted by a success, keep the breaker closed
+	_ = b.call(ctx, fail)
+	_ = b.call(ctx, succeed)
+	_ = b.call(ctx, fail)
+	if state := b.Status().State; state != StateClosed {
+		t.Fatalf("state = %v, want %v", state, StateClosed)
+	}
+
+	// Consecutive failures open it, failing the next calls right away
+	_ = b.call(ctx, fail)
+	if state := b.Status().State; state != StateOpen {
+		t.Fatalf("state = %v, want %v", state, StateOpen)
+	}
+	called := false
+	if err := b.call(ctx, func() error { called = true; return nil }); !errors.Is(err, ErrOpen) || called {
+		t.Errorf("call() on an open breaker = %v, called = %v, want ErrOpen without calling", err, called)
+	}
+	if !b.Open() {
+		t.Errorf("Open() = false, want true")
+	}
+
+	// Once the cooldown elapses, a single trial call goes through, and a failed one opens the breaker again
+	now = now.Add(31 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() after the cooldown = %v, want nil", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Allow() during the trial call = %v, want ErrOpen", err)
+	}
+	b.Done(ctx, unavailable)
+	if state := b.Status().State; state != StateOpen {
+		t.Fatalf("state after a failed trial call = %v, want %v", state, StateOpen)
+	}
+
+	// A successful trial call closes it
+	now = now.Add(31 * time.Second)
+	if err := b.call(ctx, succeed); err != nil {
+		t.Fatalf("call() after the cooldown = %v, want nil", err)
+	}
+	if status := b.Status(); status.State != StateClosed || status.ConsecutiveFailures != 0 || status.OpenedAt != nil {
+		t.Errorf("status after a successful trial call = %+v, want closed", status)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Now()
+	b := New(1, 30*time.Second)
+	b.now = func() time.Time { return now }
+	handler := b.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/deployments/foo/bar/replicas", nil))
+		return w
+	}
+
+	if w := serve(); w.Code != http.StatusOK {
+		t.Errorf("status with a closed breaker = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	b.Done(context.Background(), apierrors.NewServiceUnavailable("unavailable"))
+	now = now.Add(10 * time.Second)
+	w := serve()
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status with an open breaker = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "20" {
+		t.Errorf("Retry-After = %q, want %q", retryAfter, "20")
+	}
+
+	// Once the cooldown elapses, requests go through to find out whether the API server is back
+	now = now.Add(30 * time.Second)
+	if w := serve(); w.Code != http.StatusOK {
+		t.Errorf("status after the cooldown = %d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,72 @@
+package breaker
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reader guards the reads of the wrapped reader, which must be served by the API server (e.g. the manager's API reader),
+// with the breaker
+type Reader struct {
+	client.Reader
+	Breaker *Breaker
+}
+
+// Get retrieves an obj for the given object key, unless the breaker is open
+func (r *Reader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return r.Breaker.call(ctx, func() error {
+		return r.Reader.Get(ctx, key, obj, opts...)
+	})
+}
+
+// List retrieves a list of objects for the given options, unless the breaker is open
+func (r *Reader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return r.Breaker.call(ctx, func() error {
+		return r.Reader.List(ctx, list, opts...)
+	})
+}
+
+// Client guards the writes of the wrapped client with the breaker. Its reads are served by the cache, so they aren't.
+type Client struct {
+	client.Client
+	Breaker *Breaker
+}
+
+// Create saves the object obj in the Kubernetes cluster, unless the breaker is open
+func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return c.Breaker.call(ctx, func() error {
+		return c.Client.Create(ctx, obj, opts...)
+	})
+}
+
+// Update updates the given obj in the Kubernetes cluster, unless the breaker is open
+func (c *Client) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return c.Breaker.call(ctx, func() error {
+		return c.Client.Update(ctx, obj, opts...)
+	})
+}
+
+// Patch patches the given obj in the Kubernetes cluster, unless the breaker is open
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.Breaker.call(ctx, func() error {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	})
+}
+
+// Delete deletes the given obj from the Kubernetes cluster, unless the breaker is open
+func (c *Client) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return c.Breaker.call(ctx, func() error {
+		return c.Client.Delete(ctx, obj, opts...)
+	})
+}
+
+// call makes the given call to the API server if the breaker allows it, and reports its result
+func (b *Breaker) call(ctx context.Context, fn func() error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.Done(ctx, err)
+	return err
+}
@@ -0,0 +1,252 @@
+package cachesync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Informer is the subset of the controller-runtime cache.Informer interface needed to track its initial sync
+type Informer interface {
+	AddEventHandler(handler toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error)
+	HasSynced() bool
+}
+
+// InformerStatus is the sync progress of a single informer
+type InformerStatus struct {
+	Name    string `json:"name"`
+	Synced  bool   `json:"synced"`
+	Objects int64  `json:"objects"`
+	// Expected is the number of objects the initial list is expected to return, when known
+	Expected int64 `json:"expected,omitempty"`
+	// Progress is the percentage of the expected objects received so far. It only reaches 100 once the informer has
+	// synced, and stays 0 until then when the number of expected objects isn't known.
+	Progress int `json:"progress"`
+	// SyncDuration is how long the initial list took. Only set once the informer has synced.
+	SyncDuration string `json:"syncDuration,omitempty"`
+}
+
+// syncPollPeriod is how often the informers are checked for the end of their initial sync, which bounds the error of
+// the sync durations reported
+const syncPollPeriod = 100 * time.Millisecond
+
+// trackedInformer holds the progress of a single informer's initial sync
+type trackedInformer struct {
+	informer Informer
+	objects  atomic.Int64
+	expected atomic.Int64
+	syncedAt time.Time
+}
+
+// Tracker keeps track of the initial sync progress of the manager cache's informers,
+// so that cold starts against large clusters can be reported at startup and in /readyz.
+type Tracker struct {
+	mu        sync.Mutex
+	start     time.Time
+	informers map[string]*trackedInformer
+	// lastEvent is the time the last watch event was received by any of the tracked informers, in Unix nanoseconds
+	lastEvent atomic.Int64
+}
+
+// NewTracker returns a new Tracker. The sync durations are measured from the time it is created.
+func NewTracker() *Tracker {
+	return &Tracker{
+		start:     time.Now(),
+		informers: map[string]*trackedInformer{},
+	}
+}
+
+// Track starts tracking the initial sync progress of the given informer, counting the objects received in its initial
+// list. The time it syncs is recorded as it happens, until the context is cancelled.
+func (t *Tracker) Track(ctx context.Context, name string, informer Informer) error {
+	ti := &trackedInformer{informer: informer}
+	_, err := informer.AddEventHandler(toolscache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if isInInitialList {
+				ti.objects.Add(1)
+				return
+			}
+			t.lastEvent.Store(time.Now().UnixNano())
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			t.lastEvent.Store(time.Now().UnixNano())
+		},
+		DeleteFunc: func(obj interface{}) {
+			t.lastEvent.Store(time.Now().UnixNano())
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to track informer %s: %w", name, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.informers[name] = ti
+
+	go func() {
+		err := wait.PollUntilContextCancel(ctx, syncPollPeriod, true, func(context.Context) (bool, error) {
+			return informer.HasSynced(), nil
+		})
+		if err == nil {
+			t.markSynced(ti)
+		}
+	}()
+	return nil
+}
+
+// markSynced records the time the given informer synced, unless already recorded
+func (t *Tracker) markSynced(ti *trackedInformer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ti.syncedAt.IsZero() {
+		ti.syncedAt = time.Now()
+	}
+}
+
+// SetExpected sets the number of objects the initial list of the given informer is expected to return, from which its
+// sync progress percentage is derived
+func (t *Tracker) SetExpected(name string, expected int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ti, ok := t.informers[name]; ok {
+		ti.expected.Store(expected)
+	}
+}
+
+// CountObjects returns the number of objects of the given kind in the given namespaces, or in all of them when empty,
+// for SetExpected. Only the metadata of the first object of each namespace is listed, the rest being counted by the
+// API server as the remaining items.
+func CountObjects(ctx context.Context, reader client.Reader, gvk schema.GroupVersionKind, namespaces []string) (int64, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	var total int64
+	for _, namespace := range namespaces {
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := reader.List(ctx, list, client.InNamespace(namespace), client.Limit(1)); err != nil {
+			return 0, fmt.Errorf("failed to count %s: %w", gvk.Kind, err)
+		}
+		total += int64(len(list.Items))
+		if remaining := list.GetRemainingItemCount(); remaining != nil {
+			total += *remaining
+		}
+	}
+	return total, nil
+}
+
+// Status returns the sync progress of every tracked informer, sorted by name
+func (t *Tracker) Status() []InformerStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make([]InformerStatus, 0, len(t.informers))
+	for name, ti := range t.informers {
+		status := InformerStatus{
+			Name:     name,
+			Synced:   ti.informer.HasSynced(),
+			Objects:  ti.objects.Load(),
+			Expected: ti.expected.Load(),
+		}
+		switch {
+		case status.Synced:
+			status.Progress = 100
+		case status.Expected > 0:
+			// The objects created during the initial list may exceed the expected ones
+			status.Progress = int(min(99, status.Objects*100/status.Expected))
+		}
+		if status.Synced {
+			// The informer may be observed as synced before its sync is recorded by Track, by up to syncPollPeriod
+			if ti.syncedAt.IsZero() {
+				ti.syncedAt = time.Now()
+			}
+			status.SyncDuration = ti.syncedAt.Sub(t.start).Round(time.Millisecond).String()
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Synced returns whether all the tracked informers have completed their initial sync
+func (t *Tracker) Synced() bool {
+	for _, status := range t.Status() {
+		if !status.Synced {
+			return false
+		}
+	}
+	return true
+}
+
+// LastSync returns the last time the cache received data from the API server: the end of the initial sync of the
+// informers, or the last watch event received since. It returns the zero time until all the informers have synced.
+func (t *Tracker) LastSync() time.Time {
+	if !t.Synced() {
+		return time.Time{}
+	}
+
+	t.mu.Lock()
+	var lastSync time.Time
+	for _, ti := range t.informers {
+		if ti.syncedAt.After(lastSync) {
+			lastSync = ti.syncedAt
+		}
+	}
+	t.mu.Unlock()
+
+	if lastEvent := time.Unix(0, t.lastEvent.Load()); lastEvent.After(lastSync) {
+		return lastEvent
+	}
+	return lastSync
+}
+
+// ReadyzCheck reports whether all the tracked informers have synced, along with the progress of each one
+func (t *Tracker) ReadyzCheck() (bool, any) {
+	statuses := t.Status()
+	for _, status := range statuses {
+		if !status.Synced {
+			return false, statuses
+		}
+	}
+	return true, statuses
+}
+
+// LogProgress periodically logs the sync progress of every tracked informer until all of them have synced,
+// or until the context is cancelled
+func (t *Tracker) LogProgress(ctx context.Context, interval time.Duration) {
+	logger := klog.FromContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		synced := true
+		for _, status := range t.Status() {
+			if status.Synced {
+				logger.V(5).Info("Informer synced", "informer", status.Name, "objects", status.Objects, "syncDuration", status.SyncDuration)
+				continue
+			}
+			synced = false
+			logger.Info("Informer is still syncing", "informer", status.Name, "objects", status.Objects)
+		}
+		if synced {
+			logger.Info("All informers synced", "duration", time.Since(t.start).Round(time.Millisecond))
+			return
+		}
+	}
+}
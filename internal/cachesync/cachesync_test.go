@@ -0,0 +1,183 @@
+package cachesync
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeInformer is a minimal Informer implementation for testing purposes
+type fakeInformer struct {
+	handler toolscache.ResourceEventHandler
+	synced  atomic.Bool
+}
+
+func newFakeInformer(synced bool) *fakeInformer {
+	f := &fakeInformer{}
+	f.synced.Store(synced)
+	return f
+}
+
+func (f *fakeInformer) AddEventHandler(handler toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error) {
+	f.handler = handler
+	return nil, nil
+}
+
+func (f *fakeInformer) HasSynced() bool {
+	return f.synced.Load()
+}
+
+func TestTracker_Status(t *testing.T) {
+	tracker := NewTracker()
+	deployments := newFakeInformer(false)
+	pods := newFakeInformer(true)
+	if err := tracker.Track(context.Background(), "deployments", deployments); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	if err := tracker.Track(context.Background(), "pods", pods); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+
+	// Only objects from the initial list count towards the sync progress
+	deployments.handler.OnAdd("foo", true)
+	deployments.handler.OnAdd("bar", true)
+	deployments.handler.OnAdd("baz", false)
+
+	statuses := tracker.Status()
+	if len(statuses) != 2 {
+		t.Fatalf("Status() returned %d informers, want 2", len(statuses))
+	}
+	if statuses[0].Name != "deployments" || statuses[0].Synced || statuses[0].Objects != 2 || statuses[0].SyncDuration != "" {
+		t.Errorf("Status()[0] = %+v, want deployments not synced with 2 objects", statuses[0])
+	}
+	if statuses[1].Name != "pods" || !statuses[1].Synced || statuses[1].SyncDuration == "" {
+		t.Errorf("Status()[1] = %+v, want pods synced with a sync duration", statuses[1])
+	}
+	if tracker.Synced() {
+		t.Errorf("Synced() = true, want false")
+	}
+
+	deployments.synced.Store(true)
+	if !tracker.Synced() {
+		t.Errorf("Synced() = false, want true")
+	}
+}
+
+func TestTracker_LastSync(t *testing.T) {
+	tracker := NewTracker()
+	deployments := newFakeInformer(false)
+	if err := tracker.Track(context.Background(), "deployments", deployments); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	if lastSync := tracker.LastSync(); !lastSync.IsZero() {
+		t.Errorf("LastSync() before the initial sync = %v, want zero", lastSync)
+	}
+
+	deployments.synced.Store(true)
+	synced := tracker.LastSync()
+	if synced.IsZero() {
+		t.Fatalf("LastSync() after the initial sync = zero, want the sync time")
+	}
+
+	// Watch events move the last sync forward
+	time.Sleep(time.Millisecond)
+	deployments.handler.OnUpdate("foo", "foo")
+	if lastSync := tracker.LastSync(); !lastSync.After(synced) {
+		t.Errorf("LastSync() after a watch event = %v, want after %v", lastSync, synced)
+	}
+}
+
+func TestTracker_SyncedAt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracker := NewTracker()
+	deployments := newFakeInformer(false)
+	if err := tracker.Track(ctx, "deployments", deployments); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+
+	// The sync is recorded as it happens, rather than when the status is next read
+	deployments.synced.Store(true)
+	synced := time.Now()
+	time.Sleep(10 * syncPollPeriod)
+	if lastSync := tracker.LastSync(); lastSync.Sub(synced) > 5*syncPollPeriod {
+		t.Errorf("LastSync() = %v, want about %v", lastSync, synced)
+	}
+	if duration, _ := time.ParseDuration(tracker.Status()[0].SyncDuration); duration > synced.Sub(tracker.start)+5*syncPollPeriod {
+		t.Errorf("SyncDuration = %v, want about %v", duration, synced.Sub(tracker.start))
+	}
+}
+
+func TestTracker_Progress(t *testing.T) {
+	tests := []struct {
+		name             string
+		expected         int64
+		objects          int
+		synced           bool
+		expectedProgress int
+	}{
+		{name: "unknown expected objects", objects: 3, expectedProgress: 0},
+		{name: "partial initial list", expected: 4, objects: 1, expectedProgress: 25},
+		{name: "all the expected objects before the sync", expected: 4, objects: 5, expectedProgress: 99},
+		{name: "synced", expected: 4, objects: 4, synced: true, expectedProgress: 100},
+		{name: "synced with unknown expected objects", synced: true, expectedProgress: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker()
+			deployments := newFakeInformer(tt.synced)
+			if err := tracker.Track(context.Background(), "deployments", deployments); err != nil {
+				t.Fatalf("Track() error = %v", err)
+			}
+			tracker.SetExpected("deployments", tt.expected)
+			for range tt.objects {
+				deployments.handler.OnAdd("foo", true)
+			}
+
+			status := tracker.Status()[0]
+			if status.Progress != tt.expectedProgress {
+				t.Errorf("Progress = %v, want %v", status.Progress, tt.expectedProgress)
+			}
+			if status.Expected != tt.expected {
+				t.Errorf("Expected = %v, want %v", status.Expected, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCountObjects(t *testing.T) {
+	var objects []client.Object
+	for _, d := range []struct{ namespace, name string }{{"default", "foo"}, {"default", "bar"}, {"other", "baz"}} {
+		objects = append(objects, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: d.namespace, Name: d.name}})
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+	gvk := appsv1.SchemeGroupVersion.WithKind("Deployment")
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		expected   int64
+	}{
+		{name: "all namespaces", expected: 3},
+		{name: "cached namespaces", namespaces: []string{"default", "empty"}, expected: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := CountObjects(context.Background(), c, gvk, tt.namespaces)
+			if err != nil {
+				t.Fatalf("CountObjects() error = %v", err)
+			}
+			if count != tt.expected {
+				t.Errorf("CountObjects() = %v, want %v", count, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package cachetransform
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+// StripServerFields returns a cache transform function which strips fields the API never serves from objects before
+// they are stored in the cache, in order to reduce the cache's memory footprint:
+//   - managedFields, if stripManagedFields is set
+//   - the kubectl last-applied-configuration annotation (which holds a full copy of the object), if stripLastApplied is set
+func StripServerFields(stripManagedFields, stripLastApplied bool) toolscache.TransformFunc {
+	return func(in any) (any, error) {
+		obj, err := meta.Accessor(in)
+		if err != nil {
+			// Not an object (e.g. a DeletedFinalStateUnknown tombstone), leave it as is
+			return in, nil
+		}
+
+		// Nil-check managed fields to avoid hitting https://github.com/kubernetes/kubernetes/issues/124337
+		if stripManagedFields && obj.GetManagedFields() != nil {
+			obj.SetManagedFields(nil)
+		}
+
+		if annotations := obj.GetAnnotations(); stripLastApplied && annotations != nil {
+			if _, ok := annotations[corev1.LastAppliedConfigAnnotation]; ok {
+				delete(annotations, corev1.LastAppliedConfigAnnotation)
+				obj.SetAnnotations(annotations)
+			}
+		}
+
+		return in, nil
+	}
+}
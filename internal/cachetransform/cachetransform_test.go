@@ -0,0 +1,71 @@
+package cachetransform
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-deployment",
+			Namespace: "test-namespace",
+			Annotations: map[string]string{
+				corev1.LastAppliedConfigAnnotation: "{\"kind\":\"Deployment\"}",
+				"foo":                              "bar",
+			},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+	}
+}
+
+func TestStripServerFields(t *testing.T) {
+	tests := []struct {
+		name                string
+		stripManagedFields  bool
+		stripLastApplied    bool
+		expectedAnnotations map[string]string
+		expectManagedFields bool
+	}{
+		{
+			"Test StripServerFields All",
+			true,
+			true,
+			map[string]string{"foo": "bar"},
+			false,
+		},
+		{
+			"Test StripServerFields Managed Fields Only",
+			true,
+			false,
+			map[string]string{corev1.LastAppliedConfigAnnotation: "{\"kind\":\"Deployment\"}", "foo": "bar"},
+			false,
+		},
+		{
+			"Test StripServerFields Last Applied Only",
+			false,
+			true,
+			map[string]string{"foo": "bar"},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := StripServerFields(tt.stripManagedFields, tt.stripLastApplied)(newTestDeployment())
+			if err != nil {
+				t.Fatalf("StripServerFields() error = %v", err)
+			}
+			d := out.(*appsv1.Deployment)
+			if !reflect.DeepEqual(d.Annotations, tt.expectedAnnotations) {
+				t.Errorf("StripServerFields() annotations = %v, want %v", d.Annotations, tt.expectedAnnotations)
+			}
+			if (d.ManagedFields != nil) != tt.expectManagedFields {
+				t.Errorf("StripServerFields() managedFields = %v, expectManagedFields %v", d.ManagedFields, tt.expectManagedFields)
+			}
+		})
+	}
+}
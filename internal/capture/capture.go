@@ -0,0 +1,395 @@
+// Package capture records the requests to a chosen route, along with their responses, to debug the integration of a
+// client without asking its users for the requests they make. The capture is opt-in: it's started by an admin for a
+// single route, and optionally a single identity, for a limited time. The exchanges are sanitized before they're kept
+// in a ring buffer in memory: the credentials are left out of the headers, and the bodies are redacted with the audit
+// redaction rules.
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/audit"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/yaml"
+)
+
+// Durations of the sessions
+const (
+	// DefaultDuration is the duration of the sessions started without one
+	DefaultDuration = 10 * time.Minute
+	// MaxDuration is the longest a session may last, so that a capture left behind stops on its own
+	MaxDuration = time.Hour
+)
+
+// ErrUnknownRoute is returned when starting a capture of a route which isn't registered
+var ErrUnknownRoute = errors.New("unknown route")
+
+// sensitiveHeaders are the headers carrying credentials, whose values are never kept
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", auth.APIKeyHeader}
+
+var capturedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "k8s_api_proxy_captured_exchanges_total",
+	Help: "Number of requests recorded, along with their responses, by the captures, by route",
+}, []string{"route"})
+
+func init() {
+	metrics.Registry.MustRegister(capturedTotal)
+}
+
+// Session is a capture, as reported by /admin/capture
+type Session struct {
+	// Route is the pattern of the route captured, e.g. GET /deployments/{namespace}/{deployment}
+	Route string `json:"route"`
+	// Identity restricts the capture to the requests of a single identity, when set
+	Identity string    `json:"identity,omitempty"`
+	Started  time.Time `json:"started"`
+	Until    time.Time `json:"until"`
+	// Active is whether the requests are still being captured, i.e. the session hasn't expired
+	Active bool `json:"active"`
+	// Captured is the number of exchanges captured since the session started, some of which may have been evicted
+	Captured int `json:"captured"`
+}
+
+// Exchange is a sanitized request, along with its response
+type Exchange struct {
+	// ID increases with every exchange, so that the captured exchanges can be polled for the ones after the latest seen
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"requestID,omitempty"`
+	Identity  string    `json:"identity,omitempty"`
+	Route     string    `json:"route"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Query     string    `json:"query,omitempty"`
+	// RequestHeaders and ResponseHeaders are the headers, whose credentials are redacted
+	RequestHeaders http.Header `json:"requestHeaders,omitempty"`
+	// RequestBody and ResponseBody are the redacted bodies, when they are JSON (or YAML for the requests)
+	RequestBody json.RawMessage `json:"requestBody,omitempty"`
+	// RequestBodyOmitted and ResponseBodyOmitted are the reasons the bodies were left out, e.g. when they can't be parsed
+	// for redaction
+	RequestBodyOmitted  string          `json:"requestBodyOmitted,omitempty"`
+	Status              int             `json:"status"`
+	ResponseHeaders     http.Header     `json:"responseHeaders,omitempty"`
+	ResponseBody        json.RawMessage `json:"responseBody,omitempty"`
+	ResponseBodyOmitted string          `json:"responseBodyOmitted,omitempty"`
+	Duration            string          `json:"duration"`
+	// Curl is a curl command replaying the request, without its credentials
+	Curl string `json:"curl"`
+}
+
+// Recorder captures the exchanges of the routes of a mux
+type Recorder struct {
+	mux          *http.ServeMux
+	size         int
+	maxBodyBytes int
+	rules        []audit.Rule
+	now          func() time.Time
+
+	mu        sync.Mutex
+	session   *Session
+	exchanges []Exchange
+	lastID    int64
+}
+
+// NewRecorder returns a new Recorder of the routes of the mux, keeping the latest size exchanges, whose bodies are
+// redacted with the given rules and left out when larger than maxBodyBytes
+func NewRecorder(mux *http.ServeMux, size, maxBodyBytes int, rules []audit.Rule) *Recorder {
+	return &Recorder{mux: mux, size: size, maxBodyBytes: maxBodyBytes, rules: rules, now: time.Now}
+}
+
+// Start starts capturing the requests to the route, of the given identity if not empty, for the given duration. The
+// exchanges of the previous session, if any, are discarded.
+func (rec *Recorder) Start(route, identity string, duration time.Duration) (Session, error) {
+	if !rec.registered(route) {
+		return Session{}, fmt.Errorf("%w %q", ErrUnknownRoute, route)
+	}
+	now := rec.now().UTC()
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.session = &Session{Route: route, Identity: identity, Started: now, Until: now.Add(duration)}
+	rec.exchanges = nil
+	return rec.status(now), nil
+}
+
+// Stop stops capturing, and discards the captured exchanges
+func (rec *Recorder) Stop() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.session = nil
+	rec.exchanges = nil
+}
+
+// Session returns the current session, if any, which may have expired
+func (rec *Recorder) Session() (Session, bool) {
+	now := rec.now()
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.session == nil {
+		return Session{}, false
+	}
+	return rec.status(now), true
+}
+
+// status returns the current session. It must be called with the lock held, and a session.
+func (rec *Recorder) status(now time.Time) Session {
+	session := *rec.session
+	session.Active = now.Before(session.Until)
+	return session
+}
+
+// Exchanges returns the captured exchanges after the given ID, oldest first
+func (rec *Recorder) Exchanges(after int64) []Exchange {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	exchanges := []Exchange{}
+	for _, exchange := range rec.exchanges {
+		if exchange.ID > after {
+			exchanges = append(exchanges, exchange)
+		}
+	}
+	return exchanges
+}
+
+// registered returns whether the route is the pattern of a route of the mux, by matching a request made of the
+// pattern itself, its wildcards standing for any value
+func (rec *Recorder) registered(route string) bool {
+	method, path, ok := strings.Cut(route, " ")
+	if !ok || method == "" || !strings.HasPrefix(path, "/") {
+		return false
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "{$}":
+			segments[i] = ""
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			segments[i] = "x"
+		}
+	}
+	_, pattern := rec.mux.Handler(&http.Request{Method: method, URL: &url.URL{Path: strings.Join(segments, "/")}})
+	return pattern == route
+}
+
+// route returns the pattern of the route of the mux matching the request. The requests to the versioned API are
+// matched by their unversioned route.
+func (rec *Recorder) route(r *http.Request) string {
+	lookup := r
+	if path := strings.TrimPrefix(r.URL.Path, apiversion.Prefix); path != r.URL.Path && strings.HasPrefix(path, "/") {
+		lookup = &http.Request{Method: r.Method, Host: r.Host, URL: &url.URL{Path: path}}
+	}
+	_, pattern := rec.mux.Handler(lookup)
+	return pattern
+}
+
+// capturing returns the active session, if the request is to its route
+func (rec *Recorder) capturing(r *http.Request) (Session, bool) {
+	now := rec.now()
+	rec.mu.Lock()
+	if rec.session == nil {
+		rec.mu.Unlock()
+		return Session{}, false
+	}
+	session := rec.status(now)
+	rec.mu.Unlock()
+	if !session.Active || rec.route(r) != session.Route {
+		return Session{}, false
+	}
+	return session, true
+}
+
+// record adds the exchange to the buffer, evicting the oldest one once full, unless the session changed in between
+func (rec *Recorder) record(session Session, exchange Exchange) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.session == nil || !rec.session.Started.Equal(session.Started) {
+		return
+	}
+	rec.session.Captured++
+	rec.lastID++
+	exchange.ID = rec.lastID
+	if len(rec.exchanges) == rec.size {
+		copy(rec.exchanges, rec.exchanges[1:])
+		rec.exchanges = rec.exchanges[:len(rec.exchanges)-1]
+	}
+	rec.exchanges = append(rec.exchanges, exchange)
+	capturedTotal.WithLabelValues(session.Route).Inc()
+}
+
+// recorder passes the response through, while keeping a copy of its status code and of the start of its body
+type recorder struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if room := r.limit - r.body.Len(); len(b) > room {
+		r.truncated = true
+		r.body.Write(b[:max(room, 0)])
+	} else {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so that http.ResponseController can reach it, e.g. to flush the
+// events of a watch
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Middleware returns a new http.Handler which records the requests to the route of the active session, if any, along
+// with their responses. The other requests are passed to the provided handler as is.
+func (rec *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, ok := rec.capturing(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := rec.now()
+
+		// The start of the body is read up front, and served again to the handler along with the rest of it
+		var requestBody []byte
+		var requestErr error
+		if r.Body != nil && r.Body != http.NoBody {
+			requestBody, requestErr = io.ReadAll(io.LimitReader(r.Body, int64(rec.maxBodyBytes)+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(requestBody), r.Body), r.Body}
+		}
+
+		rw := &recorder{ResponseWriter: w, limit: rec.maxBodyBytes}
+		next.ServeHTTP(rw, r)
+
+		identity := auth.Identity(r)
+		if session.Identity != "" && identity != session.Identity {
+			return
+		}
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		exchange := Exchange{
+			Time:            start.UTC(),
+			RequestID:       w.Header().Get(logging.RequestIDHeader),
+			Identity:        identity,
+			Route:           session.Route,
+			Method:          r.Method,
+			Path:            r.URL.Path,
+			Query:           r.URL.RawQuery,
+			RequestHeaders:  sanitize(r.Header),
+			Status:          status,
+			ResponseHeaders: sanitize(w.Header()),
+			Duration:        time.Since(start).String(),
+		}
+		if requestErr != nil {
+			exchange.RequestBodyOmitted = fmt.Sprintf("failed to read the body: %v", requestErr)
+		} else {
+			exchange.RequestBody, exchange.RequestBodyOmitted = rec.body(requestBody, len(requestBody) > rec.maxBodyBytes, audit.BodyRequest)
+		}
+		exchange.ResponseBody, exchange.ResponseBodyOmitted = rec.body(rw.body.Bytes(), rw.truncated, audit.BodyResponse)
+		exchange.Curl = curl(r, exchange.RequestHeaders, exchange.RequestBody)
+		rec.record(session, exchange)
+		klog.FromContext(r.Context()).V(4).Info("Captured request", "route", session.Route, "status", status)
+	})
+}
+
+// body returns the redacted body, or the reason it's omitted. Bodies which can't be parsed are omitted as a whole,
+// since they can't be redacted.
+func (rec *Recorder) body(raw []byte, truncated bool, target string) (json.RawMessage, string) {
+	switch {
+	case len(bytes.TrimSpace(raw)) == 0:
+		return nil, ""
+	case truncated:
+		return nil, fmt.Sprintf("body larger than %d bytes", rec.maxBodyBytes)
+	}
+	var document any
+	if err := json.Unmarshal(raw, &document); err != nil {
+		// Requests may also be sent as YAML, e.g. the manifests to apply
+		converted, yamlErr := yaml.YAMLToJSON(raw)
+		if yamlErr != nil || json.Unmarshal(converted, &document) != nil {
+			return nil, "body isn't a JSON or YAML document"
+		}
+	}
+	audit.Redact(rec.rules, document, target)
+	redacted, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Sprintf("failed to encode the body: %v", err)
+	}
+	return redacted, ""
+}
+
+// sanitize returns a copy of the headers, whose credentials are redacted
+func sanitize(header http.Header) http.Header {
+	sanitized := header.Clone()
+	for name := range sanitized {
+		if slices.ContainsFunc(sensitiveHeaders, func(sensitive string) bool { return strings.EqualFold(name, sensitive) }) {
+			sanitized[name] = []string{audit.Redacted}
+		}
+	}
+	return sanitized
+}
+
+// curl returns a curl command replaying the request with the sanitized headers and the redacted body, leaving out the
+// credentials, which the client replaying it adds on its own
+func curl(r *http.Request, header http.Header, body json.RawMessage) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	target := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	command := []string{"curl", "-X", r.Method, quote(target.String())}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "Content-Length" || slices.ContainsFunc(sensitiveHeaders, func(sensitive string) bool { return strings.EqualFold(name, sensitive) }) {
+			continue
+		}
+		for _, value := range header[name] {
+			command = append(command, "-H", quote(name+": "+value))
+		}
+	}
+	if len(body) > 0 {
+		command = append(command, "--data-binary", quote(string(body)))
+	}
+	return strings.Join(command, " ")
+}
+
+// quote quotes the value for a POSIX shell
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,150 @@
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/audit"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+)
+
+const replicasRoute = "PUT /deployments/{namespace}/{deployment}/replicas"
+
+func newTestRecorder(t *testing.T, size int) *Recorder {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc(replicasRoute, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=s3cr3t")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+	})
+	mux.HandleFunc("GET /deployments", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("[]"))
+	})
+	mux.Handle(apiversion.Prefix+"/", apiversion.Handler(mux))
+	rules, err := audit.NewRules(audit.RulesConfig{Rules: []audit.RuleConfig{{JSONPath: "$.token"}}})
+	if err != nil {
+		t.Fatalf("NewRules() error = %v", err)
+	}
+	return NewRecorder(mux, size, 1024, rules)
+}
+
+func serve(rec *Recorder, method, path, identity, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer s3cr3t")
+	r.Header.Set("Content-Type", "application/json")
+	if identity != "" {
+		r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{Name: identity}))
+	}
+	w := httptest.NewRecorder()
+	rec.Middleware(rec.mux).ServeHTTP(w, r)
+	return w
+}
+
+func TestRecorder_Start(t *testing.T) {
+	rec := newTestRecorder(t, 10)
+	tests := []struct {
+		route       string
+		expectedErr error
+	}{
+		{route: replicasRoute},
+		{route: "GET /deployments"},
+		{route: "GET /deployments/{namespace}/{deployment}/replicas", expectedErr: ErrUnknownRoute},
+		{route: "/deployments", expectedErr: ErrUnknownRoute},
+		{route: "GET /pods", expectedErr: ErrUnknownRoute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			if _, err := rec.Start(tt.route, "", time.Minute); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Start() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRecorder_Middleware(t *testing.T) {
+	rec := newTestRecorder(t, 2)
+	if _, err := rec.Start(replicasRoute, "ci-bot", time.Minute); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// The request is served as is, its body included
+	w := serve(rec, "PUT", "/deployments/default/foo/replicas?dryRun=true", "ci-bot", `{"replicas": 3, "token": "s3cr3t"}`)
+	if body := w.Body.String(); body != `{"replicas": 3, "token": "s3cr3t"}` {
+		t.Errorf("response body = %s, want the request body echoed", body)
+	}
+	// Neither the other routes, nor the other identities are captured
+	serve(rec, "GET", "/deployments", "ci-bot", "")
+	serve(rec, "PUT", "/deployments/default/foo/replicas", "alice", `{"replicas": 1}`)
+	// The versioned API is captured along with its unversioned route
+	serve(rec, "PUT", "/v1/deployments/default/foo/replicas", "ci-bot", `{"replicas": 4}`)
+
+	exchanges := rec.Exchanges(0)
+	if len(exchanges) != 2 {
+		t.Fatalf("Exchanges() = %d exchanges, want 2", len(exchanges))
+	}
+	exchange := exchanges[0]
+	if exchange.ID != 1 || exchange.Identity != "ci-bot" || exchange.Route != replicasRoute || exchange.Query != "dryRun=true" ||
+		exchange.Status != http.StatusOK {
+		t.Errorf("exchange = %+v, want the request of ci-bot", exchange)
+	}
+	if expected := `{"replicas":3,"token":"REDACTED"}`; string(exchange.RequestBody) != expected || string(exchange.ResponseBody) != expected {
+		t.Errorf("bodies = %s and %s, want %s", exchange.RequestBody, exchange.ResponseBody, expected)
+	}
+	if exchange.RequestHeaders.Get("Authorization") != audit.Redacted || exchange.ResponseHeaders.Get("Set-Cookie") != audit.Redacted {
+		t.Errorf("headers = %v and %v, want the credentials redacted", exchange.RequestHeaders, exchange.ResponseHeaders)
+	}
+	expectedCurl := `curl -X PUT 'http://example.com/deployments/default/foo/replicas?dryRun=true' -H 'Content-Type: application/json' --data-binary '{"replicas":3,"token":"REDACTED"}'`
+	if exchange.Curl != expectedCurl {
+		t.Errorf("Curl = %s, want %s", exchange.Curl, expectedCurl)
+	}
+	if exchanges[1].Path != "/v1/deployments/default/foo/replicas" {
+		t.Errorf("Path = %s, want the versioned path", exchanges[1].Path)
+	}
+
+	// The oldest exchanges are evicted once the buffer is full
+	serve(rec, "PUT", "/deployments/default/foo/replicas", "ci-bot", "not: [json")
+	exchanges = rec.Exchanges(1)
+	if len(exchanges) != 2 || exchanges[0].ID != 2 || exchanges[1].RequestBodyOmitted == "" {
+		t.Errorf("Exchanges(1) = %+v, want the 2 latest exchanges, the body of the latest left out", exchanges)
+	}
+	if session, _ := rec.Session(); session.Captured != 3 || !session.Active {
+		t.Errorf("Session() = %+v, want 3 exchanges captured", session)
+	}
+
+	rec.Stop()
+	if _, ok := rec.Session(); ok || len(rec.Exchanges(0)) != 0 {
+		t.Errorf("the capture wasn't discarded once stopped")
+	}
+	serve(rec, "PUT", "/deployments/default/foo/replicas", "ci-bot", `{"replicas": 3}`)
+	if len(rec.Exchanges(0)) != 0 {
+		t.Errorf("a request was captured once stopped")
+	}
+}
+
+func TestRecorder_Expiry(t *testing.T) {
+	rec := newTestRecorder(t, 10)
+	now := time.Now()
+	rec.now = func() time.Time { return now }
+	if _, err := rec.Start(replicasRoute, "", time.Minute); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+
+	serve(rec, "PUT", "/deployments/default/foo/replicas", "ci-bot", `{"replicas": 3}`)
+	session, ok := rec.Session()
+	if !ok || session.Active || len(rec.Exchanges(0)) != 0 {
+		t.Errorf("Session() = %+v, want an inactive session without exchanges", session)
+	}
+	if encoded, _ := json.Marshal(session); !strings.Contains(string(encoded), `"active":false`) {
+		t.Errorf("session = %s, want inactive", encoded)
+	}
+}
@@ -0,0 +1,135 @@
+// Package certexpiry periodically checks the expiry of the serving certificate and of the client CAs of the main
+// server, so that a failed rotation surfaces in the metrics, the logs and /healthz before the certificates expire and
+// the clients can't connect anymore.
+package certexpiry
+
+import (
+	"context"
+	"crypto/x509"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/clientca"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Kinds of the certificates checked
+const (
+	// KindServing is the kind of the serving certificate of the main server
+	KindServing = "serving"
+	// KindClientCA is the kind of the CAs verifying the client certificates
+	KindClientCA = "client-ca"
+)
+
+var expiryDays = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "k8s_api_proxy_certificate_expiry_days",
+	Help: "Number of days until the certificates of the main server expire, negative once expired, by kind and name",
+}, []string{"kind", "name"})
+
+func init() {
+	metrics.Registry.MustRegister(expiryDays)
+}
+
+// Certificate is the expiry of a certificate of the main server
+type Certificate struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Bundle is the path of the bundle of the client CAs
+	Bundle          string    `json:"bundle,omitempty"`
+	NotAfter        time.Time `json:"notAfter"`
+	DaysUntilExpiry float64   `json:"daysUntilExpiry"`
+	// Expiring is whether the certificate expires within the warning threshold, or has expired
+	Expiring bool `json:"expiring"`
+}
+
+// Checker checks the expiry of the serving certificate and of the client CAs of the main server
+type Checker struct {
+	serving   *x509.Certificate
+	clientCAs *clientca.Pool
+	threshold time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	latest []Certificate
+}
+
+// New returns a new Checker of the serving certificate and of the client CAs of the pool, as currently loaded, which
+// warns about the certificates expiring within the threshold
+func New(serving *x509.Certificate, clientCAs *clientca.Pool, threshold time.Duration) *Checker {
+	return &Checker{serving: serving, clientCAs: clientCAs, threshold: threshold, now: time.Now}
+}
+
+// Check checks the expiry of the certificates, exports it as metrics, and logs the certificates expiring within the
+// threshold
+func (c *Checker) Check(ctx context.Context) []Certificate {
+	logger := klog.FromContext(ctx)
+	now := c.now()
+	var certificates []Certificate
+	if c.serving != nil {
+		certificates = append(certificates, c.certificate(now, KindServing, clientca.Name(c.serving), "", c.serving.NotAfter))
+	}
+	if c.clientCAs != nil {
+		for _, ca := range c.clientCAs.CAs() {
+			certificates = append(certificates, c.certificate(now, KindClientCA, ca.Name, ca.Bundle, ca.NotAfter))
+		}
+	}
+
+	// The CAs removed from their bundles are removed from the metrics too
+	expiryDays.Reset()
+	for _, cert := range certificates {
+		expiryDays.WithLabelValues(cert.Kind, cert.Name).Set(cert.DaysUntilExpiry)
+		switch {
+		case !cert.NotAfter.After(now):
+			logger.Error(nil, "Certificate expired", "kind", cert.Kind, "name", cert.Name, "bundle", cert.Bundle, "notAfter", cert.NotAfter)
+		case cert.Expiring:
+			logger.Info("Certificate expiring soon, it should be rotated", "kind", cert.Kind, "name", cert.Name, "bundle", cert.Bundle,
+				"notAfter", cert.NotAfter, "daysUntilExpiry", cert.DaysUntilExpiry)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.latest = certificates
+	return certificates
+}
+
+// certificate returns the expiry of a certificate at the given time
+func (c *Checker) certificate(now time.Time, kind, name, bundle string, notAfter time.Time) Certificate {
+	remaining := notAfter.Sub(now)
+	return Certificate{
+		Kind:            kind,
+		Name:            name,
+		Bundle:          bundle,
+		NotAfter:        notAfter.UTC(),
+		DaysUntilExpiry: remaining.Hours() / 24,
+		Expiring:        remaining < c.threshold,
+	}
+}
+
+// Run checks the certificates right away, and then every interval until the context is done
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// HealthzCheck reports the expiry of the certificates as of the latest check, and whether none of them is expiring
+func (c *Checker) HealthzCheck() (bool, any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cert := range c.latest {
+		if cert.Expiring {
+			return false, c.latest
+		}
+	}
+	return true, c.latest
+}
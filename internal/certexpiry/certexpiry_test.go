@@ -0,0 +1,68 @@
+package certexpiry
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/clientca"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/testenv"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestChecker_Check(t *testing.T) {
+	pool, err := clientca.Load([]string{testenv.NewCertificates(t).CACert})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	ca := pool.CAs()[0]
+	serving := &x509.Certificate{Subject: pkix.Name{CommonName: "k8s-api-proxy"}, NotAfter: ca.NotAfter.Add(90 * 24 * time.Hour)}
+	checker := New(serving, pool, 30*24*time.Hour)
+
+	tests := []struct {
+		name            string
+		now             time.Time
+		expectedServing Certificate
+		expectedCA      Certificate
+		expectedHealthy bool
+	}{
+		{
+			name:            "valid certificates",
+			now:             ca.NotAfter.Add(-60 * 24 * time.Hour),
+			expectedServing: Certificate{Kind: KindServing, Name: "k8s-api-proxy", NotAfter: serving.NotAfter, DaysUntilExpiry: serving.NotAfter.Sub(ca.NotAfter.Add(-60*24*time.Hour)).Hours() / 24},
+			expectedCA:      Certificate{Kind: KindClientCA, Name: "testenv-ca", Bundle: ca.Bundle, NotAfter: ca.NotAfter.UTC(), DaysUntilExpiry: 60},
+			expectedHealthy: true,
+		},
+		{
+			name:            "client CA expiring",
+			now:             ca.NotAfter.Add(-12 * time.Hour),
+			expectedServing: Certificate{Kind: KindServing, Name: "k8s-api-proxy", NotAfter: serving.NotAfter, DaysUntilExpiry: serving.NotAfter.Sub(ca.NotAfter.Add(-12*time.Hour)).Hours() / 24},
+			expectedCA:      Certificate{Kind: KindClientCA, Name: "testenv-ca", Bundle: ca.Bundle, NotAfter: ca.NotAfter.UTC(), DaysUntilExpiry: 0.5, Expiring: true},
+		},
+		{
+			name:            "client CA expired",
+			now:             ca.NotAfter.Add(24 * time.Hour),
+			expectedServing: Certificate{Kind: KindServing, Name: "k8s-api-proxy", NotAfter: serving.NotAfter, DaysUntilExpiry: serving.NotAfter.Sub(ca.NotAfter.Add(24*time.Hour)).Hours() / 24},
+			expectedCA:      Certificate{Kind: KindClientCA, Name: "testenv-ca", Bundle: ca.Bundle, NotAfter: ca.NotAfter.UTC(), DaysUntilExpiry: -1, Expiring: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.expectedServing.Expiring = tt.expectedServing.DaysUntilExpiry < 30
+			checker.now = func() time.Time { return tt.now }
+			certificates := checker.Check(context.Background())
+			if len(certificates) != 2 || certificates[0] != tt.expectedServing || certificates[1] != tt.expectedCA {
+				t.Fatalf("Check() = %+v, want %+v and %+v", certificates, tt.expectedServing, tt.expectedCA)
+			}
+			if got := testutil.ToFloat64(expiryDays.WithLabelValues(KindClientCA, "testenv-ca")); got != tt.expectedCA.DaysUntilExpiry {
+				t.Errorf("expiry days of the client CA = %v, want %v", got, tt.expectedCA.DaysUntilExpiry)
+			}
+			healthy, details := checker.HealthzCheck()
+			if healthy != tt.expectedHealthy || len(details.([]Certificate)) != 2 {
+				t.Errorf("HealthzCheck() = %v, %+v, want healthy %v", healthy, details, tt.expectedHealthy)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Package clientca loads the CA bundles verifying the client certificates of the main server, e.g. both the old and
+// the new corporate CA during a rotation, and reloads them whenever their files change, without restarting the server
+// nor dropping its connections.
+package clientca
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var reloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "k8s_api_proxy_client_ca_reloads_total",
+	Help: "Number of reloads of the client CA bundles after their files changed, by result",
+}, []string{"result"})
+
+func init() {
+	metrics.Registry.MustRegister(reloadsTotal)
+}
+
+// CA is a certificate authority of a client CA bundle
+type CA struct {
+	// Name is the name of the CA, as reported for the clients it verified
+	Name     string
+	Bundle   string
+	NotAfter time.Time
+}
+
+// Pool is the pool of the client CAs, out of the CA bundles at the given paths
+type Pool struct {
+	paths []string
+
+	mu       sync.RWMutex
+	pool     *x509.CertPool
+	cas      []CA
+	checksum [sha256.Size]byte
+}
+
+// Load returns a new Pool of the client CAs of the bundles at the given paths, which must each hold at least one PEM
+// encoded certificate
+func Load(paths []string) (*Pool, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no client CA bundle")
+	}
+	p := &Pool{paths: paths}
+	if _, err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Name returns the name of a CA, as reported for the clients it verified: its Common Name, or its whole subject when
+// it has none
+func Name(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return cert.Subject.String()
+}
+
+// Reload loads the bundles again, and returns whether they changed. On error, e.g. while a bundle is being rewritten,
+// the CAs previously loaded are kept.
+func (p *Pool) Reload() (bool, error) {
+	hash := sha256.New()
+	pool := x509.NewCertPool()
+	var cas []CA
+	for _, path := range p.paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		hash.Write(data)
+		certs, err := parse(data)
+		if err != nil {
+			return false, fmt.Errorf("invalid client CA bundle %q: %w", path, err)
+		}
+		for _, cert := range certs {
+			pool.AddCert(cert)
+			cas = append(cas, CA{Name: Name(cert), Bundle: path, NotAfter: cert.NotAfter})
+		}
+	}
+
+	var checksum [sha256.Size]byte
+	copy(checksum[:], hash.Sum(nil))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if checksum == p.checksum {
+		return false, nil
+	}
+	p.pool, p.cas, p.checksum = pool, cas, checksum
+	return true, nil
+}
+
+// parse returns the certificates of a PEM bundle
+func parse(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificate")
+	}
+	return certs, nil
+}
+
+// CertPool returns the pool of the client CAs currently loaded
+func (p *Pool) CertPool() *x509.CertPool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pool
+}
+
+// CAs returns the client CAs currently loaded, in the order of their bundles
+func (p *Pool) CAs() []CA {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.cas
+}
+
+// ConfigForClient returns a tls.Config GetConfigForClient hook, which verifies the client certificates with the CAs
+// currently loaded, and with the given configuration otherwise, so that the reloaded CAs apply to the new connections
+func (p *Pool) ConfigForClient(base *tls.Config) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		config := base.Clone()
+		config.GetConfigForClient = nil
+		config.ClientCAs = p.CertPool()
+		return config, nil
+	}
+}
+
+// Run reloads the bundles at the given interval until the context is done, logging the CAs whenever they change
+func (p *Pool) Run(ctx context.Context, interval time.Duration) {
+	logger := klog.FromContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := p.Reload()
+		switch {
+		case err != nil:
+			reloadsTotal.WithLabelValues("failure").Inc()
+			logger.Error(err, "Error reloading the client CA bundles, keeping the CAs previously loaded")
+		case changed:
+			reloadsTotal.WithLabelValues("success").Inc()
+			logger.Info("Reloaded the client CA bundles", "cas", p.CAs())
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package clientca
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/testenv"
+)
+
+// readFile returns the content of the file, failing the test otherwise
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.crt")
+	if err := os.WriteFile(empty, []byte("not a certificate\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		paths []string
+	}{
+		{"Test No Bundle", nil},
+		{"Test Missing Bundle", []string{filepath.Join(dir, "missing.crt")}},
+		{"Test Bundle Without Certificate", []string{empty}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.paths); err == nil {
+				t.Errorf("Load() expected an error")
+			}
+		})
+	}
+}
+
+func TestPool_Reload(t *testing.T) {
+	oldCA, newCA := testenv.NewCertificates(t), testenv.NewCertificates(t)
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(path, readFile(t, oldCA.CACert), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := Load([]string{path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = oldCA.ServerTLSConfig()
+	server.TLS.GetConfigForClient = pool.ConfigForClient(server.TLS)
+	server.StartTLS()
+	defer server.Close()
+
+	// The client of the new CA doesn't verify the server certificate, which is issued by the old CA
+	client := newCA.Client(t, "alice")
+	client.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
+	get := func() error {
+		client.CloseIdleConnections()
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	tests := []struct {
+		name            string
+		bundle          []byte
+		expectedChanged bool
+		expectedError   bool
+		expectedCAs     int
+		expectedAllowed bool
+	}{
+		{name: "new CA not loaded yet", expectedCAs: 1},
+		{name: "both CAs during the rotation", bundle: append(readFile(t, oldCA.CACert), readFile(t, newCA.CACert)...), expectedChanged: true, expectedCAs: 2, expectedAllowed: true},
+		{name: "unchanged bundle", expectedCAs: 2, expectedAllowed: true},
+		{name: "invalid bundle keeps the CAs loaded", bundle: []byte("-----BEGIN CERTIFICATE-----\n"), expectedError: true, expectedCAs: 2, expectedAllowed: true},
+		{name: "old CA removed", bundle: readFile(t, newCA.CACert), expectedChanged: true, expectedCAs: 1, expectedAllowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.bundle != nil {
+				if err := os.WriteFile(path, tt.bundle, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			changed, err := pool.Reload()
+			if (err != nil) != tt.expectedError || changed != tt.expectedChanged {
+				t.Fatalf("Reload() = %v, %v, want changed %v and error %v", changed, err, tt.expectedChanged, tt.expectedError)
+			}
+			if cas := pool.CAs(); len(cas) != tt.expectedCAs {
+				t.Errorf("CAs() = %+v, want %d CAs", cas, tt.expectedCAs)
+			}
+			if err := get(); (err == nil) != tt.expectedAllowed {
+				t.Errorf("Get() error = %v, want allowed %v", err, tt.expectedAllowed)
+			}
+		})
+	}
+}
+
+func TestPool_ConfigForClient(t *testing.T) {
+	certs := testenv.NewCertificates(t)
+	pool, err := Load([]string{certs.CACert})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	base := certs.ServerTLSConfig()
+	base.NextProtos = []string{"h2", "http/1.1"}
+	base.GetConfigForClient = pool.ConfigForClient(base)
+	config, err := base.GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetConfigForClient() error = %v", err)
+	}
+	if config.GetConfigForClient != nil || config.ClientCAs != pool.CertPool() || !reflect.DeepEqual(config.NextProtos, base.NextProtos) ||
+		config.ClientAuth != base.ClientAuth || config.MinVersion != base.MinVersion {
+		t.Errorf("GetConfigForClient() = %+v, want the base configuration with the client CAs of the pool", config)
+	}
+}
@@ -0,0 +1,185 @@
+// Package conntrack exports metrics about the connections of the main server: the open connections, the connections
+// by client certificate identity, and the failed TLS handshakes by reason, which net/http otherwise only reports as
+// log lines, so that the clients failing mutual TLS show on the dashboards.
+package conntrack
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/stats"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// NoCertificate is the identity of the connections of the clients which didn't present a client certificate, e.g.
+// the clients authenticating with a bearer token
+const NoCertificate = "none"
+
+// Reasons of the failed TLS handshakes
+const (
+	// ReasonUnknownCA is the reason of the client certificates not issued by the CA of --ca-cert
+	ReasonUnknownCA = "unknown_ca"
+	// ReasonExpiredCertificate is the reason of the expired, or not yet valid, client certificates
+	ReasonExpiredCertificate = "expired_certificate"
+	// ReasonBadCertificate is the reason of the client certificates failing the verification otherwise, e.g. without
+	// the client auth extended key usage
+	ReasonBadCertificate = "bad_certificate"
+	// ReasonRejectedByClient is the reason of the handshakes aborted by the client with an alert, e.g. when it doesn't
+	// trust the server certificate
+	ReasonRejectedByClient = "rejected_by_client"
+	// ReasonProtocolMismatch is the reason of the clients without a TLS version, cipher suite or curve in common
+	ReasonProtocolMismatch = "protocol_mismatch"
+	// ReasonNotTLS is the reason of the clients speaking plain HTTP, or another protocol, to the TLS port
+	ReasonNotTLS = "not_tls"
+	// ReasonConnectionClosed is the reason of the connections closed, reset or timed out during the handshake, e.g.
+	// by port scanners and TCP load balancer health checks
+	ReasonConnectionClosed = "connection_closed"
+	// ReasonOther is the reason of the other failures
+	ReasonOther = "other"
+)
+
+var (
+	openConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "k8s_api_proxy_open_connections",
+		Help: "Number of connections open to the server, including the ones still in their TLS handshake",
+	})
+	identityConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_api_proxy_open_connections_by_identity",
+		Help: "Number of connections open to the server past their TLS handshake, by client certificate identity",
+	}, []string{"identity"})
+	connectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_connections_total",
+		Help: "Number of connections which completed their TLS handshake, by client certificate identity",
+	}, []string{"identity"})
+	handshakeErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_tls_handshake_errors_total",
+		Help: "Number of failed TLS handshakes, by reason",
+	}, []string{"reason"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(openConnections, identityConnections, connectionsTotal, handshakeErrorsTotal)
+}
+
+// handshakeError matches the log lines of net/http about the failed TLS handshakes
+var handshakeError = regexp.MustCompile(`^http: TLS handshake error from (\S+): (.*)$`)
+
+// reasons maps the fragments of the handshake errors to their reason, in order, since e.g. the alerts sent by the
+// clients name the same failures as the local ones
+var reasons = []struct {
+	fragments []string
+	reason    string
+}{
+	{[]string{"remote error:"}, ReasonRejectedByClient},
+	{[]string{"certificate signed by unknown authority"}, ReasonUnknownCA},
+	{[]string{"certificate has expired or is not yet valid"}, ReasonExpiredCertificate},
+	{[]string{"x509:", "failed to verify certificate", "bad certificate"}, ReasonBadCertificate},
+	{[]string{"unsupported versions", "protocol version", "no cipher suite", "no mutual cipher suite", "unsupported elliptic", "no application protocol"}, ReasonProtocolMismatch},
+	{[]string{"first record does not look like a TLS handshake", "unsupported SSLv2", "oversized record"}, ReasonNotTLS},
+	{[]string{"EOF", "connection reset", "broken pipe", "i/o timeout"}, ReasonConnectionClosed},
+}
+
+// Reason returns the reason of the failed TLS handshake, by its error message
+func Reason(message string) string {
+	for _, r := range reasons {
+		for _, fragment := range r.fragments {
+			if strings.Contains(message, fragment) {
+				return r.reason
+			}
+		}
+	}
+	return ReasonOther
+}
+
+// Tracker tracks the connections of a server, by its ConnState hook and error log
+type Tracker struct {
+	maxIdentities int
+
+	mu sync.Mutex
+	// conns holds the identity of the open connections, empty until their handshake completes
+	conns      map[net.Conn]string
+	identities map[string]bool
+}
+
+// NewTracker returns a new Tracker labelling the connections with at most maxIdentities identities, beyond which the
+// connections of new identities are counted under stats.OtherIdentities
+func NewTracker(maxIdentities int) *Tracker {
+	return &Tracker{maxIdentities: maxIdentities, conns: map[net.Conn]string{}, identities: map[string]bool{}}
+}
+
+// Instrument sets the ConnState hook and error log of the server, to track its connections and failed handshakes
+func (t *Tracker) Instrument(server *http.Server) {
+	server.ConnState = t.ConnState
+	server.ErrorLog = log.New(&errorLog{}, "", 0)
+}
+
+// ConnState tracks the state changes of a connection, as an http.Server ConnState hook
+func (t *Tracker) ConnState(conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch state {
+	case http.StateNew:
+		t.conns[conn] = ""
+		openConnections.Inc()
+	case http.StateActive:
+		identity, ok := t.conns[conn]
+		if !ok || identity != "" {
+			return
+		}
+		// The handshake is complete once the connection is first active
+		identity = t.identity(conn)
+		t.conns[conn] = identity
+		connectionsTotal.WithLabelValues(identity).Inc()
+		identityConnections.WithLabelValues(identity).Inc()
+	case http.StateClosed, http.StateHijacked:
+		identity, ok := t.conns[conn]
+		if !ok {
+			return
+		}
+		delete(t.conns, conn)
+		openConnections.Dec()
+		if identity != "" {
+			identityConnections.WithLabelValues(identity).Dec()
+		}
+	}
+}
+
+// identity returns the label of the client certificate identity of the connection, which is capped. It must be called
+// with the lock held.
+func (t *Tracker) identity(conn net.Conn) string {
+	identity := NoCertificate
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if chains := tlsConn.ConnectionState().VerifiedChains; len(chains) > 0 && len(chains[0]) > 0 {
+			identity = chains[0][0].Subject.CommonName
+		}
+	}
+	if !t.identities[identity] {
+		if len(t.identities) >= t.maxIdentities {
+			return stats.OtherIdentities
+		}
+		t.identities[identity] = true
+	}
+	return identity
+}
+
+// errorLog is the error log of the server, which counts the failed handshakes and passes every line on to klog
+type errorLog struct{}
+
+func (l *errorLog) Write(p []byte) (int, error) {
+	line := strings.TrimSpace(string(p))
+	if match := handshakeError.FindStringSubmatch(line); match != nil {
+		reason := Reason(match[2])
+		handshakeErrorsTotal.WithLabelValues(reason).Inc()
+		klog.Background().Info("TLS handshake failed", "remoteAddr", match[1], "reason", reason, "err", match[2])
+		return len(p), nil
+	}
+	klog.Background().Info("HTTP server error", "err", line)
+	return len(p), nil
+}
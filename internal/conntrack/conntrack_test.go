@@ -0,0 +1,123 @@
+package conntrack
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/stats"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/testenv"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReason(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"tls: failed to verify certificate: x509: certificate signed by unknown authority", ReasonUnknownCA},
+		{"tls: failed to verify certificate: x509: certificate has expired or is not yet valid: current time 2024-05-01T10:00:00Z is after 2024-04-01T10:00:00Z", ReasonExpiredCertificate},
+		{"tls: failed to verify certificate: x509: certificate specifies an incompatible key usage", ReasonBadCertificate},
+		{"remote error: tls: bad certificate", ReasonRejectedByClient},
+		{"remote error: tls: unknown certificate authority", ReasonRejectedByClient},
+		{"tls: client offered only unsupported versions: [303 302 301]", ReasonProtocolMismatch},
+		{"tls: no cipher suite supported by both client and server", ReasonProtocolMismatch},
+		{"tls: first record does not look like a TLS handshake", ReasonNotTLS},
+		{"EOF", ReasonConnectionClosed},
+		{"read tcp 10.0.0.1:8443->10.0.0.2:51234: read: connection reset by peer", ReasonConnectionClosed},
+		{"something else", ReasonOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if got := Reason(tt.message); got != tt.expected {
+				t.Errorf("Reason() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	certs := testenv.NewCertificates(t)
+	tracker := NewTracker(1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = certs.ServerTLSConfig()
+	server.TLS.ClientAuth = tls.VerifyClientCertIfGiven
+	tracker.Instrument(server.Config)
+	server.StartTLS()
+	defer server.Close()
+
+	// A client certificate issued by another CA, presented by a client which trusts the server
+	untrusted := testenv.NewCertificates(t).Client(t, "mallory")
+	untrusted.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
+	// A client which only speaks TLS 1.2, while the server requires TLS 1.3
+	legacy := certs.Client(t, "legacy")
+	legacy.Transport.(*http.Transport).TLSClientConfig.MinVersion = tls.VersionTLS12
+	legacy.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS12
+
+	tests := []struct {
+		name           string
+		client         *http.Client
+		raw            bool
+		identity       string
+		expectedReason string
+	}{
+		{name: "client certificate", client: certs.Client(t, "alice"), identity: "alice"},
+		{name: "identities beyond the cap", client: certs.Client(t, "bob"), identity: stats.OtherIdentities},
+		{name: "unknown CA", client: untrusted, expectedReason: ReasonUnknownCA},
+		{name: "protocol mismatch", client: legacy, expectedReason: ReasonProtocolMismatch},
+		{name: "not TLS", raw: true, expectedReason: ReasonNotTLS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connections := testutil.ToFloat64(connectionsTotal.WithLabelValues(tt.identity))
+			handshakeErrors := testutil.ToFloat64(handshakeErrorsTotal.WithLabelValues(tt.expectedReason))
+			switch {
+			case tt.raw:
+				conn, err := net.Dial("tcp", server.Listener.Addr().String())
+				if err != nil {
+					t.Fatalf("Dial() error = %v", err)
+				}
+				_, _ = conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+				_, _ = conn.Read(make([]byte, 1024))
+				conn.Close()
+			default:
+				resp, err := tt.client.Get(server.URL)
+				if err == nil {
+					resp.Body.Close()
+				}
+				if (err != nil) != (tt.expectedReason != "") {
+					t.Fatalf("Get() error = %v", err)
+				}
+				tt.client.CloseIdleConnections()
+			}
+
+			if tt.expectedReason == "" {
+				if got := testutil.ToFloat64(connectionsTotal.WithLabelValues(tt.identity)); got != connections+1 {
+					t.Errorf("connections of %q = %v, want %v", tt.identity, got, connections+1)
+				}
+				return
+			}
+			// The server logs the failed handshakes asynchronously
+			deadline := time.Now().Add(5 * time.Second)
+			for testutil.ToFloat64(handshakeErrorsTotal.WithLabelValues(tt.expectedReason)) != handshakeErrors+1 {
+				if time.Now().After(deadline) {
+					t.Fatalf("handshake errors %q = %v, want %v", tt.expectedReason,
+						testutil.ToFloat64(handshakeErrorsTotal.WithLabelValues(tt.expectedReason)), handshakeErrors+1)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+
+	// Once the connections are closed, none is open anymore
+	server.Close()
+	if got := testutil.ToFloat64(openConnections); got != 0 {
+		t.Errorf("open connections = %v, want 0", got)
+	}
+	if got := testutil.ToFloat64(identityConnections.WithLabelValues("alice")); got != 0 {
+		t.Errorf("open connections of alice = %v, want 0", got)
+	}
+}
@@ -0,0 +1,147 @@
+// Package cost estimates the monthly cost of deployments from the resources of their pods and a price sheet, for
+// FinOps chargeback reports.
+package cost
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Bases of the estimates, set in the price sheet
+const (
+	// BasisRequests estimates the cost from the resource requests of the containers, i.e. what the scheduler reserves
+	BasisRequests = "requests"
+	// BasisLimits estimates the cost from the resource limits of the containers, falling back to their requests when
+	// they have no limit, i.e. the most they may use
+	BasisLimits = "limits"
+)
+
+// bytesPerGiB is the number of bytes in a GiB
+const bytesPerGiB = 1 << 30
+
+// PriceSheet is the price sheet configuration file
+type PriceSheet struct {
+	// Currency is the currency of the prices, reported along with the estimates (e.g. USD)
+	Currency string `json:"currency"`
+	// CPUPerCoreMonth is the price of a CPU core for a month
+	CPUPerCoreMonth float64 `json:"cpuPerCoreMonth"`
+	// MemoryPerGiBMonth is the price of a GiB of memory for a month
+	MemoryPerGiBMonth float64 `json:"memoryPerGiBMonth"`
+	// Basis is what the estimates are based on, either requests (the default) or limits
+	Basis string `json:"basis,omitempty"`
+}
+
+// Validate validates the price sheet and returns an error if it is invalid
+func (p PriceSheet) Validate() error {
+	if p.CPUPerCoreMonth < 0 || p.MemoryPerGiBMonth < 0 {
+		return fmt.Errorf("prices must be greater than or equal to 0")
+	}
+	if p.Basis != "" && p.Basis != BasisRequests && p.Basis != BasisLimits {
+		return fmt.Errorf("invalid basis %q, must be either %s or %s", p.Basis, BasisRequests, BasisLimits)
+	}
+	return nil
+}
+
+// LoadFile returns the price sheet of the given YAML or JSON configuration file
+func LoadFile(path string) (*PriceSheet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price sheet file: %w", err)
+	}
+	var p PriceSheet
+	if err := yaml.UnmarshalStrict(raw, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse price sheet file %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid price sheet file %s: %w", path, err)
+	}
+	if p.Basis == "" {
+		p.Basis = BasisRequests
+	}
+	return &p, nil
+}
+
+// Estimate is the estimated monthly cost of a set of resources
+type Estimate struct {
+	CPUCores    float64 `json:"cpuCores"`
+	MemoryGiB   float64 `json:"memoryGiB"`
+	MonthlyCost float64 `json:"monthlyCost"`
+}
+
+// Add adds the resources and cost of the other estimate
+func (e *Estimate) Add(other Estimate) {
+	e.CPUCores += other.CPUCores
+	e.MemoryGiB += other.MemoryGiB
+	e.MonthlyCost += other.MonthlyCost
+}
+
+// Round rounds the estimate for reporting, to the millicore, MiB and cent
+func (e Estimate) Round() Estimate {
+	return Estimate{
+		CPUCores:    math.Round(e.CPUCores*1000) / 1000,
+		MemoryGiB:   math.Round(e.MemoryGiB*1024) / 1024,
+		MonthlyCost: math.Round(e.MonthlyCost*100) / 100,
+	}
+}
+
+// Deployment estimates the monthly cost of the deployment, at its desired number of replicas
+func (p *PriceSheet) Deployment(d *appsv1.Deployment) Estimate {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	cpu, memory := p.podResources(&d.Spec.Template.Spec)
+	e := Estimate{CPUCores: cpu * float64(replicas), MemoryGiB: memory / bytesPerGiB * float64(replicas)}
+	e.MonthlyCost = e.CPUCores*p.CPUPerCoreMonth + e.MemoryGiB*p.MemoryPerGiBMonth
+	return e
+}
+
+// podResources returns the CPU cores and memory bytes of a pod, as the scheduler accounts them: the containers and
+// sidecars (restartable init containers) run together, while the other init containers run one at a time before them
+func (p *PriceSheet) podResources(spec *corev1.PodSpec) (float64, float64) {
+	var cpu, memory float64
+	for i := range spec.Containers {
+		c, m := p.containerResources(&spec.Containers[i])
+		cpu, memory = cpu+c, memory+m
+	}
+	var initCPU, initMemory, sidecarCPU, sidecarMemory float64
+	for i := range spec.InitContainers {
+		container := &spec.InitContainers[i]
+		c, m := p.containerResources(container)
+		if container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			sidecarCPU, sidecarMemory = sidecarCPU+c, sidecarMemory+m
+			continue
+		}
+		// Init containers run after the sidecars declared before them were started
+		initCPU, initMemory = max(initCPU, sidecarCPU+c), max(initMemory, sidecarMemory+m)
+	}
+	cpu, memory = max(cpu+sidecarCPU, initCPU), max(memory+sidecarMemory, initMemory)
+	for name, quantity := range spec.Overhead {
+		switch name {
+		case corev1.ResourceCPU:
+			cpu += quantity.AsApproximateFloat64()
+		case corev1.ResourceMemory:
+			memory += quantity.AsApproximateFloat64()
+		}
+	}
+	return cpu, memory
+}
+
+// containerResources returns the CPU cores and memory bytes of a container, on the basis of the price sheet
+func (p *PriceSheet) containerResources(container *corev1.Container) (float64, float64) {
+	resources := func(name corev1.ResourceName) float64 {
+		if p.Basis == BasisLimits {
+			if quantity, ok := container.Resources.Limits[name]; ok {
+				return quantity.AsApproximateFloat64()
+			}
+		}
+		quantity := container.Resources.Requests[name]
+		return quantity.AsApproximateFloat64()
+	}
+	return resources(corev1.ResourceCPU), resources(corev1.ResourceMemory)
+}
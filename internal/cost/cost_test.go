@@ -0,0 +1,119 @@
+package cost
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+)
+
+func container(cpuRequest, memoryRequest, cpuLimit, memoryLimit string) corev1.Container {
+	c := corev1.Container{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}}
+	for quantity, into := range map[string]func(resource.Quantity){
+		cpuRequest:    func(q resource.Quantity) { c.Resources.Requests[corev1.ResourceCPU] = q },
+		memoryRequest: func(q resource.Quantity) { c.Resources.Requests[corev1.ResourceMemory] = q },
+		cpuLimit:      func(q resource.Quantity) { c.Resources.Limits[corev1.ResourceCPU] = q },
+		memoryLimit:   func(q resource.Quantity) { c.Resources.Limits[corev1.ResourceMemory] = q },
+	} {
+		if quantity != "" {
+			into(resource.MustParse(quantity))
+		}
+	}
+	return c
+}
+
+func TestPriceSheet_Deployment(t *testing.T) {
+	sidecar := container("500m", "512Mi", "", "")
+	sidecar.RestartPolicy = ptr.To(corev1.ContainerRestartPolicyAlways)
+
+	tests := []struct {
+		name     string
+		basis    string
+		replicas *int32
+		spec     corev1.PodSpec
+		expected Estimate
+	}{
+		{
+			"Test Deployment Requests",
+			BasisRequests,
+			ptr.To(int32(2)),
+			corev1.PodSpec{Containers: []corev1.Container{container("500m", "1Gi", "1", "2Gi"), container("250m", "512Mi", "", "")}},
+			Estimate{CPUCores: 1.5, MemoryGiB: 3, MonthlyCost: 1.5*20 + 3*4},
+		},
+		{
+			"Test Deployment Limits Falling Back To Requests",
+			BasisLimits,
+			ptr.To(int32(2)),
+			corev1.PodSpec{Containers: []corev1.Container{container("500m", "1Gi", "1", "2Gi"), container("250m", "512Mi", "", "")}},
+			Estimate{CPUCores: 2.5, MemoryGiB: 5, MonthlyCost: 2.5*20 + 5*4},
+		},
+		{
+			"Test Deployment Default Replicas",
+			BasisRequests,
+			nil,
+			corev1.PodSpec{Containers: []corev1.Container{container("1", "1Gi", "", "")}},
+			Estimate{CPUCores: 1, MemoryGiB: 1, MonthlyCost: 24},
+		},
+		{
+			"Test Deployment Scaled To Zero",
+			BasisRequests,
+			ptr.To(int32(0)),
+			corev1.PodSpec{Containers: []corev1.Container{container("1", "1Gi", "", "")}},
+			Estimate{},
+		},
+		{
+			"Test Deployment Init Containers And Sidecars",
+			BasisRequests,
+			ptr.To(int32(1)),
+			corev1.PodSpec{
+				InitContainers: []corev1.Container{sidecar, container("2", "256Mi", "", "")},
+				Containers:     []corev1.Container{container("1", "1Gi", "", "")},
+			},
+			// The init container runs along with the sidecar, and needs more CPU than the containers and the sidecar
+			Estimate{CPUCores: 2.5, MemoryGiB: 1.5, MonthlyCost: 2.5*20 + 1.5*4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PriceSheet{Currency: "USD", CPUPerCoreMonth: 20, MemoryPerGiBMonth: 4, Basis: tt.basis}
+			d := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: tt.replicas, Template: corev1.PodTemplateSpec{Spec: tt.spec}}}
+			if got := p.Deployment(d).Round(); got != tt.expected {
+				t.Errorf("Deployment() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedError bool
+		expectedBasis string
+	}{
+		{"Test LoadFile Default Basis", "currency: USD\ncpuPerCoreMonth: 20\nmemoryPerGiBMonth: 4\n", false, BasisRequests},
+		{"Test LoadFile Limits Basis", "currency: EUR\ncpuPerCoreMonth: 20\nmemoryPerGiBMonth: 4\nbasis: limits\n", false, BasisLimits},
+		{"Test LoadFile Invalid Basis", "cpuPerCoreMonth: 20\nbasis: usage\n", true, ""},
+		{"Test LoadFile Negative Price", "cpuPerCoreMonth: -1\n", true, ""},
+		{"Test LoadFile Unknown Field", "cpuPerCore: 20\n", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "prices.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("failed to write price sheet file: %v", err)
+			}
+			p, err := LoadFile(path)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("LoadFile() error = %v, expectedError %v", err, tt.expectedError)
+			}
+			if !tt.expectedError && p.Basis != tt.expectedBasis {
+				t.Errorf("basis = %q, want %q", p.Basis, tt.expectedBasis)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Package dashboards bundles the Grafana dashboards of the metrics of the API, e.g. the rate, errors and duration of the
+// requests per route and per client identity, so that they ship with the version of the server whose metrics they
+// query.
+package dashboards
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Dashboard describes a bundled dashboard
+type Dashboard struct {
+	// Name is the name the dashboard is served under, e.g. red
+	Name  string `json:"name"`
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+	// Description is what the dashboard shows
+	Description string `json:"description"`
+}
+
+//go:embed grafana/*.json
+var dashboardsFS embed.FS
+
+// dashboard is a bundled dashboard, along with its Grafana JSON model
+type dashboard struct {
+	Dashboard
+	model []byte
+}
+
+// bundled holds the bundled dashboards, by name
+var bundled = mustLoadDashboards()
+
+func mustLoadDashboards() map[string]dashboard {
+	entries, err := dashboardsFS.ReadDir("grafana")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]dashboard, len(entries))
+	for _, entry := range entries {
+		raw, err := dashboardsFS.ReadFile(path.Join("grafana", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		d := dashboard{model: raw}
+		if err := json.Unmarshal(raw, &d.Dashboard); err != nil {
+			panic(fmt.Sprintf("invalid dashboard %s: %v", entry.Name(), err))
+		}
+		d.Name = strings.TrimSuffix(entry.Name(), ".json")
+		loaded[d.Name] = d
+	}
+	return loaded
+}
+
+// List returns the bundled dashboards, sorted by name
+func List() []Dashboard {
+	list := make([]Dashboard, 0, len(bundled))
+	for _, d := range bundled {
+		list = append(list, d.Dashboard)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns the Grafana JSON model of the named dashboard, which can be imported as is
+func Get(name string) ([]byte, bool) {
+	d, ok := bundled[name]
+	return d.model, ok
+}
@@ -0,0 +1,56 @@
+package dashboards
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+// metricNames are the names of the metrics queried by the dashboards, which must be exported by the server
+var metricNames = map[string]bool{
+	"k8s_api_proxy_http_requests_total":                  true,
+	"k8s_api_proxy_http_request_duration_seconds_bucket": true,
+}
+
+var metricPattern = regexp.MustCompile(`\bk8s_api_proxy_[a-z_]+`)
+
+func TestDashboards(t *testing.T) {
+	list := List()
+	if len(list) == 0 {
+		t.Fatal("List() = [], want the bundled dashboards")
+	}
+	for _, d := range list {
+		t.Run(d.Name, func(t *testing.T) {
+			if d.UID == "" || d.Title == "" {
+				t.Errorf("dashboard = %+v, want a uid and a title", d)
+			}
+			model, ok := Get(d.Name)
+			if !ok {
+				t.Fatalf("Get(%q) not found", d.Name)
+			}
+			var parsed struct {
+				Panels []struct {
+					Title   string `json:"title"`
+					Targets []struct {
+						Expr string `json:"expr"`
+					} `json:"targets"`
+				} `json:"panels"`
+			}
+			if err := json.Unmarshal(model, &parsed); err != nil {
+				t.Fatalf("invalid dashboard model: %v", err)
+			}
+			for _, panel := range parsed.Panels {
+				for _, target := range panel.Targets {
+					for _, name := range metricPattern.FindAllString(target.Expr, -1) {
+						if !metricNames[name] {
+							t.Errorf("panel %q queries unknown metric %s", panel.Title, name)
+						}
+					}
+				}
+			}
+		})
+	}
+	if _, ok := Get("missing"); ok {
+		t.Error(`Get("missing") found, want not found`)
+	}
+}
@@ -0,0 +1,208 @@
+// Package deadline bounds every call made to the Kubernetes API server while serving a request with a deadline,
+// configured globally and per route, instead of only inheriting the context of the request. The requests whose calls
+// hit their deadline are answered with a 504 Gateway Timeout, rather than the error the handler maps the failed call
+// to (e.g. a 404 for a deployment which couldn't be read).
+package deadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Deadlines are the timeouts of the API server calls of the routes
+type Deadlines struct {
+	// Default is the timeout of the calls of the routes without their own. Their calls aren't bounded when zero.
+	Default time.Duration
+	// Routes are the timeouts of the calls by route pattern, e.g. "POST /apply". A zero timeout leaves the calls of the
+	// route unbounded.
+	Routes map[string]time.Duration
+}
+
+// ParseRoutes parses a comma separated list of PATTERN=duration pairs, e.g. "POST /apply=2m,GET /deployments=5s"
+func ParseRoutes(value string) (map[string]time.Duration, error) {
+	routes := map[string]time.Duration{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		pattern, raw, found := strings.Cut(pair, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !found || pattern == "" {
+			return nil, fmt.Errorf("invalid route timeout %q, must be PATTERN=duration", pair)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid timeout %q of route %q, must be a non-negative duration", raw, pattern)
+		}
+		routes[pattern] = timeout
+	}
+	return routes, nil
+}
+
+// timeout returns the timeout of the calls of the route
+func (d *Deadlines) timeout(pattern string) time.Duration {
+	if timeout, ok := d.Routes[pattern]; ok {
+		return timeout
+	}
+	return d.Default
+}
+
+// state is the deadline of the calls of a request, and whether any of them hit it
+type state struct {
+	timeout  time.Duration
+	timedOut atomic.Bool
+}
+
+type stateKey struct{}
+
+// Middleware returns a new http.HandlerFunc bounding the API server calls made by the provided handler with the
+// timeout of its route, and answering with a 504 Gateway Timeout instead of the error response of the handler when any
+// of them hit it. It must be registered on a ServeMux, which sets the pattern of the route on the request, and the
+// clients of the handler must be wrapped in a Client or Reader.
+func (d *Deadlines) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timeout := d.timeout(r.Pattern)
+		if timeout <= 0 {
+			next(w, r)
+			return
+		}
+		s := &state{timeout: timeout}
+		ctx := context.WithValue(r.Context(), stateKey{}, s)
+		next(&writer{ResponseWriter: w, state: s, logger: klog.FromContext(ctx)}, r.WithContext(ctx))
+	}
+}
+
+// writer replaces the error response of a request whose calls hit their deadline with a 504 Gateway Timeout
+type writer struct {
+	http.ResponseWriter
+	state       *state
+	logger      klog.Logger
+	wroteHeader bool
+	replaced    bool
+}
+
+func (w *writer) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status < http.StatusBadRequest || !w.state.timedOut.Load() {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.replaced = true
+	w.logger.Info("Kubernetes API server call timed out", "timeout", w.state.timeout, "status", status)
+	w.Header().Del("Content-Length")
+	message := fmt.Sprintf("Timed out after %s waiting for the Kubernetes API server", w.state.timeout)
+	if err := problem.Write(w.ResponseWriter, http.StatusGatewayTimeout, message); err != nil {
+		w.logger.Error(err, "Error encoding response")
+	}
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.replaced {
+		// The body of the replaced error response is dropped
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so that http.ResponseController can reach it
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// call runs fn with the deadline of the request of the context, if any, and records whether it hit it
+func call(ctx context.Context, fn func(ctx context.Context) error) error {
+	s, _ := ctx.Value(stateKey{}).(*state)
+	if s == nil {
+		return fn(ctx)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	err := fn(callCtx)
+	if err != nil && (errors.Is(err, context.DeadlineExceeded) || errors.Is(callCtx.Err(), context.DeadlineExceeded)) {
+		s.timedOut.Store(true)
+	}
+	return err
+}
+
+// Reader bounds the reads of the wrapped reader with the deadline of their request
+type Reader struct {
+	client.Reader
+}
+
+// Get retrieves an obj for the given object key, within the deadline of the request
+func (r *Reader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return call(ctx, func(ctx context.Context) error {
+		return r.Reader.Get(ctx, key, obj, opts...)
+	})
+}
+
+// List retrieves a list of objects for the given options, within the deadline of the request
+func (r *Reader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return call(ctx, func(ctx context.Context) error {
+		return r.Reader.List(ctx, list, opts...)
+	})
+}
+
+// Client bounds the calls of the wrapped client with the deadline of their request
+type Client struct {
+	client.Client
+}
+
+// Get retrieves an obj for the given object key, within the deadline of the request
+func (c *Client) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return call(ctx, func(ctx context.Context) error {
+		return c.Client.Get(ctx, key, obj, opts...)
+	})
+}
+
+// List retrieves a list of objects for the given options, within the deadline of the request
+func (c *Client) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return call(ctx, func(ctx context.Context) error {
+		return c.Client.List(ctx, list, opts...)
+	})
+}
+
+// Create saves the object obj in the Kubernetes cluster, within the deadline of the request
+func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return call(ctx, func(ctx context.Context) error {
+		return c.Client.Create(ctx, obj, opts...)
+	})
+}
+
+// Update updates the given obj in the Kubernetes cluster, within the deadline of the request
+func (c *Client) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return call(ctx, func(ctx context.Context) error {
+		return c.Client.Update(ctx, obj, opts...)
+	})
+}
+
+// Patch patches the given obj in the Kubernetes cluster, within the deadline of the request
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return call(ctx, func(ctx context.Context) error {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	})
+}
+
+// Delete deletes the given obj from the Kubernetes cluster, within the deadline of the request
+func (c *Client) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return call(ctx, func(ctx context.Context) error {
+		return c.Client.Delete(ctx, obj, opts...)
+	})
+}
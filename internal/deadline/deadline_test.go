@@ -0,0 +1,112 @@
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestParseRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string]time.Duration
+		wantErr  bool
+	}{
+		{"Test Empty", "", map[string]time.Duration{}, false},
+		{"Test Routes", "POST /apply=2m, GET /deployments=5s", map[string]time.Duration{"POST /apply": 2 * time.Minute, "GET /deployments": 5 * time.Second}, false},
+		{"Test Unbounded Route", "GET /deployments/{namespace}/{deployment}/watch=0", map[string]time.Duration{"GET /deployments/{namespace}/{deployment}/watch": 0}, false},
+		{"Test Missing Timeout", "POST /apply", nil, true},
+		{"Test Missing Pattern", "=5s", nil, true},
+		{"Test Invalid Timeout", "POST /apply=soon", nil, true},
+		{"Test Negative Timeout", "POST /apply=-1s", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := ParseRoutes(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRoutes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(routes, tt.expected) {
+				t.Errorf("ParseRoutes() = %v, want %v", routes, tt.expected)
+			}
+		})
+	}
+}
+
+// slowReader blocks every read until its context is done, or returns its error right away
+type slowReader struct {
+	client.Reader
+	err error
+}
+
+func (r *slowReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if r.err != nil {
+		return r.err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestDeadlines_Middleware(t *testing.T) {
+	deadlines := &Deadlines{Default: 20 * time.Millisecond, Routes: map[string]time.Duration{"GET /unbounded/{name}": 0}}
+	notFound := &slowReader{err: context.Canceled}
+
+	tests := []struct {
+		name           string
+		reader         client.Reader
+		pattern        string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Test Timed Out", &slowReader{}, "GET /deployments/{name}", "/deployments/web", http.StatusGatewayTimeout,
+			"{\"type\":\"urn:go-k8s-http-api:problem:timeout\",\"title\":\"Gateway Timeout\",\"status\":504," +
+				"\"detail\":\"Timed out after 20ms waiting for the Kubernetes API server\"," +
+				"\"message\":\"Timed out after 20ms waiting for the Kubernetes API server\"}\n"},
+		{"Test Other Error", notFound, "GET /deployments/{name}", "/deployments/web", http.StatusNotFound, "{\"message\":\"not found\"}"},
+		{"Test Unbounded Route", notFound, "GET /unbounded/{name}", "/unbounded/web", http.StatusNotFound, "{\"message\":\"not found\"}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &Reader{Reader: tt.reader}
+			mux := http.NewServeMux()
+			mux.HandleFunc(tt.pattern, deadlines.Middleware(func(w http.ResponseWriter, r *http.Request) {
+				if err := reader.Get(r.Context(), client.ObjectKey{Name: r.PathValue("name")}, &appsv1.Deployment{}); err != nil {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte("{\"message\":\"not found\"}"))
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if w.Body.String() != tt.expectedBody {
+				t.Errorf("response body = %q, want %q", w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestCall_WithoutDeadline(t *testing.T) {
+	// Calls made outside of a request with a deadline, e.g. by the controllers, are left alone
+	err := call(context.Background(), func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); ok {
+			t.Errorf("call() set a deadline outside of a request")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("call() error = %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package debug
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+
+	"k8s.io/klog/v2"
+)
+
+// NewMux returns a new http.ServeMux serving the runtime debug endpoints:
+//   - /debug/pprof/ for the pprof profiles
+//   - /debug/vars for the expvar variables
+//   - /debug/goroutines for a full dump of the stacks of all goroutines
+func NewMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/goroutines", goroutinesHandler)
+	return mux
+}
+
+// goroutinesHandler writes the stacks of all goroutines, in the same format as an unrecovered panic
+func goroutinesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	err := runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+	if err != nil {
+		klog.FromContext(r.Context()).Error(err, "Error writing goroutine dump")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// ValidateLoopbackAddress returns an error if the given listen address is not bound to a loopback interface,
+// since the debug endpoints are unauthenticated and must never be exposed outside of the pod.
+func ValidateLoopbackAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("address %q is not a loopback address", address)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package debug
+
+import (
+	"testing"
+)
+
+func TestValidateLoopbackAddress(t *testing.T) {
+	tests := []struct {
+		name          string
+		address       string
+		expectedError bool
+	}{
+		{"Test ValidateLoopbackAddress IPv4", "127.0.0.1:6060", false},
+		{"Test ValidateLoopbackAddress IPv6", "[::1]:6060", false},
+		{"Test ValidateLoopbackAddress Localhost", "localhost:6060", false},
+		{"Test ValidateLoopbackAddress All Interfaces", ":6060", true},
+		{"Test ValidateLoopbackAddress Non Loopback", "10.0.0.1:6060", true},
+		{"Test ValidateLoopbackAddress Hostname", "example.com:6060", true},
+		{"Test ValidateLoopbackAddress Missing Port", "127.0.0.1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLoopbackAddress(tt.address)
+			if (err != nil) != tt.expectedError {
+				t.Errorf("ValidateLoopbackAddress() error = %v, expectedError %v", err, tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,456 @@
+// Package demo sets up the demo mode, which serves the API from a fake cluster (see package mock) holding a generated
+// dataset, along with the certificates, the token and the configuration files the server and its clients need, so that
+// every endpoint can be explored with a single command and without any access to Kubernetes.
+//
+// The dataset spreads a few applications over the shop, payments, analytics and platform namespaces, in the states the
+// endpoints are meant to surface: healthy, rolling out, unschedulable, crash looping past their progress deadline,
+// scaled to zero and paused, along with their pods, services, ingresses, autoscalers and events.
+package demo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apps"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/mock"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Namespaces are the namespaces of the dataset
+var Namespaces = []string{"shop", "payments", "analytics", "platform"}
+
+// componentLabel tells the workloads of an application apart, e.g. its deployment from its database
+const componentLabel = "app.kubernetes.io/component"
+
+// workload describes a deployment or a StatefulSet of the dataset
+type workload struct {
+	namespace string
+	// app is the application the workload belongs to, its name unless set
+	app      string
+	name     string
+	image    string
+	replicas int32
+	cpu      string
+	memory   string
+	port     int32
+	// age is how long before now the workload was created
+	age time.Duration
+}
+
+// Objects returns the objects of the dataset, as of the given time
+func Objects(now time.Time) []client.Object {
+	var objects []client.Object
+	add := func(objs ...client.Object) {
+		objects = append(objects, objs...)
+	}
+	for _, namespace := range Namespaces {
+		add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:              namespace,
+			Labels:            map[string]string{corev1.LabelMetadataName: namespace},
+			CreationTimestamp: metav1.NewTime(now.Add(-90 * 24 * time.Hour)),
+		}})
+	}
+
+	// shop: a healthy, autoscaled storefront, a checkout rolling out a new version, and a cart backed by Redis
+	web := newDeployment(workload{namespace: "shop", name: "web", image: "nginx:1.27.2", replicas: 3, cpu: "100m", memory: "128Mi", port: 8080, age: 40 * 24 * time.Hour}, now)
+	add(web, newService(web), newIngress(web, "shop.example.com"), newHPA(web, 3, 10, now))
+	add(runningPods(web, 3, now.Add(-6*time.Hour))...)
+	add(newEvent(web, corev1.EventTypeNormal, "ScalingReplicaSet", "deployment-controller",
+		fmt.Sprintf("Scaled up replica set %s to 3", replicaSetName(web)), 1, now.Add(-6*time.Hour)))
+
+	checkout := newDeployment(workload{namespace: "shop", name: "checkout", image: "ghcr.io/example/checkout:2.4.0", replicas: 2, cpu: "250m", memory: "256Mi", port: 8080, age: 30 * 24 * time.Hour}, now)
+	previous := checkout.DeepCopy()
+	previous.Spec.Template.Spec.Containers[0].Image = "ghcr.io/example/checkout:2.3.1"
+	add(checkout, newService(checkout))
+	add(runningPods(previous, 2, now.Add(-5*24*time.Hour))...)
+	add(runningPods(checkout, 1, now.Add(-time.Minute))...)
+	checkout.Generation = 2
+	checkout.Status = rollingOutStatus(checkout, 3, 1, 3, now.Add(-time.Minute))
+	add(newEvent(checkout, corev1.EventTypeNormal, "ScalingReplicaSet", "deployment-controller",
+		fmt.Sprintf("Scaled up replica set %s to 1", replicaSetName(checkout)), 1, now.Add(-time.Minute)))
+
+	cart := newDeployment(workload{namespace: "shop", app: "cart", name: "cart-api", image: "ghcr.io/example/cart:1.8.0", replicas: 2, cpu: "200m", memory: "256Mi", port: 8080, age: 30 * 24 * time.Hour}, now)
+	redis := newStatefulSet(workload{namespace: "shop", app: "cart", name: "cart-redis", image: "redis:7.4.1", replicas: 1, cpu: "250m", memory: "512Mi", port: 6379, age: 30 * 24 * time.Hour}, now)
+	add(cart, newService(cart), redis, newService(redis))
+	add(runningPods(cart, 2, now.Add(-3*24*time.Hour))...)
+
+	// payments: an autoscaled gateway, and a fraud detector whose latest replica doesn't fit on any node
+	gateway := newDeployment(workload{namespace: "payments", name: "gateway", image: "ghcr.io/example/payments-gateway:3.2.1", replicas: 2, cpu: "500m", memory: "512Mi", port: 8443, age: 60 * 24 * time.Hour}, now)
+	add(gateway, newService(gateway), newHPA(gateway, 2, 6, now))
+	add(runningPods(gateway, 2, now.Add(-2*24*time.Hour))...)
+
+	fraud := newDeployment(workload{namespace: "payments", name: "fraud-detector", image: "ghcr.io/example/fraud-detector:1.12.0", replicas: 3, cpu: "2", memory: "4Gi", port: 8080, age: 20 * 24 * time.Hour}, now)
+	fraud.Status = rollingOutStatus(fraud, 3, 3, 2, now.Add(-20*time.Minute))
+	add(fraud, newService(fraud))
+	add(runningPods(fraud, 2, now.Add(-4*24*time.Hour))...)
+	pending := pendingPod(fraud, "0/3 nodes are available: 3 Insufficient cpu. preemption: 0/3 nodes are available: 3 No preemption victims found for incoming pod.", now.Add(-20*time.Minute))
+	add(pending, newEvent(pending, corev1.EventTypeWarning, "FailedScheduling", "default-scheduler", pending.Status.Conditions[0].Message, 12, now.Add(-20*time.Minute)))
+
+	// analytics: a crash looping worker, a trainer waiting for a GPU node, and reports scaled to zero off hours
+	etl := newDeployment(workload{namespace: "analytics", name: "etl-worker", image: "ghcr.io/example/etl-worker:0.9.3", replicas: 1, cpu: "500m", memory: "1Gi", age: 10 * 24 * time.Hour}, now)
+	etl.Generation = 4
+	etl.Status = deadlineExceededStatus(etl, now.Add(-2*time.Hour))
+	crashing := crashLoopingPod(etl, 14, now.Add(-2*time.Hour))
+	add(etl, crashing, newEvent(crashing, corev1.EventTypeWarning, "BackOff", "kubelet",
+		fmt.Sprintf("Back-off restarting failed container %s in pod %s_%s(%s)", etl.Name, crashing.Name, crashing.Namespace, crashing.UID), 14, now.Add(-2*time.Hour)))
+
+	trainer := newDeployment(workload{namespace: "analytics", name: "model-trainer", image: "ghcr.io/example/model-trainer:0.3.0", replicas: 1, cpu: "4", memory: "16Gi", age: 2 * 24 * time.Hour}, now)
+	trainer.Status = rollingOutStatus(trainer, 1, 1, 0, now.Add(-45*time.Minute))
+	waiting := pendingPod(trainer, "0/3 nodes are available: 1 node(s) had untolerated taint {dedicated: gpu}, 2 Insufficient memory. preemption: 0/3 nodes are available: 3 Preemption is not helpful for scheduling.", now.Add(-45*time.Minute))
+	add(trainer, waiting, newEvent(waiting, corev1.EventTypeWarning, "FailedScheduling", "default-scheduler", waiting.Status.Conditions[0].Message, 9, now.Add(-45*time.Minute)))
+
+	reports := newDeployment(workload{namespace: "analytics", name: "reporting", image: "ghcr.io/example/reporting:2.0.0", replicas: 0, cpu: "1", memory: "2Gi", port: 8080, age: 50 * 24 * time.Hour}, now)
+	add(reports, newService(reports), newEvent(reports, corev1.EventTypeNormal, "ScalingReplicaSet", "deployment-controller",
+		fmt.Sprintf("Scaled down replica set %s to 0 from 2", replicaSetName(reports)), 1, now.Add(-8*time.Hour)))
+
+	// platform: the API gateway in front of the other namespaces, and a metrics collector whose rollouts are paused
+	apiGateway := newDeployment(workload{namespace: "platform", name: "api-gateway", image: "envoyproxy/envoy:v1.31.2", replicas: 2, cpu: "250m", memory: "256Mi", port: 8443, age: 80 * 24 * time.Hour}, now)
+	add(apiGateway, newService(apiGateway), newIngress(apiGateway, "api.example.com"))
+	add(runningPods(apiGateway, 2, now.Add(-7*24*time.Hour))...)
+
+	metrics := newDeployment(workload{namespace: "platform", name: "metrics-collector", image: "prom/prometheus:v2.54.1", replicas: 1, cpu: "500m", memory: "2Gi", port: 9090, age: 80 * 24 * time.Hour}, now)
+	metrics.Spec.Paused = true
+	add(metrics, newService(metrics))
+	add(runningPods(metrics, 1, now.Add(-7*24*time.Hour))...)
+
+	return objects
+}
+
+// labels returns the labels of the workload, which its selector matches
+func (w workload) labels() map[string]string {
+	app := w.app
+	if app == "" {
+		app = w.name
+	}
+	return map[string]string{apps.DefaultLabel: app, componentLabel: w.name}
+}
+
+func (w workload) podTemplate() corev1.PodTemplateSpec {
+	container := corev1.Container{
+		Name:  w.name,
+		Image: w.image,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(w.cpu),
+				corev1.ResourceMemory: resource.MustParse(w.memory),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse(w.memory),
+			},
+		},
+	}
+	if w.port != 0 {
+		container.Ports = []corev1.ContainerPort{{Name: "http", ContainerPort: w.port, Protocol: corev1.ProtocolTCP}}
+	}
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: w.labels()},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{container}},
+	}
+}
+
+// newDeployment returns the deployment of the workload, fully rolled out
+func newDeployment(w workload, now time.Time) *appsv1.Deployment {
+	created := metav1.NewTime(now.Add(-w.age))
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              w.name,
+			Namespace:         w.namespace,
+			Labels:            w.labels(),
+			Generation:        1,
+			CreationTimestamp: created,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(w.replicas),
+			Selector: &metav1.LabelSelector{MatchLabels: w.labels()},
+			Template: w.podTemplate(),
+		},
+	}
+	d.Status = appsv1.DeploymentStatus{
+		ObservedGeneration: 1,
+		Replicas:           w.replicas,
+		UpdatedReplicas:    w.replicas,
+		ReadyReplicas:      w.replicas,
+		AvailableReplicas:  w.replicas,
+		Conditions: []appsv1.DeploymentCondition{
+			condition(appsv1.DeploymentAvailable, corev1.ConditionTrue, "MinimumReplicasAvailable", "Deployment has minimum availability.", created.Time),
+			condition(appsv1.DeploymentProgressing, corev1.ConditionTrue, "NewReplicaSetAvailable", fmt.Sprintf("ReplicaSet %q has successfully progressed.", replicaSetName(d)), created.Time),
+		},
+	}
+	return d
+}
+
+// rollingOutStatus returns the status of the deployment while its rollout is in progress, last progressing at the
+// given time
+func rollingOutStatus(d *appsv1.Deployment, replicas, updated, available int32, since time.Time) appsv1.DeploymentStatus {
+	availableStatus, availableReason, availableMessage := corev1.ConditionTrue, "MinimumReplicasAvailable", "Deployment has minimum availability."
+	if available < *d.Spec.Replicas {
+		availableStatus, availableReason, availableMessage = corev1.ConditionFalse, "MinimumReplicasUnavailable", "Deployment does not have minimum availability."
+	}
+	return appsv1.DeploymentStatus{
+		ObservedGeneration:  d.Generation,
+		Replicas:            replicas,
+		UpdatedReplicas:     updated,
+		ReadyReplicas:       available,
+		AvailableReplicas:   available,
+		UnavailableReplicas: max(*d.Spec.Replicas-available, 0),
+		Conditions: []appsv1.DeploymentCondition{
+			condition(appsv1.DeploymentAvailable, availableStatus, availableReason, availableMessage, since),
+			condition(appsv1.DeploymentProgressing, corev1.ConditionTrue, "ReplicaSetUpdated", fmt.Sprintf("ReplicaSet %q is progressing.", replicaSetName(d)), since),
+		},
+	}
+}
+
+// deadlineExceededStatus returns the status of the deployment whose rollout the deployment controller gave up on at
+// the given time, none of its replicas being available
+func deadlineExceededStatus(d *appsv1.Deployment, since time.Time) appsv1.DeploymentStatus {
+	return appsv1.DeploymentStatus{
+		ObservedGeneration:  d.Generation,
+		Replicas:            *d.Spec.Replicas,
+		UpdatedReplicas:     *d.Spec.Replicas,
+		UnavailableReplicas: *d.Spec.Replicas,
+		Conditions: []appsv1.DeploymentCondition{
+			condition(appsv1.DeploymentAvailable, corev1.ConditionFalse, "MinimumReplicasUnavailable", "Deployment does not have minimum availability.", since),
+			condition(appsv1.DeploymentProgressing, corev1.ConditionFalse, "ProgressDeadlineExceeded", fmt.Sprintf("ReplicaSet %q has timed out progressing.", replicaSetName(d)), since),
+		},
+	}
+}
+
+func condition(conditionType appsv1.DeploymentConditionType, status corev1.ConditionStatus, reason, message string, at time.Time) appsv1.DeploymentCondition {
+	return appsv1.DeploymentCondition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastUpdateTime:     metav1.NewTime(at),
+		LastTransitionTime: metav1.NewTime(at),
+	}
+}
+
+// replicaSetName returns the name of the current ReplicaSet of the deployment, which its new pods are controlled by
+func replicaSetName(d *appsv1.Deployment) string {
+	return metav1.GetControllerOf(mock.NewPod(d, time.Time{})).Name
+}
+
+// runningPods returns n running and ready pods of the current pod template of the deployment, started at the given time
+func runningPods(d *appsv1.Deployment, n int, started time.Time) []client.Object {
+	pods := make([]client.Object, 0, n)
+	for i := 0; i < n; i++ {
+		pods = append(pods, mock.NewPod(d, started))
+	}
+	return pods
+}
+
+// pendingPod returns a pod of the deployment the scheduler couldn't place since the given time, for the given reasons
+func pendingPod(d *appsv1.Deployment, message string, since time.Time) *corev1.Pod {
+	pod := mock.NewPod(d, since)
+	pod.Spec.NodeName = ""
+	pod.Status = corev1.PodStatus{
+		Phase: corev1.PodPending,
+		Conditions: []corev1.PodCondition{{
+			Type:               corev1.PodScheduled,
+			Status:             corev1.ConditionFalse,
+			Reason:             corev1.PodReasonUnschedulable,
+			Message:            message,
+			LastTransitionTime: metav1.NewTime(since),
+		}},
+	}
+	return pod
+}
+
+// crashLoopingPod returns a pod of the deployment whose container kept exiting with an error since the given time
+func crashLoopingPod(d *appsv1.Deployment, restarts int32, since time.Time) *corev1.Pod {
+	pod := mock.NewPod(d, since)
+	pod.UID = "7c1e2b9a-4f3d-4c8e-9a51-2d6b0e8f4a17"
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == corev1.PodReady {
+			pod.Status.Conditions[i].Status = corev1.ConditionFalse
+			pod.Status.Conditions[i].Reason = "ContainersNotReady"
+		}
+	}
+	for i := range pod.Status.ContainerStatuses {
+		status := &pod.Status.ContainerStatuses[i]
+		status.Ready = false
+		status.Started = ptr.To(false)
+		status.RestartCount = restarts
+		status.State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+			Reason:  "CrashLoopBackOff",
+			Message: fmt.Sprintf("back-off 5m0s restarting failed container=%s pod=%s_%s(%s)", status.Name, pod.Name, pod.Namespace, pod.UID),
+		}}
+		status.LastTerminationState = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			ExitCode:   1,
+			Reason:     "Error",
+			StartedAt:  metav1.NewTime(since.Add(time.Duration(restarts) * 8 * time.Minute)),
+			FinishedAt: metav1.NewTime(since.Add(time.Duration(restarts)*8*time.Minute + 3*time.Second)),
+		}}
+	}
+	return pod
+}
+
+// newStatefulSet returns the StatefulSet of the workload, fully rolled out
+func newStatefulSet(w workload, now time.Time) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              w.name,
+			Namespace:         w.namespace,
+			Labels:            w.labels(),
+			Generation:        1,
+			CreationTimestamp: metav1.NewTime(now.Add(-w.age)),
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    ptr.To(w.replicas),
+			ServiceName: w.name,
+			Selector:    &metav1.LabelSelector{MatchLabels: w.labels()},
+			Template:    w.podTemplate(),
+		},
+		Status: appsv1.StatefulSetStatus{
+			ObservedGeneration: 1,
+			Replicas:           w.replicas,
+			ReadyReplicas:      w.replicas,
+			CurrentReplicas:    w.replicas,
+			UpdatedReplicas:    w.replicas,
+			AvailableReplicas:  w.replicas,
+		},
+	}
+}
+
+// newService returns the service exposing the port of the workload, named after it
+func newService(obj client.Object) *corev1.Service {
+	var template corev1.PodTemplateSpec
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		template = workload.Spec.Template
+	case *appsv1.StatefulSet:
+		template = workload.Spec.Template
+	}
+	port := template.Spec.Containers[0].Ports[0].ContainerPort
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              obj.GetName(),
+			Namespace:         obj.GetNamespace(),
+			Labels:            obj.GetLabels(),
+			CreationTimestamp: obj.GetCreationTimestamp(),
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: template.Labels,
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       servicePort(port),
+				TargetPort: intstr.FromString("http"),
+				Protocol:   corev1.ProtocolTCP,
+			}},
+		},
+	}
+}
+
+// servicePort returns the port a service exposes a container port on, the usual one of the protocol
+func servicePort(containerPort int32) int32 {
+	switch containerPort {
+	case 8080:
+		return 80
+	case 8443:
+		return 443
+	}
+	return containerPort
+}
+
+// newIngress returns the ingress routing the host to the service of the deployment
+func newIngress(d *appsv1.Deployment, host string) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              d.Name,
+			Namespace:         d.Namespace,
+			Labels:            d.Labels,
+			CreationTimestamp: d.CreationTimestamp,
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: ptr.To("nginx"),
+			TLS:              []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: strings.ReplaceAll(host, ".", "-") + "-tls"}},
+			Rules: []networkingv1.IngressRule{{
+				Host: host,
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     "/",
+						PathType: ptr.To(networkingv1.PathTypePrefix),
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: d.Name,
+							Port: networkingv1.ServiceBackendPort{Name: "http"},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+}
+
+// newHPA returns the autoscaler of the deployment, scaling it on its CPU utilization
+func newHPA(d *appsv1.Deployment, minReplicas, maxReplicas int32, now time.Time) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              d.Name,
+			Namespace:         d.Namespace,
+			Labels:            d.Labels,
+			CreationTimestamp: d.CreationTimestamp,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: d.Name},
+			MinReplicas:    ptr.To(minReplicas),
+			MaxReplicas:    maxReplicas,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name:   corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: ptr.To(int32(70))},
+				},
+			}},
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: *d.Spec.Replicas,
+			DesiredReplicas: *d.Spec.Replicas,
+			LastScaleTime:   ptr.To(metav1.NewTime(now.Add(-6 * time.Hour))),
+		},
+	}
+}
+
+// newEvent returns an event about the object, last seen at the given time after count occurrences
+func newEvent(obj client.Object, eventType, reason, component, message string, count int32, last time.Time) *corev1.Event {
+	kind := "Deployment"
+	apiVersion := appsv1.SchemeGroupVersion.String()
+	if _, ok := obj.(*corev1.Pod); ok {
+		kind, apiVersion = "Pod", corev1.SchemeGroupVersion.String()
+	}
+	first := last
+	if count > 1 {
+		first = last.Add(-time.Duration(count) * 5 * time.Minute)
+	}
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              fmt.Sprintf("%s.%x", obj.GetName(), last.UnixNano()),
+			Namespace:         obj.GetNamespace(),
+			CreationTimestamp: metav1.NewTime(first),
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: apiVersion,
+			Kind:       kind,
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+			UID:        obj.GetUID(),
+		},
+		Type:           eventType,
+		Reason:         reason,
+		Message:        message,
+		Source:         corev1.EventSource{Component: component},
+		Count:          count,
+		FirstTimestamp: metav1.NewTime(first),
+		LastTimestamp:  metav1.NewTime(last),
+	}
+}
@@ -0,0 +1,139 @@
+package demo
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/mock"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{appsv1.AddToScheme, corev1.AddToScheme, autoscalingv2.AddToScheme, networkingv1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatalf("AddToScheme() error = %v", err)
+		}
+	}
+	return scheme
+}
+
+func TestObjects(t *testing.T) {
+	scheme := newTestScheme(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	objects := Objects(now)
+
+	kinds := map[string]int{}
+	deployments := map[string]*appsv1.Deployment{}
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			deployments[o.Namespace+"/"+o.Name] = o
+		case *corev1.Pod:
+			// Every pod belongs to a deployment, which its handlers find it through
+			owner := metav1.GetControllerOf(o)
+			d, ok := deployments[o.Namespace+"/"+strings.TrimSuffix(owner.Name, "-"+o.Labels[appsv1.DefaultDeploymentUniqueLabelKey])]
+			if !ok {
+				t.Errorf("pod %s/%s is controlled by %s, want a ReplicaSet of a deployment", o.Namespace, o.Name, owner.Name)
+				continue
+			}
+			if selector, _ := metav1.LabelSelectorAsSelector(d.Spec.Selector); !selector.Matches(labels.Set(o.Labels)) {
+				t.Errorf("pod %s/%s doesn't match the selector of %s", o.Namespace, o.Name, d.Name)
+			}
+		}
+		if obj.GetCreationTimestamp().Time.IsZero() || obj.GetCreationTimestamp().After(now) {
+			t.Errorf("%T %s was created at %v, want before %v", obj, obj.GetName(), obj.GetCreationTimestamp(), now)
+		}
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			t.Fatalf("GVKForObject() error = %v", err)
+		}
+		kinds[gvk.Kind]++
+	}
+	for _, kind := range []string{"Namespace", "Deployment", "Pod", "Service", "Ingress", "HorizontalPodAutoscaler", "StatefulSet", "Event"} {
+		if kinds[kind] == 0 {
+			t.Errorf("the dataset has no %s", kind)
+		}
+	}
+
+	// The dataset covers the states the endpoints surface
+	var paused, deadlineExceeded, scaledToZero int
+	for _, d := range deployments {
+		if d.Spec.Paused {
+			paused++
+		}
+		if *d.Spec.Replicas == 0 {
+			scaledToZero++
+		}
+		for _, condition := range d.Status.Conditions {
+			if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+				deadlineExceeded++
+			}
+		}
+	}
+	if paused == 0 || deadlineExceeded == 0 || scaledToZero == 0 {
+		t.Errorf("the dataset has %d paused deployments, %d past their progress deadline and %d scaled to zero, want some of each", paused, deadlineExceeded, scaledToZero)
+	}
+}
+
+func TestObjects_Fixtures(t *testing.T) {
+	scheme := newTestScheme(t)
+	objects := Objects(time.Now())
+
+	// The dataset is written as fixtures, which the fake cluster is seeded with
+	var fixtures bytes.Buffer
+	if err := mock.EncodeFixtures(&fixtures, scheme, objects...); err != nil {
+		t.Fatalf("EncodeFixtures() error = %v", err)
+	}
+	decoded, err := mock.DecodeFixtures(fixtures.Bytes(), scheme)
+	if err != nil {
+		t.Fatalf("DecodeFixtures() error = %v", err)
+	}
+	if len(decoded) != len(objects) {
+		t.Fatalf("DecodeFixtures() = %d objects, want %d", len(decoded), len(objects))
+	}
+	cluster, err := mock.NewCluster(scheme, decoded...)
+	if err != nil {
+		t.Fatalf("NewCluster() error = %v", err)
+	}
+
+	// The rollouts in progress complete, while the ones that can't stay as they are
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		if err := cluster.Rollouts().Step(ctx); err != nil {
+			t.Fatalf("Step() error = %v", err)
+		}
+	}
+	tests := []struct {
+		name              string
+		expectedUpdated   int32
+		expectedAvailable int32
+	}{
+		{name: "shop/checkout", expectedUpdated: 2, expectedAvailable: 2},
+		{name: "payments/fraud-detector", expectedUpdated: 3, expectedAvailable: 2},
+		{name: "analytics/etl-worker", expectedUpdated: 1, expectedAvailable: 0},
+		{name: "analytics/reporting", expectedUpdated: 0, expectedAvailable: 0},
+	}
+	for _, tt := range tests {
+		namespace, name, _ := strings.Cut(tt.name, "/")
+		var d appsv1.Deployment
+		if err := cluster.Client().Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &d); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if d.Status.UpdatedReplicas != tt.expectedUpdated || d.Status.AvailableReplicas != tt.expectedAvailable {
+			t.Errorf("%s status = %d updated and %d available, want %d and %d", tt.name, d.Status.UpdatedReplicas, d.Status.AvailableReplicas, tt.expectedUpdated, tt.expectedAvailable)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package demo
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/mock"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Identity is the identity the demo clients act as, both through the client certificate and the token
+const Identity = "demo"
+
+// certificateValidity is how long the certificates of the demo are valid for, so that a kept directory lasts a while
+const certificateValidity = 365 * 24 * time.Hour
+
+// Files are the paths of the files of the demo in its directory
+type Files struct {
+	// CACert, ServerCert and ServerKey are the PEM files of the CA and the server certificate it issued for localhost,
+	// for --ca-cert, --server-cert and --cert-key
+	CACert     string
+	ServerCert string
+	ServerKey  string
+	// ClientCert and ClientKey are the PEM files of a client certificate issued for the demo identity
+	ClientCert string
+	ClientKey  string
+	// TokenFile holds the token of the demo identity, for --token-auth-file
+	TokenFile string
+	// Fixtures is the directory of the dataset, for --mock-fixtures
+	Fixtures string
+	// PriceSheet holds the prices the costs are estimated with, for --cost-price-sheet-file
+	PriceSheet string
+}
+
+// NewFiles returns the paths of the files of the demo in the given directory
+func NewFiles(dir string) *Files {
+	return &Files{
+		CACert:     filepath.Join(dir, "ca.crt"),
+		ServerCert: filepath.Join(dir, "server.crt"),
+		ServerKey:  filepath.Join(dir, "server.key"),
+		ClientCert: filepath.Join(dir, "client.crt"),
+		ClientKey:  filepath.Join(dir, "client.key"),
+		TokenFile:  filepath.Join(dir, "tokens.csv"),
+		Fixtures:   filepath.Join(dir, "fixtures"),
+		PriceSheet: filepath.Join(dir, "price-sheet.yaml"),
+	}
+}
+
+// Write writes the files of the demo, the dataset being generated as of the given time. The files already written,
+// e.g. by a previous demo using the same directory, are kept as they are, so that they can be edited between demos.
+func (f *Files) Write(scheme *runtime.Scheme, now time.Time) error {
+	if !exists(f.CACert, f.ServerCert, f.ServerKey, f.ClientCert, f.ClientKey) {
+		if err := f.writeCertificates(now); err != nil {
+			return err
+		}
+	}
+	if !exists(f.TokenFile) {
+		token := make([]byte, 16)
+		if _, err := rand.Read(token); err != nil {
+			return fmt.Errorf("failed to generate the token: %w", err)
+		}
+		content := fmt.Sprintf("# token,user,uid,groups\n%s,%s,%s\n", hex.EncodeToString(token), Identity, Identity)
+		if err := os.WriteFile(f.TokenFile, []byte(content), 0o600); err != nil {
+			return fmt.Errorf("failed to write the token file: %w", err)
+		}
+	}
+	if !exists(f.Fixtures) {
+		var fixtures bytes.Buffer
+		if err := mock.EncodeFixtures(&fixtures, scheme, Objects(now)...); err != nil {
+			return fmt.Errorf("failed to encode the dataset: %w", err)
+		}
+		if err := os.Mkdir(f.Fixtures, 0o700); err != nil {
+			return fmt.Errorf("failed to create the fixtures directory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(f.Fixtures, "dataset.yaml"), fixtures.Bytes(), 0o600); err != nil {
+			return fmt.Errorf("failed to write the dataset: %w", err)
+		}
+	}
+	if !exists(f.PriceSheet) {
+		content := "currency: USD\ncpuPerCoreMonth: 23.5\nmemoryPerGiBMonth: 3.2\nbasis: requests\n"
+		if err := os.WriteFile(f.PriceSheet, []byte(content), 0o600); err != nil {
+			return fmt.Errorf("failed to write the price sheet: %w", err)
+		}
+	}
+	return nil
+}
+
+// Token returns the token of the demo identity, i.e. the first one of the token file
+func (f *Files) Token() (string, error) {
+	file, err := os.Open(f.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the token file: %w", err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	record, err := reader.Read()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the token file: %w", err)
+	}
+	return record[0], nil
+}
+
+// writeCertificates generates a CA, and the server and client certificates it issues
+func (f *Files) writeCertificates(now time.Time) error {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate the CA key: %w", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "go-k8s-http-api-demo-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certificateValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return fmt.Errorf("failed to create the CA certificate: %w", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return fmt.Errorf("failed to parse the CA certificate: %w", err)
+	}
+	if err := writePEM(f.CACert, "CERTIFICATE", caDER); err != nil {
+		return err
+	}
+	if err := issue(ca, caKey, "localhost", x509.ExtKeyUsageServerAuth, f.ServerCert, f.ServerKey, now); err != nil {
+		return fmt.Errorf("failed to issue the server certificate: %w", err)
+	}
+	if err := issue(ca, caKey, Identity, x509.ExtKeyUsageClientAuth, f.ClientCert, f.ClientKey, now); err != nil {
+		return fmt.Errorf("failed to issue the client certificate: %w", err)
+	}
+	return nil
+}
+
+// issue issues a certificate for the given common name, also valid for localhost, and writes it and its key to the
+// given paths
+func issue(ca *x509.Certificate, caKey *ecdsa.PrivateKey, commonName string, usage x509.ExtKeyUsage, certPath, keyPath string, now time.Time) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certificateValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := writePEM(certPath, "CERTIFICATE", der); err != nil {
+		return err
+	}
+	return writePEM(keyPath, "EC PRIVATE KEY", keyDER)
+}
+
+func writePEM(path, blockType string, der []byte) error {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// exists returns whether all the paths exist
+func exists(paths ...string) bool {
+	for _, path := range paths {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return false
+		}
+	}
+	return true
+}
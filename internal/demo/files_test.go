@@ -0,0 +1,101 @@
+package demo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/cost"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/mock"
+)
+
+func TestFiles_Write(t *testing.T) {
+	scheme := newTestScheme(t)
+	now := time.Now()
+	files := NewFiles(t.TempDir())
+	if err := files.Write(scheme, now); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// The server certificate is valid for localhost, and the client certificate for the demo identity, both issued by
+	// the CA
+	caPEM, err := os.ReadFile(files.CACert)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		t.Fatalf("the CA certificate isn't valid PEM")
+	}
+	tests := []struct {
+		name               string
+		certPath, keyPath  string
+		opts               x509.VerifyOptions
+		expectedCommonName string
+	}{
+		{name: "server", certPath: files.ServerCert, keyPath: files.ServerKey,
+			opts: x509.VerifyOptions{DNSName: "localhost", Roots: roots}, expectedCommonName: "localhost"},
+		{name: "client", certPath: files.ClientCert, keyPath: files.ClientKey,
+			opts: x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, expectedCommonName: Identity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := tls.LoadX509KeyPair(tt.certPath, tt.keyPath)
+			if err != nil {
+				t.Fatalf("LoadX509KeyPair() error = %v", err)
+			}
+			if _, err := pair.Leaf.Verify(tt.opts); err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+			if pair.Leaf.Subject.CommonName != tt.expectedCommonName {
+				t.Errorf("CommonName = %q, want %q", pair.Leaf.Subject.CommonName, tt.expectedCommonName)
+			}
+		})
+	}
+
+	// The token authenticates the demo identity, and the other files are valid configurations of the server
+	token, err := files.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	tokens, err := auth.LoadTokenFile(files.TokenFile)
+	if err != nil {
+		t.Fatalf("LoadTokenFile() error = %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/deployments", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if principal, ok, err := tokens.Authenticate(req); err != nil || !ok || principal.Name != Identity {
+		t.Errorf("Authenticate() = %+v, %v, %v, want the %s identity", principal, ok, err, Identity)
+	}
+	if _, err := cost.LoadFile(files.PriceSheet); err != nil {
+		t.Errorf("LoadFile() error = %v", err)
+	}
+	objects, err := mock.LoadFixtures(files.Fixtures, scheme)
+	if err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+	if len(objects) != len(Objects(now)) {
+		t.Errorf("LoadFixtures() = %d objects, want the %d of the dataset", len(objects), len(Objects(now)))
+	}
+
+	// The files already written are kept, e.g. once edited
+	edited := filepath.Join(files.Fixtures, "dataset.yaml")
+	if err := os.WriteFile(edited, []byte("# edited\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := files.Write(scheme, now); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if content, _ := os.ReadFile(edited); string(content) != "# edited\n" {
+		t.Errorf("the edited fixtures = %q, want them kept", content)
+	}
+	if again, err := files.Token(); err != nil || again != token {
+		t.Errorf("Token() = %q, %v, want the token kept", again, err)
+	}
+}
@@ -0,0 +1,176 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Operations describing how a field changed
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// Change is a single field which differs between two objects
+type Change struct {
+	// Path is the path of the field, e.g. spec.template.spec.containers[name=app].image.
+	// List items are addressed by their name when every item has a unique one, or by their index otherwise.
+	Path string `json:"path"`
+	Op   string `json:"op"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// serverMetadataFields are the metadata fields set by the API server, which change on every write and are never part
+// of a proposed change
+var serverMetadataFields = []string{"managedFields", "resourceVersion", "generation", "uid", "creationTimestamp", "selfLink"}
+
+// plainKey matches the map keys which can be used as-is in a path
+var plainKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Objects returns the changes needed to turn the live object into the proposed one, both in their unstructured form.
+// Fields populated by the API server (status, and metadata such as the resourceVersion or managedFields) are ignored.
+// The changes are sorted by path.
+func Objects(live, proposed map[string]any) []Change {
+	changes := compare("", stripServerFields(live), stripServerFields(proposed))
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// stripServerFields returns a shallow copy of the object without the fields populated by the API server
+func stripServerFields(obj map[string]any) map[string]any {
+	stripped := make(map[string]any, len(obj))
+	for key, value := range obj {
+		if key != "status" {
+			stripped[key] = value
+		}
+	}
+	if metadata, ok := obj["metadata"].(map[string]any); ok {
+		strippedMetadata := make(map[string]any, len(metadata))
+		for key, value := range metadata {
+			strippedMetadata[key] = value
+		}
+		for _, field := range serverMetadataFields {
+			delete(strippedMetadata, field)
+		}
+		stripped["metadata"] = strippedMetadata
+	}
+	return stripped
+}
+
+// compare recursively compares two values, and returns the changes between them
+func compare(path string, old, new any) []Change {
+	switch oldValue := old.(type) {
+	case map[string]any:
+		if newValue, ok := new.(map[string]any); ok {
+			return compareMaps(path, oldValue, newValue)
+		}
+	case []any:
+		if newValue, ok := new.([]any); ok {
+			return compareLists(path, oldValue, newValue)
+		}
+	}
+	if reflect.DeepEqual(old, new) {
+		return nil
+	}
+	return []Change{{Path: path, Op: OpReplace, Old: old, New: new}}
+}
+
+// compareMaps compares the keys of two maps
+func compareMaps(path string, old, new map[string]any) []Change {
+	var changes []Change
+	for key, oldValue := range old {
+		newValue, ok := new[key]
+		if !ok {
+			changes = append(changes, Change{Path: fieldPath(path, key), Op: OpRemove, Old: oldValue})
+			continue
+		}
+		changes = append(changes, compare(fieldPath(path, key), oldValue, newValue)...)
+	}
+	for key, newValue := range new {
+		if _, ok := old[key]; !ok {
+			changes = append(changes, Change{Path: fieldPath(path, key), Op: OpAdd, New: newValue})
+		}
+	}
+	return changes
+}
+
+// compareLists compares the items of two lists, by name when possible, or by index otherwise
+func compareLists(path string, old, new []any) []Change {
+	oldNames, oldNamed := itemNames(old)
+	newNames, newNamed := itemNames(new)
+	if oldNamed && newNamed {
+		var changes []Change
+		oldItems := make(map[string]any, len(old))
+		for i, item := range old {
+			oldItems[oldNames[i]] = item
+		}
+		newItems := make(map[string]any, len(new))
+		for i, item := range new {
+			newItems[newNames[i]] = item
+		}
+		for i, name := range oldNames {
+			itemPath := fmt.Sprintf("%s[name=%s]", path, name)
+			newItem, ok := newItems[name]
+			if !ok {
+				changes = append(changes, Change{Path: itemPath, Op: OpRemove, Old: old[i]})
+				continue
+			}
+			changes = append(changes, compare(itemPath, old[i], newItem)...)
+		}
+		for i, name := range newNames {
+			if _, ok := oldItems[name]; !ok {
+				changes = append(changes, Change{Path: fmt.Sprintf("%s[name=%s]", path, name), Op: OpAdd, New: new[i]})
+			}
+		}
+		return changes
+	}
+
+	var changes []Change
+	for i := 0; i < len(old) || i < len(new); i++ {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		switch {
+		case i >= len(new):
+			changes = append(changes, Change{Path: itemPath, Op: OpRemove, Old: old[i]})
+		case i >= len(old):
+			changes = append(changes, Change{Path: itemPath, Op: OpAdd, New: new[i]})
+		default:
+			changes = append(changes, compare(itemPath, old[i], new[i])...)
+		}
+	}
+	return changes
+}
+
+// itemNames returns the names of the list items, and whether every item is an object with a unique name
+func itemNames(items []any) ([]string, bool) {
+	names := make([]string, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		object, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		name, ok := object["name"].(string)
+		if !ok || seen[name] {
+			return nil, false
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, true
+}
+
+// fieldPath appends the key to the path, quoting keys which aren't plain identifiers (e.g. label and annotation keys)
+func fieldPath(path, key string) string {
+	if !plainKey.MatchString(key) {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,120 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newDeployment(replicas int64, image string) map[string]any {
+	return map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":            "bar",
+			"namespace":       "foo",
+			"resourceVersion": "1",
+			"generation":      int64(1),
+			"annotations":     map[string]any{"example.com/owner": "team-a"},
+		},
+		"spec": map[string]any{
+			"replicas": replicas,
+			"template": map[string]any{
+				"spec": map[string]any{
+					"containers": []any{
+						map[string]any{"name": "app", "image": image},
+						map[string]any{"name": "sidecar", "image": "proxy:1"},
+					},
+				},
+			},
+		},
+		"status": map[string]any{"replicas": replicas},
+	}
+}
+
+func TestObjects(t *testing.T) {
+	tests := []struct {
+		name       string
+		modifyLive func(live map[string]any)
+		modify     func(proposed map[string]any)
+		expected   []Change
+	}{
+		{
+			name: "no changes",
+		},
+		{
+			name: "server fields are ignored",
+			modify: func(proposed map[string]any) {
+				proposed["metadata"].(map[string]any)["resourceVersion"] = "2"
+				proposed["metadata"].(map[string]any)["generation"] = int64(2)
+				proposed["status"] = map[string]any{"replicas": int64(5)}
+			},
+		},
+		{
+			name: "replaced fields",
+			modify: func(proposed map[string]any) {
+				proposed["spec"].(map[string]any)["replicas"] = int64(3)
+				proposed["metadata"].(map[string]any)["annotations"] = map[string]any{"example.com/owner": "team-b"}
+			},
+			expected: []Change{
+				{Path: `metadata.annotations["example.com/owner"]`, Op: OpReplace, Old: "team-a", New: "team-b"},
+				{Path: "spec.replicas", Op: OpReplace, Old: int64(1), New: int64(3)},
+			},
+		},
+		{
+			name: "list items are matched by name",
+			modify: func(proposed map[string]any) {
+				spec := proposed["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)
+				spec["containers"] = []any{
+					map[string]any{"name": "sidecar", "image": "proxy:1"},
+					map[string]any{"name": "app", "image": "app:2"},
+					map[string]any{"name": "debug", "image": "busybox"},
+				}
+			},
+			expected: []Change{
+				{Path: "spec.template.spec.containers[name=app].image", Op: OpReplace, Old: "app:1", New: "app:2"},
+				{Path: "spec.template.spec.containers[name=debug]", Op: OpAdd, New: map[string]any{"name": "debug", "image": "busybox"}},
+			},
+		},
+		{
+			name: "unnamed list items are matched by index",
+			modifyLive: func(live map[string]any) {
+				live["spec"].(map[string]any)["args"] = []any{"--b"}
+			},
+			modify: func(proposed map[string]any) {
+				proposed["spec"].(map[string]any)["args"] = []any{"--a", "--c"}
+			},
+			expected: []Change{
+				{Path: "spec.args[0]", Op: OpReplace, Old: "--b", New: "--a"},
+				{Path: "spec.args[1]", Op: OpAdd, New: "--c"},
+			},
+		},
+		{
+			name: "removed fields",
+			modify: func(proposed map[string]any) {
+				delete(proposed["metadata"].(map[string]any), "annotations")
+			},
+			expected: []Change{
+				{Path: "metadata.annotations", Op: OpRemove, Old: map[string]any{"example.com/owner": "team-a"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live, proposed := newDeployment(1, "app:1"), newDeployment(1, "app:1")
+			if tt.modifyLive != nil {
+				tt.modifyLive(live)
+			}
+			if tt.modify != nil {
+				tt.modify(proposed)
+			}
+			changes := Objects(live, proposed)
+			if len(changes) == 0 && len(tt.expected) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(changes, tt.expected) {
+				t.Errorf("Objects() = %#v, want %#v", changes, tt.expected)
+			}
+		})
+	}
+}
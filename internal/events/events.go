@@ -0,0 +1,46 @@
+// Package events records Kubernetes events on the objects changed through the API, e.g. "Scaled by go-k8s-http-api on
+// behalf of CN=ci-bot from 3 to 7", so that `kubectl describe` shows the provenance of the changes even to the teams
+// not reading the audit log.
+package events
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Recorder records the changes made through the API as events on the changed objects. A nil Recorder records nothing,
+// and failing to record an event is only logged by the underlying recorder, since the change was already made.
+type Recorder struct {
+	Recorder record.EventRecorder
+	// Component names the server in the messages of the events
+	Component string
+}
+
+// Record records a Normal event on the changed object. The reason of the event is the operation which made the
+// change, e.g. SetDeploymentReplicas, and action describes it, e.g. "Scaled". From and to are the values before and
+// after the change, if any.
+func (r *Recorder) Record(obj runtime.Object, reason, action, identity, from, to string) {
+	if r == nil {
+		return
+	}
+	r.Recorder.Event(obj, corev1.EventTypeNormal, reason, Message(action, r.Component, identity, from, to))
+}
+
+// Message returns the message of the event of a change
+func Message(action, component, identity, from, to string) string {
+	var b strings.Builder
+	b.WriteString(action + " by " + component)
+	if identity != "" {
+		b.WriteString(" on behalf of CN=" + identity)
+	}
+	if from != "" {
+		b.WriteString(" from " + from)
+	}
+	if to != "" {
+		b.WriteString(" to " + to)
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		identity string
+		from     string
+		to       string
+		expected string
+	}{
+		{"Test From And To", "Scaled", "ci-bot", "3", "7", "Scaled by go-k8s-http-api on behalf of CN=ci-bot from 3 to 7"},
+		{"Test To Only", "Restored", "alice", "", "snapshot 1234", "Restored by go-k8s-http-api on behalf of CN=alice to snapshot 1234"},
+		{"Test From Only", "Replica bounds removed", "alice", "[1, 5]", "", "Replica bounds removed by go-k8s-http-api on behalf of CN=alice from [1, 5]"},
+		{"Test Anonymous", "Configured", "", "", "", "Configured by go-k8s-http-api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Message(tt.action, "go-k8s-http-api", tt.identity, tt.from, tt.to); got != tt.expected {
+				t.Errorf("Message() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecorder_Record(t *testing.T) {
+	fake := record.NewFakeRecorder(1)
+	r := &Recorder{Recorder: fake, Component: "go-k8s-http-api"}
+	r.Record(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"}}, "SetDeploymentReplicas", "Scaled", "ci-bot", "3", "7")
+	if event, expected := <-fake.Events, "Normal SetDeploymentReplicas Scaled by go-k8s-http-api on behalf of CN=ci-bot from 3 to 7"; event != expected {
+		t.Errorf("Record() recorded %q, want %q", event, expected)
+	}
+
+	// A nil recorder records nothing
+	var nilRecorder *Recorder
+	nilRecorder.Record(&appsv1.Deployment{}, "SetDeploymentReplicas", "Scaled", "ci-bot", "3", "7")
+}
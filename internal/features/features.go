@@ -0,0 +1,247 @@
+// Package features holds the feature gates of the endpoints. The gates enabled by --feature-gates at startup have
+// their endpoints registered, and can then be switched off and back on at runtime, e.g. to stop serving a misbehaving
+// endpoint during an incident without redeploying.
+package features
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Endpoint names which can be toggled via the --feature-gates flag
+const (
+	ListDeployments       = "ListDeployments"
+	GetDeployment         = "GetDeployment"
+	GetDeploymentReplicas = "GetDeploymentReplicas"
+	SetDeploymentReplicas = "SetDeploymentReplicas"
+	DiffDeployment        = "DiffDeployment"
+	ApplyManifests        = "ApplyManifests"
+	GetDeploymentManifest = "GetDeploymentManifest"
+	GetDeploymentHealth   = "GetDeploymentHealth"
+	RolloutAlerts         = "RolloutAlerts"
+	ReplicaBounds         = "ReplicaBounds"
+	DeploymentOwnership   = "DeploymentOwnership"
+	DeploymentManagers    = "DeploymentManagers"
+	CostEstimation        = "CostEstimation"
+	ImageInventory        = "ImageInventory"
+	DeploymentSnapshots   = "DeploymentSnapshots"
+	NamespaceHibernation  = "NamespaceHibernation"
+	EvictPods             = "EvictPods"
+	SchedulingInsights    = "SchedulingInsights"
+	DeploymentTopology    = "DeploymentTopology"
+	Applications          = "Applications"
+	Search                = "Search"
+)
+
+// defaultGates holds the known endpoints and whether they are enabled by default
+var defaultGates = map[string]bool{
+	ListDeployments:       true,
+	GetDeployment:         true,
+	GetDeploymentReplicas: true,
+	SetDeploymentReplicas: true,
+	DiffDeployment:        true,
+	GetDeploymentManifest: true,
+	GetDeploymentHealth:   true,
+	RolloutAlerts:         true,
+	ReplicaBounds:         true,
+	DeploymentOwnership:   true,
+	DeploymentManagers:    true,
+	ImageInventory:        true,
+	DeploymentSnapshots:   true,
+	SchedulingInsights:    true,
+	Search:                true,
+	// Applying arbitrary manifests grants clients much more than scaling, so it has to be enabled explicitly
+	ApplyManifests: false,
+	// Cost estimates need a price sheet, so they have to be enabled explicitly along with it
+	CostEstimation: false,
+	// Hibernation scales every workload of a namespace at once, including statefulsets, which needs permissions on top
+	// of the ones on deployments, so it has to be enabled explicitly
+	NamespaceHibernation: false,
+	// Evicting pods needs permissions on pods on top of the ones on deployments, so it has to be enabled explicitly
+	EvictPods: false,
+	// The topology of a deployment needs permissions on services and ingresses on top of the ones on deployments, so it
+	// has to be enabled explicitly
+	DeploymentTopology: false,
+	// Applications are grouped from statefulsets, services and HorizontalPodAutoscalers as well, which needs
+	// permissions on top of the ones on deployments, so they have to be enabled explicitly
+	Applications: false,
+}
+
+var (
+	// ErrUnknown is returned when switching a feature gate which doesn't exist
+	ErrUnknown = errors.New("unknown feature gate")
+	// ErrNotRegistered is returned when switching on a feature gate disabled at startup, whose endpoints aren't
+	// registered
+	ErrNotRegistered = errors.New("the feature gate is disabled by --feature-gates, only the ones enabled at startup can be switched at runtime")
+)
+
+var switchedOffGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "k8s_api_proxy_feature_switched_off",
+	Help: "Whether the endpoints of a feature gate enabled at startup are switched off at runtime (1) or served (0)",
+}, []string{"feature"})
+
+func init() {
+	metrics.Registry.MustRegister(switchedOffGauge)
+}
+
+// Gates holds the enabled / disabled state of every known endpoint.
+// It implements flag.Value so it can be populated from a "Name=bool,Name2=bool" command line flag.
+type Gates struct {
+	mu      sync.RWMutex
+	enabled map[string]bool
+	// switchedOff holds the gates enabled at startup which were switched off at runtime
+	switchedOff map[string]bool
+}
+
+// Status is the state of a feature gate, as reported in /admin/features
+type Status struct {
+	Name string `json:"name"`
+	// Enabled is whether the gate is enabled by --feature-gates, i.e. whether its endpoints are registered
+	Enabled bool `json:"enabled"`
+	// Serving is whether its endpoints are served, i.e. the gate is enabled and not switched off at runtime
+	Serving bool `json:"serving"`
+}
+
+// NewGates returns a Gates object populated with the default state of every known endpoint
+func NewGates() *Gates {
+	enabled := make(map[string]bool, len(defaultGates))
+	for name, value := range defaultGates {
+		enabled[name] = value
+	}
+	return &Gates{enabled: enabled, switchedOff: map[string]bool{}}
+}
+
+// Set parses a comma separated list of Name=bool pairs and applies them on top of the current state
+func (g *Gates) Set(value string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, rawValue, found := strings.Cut(pair, "=")
+		if !found {
+			return fmt.Errorf("missing bool value for feature gate %q", name)
+		}
+		name = strings.TrimSpace(name)
+		if _, ok := defaultGates[name]; !ok {
+			return fmt.Errorf("unknown feature gate %q", name)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(rawValue))
+		if err != nil {
+			return fmt.Errorf("invalid value %q for feature gate %q: %w", rawValue, name, err)
+		}
+		g.enabled[name] = enabled
+	}
+
+	return nil
+}
+
+// String returns the current state as a sorted, comma separated list of Name=bool pairs
+func (g *Gates) String() string {
+	if g == nil {
+		return ""
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	pairs := make([]string, 0, len(g.enabled))
+	for name, enabled := range g.enabled {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Enabled returns whether the given endpoint is enabled by --feature-gates, regardless of it being switched off at
+// runtime. Unknown endpoints are always disabled.
+func (g *Gates) Enabled(name string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.enabled[name]
+}
+
+// Serving returns whether the given endpoint is enabled and not switched off at runtime
+func (g *Gates) Serving(name string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.enabled[name] && !g.switchedOff[name]
+}
+
+// Switch switches the endpoints of the given gate off, or back on, at runtime. Only the gates enabled at startup can
+// be switched, since the endpoints of the other ones aren't registered.
+func (g *Gates) Switch(name string, on bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	enabled, ok := g.enabled[name]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w %q", ErrUnknown, name)
+	case !enabled:
+		return ErrNotRegistered
+	}
+	if on {
+		delete(g.switchedOff, name)
+		switchedOffGauge.WithLabelValues(name).Set(0)
+	} else {
+		g.switchedOff[name] = true
+		switchedOffGauge.WithLabelValues(name).Set(1)
+	}
+	return nil
+}
+
+// All returns a copy of the current state of every known endpoint, i.e. whether it's served
+func (g *Gates) All() map[string]bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	all := make(map[string]bool, len(g.enabled))
+	for name, enabled := range g.enabled {
+		all[name] = enabled && !g.switchedOff[name]
+	}
+	return all
+}
+
+// Statuses returns the state of every known gate, sorted by name
+func (g *Gates) Statuses() []Status {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(g.enabled))
+	for name, enabled := range g.enabled {
+		statuses = append(statuses, Status{Name: name, Enabled: enabled, Serving: enabled && !g.switchedOff[name]})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Middleware returns a new http.HandlerFunc which re-evaluates the gate on every request, rejecting them with a 503
+// Service Unavailable while the gate is switched off at runtime
+func (g *Gates) Middleware(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if g.Serving(name) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logger := klog.FromContext(r.Context())
+		logger.V(2).Info("Request rejected, its endpoint is switched off", "feature", name)
+		if err := problem.Write(w, http.StatusServiceUnavailable, fmt.Sprintf("The %s endpoints are switched off", name)); err != nil {
+			logger.Error(err, "Error encoding response")
+		}
+	}
+}
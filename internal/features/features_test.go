@@ -0,0 +1,143 @@
+package features
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGates_Set(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expectedError bool
+		expected      map[string]bool
+	}{
+		{
+			"Test Set Defaults",
+			"",
+			false,
+			map[string]bool{ListDeployments: true, GetDeployment: true, GetDeploymentReplicas: true, SetDeploymentReplicas: true, DiffDeployment: true, ApplyManifests: false, GetDeploymentManifest: true, GetDeploymentHealth: true, RolloutAlerts: true, ReplicaBounds: true, DeploymentManagers: true, DeploymentOwnership: true, CostEstimation: false, ImageInventory: true, DeploymentSnapshots: true, NamespaceHibernation: false, EvictPods: false, SchedulingInsights: true, DeploymentTopology: false, Applications: false, Search: true},
+		},
+		{
+			"Test Set Disable Single Endpoint",
+			"SetDeploymentReplicas=false",
+			false,
+			map[string]bool{ListDeployments: true, GetDeployment: true, GetDeploymentReplicas: true, SetDeploymentReplicas: false, DiffDeployment: true, ApplyManifests: false, GetDeploymentManifest: true, GetDeploymentHealth: true, RolloutAlerts: true, ReplicaBounds: true, DeploymentManagers: true, DeploymentOwnership: true, CostEstimation: false, ImageInventory: true, DeploymentSnapshots: true, NamespaceHibernation: false, EvictPods: false, SchedulingInsights: true, DeploymentTopology: false, Applications: false, Search: true},
+		},
+		{
+			"Test Set Multiple Endpoints With Spaces",
+			"ListDeployments=false, GetDeploymentReplicas=false",
+			false,
+			map[string]bool{ListDeployments: false, GetDeployment: true, GetDeploymentReplicas: false, SetDeploymentReplicas: true, DiffDeployment: true, ApplyManifests: false, GetDeploymentManifest: true, GetDeploymentHealth: true, RolloutAlerts: true, ReplicaBounds: true, DeploymentManagers: true, DeploymentOwnership: true, CostEstimation: false, ImageInventory: true, DeploymentSnapshots: true, NamespaceHibernation: false, EvictPods: false, SchedulingInsights: true, DeploymentTopology: false, Applications: false, Search: true},
+		},
+		{
+			"Test Set Unknown Endpoint",
+			"ExecPod=false",
+			true,
+			nil,
+		},
+		{
+			"Test Set Missing Value",
+			"ListDeployments",
+			true,
+			nil,
+		},
+		{
+			"Test Set Invalid Value",
+			"ListDeployments=maybe",
+			true,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGates()
+			err := g.Set(tt.value)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Set() error = %v, expectedError %v", err, tt.expectedError)
+			}
+			if tt.expectedError {
+				return
+			}
+			for name, enabled := range tt.expected {
+				if g.Enabled(name) != enabled {
+					t.Errorf("Enabled(%s) = %v, want %v", name, g.Enabled(name), enabled)
+				}
+			}
+		})
+	}
+}
+
+func TestGates_String(t *testing.T) {
+	g := NewGates()
+	if err := g.Set("SetDeploymentReplicas=false"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	expected := "Applications=false,ApplyManifests=false,CostEstimation=false,DeploymentManagers=true,DeploymentOwnership=true,DeploymentSnapshots=true,DeploymentTopology=false,DiffDeployment=true,EvictPods=false,GetDeployment=true,GetDeploymentHealth=true,GetDeploymentManifest=true,GetDeploymentReplicas=true,ImageInventory=true,ListDeployments=true,NamespaceHibernation=false,ReplicaBounds=true,RolloutAlerts=true,SchedulingInsights=true,Search=true,SetDeploymentReplicas=false"
+	if g.String() != expected {
+		t.Errorf("String() = %v, want %v", g.String(), expected)
+	}
+}
+
+func TestGates_Switch(t *testing.T) {
+	tests := []struct {
+		name            string
+		feature         string
+		on              bool
+		expectedErr     error
+		expectedServing bool
+	}{
+		{name: "switched off", feature: Search, on: false, expectedServing: false},
+		{name: "switched back on", feature: Search, on: true, expectedServing: true},
+		{name: "disabled at startup", feature: ApplyManifests, on: true, expectedErr: ErrNotRegistered},
+		{name: "unknown", feature: "ExecPod", on: false, expectedErr: ErrUnknown},
+	}
+	g := NewGates()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := g.Switch(tt.feature, tt.on)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Switch() error = %v, want %v", err, tt.expectedErr)
+			}
+			if g.Serving(tt.feature) != tt.expectedServing {
+				t.Errorf("Serving(%s) = %v, want %v", tt.feature, g.Serving(tt.feature), tt.expectedServing)
+			}
+			if g.All()[tt.feature] != tt.expectedServing {
+				t.Errorf("All()[%s] = %v, want %v", tt.feature, g.All()[tt.feature], tt.expectedServing)
+			}
+		})
+	}
+
+	// Switching an endpoint off doesn't change the configured state
+	if err := g.Switch(Search, false); err != nil {
+		t.Fatalf("Switch() error = %v", err)
+	}
+	if !g.Enabled(Search) || !strings.Contains(g.String(), "Search=true") {
+		t.Errorf("Enabled(%s) = false, want the gate still enabled by --feature-gates", Search)
+	}
+}
+
+func TestGates_Middleware(t *testing.T) {
+	g := NewGates()
+	handler := g.Middleware(Search, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, serving := range []bool{false, true} {
+		if err := g.Switch(Search, serving); err != nil {
+			t.Fatalf("Switch() error = %v", err)
+		}
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/search?q=foo", nil))
+		expected := http.StatusServiceUnavailable
+		if serving {
+			expected = http.StatusOK
+		}
+		if w.Code != expected {
+			t.Errorf("status code with serving %v = %d, want %d", serving, w.Code, expected)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// Package freeze enforces change freezes, e.g. production freezes during holidays or on weekends: the mutating
+// requests are rejected during the freeze windows, which are either configured as fixed periods and cron schedules,
+// or read from the events of an iCalendar feed.
+package freeze
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultCalendarRefresh is how often the calendar is fetched, unless configured otherwise
+const defaultCalendarRefresh = 15 * time.Minute
+
+// WindowConfig is a period during which changes are frozen, either fixed (start and end) or recurring (schedule and
+// duration)
+type WindowConfig struct {
+	Name string `json:"name"`
+	// Start and End are the RFC 3339 times the fixed window starts and ends at
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// Schedule is the cron expression of the starts of the recurring window, e.g. "0 18 * * FRI"
+	Schedule string `json:"schedule,omitempty"`
+	// Duration is how long the recurring window lasts from each of its starts, e.g. 62h
+	Duration string `json:"duration,omitempty"`
+	// TimeZone is the time zone the schedule is evaluated in, e.g. Europe/Berlin. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// Namespaces are the patterns of the namespaces frozen by the window, in the path.Match syntax (e.g. "prod-*").
+	// All namespaces are frozen when empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// CalendarConfig is an iCalendar feed whose events are freeze windows
+type CalendarConfig struct {
+	URL string `json:"url"`
+	// Refresh is how often the calendar is fetched. Defaults to 15m.
+	Refresh string `json:"refresh,omitempty"`
+	// TimeZone is the time zone of the dates of the calendar which don't name one. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// Namespaces are the patterns of the namespaces frozen by the events. All namespaces are frozen when empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// Config is the change freeze configuration file
+type Config struct {
+	Windows  []WindowConfig  `json:"windows,omitempty"`
+	Calendar *CalendarConfig `json:"calendar,omitempty"`
+	// OverrideIdentities are the identities whose changes are allowed during the freezes, e.g. to fix incidents
+	OverrideIdentities []string `json:"overrideIdentities,omitempty"`
+}
+
+// Window is an active freeze window
+type Window struct {
+	Name string
+	End  time.Time
+}
+
+// window is a configured freeze window
+type window struct {
+	name       string
+	start, end time.Time
+	schedule   *Schedule
+	duration   time.Duration
+	namespaces []string
+}
+
+// activeAt returns when the window ends if it's active at the given time, in the namespace
+func (w window) activeAt(t time.Time, namespace string) (time.Time, bool) {
+	if !matchesNamespace(w.namespaces, namespace) {
+		return time.Time{}, false
+	}
+	if w.schedule == nil {
+		return w.end, !t.Before(w.start) && t.Before(w.end)
+	}
+	start, found := w.schedule.Prev(t, t.Add(-w.duration))
+	if !found || !start.Add(w.duration).After(t) {
+		return time.Time{}, false
+	}
+	return start.Add(w.duration), true
+}
+
+// matchesNamespace returns whether the namespace matches one of the patterns. Requests without a namespace (e.g. to
+// /apply) match all of them, since they may change any namespace.
+func matchesNamespace(patterns []string, namespace string) bool {
+	if len(patterns) == 0 || namespace == "" {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Freeze rejects the changes made during its windows
+type Freeze struct {
+	windows   []window
+	overrides []string
+	calendar  *calendar
+	now       func() time.Time
+}
+
+// calendar holds the events of an iCalendar feed, as of its last fetch
+type calendar struct {
+	url        string
+	client     *http.Client
+	refresh    time.Duration
+	location   *time.Location
+	namespaces []string
+
+	mu      sync.RWMutex
+	windows []window
+}
+
+// New returns a new Freeze from the given configuration
+func New(config Config) (*Freeze, error) {
+	f := &Freeze{overrides: config.OverrideIdentities, now: time.Now}
+	for _, wc := range config.Windows {
+		w, err := newWindow(wc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", wc.Name, err)
+		}
+		f.windows = append(f.windows, w)
+	}
+	if c := config.Calendar; c != nil {
+		if c.URL == "" {
+			return nil, fmt.Errorf("the url of the calendar is required")
+		}
+		f.calendar = &calendar{url: c.URL, client: &http.Client{Timeout: 30 * time.Second}, refresh: defaultCalendarRefresh, namespaces: c.Namespaces}
+		if c.Refresh != "" {
+			refresh, err := time.ParseDuration(c.Refresh)
+			if err != nil || refresh <= 0 {
+				return nil, fmt.Errorf("invalid refresh %q of the calendar, must be a positive duration", c.Refresh)
+			}
+			f.calendar.refresh = refresh
+		}
+		var err error
+		if f.calendar.location, err = loadLocation(c.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid time zone of the calendar: %w", err)
+		}
+		if err := validatePatterns(c.Namespaces); err != nil {
+			return nil, fmt.Errorf("invalid namespaces of the calendar: %w", err)
+		}
+	}
+	return f, nil
+}
+
+func newWindow(config WindowConfig) (window, error) {
+	w := window{name: config.Name, namespaces: config.Namespaces}
+	if config.Name == "" {
+		return w, fmt.Errorf("the name of the window is required")
+	}
+	if err := validatePatterns(config.Namespaces); err != nil {
+		return w, err
+	}
+	switch {
+	case config.Schedule != "" && config.Start == "" && config.End == "":
+		location, err := loadLocation(config.TimeZone)
+		if err != nil {
+			return w, err
+		}
+		if w.schedule, err = ParseSchedule(config.Schedule, location); err != nil {
+			return w, err
+		}
+		if w.duration, err = time.ParseDuration(config.Duration); err != nil || w.duration <= 0 {
+			return w, fmt.Errorf("invalid duration %q, must be a positive duration", config.Duration)
+		}
+	case config.Start != "" && config.End != "" && config.Schedule == "" && config.Duration == "":
+		var err error
+		if w.start, err = time.Parse(time.RFC3339, config.Start); err != nil {
+			return w, fmt.Errorf("invalid start: %w", err)
+		}
+		if w.end, err = time.Parse(time.RFC3339, config.End); err != nil {
+			return w, fmt.Errorf("invalid end: %w", err)
+		}
+		if !w.end.After(w.start) {
+			return w, fmt.Errorf("the end must be after the start")
+		}
+	default:
+		return w, fmt.Errorf("windows must have either a start and an end, or a schedule and a duration")
+	}
+	return w, nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// LoadFile returns a new Freeze from the given YAML or JSON configuration file
+func LoadFile(path string) (*Freeze, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read change freeze file: %w", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse change freeze file %s: %w", path, err)
+	}
+	return New(config)
+}
+
+// Active returns the window freezing the changes of the namespace at the given time, if any. When several windows
+// are active, the one ending last is returned.
+func (f *Freeze) Active(t time.Time, namespace string) (Window, bool) {
+	windows := f.windows
+	if f.calendar != nil {
+		f.calendar.mu.RLock()
+		windows = append(slices.Clip(windows), f.calendar.windows...)
+		f.calendar.mu.RUnlock()
+	}
+	var active Window
+	found := false
+	for _, w := range windows {
+		if end, ok := w.activeAt(t, namespace); ok && (!found || end.After(active.End)) {
+			active, found = Window{Name: w.name, End: end}, true
+		}
+	}
+	return active, found
+}
+
+// Run fetches the calendar, if any, and refreshes it periodically until the context is cancelled. The events of the
+// last successful fetch are kept when a refresh fails.
+func (f *Freeze) Run(ctx context.Context) {
+	if f.calendar == nil {
+		return
+	}
+	logger := klog.FromContext(ctx).WithValues("calendar", f.calendar.url)
+	ticker := time.NewTicker(f.calendar.refresh)
+	defer ticker.Stop()
+	for {
+		if err := f.calendar.fetch(ctx); err != nil {
+			logger.Error(err, "Error fetching the change freeze calendar")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetch replaces the windows of the calendar with the events of the feed
+func (c *calendar) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("calendar returned status %d", resp.StatusCode)
+	}
+	events, err := ParseCalendar(resp.Body, c.location)
+	if err != nil {
+		return err
+	}
+
+	windows := make([]window, 0, len(events))
+	for _, event := range events {
+		windows = append(windows, window{name: event.Summary, start: event.Start, end: event.End, namespaces: c.namespaces})
+	}
+	c.mu.Lock()
+	c.windows = windows
+	c.mu.Unlock()
+	klog.FromContext(ctx).V(4).Info("Change freeze calendar fetched", "calendar", c.url, "events", len(events))
+	return nil
+}
+
+// Middleware returns a new http.HandlerFunc which rejects the mutating requests made during a freeze window with a
+// 403 Forbidden, unless made by an override identity. Safe requests (GET, HEAD and OPTIONS) are always passed to the
+// provided handler.
+func (f *Freeze) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		namespace := r.PathValue("namespace")
+		active, frozen := f.Active(f.now(), namespace)
+		if !frozen {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logger := klog.FromContext(r.Context())
+		identity := auth.Identity(r)
+		if identity != "" && slices.Contains(f.overrides, identity) {
+			logger.Info("Change freeze overridden", "window", active.Name, "identity", identity)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logger.Info("Change rejected by a change freeze", "window", active.Name, "identity", identity, "namespace", namespace)
+		w.Header().Set("Retry-After", strconv.Itoa(int(active.End.Sub(f.now()).Round(time.Second).Seconds())))
+		message := fmt.Sprintf("Changes are frozen by the %s change window until %s", active.Name, active.End.UTC().Format(time.RFC3339))
+		if encErr := problem.Write(w, http.StatusForbidden, message); encErr != nil {
+			logger.Error(encErr, "Error encoding response")
+		}
+	}
+}
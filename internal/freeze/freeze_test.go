@@ -0,0 +1,158 @@
+package freeze
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"Test Missing Name", Config{Windows: []WindowConfig{{Start: "2024-12-20T00:00:00Z", End: "2025-01-02T00:00:00Z"}}}},
+		{"Test Start And Schedule", Config{Windows: []WindowConfig{{Name: "a", Start: "2024-12-20T00:00:00Z", End: "2025-01-02T00:00:00Z", Schedule: "0 18 * * FRI"}}}},
+		{"Test Missing End", Config{Windows: []WindowConfig{{Name: "a", Start: "2024-12-20T00:00:00Z"}}}},
+		{"Test End Before Start", Config{Windows: []WindowConfig{{Name: "a", Start: "2025-01-02T00:00:00Z", End: "2024-12-20T00:00:00Z"}}}},
+		{"Test Missing Duration", Config{Windows: []WindowConfig{{Name: "a", Schedule: "0 18 * * FRI"}}}},
+		{"Test Invalid Time Zone", Config{Windows: []WindowConfig{{Name: "a", Schedule: "0 18 * * FRI", Duration: "62h", TimeZone: "Mars/Olympus"}}}},
+		{"Test Invalid Namespace Pattern", Config{Windows: []WindowConfig{{Name: "a", Schedule: "0 18 * * FRI", Duration: "62h", Namespaces: []string{"["}}}}},
+		{"Test Calendar Without URL", Config{Calendar: &CalendarConfig{}}},
+		{"Test Calendar Invalid Refresh", Config{Calendar: &CalendarConfig{URL: "http://calendar", Refresh: "often"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Errorf("New() expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "freeze.yaml")
+	config := "windows:\n  - name: weekend\n    schedule: \"0 18 * * FRI\"\n    duration: 62h\noverrideIdentities: [incident-commander]\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if len(f.windows) != 1 || len(f.overrides) != 1 {
+		t.Errorf("LoadFile() = %+v", f)
+	}
+
+	if err := os.WriteFile(path, []byte("windows:\n  - name: weekend\n    cron: \"0 18 * * FRI\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Errorf("LoadFile() expected an error for an unknown field")
+	}
+}
+
+func TestFreeze_Active(t *testing.T) {
+	f, err := New(Config{Windows: []WindowConfig{
+		{Name: "holidays", Start: "2024-12-20T00:00:00Z", End: "2025-01-02T00:00:00Z"},
+		{Name: "weekend", Schedule: "0 18 * * FRI", Duration: "62h", Namespaces: []string{"prod-*"}},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		time      time.Time
+		namespace string
+		expected  Window
+		active    bool
+	}{
+		{"Test Weekday", time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC), "prod-web", Window{}, false},
+		{"Test Weekend", time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), "prod-web", Window{"weekend", time.Date(2024, 6, 17, 8, 0, 0, 0, time.UTC)}, true},
+		{"Test Weekend Other Namespace", time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), "staging", Window{}, false},
+		{"Test Weekend No Namespace", time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), "", Window{"weekend", time.Date(2024, 6, 17, 8, 0, 0, 0, time.UTC)}, true},
+		{"Test Weekend End", time.Date(2024, 6, 17, 8, 0, 0, 0, time.UTC), "prod-web", Window{}, false},
+		{"Test Holidays", time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC), "staging", Window{"holidays", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)}, true},
+		{"Test Holidays Ending Last", time.Date(2024, 12, 28, 10, 0, 0, 0, time.UTC), "prod-web", Window{"holidays", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, ok := f.Active(tt.time, tt.namespace)
+			if ok != tt.active || active.Name != tt.expected.Name || !active.End.Equal(tt.expected.End) {
+				t.Errorf("Active() = %+v, %v, want %+v, %v", active, ok, tt.expected, tt.active)
+			}
+		})
+	}
+}
+
+func TestFreeze_Calendar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Release freeze\r\nDTSTART:20240610T000000Z\r\nDTEND:20240611T000000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	}))
+	defer server.Close()
+	f, err := New(Config{Calendar: &CalendarConfig{URL: server.URL, Namespaces: []string{"prod"}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := f.calendar.fetch(context.Background()); err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+	if active, ok := f.Active(time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC), "prod"); !ok || active.Name != "Release freeze" {
+		t.Errorf("Active() = %+v, %v, want the calendar event", active, ok)
+	}
+	if _, ok := f.Active(time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC), "dev"); ok {
+		t.Errorf("Active() expected the calendar event not to apply to other namespaces")
+	}
+}
+
+func TestFreeze_Middleware(t *testing.T) {
+	f, err := New(Config{
+		Windows:            []WindowConfig{{Name: "holidays", Start: "2024-12-20T00:00:00Z", End: "2025-01-02T00:00:00Z"}},
+		OverrideIdentities: []string{"incident-commander"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		method   string
+		identity string
+		now      time.Time
+		expected int
+	}{
+		{"Test Frozen", "PUT", "ci-bot", time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC), http.StatusForbidden},
+		{"Test Frozen Read", "GET", "ci-bot", time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC), http.StatusOK},
+		{"Test Frozen Override", "PUT", "incident-commander", time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC), http.StatusOK},
+		{"Test Not Frozen", "PUT", "ci-bot", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.now = func() time.Time { return tt.now }
+			r := httptest.NewRequest(tt.method, "/deployments/foo/bar/replicas", nil)
+			r.SetPathValue("namespace", "foo")
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: tt.identity}}
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+			w := httptest.NewRecorder()
+			f.Middleware(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })(w, r)
+			if w.Code != tt.expected {
+				t.Fatalf("Middleware() status code = %v, want %v", w.Code, tt.expected)
+			}
+			if tt.expected == http.StatusForbidden {
+				if !strings.Contains(w.Body.String(), "Changes are frozen by the holidays change window until 2025-01-02T00:00:00Z") {
+					t.Errorf("Middleware() body = %v", w.Body.String())
+				}
+				if w.Header().Get("Retry-After") != "777600" {
+					t.Errorf("Middleware() Retry-After = %v, want 777600", w.Header().Get("Retry-After"))
+				}
+			}
+		})
+	}
+}
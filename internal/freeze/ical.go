@@ -0,0 +1,97 @@
+package freeze
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event is a calendar event freezing changes
+type Event struct {
+	Summary    string
+	Start, End time.Time
+}
+
+// ParseCalendar parses the events of an iCalendar (RFC 5545) document. Events without an end last for the day they
+// start on if they're all-day events, and are skipped otherwise. Dates without a time zone are in the given location.
+// Recurring events are only considered for their first occurrence.
+func ParseCalendar(r io.Reader, location *time.Location) ([]Event, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// Long lines are folded, with the continuation lines starting with a space or a tab
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+
+	var events []Event
+	var event *Event
+	var allDay bool
+	for i, line := range lines {
+		nameAndParams, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		params := strings.Split(nameAndParams, ";")
+		switch name := strings.ToUpper(params[0]); {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event, allDay = &Event{}, false
+		case event == nil:
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if event.End.IsZero() && allDay {
+				event.End = event.Start.AddDate(0, 0, 1)
+			}
+			if !event.Start.IsZero() && event.End.After(event.Start) {
+				events = append(events, *event)
+			}
+			event = nil
+		case name == "SUMMARY":
+			event.Summary = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\\`, `\`).Replace(value)
+		case name == "DTSTART" || name == "DTEND":
+			t, date, err := parseDateTime(value, params[1:], location)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s on line %d of the calendar: %w", name, i+1, err)
+			}
+			if name == "DTSTART" {
+				event.Start, allDay = t, date
+			} else {
+				event.End = t
+			}
+		}
+	}
+	return events, nil
+}
+
+// parseDateTime parses an iCalendar date or date-time value, and returns whether it's a date
+func parseDateTime(value string, params []string, location *time.Location) (time.Time, bool, error) {
+	for _, param := range params {
+		key, paramValue, _ := strings.Cut(param, "=")
+		if strings.EqualFold(key, "TZID") {
+			tz, err := time.LoadLocation(strings.Trim(paramValue, `"`))
+			if err != nil {
+				return time.Time{}, false, err
+			}
+			location = tz
+		}
+	}
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	case len(value) == len("20060102"):
+		t, err := time.ParseInLocation("20060102", value, location)
+		return t, true, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	return t, false, err
+}
@@ -0,0 +1,64 @@
+package freeze
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCalendar(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	document := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"SUMMARY:Holiday freeze\\, all teams",
+		"DTSTART:20241220T000000Z",
+		"DTEND:20250102T000000Z",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Release ",
+		" day",
+		"DTSTART;TZID=Europe/Berlin:20240610T090000",
+		"DTEND;TZID=Europe/Berlin:20240610T170000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Black Friday",
+		"DTSTART;VALUE=DATE:20241129",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:No end",
+		"DTSTART:20240101T000000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := ParseCalendar(strings.NewReader(document), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseCalendar() error = %v", err)
+	}
+	expected := []Event{
+		{"Holiday freeze, all teams", time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"Release day", time.Date(2024, 6, 10, 9, 0, 0, 0, berlin), time.Date(2024, 6, 10, 17, 0, 0, 0, berlin)},
+		{"Black Friday", time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("ParseCalendar() = %v, want %v", events, expected)
+	}
+	for i := range expected {
+		if events[i].Summary != expected[i].Summary || !events[i].Start.Equal(expected[i].Start) || !events[i].End.Equal(expected[i].End) {
+			t.Errorf("ParseCalendar()[%d] = %v, want %v", i, events[i], expected[i])
+		}
+	}
+
+	if _, err := ParseCalendar(strings.NewReader("BEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT"), time.UTC); err == nil {
+		t.Errorf("ParseCalendar() expected an error for an invalid date")
+	}
+	if events, err := ParseCalendar(strings.NewReader(""), time.UTC); err != nil || !reflect.DeepEqual(events, []Event(nil)) {
+		t.Errorf("ParseCalendar() = %v, %v, want no events", events, err)
+	}
+}
@@ -0,0 +1,149 @@
+package freeze
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron expression with the standard 5 fields (minute, hour, day of month, month and day of week),
+// evaluated in a time zone
+type Schedule struct {
+	minutes, hours, days, months, weekdays []bool
+	// anyDay and anyWeekday are set if the day of month / day of week fields are *, in which case a day matches when
+	// the other field does. Otherwise, a day matches when either field does, as with cron.
+	anyDay, anyWeekday bool
+	location           *time.Location
+}
+
+// cronField is the range and the names of the values of a field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	minuteField  = cronField{name: "minute", min: 0, max: 59}
+	hourField    = cronField{name: "hour", min: 0, max: 23}
+	dayField     = cronField{name: "day of month", min: 1, max: 31}
+	monthField   = cronField{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	weekdayField = cronField{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// ParseSchedule parses the cron expression, e.g. "0 18 * * FRI", evaluated in the given time zone
+func ParseSchedule(expression string, location *time.Location) (*Schedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q, must have 5 fields (minute, hour, day of month, month, day of week)", expression)
+	}
+	s := &Schedule{location: location, anyDay: fields[2] == "*", anyWeekday: fields[4] == "*"}
+	var err error
+	for _, f := range []struct {
+		values *[]bool
+		field  cronField
+		raw    string
+	}{
+		{&s.minutes, minuteField, fields[0]},
+		{&s.hours, hourField, fields[1]},
+		{&s.days, dayField, fields[2]},
+		{&s.months, monthField, fields[3]},
+		{&s.weekdays, weekdayField, fields[4]},
+	} {
+		if *f.values, err = f.field.parse(f.raw); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", expression, err)
+		}
+	}
+	// Sunday is both 0 and 7
+	s.weekdays[0] = s.weekdays[0] || s.weekdays[7]
+	return s, nil
+}
+
+// parse returns the values matched by the field, indexed by value. Fields are comma separated lists of *, values and
+// ranges (e.g. 1-5), optionally with a step (e.g. */15).
+func (f cronField) parse(raw string) ([]bool, error) {
+	values := make([]bool, f.max+1)
+	for _, part := range strings.Split(raw, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q of the %s field", stepPart, f.name)
+			}
+		}
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = f.value(lowPart); err != nil {
+				return nil, err
+			}
+			high = low
+			if isRange {
+				if high, err = f.value(highPart); err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				high = f.max
+			}
+			if high < low {
+				return nil, fmt.Errorf("invalid range %q of the %s field", rangePart, f.name)
+			}
+		}
+		for v := low; v <= high; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// value parses a single value of the field, either a number or a name (e.g. mon)
+func (f cronField) value(raw string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(raw, name) {
+			return i + f.min, nil
+		}
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q of the %s field, must be within [%d, %d]", raw, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// matchesDay returns whether the day of t matches the schedule
+func (s *Schedule) matchesDay(t time.Time) bool {
+	if !s.months[t.Month()] {
+		return false
+	}
+	day, weekday := s.days[t.Day()], s.weekdays[t.Weekday()]
+	switch {
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
+
+// Prev returns the latest time at or before t which matches the schedule, searching back no further than since
+func (s *Schedule) Prev(t, since time.Time) (time.Time, bool) {
+	t = t.In(s.location).Truncate(time.Minute)
+	for !t.Before(since) {
+		switch {
+		case !s.matchesDay(t):
+			// Move to the last minute of the previous day
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location).Add(-time.Minute)
+		case !s.hours[t.Hour()]:
+			// Move to the last minute of the previous hour
+			t = t.Add(-time.Duration(t.Minute()+1) * time.Minute)
+		case !s.minutes[t.Minute()]:
+			t = t.Add(-time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
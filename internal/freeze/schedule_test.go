@@ -0,0 +1,64 @@
+package freeze
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+	}{
+		{"Test Missing Fields", "0 18 * *"},
+		{"Test Out Of Range", "60 18 * * *"},
+		{"Test Invalid Name", "0 18 * * FRIDAY"},
+		{"Test Invalid Step", "*/0 18 * * *"},
+		{"Test Reversed Range", "0 18-9 * * *"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSchedule(tt.expression, time.UTC); err == nil {
+				t.Errorf("ParseSchedule() expected an error")
+			}
+		})
+	}
+}
+
+func TestSchedule_Prev(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	// 2024-06-12 is a Wednesday
+	now := time.Date(2024, 6, 12, 10, 30, 45, 0, time.UTC)
+	tests := []struct {
+		name       string
+		expression string
+		location   *time.Location
+		expected   time.Time
+		found      bool
+	}{
+		{"Test Every Minute", "* * * * *", time.UTC, time.Date(2024, 6, 12, 10, 30, 0, 0, time.UTC), true},
+		{"Test Hourly", "15 * * * *", time.UTC, time.Date(2024, 6, 12, 10, 15, 0, 0, time.UTC), true},
+		{"Test Step", "*/20 9-17 * * *", time.UTC, time.Date(2024, 6, 12, 10, 20, 0, 0, time.UTC), true},
+		{"Test Weekday Name", "0 18 * * FRI", time.UTC, time.Date(2024, 6, 7, 18, 0, 0, 0, time.UTC), true},
+		{"Test Sunday As 7", "0 0 * * 7", time.UTC, time.Date(2024, 6, 9, 0, 0, 0, 0, time.UTC), true},
+		{"Test Day Or Weekday", "0 0 1 * MON", time.UTC, time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC), true},
+		{"Test Month", "0 0 24 dec *", time.UTC, time.Date(2023, 12, 24, 0, 0, 0, 0, time.UTC), true},
+		{"Test Time Zone", "0 12 * * *", berlin, time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC), true},
+		{"Test Never", "0 0 31 feb *", time.UTC, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSchedule(tt.expression, tt.location)
+			if err != nil {
+				t.Fatalf("ParseSchedule() error = %v", err)
+			}
+			prev, found := s.Prev(now, now.AddDate(-1, 0, 0).Add(time.Hour))
+			if found != tt.found || !prev.Equal(tt.expected) {
+				t.Errorf("Prev() = %v, %v, want %v, %v", prev, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
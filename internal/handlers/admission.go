@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/admission"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+)
+
+// AdmissionError is the response object of the changes rejected by the admission plugins
+type AdmissionError struct {
+	problem.Details
+	Message    string                `json:"message"`
+	Violations []admission.Violation `json:"violations"`
+}
+
+// admit runs the change of the deployment through the admission chain, applying the mutations of the plugins to the
+// proposed deployment, and returns the warnings of the plugins, which are also set as Warning headers. Changes which
+// aren't admitted get their response written, and false is returned.
+func (h *DeploymentsHandler) admit(w http.ResponseWriter, r *http.Request, d, proposed *appsv1.Deployment) ([]string, bool) {
+	if h.Admission == nil {
+		return nil, true
+	}
+	old, err := toUnstructured(d)
+	if err != nil {
+		writeAdmissionError(w, r, err)
+		return nil, false
+	}
+	object, err := toUnstructured(proposed)
+	if err != nil {
+		writeAdmissionError(w, r, err)
+		return nil, false
+	}
+	req := &admission.Request{
+		Object:    &unstructured.Unstructured{Object: object},
+		OldObject: &unstructured.Unstructured{Object: old},
+		Identity:  auth.Identity(r),
+	}
+	if err := h.Admission.Admit(r.Context(), req); err != nil {
+		writeAdmissionError(w, r, err)
+		return nil, false
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(req.Object.Object, proposed); err != nil {
+		writeAdmissionError(w, r, err)
+		return nil, false
+	}
+	addWarnings(w, req.Warnings)
+	return req.Warnings, true
+}
+
+// addWarnings sets the warnings as Warning headers, in the format of the API server's
+func addWarnings(w http.ResponseWriter, warnings []string) {
+	for _, warning := range warnings {
+		w.Header().Add("Warning", "299 - "+strconv.Quote(warning))
+	}
+}
+
+// writeAdmissionError writes the response of a change which wasn't admitted: a 422 Unprocessable Entity with the
+// violations if it was rejected, or a 500 Internal Server Error if it couldn't be evaluated
+func writeAdmissionError(w http.ResponseWriter, r *http.Request, err error) {
+	logger := klog.FromContext(r.Context())
+	var rejected *admission.Error
+	if !errors.As(err, &rejected) {
+		logger.Error(err, "Error evaluating the admission plugins")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.AdmissionFailed, "reason", err.Error()))
+		return
+	}
+	logger.Info("Change rejected by the admission plugins", "violations", len(rejected.Violations))
+	resp := AdmissionError{
+		Details:    problem.New(w, http.StatusUnprocessableEntity, rejected.Error()),
+		Message:    rejected.Error(),
+		Violations: rejected.Violations,
+	}
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	if encErr := json.NewEncoder(w).Encode(resp); encErr != nil {
+		logger.Error(encErr, "Error encoding response")
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/rollouts"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+)
+
+// AlertsHandler is the handler for the alerts API, reporting the problems detected by the background controllers
+type AlertsHandler struct {
+	// Rollouts holds the alerts of the stuck deployment rollouts
+	Rollouts interface{ Alerts() []rollouts.Alert }
+}
+
+// ListRolloutAlerts handles the "/alerts/rollouts" endpoint for GET method.
+// It returns the deployments whose rollout exceeded its progress deadline or is stuck, optionally filtered by the
+// namespace query parameter.
+func (h *AlertsHandler) ListRolloutAlerts(w http.ResponseWriter, r *http.Request) {
+	alerts := h.Rollouts.Alerts()
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		alerts = slices.DeleteFunc(alerts, func(alert rollouts.Alert) bool { return alert.Namespace != namespace })
+	}
+	// With tenancy enabled, only the alerts in the caller's namespaces are returned
+	if scope, ok := tenancy.ScopeFrom(r.Context()); ok && !scope.All {
+		alerts = slices.DeleteFunc(alerts, func(alert rollouts.Alert) bool { return !scope.Allows(alert.Namespace) })
+	}
+
+	writeList(w, r, alerts, ListMetadata{})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/rollouts"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+)
+
+// staticAlerts returns a fixed list of rollout alerts
+type staticAlerts []rollouts.Alert
+
+func (a staticAlerts) Alerts() []rollouts.Alert {
+	return append([]rollouts.Alert{}, a...)
+}
+
+func TestAlertsHandler_ListRolloutAlerts(t *testing.T) {
+	since := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	foo := rollouts.Alert{Name: "foo", Namespace: "team-a", Reason: rollouts.ReasonProgressDeadlineExceeded,
+		Message: `ReplicaSet "foo-5d4f8c7b9" has timed out progressing.`, Since: since, Generation: 3}
+	bar := rollouts.Alert{Name: "bar", Namespace: "team-b", Reason: rollouts.ReasonStuck,
+		Message: "rollout made no progress for 30m0s: 1 of 3 replicas updated, 2 available", Since: since, Generation: 7}
+	h := &AlertsHandler{Rollouts: staticAlerts{foo, bar}}
+
+	tests := []struct {
+		name     string
+		url      string
+		scope    *tenancy.Scope
+		expected []rollouts.Alert
+	}{
+		{
+			name:     "all alerts",
+			url:      "/alerts/rollouts",
+			expected: []rollouts.Alert{foo, bar},
+		},
+		{
+			name:     "filtered by namespace",
+			url:      "/alerts/rollouts?namespace=team-b",
+			expected: []rollouts.Alert{bar},
+		},
+		{
+			name:     "filtered to the tenant's namespaces",
+			url:      "/alerts/rollouts",
+			scope:    &tenancy.Scope{Namespaces: map[string]bool{"team-a": true}},
+			expected: []rollouts.Alert{foo},
+		},
+		{
+			name:     "namespace outside of the tenant's namespaces",
+			url:      "/alerts/rollouts?namespace=team-b",
+			scope:    &tenancy.Scope{Namespaces: map[string]bool{"team-a": true}},
+			expected: []rollouts.Alert{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			r := newHttpTestRequest("GET", tt.url, nil)
+			if tt.scope != nil {
+				r = r.WithContext(tenancy.WithScope(r.Context(), *tt.scope))
+			}
+			h.ListRolloutAlerts(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("ListRolloutAlerts() status code = %v, want %v", w.Code, http.StatusOK)
+			}
+			assertMatchesSchema(t, schema.RolloutAlerts, w)
+
+			var response []rollouts.Alert
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(response, tt.expected) {
+				t.Errorf("ListRolloutAlerts() = %+v, want %+v", response, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"k8s.io/klog/v2"
+)
+
+// APIKeyRequest is the request object for the creation of API keys
+type APIKeyRequest struct {
+	Name string `json:"name"`
+	// Scopes restrict the requests of the key, out of read and write
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// APIKeyResponse is the response object of the creation of API keys, the only one holding their secret
+type APIKeyResponse struct {
+	auth.APIKey
+	// Key is the secret of the API key, which isn't kept, so it can't be retrieved later
+	Key string `json:"key"`
+}
+
+// APIKeysHandler is the handler for the self-service management of the API keys. Every identity manages its own keys,
+// while the admins manage the keys of all the identities.
+type APIKeysHandler struct {
+	Keys   *auth.APIKeys
+	Admins []string
+}
+
+// owner returns the owner of the keys the request may manage, which is empty for the admins, and whether the request
+// may manage keys at all. The requests authenticated with an API key may not, so that a leaked key can't be used to
+// mint more.
+func (h *APIKeysHandler) owner(w http.ResponseWriter, r *http.Request) (string, bool) {
+	logger := klog.FromContext(r.Context())
+	identity := auth.Identity(r)
+	principal, _ := auth.PrincipalFrom(r.Context())
+	if identity == "" || principal.Method == auth.APIKeyAuthenticator {
+		logger.Info("Forbidden, API keys may not be managed by this client", "method", principal.Method)
+		writeError(w, logger, http.StatusForbidden, messages.New(messages.APIKeyManagementForbidden))
+		return "", false
+	}
+	if slices.Contains(h.Admins, identity) {
+		return "", true
+	}
+	return identity, true
+}
+
+// CreateAPIKey handles the "/admin/apikeys" endpoint for POST method, creating an API key on behalf of the client.
+// The secret of the key is only returned by this response.
+func (h *APIKeysHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+	if _, ok := h.owner(w, r); !ok {
+		return
+	}
+
+	var req APIKeyRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		writeBadRequest(w, r, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
+		return
+	}
+	principal, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		principal = auth.Principal{Name: auth.Identity(r)}
+	}
+	key, secret, err := h.Keys.Create(r.Context(), principal, req.Name, req.Scopes, req.ExpiresAt)
+	if errors.Is(err, auth.ErrInvalidAPIKey) {
+		writeBadRequest(w, r, messages.New(messages.ValidationFailed, "reason", err.Error()))
+		return
+	}
+	if err != nil {
+		logger.Error(err, "Error creating API key")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.APIKeyCreateFailed))
+		return
+	}
+	logger.Info("Created API key", "apiKey", key.ID, "scopes", key.Scopes)
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(APIKeyResponse{APIKey: key, Key: secret}); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// ListAPIKeys handles the "/admin/apikeys" endpoint for GET method, returning the API keys of the client, or of all
+// the identities for the admins, without their secret
+func (h *APIKeysHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+	owner, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.Keys.List(r.Context(), owner)
+	if err != nil {
+		logger.Error(err, "Error listing API keys")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.APIKeysListFailed))
+		return
+	}
+	writeList(w, r, keys, ListMetadata{})
+}
+
+// GetAPIKey handles the "/admin/apikeys/{id}" endpoint for GET method, without the secret of the key
+func (h *APIKeysHandler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logger := klog.FromContext(r.Context()).WithValues("apiKey", id)
+	owner, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := h.Keys.Get(r.Context(), owner, id)
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.APIKeyNotFound, "id", id))
+		return
+	}
+	if err != nil {
+		logger.Error(err, "Error getting API key")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.APIKeyGetFailed, "id", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(key); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// DeleteAPIKey handles the "/admin/apikeys/{id}" endpoint for DELETE method, revoking the key right away
+func (h *APIKeysHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logger := klog.FromContext(r.Context()).WithValues("apiKey", id)
+	owner, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Keys.Delete(r.Context(), owner, id)
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.APIKeyNotFound, "id", id))
+		return
+	}
+	if err != nil {
+		logger.Error(err, "Error deleting API key")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.APIKeyDeleteFailed, "id", id))
+		return
+	}
+	logger.Info("Deleted API key")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+)
+
+func TestAPIKeysHandler(t *testing.T) {
+	h := &APIKeysHandler{Keys: auth.NewAPIKeys(store.NewMemory()), Admins: []string{"admin"}}
+
+	// Alice creates a key, whose secret is only returned on creation
+	w := newResponseRecorder()
+	h.CreateAPIKey(w, withIdentity(newHttpTestRequest("POST", "/admin/apikeys", strings.NewReader(`{"name": "ci", "scopes": ["read"]}`)), "alice"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateAPIKey() status code = %v, want %v: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	assertMatchesSchema(t, schema.APIKey, w)
+	var created APIKeyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Key == "" || created.Owner != "alice" {
+		t.Fatalf("CreateAPIKey() = %+v, want a key owned by alice", created)
+	}
+
+	w = newResponseRecorder()
+	h.CreateAPIKey(w, withIdentity(newHttpTestRequest("POST", "/admin/apikeys", strings.NewReader(`{"name": "ci", "scopes": ["admin"]}`)), "alice"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("CreateAPIKey() with an unknown scope status code = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+
+	tests := []struct {
+		name           string
+		identity       string
+		method         string
+		id             string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "owner gets", identity: "alice", method: "GET", id: created.ID, expectedStatus: http.StatusOK},
+		{name: "other identity gets", identity: "bob", method: "GET", id: created.ID, expectedStatus: http.StatusNotFound, expectedBody: errorBody(http.StatusNotFound, messages.APIKeyNotFound, "id", created.ID)},
+		{name: "admin gets", identity: "admin", method: "GET", id: created.ID, expectedStatus: http.StatusOK},
+		{name: "owner lists", identity: "alice", method: "GET", expectedStatus: http.StatusOK},
+		{name: "other identity lists", identity: "bob", method: "GET", expectedStatus: http.StatusOK, expectedBody: "[]\n"},
+		{name: "unauthenticated", method: "GET", expectedStatus: http.StatusForbidden, expectedBody: errorBody(http.StatusForbidden, messages.APIKeyManagementForbidden)},
+		{name: "other identity deletes", identity: "bob", method: "DELETE", id: created.ID, expectedStatus: http.StatusNotFound},
+		{name: "owner deletes", identity: "alice", method: "DELETE", id: created.ID, expectedStatus: http.StatusNoContent},
+		{name: "owner gets deleted", identity: "alice", method: "GET", id: created.ID, expectedStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newHttpTestRequest(tt.method, "/admin/apikeys/"+tt.id, nil)
+			if tt.identity != "" {
+				r = withIdentity(r, tt.identity)
+			}
+			r.SetPathValue("id", tt.id)
+			w := newResponseRecorder()
+			name := schema.APIKey
+			switch {
+			case tt.method == "DELETE":
+				h.DeleteAPIKey(w, r)
+			case tt.id == "":
+				name = schema.APIKeysResponse
+				h.ListAPIKeys(w, r)
+			default:
+				h.GetAPIKey(w, r)
+			}
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("status code = %v, want %v: %s", w.Code, tt.expectedStatus, w.Body.String())
+			}
+			assertMatchesSchema(t, name, w)
+			if strings.Contains(w.Body.String(), created.Key) {
+				t.Errorf("response body = %v, want no secret", w.Body.String())
+			}
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("response body = %v, want %v", w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+
+	// The requests authenticated with an API key may not manage keys
+	r := withIdentity(newHttpTestRequest("GET", "/admin/apikeys", nil), "alice")
+	r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{Name: "alice", Method: auth.APIKeyAuthenticator}))
+	w = newResponseRecorder()
+	h.ListAPIKeys(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("ListAPIKeys() with an API key status code = %v, want %v", w.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/admission"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiwarnings"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/events"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/notify"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Statuses of the objects applied by the apply API
+const (
+	ApplyStatusCreated    = "created"
+	ApplyStatusConfigured = "configured"
+	ApplyStatusUnchanged  = "unchanged"
+	ApplyStatusFailed     = "failed"
+)
+
+// eventActions are the actions of the events recorded on the created and configured objects
+var eventActions = map[string]string{ApplyStatusCreated: "Created", ApplyStatusConfigured: "Configured"}
+
+// maxFieldManagerLength is the maximum length of a field manager accepted by the API server
+const maxFieldManagerLength = 128
+
+// ApplyResult is the result of applying a single object
+type ApplyResult struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	// Warnings are the warnings of the admission plugins about the object
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ApplyHandler is the handler for the apply API
+type ApplyHandler struct {
+	client.Client
+	// LiveReader reads the objects directly from the API server, to report whether they were created or changed
+	LiveReader client.Reader
+	// FieldManager is the field manager of the applies, unless the request overrides it with ?fieldManager=.
+	// Defaults to DefaultFieldManager when empty.
+	FieldManager string
+	// AllowedKinds are the only kinds which may be applied
+	AllowedKinds []schema.GroupVersionKind
+	// Admission runs the objects through the admission plugins before any of them is applied. Every object is admitted
+	// when nil.
+	Admission *admission.Chain
+	// Notifier notifies the created and configured objects to chat channels. Changes aren't notified when nil.
+	Notifier *notify.Notifier
+	// Events records the created and configured objects as events on them. Changes aren't recorded when nil.
+	Events *events.Recorder
+}
+
+// Apply handles the "/apply" and "/namespaces/{namespace}/apply" endpoints for POST method.
+// The request body holds one or more manifests, either as a multi-document YAML stream, a stream of JSON objects, or a
+// List. Every object is validated before any of them is applied: objects of kinds which aren't allowed, or outside of
+// the caller's namespaces, get the whole request rejected, and so do the objects rejected by the admission plugins,
+// with a 422 Unprocessable Entity listing every violation. The objects are then server-side applied in order, and the
+// result of each one is returned, with a 207 Multi-Status if any of them failed.
+func (h *ApplyHandler) Apply(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+
+	fieldManager, force, err := h.applyOptions(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	objects, err := decodeManifests(r.Body)
+	if err != nil {
+		writeBadRequest(w, r, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
+		return
+	}
+	if len(objects) == 0 {
+		writeBadRequest(w, r, messages.New(messages.InvalidRequestBody, "reason", "no objects found"))
+		return
+	}
+	for i, obj := range objects {
+		if err := h.validateObject(r, obj); err != nil {
+			if errors.Is(err, errNamespaceForbidden) {
+				logger.Info("Forbidden, object is outside of the tenant's namespaces", "object", klog.KObj(obj), "kind", obj.GetKind())
+				writeError(w, logger, http.StatusForbidden, messages.New(messages.ObjectOutsideTenantNamespace, "index", strconv.Itoa(i), "reason", err.Error()))
+				return
+			}
+			writeBadRequest(w, r, messages.New(messages.ValidationFailed, "reason", fmt.Sprintf("object %d: %v", i, err)))
+			return
+		}
+	}
+	// The live objects are read before any of them is applied, for the admission plugins to evaluate the changes
+	existing := make([]liveObject, len(objects))
+	warnings := make([][]string, len(objects))
+	var violations []admission.Violation
+	for i, obj := range objects {
+		existing[i] = h.getLiveObject(r, obj)
+		if existing[i].err != nil {
+			// The object is reported as failed by applyObject
+			continue
+		}
+		// Mutations of the plugins are made to the object in place
+		req := &admission.Request{Object: obj, OldObject: existing[i].obj, Identity: auth.Identity(r)}
+		err := h.Admission.Admit(r.Context(), req)
+		var rejected *admission.Error
+		if errors.As(err, &rejected) {
+			violations = append(violations, rejected.Violations...)
+			continue
+		}
+		if err != nil {
+			writeAdmissionError(w, r, err)
+			return
+		}
+		warnings[i] = req.Warnings
+	}
+	if len(violations) > 0 {
+		writeAdmissionError(w, r, &admission.Error{Violations: violations})
+		return
+	}
+
+	code := http.StatusOK
+	results := make([]ApplyResult, 0, len(objects))
+	for i, obj := range objects {
+		// The warnings of the API server are attributed to the object whose apply returned them
+		collected := len(apiwarnings.Collected(r.Context()))
+		result := h.applyObject(r, obj, existing[i], fieldManager, force)
+		if result.Status == ApplyStatusFailed {
+			code = http.StatusMultiStatus
+		} else {
+			result.Warnings = append(warnings[i], apiwarnings.Collected(r.Context())[collected:]...)
+			addWarnings(w, warnings[i])
+		}
+		if result.Status == ApplyStatusCreated || result.Status == ApplyStatusConfigured {
+			h.Events.Record(obj, approvals.OperationApplyManifests, eventActions[result.Status], auth.Identity(r), "", "")
+			h.Notifier.Notify(r.Context(), notify.Change{
+				Identity:  auth.Identity(r),
+				Operation: approvals.OperationApplyManifests,
+				Action:    result.Status,
+				Kind:      result.Kind,
+				Namespace: result.Namespace,
+				Name:      result.Name,
+			})
+		}
+		results = append(results, result)
+	}
+
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// applyOptions returns the field manager and force options of the request
+func (h *ApplyHandler) applyOptions(r *http.Request) (string, bool, error) {
+	query := r.URL.Query()
+
+	fieldManager := h.FieldManager
+	if fieldManager == "" {
+		fieldManager = DefaultFieldManager
+	}
+	if value := query.Get("fieldManager"); value != "" {
+		if len(value) > maxFieldManagerLength {
+			return "", false, fmt.Errorf("fieldManager query parameter must be at most %d characters long", maxFieldManagerLength)
+		}
+		fieldManager = value
+	}
+
+	force := false
+	if value := query.Get("force"); value != "" {
+		var err error
+		force, err = strconv.ParseBool(value)
+		if err != nil {
+			return "", false, messages.New(messages.InvalidBooleanParameter, "parameter", "force", "value", value)
+		}
+	}
+	return fieldManager, force, nil
+}
+
+// errNamespaceForbidden is returned when an object is outside of the caller's namespaces
+var errNamespaceForbidden = errors.New("namespace is outside of the tenant's namespaces")
+
+// validateObject checks that the object may be applied, and sets its namespace from the path when omitted
+func (h *ApplyHandler) validateObject(r *http.Request, obj *unstructured.Unstructured) error {
+	gvk := obj.GroupVersionKind()
+	if gvk.Kind == "" || gvk.Version == "" {
+		return fmt.Errorf("apiVersion and kind are required")
+	}
+	if !slices.Contains(h.AllowedKinds, gvk) {
+		return fmt.Errorf("kind %s is not allowed", formatKind(gvk))
+	}
+	if obj.GetName() == "" {
+		return fmt.Errorf("metadata.name is required")
+	}
+
+	namespaced, err := h.IsObjectNamespaced(obj)
+	if err != nil {
+		return fmt.Errorf("failed to get the scope of kind %s: %v", formatKind(gvk), err)
+	}
+	pathNamespace := r.PathValue("namespace")
+	switch {
+	case !namespaced && (obj.GetNamespace() != "" || pathNamespace != ""):
+		return fmt.Errorf("%s %s is cluster scoped and can't be applied in a namespace", gvk.Kind, obj.GetName())
+	case namespaced && pathNamespace != "" && obj.GetNamespace() == "":
+		obj.SetNamespace(pathNamespace)
+	case namespaced && pathNamespace != "" && obj.GetNamespace() != pathNamespace:
+		return fmt.Errorf("namespace %q of %s %s doesn't match namespace %s", obj.GetNamespace(), gvk.Kind, obj.GetName(), pathNamespace)
+	case namespaced && obj.GetNamespace() == "":
+		return fmt.Errorf("metadata.namespace of %s %s is required", gvk.Kind, obj.GetName())
+	}
+
+	// With tenancy enabled, objects may only be applied in the caller's namespaces, and cluster scoped objects may only
+	// be applied by tenants with access to all namespaces
+	if scope, ok := tenancy.ScopeFrom(r.Context()); ok && !scope.All {
+		if !namespaced || !scope.Allows(obj.GetNamespace()) {
+			return fmt.Errorf("%s %s: %w", gvk.Kind, obj.GetName(), errNamespaceForbidden)
+		}
+	}
+
+	// Exported manifests often carry managed fields, which can't be applied
+	obj.SetManagedFields(nil)
+	return nil
+}
+
+// liveObject is the live version of an object to apply, nil if it doesn't exist, or the error reading it
+type liveObject struct {
+	obj *unstructured.Unstructured
+	err error
+}
+
+// getLiveObject reads the live version of the object to apply
+func (h *ApplyHandler) getLiveObject(r *http.Request, obj *unstructured.Unstructured) liveObject {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	reader := h.LiveReader
+	if reader == nil {
+		reader = h.Client
+	}
+	err := reader.Get(r.Context(), client.ObjectKeyFromObject(obj), existing)
+	switch {
+	case err == nil:
+		return liveObject{obj: existing}
+	case apierrors.IsNotFound(err):
+		return liveObject{}
+	}
+	return liveObject{err: err}
+}
+
+// applyObject server-side applies a single object, and returns its result. The live version of the object is used to
+// report whether the apply created or changed it.
+func (h *ApplyHandler) applyObject(r *http.Request, obj *unstructured.Unstructured, existing liveObject, fieldManager string, force bool) ApplyResult {
+	gvk := obj.GroupVersionKind()
+	result := ApplyResult{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+	}
+	logger := klog.FromContext(r.Context()).WithValues("object", klog.KObj(obj), "kind", gvk.Kind)
+
+	if existing.err != nil {
+		logger.Error(existing.err, "Error getting object")
+		result.Status, result.Message = ApplyStatusFailed, existing.err.Error()
+		return result
+	}
+	previousVersion := ""
+	if existing.obj != nil {
+		previousVersion = existing.obj.GetResourceVersion()
+	}
+
+	opts := []client.PatchOption{client.FieldOwner(fieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	if err := h.Patch(r.Context(), obj, client.Apply, opts...); err != nil {
+		logger.Error(err, "Error applying object")
+		result.Status, result.Message = ApplyStatusFailed, err.Error()
+		return result
+	}
+
+	switch obj.GetResourceVersion() {
+	case previousVersion:
+		result.Status = ApplyStatusUnchanged
+	default:
+		result.Status = ApplyStatusConfigured
+		if previousVersion == "" {
+			result.Status = ApplyStatusCreated
+		}
+	}
+	logger.V(2).Info("Applied object", "status", result.Status, "fieldManager", fieldManager)
+	return result
+}
+
+// decodeManifests decodes the objects of a multi-document YAML stream or a stream of JSON objects.
+// Empty documents are skipped, and Lists are expanded to their items.
+func decodeManifests(body io.Reader) ([]*unstructured.Unstructured, error) {
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %v", err)
+	}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(raw), 4096)
+
+	var objects []*unstructured.Unstructured
+	for i := 0; ; i++ {
+		var document map[string]any
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("document %d: %v", i, err)
+		}
+		if len(document) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: document}
+		if !obj.IsList() {
+			objects = append(objects, obj)
+			continue
+		}
+		list, err := obj.ToList()
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i, err)
+		}
+		for j := range list.Items {
+			objects = append(objects, &list.Items[j])
+		}
+	}
+}
+
+// ParseKinds parses a list of kinds in the Kind.version.group format (e.g. Deployment.v1.apps, or ConfigMap.v1 for the
+// core group)
+func ParseKinds(kinds []string) ([]schema.GroupVersionKind, error) {
+	parsed := make([]schema.GroupVersionKind, 0, len(kinds))
+	for _, kind := range kinds {
+		parts := strings.SplitN(kind, ".", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid kind %q, must be in the Kind.version.group format", kind)
+		}
+		gvk := schema.GroupVersionKind{Kind: parts[0], Version: parts[1]}
+		if len(parts) == 3 {
+			gvk.Group = parts[2]
+		}
+		parsed = append(parsed, gvk)
+	}
+	return parsed, nil
+}
+
+// formatKind formats a kind in the Kind.version.group format, as accepted by ParseKinds
+func formatKind(gvk schema.GroupVersionKind) string {
+	if gvk.Group == "" {
+		return gvk.Kind + "." + gvk.Version
+	}
+	return gvk.Kind + "." + gvk.Version + "." + gvk.Group
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/admission"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/imagepolicy"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestParseKinds(t *testing.T) {
+	kinds, err := ParseKinds([]string{"Deployment.v1.apps", "ConfigMap.v1", "Certificate.v1.cert-manager.io"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []runtimeschema.GroupVersionKind{
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+		{Version: "v1", Kind: "ConfigMap"},
+		{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"},
+	}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("ParseKinds() = %v, want %v", kinds, expected)
+	}
+	if _, err := ParseKinds([]string{"Deployment"}); err == nil {
+		t.Errorf("ParseKinds() expected an error for a kind without a version")
+	}
+}
+
+// appliedPatch records the options of a server-side apply made through the fake client
+type appliedPatch struct {
+	name         string
+	fieldManager string
+	force        bool
+}
+
+// newApplyHandler returns an ApplyHandler backed by a fake client, which simulates server-side applies (unsupported
+// by the fake client) by bumping the resourceVersion of the applied objects, unless they are named "unchanged".
+// Objects named "invalid" are rejected.
+func newApplyHandler(t *testing.T, applied *[]appliedPatch) *ApplyHandler {
+	t.Helper()
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	_ = corev1.AddToScheme(testScheme)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithRESTMapper(mapper).WithObjects(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "unchanged", Namespace: "foo"}},
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			options := &client.PatchOptions{}
+			options.ApplyOptions(opts)
+			*applied = append(*applied, appliedPatch{name: obj.GetName(), fieldManager: options.FieldManager, force: options.Force != nil && *options.Force})
+
+			switch obj.GetName() {
+			case "invalid":
+				return apierrors.NewInvalid(runtimeschema.GroupKind{Group: "apps", Kind: "Deployment"}, "invalid", field.ErrorList{
+					field.Required(field.NewPath("spec", "selector"), ""),
+				})
+			case "unchanged":
+				obj.SetResourceVersion("999")
+			default:
+				obj.SetResourceVersion("1000")
+			}
+			return nil
+		},
+	}).Build()
+	return &ApplyHandler{
+		Client:     c,
+		LiveReader: c,
+		AllowedKinds: []runtimeschema.GroupVersionKind{
+			appsv1.SchemeGroupVersion.WithKind("Deployment"),
+			corev1.SchemeGroupVersion.WithKind("Namespace"),
+		},
+	}
+}
+
+func TestApplyHandler_Apply(t *testing.T) {
+	tests := []struct {
+		name            string
+		url             string
+		namespace       string
+		scope           *tenancy.Scope
+		body            string
+		expectedCode    int
+		expectedResults []ApplyResult
+		expectedError   string
+		expectedApplied []appliedPatch
+	}{
+		{
+			name: "multi-document YAML",
+			url:  "/apply?fieldManager=ci&force=true",
+			body: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: bar
+  namespace: foo
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: baz
+  namespace: foo
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: unchanged
+  namespace: foo
+`,
+			expectedCode: http.StatusOK,
+			expectedResults: []ApplyResult{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "bar", Namespace: "foo", Status: ApplyStatusConfigured},
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "baz", Namespace: "foo", Status: ApplyStatusCreated},
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "unchanged", Namespace: "foo", Status: ApplyStatusUnchanged},
+			},
+			expectedApplied: []appliedPatch{{"bar", "ci", true}, {"baz", "ci", true}, {"unchanged", "ci", true}},
+		},
+		{
+			name: "JSON list with a failing object",
+			url:  "/apply",
+			body: `{"apiVersion": "v1", "kind": "List", "items": [
+				{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "qux"}},
+				{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "invalid", "namespace": "foo"}}
+			]}`,
+			expectedCode: http.StatusMultiStatus,
+			expectedResults: []ApplyResult{
+				{APIVersion: "v1", Kind: "Namespace", Name: "qux", Status: ApplyStatusCreated},
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "invalid", Namespace: "foo", Status: ApplyStatusFailed,
+					Message: `Deployment.apps "invalid" is invalid: spec.selector: Required value`},
+			},
+			expectedApplied: []appliedPatch{{"qux", DefaultFieldManager, false}, {"invalid", DefaultFieldManager, false}},
+		},
+		{
+			name:          "kind not allowed",
+			url:           "/apply",
+			body:          "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: bar\n  namespace: foo\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: bar\n  namespace: foo\n",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Validation error: object 1: kind ConfigMap.v1 is not allowed",
+		},
+		{
+			name:          "missing namespace",
+			url:           "/apply",
+			body:          "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: bar\n",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Validation error: object 0: metadata.namespace of Deployment bar is required",
+		},
+		{
+			name:            "namespace of the path",
+			url:             "/namespaces/foo/apply",
+			namespace:       "foo",
+			body:            "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: bar\n",
+			expectedCode:    http.StatusOK,
+			expectedResults: []ApplyResult{{APIVersion: "apps/v1", Kind: "Deployment", Name: "bar", Namespace: "foo", Status: ApplyStatusConfigured}},
+			expectedApplied: []appliedPatch{{"bar", DefaultFieldManager, false}},
+		},
+		{
+			name:          "namespace mismatching the path",
+			url:           "/namespaces/foo/apply",
+			namespace:     "foo",
+			body:          "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: bar\n  namespace: other\n",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `Validation error: object 0: namespace "other" of Deployment bar doesn't match namespace foo`,
+		},
+		{
+			name:          "cluster scoped object in a namespace",
+			url:           "/namespaces/foo/apply",
+			namespace:     "foo",
+			body:          "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: qux\n",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Validation error: object 0: Namespace qux is cluster scoped and can't be applied in a namespace",
+		},
+		{
+			name:          "namespace outside of the tenant's namespaces",
+			url:           "/apply",
+			scope:         &tenancy.Scope{Namespaces: map[string]bool{"team-a": true}},
+			body:          "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: bar\n  namespace: foo\n",
+			expectedCode:  http.StatusForbidden,
+			expectedError: "Forbidden, object 0: Deployment bar: namespace is outside of the tenant's namespaces",
+		},
+		{
+			name:          "invalid force",
+			url:           "/apply?force=maybe",
+			body:          "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: bar\n  namespace: foo\n",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `invalid value "maybe" for the force query parameter, must be a boolean`,
+		},
+		{
+			name:          "empty body",
+			url:           "/apply",
+			body:          "---\n",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Error parsing request body: no objects found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var applied []appliedPatch
+			h := newApplyHandler(t, &applied)
+
+			w := newResponseRecorder()
+			r := newHttpTestRequest("POST", tt.url, strings.NewReader(tt.body))
+			if tt.namespace != "" {
+				r.SetPathValue("namespace", tt.namespace)
+			}
+			if tt.scope != nil {
+				r = r.WithContext(tenancy.WithScope(r.Context(), *tt.scope))
+			}
+			h.Apply(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Apply() status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			assertMatchesSchema(t, schema.ApplyResponse, w)
+
+			if !reflect.DeepEqual(applied, tt.expectedApplied) {
+				t.Errorf("Apply() applied %+v, want %+v", applied, tt.expectedApplied)
+			}
+			if tt.expectedError != "" {
+				var apiError APIError
+				if err := json.NewDecoder(w.Body).Decode(&apiError); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if apiError.Message != tt.expectedError {
+					t.Errorf("Apply() error = %q, want %q", apiError.Message, tt.expectedError)
+				}
+				return
+			}
+			var results []ApplyResult
+			if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(results, tt.expectedResults) {
+				t.Errorf("Apply() results = %+v, want %+v", results, tt.expectedResults)
+			}
+		})
+	}
+}
+
+func TestApplyHandler_Apply_Admission(t *testing.T) {
+	policy, err := imagepolicy.New(imagepolicy.Config{AllowedRegistries: []string{"registry.example.com"}, DenyLatest: true})
+	if err != nil {
+		t.Fatalf("failed to create image policy: %v", err)
+	}
+	chain, err := admission.NewChain([]string{admission.ImagePolicyPlugin}, admission.Options{ImagePolicy: policy})
+	if err != nil {
+		t.Fatalf("failed to create admission chain: %v", err)
+	}
+	tests := []struct {
+		name               string
+		body               string
+		expectedCode       int
+		expectedViolations []admission.Violation
+	}{
+		{
+			name:         "admitted",
+			body:         "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: bar\n  namespace: foo\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n        image: registry.example.com/app:1.2.3\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "rejected",
+			body:         "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: bar\n  namespace: foo\nspec:\n  template:\n    spec:\n      initContainers:\n      - name: migrate\n        image: registry.example.com/migrate\n      containers:\n      - name: app\n        image: nginx:1.27\n",
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedViolations: []admission.Violation{
+				{Plugin: admission.ImagePolicyPlugin, Object: "Deployment foo/bar", Message: "image registry.example.com/migrate of container migrate: the latest tag is not allowed, images must be pinned to a version"},
+				{Plugin: admission.ImagePolicyPlugin, Object: "Deployment foo/bar", Message: "image nginx:1.27 of container app: registry docker.io is not in the allowed registries registry.example.com"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var applied []appliedPatch
+			h := newApplyHandler(t, &applied)
+			h.Admission = chain
+
+			w := newResponseRecorder()
+			h.Apply(w, newHttpTestRequest("POST", "/apply", strings.NewReader(tt.body)))
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Apply() status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			assertMatchesSchema(t, schema.ApplyResponse, w)
+			if tt.expectedViolations == nil {
+				return
+			}
+
+			if len(applied) != 0 {
+				t.Errorf("Apply() applied %+v, want nothing", applied)
+			}
+			var resp AdmissionError
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(resp.Violations, tt.expectedViolations) {
+				t.Errorf("Apply() violations = %+v, want %+v", resp.Violations, tt.expectedViolations)
+			}
+		})
+	}
+}
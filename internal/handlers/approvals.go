@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	"k8s.io/klog/v2"
+)
+
+// ApprovalsHandler is the handler for the approvals API, listing the changes held for a second approval and
+// approving them
+type ApprovalsHandler struct {
+	Approvals *approvals.Manager
+}
+
+// ListApprovals handles the "/approvals" endpoint for GET method
+func (h *ApprovalsHandler) ListApprovals(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+
+	var scope *tenancy.Scope
+	if s, ok := tenancy.ScopeFrom(r.Context()); ok {
+		scope = &s
+	}
+	list, err := h.Approvals.List(r.Context(), scope)
+	if err != nil {
+		logger.Error(err, "Error listing approvals")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.ApprovalsListFailed))
+		return
+	}
+
+	writeList(w, r, list, ListMetadata{})
+}
+
+// GetApproval handles the "/approvals/{id}" endpoint for GET method
+func (h *ApprovalsHandler) GetApproval(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logger := klog.FromContext(r.Context()).WithValues("approval", id)
+
+	approval, found, err := h.Approvals.Get(r.Context(), id)
+	if err != nil {
+		logger.Error(err, "Error getting approval")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.ApprovalGetFailed))
+		return
+	}
+	// Approvals outside of the scope of the client are reported as not found, like the deployments
+	if scope, ok := tenancy.ScopeFrom(r.Context()); found && ok && !scope.All && !scope.Allows(approval.Namespace) {
+		found = false
+	}
+	if !found {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.ApprovalNotFound, "id", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(approval); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// Approve handles the "/approvals/{id}/approve" endpoint for POST method. The approved change is executed right away,
+// and its response is returned in the result of the approval.
+func (h *ApprovalsHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logger := klog.FromContext(r.Context()).WithValues("approval", id)
+
+	approval, err := h.Approvals.Approve(r, id)
+	switch {
+	case errors.Is(err, approvals.ErrNotFound):
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.ApprovalNotFound, "id", id))
+		return
+	case errors.Is(err, approvals.ErrForbidden):
+		writeError(w, logger, http.StatusForbidden, messages.New(messages.ApprovalSelfApproved))
+		return
+	case errors.Is(err, approvals.ErrNotPending):
+		writeError(w, logger, http.StatusConflict, messages.New(messages.ApprovalNotPending, "id", id))
+		return
+	case errors.Is(err, approvals.ErrExpired):
+		writeError(w, logger, http.StatusGone, messages.New(messages.ApprovalExpired, "id", id))
+		return
+	case err != nil:
+		logger.Error(err, "Error approving change")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.ApprovalFailed))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(approval); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// writeError returns the given status with the message of the catalog
+func writeError(w http.ResponseWriter, logger klog.Logger, status int, m messages.Message) {
+	resp := NewAPIError(w, status, m)
+	w.WriteHeader(status)
+	if encErr := json.NewEncoder(w).Encode(resp); encErr != nil {
+		logger.Error(encErr, "Error encoding response")
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// withIdentity sets the client certificate of the request to one with the given Common Name
+func withIdentity(r *http.Request, identity string) *http.Request {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: identity}}
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return r
+}
+
+func TestApprovalsHandler(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+	}).Build()
+	manager, err := approvals.New(approvals.Config{
+		Rules: []approvals.RuleConfig{{Operation: approvals.OperationSetDeploymentReplicas, ReplicasAbove: ptr.To(int32(10))}},
+	}, store.NewMemory())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	dh := &DeploymentsHandler{Client: c}
+	h := &ApprovalsHandler{Approvals: manager}
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /deployments/{namespace}/{deployment}/replicas", manager.Middleware(approvals.OperationSetDeploymentReplicas, dh.SetDeploymentReplicas))
+	mux.HandleFunc("GET /approvals", h.ListApprovals)
+	mux.HandleFunc("GET /approvals/{id}", h.GetApproval)
+	mux.HandleFunc("POST /approvals/{id}/approve", h.Approve)
+	replicas := func() int32 {
+		d := &appsv1.Deployment{}
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: "foo", Name: "bar"}, d); err != nil {
+			t.Fatalf("failed to get deployment: %v", err)
+		}
+		return *d.Spec.Replicas
+	}
+
+	// Scaling below the threshold doesn't require an approval
+	w := newResponseRecorder()
+	mux.ServeHTTP(w, withIdentity(newHttpTestRequest("PUT", "/deployments/foo/bar/replicas", strings.NewReader(`{"replicas":5}`)), "alice"))
+	if w.Code != http.StatusOK || replicas() != 5 {
+		t.Fatalf("SetDeploymentReplicas() status code = %v, replicas = %v, want %v and 5", w.Code, replicas(), http.StatusOK)
+	}
+
+	w = newResponseRecorder()
+	mux.ServeHTTP(w, withIdentity(newHttpTestRequest("PUT", "/deployments/foo/bar/replicas", strings.NewReader(`{"replicas":20}`)), "alice"))
+	if w.Code != http.StatusAccepted || replicas() != 5 {
+		t.Fatalf("SetDeploymentReplicas() status code = %v, replicas = %v, want %v and 5", w.Code, replicas(), http.StatusAccepted)
+	}
+	if err := schema.Validate(schema.Approval, w.Body.Bytes()); err != nil {
+		t.Errorf("response body doesn't match the %s schema: %v", schema.Approval, err)
+	}
+	var pending approvals.Approval
+	if err := json.NewDecoder(w.Body).Decode(&pending); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	w = newResponseRecorder()
+	mux.ServeHTTP(w, newHttpTestRequest("GET", "/approvals", nil))
+	assertMatchesSchema(t, schema.ApprovalsResponse, w)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), pending.ID) {
+		t.Errorf("ListApprovals() = %v %v, want the pending approval", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name     string
+		id       string
+		identity string
+		expected int
+	}{
+		{"Test Approve Not Found", "missing", "bob", http.StatusNotFound},
+		{"Test Approve By Requester", pending.ID, "alice", http.StatusForbidden},
+		{"Test Approve", pending.ID, "bob", http.StatusOK},
+		{"Test Approve Twice", pending.ID, "carol", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			mux.ServeHTTP(w, withIdentity(newHttpTestRequest("POST", "/approvals/"+tt.id+"/approve", nil), tt.identity))
+			if w.Code != tt.expected {
+				t.Fatalf("Approve() status code = %v, want %v", w.Code, tt.expected)
+			}
+			assertMatchesSchema(t, schema.Approval, w)
+		})
+	}
+	if replicas() != 20 {
+		t.Errorf("expected the approved change to scale the deployment to 20 replicas, got %v", replicas())
+	}
+
+	w = newResponseRecorder()
+	mux.ServeHTTP(w, newHttpTestRequest("GET", "/approvals/"+pending.ID, nil))
+	assertMatchesSchema(t, schema.Approval, w)
+	var executed approvals.Approval
+	if err := json.NewDecoder(w.Body).Decode(&executed); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if executed.Status != approvals.StatusExecuted || executed.ApprovedBy != "bob" || executed.Result.Status != http.StatusOK {
+		t.Errorf("GetApproval() = %+v", executed)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apps"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AppsHandler is the handler for the applications API, grouping the objects sharing the same value of a label into
+// logical applications
+type AppsHandler struct {
+	// Client reads the deployments, from the cache
+	Client client.Reader
+	// LiveReader reads the statefulsets, services and HorizontalPodAutoscalers directly from the API server, since they
+	// aren't cached
+	LiveReader client.Reader
+	// Label is the label the applications are keyed on, e.g. app.kubernetes.io/name
+	Label string
+}
+
+// ListApps handles the "/apps" and "/namespaces/{namespace}/apps" endpoints for GET method.
+// It returns the applications, optionally filtered by the namespace query parameter, along with their deployments,
+// statefulsets, services and HorizontalPodAutoscalers.
+func (h *AppsHandler) ListApps(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	if namespace == "" {
+		namespace = r.URL.Query().Get("namespace")
+	}
+	if namespace != "" {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			writeBadRequest(w, r, messages.New(messages.InvalidNamespace, "namespace", namespace, "reason", strings.Join(errs, ", ")))
+			return
+		}
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace)
+
+	// Only the labeled objects are listed, except the autoscalers which may belong to the application of the workload
+	// they scale
+	objects, err := h.listObjects(r.Context(), namespace, client.HasLabels{h.Label})
+	if err != nil {
+		logger.Error(err, "Error listing the objects of the applications")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.ApplicationsListFailed))
+		return
+	}
+	list := apps.Group(h.Label, *objects)
+	// With tenancy enabled, only the applications in the caller's namespaces are returned
+	if scope, ok := tenancy.ScopeFrom(r.Context()); ok && !scope.All {
+		list = slices.DeleteFunc(list, func(app apps.App) bool { return !scope.Allows(app.Namespace) })
+	}
+
+	writeList(w, r, list, ListMetadata{})
+}
+
+// GetApp handles the "/apps/{namespace}/{app}" endpoint (and its namespace scoped equivalent) for GET method.
+// It returns the deployments, statefulsets, services and HorizontalPodAutoscalers of the application, or a 404 when
+// no object is labeled with it.
+func (h *AppsHandler) GetApp(w http.ResponseWriter, r *http.Request) {
+	namespace, name := r.PathValue("namespace"), r.PathValue("app")
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		writeBadRequest(w, r, messages.New(messages.InvalidNamespace, "namespace", namespace, "reason", strings.Join(errs, ", ")))
+		return
+	}
+	if errs := validation.IsValidLabelValue(name); len(errs) > 0 || name == "" {
+		writeBadRequest(w, r, messages.New(messages.InvalidApplicationName, "name", name, "reason", strings.Join(errs, ", ")))
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "app", name)
+
+	objects, err := h.listObjects(r.Context(), namespace, client.MatchingLabels{h.Label: name})
+	if err != nil {
+		logger.Error(err, "Error listing the objects of the application")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.ApplicationGetFailed, "namespace", namespace, "name", name))
+		return
+	}
+	list := apps.Group(h.Label, *objects)
+	index := slices.IndexFunc(list, func(app apps.App) bool { return app.Name == name })
+	if index < 0 {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.ApplicationNotFound, "namespace", namespace, "name", name))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(list[index]); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// listObjects lists the objects of the applications in the namespace (or all namespaces when empty), the workloads
+// and services matching the given labels, along with all the HorizontalPodAutoscalers
+func (h *AppsHandler) listObjects(ctx context.Context, namespace string, labels client.ListOption) (*apps.Objects, error) {
+	opts := []client.ListOption{labels}
+	hpaOpts := []client.ListOption{}
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+		hpaOpts = append(hpaOpts, client.InNamespace(namespace))
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := h.Client.List(ctx, deployments, opts...); err != nil {
+		return nil, fmt.Errorf("error listing deployments: %w", err)
+	}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := h.LiveReader.List(ctx, statefulSets, opts...); err != nil {
+		return nil, fmt.Errorf("error listing statefulsets: %w", err)
+	}
+	services := &corev1.ServiceList{}
+	if err := h.LiveReader.List(ctx, services, opts...); err != nil {
+		return nil, fmt.Errorf("error listing services: %w", err)
+	}
+	autoscalers := &autoscalingv2.HorizontalPodAutoscalerList{}
+	if err := h.LiveReader.List(ctx, autoscalers, hpaOpts...); err != nil {
+		return nil, fmt.Errorf("error listing HorizontalPodAutoscalers: %w", err)
+	}
+	return &apps.Objects{Deployments: deployments.Items, StatefulSets: statefulSets.Items, Services: services.Items, Autoscalers: autoscalers.Items}, nil
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apps"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAppsHandler(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	_ = corev1.AddToScheme(testScheme)
+	_ = autoscalingv2.AddToScheme(testScheme)
+	meta := func(namespace, name, app string) metav1.ObjectMeta {
+		m := metav1.ObjectMeta{Namespace: namespace, Name: name}
+		if app != "" {
+			m.Labels = map[string]string{apps.DefaultLabel: app}
+		}
+		return m
+	}
+	objects := []client.Object{
+		&appsv1.Deployment{ObjectMeta: meta("team-a", "shop-web", "shop"), Spec: appsv1.DeploymentSpec{Replicas: ptr.To[int32](2)}, Status: appsv1.DeploymentStatus{ReadyReplicas: 2}},
+		&appsv1.Deployment{ObjectMeta: meta("team-b", "blog", "blog")},
+		&appsv1.Deployment{ObjectMeta: meta("team-b", "debug", "")},
+		&appsv1.StatefulSet{ObjectMeta: meta("team-a", "shop-db", "shop"), Spec: appsv1.StatefulSetSpec{Replicas: ptr.To[int32](1)}},
+		&corev1.Service{ObjectMeta: meta("team-a", "shop", "shop"), Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}},
+		&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: meta("team-a", "shop-web", ""), Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "shop-web"}, MaxReplicas: 5}},
+	}
+	shop := "{\"name\":\"shop\",\"namespace\":\"team-a\"," +
+		"\"deployments\":[{\"name\":\"shop-web\",\"replicas\":2,\"readyReplicas\":2}]," +
+		"\"statefulSets\":[{\"name\":\"shop-db\",\"replicas\":1,\"readyReplicas\":0}]," +
+		"\"services\":[{\"name\":\"shop\",\"type\":\"ClusterIP\"}]," +
+		"\"autoscalers\":[{\"name\":\"shop-web\",\"target\":\"Deployment/shop-web\",\"minReplicas\":1,\"maxReplicas\":5,\"currentReplicas\":0}]}"
+	blog := "{\"name\":\"blog\",\"namespace\":\"team-b\",\"deployments\":[{\"name\":\"blog\",\"replicas\":1,\"readyReplicas\":0}],\"statefulSets\":[],\"services\":[],\"autoscalers\":[]}"
+
+	tests := []struct {
+		name             string
+		pattern          string
+		url              string
+		get              bool
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{"Test List Apps", "GET /apps", "/apps", false, http.StatusOK, "[" + shop + "," + blog + "]\n"},
+		{"Test List Apps In Namespace", "GET /apps", "/apps?namespace=team-b", false, http.StatusOK, "[" + blog + "]\n"},
+		{"Test List Apps Namespace Scoped", "GET /namespaces/{namespace}/apps", "/namespaces/team-a/apps", false, http.StatusOK, "[" + shop + "]\n"},
+		{"Test Get App", "GET /apps/{namespace}/{app}", "/apps/team-a/shop", true, http.StatusOK, shop + "\n"},
+		{"Test App Not Found", "GET /apps/{namespace}/{app}", "/apps/team-b/shop", true, http.StatusNotFound, errorBody(http.StatusNotFound, messages.ApplicationNotFound, "namespace", "team-b", "name", "shop")},
+		{"Test Invalid App Name", "GET /apps/{namespace}/{app}", "/apps/team-a/shop%20web", true, http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.InvalidApplicationName, "name", "shop web", "reason", "a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).Build()
+			h := &AppsHandler{Client: c, LiveReader: c, Label: apps.DefaultLabel}
+			mux := http.NewServeMux()
+			handler, schemaName := h.ListApps, schema.AppsResponse
+			if tt.get {
+				handler, schemaName = h.GetApp, schema.App
+			}
+			mux.HandleFunc(tt.pattern, handler)
+
+			w := newResponseRecorder()
+			mux.ServeHTTP(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if w.Body.String() != tt.expectedResponse {
+				t.Errorf("response = %v, want %v", w.Body.String(), tt.expectedResponse)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assertMatchesSchema(t, schemaName, w)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/audit"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"k8s.io/klog/v2"
+)
+
+// defaultAuditLimit is the number of audit events returned when the limit query parameter isn't set
+const defaultAuditLimit = 100
+
+// AuditHandler is an HTTP handler for the queries of the audit events kept in the state store
+type AuditHandler struct {
+	Events *audit.StoreSink
+}
+
+// ListAuditEvents handles the "/audit" endpoint for GET method, returning the audit events newest first. The events may
+// be filtered by the identity, namespace, resource, verb and outcome query parameters, and by the time range of the
+// since and until query parameters. They're paginated by the limit and continue query parameters, the continue token
+// of the next page being returned in the metadata of the versioned API.
+func (h *AuditHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := audit.Query{
+		Identity:  query.Get("identity"),
+		Namespace: query.Get("namespace"),
+		Resource:  query.Get("resource"),
+		Verb:      strings.ToUpper(query.Get("verb")),
+		Outcome:   query.Get("outcome"),
+		Limit:     defaultAuditLimit,
+		Continue:  query.Get("continue"),
+	}
+	switch q.Outcome {
+	case "", audit.OutcomeSuccess, audit.OutcomeFailure:
+	default:
+		writeBadRequest(w, r, messages.New(messages.UnsupportedParameterValue, "parameter", "outcome", "value", q.Outcome, "allowed", audit.OutcomeSuccess+", "+audit.OutcomeFailure))
+		return
+	}
+	for _, bound := range []struct {
+		parameter string
+		time      *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if value := query.Get(bound.parameter); value != "" {
+			var err error
+			if *bound.time, err = time.Parse(time.RFC3339, value); err != nil {
+				writeBadRequest(w, r, messages.New(messages.InvalidTimeParameter, "parameter", bound.parameter, "value", value))
+				return
+			}
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(value); err != nil || q.Limit <= 0 {
+			writeBadRequest(w, r, messages.New(messages.InvalidPositiveIntParameter, "parameter", "limit", "value", value))
+			return
+		}
+	}
+
+	logger := klog.FromContext(r.Context())
+	events, next, err := h.Events.Query(r.Context(), q)
+	if errors.Is(err, audit.ErrInvalidContinue) {
+		writeBadRequest(w, r, messages.New(messages.InvalidContinueToken))
+		return
+	} else if err != nil {
+		logger.Error(err, "Error listing audit events")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.AuditEventsListFailed))
+		return
+	}
+	writeList(w, r, events, ListMetadata{Continue: next})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/audit"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+)
+
+func TestAuditHandler_ListAuditEvents(t *testing.T) {
+	events := audit.NewStoreSink(store.NewMemory(), time.Hour)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, event := range []audit.Event{
+		{Identity: "alice", Method: "PUT", Path: "/deployments/default/web/replicas", Namespace: "default", Name: "web", Status: 200, Duration: "12ms"},
+		{Identity: "bob", Method: "POST", Path: "/namespaces/default/pods/web-1/evict", Namespace: "default", Status: 429, Duration: "8ms"},
+		{Identity: "alice", Method: "POST", Path: "/apply", Status: 201, Duration: "40ms", RequestBody: json.RawMessage(`{"kind":"ConfigMap"}`)},
+	} {
+		event.Time = start.Add(time.Duration(i) * time.Minute)
+		if err := events.Write(context.Background(), &event); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	h := &AuditHandler{Events: events}
+
+	tests := []struct {
+		name          string
+		url           string
+		expectedCode  int
+		expectedPaths []string
+		expectedBody  string
+	}{
+		{name: "all events", url: "/audit", expectedCode: http.StatusOK, expectedPaths: []string{"/apply", "/namespaces/default/pods/web-1/evict", "/deployments/default/web/replicas"}},
+		{name: "filtered", url: "/audit?identity=alice&verb=put&resource=deployments&outcome=success", expectedCode: http.StatusOK, expectedPaths: []string{"/deployments/default/web/replicas"}},
+		{name: "time range", url: "/audit?since=2024-06-01T12:01:00Z&until=2024-06-01T12:02:00Z", expectedCode: http.StatusOK, expectedPaths: []string{"/namespaces/default/pods/web-1/evict"}},
+		{name: "none matching", url: "/audit?namespace=prod", expectedCode: http.StatusOK, expectedPaths: []string{}},
+		{
+			name: "invalid outcome", url: "/audit?outcome=denied", expectedCode: http.StatusBadRequest,
+			expectedBody: errorBody(http.StatusBadRequest, messages.UnsupportedParameterValue, "parameter", "outcome", "value", "denied", "allowed", "success, failure"),
+		},
+		{
+			name: "invalid since", url: "/audit?since=yesterday", expectedCode: http.StatusBadRequest,
+			expectedBody: errorBody(http.StatusBadRequest, messages.InvalidTimeParameter, "parameter", "since", "value", "yesterday"),
+		},
+		{
+			name: "invalid limit", url: "/audit?limit=0", expectedCode: http.StatusBadRequest,
+			expectedBody: errorBody(http.StatusBadRequest, messages.InvalidPositiveIntParameter, "parameter", "limit", "value", "0"),
+		},
+		{
+			name: "invalid continue", url: "/audit?continue=2", expectedCode: http.StatusBadRequest,
+			expectedBody: errorBody(http.StatusBadRequest, messages.InvalidContinueToken),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			h.ListAuditEvents(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedCode {
+				t.Fatalf("status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			assertMatchesSchema(t, schema.AuditEvents, w)
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("body = %v, want %v", w.Body.String(), tt.expectedBody)
+			}
+			if tt.expectedPaths == nil {
+				return
+			}
+			var got []audit.Event
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("error decoding response: %v", err)
+			}
+			if len(got) != len(tt.expectedPaths) {
+				t.Fatalf("events = %+v, want %v", got, tt.expectedPaths)
+			}
+			for i, event := range got {
+				if event.Path != tt.expectedPaths[i] {
+					t.Errorf("events[%d].path = %v, want %v", i, event.Path, tt.expectedPaths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAuditHandler_ListAuditEvents_Pagination(t *testing.T) {
+	events := audit.NewStoreSink(store.NewMemory(), time.Hour)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		event := audit.Event{Time: start.Add(time.Duration(i) * time.Minute), Identity: "alice", Method: "POST", Path: "/apply", Status: 201, Duration: "1ms"}
+		if err := events.Write(context.Background(), &event); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	h := &AuditHandler{Events: events}
+
+	var times []time.Time
+	url := "/v1/audit?limit=2"
+	for url != "" {
+		r := newHttpTestRequest("GET", url, nil)
+		r = r.WithContext(apiversion.WithVersion(r.Context(), apiversion.V1))
+		w := newResponseRecorder()
+		h.ListAuditEvents(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status code = %v, want %v: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		assertMatchesSchema(t, schema.AuditEvents, w)
+		var page List[audit.Event]
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatalf("error decoding response: %v", err)
+		}
+		for _, event := range page.Items {
+			times = append(times, event.Time)
+		}
+		url = ""
+		if page.Metadata.Continue != "" {
+			url = "/v1/audit?limit=2&continue=" + page.Metadata.Continue
+		}
+	}
+	if len(times) != 3 || !times[0].Equal(start.Add(2*time.Minute)) || !times[2].Equal(start) {
+		t.Errorf("paginated event times = %v, want the 3 events newest first", times)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/loadtest"
+)
+
+// benchmarkCluster is the cluster the endpoints are benchmarked against, large enough for the cost of listing and
+// encoding the deployments to dominate
+var benchmarkCluster = loadtest.Cluster{Deployments: 10000, Namespaces: 100}
+
+// newBenchmarkMux returns a mux serving the deployments routes of the handler, backed by a fake client with the
+// deployments of benchmarkCluster, and listing their summaries rather than the full objects if enabled
+func newBenchmarkMux(b *testing.B, summaries bool) *http.ServeMux {
+	b.Helper()
+	c, err := benchmarkCluster.NewClient()
+	if err != nil {
+		b.Fatalf("NewClient() error = %v", err)
+	}
+	h := &DeploymentsHandler{Client: c}
+	if summaries {
+		h.Summaries, err = benchmarkCluster.NewSummaries()
+		if err != nil {
+			b.Fatalf("NewSummaries() error = %v", err)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /deployments", h.ListDeployments)
+	mux.HandleFunc("GET /namespaces/{namespace}/deployments", h.ListDeployments)
+	mux.HandleFunc("GET /deployments/{namespace}/{deployment}/replicas", h.GetDeploymentReplicas)
+	mux.HandleFunc("PUT /deployments/{namespace}/{deployment}/replicas", h.SetDeploymentReplicas)
+	return mux
+}
+
+func BenchmarkListDeployments(b *testing.B) {
+	objects, summaries := newBenchmarkMux(b, false), newBenchmarkMux(b, true)
+	benchmarks := []struct {
+		name string
+		mux  *http.ServeMux
+		path string
+	}{
+		{"all namespaces", objects, "/deployments"},
+		{"all namespaces with meta", objects, "/deployments?meta=true"},
+		{"namespace", objects, "/deployments?namespace=" + loadtest.Namespace(0)},
+		{"namespace scoped route", objects, "/namespaces/" + loadtest.Namespace(0) + "/deployments"},
+		{"summaries of all namespaces", summaries, "/deployments"},
+		{"summaries of a namespace", summaries, "/deployments?namespace=" + loadtest.Namespace(0)},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			mux := bm.mux
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, bm.path, nil))
+				if w.Code != http.StatusOK {
+					b.Fatalf("GET %s status = %d, want %d", bm.path, w.Code, http.StatusOK)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetDeploymentReplicas(b *testing.B) {
+	mux := newBenchmarkMux(b, false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		namespace, name := benchmarkCluster.Deployment(i % benchmarkCluster.Deployments)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/deployments/"+namespace+"/"+name+"/replicas", nil))
+		if w.Code != http.StatusOK {
+			b.Fatalf("GET replicas status = %d, want %d", w.Code, http.StatusOK)
+		}
+	}
+}
+
+func BenchmarkSetDeploymentReplicas(b *testing.B) {
+	mux := newBenchmarkMux(b, false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		namespace, name := benchmarkCluster.Deployment(i % benchmarkCluster.Deployments)
+		body := fmt.Sprintf(`{"replicas": %d}`, i%10)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/deployments/"+namespace+"/"+name+"/replicas", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			b.Fatalf("PUT replicas status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+	}
+}
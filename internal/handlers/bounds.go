@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiwarnings"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/bounds"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeploymentBoundsResponse is the response object for the replica bounds API
+type DeploymentBoundsResponse struct {
+	DeploymentResponse
+	bounds.Bounds
+	// Warnings are the warnings of the admission plugins, only set on writes
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// GetDeploymentBounds handles the "/deployments/{namespace}/{deployment}/bounds" endpoint (and its namespace scoped
+// equivalent) for GET method
+func (h *DeploymentsHandler) GetDeploymentBounds(w http.ResponseWriter, r *http.Request) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	b, err := bounds.FromAnnotations(d.Annotations)
+	if err != nil {
+		// The annotations were edited by hand, which is reported rather than hidden
+		logger.Error(err, "Invalid replica bounds")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.InvalidReplicaBounds, "namespace", namespace, "name", deployment, "reason", err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(DeploymentBoundsResponse{
+		DeploymentResponse: DeploymentResponse{Name: deployment, Namespace: namespace},
+		Bounds:             b,
+	})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// SetDeploymentBounds handles the "/deployments/{namespace}/{deployment}/bounds" endpoint (and its namespace scoped
+// equivalent) for PUT method.
+// The bounds are declared in annotations of the deployment, and enforced by the replica bounds controller, which
+// scales the deployment back within its bounds whenever it's scaled outside of them.
+func (h *DeploymentsHandler) SetDeploymentBounds(w http.ResponseWriter, r *http.Request) {
+	var b bounds.Bounds
+	if err := decodeJSONBody(r, &b); err != nil {
+		writeBadRequest(w, r, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
+		return
+	}
+	if b.IsZero() {
+		writeBadRequest(w, r, messages.New(messages.ValidationFailed, "reason", "at least one of min or max is required"))
+		return
+	}
+	if err := b.Validate(); err != nil {
+		writeBadRequest(w, r, messages.New(messages.ValidationFailed, "reason", err.Error()))
+		return
+	}
+	h.patchBounds(w, r, b, http.StatusOK)
+}
+
+// DeleteDeploymentBounds handles the "/deployments/{namespace}/{deployment}/bounds" endpoint (and its namespace
+// scoped equivalent) for DELETE method, removing the bounds of the deployment
+func (h *DeploymentsHandler) DeleteDeploymentBounds(w http.ResponseWriter, r *http.Request) {
+	h.patchBounds(w, r, bounds.Bounds{}, http.StatusNoContent)
+}
+
+// patchBounds declares the given bounds in the annotations of the deployment, and responds with the given status code
+func (h *DeploymentsHandler) patchBounds(w http.ResponseWriter, r *http.Request, b bounds.Bounds, statusCode int) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	patch := client.MergeFrom(d.DeepCopy())
+	proposed := d.DeepCopy()
+	proposed.Annotations = bounds.SetAnnotations(proposed.Annotations, b)
+	warnings, admitted := h.admit(w, r, d, proposed)
+	if !admitted {
+		return
+	}
+	previous := boundsString(d.Annotations)
+	d = proposed
+	if err := h.Patch(r.Context(), d, patch); err != nil {
+		logger.Error(err, "Error patching deployment")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.DeploymentPatchFailed, "namespace", namespace, "name", deployment))
+		return
+	}
+	logger.Info("Updated replica bounds", "bounds", b.String())
+	if b.IsZero() {
+		h.recordDeploymentChange(r, approvals.OperationDeleteDeploymentBounds, "removed the replica bounds of", "Replica bounds removed", d, previous, "")
+	} else {
+		h.recordDeploymentChange(r, approvals.OperationSetDeploymentBounds, "set the replica bounds of", "Replica bounds set", d, previous, b.String())
+	}
+
+	w.WriteHeader(statusCode)
+	if statusCode == http.StatusNoContent {
+		return
+	}
+	err = json.NewEncoder(w).Encode(DeploymentBoundsResponse{
+		DeploymentResponse: DeploymentResponse{Name: deployment, Namespace: namespace},
+		Bounds:             b,
+		Warnings:           append(warnings, apiwarnings.Collected(r.Context())...),
+	})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
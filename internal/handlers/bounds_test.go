@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/bounds"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeploymentsHandler_DeploymentBounds(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+
+	tests := []struct {
+		name                string
+		annotations         map[string]string
+		method              string
+		url                 string
+		body                string
+		expectedCode        int
+		expectedResponse    string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:             "get",
+			annotations:      map[string]string{bounds.MinReplicasAnnotation: "2", bounds.MaxReplicasAnnotation: "10"},
+			method:           "GET",
+			url:              "/deployments/foo/bar/bounds",
+			expectedCode:     http.StatusOK,
+			expectedResponse: "{\"name\":\"bar\",\"namespace\":\"foo\",\"min\":2,\"max\":10}\n",
+		},
+		{
+			name:             "get without bounds",
+			method:           "GET",
+			url:              "/deployments/foo/bar/bounds",
+			expectedCode:     http.StatusOK,
+			expectedResponse: "{\"name\":\"bar\",\"namespace\":\"foo\"}\n",
+		},
+		{
+			name:             "get invalid bounds",
+			annotations:      map[string]string{bounds.MinReplicasAnnotation: "5", bounds.MaxReplicasAnnotation: "2"},
+			method:           "GET",
+			url:              "/deployments/foo/bar/bounds",
+			expectedCode:     http.StatusInternalServerError,
+			expectedResponse: errorBody(http.StatusInternalServerError, messages.InvalidReplicaBounds, "namespace", "foo", "name", "bar", "reason", "min (5) must be less than or equal to max (2)"),
+		},
+		{
+			name:             "get not found",
+			method:           "GET",
+			url:              "/deployments/foo/baz/bounds",
+			expectedCode:     http.StatusNotFound,
+			expectedResponse: errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "foo", "name", "baz"),
+		},
+		{
+			name:                "set",
+			annotations:         map[string]string{"team": "a", bounds.MaxReplicasAnnotation: "10"},
+			method:              "PUT",
+			url:                 "/deployments/foo/bar/bounds",
+			body:                "{\"min\":1}",
+			expectedCode:        http.StatusOK,
+			expectedResponse:    "{\"name\":\"bar\",\"namespace\":\"foo\",\"min\":1}\n",
+			expectedAnnotations: map[string]string{"team": "a", bounds.MinReplicasAnnotation: "1"},
+		},
+		{
+			name:             "set without bounds",
+			method:           "PUT",
+			url:              "/deployments/foo/bar/bounds",
+			body:             "{}",
+			expectedCode:     http.StatusBadRequest,
+			expectedResponse: errorBody(http.StatusBadRequest, messages.ValidationFailed, "reason", "at least one of min or max is required"),
+		},
+		{
+			name:             "set min greater than max",
+			method:           "PUT",
+			url:              "/deployments/foo/bar/bounds",
+			body:             "{\"min\":3,\"max\":1}",
+			expectedCode:     http.StatusBadRequest,
+			expectedResponse: errorBody(http.StatusBadRequest, messages.ValidationFailed, "reason", "min (3) must be less than or equal to max (1)"),
+		},
+		{
+			name:             "set unknown field",
+			method:           "PUT",
+			url:              "/deployments/foo/bar/bounds",
+			body:             "{\"minimum\":3}",
+			expectedCode:     http.StatusBadRequest,
+			expectedResponse: errorBody(http.StatusBadRequest, messages.InvalidRequestBody, "reason", "unknown field \"minimum\" at offset 1"),
+		},
+		{
+			name:                "delete",
+			annotations:         map[string]string{"team": "a", bounds.MinReplicasAnnotation: "2"},
+			method:              "DELETE",
+			url:                 "/deployments/foo/bar/bounds",
+			expectedCode:        http.StatusNoContent,
+			expectedAnnotations: map[string]string{"team": "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo", Annotations: tt.annotations},
+			}).Build()
+			h := &DeploymentsHandler{Client: c}
+
+			w := newResponseRecorder()
+			r := newHttpTestRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			switch tt.method {
+			case "GET":
+				h.GetDeploymentBounds(w, r)
+			case "PUT":
+				h.SetDeploymentBounds(w, r)
+			case "DELETE":
+				h.DeleteDeploymentBounds(w, r)
+			}
+			if w.Code != tt.expectedCode {
+				t.Fatalf("status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("response body = %v, want %v", rb, tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.BoundsResponse, w)
+
+			if tt.expectedAnnotations != nil {
+				d := &appsv1.Deployment{}
+				if err := c.Get(context.Background(), client.ObjectKey{Namespace: "foo", Name: "bar"}, d); err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				if !reflect.DeepEqual(d.Annotations, tt.expectedAnnotations) {
+					t.Errorf("annotations = %v, want %v", d.Annotations, tt.expectedAnnotations)
+				}
+			}
+		})
+	}
+}
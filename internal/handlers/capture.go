@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/capture"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"k8s.io/klog/v2"
+)
+
+// CaptureRequest is the request object of the capture API
+type CaptureRequest struct {
+	// Route is the pattern of the route to capture, e.g. GET /deployments/{namespace}/{deployment}
+	Route string `json:"route"`
+	// Identity restricts the capture to the requests of a single identity, when set
+	Identity string `json:"identity,omitempty"`
+	// Duration is how long to capture for, e.g. 15m, capture.DefaultDuration when empty
+	Duration string `json:"duration,omitempty"`
+}
+
+// Validate validates the CaptureRequest object and returns the duration of the capture, or an error if it is invalid
+func (c *CaptureRequest) Validate() (time.Duration, error) {
+	if c.Route == "" {
+		return 0, fmt.Errorf("route field is required")
+	}
+	if c.Duration == "" {
+		return capture.DefaultDuration, nil
+	}
+	duration, err := time.ParseDuration(c.Duration)
+	if err != nil || duration <= 0 || duration > capture.MaxDuration {
+		return 0, fmt.Errorf("duration field must be a positive duration of at most %s", capture.MaxDuration)
+	}
+	return duration, nil
+}
+
+// CaptureHandler is the handler for the capture API, which lets admins record the requests to a route, along with
+// their responses, to debug the integration of a client
+type CaptureHandler struct {
+	Recorder *capture.Recorder
+}
+
+// StartCapture handles the "/admin/capture" endpoint for PUT method, starting the capture of a route, in place of the
+// previous one if any
+func (h *CaptureHandler) StartCapture(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+	var c CaptureRequest
+	if err := decodeJSONBody(r, &c); err != nil {
+		writeBadRequest(w, r, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
+		return
+	}
+	duration, err := c.Validate()
+	if err != nil {
+		writeBadRequest(w, r, messages.New(messages.ValidationFailed, "reason", err.Error()))
+		return
+	}
+
+	session, err := h.Recorder.Start(c.Route, c.Identity, duration)
+	switch {
+	case errors.Is(err, capture.ErrUnknownRoute):
+		writeBadRequest(w, r, messages.New(messages.ValidationFailed, "reason", err.Error()))
+		return
+	case err != nil:
+		logger.Error(err, "Error starting capture")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Capture started", "route", session.Route, "captureIdentity", session.Identity, "until", session.Until,
+		"identity", auth.Identity(r))
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(session); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// GetCapture handles the "/admin/capture" endpoint for GET method, returning the current capture
+func (h *CaptureHandler) GetCapture(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+	session, ok := h.Recorder.Session()
+	if !ok {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.CaptureNotStarted))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(session); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// StopCapture handles the "/admin/capture" endpoint for DELETE method, stopping the capture and discarding the
+// captured exchanges
+func (h *CaptureHandler) StopCapture(w http.ResponseWriter, r *http.Request) {
+	h.Recorder.Stop()
+	klog.FromContext(r.Context()).Info("Capture stopped", "identity", auth.Identity(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListCapturedExchanges handles the "/admin/capture/exchanges" endpoint for GET method, returning the captured
+// exchanges, oldest first. The exchanges after a given ID are returned with ?after=, so that they can be polled.
+func (h *CaptureHandler) ListCapturedExchanges(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	if value := r.URL.Query().Get("after"); value != "" {
+		var err error
+		if after, err = strconv.ParseInt(value, 10, 64); err != nil || after < 0 {
+			writeBadRequest(w, r, errors.New("after must be the ID of an exchange"))
+			return
+		}
+	}
+	writeList(w, r, h.Recorder.Exchanges(after), ListMetadata{})
+}
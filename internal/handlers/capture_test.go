@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/capture"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+)
+
+func TestCaptureHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /deployments", func(w http.ResponseWriter, r *http.Request) {})
+	h := &CaptureHandler{Recorder: capture.NewRecorder(mux, 10, 1024, nil)}
+
+	// No capture was started yet
+	w := newResponseRecorder()
+	h.GetCapture(w, newHttpTestRequest("GET", "/admin/capture", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GetCapture() status code = %v, want %v", w.Code, http.StatusNotFound)
+	}
+	assertMatchesSchema(t, schema.CaptureSession, w)
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:           "started",
+			body:           `{"route":"GET /deployments","identity":"ci-bot","duration":"15m"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:             "missing route",
+			body:             `{"duration":"15m"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: errorBody(http.StatusBadRequest, messages.ValidationFailed, "reason", "route field is required"),
+		},
+		{
+			name:             "duration too long",
+			body:             `{"route":"GET /deployments","duration":"2h"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: errorBody(http.StatusBadRequest, messages.ValidationFailed, "reason", "duration field must be a positive duration of at most 1h0m0s"),
+		},
+		{
+			name:             "unknown route",
+			body:             `{"route":"GET /pods"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: errorBody(http.StatusBadRequest, messages.ValidationFailed, "reason", `unknown route "GET /pods"`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			h.StartCapture(w, newHttpTestRequest("PUT", "/admin/capture", strings.NewReader(tt.body)))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("StartCapture() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if tt.expectedResponse != "" && w.Body.String() != tt.expectedResponse {
+				t.Errorf("StartCapture() response body = %v, want %v", w.Body.String(), tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.CaptureSession, w)
+		})
+	}
+
+	// The capture started first is still the current one
+	w = newResponseRecorder()
+	h.GetCapture(w, newHttpTestRequest("GET", "/admin/capture", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"identity":"ci-bot"`) {
+		t.Errorf("GetCapture() = %v %s, want the capture of ci-bot", w.Code, w.Body.String())
+	}
+	assertMatchesSchema(t, schema.CaptureSession, w)
+
+	w = newResponseRecorder()
+	h.ListCapturedExchanges(w, newHttpTestRequest("GET", "/admin/capture/exchanges?after=foo", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("ListCapturedExchanges() status code = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+
+	w = newResponseRecorder()
+	h.StopCapture(w, newHttpTestRequest("DELETE", "/admin/capture", nil))
+	if _, ok := h.Recorder.Session(); w.Code != http.StatusNoContent || ok {
+		t.Errorf("StopCapture() status code = %v, want %v and the capture stopped", w.Code, http.StatusNoContent)
+	}
+}
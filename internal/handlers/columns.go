@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// deploymentColumn computes a column of a deployment, as of now
+type deploymentColumn func(d *appsv1.Deployment, now time.Time) any
+
+// deploymentColumns are the columns the deployments list can be projected to with the columns query parameter
+var deploymentColumns = map[string]deploymentColumn{
+	"namespace":     func(d *appsv1.Deployment, _ time.Time) any { return d.Namespace },
+	"name":          func(d *appsv1.Deployment, _ time.Time) any { return d.Name },
+	"replicas":      func(d *appsv1.Deployment, _ time.Time) any { return desiredReplicas(d) },
+	"readyReplicas": func(d *appsv1.Deployment, _ time.Time) any { return d.Status.ReadyReplicas },
+	"readyRatio": func(d *appsv1.Deployment, _ time.Time) any {
+		return readyRatio(d.Status.ReadyReplicas, desiredReplicas(d))
+	},
+	// The unique images of the init containers and containers, in their order
+	"images": func(d *appsv1.Deployment, _ time.Time) any {
+		images := []string{}
+		for _, c := range append(slices.Clone(d.Spec.Template.Spec.InitContainers), d.Spec.Template.Spec.Containers...) {
+			if !slices.Contains(images, c.Image) {
+				images = append(images, c.Image)
+			}
+		}
+		return images
+	},
+	"created": func(d *appsv1.Deployment, _ time.Time) any { return d.CreationTimestamp.UTC().Format(time.RFC3339) },
+	"age":     func(d *appsv1.Deployment, now time.Time) any { return age(d.CreationTimestamp, now) },
+}
+
+// deploymentColumnNames are the names of the deployment columns, in the order they're documented in
+var deploymentColumnNames = []string{"namespace", "name", "replicas", "readyReplicas", "readyRatio", "images", "created", "age"}
+
+// defaultCSVColumns are the columns of the deployments exported as CSV when the columns query parameter isn't set
+var defaultCSVColumns = []string{"namespace", "name", "replicas", "images", "created"}
+
+// parseColumns returns the columns asked by the comma separated columns query parameter of the request, in the order
+// they're asked in, or nil when it isn't set
+func parseColumns(r *http.Request) ([]string, error) {
+	if !r.URL.Query().Has("columns") {
+		return nil, nil
+	}
+	var names []string
+	for _, name := range strings.Split(r.URL.Query().Get("columns"), ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := deploymentColumns[name]; !ok {
+			return nil, messages.New(messages.UnsupportedParameterValue, "parameter", "columns", "value", name, "allowed", strings.Join(deploymentColumnNames, ", "))
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// DeploymentRow is a deployment projected to the columns asked with the columns query parameter. It is encoded as an
+// object holding the columns in the order they were asked in.
+type DeploymentRow struct {
+	Namespace string
+	Name      string
+	columns   []string
+	values    []any
+}
+
+// newDeploymentRow returns the row of the deployment projected to the given columns, as of now
+func newDeploymentRow(d *appsv1.Deployment, columns []string, now time.Time) DeploymentRow {
+	row := DeploymentRow{Namespace: d.Namespace, Name: d.Name, columns: columns, values: make([]any, len(columns))}
+	for i, column := range columns {
+		row.values[i] = deploymentColumns[column](d, now)
+	}
+	return row
+}
+
+// MarshalJSON encodes the row as an object of its columns
+func (row DeploymentRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range row.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(row.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// csvRecord returns the columns of the row as CSV fields, where the lists share a field separated by spaces
+func (row DeploymentRow) csvRecord() []string {
+	record := make([]string, len(row.values))
+	for i, value := range row.values {
+		switch v := value.(type) {
+		case string:
+			record[i] = v
+		case int32:
+			record[i] = strconv.Itoa(int(v))
+		case []string:
+			record[i] = strings.Join(v, " ")
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return record
+}
+
+// desiredReplicas returns the desired replicas of the deployment, which default to 1 when unset
+func desiredReplicas(d *appsv1.Deployment) int32 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/cost"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeploymentCost is the estimated monthly cost of a deployment
+type DeploymentCost struct {
+	Name     string `json:"name"`
+	Replicas int32  `json:"replicas"`
+	cost.Estimate
+}
+
+// DeploymentCostResponse is the response object of the cost of a deployment
+type DeploymentCostResponse struct {
+	Namespace string `json:"namespace"`
+	Currency  string `json:"currency"`
+	Basis     string `json:"basis"`
+	DeploymentCost
+}
+
+// NamespaceCostResponse is the response object of the cost of a namespace, which totals the cost of its deployments
+type NamespaceCostResponse struct {
+	Namespace string `json:"namespace"`
+	Currency  string `json:"currency"`
+	Basis     string `json:"basis"`
+	cost.Estimate
+	Deployments []DeploymentCost `json:"deployments"`
+}
+
+// CostHandler is the handler for the cost API, estimating the monthly cost of the cached deployments
+type CostHandler struct {
+	// Client reads the deployments, from the cache
+	Client client.Reader
+	// PriceSheet holds the prices the costs are estimated with
+	PriceSheet *cost.PriceSheet
+}
+
+// GetNamespaceCost handles the "/cost/{namespace}" endpoint (and its namespace scoped equivalent) for GET method
+func (h *CostHandler) GetNamespaceCost(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	if namespace == "" {
+		namespace = strings.TrimPrefix(r.URL.Path, "/cost/")
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		writeBadRequest(w, r, messages.New(messages.InvalidNamespace, "namespace", namespace, "reason", strings.Join(errs, ", ")))
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace)
+
+	dl := &appsv1.DeploymentList{}
+	if err := h.Client.List(r.Context(), dl, client.InNamespace(namespace)); err != nil {
+		logger.Error(err, "Error listing deployments")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := NamespaceCostResponse{
+		Namespace:   namespace,
+		Currency:    h.PriceSheet.Currency,
+		Basis:       h.PriceSheet.Basis,
+		Deployments: make([]DeploymentCost, 0, len(dl.Items)),
+	}
+	for i := range dl.Items {
+		dc := h.deploymentCost(&dl.Items[i])
+		response.Estimate.Add(dc.Estimate)
+		dc.Estimate = dc.Estimate.Round()
+		response.Deployments = append(response.Deployments, dc)
+	}
+	response.Estimate = response.Estimate.Round()
+	slices.SortFunc(response.Deployments, func(a, b DeploymentCost) int { return strings.Compare(a.Name, b.Name) })
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// GetDeploymentCost handles the "/cost/{namespace}/{deployment}" endpoint (and its namespace scoped equivalent) for
+// GET method
+func (h *CostHandler) GetDeploymentCost(w http.ResponseWriter, r *http.Request) {
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d := &appsv1.Deployment{}
+	if err := h.Client.Get(r.Context(), client.ObjectKey{Namespace: namespace, Name: deployment}, d); err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	dc := h.deploymentCost(d)
+	dc.Estimate = dc.Estimate.Round()
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(DeploymentCostResponse{
+		Namespace:      namespace,
+		Currency:       h.PriceSheet.Currency,
+		Basis:          h.PriceSheet.Basis,
+		DeploymentCost: dc,
+	})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// deploymentCost returns the estimated cost of the deployment, at its desired number of replicas
+func (h *CostHandler) deploymentCost(d *appsv1.Deployment) DeploymentCost {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return DeploymentCost{Name: d.Name, Replicas: replicas, Estimate: h.PriceSheet.Deployment(d)}
+}
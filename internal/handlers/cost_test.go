@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/cost"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func costDeployment(namespace, name string, replicas int32, cpu, memory string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(replicas),
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}}}},
+		},
+	}
+}
+
+func TestCostHandler(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		costDeployment("foo", "web", 3, "500m", "1Gi"),
+		costDeployment("foo", "api", 2, "250m", "256Mi"),
+		costDeployment("bar", "web", 1, "4", "8Gi"),
+	).Build()
+	h := &CostHandler{Client: c, PriceSheet: &cost.PriceSheet{Currency: "USD", CPUPerCoreMonth: 20, MemoryPerGiBMonth: 4, Basis: cost.BasisRequests}}
+
+	tests := []struct {
+		name             string
+		url              string
+		expectedCode     int
+		expectedResponse string
+	}{
+		{
+			"Test GetNamespaceCost",
+			"/cost/foo",
+			http.StatusOK,
+			"{\"namespace\":\"foo\",\"currency\":\"USD\",\"basis\":\"requests\",\"cpuCores\":2,\"memoryGiB\":3.5,\"monthlyCost\":54,\"deployments\":[" +
+				"{\"name\":\"api\",\"replicas\":2,\"cpuCores\":0.5,\"memoryGiB\":0.5,\"monthlyCost\":12}," +
+				"{\"name\":\"web\",\"replicas\":3,\"cpuCores\":1.5,\"memoryGiB\":3,\"monthlyCost\":42}]}\n",
+		},
+		{
+			"Test GetNamespaceCost Empty Namespace",
+			"/cost/baz",
+			http.StatusOK,
+			"{\"namespace\":\"baz\",\"currency\":\"USD\",\"basis\":\"requests\",\"cpuCores\":0,\"memoryGiB\":0,\"monthlyCost\":0,\"deployments\":[]}\n",
+		},
+		{
+			"Test GetNamespaceCost Invalid Namespace",
+			"/cost/Foo",
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.InvalidNamespace, "namespace", "Foo", "reason", "a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')"),
+		},
+		{
+			"Test GetDeploymentCost",
+			"/cost/bar/web",
+			http.StatusOK,
+			"{\"namespace\":\"bar\",\"currency\":\"USD\",\"basis\":\"requests\",\"name\":\"web\",\"replicas\":1,\"cpuCores\":4,\"memoryGiB\":8,\"monthlyCost\":112}\n",
+		},
+		{
+			"Test GetDeploymentCost Not Found",
+			"/cost/bar/api",
+			http.StatusNotFound,
+			errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "bar", "name", "api"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /cost/{namespace}", h.GetNamespaceCost)
+			mux.HandleFunc("GET /cost/{namespace}/{deployment}", h.GetDeploymentCost)
+
+			w := newResponseRecorder()
+			mux.ServeHTTP(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedCode {
+				t.Fatalf("status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("response body = %v, want %v", rb, tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.CostResponse, w)
+		})
+	}
+}
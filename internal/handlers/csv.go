@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+)
+
+// Formats of the list responses
+const (
+	ListFormatJSON = "json"
+	ListFormatCSV  = "csv"
+)
+
+// ContentTypeCSV is the media type of the list responses exported as CSV
+const ContentTypeCSV = "text/csv"
+
+// wantsCSV returns whether the list response to the request is exported as CSV, either with ?format=csv, or with an
+// Accept header of text/csv when the format query parameter isn't set
+func wantsCSV(r *http.Request) (bool, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case ListFormatCSV:
+		return true, nil
+	case ListFormatJSON:
+		return false, nil
+	case "":
+	default:
+		return false, messages.New(messages.UnsupportedParameterValue, "parameter", "format", "value", format, "allowed", ListFormatJSON+", "+ListFormatCSV)
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mediaType == ContentTypeCSV {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// csvWriter writes the rows of a list response exported as CSV, as an attachment named after the list, so that
+// browsers download it and spreadsheets open it as is. The lines end with CRLF, as expected by RFC 4180.
+type csvWriter struct {
+	*csv.Writer
+}
+
+// newCSVWriter writes the headers of the CSV response to w, and returns the writer of its rows starting with the header
+// row. The rows must be flushed once written.
+func newCSVWriter(w http.ResponseWriter, name string, header ...string) (*csvWriter, error) {
+	w.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".csv"}))
+	w.WriteHeader(http.StatusOK)
+	cw := &csvWriter{Writer: csv.NewWriter(w)}
+	cw.UseCRLF = true
+	return cw, cw.Write(header)
+}
+
+// Close flushes the rows, and returns the error of any of the writes
+func (cw *csvWriter) Close() error {
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/dashboards"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"k8s.io/klog/v2"
+)
+
+// DashboardsHandler is an HTTP handler for the Grafana dashboards bundled with the server
+type DashboardsHandler struct{}
+
+// ListDashboards handles the "/admin/dashboards" endpoint for GET method, returning the bundled dashboards
+func (h *DashboardsHandler) ListDashboards(w http.ResponseWriter, r *http.Request) {
+	writeList(w, r, dashboards.List(), ListMetadata{})
+}
+
+// GetDashboard handles the "/admin/dashboards/{name}" endpoint for GET method, returning the Grafana JSON model of the
+// dashboard, to be imported as is
+func (h *DashboardsHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	logger := klog.FromContext(r.Context()).WithValues("dashboard", name)
+	model, ok := dashboards.Get(name)
+	if !ok {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DashboardNotFound, "name", name))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(model); err != nil {
+		logger.Error(err, "Error writing response")
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/dashboards"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+)
+
+func TestDashboardsHandler_ListDashboards(t *testing.T) {
+	w := newResponseRecorder()
+	(&DashboardsHandler{}).ListDashboards(w, newHttpTestRequest("GET", "/admin/dashboards", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code = %v, want %v", w.Code, http.StatusOK)
+	}
+	assertMatchesSchema(t, schema.DashboardsResponse, w)
+	var list []dashboards.Dashboard
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+	if len(list) == 0 || list[0].Name != "red" {
+		t.Errorf("dashboards = %+v, want the red dashboard", list)
+	}
+}
+
+func TestDashboardsHandler_GetDashboard(t *testing.T) {
+	tests := []struct {
+		name         string
+		dashboard    string
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "bundled", dashboard: "red", expectedCode: http.StatusOK},
+		{name: "missing", dashboard: "use", expectedCode: http.StatusNotFound, expectedBody: errorBody(http.StatusNotFound, messages.DashboardNotFound, "name", "use")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newHttpTestRequest("GET", "/admin/dashboards/"+tt.dashboard, nil)
+			r.SetPathValue("name", tt.dashboard)
+			w := newResponseRecorder()
+			(&DashboardsHandler{}).GetDashboard(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("status code = %v, want %v", w.Code, tt.expectedCode)
+			}
+			if tt.expectedBody != "" {
+				if w.Body.String() != tt.expectedBody {
+					t.Errorf("body = %v, want %v", w.Body.String(), tt.expectedBody)
+				}
+				return
+			}
+			var model struct {
+				UID string `json:"uid"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &model); err != nil || model.UID != "k8s-api-proxy-red" {
+				t.Errorf("dashboard uid = %q (%v), want the Grafana model of the red dashboard", model.UID, err)
+			}
+		})
+	}
+}
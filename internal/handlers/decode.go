@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// decodeJSONBody strictly decodes the JSON request body into v. Unknown fields and trailing data are rejected,
+// and the returned errors point at the offending field and its byte offset in the body.
+func decodeJSONBody(r *http.Request, v any) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %v", err)
+	}
+	return decodeJSON(body, v)
+}
+
+// decodeJSON strictly decodes the JSON document into v, the same way decodeJSONBody does
+func decodeJSON(body []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return fmt.Errorf("invalid JSON at offset %d: %v", syntaxErr.Offset, syntaxErr)
+		case errors.As(err, &typeErr):
+			return fmt.Errorf("field %q must be of type %s, got %s at offset %d", typeErr.Field, typeErr.Type, typeErr.Value, typeErr.Offset)
+		case errors.Is(err, io.EOF):
+			return fmt.Errorf("request body is empty")
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return fmt.Errorf("unexpected end of JSON at offset %d", decoder.InputOffset())
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			// The json package has no dedicated error type for unknown fields, and reports them once the whole value
+			// has been read, so the offset of the field is looked up separately
+			var field string
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(err.Error(), "json: unknown field ")), &field); err != nil {
+				return fmt.Errorf("unknown field: %v", err)
+			}
+			return fmt.Errorf("unknown field %q at offset %d", field, keyOffset(body, field))
+		default:
+			return err
+		}
+	}
+
+	// The body must hold a single JSON value
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("unexpected data after the JSON value at offset %d", decoder.InputOffset())
+	}
+	return nil
+}
+
+// keyOffset returns the byte offset of the first object key named field in the JSON document, or -1 if not found
+func keyOffset(document []byte, field string) int64 {
+	// level is a nested object or array, along with whether the next token is an object key
+	type level struct {
+		object    bool
+		expectKey bool
+	}
+	var levels []*level
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	for {
+		// The token starts after the delimiters and whitespace following the previous one
+		start := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return -1
+		}
+
+		var current *level
+		if len(levels) > 0 {
+			current = levels[len(levels)-1]
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			// A nested value within an object is followed by the next key, once closed
+			if current != nil && current.object {
+				current.expectKey = true
+			}
+			levels = append(levels, &level{object: token == json.Delim('{'), expectKey: token == json.Delim('{')})
+		case json.Delim('}'), json.Delim(']'):
+			levels = levels[:len(levels)-1]
+		default:
+			if current == nil || !current.object {
+				continue
+			}
+			if current.expectKey && token == field {
+				// Keys may be written with escape sequences, so the offset is that of their opening quote rather than
+				// computed from their length
+				return int64(bytes.IndexByte(document[start:], '"')) + start
+			}
+			current.expectKey = !current.expectKey
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{name: "valid", body: `{"replicas": 3}`},
+		{name: "valid with trailing whitespace", body: "{\"replicas\": 3}\n"},
+		{name: "unknown field", body: `{"replikas": 3}`, wantErr: `unknown field "replikas" at offset 1`},
+		{name: "unknown field after a nested value", body: `{"replicas": 3, "foo": {"replikas": 1}, "replikas": 3}`, wantErr: `unknown field "foo" at offset 16`},
+		{name: "wrong type", body: `{"replicas": "3"}`, wantErr: `field "replicas" must be of type int32, got string at offset 16`},
+		{name: "out of range", body: `{"replicas": 3000000000}`, wantErr: `field "replicas" must be of type int32, got number 3000000000 at offset 23`},
+		{name: "syntax error", body: `{"replicas" 3}`, wantErr: "invalid JSON at offset 13"},
+		{name: "truncated", body: `{"replicas": 3`, wantErr: "unexpected end of JSON"},
+		{name: "empty", body: ``, wantErr: "request body is empty"},
+		{name: "trailing data", body: `{"replicas": 3} {"replicas": 4}`, wantErr: "unexpected data after the JSON value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rep Replicas
+			err := decodeJSONBody(newHttpTestRequest("PUT", "/", strings.NewReader(tt.body)), &rep)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestKeyOffset(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		field    string
+		want     int64
+	}{
+		{name: "top level key", document: `{"a": 1, "b": 2}`, field: "b", want: 9},
+		{name: "value is not a key", document: `{"a": "b", "b": 2}`, field: "b", want: 11},
+		{name: "key after nested object", document: `{"a": {"c": 1}, "b": 2}`, field: "b", want: 16},
+		{name: "key in array of objects", document: `[{"a": ["b"]}, {"b": 1}]`, field: "b", want: 16},
+		{name: "not found", document: `{"a": 1}`, field: "b", want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyOffset([]byte(tt.document), tt.field); got != tt.want {
+				t.Errorf("keyOffset() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultLongPollTimeout is how long a watch waits for a change when the request doesn't pass ?timeoutSeconds=
+	defaultLongPollTimeout = 30 * time.Second
+	// longPollInterval is how often a watch checks for a change. The deployments are read from the cache, so
+	// checking is cheap.
+	longPollInterval = 250 * time.Millisecond
+)
+
+// DeploymentDetailResponse is the response object for the single deployment API
+type DeploymentDetailResponse struct {
+	DeploymentResponse
+	// ResourceVersion changes on every update of the deployment, and can be passed back with ?watch=true to wait for
+	// the next change
+	ResourceVersion   string `json:"resourceVersion"`
+	Generation        int64  `json:"generation"`
+	Replicas          *int32 `json:"replicas"`
+	ReadyReplicas     int32  `json:"readyReplicas"`
+	UpdatedReplicas   int32  `json:"updatedReplicas"`
+	AvailableReplicas int32  `json:"availableReplicas"`
+	// ReadyRatio is the ready replicas out of the desired ones, e.g. 3/5
+	ReadyRatio string `json:"readyRatio"`
+	// Age is the time since the creation of the deployment, e.g. 3d4h
+	Age    string           `json:"age"`
+	Images []ContainerImage `json:"images"`
+	// Meta reports where the deployment was read from
+	Meta *ResponseMeta `json:"meta"`
+}
+
+// GetDeployment handles the "/deployments/{namespace}/{deployment}" endpoint (and its namespace scoped equivalent)
+// for GET method.
+// With ?watch=true&resourceVersion=N, the request is held until the resourceVersion of the deployment differs from N,
+// or until ?timeoutSeconds= elapse, in which case it gets a 304 Not Modified. This gives simple clients change
+// detection through long polling.
+func (h *DeploymentsHandler) GetDeployment(w http.ResponseWriter, r *http.Request) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	watch, resourceVersion, timeout, err := h.parseLongPoll(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	var d *appsv1.Deployment
+	if watch {
+		d, err = h.waitForChange(r.Context(), reader, namespace, deployment, resourceVersion, timeout)
+	} else {
+		d, err = h.getDeployment(r.Context(), reader, namespace, deployment)
+	}
+	switch {
+	case wait.Interrupted(err):
+		logger.V(5).Info("Deployment didn't change before the watch timed out", "resourceVersion", resourceVersion)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	case err != nil:
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(DeploymentDetailResponse{
+		DeploymentResponse: DeploymentResponse{Name: deployment, Namespace: namespace},
+		ResourceVersion:    d.ResourceVersion,
+		Generation:         d.Generation,
+		Replicas:           d.Spec.Replicas,
+		ReadyReplicas:      d.Status.ReadyReplicas,
+		UpdatedReplicas:    d.Status.UpdatedReplicas,
+		AvailableReplicas:  d.Status.AvailableReplicas,
+		ReadyRatio:         readyRatio(d.Status.ReadyReplicas, desiredReplicas(d)),
+		Age:                age(d.CreationTimestamp, h.clock()),
+		Images:             containerImages(&d.Spec.Template.Spec),
+		Meta:               h.responseMeta(w),
+	})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// parseLongPoll parses the ?watch=, ?resourceVersion= and ?timeoutSeconds= query parameters. The timeout is capped
+// to the handler's MaxLongPollTimeout, if any.
+func (h *DeploymentsHandler) parseLongPoll(r *http.Request) (bool, string, time.Duration, error) {
+	query := r.URL.Query()
+	watch := false
+	if value := query.Get("watch"); value != "" {
+		var err error
+		watch, err = strconv.ParseBool(value)
+		if err != nil {
+			return false, "", 0, messages.New(messages.InvalidBooleanParameter, "parameter", "watch", "value", value)
+		}
+	}
+	resourceVersion := query.Get("resourceVersion")
+	if !watch {
+		return false, "", 0, nil
+	}
+	if resourceVersion == "" {
+		return false, "", 0, messages.New(messages.ResourceVersionRequired)
+	}
+
+	timeout := defaultLongPollTimeout
+	if value := query.Get("timeoutSeconds"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			return false, "", 0, messages.New(messages.InvalidPositiveIntParameter, "parameter", "timeoutSeconds", "value", value)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if h.MaxLongPollTimeout > 0 && timeout > h.MaxLongPollTimeout {
+		timeout = h.MaxLongPollTimeout
+	}
+	return true, resourceVersion, timeout, nil
+}
+
+// waitForChange returns the deployment once its resourceVersion differs from the given one. It returns an error
+// satisfying wait.Interrupted if the deployment doesn't change within the timeout.
+func (h *DeploymentsHandler) waitForChange(ctx context.Context, reader client.Reader, namespace, deployment, resourceVersion string, timeout time.Duration) (*appsv1.Deployment, error) {
+	var changed *appsv1.Deployment
+	err := wait.PollUntilContextTimeout(ctx, longPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		d := &appsv1.Deployment{}
+		if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: deployment}, d); err != nil {
+			return false, err
+		}
+		if d.ResourceVersion == resourceVersion {
+			return false, nil
+		}
+		changed = d
+		return true, nil
+	})
+	return changed, err
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeploymentsHandler_GetDeployment(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		url              string
+		updateAfter      time.Duration
+		expectedCode     int
+		expectedResponse string
+	}{
+		{
+			name:             "get",
+			url:              "/deployments/foo/bar",
+			expectedCode:     http.StatusOK,
+			expectedResponse: "{\"name\":\"bar\",\"namespace\":\"foo\",\"resourceVersion\":\"999\",\"generation\":3,\"replicas\":2,\"readyReplicas\":1,\"updatedReplicas\":2,\"availableReplicas\":1,\"readyRatio\":\"1/2\",\"age\":\"3d4h\",\"images\":[{\"container\":\"app\",\"image\":\"registry.example.com/team/bar:1.4.2\",\"shortName\":\"bar:1.4.2\"}],\"meta\":{\"dataSource\":\"cache\"}}\n",
+		},
+		{
+			name:             "not found",
+			url:              "/deployments/foo/baz",
+			expectedCode:     http.StatusNotFound,
+			expectedResponse: errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "foo", "name", "baz"),
+		},
+		{
+			name:             "watch an outdated resource version",
+			url:              "/deployments/foo/bar?watch=true&resourceVersion=1",
+			expectedCode:     http.StatusOK,
+			expectedResponse: "{\"name\":\"bar\",\"namespace\":\"foo\",\"resourceVersion\":\"999\",\"generation\":3,\"replicas\":2,\"readyReplicas\":1,\"updatedReplicas\":2,\"availableReplicas\":1,\"readyRatio\":\"1/2\",\"age\":\"3d4h\",\"images\":[{\"container\":\"app\",\"image\":\"registry.example.com/team/bar:1.4.2\",\"shortName\":\"bar:1.4.2\"}],\"meta\":{\"dataSource\":\"cache\"}}\n",
+		},
+		{
+			name:         "watch until the deployment changes",
+			url:          "/deployments/foo/bar?watch=true&resourceVersion=999",
+			updateAfter:  100 * time.Millisecond,
+			expectedCode: http.StatusOK,
+			// The fake client increments the resource version on updates
+			expectedResponse: "{\"name\":\"bar\",\"namespace\":\"foo\",\"resourceVersion\":\"1000\",\"generation\":3,\"replicas\":5,\"readyReplicas\":1,\"updatedReplicas\":2,\"availableReplicas\":1,\"readyRatio\":\"1/5\",\"age\":\"3d4h\",\"images\":[{\"container\":\"app\",\"image\":\"registry.example.com/team/bar:1.4.2\",\"shortName\":\"bar:1.4.2\"}],\"meta\":{\"dataSource\":\"cache\"}}\n",
+		},
+		{
+			name:         "watch times out",
+			url:          "/deployments/foo/bar?watch=true&resourceVersion=999&timeoutSeconds=60",
+			expectedCode: http.StatusNotModified,
+		},
+		{
+			name:             "watch a missing deployment",
+			url:              "/deployments/foo/baz?watch=true&resourceVersion=1",
+			expectedCode:     http.StatusNotFound,
+			expectedResponse: errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "foo", "name", "baz"),
+		},
+		{
+			name:             "watch without a resource version",
+			url:              "/deployments/foo/bar?watch=true",
+			expectedCode:     http.StatusBadRequest,
+			expectedResponse: errorBody(http.StatusBadRequest, messages.ResourceVersionRequired),
+		},
+		{
+			name:             "invalid timeout",
+			url:              "/deployments/foo/bar?watch=true&resourceVersion=1&timeoutSeconds=soon",
+			expectedCode:     http.StatusBadRequest,
+			expectedResponse: errorBody(http.StatusBadRequest, messages.InvalidPositiveIntParameter, "parameter", "timeoutSeconds", "value", "soon"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo", ResourceVersion: "999", Generation: 3, CreationTimestamp: metav1.NewTime(now.Add(-76 * time.Hour))},
+				Spec: appsv1.DeploymentSpec{Replicas: ptr.To[int32](2), Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Image: "registry.example.com/team/bar:1.4.2"}},
+				}}},
+				Status: appsv1.DeploymentStatus{ReadyReplicas: 1, UpdatedReplicas: 2, AvailableReplicas: 1},
+			}).Build()
+			// Watches are capped well below the requested timeout, to keep the test fast
+			h := &DeploymentsHandler{Client: c, MaxLongPollTimeout: time.Second, now: func() time.Time { return now }}
+
+			if tt.updateAfter > 0 {
+				go func() {
+					time.Sleep(tt.updateAfter)
+					d := &appsv1.Deployment{}
+					if err := c.Get(context.Background(), client.ObjectKey{Namespace: "foo", Name: "bar"}, d); err != nil {
+						t.Errorf("Get() error = %v", err)
+						return
+					}
+					d.Spec.Replicas = ptr.To[int32](5)
+					if err := c.Update(context.Background(), d); err != nil {
+						t.Errorf("Update() error = %v", err)
+					}
+				}()
+			}
+
+			w := newResponseRecorder()
+			h.GetDeployment(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedCode {
+				t.Fatalf("status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("response body = %v, want %v", rb, tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.DeploymentResponse, w)
+		})
+	}
+}
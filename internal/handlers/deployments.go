@@ -1,15 +1,34 @@
 package handlers
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"context"
 
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/admission"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiwarnings"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/events"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/notify"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/operations"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/ownership"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/snapshots"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
 	appsv1 "k8s.io/api/apps/v1"
-	"k8s.io/klog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -24,9 +43,24 @@ type Replicas struct {
 	Replicas *int32 `json:"replicas"`
 }
 
-// APIError is a response object for cases when an error occurs
+// APIError is a response object for cases when an error occurs. It's a problem (RFC 7807), whose extension members
+// are the message and its code and parameters.
 type APIError struct {
+	problem.Details
+	// Message is the message rendered in English, i.e. the detail of the problem, kept for the clients predating
+	// problem details
 	Message string `json:"message"`
+	// Code identifies the message in the catalog of the messages package, so that clients can localize it
+	Code messages.Code `json:"code,omitempty"`
+	// Params are the parameters of the message, e.g. the namespace and name of a deployment
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// NewAPIError returns the response object of the given message of the catalog, responded with the given status. It
+// sets the problem content type of the response.
+func NewAPIError(w http.ResponseWriter, status int, m messages.Message) APIError {
+	message := m.String()
+	return APIError{Details: problem.New(w, status, message), Message: message, Code: m.Code, Params: m.Params}
 }
 
 // Validate validates the Replicas object and returns an error if it is invalid
@@ -44,57 +78,298 @@ func (r *Replicas) Validate() error {
 type DeploymentResponseWithReplicas struct {
 	DeploymentResponse
 	Replicas
+	// Meta is only set on reads
+	Meta *ResponseMeta `json:"meta,omitempty"`
+	// Warnings are the warnings of the admission plugins, only set on writes
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// DeploymentListItem is a deployment of the deployments list, along with its owners when declared
+type DeploymentListItem struct {
+	DeploymentResponse
+	Ownership *ownership.Ownership `json:"ownership,omitempty"`
+}
+
+// newDeploymentListItem returns the list item of the deployment with the given annotations
+func newDeploymentListItem(namespace, name string, annotations map[string]string) DeploymentListItem {
+	item := DeploymentListItem{DeploymentResponse: DeploymentResponse{Name: name, Namespace: namespace}}
+	if o := ownership.FromAnnotations(annotations); !o.IsZero() {
+		item.Ownership = &o
+	}
+	return item
+}
+
+// DeploymentsListResponse is the response object of the deployments list with ?meta=true, which wraps the deployments
+// along with where they were read from. The handler streams it rather than encoding it as a whole.
+type DeploymentsListResponse struct {
+	Items []DeploymentListItem `json:"items"`
+	Meta  *ResponseMeta        `json:"meta"`
 }
 
 // DeploymentsHandler is the handler for the deployments API
 type DeploymentsHandler struct {
 	client.Client
+	// LiveReader reads directly from the API server, bypassing the cache.
+	// It is used when a request passes ?cache=false, when the cache hasn't synced yet, or when the resource isn't cached.
+	LiveReader client.Reader
+	// CacheSynced reports whether the cache has completed its initial sync
+	CacheSynced func() bool
+	// CacheLastSync returns the last time the cache received data from the API server, reported in the responses read
+	// from the cache
+	CacheLastSync func() time.Time
+	// Summaries lists the summaries of the cached deployments, which the deployment lists read from the cache are served
+	// from rather than from the full objects. The full objects are listed when nil.
+	Summaries SummaryLister
+	// APIServerUnavailable reports whether the API server is unavailable, in which case reads are served from the cache
+	// and flagged as possibly stale
+	APIServerUnavailable func() bool
+	// Operations runs the requests passing ?async=true in the background. Async mode is unavailable when nil.
+	Operations *operations.Manager
+	// FieldManager is the field manager of the server-side applies. Defaults to DefaultFieldManager when empty.
+	FieldManager string
+	// MaxLongPollTimeout caps how long watches may wait for a change, e.g. to stay within the server's write timeout.
+	// Watches aren't capped when zero.
+	MaxLongPollTimeout time.Duration
+	// Admission runs the changes of the deployments through the admission plugins before they are patched. Every change
+	// is admitted when nil.
+	Admission *admission.Chain
+	// Notifier notifies the changes of the deployments to chat channels. Changes aren't notified when nil.
+	Notifier *notify.Notifier
+	// Events records the changes of the deployments as events on them. Changes aren't recorded when nil.
+	Events *events.Recorder
+	// Snapshots keeps the snapshots of the specs of the deployments, which the snapshot endpoints are unavailable
+	// without
+	Snapshots *snapshots.Manager
+
+	// now returns the current time, which the age of the deployments is computed from. Defaults to time.Now.
+	now func() time.Time
 }
 
-// ListDeployments handles the "/deployments" endpoint
-func (h *DeploymentsHandler) ListDeployments(w http.ResponseWriter, r *http.Request) {
-	dl := &appsv1.DeploymentList{}
+func (h *DeploymentsHandler) clock() time.Time {
+	if h.now != nil {
+		return h.now()
+	}
+	return time.Now()
+}
 
-	// If namespace was passed as a query parameter, use it. Otherwise return deployments from all namespaces.
-	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
-		err := h.List(r.Context(), dl, client.InNamespace(namespace))
+// ListDeployments handles the "/deployments" and "/namespaces/{namespace}/deployments" endpoints
+func (h *DeploymentsHandler) ListDeployments(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	// The deployments are returned as a bare array, unless ?meta=true asks for them to be wrapped along with where they
+	// were read from
+	withMeta := false
+	if value := r.URL.Query().Get("meta"); value != "" {
+		withMeta, err = strconv.ParseBool(value)
 		if err != nil {
-			klog.Errorf("Error listing deployments in namespace %s: %v", namespace, err)
-			w.WriteHeader(http.StatusInternalServerError)
+			writeBadRequest(w, r, messages.New(messages.InvalidBooleanParameter, "parameter", "meta", "value", value))
 			return
 		}
-	} else {
-		// list deployments in all namespaces
-		err := h.List(r.Context(), dl)
+	}
+	exportCSV, err := wantsCSV(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	// The deployments are projected to the columns asked with ?columns, rather than returned as the usual items
+	columns, err := parseColumns(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	// The versioned API always wraps the deployments in a List envelope, which may be paginated
+	enveloped := enveloped(r) && !exportCSV
+	var pageOpts []client.ListOption
+	if enveloped {
+		pageOpts, err = pagination(r)
 		if err != nil {
-			klog.Errorf("Error listing deployments in all namespaces: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			writeBadRequest(w, r, err)
 			return
 		}
+		// The cache can't continue a list, so the pages are read directly from the API server
+		if len(pageOpts) > 0 && reader.live != nil {
+			reader.useLive = true
+		}
 	}
-	response := generateListDeploymentsResponse(dl)
-	w.WriteHeader(http.StatusOK)
-	err := json.NewEncoder(w).Encode(response)
+
+	// Namespace scoped routes (/namespaces/{namespace}/deployments) only list deployments in their namespace.
+	// Otherwise, if namespace was passed as a query parameter, use it, or return deployments from all namespaces.
+	namespace := r.PathValue("namespace")
+	if namespace == "" {
+		namespace = r.URL.Query().Get("namespace")
+	}
+	if exportCSV {
+		if columns == nil {
+			columns = defaultCSVColumns
+		}
+		h.exportDeployments(w, r, reader, namespace, columns)
+		return
+	}
+	deployments, listMeta, err := h.listDeployments(r.Context(), reader, namespace, columns, pageOpts...)
 	if err != nil {
-		klog.Errorf("Error encoding response: %v", err)
+		logger.Error(err, "Error listing deployments", "namespace", namespace)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// With tenancy enabled, only the deployments in the caller's namespaces are returned
+	scope, scoped := tenancy.ScopeFrom(r.Context())
+	scoped = scoped && !scope.All
+	// The deployments are streamed one at a time, rather than building the whole response in memory first
+	w.WriteHeader(http.StatusOK)
+	bw := bufio.NewWriterSize(w, streamBufferSize)
+	if withMeta || enveloped {
+		bw.WriteString(`{"items":`)
+	}
+	items := newJSONArrayWriter(bw)
+	for key, d := range deployments {
+		if scoped && !scope.Allows(key.Namespace) {
+			continue
+		}
+		logger.V(5).Info("Listed deployment", "deployment", klog.KRef(key.Namespace, key.Name))
+		if err := items.Write(d); err != nil {
+			logger.Error(err, "Error encoding response")
+			return
+		}
+	}
+	if err := items.Close(); err != nil {
+		logger.Error(err, "Error encoding response")
+		return
+	}
+	switch {
+	case enveloped:
+		meta := h.responseMeta(w)
+		metadata, err := json.Marshal(ListMetadata{
+			Count: items.n, Continue: listMeta.Continue, ResourceVersion: listMeta.ResourceVersion,
+			DataSource: meta.DataSource, CacheLastSync: meta.CacheLastSync, Stale: meta.Stale,
+		})
+		if err != nil {
+			logger.Error(err, "Error encoding response")
+			return
+		}
+		bw.WriteString(`,"metadata":`)
+		bw.Write(metadata)
+		bw.WriteString("}")
+	case withMeta:
+		meta, err := json.Marshal(h.responseMeta(w))
+		if err != nil {
+			logger.Error(err, "Error encoding response")
+			return
+		}
+		bw.WriteString(`,"meta":`)
+		bw.Write(meta)
+		bw.WriteString("}")
+	}
+	bw.WriteString("\n")
+	if err := bw.Flush(); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// listDeployments lists the deployments in the given namespace, or in all namespaces when empty, along with the metadata
+// of the list. The deployments are listed by their key, as DeploymentListItem, or as DeploymentRow projected to the
+// given columns when any. The deployments read from the cache are listed from their summaries when available, rather
+// than deep copying the full objects. The summaries aren't paginated, and hold none of the computed columns, so they're
+// only listed without pagination options nor columns.
+func (h *DeploymentsHandler) listDeployments(ctx context.Context, reader *sourceReader, namespace string, columns []string, pageOpts ...client.ListOption) (iter.Seq2[types.NamespacedName, any], metav1.ListMeta, error) {
+	if summaries, ok := reader.summaries(h.Summaries, namespace); ok && len(pageOpts) == 0 && columns == nil {
+		return func(yield func(types.NamespacedName, any) bool) {
+			for _, s := range summaries {
+				key := types.NamespacedName{Namespace: s.Namespace, Name: s.Name}
+				if !yield(key, DeploymentListItem{DeploymentResponse: DeploymentResponse{Name: s.Name, Namespace: s.Namespace}, Ownership: s.Ownership}) {
+					return
+				}
+			}
+		}, metav1.ListMeta{}, nil
+	}
+
+	dl := &appsv1.DeploymentList{}
+	opts := slices.Clone(pageOpts)
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := reader.List(ctx, dl, opts...); err != nil {
+		return nil, metav1.ListMeta{}, err
+	}
+	now := h.clock()
+	return func(yield func(types.NamespacedName, any) bool) {
+		for i := range dl.Items {
+			d := &dl.Items[i]
+			var item any = newDeploymentListItem(d.Namespace, d.Name, d.Annotations)
+			if columns != nil {
+				item = newDeploymentRow(d, columns, now)
+			}
+			if !yield(types.NamespacedName{Namespace: d.Namespace, Name: d.Name}, item) {
+				return
+			}
+		}
+	}, dl.ListMeta, nil
+}
+
+// exportDeployments writes the deployments in the given namespace, or in all namespaces when empty, as CSV rows of the
+// given columns. Like the rows of the JSON list, they need the full deployments, so they're never listed from the
+// summaries.
+func (h *DeploymentsHandler) exportDeployments(w http.ResponseWriter, r *http.Request, reader *sourceReader, namespace string, columns []string) {
+	logger := klog.FromContext(r.Context())
+	dl := &appsv1.DeploymentList{}
+	var opts []client.ListOption
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := reader.List(r.Context(), dl, opts...); err != nil {
+		logger.Error(err, "Error listing deployments", "namespace", namespace)
 		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// With tenancy enabled, only the deployments in the caller's namespaces are returned
+	if scope, ok := tenancy.ScopeFrom(r.Context()); ok && !scope.All {
+		dl.Items = slices.DeleteFunc(dl.Items, func(d appsv1.Deployment) bool { return !scope.Allows(d.Namespace) })
+	}
+	slices.SortFunc(dl.Items, func(a, b appsv1.Deployment) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+
+	cw, err := newCSVWriter(w, "deployments", columns...)
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		return
+	}
+	now := h.clock()
+	for i := range dl.Items {
+		if err := cw.Write(newDeploymentRow(&dl.Items[i], columns, now).csvRecord()); err != nil {
+			logger.Error(err, "Error encoding response")
+			return
+		}
+	}
+	if err := cw.Close(); err != nil {
+		logger.Error(err, "Error encoding response")
 	}
 }
 
-// GetDeploymentReplicas handles the "/deployments/{namespace}/{deployment}/replicas" endpoint for GET method
+// GetDeploymentReplicas handles the "/deployments/{namespace}/{deployment}/replicas" endpoint (and its namespace scoped
+// equivalent) for GET method
 func (h *DeploymentsHandler) GetDeploymentReplicas(w http.ResponseWriter, r *http.Request) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
 	// Parse namespace and deployment from the URL path
-	namespace, deployment := parseNamespaceAndDeploymentNameFromURL(r)
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
 
 	// Get the deployment object
-	d, err := h.getDeployment(r.Context(), namespace, deployment)
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		encErr := json.NewEncoder(w).Encode(APIError{fmt.Sprintf("Error getting deployment %s in namespace %s", deployment, namespace)})
-		if encErr != nil {
-			klog.Errorf("Error encoding response: %v", encErr)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
 		return
 	}
 	// Get the deployment's replicas field
@@ -107,71 +382,83 @@ func (h *DeploymentsHandler) GetDeploymentReplicas(w http.ResponseWriter, r *htt
 			Namespace: namespace,
 		},
 		Replicas: Replicas{replicas},
+		Meta:     h.responseMeta(w),
 	},
 	)
 	if err != nil {
-		klog.Errorf("Error encoding response: %v", err)
+		logger.Error(err, "Error encoding response")
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
-// SetDeploymentReplicas handles the "/deployments/{namespace}/{deployment}/replicas" endpoint for PUT method
+// SetDeploymentReplicas handles the "/deployments/{namespace}/{deployment}/replicas" endpoint (and its namespace scoped
+// equivalent) for PUT method
 func (h *DeploymentsHandler) SetDeploymentReplicas(w http.ResponseWriter, r *http.Request) {
-	namespace, deployment := parseNamespaceAndDeploymentNameFromURL(r)
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	async, err := h.async(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
 
 	// Get the deployment object
-	d, err := h.getDeployment(r.Context(), namespace, deployment)
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		encErr := json.NewEncoder(w).Encode(APIError{fmt.Sprintf("Error getting deployment %s in namespace %s", deployment, namespace)})
-		if encErr != nil {
-			klog.Errorf("Error encoding response: %v", encErr)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
 		return
 	}
 
 	// Parse the request body
 	var rep Replicas
-	err = json.NewDecoder(r.Body).Decode(&rep)
+	err = decodeJSONBody(r, &rep)
 	if err != nil {
 		// log the error, return a 400 Bad Request and the error message
-		resp := fmt.Sprintf("Error parsing request body: %v", err)
-		klog.Errorf("%v", resp)
-		w.WriteHeader(http.StatusBadRequest)
-		encErr := json.NewEncoder(w).Encode(APIError{resp})
-		if encErr != nil {
-			klog.Errorf("Error encoding response: %v", encErr)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		logger.Error(err, "Error parsing request body")
+		writeError(w, logger, http.StatusBadRequest, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
 		return
 	}
 
 	// validate the passed in replicas
 	err = rep.Validate()
 	if err != nil {
-		resp := fmt.Sprintf("Validation error: %v", err)
-		klog.Errorf("%v", resp)
-		w.WriteHeader(http.StatusBadRequest)
-		encErr := json.NewEncoder(w).Encode(APIError{resp})
-		if encErr != nil {
-			klog.Errorf("Error encoding response: %v", encErr)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		logger.Error(err, "Validation error")
+		writeError(w, logger, http.StatusBadRequest, messages.New(messages.ValidationFailed, "reason", err.Error()))
 		return
 	}
 
-	// Create a patch that updates the replicas field
+	// Create a patch that updates the replicas field, once admitted
 	patch := client.MergeFrom(d.DeepCopy())
-	d.Spec.Replicas = rep.Replicas
+	proposed := d.DeepCopy()
+	proposed.Spec.Replicas = rep.Replicas
+	warnings, admitted := h.admit(w, r, d, proposed)
+	if !admitted {
+		return
+	}
+	previous := replicasString(d.Spec.Replicas)
+	d = proposed
 	err = h.Patch(r.Context(), d, patch)
 	if err != nil {
-		klog.Errorf("Error patching deployment %s in namespace %s: %v", deployment, namespace, err)
-		w.WriteHeader(http.StatusInternalServerError)
-		encErr := json.NewEncoder(w).Encode(APIError{fmt.Sprintf("Error patching deployment %s in namespace %s", deployment, namespace)})
-		if encErr != nil {
-			klog.Errorf("Error encoding response: %v", encErr)
-		}
+		logger.Error(err, "Error patching deployment")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.DeploymentPatchFailed, "namespace", namespace, "name", deployment))
+		return
+	}
+	h.recordDeploymentChange(r, approvals.OperationSetDeploymentReplicas, "scaled", "Scaled", d, previous, replicasString(d.Spec.Replicas))
+
+	// In async mode, wait for the rollout in the background, and let the client poll the operation
+	if async {
+		h.startScaleOperation(w, r, d)
 		return
 	}
 
@@ -183,55 +470,65 @@ func (h *DeploymentsHandler) SetDeploymentReplicas(w http.ResponseWriter, r *htt
 			Namespace: namespace,
 		},
 		Replicas: Replicas{d.Spec.Replicas},
+		Warnings: append(warnings, apiwarnings.Collected(r.Context())...),
 	},
 	)
 	if err != nil {
-		klog.Errorf("Error encoding response: %v", err)
+		logger.Error(err, "Error encoding response")
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
-// generateListDeploymentsResponse generates a list of DeploymentResponse objects from a DeploymentList
-func generateListDeploymentsResponse(result *appsv1.DeploymentList) []DeploymentResponse {
-	response := make([]DeploymentResponse, 0, len(result.Items))
-	for _, d := range result.Items {
-		klog.V(5).Infof("Deployment %s in namespace %s", d.Name, d.Namespace)
-		response = append(response, DeploymentResponse{
-			Name:      d.Name,
-			Namespace: d.Namespace,
-		})
+// parseNamespaceAndDeploymentNameFromURL parses the namespace and deployment name from the path values of the route,
+// or else from the /deployments/{namespace}/{deployment} segments of the URL path. It returns an error if either is
+// missing or isn't a valid name, rather than letting malformed paths fall through to lookups of empty names.
+func parseNamespaceAndDeploymentNameFromURL(r *http.Request) (string, string, error) {
+	namespace, deployment := r.PathValue("namespace"), r.PathValue("deployment")
+	if namespace == "" || deployment == "" {
+		// Split the URL path into segments
+		pathSegments := strings.Split(r.URL.Path, "/")
+		if len(pathSegments) < 4 {
+			return "", "", fmt.Errorf("invalid path %q, must be /deployments/{namespace}/{deployment}", r.URL.Path)
+		}
+		namespace, deployment = pathSegments[2], pathSegments[3]
 	}
-	return response
-}
 
-// parseNamespaceAndDeploymentNameFromURL parses the namespace and deployment name from the URL path
-func parseNamespaceAndDeploymentNameFromURL(r *http.Request) (string, string) {
-	// Split the URL path into segments
-	pathSegments := strings.Split(r.URL.Path, "/")
-
-	// Check if the pathSegments slice has at least 4 elements. If not- return empty strings for now
-	// TODO we may want to return an error here instead in a future iteration
-	if len(pathSegments) < 4 {
-		klog.Errorf("Error parsing namespace and deployment name from URL path: %s", r.URL.Path)
-		return "", ""
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return "", "", messages.New(messages.InvalidNamespace, "namespace", namespace, "reason", strings.Join(errs, ", "))
 	}
+	if errs := validation.IsDNS1123Subdomain(deployment); len(errs) > 0 {
+		return "", "", messages.New(messages.InvalidDeploymentName, "name", deployment, "reason", strings.Join(errs, ", "))
+	}
+	return namespace, deployment, nil
+}
 
-	// Extract namespace and deployment name from the URL path
-	namespace := pathSegments[2]
-	deployment := pathSegments[3]
-
-	return namespace, deployment
+// writeBadRequest logs the error, and returns a 400 Bad Request with the error message. The errors which aren't a
+// message of the catalog are returned with the BadRequest code, and the error as the reason.
+func writeBadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	logger := klog.FromContext(r.Context())
+	logger.Error(err, "Bad request")
+	m, ok := err.(messages.Message)
+	if !ok {
+		m = messages.New(messages.BadRequest, "reason", err.Error())
+	}
+	resp := NewAPIError(w, http.StatusBadRequest, m)
+	w.WriteHeader(http.StatusBadRequest)
+	encErr := json.NewEncoder(w).Encode(resp)
+	if encErr != nil {
+		logger.Error(encErr, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
 }
 
-// getDeployment returns a deployment object from the client (either from the cache or from the API)
-func (h *DeploymentsHandler) getDeployment(ctx context.Context, namespace, deployment string) (*appsv1.Deployment, error) {
+// getDeployment returns a deployment object from the given reader (either from the cache or from the API)
+func (h *DeploymentsHandler) getDeployment(ctx context.Context, reader client.Reader, namespace, deployment string) (*appsv1.Deployment, error) {
 	d := &appsv1.Deployment{}
-	err := h.Get(ctx, client.ObjectKey{
+	err := reader.Get(ctx, client.ObjectKey{
 		Namespace: namespace,
 		Name:      deployment,
 	}, d)
 	if err != nil {
-		klog.Errorf("Error getting deployment %s in namespace %s: %v", deployment, namespace, err)
+		klog.FromContext(ctx).Error(err, "Error getting deployment", "namespace", namespace, "deployment", deployment)
 		return nil, err
 	}
 	return d, nil
@@ -1,19 +1,33 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/admission"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/bounds"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/ownership"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/projection"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
 	"k8s.io/utils/ptr"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 // newHttpTestRequest creates a new http.Request for testing purposes
@@ -27,6 +41,35 @@ func newResponseRecorder() *httptest.ResponseRecorder {
 	return httptest.NewRecorder()
 }
 
+// assertMatchesSchema checks that the JSON response body matches the named schema, or the operation / error schemas for
+// 202 / non 2xx responses
+func assertMatchesSchema(t *testing.T, name string, w *httptest.ResponseRecorder) {
+	t.Helper()
+	switch {
+	case w.Code == http.StatusAccepted:
+		name = schema.Operation
+	case w.Code < 200 || w.Code > 299:
+		name = schema.Error
+		if w.Body.Len() > 0 && w.Header().Get("Content-Type") != problem.ContentType {
+			t.Errorf("Content-Type = %q, want %q", w.Header().Get("Content-Type"), problem.ContentType)
+		}
+	}
+	// Only the JSON responses have a schema, e.g. not the lists exported as CSV
+	if w.Body.Len() == 0 || strings.HasPrefix(w.Header().Get("Content-Type"), ContentTypeCSV) {
+		return
+	}
+	if err := schema.Validate(name, w.Body.Bytes()); err != nil {
+		t.Errorf("response body doesn't match the %s schema: %v", name, err)
+	}
+}
+
+// errorBody returns the response body of the given message of the catalog, so that the tests assert on the code and
+// parameters of the message rather than on its English sentence
+func errorBody(status int, code messages.Code, params ...string) string {
+	body, _ := json.Marshal(NewAPIError(httptest.NewRecorder(), status, messages.New(code, params...)))
+	return string(body) + "\n"
+}
+
 func TestDeploymentsHandler_ListDeployments(t *testing.T) {
 	testScheme := runtime.NewScheme()
 	_ = appsv1.AddToScheme(testScheme) // Register apps/v1 types
@@ -87,11 +130,28 @@ func TestDeploymentsHandler_ListDeployments(t *testing.T) {
 			},
 			"[{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\"}]\n",
 		},
+		{
+			"Test ListDeployments With Meta",
+			fields{
+				Client: fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-deployment",
+						Namespace: "test-namespace",
+					},
+				}).Build(),
+			},
+			args{
+				w: newResponseRecorder(),
+				r: newHttpTestRequest("GET", "/deployments?meta=true", nil),
+			},
+			"{\"items\":[{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\"}],\"meta\":{\"dataSource\":\"cache\",\"cacheLastSync\":\"2026-10-18T12:00:00Z\"}}\n",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			h := &DeploymentsHandler{
-				Client: tt.fields.Client,
+				Client:        tt.fields.Client,
+				CacheLastSync: func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) },
 			}
 			h.ListDeployments(tt.args.w, tt.args.r)
 
@@ -105,6 +165,7 @@ func TestDeploymentsHandler_ListDeployments(t *testing.T) {
 			if rb != tt.expectedResponse {
 				t.Errorf("ListDeployments() response body = %v, want %v", rb, tt.expectedResponse)
 			}
+			assertMatchesSchema(t, schema.DeploymentsResponse, tt.args.w.(*httptest.ResponseRecorder))
 		})
 	}
 }
@@ -144,7 +205,7 @@ func TestDeploymentsHandler_GetDeploymentReplicas(t *testing.T) {
 				r: newHttpTestRequest("GET", "/deployments/test-namespace/test-deployment/replicas", nil),
 			},
 			http.StatusOK,
-			"{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\",\"replicas\":3}\n",
+			"{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\",\"replicas\":3,\"meta\":{\"dataSource\":\"cache\"}}\n",
 		},
 		{
 			"Test GetDeploymentReplicas Not Found",
@@ -156,7 +217,7 @@ func TestDeploymentsHandler_GetDeploymentReplicas(t *testing.T) {
 				r: newHttpTestRequest("GET", "/deployments/foo/bar/replicas", nil),
 			},
 			http.StatusNotFound,
-			"{\"message\":\"Error getting deployment bar in namespace foo\"}\n",
+			errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "foo", "name", "bar"),
 		},
 	}
 	for _, tt := range tests {
@@ -176,6 +237,7 @@ func TestDeploymentsHandler_GetDeploymentReplicas(t *testing.T) {
 			if rb != tt.expectedResponse {
 				t.Errorf("GetDeploymentReplicas() response body = %v, want %v", rb, tt.expectedResponse)
 			}
+			assertMatchesSchema(t, schema.ReplicasResponse, tt.args.w.(*httptest.ResponseRecorder))
 		})
 	}
 }
@@ -183,6 +245,10 @@ func TestDeploymentsHandler_GetDeploymentReplicas(t *testing.T) {
 func TestDeploymentsHandler_SetDeploymentReplicas(t *testing.T) {
 	testScheme := runtime.NewScheme()
 	_ = appsv1.AddToScheme(testScheme) // Register apps/v1 types
+	chain, err := admission.NewChain([]string{admission.ReplicaBoundsPlugin}, admission.Options{})
+	if err != nil {
+		t.Fatalf("failed to create admission chain: %v", err)
+	}
 	type fields struct {
 		Client client.Client
 	}
@@ -235,7 +301,7 @@ func TestDeploymentsHandler_SetDeploymentReplicas(t *testing.T) {
 				r: newHttpTestRequest("PUT", "/deployments/foo/bar/replicas", strings.NewReader("{\"replicas\":99}")),
 			},
 			http.StatusNotFound,
-			"{\"message\":\"Error getting deployment bar in namespace foo\"}\n",
+			errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "foo", "name", "bar"),
 		},
 		{
 			"Test SetDeploymentReplicas Bad Request - typo in request body",
@@ -255,7 +321,7 @@ func TestDeploymentsHandler_SetDeploymentReplicas(t *testing.T) {
 				r: newHttpTestRequest("PUT", "/deployments/foo/bar/replicas", strings.NewReader("{\"replikas\":99}")),
 			},
 			http.StatusBadRequest,
-			"{\"message\":\"Validation error: replicas field is required\"}\n",
+			errorBody(http.StatusBadRequest, messages.InvalidRequestBody, "reason", "unknown field \"replikas\" at offset 1"),
 		},
 		{
 			"Test SetDeploymentReplicas Bad Request - negative replicas",
@@ -275,13 +341,37 @@ func TestDeploymentsHandler_SetDeploymentReplicas(t *testing.T) {
 				r: newHttpTestRequest("PUT", "/deployments/foo/bar/replicas", strings.NewReader("{\"replicas\":-1}")),
 			},
 			http.StatusBadRequest,
-			"{\"message\":\"Validation error: replicas field must be greater than or equal to 0\"}\n",
+			errorBody(http.StatusBadRequest, messages.ValidationFailed, "reason", "replicas field must be greater than or equal to 0"),
+		},
+		{
+			"Test SetDeploymentReplicas Unprocessable Entity - outside of the replica bounds",
+			fields{
+				Client: fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "bar",
+						Namespace:   "foo",
+						Annotations: map[string]string{bounds.MinReplicasAnnotation: "2", bounds.MaxReplicasAnnotation: "5"},
+					},
+					Spec: appsv1.DeploymentSpec{
+						Replicas: ptr.To(int32(3)),
+					},
+				}).Build(),
+			},
+			args{
+				w: newResponseRecorder(),
+				r: newHttpTestRequest("PUT", "/deployments/foo/bar/replicas", strings.NewReader("{\"replicas\":0}")),
+			},
+			http.StatusUnprocessableEntity,
+			"{\"type\":\"urn:go-k8s-http-api:problem:rejected\",\"title\":\"Unprocessable Entity\",\"status\":422," +
+				"\"detail\":\"Replicas must be within the bounds [2, 5] of deployment bar in namespace foo\"," +
+				"\"message\":\"Replicas must be within the bounds [2, 5] of deployment bar in namespace foo\",\"violations\":[{\"plugin\":\"ReplicaBounds\",\"object\":\"Deployment foo/bar\",\"field\":\"spec.replicas\",\"message\":\"Replicas must be within the bounds [2, 5] of deployment bar in namespace foo\"}]}\n",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			h := &DeploymentsHandler{
-				Client: tt.fields.Client,
+				Client:    tt.fields.Client,
+				Admission: chain,
 			}
 			h.SetDeploymentReplicas(tt.args.w, tt.args.r)
 
@@ -295,6 +385,600 @@ func TestDeploymentsHandler_SetDeploymentReplicas(t *testing.T) {
 			if rb != tt.expectedResponse {
 				t.Errorf("SetDeploymentReplicas() response body = %v, want %v", rb, tt.expectedResponse)
 			}
+			assertMatchesSchema(t, schema.ReplicasResponse, tt.args.w.(*httptest.ResponseRecorder))
+		})
+	}
+}
+
+func TestDeploymentsHandler_SetDeploymentReplicas_GitOps(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme) // Register apps/v1 types
+
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "bar",
+			Namespace:   "foo",
+			Annotations: map[string]string{admission.ArgoCDTrackingIDAnnotation: "shop:apps/Deployment:foo/bar"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(int32(3)),
+		},
+	}
+	message := "Deployment foo/bar is managed by the Argo CD application shop, which may revert this change on its next sync unless it's also made in Git"
+	tests := []struct {
+		name             string
+		mode             string
+		expectedStatus   int
+		expectedWarning  string
+		expectedResponse string
+	}{
+		{"Test SetDeploymentReplicas GitOps Warn", admission.GitOpsWarn, http.StatusOK, "299 - \"" + message + "\"",
+			"{\"name\":\"bar\",\"namespace\":\"foo\",\"replicas\":5,\"warnings\":[\"" + message + "\"]}\n"},
+		{"Test SetDeploymentReplicas GitOps Reject", admission.GitOpsReject, http.StatusUnprocessableEntity, "",
+			"{\"type\":\"urn:go-k8s-http-api:problem:rejected\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"" + message + "\"," +
+				"\"message\":\"" + message + "\",\"violations\":[{\"plugin\":\"GitOps\",\"object\":\"Deployment foo/bar\",\"message\":\"" + message + "\"}]}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := admission.NewChain([]string{admission.GitOpsPlugin}, admission.Options{GitOpsMode: tt.mode})
+			if err != nil {
+				t.Fatalf("NewChain() error = %v", err)
+			}
+			h := &DeploymentsHandler{
+				Client:    fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(d.DeepCopy()).Build(),
+				Admission: chain,
+			}
+			w := newResponseRecorder()
+			h.SetDeploymentReplicas(w, newHttpTestRequest("PUT", "/deployments/foo/bar/replicas", strings.NewReader("{\"replicas\":5}")))
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("SetDeploymentReplicas() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if warning := w.Header().Get("Warning"); warning != tt.expectedWarning {
+				t.Errorf("SetDeploymentReplicas() Warning header = %q, want %q", warning, tt.expectedWarning)
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("SetDeploymentReplicas() response body = %v, want %v", rb, tt.expectedResponse)
+			}
+		})
+	}
+}
+
+func TestDeploymentsHandler_DataSource(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme) // Register apps/v1 types
+
+	// The cached client holds a stale replica count, while the live reader holds the up to date one
+	newDeployment := func(replicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-deployment",
+				Namespace: "test-namespace",
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To(replicas),
+			},
+		}
+	}
+	cachedClient := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(newDeployment(3)).Build()
+	liveReader := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(newDeployment(7)).Build()
+	notCachedClient := fake.NewClientBuilder().WithScheme(testScheme).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, client client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			return &cache.ErrResourceNotCached{}
+		},
+	}).Build()
+
+	lastSync := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		client           client.Client
+		cacheSynced      bool
+		url              string
+		expectedStatus   int
+		expectedSource   string
+		expectedResponse string
+		// apiServerUnavailable is whether the circuit breaker is open
+		apiServerUnavailable bool
+		expectedStale        string
+	}{
+		{
+			"Test GetDeploymentReplicas From Cache",
+			cachedClient,
+			true,
+			"/deployments/test-namespace/test-deployment/replicas",
+			http.StatusOK,
+			DataSourceCache,
+			"{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\",\"replicas\":3,\"meta\":{\"dataSource\":\"cache\",\"cacheLastSync\":\"2026-10-18T12:00:00Z\"}}\n",
+			false,
+			"",
+		},
+		{
+			"Test GetDeploymentReplicas Explicitly From Cache",
+			cachedClient,
+			true,
+			"/deployments/test-namespace/test-deployment/replicas?cache=true",
+			http.StatusOK,
+			DataSourceCache,
+			"{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\",\"replicas\":3,\"meta\":{\"dataSource\":\"cache\",\"cacheLastSync\":\"2026-10-18T12:00:00Z\"}}\n",
+			false,
+			"",
+		},
+		{
+			"Test GetDeploymentReplicas Bypass Cache",
+			cachedClient,
+			true,
+			"/deployments/test-namespace/test-deployment/replicas?cache=false",
+			http.StatusOK,
+			DataSourceLive,
+			"{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\",\"replicas\":7,\"meta\":{\"dataSource\":\"live\"}}\n",
+			false,
+			"",
+		},
+		{
+			"Test GetDeploymentReplicas Cache Not Synced",
+			cachedClient,
+			false,
+			"/deployments/test-namespace/test-deployment/replicas",
+			http.StatusOK,
+			DataSourceLive,
+			"{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\",\"replicas\":7,\"meta\":{\"dataSource\":\"live\"}}\n",
+			false,
+			"",
+		},
+		{
+			"Test GetDeploymentReplicas Resource Not Cached",
+			notCachedClient,
+			true,
+			"/deployments/test-namespace/test-deployment/replicas",
+			http.StatusOK,
+			DataSourceLive,
+			"{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\",\"replicas\":7,\"meta\":{\"dataSource\":\"live\"}}\n",
+			false,
+			"",
+		},
+		{
+			"Test GetDeploymentReplicas Invalid Cache Value",
+			cachedClient,
+			true,
+			"/deployments/test-namespace/test-deployment/replicas?cache=nope",
+			http.StatusBadRequest,
+			"",
+			errorBody(http.StatusBadRequest, messages.InvalidBooleanParameter, "parameter", "cache", "value", "nope"),
+			false,
+			"",
+		},
+		{
+			"Test GetDeploymentReplicas API Server Unavailable",
+			cachedClient,
+			true,
+			"/deployments/test-namespace/test-deployment/replicas",
+			http.StatusOK,
+			DataSourceCache,
+			"{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\",\"replicas\":3,\"meta\":{\"dataSource\":\"cache\",\"cacheLastSync\":\"2026-10-18T12:00:00Z\",\"stale\":true}}\n",
+			true,
+			"true",
+		},
+		{
+			"Test GetDeploymentReplicas Bypass Cache API Server Unavailable",
+			cachedClient,
+			true,
+			"/deployments/test-namespace/test-deployment/replicas?cache=false",
+			http.StatusOK,
+			DataSourceCache,
+			"{\"name\":\"test-deployment\",\"namespace\":\"test-namespace\",\"replicas\":3,\"meta\":{\"dataSource\":\"cache\",\"cacheLastSync\":\"2026-10-18T12:00:00Z\",\"stale\":true}}\n",
+			true,
+			"true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &DeploymentsHandler{
+				Client:               tt.client,
+				LiveReader:           liveReader,
+				CacheSynced:          func() bool { return tt.cacheSynced },
+				APIServerUnavailable: func() bool { return tt.apiServerUnavailable },
+				CacheLastSync:        func() time.Time { return lastSync },
+			}
+			w := newResponseRecorder()
+			h.GetDeploymentReplicas(w, newHttpTestRequest("GET", tt.url, nil))
+
+			// Check the response status code
+			if w.Code != tt.expectedStatus {
+				t.Errorf("GetDeploymentReplicas() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+
+			// Check the data source header
+			if source := w.Header().Get(DataSourceHeader); source != tt.expectedSource {
+				t.Errorf("GetDeploymentReplicas() data source = %v, want %v", source, tt.expectedSource)
+			}
+			if stale := w.Header().Get(StaleHeader); stale != tt.expectedStale {
+				t.Errorf("GetDeploymentReplicas() stale = %v, want %v", stale, tt.expectedStale)
+			}
+
+			// Check the response body
+			rb := w.Body.String()
+			if rb != tt.expectedResponse {
+				t.Errorf("GetDeploymentReplicas() response body = %v, want %v", rb, tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.ReplicasResponse, w)
+		})
+	}
+}
+
+func TestDeploymentsHandler_NamespaceScopedRoutes(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	h := &DeploymentsHandler{Client: fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "team-a"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(2))},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "team-b"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(5))},
+		},
+	).Build()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /namespaces/{namespace}/deployments", h.ListDeployments)
+	mux.HandleFunc("GET /namespaces/{namespace}/deployments/{deployment}/replicas", h.GetDeploymentReplicas)
+
+	tests := []struct {
+		name             string
+		url              string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			"Test ListDeployments scoped to the namespace",
+			"/namespaces/team-a/deployments",
+			http.StatusOK,
+			"[{\"name\":\"foo\",\"namespace\":\"team-a\"}]\n",
+		},
+		{
+			"Test ListDeployments ignores the namespace query parameter",
+			"/namespaces/team-a/deployments?namespace=team-b",
+			http.StatusOK,
+			"[{\"name\":\"foo\",\"namespace\":\"team-a\"}]\n",
+		},
+		{
+			"Test GetDeploymentReplicas scoped to the namespace",
+			"/namespaces/team-a/deployments/foo/replicas",
+			http.StatusOK,
+			"{\"name\":\"foo\",\"namespace\":\"team-a\",\"replicas\":2,\"meta\":{\"dataSource\":\"cache\"}}\n",
+		},
+		{
+			"Test GetDeploymentReplicas of a deployment in another namespace",
+			"/namespaces/team-a/deployments/bar/replicas",
+			http.StatusNotFound,
+			errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "team-a", "name", "bar"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			mux.ServeHTTP(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("response body = %v, want %v", rb, tt.expectedResponse)
+			}
+		})
+	}
+}
+
+func TestDeploymentsHandler_ListDeploymentsTenancy(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	h := &DeploymentsHandler{Client: fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "team-a"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "team-b"}},
+	).Build()}
+
+	tests := []struct {
+		name             string
+		scope            tenancy.Scope
+		url              string
+		expectedResponse string
+	}{
+		{
+			"Test ListDeployments filtered to the tenant's namespaces",
+			tenancy.Scope{Namespaces: map[string]bool{"team-a": true}},
+			"/deployments",
+			"[{\"name\":\"foo\",\"namespace\":\"team-a\"}]\n",
+		},
+		{
+			"Test ListDeployments in a namespace outside of the tenant's namespaces",
+			tenancy.Scope{Namespaces: map[string]bool{"team-a": true}},
+			"/deployments?namespace=team-b",
+			"[]\n",
+		},
+		{
+			"Test ListDeployments with access to all namespaces",
+			tenancy.Scope{All: true},
+			"/deployments",
+			"[{\"name\":\"foo\",\"namespace\":\"team-a\"},{\"name\":\"bar\",\"namespace\":\"team-b\"}]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			r := newHttpTestRequest("GET", tt.url, nil)
+			h.ListDeployments(w, r.WithContext(tenancy.WithScope(r.Context(), tt.scope)))
+			if w.Code != http.StatusOK {
+				t.Errorf("ListDeployments() status code = %v, want %v", w.Code, http.StatusOK)
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("ListDeployments() response body = %v, want %v", rb, tt.expectedResponse)
+			}
+		})
+	}
+}
+
+// fakeSummaryLister is a SummaryLister serving the given summaries
+type fakeSummaryLister struct {
+	synced    bool
+	summaries []*projection.Summary
+}
+
+func (f *fakeSummaryLister) Synced() bool {
+	return f.synced
+}
+
+func (f *fakeSummaryLister) List(namespace string) []*projection.Summary {
+	var summaries []*projection.Summary
+	for _, s := range f.summaries {
+		if namespace == "" || s.Namespace == namespace {
+			summaries = append(summaries, s)
+		}
+	}
+	return summaries
+}
+
+func TestDeploymentsHandler_ListDeploymentsCSV(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	created := metav1.NewTime(time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC))
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-b", CreationTimestamp: created},
+			Spec: appsv1.DeploymentSpec{Replicas: ptr.To[int32](3), Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "migrate", Image: "web:1.2"}},
+				Containers:     []corev1.Container{{Name: "web", Image: "web:1.2"}, {Name: "proxy", Image: "envoy:1.30"}},
+			}}},
+		},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team-a", CreationTimestamp: created}},
+	).Build()
+	// The summaries have none of the exported columns, so the rows are never listed from them
+	summaries := &fakeSummaryLister{synced: true, summaries: []*projection.Summary{{Namespace: "team-a", Name: "summary"}}}
+	header := "namespace,name,replicas,images,created\r\n"
+
+	tests := []struct {
+		name             string
+		url              string
+		accept           string
+		v1               bool
+		scope            *tenancy.Scope
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			"Test ListDeployments As CSV",
+			"/deployments?format=csv",
+			"",
+			false,
+			nil,
+			http.StatusOK,
+			header + "team-a,api,1,,2026-10-01T09:30:00Z\r\nteam-b,web,3,web:1.2 envoy:1.30,2026-10-01T09:30:00Z\r\n",
+		},
+		{
+			"Test ListDeployments As CSV From The Accept Header",
+			"/deployments?namespace=team-b",
+			"text/csv, application/json;q=0.5",
+			false,
+			nil,
+			http.StatusOK,
+			header + "team-b,web,3,web:1.2 envoy:1.30,2026-10-01T09:30:00Z\r\n",
+		},
+		{
+			"Test ListDeployments As CSV Filtered To The Tenant's Namespaces, Without Envelope In The Versioned API",
+			"/deployments?format=csv",
+			"",
+			true,
+			&tenancy.Scope{Namespaces: map[string]bool{"team-a": true}},
+			http.StatusOK,
+			header + "team-a,api,1,,2026-10-01T09:30:00Z\r\n",
+		},
+		{
+			"Test ListDeployments Invalid Format",
+			"/deployments?format=xlsx",
+			"",
+			false,
+			nil,
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.UnsupportedParameterValue, "parameter", "format", "value", "xlsx", "allowed", "json, csv"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &DeploymentsHandler{Client: c, Summaries: summaries}
+			w := newResponseRecorder()
+			r := newHttpTestRequest("GET", tt.url, nil)
+			if tt.v1 {
+				r = newV1TestRequest("GET", tt.url)
+			}
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if tt.scope != nil {
+				r = r.WithContext(tenancy.WithScope(r.Context(), *tt.scope))
+			}
+			h.ListDeployments(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("ListDeployments() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("ListDeployments() response body = %q, want %q", rb, tt.expectedResponse)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				if contentType := w.Header().Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+					t.Errorf("ListDeployments() content type = %v, want text/csv", contentType)
+				}
+				if disposition := w.Header().Get("Content-Disposition"); disposition != "attachment; filename=deployments.csv" {
+					t.Errorf("ListDeployments() content disposition = %v", disposition)
+				}
+			}
+		})
+	}
+}
+
+func TestDeploymentsHandler_ListDeploymentsColumns(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a", CreationTimestamp: metav1.NewTime(now.Add(-76 * time.Hour))},
+			Spec: appsv1.DeploymentSpec{Replicas: ptr.To[int32](3), Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "web", Image: "web:1.2"}},
+			}}},
+			Status: appsv1.DeploymentStatus{ReadyReplicas: 2},
+		},
+	).Build()
+	// The summaries have none of the computed columns, so the rows are never listed from them
+	summaries := &fakeSummaryLister{synced: true, summaries: []*projection.Summary{{Namespace: "team-a", Name: "summary"}}}
+
+	tests := []struct {
+		name             string
+		r                *http.Request
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			"Test ListDeployments Columns",
+			newHttpTestRequest("GET", "/deployments?columns=name,replicas,readyRatio,images,age", nil),
+			http.StatusOK,
+			"[{\"name\":\"web\",\"replicas\":3,\"readyRatio\":\"2/3\",\"images\":[\"web:1.2\"],\"age\":\"3d4h\"}]\n",
+		},
+		{
+			"Test ListDeployments Columns In The Versioned API",
+			newV1TestRequest("GET", "/deployments?columns=namespace,name,created"),
+			http.StatusOK,
+			"{\"items\":[{\"namespace\":\"team-a\",\"name\":\"web\",\"created\":\"2026-10-15T08:00:00Z\"}],\"metadata\":{\"count\":1,\"dataSource\":\"cache\"}}\n",
+		},
+		{
+			"Test ListDeployments Columns As CSV",
+			newHttpTestRequest("GET", "/deployments?format=csv&columns=name,age,name", nil),
+			http.StatusOK,
+			"name,age\r\nweb,3d4h\r\n",
+		},
+		{
+			"Test ListDeployments Unknown Column",
+			newHttpTestRequest("GET", "/deployments?columns=name,owner", nil),
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.UnsupportedParameterValue, "parameter", "columns", "value", "owner", "allowed", strings.Join(deploymentColumnNames, ", ")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &DeploymentsHandler{Client: c, Summaries: summaries, now: func() time.Time { return now }}
+			w := newResponseRecorder()
+			h.ListDeployments(w, tt.r)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("ListDeployments() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("ListDeployments() response body = %v, want %v", rb, tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.DeploymentsResponse, w)
+		})
+	}
+}
+
+func TestDeploymentsHandler_ListDeploymentsSummaries(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	// The summaries differ from the objects held by the clients, to tell which one the deployments were listed from
+	cachedClient := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "cached", Namespace: "team-a", Annotations: map[string]string{ownership.TeamAnnotation: "a"}}},
+	).Build()
+	liveReader := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "live", Namespace: "team-a"}},
+	).Build()
+	summaries := []*projection.Summary{{Namespace: "team-a", Name: "summary"}, {Namespace: "team-b", Name: "summary", Ownership: &ownership.Ownership{Team: "b"}}}
+
+	tests := []struct {
+		name             string
+		summariesSynced  bool
+		url              string
+		scope            *tenancy.Scope
+		expectedSource   string
+		expectedResponse string
+	}{
+		{
+			"Test ListDeployments from the summaries",
+			true,
+			"/deployments",
+			nil,
+			DataSourceCache,
+			"[{\"name\":\"summary\",\"namespace\":\"team-a\"},{\"name\":\"summary\",\"namespace\":\"team-b\",\"ownership\":{\"team\":\"b\"}}]\n",
+		},
+		{
+			"Test ListDeployments in a namespace from the summaries",
+			true,
+			"/deployments?namespace=team-b",
+			nil,
+			DataSourceCache,
+			"[{\"name\":\"summary\",\"namespace\":\"team-b\",\"ownership\":{\"team\":\"b\"}}]\n",
+		},
+		{
+			"Test ListDeployments from the summaries filtered to the tenant's namespaces",
+			true,
+			"/deployments",
+			&tenancy.Scope{Namespaces: map[string]bool{"team-b": true}},
+			DataSourceCache,
+			"[{\"name\":\"summary\",\"namespace\":\"team-b\",\"ownership\":{\"team\":\"b\"}}]\n",
+		},
+		{
+			"Test ListDeployments from the cache before the summaries synced",
+			false,
+			"/deployments",
+			nil,
+			DataSourceCache,
+			"[{\"name\":\"cached\",\"namespace\":\"team-a\",\"ownership\":{\"team\":\"a\"}}]\n",
+		},
+		{
+			"Test ListDeployments bypassing the cache",
+			true,
+			"/deployments?cache=false",
+			nil,
+			DataSourceLive,
+			"[{\"name\":\"live\",\"namespace\":\"team-a\"}]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &DeploymentsHandler{
+				Client:     cachedClient,
+				LiveReader: liveReader,
+				Summaries:  &fakeSummaryLister{synced: tt.summariesSynced, summaries: summaries},
+			}
+			w := newResponseRecorder()
+			r := newHttpTestRequest("GET", tt.url, nil)
+			if tt.scope != nil {
+				r = r.WithContext(tenancy.WithScope(r.Context(), *tt.scope))
+			}
+			h.ListDeployments(w, r)
+			if w.Code != http.StatusOK {
+				t.Errorf("ListDeployments() status code = %v, want %v", w.Code, http.StatusOK)
+			}
+			if source := w.Header().Get(DataSourceHeader); source != tt.expectedSource {
+				t.Errorf("ListDeployments() data source = %v, want %v", source, tt.expectedSource)
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("ListDeployments() response body = %v, want %v", rb, tt.expectedResponse)
+			}
 		})
 	}
 }
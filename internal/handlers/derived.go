@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// unknownAge is the age of the objects without a creation time, as reported by kubectl
+const unknownAge = "<unknown>"
+
+// age returns the time since the creation of an object as of now, rounded like kubectl does, e.g. 3d4h
+func age(created metav1.Time, now time.Time) string {
+	if created.IsZero() {
+		return unknownAge
+	}
+	return duration.HumanDuration(now.Sub(created.Time))
+}
+
+// readyRatio returns the ready replicas out of the desired ones, e.g. 3/5, like the READY column of kubectl
+func readyRatio(ready, desired int32) string {
+	return fmt.Sprintf("%d/%d", ready, desired)
+}
+
+// ContainerImage is the image of a container, along with its short name
+type ContainerImage struct {
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	// ShortName is the image stripped of its registry and repository path, e.g. foo:1.4.2 for
+	// registry.example.com/team/foo:1.4.2
+	ShortName string `json:"shortName"`
+}
+
+// containerImages returns the images of the init containers and containers of the pod template, in their order
+func containerImages(spec *corev1.PodSpec) []ContainerImage {
+	images := []ContainerImage{}
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for _, c := range containers {
+			images = append(images, ContainerImage{Container: c.Name, Image: c.Image, ShortName: imageShortName(c.Image)})
+		}
+	}
+	return images
+}
+
+// imageShortName returns the image stripped of its registry and repository path, and of its digest when it has a tag.
+// The digest of the images pinned by digest only is shortened to 12 characters, as shown by docker, e.g.
+// foo@sha256:0123456789ab.
+func imageShortName(image string) string {
+	name := image[strings.LastIndex(image, "/")+1:]
+	name, digest, pinned := strings.Cut(name, "@")
+	if !pinned || strings.Contains(name, ":") {
+		return name
+	}
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+	return name + "@" + algorithm + ":" + hex
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImageShortName(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"nginx", "nginx"},
+		{"nginx:1.25", "nginx:1.25"},
+		{"registry.example.com/team/foo:1.4.2", "foo:1.4.2"},
+		{"localhost:5000/foo", "foo"},
+		{"registry.example.com/foo:1.4.2@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", "foo:1.4.2"},
+		{"registry.example.com/foo@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", "foo@sha256:0123456789ab"},
+	}
+	for _, tt := range tests {
+		if got := imageShortName(tt.image); got != tt.want {
+			t.Errorf("imageShortName(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestAge(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		created metav1.Time
+		want    string
+	}{
+		{metav1.NewTime(now.Add(-45 * time.Second)), "45s"},
+		{metav1.NewTime(now.Add(-90 * time.Minute)), "90m"},
+		{metav1.NewTime(now.Add(-76 * time.Hour)), "3d4h"},
+		{metav1.NewTime(now.Add(-400 * 24 * time.Hour)), "400d"},
+		{metav1.Time{}, "<unknown>"},
+	}
+	for _, tt := range tests {
+		if got := age(tt.created, now); got != tt.want {
+			t.Errorf("age(%v) = %q, want %q", tt.created, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/diff"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/redaction"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultFieldManager is the field manager of the server-side applies made by the API, unless configured otherwise
+const DefaultFieldManager = "go-k8s-http-api"
+
+// DeploymentSpecChange is the simplified form of a proposed deployment change, accepted by the diff API
+type DeploymentSpecChange struct {
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Images maps container names to their proposed image
+	Images map[string]string `json:"images,omitempty"`
+}
+
+// Validate validates the DeploymentSpecChange object against the deployment it applies to
+func (c *DeploymentSpecChange) Validate(d *appsv1.Deployment) error {
+	if c.Replicas == nil && len(c.Images) == 0 {
+		return fmt.Errorf("at least one of the replicas or images fields is required")
+	}
+	if c.Replicas != nil && *c.Replicas < 0 {
+		return fmt.Errorf("replicas field must be greater than or equal to 0")
+	}
+	for name, image := range c.Images {
+		if image == "" {
+			return fmt.Errorf("image of container %q must not be empty", name)
+		}
+		if containerImage(d, name) == nil {
+			return fmt.Errorf("container %q not found in deployment %s", name, d.Name)
+		}
+	}
+	return nil
+}
+
+// apply applies the change to the deployment
+func (c *DeploymentSpecChange) apply(d *appsv1.Deployment) {
+	if c.Replicas != nil {
+		d.Spec.Replicas = c.Replicas
+	}
+	for name, image := range c.Images {
+		*containerImage(d, name) = image
+	}
+}
+
+// containerImage returns a pointer to the image of the named container (or init container), or nil if not found
+func containerImage(d *appsv1.Deployment, name string) *string {
+	spec := &d.Spec.Template.Spec
+	for i := range spec.InitContainers {
+		if spec.InitContainers[i].Name == name {
+			return &spec.InitContainers[i].Image
+		}
+	}
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == name {
+			return &spec.Containers[i].Image
+		}
+	}
+	return nil
+}
+
+// DeploymentDiffResponse is the response object for the diff API
+type DeploymentDiffResponse struct {
+	DeploymentResponse
+	Changes []diff.Change `json:"changes"`
+}
+
+// DiffDeployment handles the "/deployments/{namespace}/{deployment}/diff" endpoint (and its namespace scoped
+// equivalent) for POST method.
+// The request body is either a full Deployment manifest (JSON, or YAML when sent with a YAML content type), which is
+// server-side applied, or a DeploymentSpecChange, which is patched onto the live object. Either way, the change is
+// evaluated by the API server as a dry-run, so that defaulting, admission and validation are accounted for, and
+// nothing is persisted.
+func (h *DeploymentsHandler) DiffDeployment(w http.ResponseWriter, r *http.Request) {
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	// Diffs are always computed against the object read directly from the API server, since the dry-run is evaluated
+	// against it, while the cache may hold a stale or transformed copy
+	reader := &sourceReader{cache: h.Client, live: h.LiveReader, useLive: h.LiveReader != nil, w: w}
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	body, err := readManifestBody(r)
+	if err != nil {
+		writeBadRequest(w, r, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
+		return
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		writeBadRequest(w, r, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
+		return
+	}
+
+	var proposed client.Object
+	if _, ok := fields["kind"]; ok {
+		proposed, err = h.dryRunApply(r, d, fields)
+	} else {
+		proposed, err = h.dryRunPatch(r, d, body)
+	}
+	if err != nil {
+		writeDryRunError(w, r, namespace, deployment, err)
+		return
+	}
+
+	live, err := toUnstructured(d)
+	if err != nil {
+		logger.Error(err, "Error converting the live deployment")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	result, err := toUnstructured(proposed)
+	if err != nil {
+		logger.Error(err, "Error converting the proposed deployment")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// The sensitive fields are redacted on both sides for the clients which aren't privileged, so that the changes to
+	// them are left out of the diff
+	if policy := redaction.From(r.Context()); policy != nil {
+		policy.Redact(live)
+		policy.Redact(result)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(DeploymentDiffResponse{
+		DeploymentResponse: DeploymentResponse{
+			Name:      deployment,
+			Namespace: namespace,
+		},
+		Changes: append([]diff.Change{}, diff.Objects(live, result)...),
+	})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// dryRunApply server-side applies the manifest onto the live deployment as a dry-run, and returns the resulting object
+func (h *DeploymentsHandler) dryRunApply(r *http.Request, d *appsv1.Deployment, manifest map[string]any) (client.Object, error) {
+	u := &unstructured.Unstructured{Object: manifest}
+	if gvk := u.GroupVersionKind(); gvk != appsv1.SchemeGroupVersion.WithKind("Deployment") {
+		return nil, badRequestError{fmt.Errorf("manifest must be an %s Deployment, got %s %s", appsv1.SchemeGroupVersion, gvk.GroupVersion(), gvk.Kind)}
+	}
+	// The name and namespace may be omitted, but must match the path otherwise
+	if name := u.GetName(); name != "" && name != d.Name {
+		return nil, badRequestError{fmt.Errorf("manifest name %q doesn't match deployment %s", name, d.Name)}
+	}
+	if namespace := u.GetNamespace(); namespace != "" && namespace != d.Namespace {
+		return nil, badRequestError{fmt.Errorf("manifest namespace %q doesn't match namespace %s", namespace, d.Namespace)}
+	}
+	u.SetName(d.Name)
+	u.SetNamespace(d.Namespace)
+	// Exported manifests often carry server populated fields which can't be applied
+	u.SetManagedFields(nil)
+	u.SetResourceVersion("")
+	unstructured.RemoveNestedField(u.Object, "status")
+
+	fieldManager := h.FieldManager
+	if fieldManager == "" {
+		fieldManager = DefaultFieldManager
+	}
+	err := h.Patch(r.Context(), u, client.Apply, client.DryRunAll, client.FieldOwner(fieldManager), client.ForceOwnership)
+	return u, err
+}
+
+// dryRunPatch patches the simplified change onto the live deployment as a dry-run, and returns the resulting object
+func (h *DeploymentsHandler) dryRunPatch(r *http.Request, d *appsv1.Deployment, body []byte) (client.Object, error) {
+	var change DeploymentSpecChange
+	if err := decodeJSON(body, &change); err != nil {
+		return nil, badRequestError{messages.New(messages.InvalidRequestBody, "reason", err.Error())}
+	}
+	if err := change.Validate(d); err != nil {
+		return nil, badRequestError{messages.New(messages.ValidationFailed, "reason", err.Error())}
+	}
+
+	proposed := d.DeepCopy()
+	change.apply(proposed)
+	err := h.Patch(r.Context(), proposed, client.MergeFrom(d), client.DryRunAll)
+	return proposed, err
+}
+
+// badRequestError is an error caused by an invalid request, as opposed to an error returned by the API server
+type badRequestError struct {
+	error
+}
+
+// writeDryRunError writes the response for an error of a dry-run. Requests rejected by the API server (e.g. failing
+// validation or admission) get a 422 Unprocessable Entity with the reason, since they are only rejected because of
+// their content.
+func writeDryRunError(w http.ResponseWriter, r *http.Request, namespace, deployment string, err error) {
+	logger := klog.FromContext(r.Context())
+	if badRequest, ok := err.(badRequestError); ok {
+		writeBadRequest(w, r, badRequest.error)
+		return
+	}
+
+	code, message := http.StatusInternalServerError, messages.New(messages.DeploymentDiffFailed)
+	switch {
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err), apierrors.IsForbidden(err):
+		code, message = http.StatusUnprocessableEntity, messages.New(messages.ChangeRejected, "reason", err.Error())
+	case apierrors.IsNotFound(err):
+		code, message = http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment)
+	case apierrors.IsConflict(err):
+		code, message = http.StatusConflict, messages.New(messages.ChangeConflict, "reason", err.Error())
+	}
+	logger.Error(err, "Error running the dry-run")
+	writeError(w, logger, code, message)
+}
+
+// readManifestBody reads the request body, converting it to JSON if it was sent as YAML
+func readManifestBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %v", err)
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("request body is empty")
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml":
+		return yaml.YAMLToJSON(body)
+	}
+	return body, nil
+}
+
+// toUnstructured converts a typed deployment to its unstructured form, setting its apiVersion and kind, which typed
+// objects read from the API often lack
+func toUnstructured(obj client.Object) (map[string]any, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.Object, nil
+	}
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	object["apiVersion"], object["kind"] = appsv1.SchemeGroupVersion.String(), "Deployment"
+	return object, nil
+}
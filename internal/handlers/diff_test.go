@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/diff"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestDeploymentsHandler_DiffDeployment(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	newDeployment := func() *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To(int32(1)),
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:1"}}},
+				},
+			},
+		}
+	}
+	rejectingPatch := interceptor.Funcs{
+		Patch: func(ctx context.Context, client client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			return apierrors.NewInvalid(runtimeschema.GroupKind{Group: "apps", Kind: "Deployment"}, "bar", field.ErrorList{
+				field.Invalid(field.NewPath("spec", "replicas"), 1, "rejected by a policy"),
+			})
+		},
+	}
+
+	tests := []struct {
+		name            string
+		url             string
+		contentType     string
+		body            string
+		interceptors    *interceptor.Funcs
+		expectedCode    int
+		expectedChanges []diff.Change
+		expectedError   string
+	}{
+		{
+			name:         "simplified spec",
+			url:          "/deployments/foo/bar/diff",
+			body:         `{"replicas": 3, "images": {"app": "app:2"}}`,
+			expectedCode: http.StatusOK,
+			expectedChanges: []diff.Change{
+				{Path: "spec.replicas", Op: diff.OpReplace, Old: float64(1), New: float64(3)},
+				{Path: "spec.template.spec.containers[name=app].image", Op: diff.OpReplace, Old: "app:1", New: "app:2"},
+			},
+		},
+		{
+			name:            "no changes",
+			url:             "/deployments/foo/bar/diff",
+			body:            `{"replicas": 1}`,
+			expectedCode:    http.StatusOK,
+			expectedChanges: []diff.Change{},
+		},
+		{
+			name:          "unknown container",
+			url:           "/deployments/foo/bar/diff",
+			body:          `{"images": {"sidecar": "proxy:2"}}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `Validation error: container "sidecar" not found in deployment bar`,
+		},
+		{
+			name:          "unknown field",
+			url:           "/deployments/foo/bar/diff",
+			body:          `{"replikas": 3}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `Error parsing request body: unknown field "replikas" at offset 1`,
+		},
+		{
+			name:          "manifest of another kind",
+			url:           "/deployments/foo/bar/diff",
+			contentType:   "application/yaml",
+			body:          "apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: bar\n",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "manifest must be an apps/v1 Deployment, got apps/v1 StatefulSet",
+		},
+		{
+			name:          "manifest of another deployment",
+			url:           "/deployments/foo/bar/diff",
+			body:          `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "baz"}}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `manifest name "baz" doesn't match deployment bar`,
+		},
+		{
+			name:          "deployment not found",
+			url:           "/deployments/foo/baz/diff",
+			body:          `{"replicas": 3}`,
+			expectedCode:  http.StatusNotFound,
+			expectedError: "Error getting deployment baz in namespace foo",
+		},
+		{
+			name:          "change rejected by the API server",
+			url:           "/deployments/foo/bar/diff",
+			body:          `{"replicas": 3}`,
+			interceptors:  &rejectingPatch,
+			expectedCode:  http.StatusUnprocessableEntity,
+			expectedError: "Change rejected by the API server",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(newDeployment())
+			if tt.interceptors != nil {
+				builder = builder.WithInterceptorFuncs(*tt.interceptors)
+			}
+			c := builder.Build()
+			h := &DeploymentsHandler{Client: c, LiveReader: c}
+
+			w := newResponseRecorder()
+			r := newHttpTestRequest("POST", tt.url, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			h.DiffDeployment(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("DiffDeployment() status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			assertMatchesSchema(t, schema.DiffResponse, w)
+
+			if tt.expectedError != "" {
+				var apiError APIError
+				if err := json.NewDecoder(w.Body).Decode(&apiError); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if !strings.HasPrefix(apiError.Message, tt.expectedError) {
+					t.Errorf("DiffDeployment() error = %q, want %q", apiError.Message, tt.expectedError)
+				}
+				return
+			}
+			var response struct {
+				Changes []diff.Change `json:"changes"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(response.Changes, tt.expectedChanges) {
+				t.Errorf("DiffDeployment() changes = %#v, want %#v", response.Changes, tt.expectedChanges)
+			}
+
+			// Diffs are dry-runs, so the deployment must be left untouched
+			d := &appsv1.Deployment{}
+			if err := c.Get(context.Background(), client.ObjectKey{Namespace: "foo", Name: "bar"}, d); err != nil {
+				t.Fatalf("failed to get deployment: %v", err)
+			}
+			if *d.Spec.Replicas != 1 || d.Spec.Template.Spec.Containers[0].Image != "app:1" {
+				t.Errorf("DiffDeployment() modified the deployment: %+v", d.Spec)
+			}
+		})
+	}
+}
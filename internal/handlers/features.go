@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/features"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"k8s.io/klog/v2"
+)
+
+// FeaturesHandler is an HTTP handler for the features API.
+// It reports which endpoints are enabled or disabled in the current environment, and lets admins switch them off and
+// back on at runtime.
+type FeaturesHandler struct {
+	Gates *features.Gates
+}
+
+// FeatureSwitch is the request object of the feature switch API
+type FeatureSwitch struct {
+	Serving *bool `json:"serving"`
+}
+
+// Validate validates the FeatureSwitch object and returns an error if it is invalid
+func (s *FeatureSwitch) Validate() error {
+	if s.Serving == nil {
+		return fmt.Errorf("serving field is required")
+	}
+	return nil
+}
+
+func (h *FeaturesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(h.Gates.All())
+	if err != nil {
+		klog.FromContext(r.Context()).Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// ListFeatures handles the "/admin/features" endpoint for GET method, returning whether every feature gate is enabled
+// at startup and served
+func (h *FeaturesHandler) ListFeatures(w http.ResponseWriter, r *http.Request) {
+	writeList(w, r, h.Gates.Statuses(), ListMetadata{})
+}
+
+// SwitchFeature handles the "/admin/features/{name}" endpoint for PUT method, switching the endpoints of the feature
+// gate off, or back on. The change is effective right away, for the requests of this instance.
+func (h *FeaturesHandler) SwitchFeature(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	logger := klog.FromContext(r.Context()).WithValues("feature", name)
+	var s FeatureSwitch
+	if err := decodeJSONBody(r, &s); err != nil {
+		writeBadRequest(w, r, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
+		return
+	}
+	if err := s.Validate(); err != nil {
+		writeBadRequest(w, r, messages.New(messages.ValidationFailed, "reason", err.Error()))
+		return
+	}
+
+	err := h.Gates.Switch(name, *s.Serving)
+	switch {
+	case errors.Is(err, features.ErrUnknown):
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.FeatureNotFound, "name", name))
+		return
+	case errors.Is(err, features.ErrNotRegistered):
+		writeError(w, logger, http.StatusConflict, messages.New(messages.FeatureNotRegistered, "name", name))
+		return
+	case err != nil:
+		logger.Error(err, "Error switching feature gate")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Feature gate switched", "serving", *s.Serving, "identity", auth.Identity(r))
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(features.Status{Name: name, Enabled: true, Serving: *s.Serving}); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
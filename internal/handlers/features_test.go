@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/features"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+)
+
+func TestFeaturesHandler_SwitchFeature(t *testing.T) {
+	tests := []struct {
+		name             string
+		feature          string
+		body             string
+		expectedStatus   int
+		expectedResponse string
+		expectedServing  bool
+	}{
+		{
+			name:             "switched off",
+			feature:          features.Search,
+			body:             `{"serving":false}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"name\":\"Search\",\"enabled\":true,\"serving\":false}\n",
+		},
+		{
+			name:             "switched back on",
+			feature:          features.Search,
+			body:             `{"serving":true}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"name\":\"Search\",\"enabled\":true,\"serving\":true}\n",
+			expectedServing:  true,
+		},
+		{
+			name:             "missing serving",
+			feature:          features.Search,
+			body:             `{}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: errorBody(http.StatusBadRequest, messages.ValidationFailed, "reason", "serving field is required"),
+			expectedServing:  true,
+		},
+		{
+			name:             "disabled at startup",
+			feature:          features.ApplyManifests,
+			body:             `{"serving":true}`,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: errorBody(http.StatusConflict, messages.FeatureNotRegistered, "name", features.ApplyManifests),
+		},
+		{
+			name:             "unknown",
+			feature:          "ExecPod",
+			body:             `{"serving":false}`,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: errorBody(http.StatusNotFound, messages.FeatureNotFound, "name", "ExecPod"),
+		},
+	}
+	h := &FeaturesHandler{Gates: features.NewGates()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			r := newHttpTestRequest("PUT", "/admin/features/"+tt.feature, strings.NewReader(tt.body))
+			r.SetPathValue("name", tt.feature)
+			h.SwitchFeature(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("SwitchFeature() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("SwitchFeature() response body = %v, want %v", rb, tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.FeatureStatus, w)
+			if serving := h.Gates.Serving(tt.feature); serving != tt.expectedServing {
+				t.Errorf("Serving(%s) = %v, want %v", tt.feature, serving, tt.expectedServing)
+			}
+		})
+	}
+
+	// The switched off endpoints are reported by the admin list
+	if err := h.Gates.Switch(features.GetDeployment, false); err != nil {
+		t.Fatalf("Switch() error = %v", err)
+	}
+	w := newResponseRecorder()
+	h.ListFeatures(w, newHttpTestRequest("GET", "/admin/features", nil))
+	assertMatchesSchema(t, schema.FeatureStatuses, w)
+	if !strings.Contains(w.Body.String(), `{"name":"GetDeployment","enabled":true,"serving":false}`) {
+		t.Errorf("ListFeatures() response body = %v, want GetDeployment switched off", w.Body.String())
+	}
+}
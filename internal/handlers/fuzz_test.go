@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// validNames returns whether the namespace and deployment name are valid object names
+func validNames(namespace, deployment string) bool {
+	return len(validation.IsDNS1123Label(namespace)) == 0 && len(validation.IsDNS1123Subdomain(deployment)) == 0
+}
+
+func FuzzParseNamespaceAndDeploymentNameFromURL(f *testing.F) {
+	f.Add("/deployments/foo/bar/replicas", "", "")
+	f.Add("/deployments/foo/bar.baz/health", "", "")
+	f.Add("/deployments/foo", "", "")
+	f.Add("/deployments//bar/replicas", "", "")
+	f.Add("/deployments/Foo/bar/replicas", "", "")
+	f.Add("/deployments/../bar/replicas", "", "")
+	f.Add("", "", "")
+	f.Add("/namespaces/foo/deployments/bar/replicas", "foo", "bar")
+	f.Add("/namespaces/foo/deployments/bar/replicas", "foo", "")
+	f.Add("/namespaces/foo%2Fbaz/deployments/bar/replicas", "foo/baz", "bar")
+
+	f.Fuzz(func(t *testing.T, path, namespaceValue, deploymentValue string) {
+		r := (&http.Request{URL: &url.URL{Path: path}}).WithContext(context.Background())
+		if namespaceValue != "" {
+			r.SetPathValue("namespace", namespaceValue)
+		}
+		if deploymentValue != "" {
+			r.SetPathValue("deployment", deploymentValue)
+		}
+
+		namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+		if err != nil {
+			if namespace != "" || deployment != "" {
+				t.Errorf("parseNamespaceAndDeploymentNameFromURL() = %q, %q along with error %v, want empty names", namespace, deployment, err)
+			}
+			return
+		}
+		if !validNames(namespace, deployment) {
+			t.Errorf("parseNamespaceAndDeploymentNameFromURL() = %q, %q, want valid names or an error", namespace, deployment)
+		}
+	})
+}
+
+func FuzzDecodeReplicas(f *testing.F) {
+	f.Add([]byte(`{"replicas": 3}`))
+	f.Add([]byte(`{"replicas": null}`))
+	f.Add([]byte(`{"replicas": -1}`))
+	f.Add([]byte(`{"replikas": 3}`))
+	f.Add([]byte(`{"replicas": "3"}`))
+	f.Add([]byte(`{"replicas": 3000000000}`))
+	f.Add([]byte(`{"replicas": 3} {"replicas": 4}`))
+	f.Add([]byte(`{"replicas": `))
+	f.Add([]byte(`{"replicas": 3, "foo": 1}`))
+	f.Add([]byte(``))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var replicas Replicas
+		if err := decodeJSON(body, &replicas); err != nil {
+			return
+		}
+
+		// Accepted bodies hold a single JSON value, whose decoded replicas round trip
+		if !json.Valid(bytes.TrimSpace(body)) {
+			t.Errorf("decodeJSON() accepted invalid JSON %q", body)
+		}
+		encoded, err := json.Marshal(replicas)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		var again Replicas
+		if err := decodeJSON(encoded, &again); err != nil || !reflect.DeepEqual(replicas, again) {
+			t.Errorf("decodeJSON(%s) = %+v, %v, want %+v", encoded, again, err, replicas)
+		}
+	})
+}
+
+func FuzzKeyOffset(f *testing.F) {
+	f.Add([]byte(`{"a": 1, "b": 2}`), "b")
+	f.Add([]byte(`{"a": {"c": 1}, "b": 2}`), "b")
+	f.Add([]byte(`[{"a": ["b"]}, {"b": 1}]`), "b")
+	f.Add([]byte(`{"a": 1, "b": 2}`), "b")
+	f.Add([]byte(`{"a": 1`), "b")
+
+	f.Fuzz(func(t *testing.T, document []byte, field string) {
+		offset := keyOffset(document, field)
+		if offset == -1 {
+			return
+		}
+		// The offset points at the opening quote of the key
+		if offset < 0 || offset >= int64(len(document)) || document[offset] != '"' {
+			t.Errorf("keyOffset(%q, %q) = %d, want the offset of a key", document, field, offset)
+		}
+	})
+}
+
+// segment is a path segment generated by testing/quick, biased towards the characters that make paths malformed
+type segment string
+
+func (segment) Generate(rand *rand.Rand, size int) reflect.Value {
+	const alphabet = "abc-.ABC019/%_ "
+	switch rand.Intn(5) {
+	case 0:
+		return reflect.ValueOf(segment(""))
+	case 1:
+		return reflect.ValueOf(segment(".."))
+	}
+	b := make([]byte, rand.Intn(size+1))
+	for i := range b {
+		b[i] = alphabet[rand.Intn(len(alphabet))]
+	}
+	return reflect.ValueOf(segment(b))
+}
+
+func TestRouter_NeverLooksUpInvalidNames(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+
+	var lookups []client.ObjectKey
+	h := &DeploymentsHandler{Client: fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"}},
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			lookups = append(lookups, key)
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}).Build()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /deployments/{namespace}/{deployment}", h.GetDeployment)
+	mux.HandleFunc("GET /deployments/{namespace}/{deployment}/replicas", h.GetDeploymentReplicas)
+	mux.HandleFunc("GET /namespaces/{namespace}/deployments/{deployment}/replicas", h.GetDeploymentReplicas)
+
+	property := func(prefix bool, namespace, deployment segment, suffix bool) bool {
+		path := "/deployments/" + string(namespace) + "/" + string(deployment)
+		if prefix {
+			path = "/namespaces/" + string(namespace) + "/deployments/" + string(deployment)
+		}
+		if suffix || prefix {
+			path += "/replicas"
+		}
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.URL.Path, r.URL.RawPath = path, ""
+
+		lookups = nil
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		for _, key := range lookups {
+			if !validNames(key.Namespace, key.Name) {
+				t.Logf("path %q looked up %v", path, key)
+				return false
+			}
+		}
+		// Paths with dot segments are redirected to their clean equivalent by the mux
+		switch w.Code {
+		case http.StatusOK, http.StatusBadRequest, http.StatusNotFound, http.StatusMovedPermanently, http.StatusTemporaryRedirect:
+			return true
+		}
+		t.Logf("path %q returned %d: %s", path, w.Code, strings.TrimSpace(w.Body.String()))
+		return false
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Overall health statuses of a deployment
+const (
+	// HealthStatusHealthy means none of the pods of the deployment has an issue
+	HealthStatusHealthy = "healthy"
+	// HealthStatusDegraded means some of the pods have issues which don't prevent them from running, e.g. they were
+	// OOMKilled in the past, or are running but not ready
+	HealthStatusDegraded = "degraded"
+	// HealthStatusUnhealthy means some pods can't run at all (e.g. they are crash looping or can't pull their image),
+	// or none of the pods is ready
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// Issues detected on the containers of a deployment, from the most to the least severe
+const (
+	IssueCrashLoopBackOff = "CrashLoopBackOff"
+	IssueImagePullBackOff = "ImagePullBackOff"
+	IssueOOMKilled        = "OOMKilled"
+	IssueNotReady         = "NotReady"
+)
+
+// issueSeverity orders the issues, from the most to the least severe
+var issueSeverity = map[string]int{
+	IssueCrashLoopBackOff: 0,
+	IssueImagePullBackOff: 1,
+	IssueOOMKilled:        2,
+	IssueNotReady:         3,
+}
+
+// Termination is the last termination of a container
+type Termination struct {
+	Reason     string `json:"reason,omitempty"`
+	ExitCode   int32  `json:"exitCode"`
+	Message    string `json:"message,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+}
+
+// ContainerHealth is the health of a single container with issues
+type ContainerHealth struct {
+	Pod          string `json:"pod"`
+	Container    string `json:"container"`
+	Issue        string `json:"issue"`
+	Ready        bool   `json:"ready"`
+	RestartCount int32  `json:"restartCount"`
+	// Message is the reason the container is waiting, e.g. the image pull error
+	Message         string       `json:"message,omitempty"`
+	LastTermination *Termination `json:"lastTermination,omitempty"`
+}
+
+// DeploymentHealthResponse is the response object for the health API
+type DeploymentHealthResponse struct {
+	DeploymentResponse
+	Status    string `json:"status"`
+	Pods      int    `json:"pods"`
+	ReadyPods int    `json:"readyPods"`
+	// ReadyRatio is the ready pods out of the desired replicas of the deployment, e.g. 3/5
+	ReadyRatio string `json:"readyRatio"`
+	Restarts   int32  `json:"restarts"`
+	// Issues counts the containers with each issue
+	Issues map[string]int `json:"issues"`
+	// Containers are the containers with issues, from the most to the least severe
+	Containers []ContainerHealth `json:"containers"`
+}
+
+// GetDeploymentHealth handles the "/deployments/{namespace}/{deployment}/health" endpoint (and its namespace scoped
+// equivalent) for GET method.
+// It inspects the pods of the deployment for crash loops, image pull failures, OOM kills and readiness failures, and
+// returns a triaged summary with the offending containers and their last termination. Pods aren't cached, so they are
+// always listed directly from the API server.
+func (h *DeploymentsHandler) GetDeploymentHealth(w http.ResponseWriter, r *http.Request) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	pods, err := h.listPods(r, d)
+	if err != nil {
+		logger.Error(err, "Error listing pods")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.DeploymentPodsListFailed, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	response := deploymentHealth(d, pods)
+	response.DeploymentResponse = DeploymentResponse{Name: deployment, Namespace: namespace}
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// listPods lists the pods of the deployment, i.e. the pods matching its selector which are controlled by one of its
+// ReplicaSets (named after the deployment)
+func (h *DeploymentsHandler) listPods(r *http.Request, d *appsv1.Deployment) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	var reader client.Reader = h.Client
+	if h.LiveReader != nil {
+		reader = h.LiveReader
+	}
+	pods := &corev1.PodList{}
+	if err := reader.List(r.Context(), pods, client.InNamespace(d.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	owned := make([]corev1.Pod, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		owner := metav1.GetControllerOf(&pod)
+		if owner != nil && owner.Kind == "ReplicaSet" && strings.HasPrefix(owner.Name, d.Name+"-") {
+			owned = append(owned, pod)
+		}
+	}
+	return owned, nil
+}
+
+// deploymentHealth triages the health of the deployment from its pods
+func deploymentHealth(d *appsv1.Deployment, pods []corev1.Pod) DeploymentHealthResponse {
+	response := DeploymentHealthResponse{
+		Status:     HealthStatusHealthy,
+		Pods:       len(pods),
+		Issues:     map[string]int{},
+		Containers: []ContainerHealth{},
+	}
+	for _, pod := range pods {
+		if podReady(&pod) {
+			response.ReadyPods++
+		}
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			response.Restarts += status.RestartCount
+			container, ok := containerHealth(status)
+			if !ok {
+				continue
+			}
+			container.Pod = pod.Name
+			response.Issues[container.Issue]++
+			response.Containers = append(response.Containers, container)
+		}
+	}
+	response.ReadyRatio = readyRatio(int32(response.ReadyPods), desiredReplicas(d))
+	sort.SliceStable(response.Containers, func(i, j int) bool {
+		return issueSeverity[response.Containers[i].Issue] < issueSeverity[response.Containers[j].Issue]
+	})
+
+	switch {
+	case response.Issues[IssueCrashLoopBackOff] > 0 || response.Issues[IssueImagePullBackOff] > 0:
+		response.Status = HealthStatusUnhealthy
+	case d.Spec.Replicas != nil && *d.Spec.Replicas > 0 && response.ReadyPods == 0:
+		response.Status = HealthStatusUnhealthy
+	case len(response.Containers) > 0:
+		response.Status = HealthStatusDegraded
+	}
+	return response
+}
+
+// containerHealth returns the health of the container, and whether it has an issue
+func containerHealth(status corev1.ContainerStatus) (ContainerHealth, bool) {
+	container := ContainerHealth{
+		Container:    status.Name,
+		Ready:        status.Ready,
+		RestartCount: status.RestartCount,
+	}
+	if terminated := status.LastTerminationState.Terminated; terminated != nil {
+		container.LastTermination = &Termination{
+			Reason:   terminated.Reason,
+			ExitCode: terminated.ExitCode,
+			Message:  terminated.Message,
+		}
+		if !terminated.FinishedAt.IsZero() {
+			container.LastTermination.FinishedAt = terminated.FinishedAt.UTC().Format(time.RFC3339)
+		}
+	}
+
+	waiting := status.State.Waiting
+	switch {
+	case waiting != nil && waiting.Reason == "CrashLoopBackOff":
+		container.Issue, container.Message = IssueCrashLoopBackOff, waiting.Message
+	case waiting != nil && (waiting.Reason == "ImagePullBackOff" || waiting.Reason == "ErrImagePull"):
+		container.Issue, container.Message = IssueImagePullBackOff, waiting.Message
+	case container.LastTermination != nil && container.LastTermination.Reason == "OOMKilled":
+		container.Issue = IssueOOMKilled
+	case status.State.Terminated != nil && status.State.Terminated.Reason == "OOMKilled":
+		container.Issue = IssueOOMKilled
+	case status.State.Running != nil && !status.Ready:
+		container.Issue = IssueNotReady
+	default:
+		return container, false
+	}
+	return container, true
+}
+
+// podReady returns whether the pod's Ready condition is true
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newPod returns a pod of the bar deployment with the given container statuses
+func newPod(name string, ready bool, statuses ...corev1.ContainerStatus) *corev1.Pod {
+	condition := corev1.ConditionFalse
+	if ready {
+		condition = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "foo",
+			Labels:    map[string]string{"app": "bar"},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "bar-5d4f8c7b9", UID: "1", Controller: ptr.To(true)},
+			},
+		},
+		Status: corev1.PodStatus{
+			Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: condition}},
+			ContainerStatuses: statuses,
+		},
+	}
+}
+
+func TestDeploymentsHandler_GetDeploymentHealth(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	_ = corev1.AddToScheme(testScheme)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(int32(3)),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "bar"}},
+		},
+	}
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	finishedAt := metav1.NewTime(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	oomKilled := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137, FinishedAt: finishedAt}}
+
+	tests := []struct {
+		name             string
+		objects          []client.Object
+		url              string
+		expectedCode     int
+		expectedResponse *DeploymentHealthResponse
+	}{
+		{
+			name: "healthy",
+			objects: []client.Object{
+				newPod("bar-1", true, corev1.ContainerStatus{Name: "app", Ready: true, State: running}),
+				newPod("bar-2", true, corev1.ContainerStatus{Name: "app", Ready: true, State: running, RestartCount: 1}),
+			},
+			url:          "/deployments/foo/bar/health",
+			expectedCode: http.StatusOK,
+			expectedResponse: &DeploymentHealthResponse{
+				DeploymentResponse: DeploymentResponse{Name: "bar", Namespace: "foo"},
+				Status:             HealthStatusHealthy, Pods: 2, ReadyPods: 2, ReadyRatio: "2/3", Restarts: 1,
+				Issues: map[string]int{}, Containers: []ContainerHealth{},
+			},
+		},
+		{
+			name: "degraded",
+			objects: []client.Object{
+				newPod("bar-1", true, corev1.ContainerStatus{Name: "app", Ready: true, State: running, RestartCount: 2, LastTerminationState: oomKilled}),
+				newPod("bar-2", false, corev1.ContainerStatus{Name: "app", Ready: false, State: running}),
+			},
+			url:          "/deployments/foo/bar/health",
+			expectedCode: http.StatusOK,
+			expectedResponse: &DeploymentHealthResponse{
+				DeploymentResponse: DeploymentResponse{Name: "bar", Namespace: "foo"},
+				Status:             HealthStatusDegraded, Pods: 2, ReadyPods: 1, ReadyRatio: "1/3", Restarts: 2,
+				Issues: map[string]int{IssueOOMKilled: 1, IssueNotReady: 1},
+				Containers: []ContainerHealth{
+					{Pod: "bar-1", Container: "app", Issue: IssueOOMKilled, Ready: true, RestartCount: 2,
+						LastTermination: &Termination{Reason: "OOMKilled", ExitCode: 137, FinishedAt: "2024-05-01T10:00:00Z"}},
+					{Pod: "bar-2", Container: "app", Issue: IssueNotReady},
+				},
+			},
+		},
+		{
+			name: "unhealthy",
+			objects: []client.Object{
+				newPod("bar-1", false,
+					corev1.ContainerStatus{Name: "app", Ready: false, State: running},
+					corev1.ContainerStatus{Name: "sidecar", RestartCount: 5,
+						State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 5m0s restarting failed container"}},
+						LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1, Message: "config file not found"}},
+					},
+				),
+				newPod("bar-2", false, corev1.ContainerStatus{Name: "app",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "manifest unknown"}}}),
+				// Pods of other workloads sharing the labels are ignored
+				func() *corev1.Pod {
+					pod := newPod("baz-1", false, corev1.ContainerStatus{Name: "app", State: running})
+					pod.OwnerReferences[0].Name = "baz-6c9d7f8b5"
+					return pod
+				}(),
+			},
+			url:          "/deployments/foo/bar/health",
+			expectedCode: http.StatusOK,
+			expectedResponse: &DeploymentHealthResponse{
+				DeploymentResponse: DeploymentResponse{Name: "bar", Namespace: "foo"},
+				Status:             HealthStatusUnhealthy, Pods: 2, ReadyPods: 0, ReadyRatio: "0/3", Restarts: 5,
+				Issues: map[string]int{IssueCrashLoopBackOff: 1, IssueImagePullBackOff: 1, IssueNotReady: 1},
+				Containers: []ContainerHealth{
+					{Pod: "bar-1", Container: "sidecar", Issue: IssueCrashLoopBackOff, RestartCount: 5, Message: "back-off 5m0s restarting failed container",
+						LastTermination: &Termination{Reason: "Error", ExitCode: 1, Message: "config file not found"}},
+					{Pod: "bar-2", Container: "app", Issue: IssueImagePullBackOff, Message: "manifest unknown"},
+					{Pod: "bar-1", Container: "app", Issue: IssueNotReady},
+				},
+			},
+		},
+		{
+			name:         "deployment not found",
+			url:          "/deployments/foo/baz/health",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(append(tt.objects, deployment.DeepCopy())...).Build()
+			h := &DeploymentsHandler{Client: c}
+
+			w := newResponseRecorder()
+			h.GetDeploymentHealth(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedCode {
+				t.Fatalf("GetDeploymentHealth() status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			assertMatchesSchema(t, schema.HealthResponse, w)
+			if tt.expectedResponse == nil {
+				return
+			}
+
+			var response DeploymentHealthResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(&response, tt.expectedResponse) {
+				t.Errorf("GetDeploymentHealth() = %+v, want %+v", response, *tt.expectedResponse)
+			}
+		})
+	}
+}
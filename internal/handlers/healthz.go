@@ -1,70 +1,151 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync/atomic"
+	"time"
 
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/healthhistory"
 	"k8s.io/client-go/rest"
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
 )
 
 type healthResponse struct {
 	Status string `json:"status"`
+	// CheckedAt is the time of the background probe the response was served from, if any
+	CheckedAt *time.Time `json:"checkedAt,omitempty"`
+	// Checks are the results of the additional checks, only reported with ?verbose
+	Checks map[string]healthzCheckResult `json:"checks,omitempty"`
+}
+
+type healthzCheckResult struct {
+	Healthy bool `json:"healthy"`
+	Details any  `json:"details,omitempty"`
+}
+
+// probeResult is the result of a probe of the API server
+type probeResult struct {
+	code   int
+	status string
+	time   time.Time
 }
 
 // HealthzHandler is an HTTP handler for the healthz API.
+// Once RunProbes is running, it serves the result of the latest background probe, so that the probes of the kubelet
+// don't add to the load of the API server, e.g. during an incident. Until then, every request probes the API server.
 type HealthzHandler struct {
 	Client rest.Interface
+	// History records the result of every probe of the API server, if set
+	History *healthhistory.History
+	// ProbeTimeout is the timeout of the background probes, which defaults to their interval
+	ProbeTimeout time.Duration
+	// Checks are reported along with the API server health by /healthz?verbose, without failing it, e.g. the expiry
+	// of the certificates
+	Checks []ReadyzCheck
+
+	latest atomic.Pointer[probeResult]
 }
 
 func (h *HealthzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Query the /healthz endpoint of the Kubernetes API
-	result := h.Client.Get().AbsPath("/healthz").Do(r.Context())
-	err := result.Error()
+	logger := klog.FromContext(r.Context())
+
+	resp := healthResponse{}
+	var code int
+	if latest := h.latest.Load(); latest != nil {
+		code, resp.Status, resp.CheckedAt = latest.code, latest.status, &latest.time
+	} else {
+		code, resp.Status = h.probe(r.Context())
+	}
+	if r.URL.Query().Has("verbose") && len(h.Checks) > 0 {
+		resp.Checks = make(map[string]healthzCheckResult, len(h.Checks))
+		for _, check := range h.Checks {
+			healthy, details := check.Check()
+			resp.Checks[check.Name] = healthzCheckResult{Healthy: healthy, Details: details}
+		}
+	}
 
 	// Prepare the response header
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encErr := json.NewEncoder(w).Encode(resp)
+	if encErr != nil {
+		logger.Error(encErr, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
 
-	if err != nil {
-		// Send an error response
-		w.WriteHeader(http.StatusServiceUnavailable)
-		encErr := json.NewEncoder(w).Encode(healthResponse{Status: fmt.Sprintf("Error: %v", err)})
-		if encErr != nil {
-			klog.Errorf("Error encoding response: %v", encErr)
-			w.WriteHeader(http.StatusInternalServerError)
+// RunProbes probes the API server right away, and then every interval until the context is cancelled. The result of
+// the latest probe is served by /healthz from then on.
+func (h *HealthzHandler) RunProbes(ctx context.Context, interval time.Duration) {
+	timeout := h.ProbeTimeout
+	if timeout <= 0 {
+		timeout = interval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		code, status := h.probe(probeCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		h.latest.Store(&probeResult{code: code, status: status, time: time.Now().UTC()})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
-		return
+	}
+}
+
+// probe queries the /healthz endpoint of the Kubernetes API, records its result to the history, and returns the
+// status code and status of the response. The probes cancelled by the client going away aren't recorded, unlike the
+// ones timing out.
+func (h *HealthzHandler) probe(ctx context.Context) (int, string) {
+	start := time.Now()
+	code, status := h.check(ctx)
+	if h.History != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		h.History.Record(code == http.StatusOK, status, time.Since(start))
+	}
+	return code, status
+}
+
+func (h *HealthzHandler) check(ctx context.Context) (int, string) {
+	// Query the /healthz endpoint of the Kubernetes API
+	result := h.Client.Get().AbsPath("/healthz").Do(ctx)
+	if err := result.Error(); err != nil {
+		return http.StatusServiceUnavailable, fmt.Sprintf("Error: %v", err)
 	}
 
 	rawResult, err := result.Raw()
 	if err != nil {
-		// Send an error response
-		w.WriteHeader(http.StatusInternalServerError)
-		encErr := json.NewEncoder(w).Encode(healthResponse{Status: fmt.Sprintf("Error reading response: %v", err)})
-		if encErr != nil {
-			klog.Errorf("Error encoding response: %v", encErr)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		return
+		return http.StatusInternalServerError, fmt.Sprintf("Error reading response: %v", err)
 	}
 
 	// Check the health status
 	if string(rawResult) == "ok" {
-		// Send a healthy response
-		w.WriteHeader(http.StatusOK)
-		encErr := json.NewEncoder(w).Encode(healthResponse{Status: "ok"})
-		if encErr != nil {
-			klog.Errorf("Error encoding response: %v", encErr)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-	} else {
-		// Send an unhealthy response
-		w.WriteHeader(http.StatusServiceUnavailable)
-		encErr := json.NewEncoder(w).Encode(healthResponse{Status: fmt.Sprintf("unhealthy: %v", string(rawResult))})
-		if encErr != nil {
-			klog.Errorf("Error encoding response: %v", encErr)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		return http.StatusOK, "ok"
+	}
+	return http.StatusServiceUnavailable, fmt.Sprintf("unhealthy: %v", string(rawResult))
+}
+
+// HealthzHistoryHandler is an HTTP handler for the history of the healthz probes
+type HealthzHistoryHandler struct {
+	History *healthhistory.History
+}
+
+// ServeHTTP handles the "/healthz/history" endpoint. It returns the latest probes of the API server, and whether they
+// are flapping, so that transient API server blips are told from sustained outages.
+func (h *HealthzHistoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(h.History.Report()); err != nil {
+		klog.FromContext(r.Context()).Error(err, "Error encoding response")
 	}
 }
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/healthhistory"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakerest "k8s.io/client-go/rest/fake"
+)
+
+func TestHealthzHandler_History(t *testing.T) {
+	// The API server fails the second probe only
+	probes := 0
+	client := &fakerest.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fakerest.CreateHTTPClient(func(*http.Request) (*http.Response, error) {
+			probes++
+			if probes == 2 {
+				return nil, errors.New("connection refused")
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		}),
+	}
+	history := healthhistory.New(10, 2)
+	h := &HealthzHandler{Client: client, History: history}
+
+	tests := []struct {
+		name           string
+		expectedStatus int
+		expectedState  healthhistory.State
+	}{
+		{"Test Healthy", http.StatusOK, healthhistory.StateHealthy},
+		{"Test Unhealthy", http.StatusServiceUnavailable, healthhistory.StateUnhealthy},
+		{"Test Flapping", http.StatusOK, healthhistory.StateFlapping},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			h.ServeHTTP(w, newHttpTestRequest("GET", "/healthz", nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+
+			w = newResponseRecorder()
+			(&HealthzHistoryHandler{History: history}).ServeHTTP(w, newHttpTestRequest("GET", "/healthz/history", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("history status code = %v, want %v", w.Code, http.StatusOK)
+			}
+			if report := history.Report(); report.State != tt.expectedState {
+				t.Errorf("state = %v, want %v", report.State, tt.expectedState)
+			}
+			if !strings.Contains(w.Body.String(), "\"state\":\""+string(tt.expectedState)+"\"") {
+				t.Errorf("history response body = %v, want state %v", w.Body.String(), tt.expectedState)
+			}
+		})
+	}
+}
+
+func TestHealthzHandler_RunProbes(t *testing.T) {
+	var probes atomic.Int32
+	client := &fakerest.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fakerest.CreateHTTPClient(func(*http.Request) (*http.Response, error) {
+			probes.Add(1)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		}),
+	}
+	h := &HealthzHandler{Client: client}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.RunProbes(ctx, time.Hour)
+
+	// The first probe is made right away
+	deadline := time.Now().Add(5 * time.Second)
+	for h.latest.Load() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the first probe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The requests are served from the latest probe, without probing the API server
+	for range 3 {
+		w := newResponseRecorder()
+		h.ServeHTTP(w, newHttpTestRequest("GET", "/healthz", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("status code = %v, want %v", w.Code, http.StatusOK)
+		}
+		if !strings.Contains(w.Body.String(), "\"checkedAt\"") {
+			t.Errorf("response body = %v, want a checkedAt field", w.Body.String())
+		}
+	}
+	if got := probes.Load(); got != 1 {
+		t.Errorf("probes = %v, want 1", got)
+	}
+}
+
+func TestHealthzHandler_Verbose(t *testing.T) {
+	client := &fakerest.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fakerest.CreateHTTPClient(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		}),
+	}
+	h := &HealthzHandler{Client: client, Checks: []ReadyzCheck{
+		{Name: "certificates", Check: func() (bool, any) { return false, map[string]any{"daysUntilExpiry": 3} }},
+	}}
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedChecks bool
+	}{
+		{"Test Not Verbose", "/healthz", false},
+		{"Test Verbose", "/healthz?verbose", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			h.ServeHTTP(w, newHttpTestRequest("GET", tt.url, nil))
+			// The checks are reported without failing /healthz
+			if w.Code != http.StatusOK {
+				t.Errorf("status code = %v, want %v", w.Code, http.StatusOK)
+			}
+			checks := strings.Contains(w.Body.String(), `"checks":{"certificates":{"healthy":false,"details":{"daysUntilExpiry":3}}}`)
+			if checks != tt.expectedChecks {
+				t.Errorf("response body = %v, want checks %v", w.Body.String(), tt.expectedChecks)
+			}
+		})
+	}
+}
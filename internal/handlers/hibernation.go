@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/hibernation"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/notify"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+)
+
+// HibernationResponse is the response object of the namespace hibernation API
+type HibernationResponse struct {
+	Namespace string                 `json:"namespace"`
+	Workloads []hibernation.Workload `json:"workloads"`
+}
+
+// HibernationHandler scales all the workloads of a namespace to zero, and back
+type HibernationHandler struct {
+	Hibernator *hibernation.Hibernator
+	// Notifier notifies the hibernations to chat channels. They aren't notified when nil.
+	Notifier *notify.Notifier
+}
+
+// Hibernate handles the "/namespaces/{namespace}/hibernate" endpoint for POST method
+func (h *HibernationHandler) Hibernate(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, approvals.OperationHibernateNamespace, "hibernated", h.Hibernator.Hibernate)
+}
+
+// Wake handles the "/namespaces/{namespace}/wake" endpoint for POST method
+func (h *HibernationHandler) Wake(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, approvals.OperationWakeNamespace, "woke up", h.Hibernator.Wake)
+}
+
+// serve changes the workloads of the namespace of the path, and responds with their outcome: a 200 OK if every change
+// succeeded, or a 207 Multi-Status otherwise
+func (h *HibernationHandler) serve(w http.ResponseWriter, r *http.Request, operation, action string,
+	change func(ctx context.Context, identity, namespace string) ([]hibernation.Workload, error)) {
+	namespace := r.PathValue("namespace")
+	if namespace == "" {
+		namespace, _, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, "/namespaces/"), "/")
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		writeBadRequest(w, r, messages.New(messages.InvalidNamespace, "namespace", namespace, "reason", strings.Join(errs, ", ")))
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace)
+
+	workloads, err := change(r.Context(), auth.Identity(r), namespace)
+	if err != nil {
+		logger.Error(err, "Error changing the workloads", "operation", operation)
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.NamespaceWorkloadsListFailed, "namespace", namespace))
+		return
+	}
+
+	code, changed := http.StatusOK, 0
+	for _, workload := range workloads {
+		switch workload.Status {
+		case hibernation.StatusFailed:
+			code = http.StatusMultiStatus
+		case hibernation.StatusHibernated, hibernation.StatusWoken:
+			changed++
+		}
+		addWarnings(w, workload.Warnings)
+	}
+	logger.Info("Changed the workloads of the namespace", "operation", operation, "workloads", len(workloads), "changed", changed)
+	if changed > 0 {
+		h.Notifier.Notify(r.Context(), notify.Change{
+			Identity:  auth.Identity(r),
+			Operation: operation,
+			Action:    action,
+			Kind:      "Namespace",
+			Name:      namespace,
+		})
+	}
+
+	if workloads == nil {
+		workloads = []hibernation.Workload{}
+	}
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(HibernationResponse{Namespace: namespace, Workloads: workloads}); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/admission"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/bounds"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/hibernation"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHibernationHandler(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme) // Register apps/v1 types
+
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "dev"}, Spec: appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "qa", Annotations: map[string]string{bounds.MinReplicasAnnotation: "1"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+		},
+	).Build()
+	chain, err := admission.NewChain([]string{admission.ReplicaBoundsPlugin}, admission.Options{})
+	if err != nil {
+		t.Fatalf("NewChain() error = %v", err)
+	}
+	h := &HibernationHandler{Hibernator: &hibernation.Hibernator{Client: c, Reader: c, Admission: chain}}
+
+	tests := []struct {
+		name             string
+		handler          http.HandlerFunc
+		namespace        string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{"Test Hibernate OK", h.Hibernate, "dev", http.StatusOK,
+			"{\"namespace\":\"dev\",\"workloads\":[{\"kind\":\"Deployment\",\"name\":\"web\",\"replicas\":3,\"status\":\"hibernated\"}]}\n"},
+		{"Test Wake OK", h.Wake, "dev", http.StatusOK,
+			"{\"namespace\":\"dev\",\"workloads\":[{\"kind\":\"Deployment\",\"name\":\"web\",\"replicas\":0,\"status\":\"woken\"}]}\n"},
+		{"Test Wake Nothing Hibernated", h.Wake, "dev", http.StatusOK, "{\"namespace\":\"dev\",\"workloads\":[]}\n"},
+		{"Test Hibernate Multi-Status", h.Hibernate, "qa", http.StatusMultiStatus,
+			"{\"namespace\":\"qa\",\"workloads\":[{\"kind\":\"Deployment\",\"name\":\"web\",\"replicas\":3,\"status\":\"failed\",\"message\":\"Replicas must be within the bounds [1, ∞) of deployment web in namespace qa\"}]}\n"},
+		{"Test Hibernate Bad Request", h.Hibernate, "Invalid_Namespace", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newHttpTestRequest("POST", "/namespaces/"+tt.namespace+"/hibernate", nil)
+			r.SetPathValue("namespace", tt.namespace)
+			w := newResponseRecorder()
+			tt.handler(w, r)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status code = %v, want %v: %s", w.Code, tt.expectedStatus, w.Body.String())
+			}
+			if tt.expectedResponse != "" && w.Body.String() != tt.expectedResponse {
+				t.Errorf("response body = %v, want %v", w.Body.String(), tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.HibernationResponse, w)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/images"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ImagesHandler is the handler for the images API, inventorying the container images of the cached deployments
+type ImagesHandler struct {
+	// Client reads the deployments, from the cache
+	Client client.Reader
+	// Scanner enriches the images with their vulnerability scan results. Images aren't scanned when nil.
+	Scanner *images.Scanner
+}
+
+// ListImages handles the "/images" and "/namespaces/{namespace}/images" endpoints for GET method.
+// It returns the unique images of the deployments, optionally filtered by the namespace query parameter.
+func (h *ImagesHandler) ListImages(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+
+	dl := &appsv1.DeploymentList{}
+	var opts []client.ListOption
+	namespace := r.PathValue("namespace")
+	if namespace == "" {
+		namespace = r.URL.Query().Get("namespace")
+	}
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := h.Client.List(r.Context(), dl, opts...); err != nil {
+		logger.Error(err, "Error listing deployments")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// With tenancy enabled, only the images of the deployments in the caller's namespaces are returned
+	if scope, ok := tenancy.ScopeFrom(r.Context()); ok && !scope.All {
+		dl.Items = slices.DeleteFunc(dl.Items, func(d appsv1.Deployment) bool { return !scope.Allows(d.Namespace) })
+	}
+
+	inventory := images.Inventory(dl.Items)
+	if h.Scanner != nil {
+		h.Scanner.Enrich(r.Context(), inventory)
+	}
+
+	// The deployments are always read from the cache
+	writeList(w, r, inventory, ListMetadata{DataSource: DataSourceCache})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestImagesHandler_ListImages(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	imageDeployment := func(namespace, name string, images ...string) *appsv1.Deployment {
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		for _, image := range images {
+			d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, corev1.Container{Name: "app", Image: image})
+		}
+		return d
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		imageDeployment("team-a", "web", "nginx:1.25"),
+		imageDeployment("team-a", "api", "api:2.0", "envoy:1.30"),
+		imageDeployment("team-b", "web", "nginx:1.25"),
+	).Build()
+	h := &ImagesHandler{Client: c}
+
+	tests := []struct {
+		name             string
+		url              string
+		scope            *tenancy.Scope
+		expectedResponse string
+	}{
+		{
+			"Test ListImages",
+			"/images",
+			nil,
+			"[{\"image\":\"api:2.0\",\"deployments\":1,\"namespaces\":[\"team-a\"]},{\"image\":\"envoy:1.30\",\"deployments\":1,\"namespaces\":[\"team-a\"]},{\"image\":\"nginx:1.25\",\"deployments\":2,\"namespaces\":[\"team-a\",\"team-b\"]}]\n",
+		},
+		{
+			"Test ListImages In Namespace",
+			"/images?namespace=team-b",
+			nil,
+			"[{\"image\":\"nginx:1.25\",\"deployments\":1,\"namespaces\":[\"team-b\"]}]\n",
+		},
+		{
+			"Test ListImages Filtered To The Tenant's Namespaces",
+			"/images",
+			&tenancy.Scope{Namespaces: map[string]bool{"team-b": true}},
+			"[{\"image\":\"nginx:1.25\",\"deployments\":1,\"namespaces\":[\"team-b\"]}]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			r := newHttpTestRequest("GET", tt.url, nil)
+			if tt.scope != nil {
+				r = r.WithContext(tenancy.WithScope(r.Context(), *tt.scope))
+			}
+			h.ListImages(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code = %v, want %v: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("response body = %v, want %v", rb, tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.ImagesResponse, w)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/scheduling"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SchedulingInsightsResponse is the response object for the scheduling insights API
+type SchedulingInsightsResponse struct {
+	// PendingPods is the number of pending pods, including the ones which were scheduled and are e.g. pulling images
+	PendingPods int `json:"pendingPods"`
+	// Causes are the causes of the scheduling failures, from the cause of the most pods to the cause of the fewest
+	Causes []scheduling.Cause `json:"causes"`
+}
+
+// InsightsHandler is the handler for the insights API, explaining what blocks the workloads of the cluster
+type InsightsHandler struct {
+	// LiveReader lists the pods directly from the API server, since they aren't cached
+	LiveReader client.Reader
+}
+
+// GetSchedulingInsights handles the "/insights/scheduling" and "/namespaces/{namespace}/insights/scheduling"
+// endpoints for GET method.
+// It lists the pending pods which failed to be scheduled, optionally filtered by the namespace query parameter, grouped
+// by the cause the scheduler reported in their PodScheduled condition (the same message as their FailedScheduling
+// events), e.g. insufficient CPU, untolerated taints or unsatisfiable affinity rules.
+func (h *InsightsHandler) GetSchedulingInsights(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	if namespace == "" {
+		namespace = r.URL.Query().Get("namespace")
+	}
+	if namespace != "" {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			writeBadRequest(w, r, messages.New(messages.InvalidNamespace, "namespace", namespace, "reason", strings.Join(errs, ", ")))
+			return
+		}
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace)
+
+	// Only the pending pods are listed, which the API server filters on its side
+	pods := &corev1.PodList{}
+	opts := []client.ListOption{client.MatchingFields{"status.phase": string(corev1.PodPending)}}
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := h.LiveReader.List(r.Context(), pods, opts...); err != nil {
+		logger.Error(err, "Error listing pods")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.PendingPodsListFailed))
+		return
+	}
+	// With tenancy enabled, only the pods in the caller's namespaces are returned
+	if scope, ok := tenancy.ScopeFrom(r.Context()); ok && !scope.All {
+		pods.Items = slices.DeleteFunc(pods.Items, func(pod corev1.Pod) bool { return !scope.Allows(pod.Namespace) })
+	}
+
+	w.Header().Set(DataSourceHeader, DataSourceLive)
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(SchedulingInsightsResponse{PendingPods: len(pods.Items), Causes: scheduling.Insights(pods.Items)})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
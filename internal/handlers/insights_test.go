@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInsightsHandler_GetSchedulingInsights(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(testScheme)
+	since := metav1.NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	pod := func(namespace, name string, phase corev1.PodPhase, message string) *corev1.Pod {
+		p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Status: corev1.PodStatus{Phase: phase}}
+		if message != "" {
+			p.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable, Message: message, LastTransitionTime: since}}
+		}
+		return p
+	}
+	objects := []client.Object{
+		pod("team-a", "web", corev1.PodPending, "0/3 nodes are available: 3 Insufficient cpu."),
+		pod("team-b", "api", corev1.PodPending, "0/3 nodes are available: 3 node(s) had untolerated taint {dedicated: gpu}."),
+		// Scheduled, and pulling its images
+		pod("team-b", "worker", corev1.PodPending, ""),
+		pod("team-b", "db", corev1.PodRunning, ""),
+	}
+
+	tests := []struct {
+		name             string
+		url              string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			"Test Scheduling Insights",
+			"/insights/scheduling",
+			http.StatusOK,
+			"{\"pendingPods\":3,\"causes\":[" +
+				"{\"cause\":\"InsufficientCPU\",\"pods\":[{\"name\":\"web\",\"namespace\":\"team-a\",\"nodes\":3,\"since\":\"2024-05-01T12:00:00Z\",\"message\":\"0/3 nodes are available: 3 Insufficient cpu.\"}]}," +
+				"{\"cause\":\"Taints\",\"pods\":[{\"name\":\"api\",\"namespace\":\"team-b\",\"nodes\":3,\"since\":\"2024-05-01T12:00:00Z\",\"message\":\"0/3 nodes are available: 3 node(s) had untolerated taint {dedicated: gpu}.\"}]}]}\n",
+		},
+		{
+			"Test Namespace Filter",
+			"/insights/scheduling?namespace=team-a",
+			http.StatusOK,
+			"{\"pendingPods\":1,\"causes\":[{\"cause\":\"InsufficientCPU\",\"pods\":[{\"name\":\"web\",\"namespace\":\"team-a\",\"nodes\":3,\"since\":\"2024-05-01T12:00:00Z\",\"message\":\"0/3 nodes are available: 3 Insufficient cpu.\"}]}]}\n",
+		},
+		{
+			"Test Nothing Pending",
+			"/insights/scheduling?namespace=team-c",
+			http.StatusOK,
+			"{\"pendingPods\":0,\"causes\":[]}\n",
+		},
+		{
+			"Test Invalid Namespace",
+			"/insights/scheduling?namespace=Team",
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.InvalidNamespace, "namespace", "Team", "reason", "a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The fake client only filters on the fields it has an index for
+			live := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).
+				WithIndex(&corev1.Pod{}, "status.phase", func(obj client.Object) []string {
+					return []string{string(obj.(*corev1.Pod).Status.Phase)}
+				}).Build()
+			h := &InsightsHandler{LiveReader: live}
+
+			w := newResponseRecorder()
+			h.GetSchedulingInsights(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("GetSchedulingInsights() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if w.Body.String() != tt.expectedResponse {
+				t.Errorf("GetSchedulingInsights() = %v, want %v", w.Body.String(), tt.expectedResponse)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assertMatchesSchema(t, schema.SchedulingInsights, w)
+			}
+		})
+	}
+}
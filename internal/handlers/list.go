@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ListMetadata is the metadata of the list responses of the versioned API
+type ListMetadata struct {
+	// Count is the number of items in the response
+	Count int `json:"count"`
+	// Continue is the token to pass as the continue query parameter to get the next page of the list. Only set when
+	// the list was paginated with the limit query parameter, and more items remain.
+	Continue string `json:"continue,omitempty"`
+	// ResourceVersion is the resource version of the list. Only set for lists read directly from the API server.
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	// DataSource is where the items were read from, either DataSourceCache or DataSourceLive. Only set for the lists of
+	// Kubernetes objects.
+	DataSource string `json:"dataSource,omitempty"`
+	// CacheLastSync is the last time the cache received data from the API server. Only set for items read from the
+	// cache.
+	CacheLastSync *time.Time `json:"cacheLastSync,omitempty"`
+	// Stale is set when the items were read from the cache while the API server is unavailable
+	Stale bool `json:"stale,omitempty"`
+}
+
+// List is the envelope the list responses of the versioned API are wrapped in, rather than returned as bare arrays,
+// so that their shape stays the same as metadata is added to them
+type List[T any] struct {
+	Items    []T          `json:"items"`
+	Metadata ListMetadata `json:"metadata"`
+}
+
+// enveloped returns whether the list response to the request is wrapped in a List envelope, i.e. the request was made
+// to the versioned API
+func enveloped(r *http.Request) bool {
+	return apiversion.Version(r.Context()) == apiversion.V1
+}
+
+// writeList writes the items as the response to a list request, wrapped in a List envelope along with the metadata for
+// the versioned API, or as a bare array otherwise. The count of the metadata is set from the items.
+func writeList[T any](w http.ResponseWriter, r *http.Request, items []T, metadata ListMetadata) {
+	logger := klog.FromContext(r.Context())
+	var body any = items
+	if enveloped(r) {
+		if items == nil {
+			items = []T{}
+		}
+		metadata.Count = len(items)
+		body = List[T]{Items: items, Metadata: metadata}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// pagination returns the options paginating a list as asked by the limit and continue query parameters of the request
+func pagination(r *http.Request) ([]client.ListOption, error) {
+	var opts []client.ListOption
+	query := r.URL.Query()
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit <= 0 {
+			return nil, messages.New(messages.InvalidPositiveIntParameter, "parameter", "limit", "value", value)
+		}
+		opts = append(opts, client.Limit(limit))
+	}
+	if token := query.Get("continue"); token != "" {
+		opts = append(opts, client.Continue(token))
+	}
+	return opts, nil
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/rollouts"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// newV1TestRequest returns a request made to the versioned API
+func newV1TestRequest(method, url string) *http.Request {
+	r := newHttpTestRequest(method, url, nil)
+	return r.WithContext(apiversion.WithVersion(r.Context(), apiversion.V1))
+}
+
+func TestDeploymentsHandler_ListDeploymentsV1(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	objects := []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "team-a"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "team-b"}},
+	}
+
+	tests := []struct {
+		name             string
+		url              string
+		expectedStatus   int
+		expectedResponse string
+		expectedLimit    int64
+	}{
+		{
+			"Test Envelope",
+			"/deployments",
+			http.StatusOK,
+			"{\"items\":[{\"name\":\"foo\",\"namespace\":\"team-a\"},{\"name\":\"bar\",\"namespace\":\"team-b\"}],\"metadata\":{\"count\":2,\"dataSource\":\"cache\"}}\n",
+			0,
+		},
+		{
+			"Test Empty Envelope",
+			"/deployments?namespace=team-c",
+			http.StatusOK,
+			"{\"items\":[],\"metadata\":{\"count\":0,\"dataSource\":\"cache\"}}\n",
+			0,
+		},
+		{
+			"Test Paginated From The API Server",
+			"/deployments?limit=1&continue=abc",
+			http.StatusOK,
+			"{\"items\":[{\"name\":\"foo\",\"namespace\":\"team-a\"},{\"name\":\"bar\",\"namespace\":\"team-b\"}],\"metadata\":{\"count\":2,\"continue\":\"def\",\"resourceVersion\":\"42\",\"dataSource\":\"live\"}}\n",
+			1,
+		},
+		{
+			"Test Invalid Limit",
+			"/deployments?limit=0",
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.InvalidPositiveIntParameter, "parameter", "limit", "value", "0"),
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The fake client doesn't paginate, so the live reader reports the options it was passed and a next page
+			var limit int64
+			live := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(objects...).WithInterceptorFuncs(interceptor.Funcs{
+				List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+					listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+					limit = listOpts.Limit
+					if err := c.List(ctx, list, opts...); err != nil {
+						return err
+					}
+					list.SetContinue("def")
+					list.SetResourceVersion("42")
+					return nil
+				},
+			}).Build()
+			h := &DeploymentsHandler{Client: fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(objects...).Build(), LiveReader: live}
+
+			w := newResponseRecorder()
+			h.ListDeployments(w, newV1TestRequest("GET", tt.url))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("ListDeployments() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if w.Body.String() != tt.expectedResponse {
+				t.Errorf("ListDeployments() = %v, want %v", w.Body.String(), tt.expectedResponse)
+			}
+			if limit != tt.expectedLimit {
+				t.Errorf("ListDeployments() listed with limit %d, want %d", limit, tt.expectedLimit)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assertMatchesSchema(t, schema.DeploymentsResponse, w)
+			}
+		})
+	}
+}
+
+func TestWriteList(t *testing.T) {
+	alerts := []rollouts.Alert{{Name: "foo", Namespace: "team-a", Reason: "ProgressDeadlineExceeded", Message: "stuck", Generation: 3}}
+	tests := []struct {
+		name             string
+		r                *http.Request
+		alerts           []rollouts.Alert
+		expectedResponse string
+	}{
+		{"Test Unversioned", newHttpTestRequest("GET", "/alerts/rollouts", nil), alerts,
+			"[{\"name\":\"foo\",\"namespace\":\"team-a\",\"reason\":\"ProgressDeadlineExceeded\",\"message\":\"stuck\",\"since\":\"0001-01-01T00:00:00Z\",\"generation\":3}]\n"},
+		{"Test Versioned", newV1TestRequest("GET", "/alerts/rollouts"), alerts,
+			"{\"items\":[{\"name\":\"foo\",\"namespace\":\"team-a\",\"reason\":\"ProgressDeadlineExceeded\",\"message\":\"stuck\",\"since\":\"0001-01-01T00:00:00Z\",\"generation\":3}],\"metadata\":{\"count\":1}}\n"},
+		{"Test Versioned Empty", newV1TestRequest("GET", "/alerts/rollouts"), nil,
+			"{\"items\":[],\"metadata\":{\"count\":0}}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			writeList(w, tt.r, tt.alerts, ListMetadata{})
+			if w.Body.String() != tt.expectedResponse {
+				t.Errorf("writeList() = %v, want %v", w.Body.String(), tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.RolloutAlerts, w)
+		})
+	}
+}
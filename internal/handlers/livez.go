@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"k8s.io/klog/v2"
+)
+
+// LivezHandler is an HTTP handler for the livez API.
+// Unlike the healthz API, it doesn't depend on the Kubernetes API server, so that an API server outage doesn't get
+// the instance restarted by its liveness probe.
+type LivezHandler struct{}
+
+func (h *LivezHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(healthResponse{Status: "ok"})
+	if err != nil {
+		klog.FromContext(r.Context()).Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
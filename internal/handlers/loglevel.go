@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"k8s.io/klog/v2"
+)
+
+// LogLevel is the request / response object for the log level API
+type LogLevel struct {
+	Verbosity *int `json:"verbosity"`
+}
+
+// Validate validates the LogLevel object and returns an error if it is invalid
+func (l *LogLevel) Validate() error {
+	if l.Verbosity == nil {
+		return fmt.Errorf("verbosity field is required")
+	} else if *l.Verbosity < 0 {
+		return fmt.Errorf("verbosity field must be greater than or equal to 0")
+	}
+
+	return nil
+}
+
+// LogLevelHandler is the handler for the log level API, which allows changing the log verbosity at runtime
+type LogLevelHandler struct {
+	// Verbosity is the klog "v" flag
+	Verbosity flag.Value
+}
+
+// GetLogLevel handles the "/admin/loglevel" endpoint for GET method
+func (h *LogLevelHandler) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+	verbosity, err := strconv.Atoi(h.Verbosity.String())
+	if err != nil {
+		logger.Error(err, "Error parsing current verbosity")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(LogLevel{&verbosity})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// SetLogLevel handles the "/admin/loglevel" endpoint for PUT method
+func (h *LogLevelHandler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+	var l LogLevel
+	err := decodeJSONBody(r, &l)
+	if err != nil {
+		writeBadRequest(w, r, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
+		return
+	}
+
+	err = l.Validate()
+	if err != nil {
+		writeBadRequest(w, r, messages.New(messages.ValidationFailed, "reason", err.Error()))
+		return
+	}
+
+	err = h.Verbosity.Set(strconv.Itoa(*l.Verbosity))
+	if err != nil {
+		logger.Error(err, "Error setting verbosity")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Log verbosity changed", "verbosity", *l.Verbosity)
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(l)
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
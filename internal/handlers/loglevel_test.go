@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"flag"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	tests := []struct {
+		name              string
+		body              string
+		expectedStatus    int
+		expectedResponse  string
+		expectedVerbosity string
+	}{
+		{
+			"Test SetLogLevel",
+			"{\"verbosity\":5}",
+			http.StatusOK,
+			"{\"verbosity\":5}\n",
+			"5",
+		},
+		{
+			"Test SetLogLevel Bad Request - missing verbosity",
+			"{}",
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.ValidationFailed, "reason", "verbosity field is required"),
+			"2",
+		},
+		{
+			"Test SetLogLevel Bad Request - unknown field",
+			"{\"level\":5}",
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.InvalidRequestBody, "reason", "unknown field \"level\" at offset 1"),
+			"2",
+		},
+		{
+			"Test SetLogLevel Bad Request - negative verbosity",
+			"{\"verbosity\":-1}",
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.ValidationFailed, "reason", "verbosity field must be greater than or equal to 0"),
+			"2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+			flagSet.Int("v", 2, "log level for V logs")
+			h := &LogLevelHandler{Verbosity: flagSet.Lookup("v").Value}
+
+			w := newResponseRecorder()
+			h.SetLogLevel(w, newHttpTestRequest("PUT", "/admin/loglevel", strings.NewReader(tt.body)))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("SetLogLevel() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("SetLogLevel() response body = %v, want %v", rb, tt.expectedResponse)
+			}
+
+			// The current verbosity should be reported by GetLogLevel
+			w = newResponseRecorder()
+			h.GetLogLevel(w, newHttpTestRequest("GET", "/admin/loglevel", nil))
+			expected := "{\"verbosity\":" + tt.expectedVerbosity + "}\n"
+			if rb := w.Body.String(); rb != expected {
+				t.Errorf("GetLogLevel() response body = %v, want %v", rb, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+// FieldManager is a field manager of a deployment, along with the fields it owns
+type FieldManager struct {
+	// Manager is the name of the field manager, e.g. kube-controller-manager, or argocd-controller for Argo CD
+	Manager string `json:"manager"`
+	// Operation is how the manager last wrote its fields, either Apply for server-side applies or Update
+	Operation string `json:"operation"`
+	// Subresource is the subresource the fields were written through, e.g. status or scale. Empty for the main resource.
+	Subresource string `json:"subresource,omitempty"`
+	// Time is the last time the manager changed its fields
+	Time *time.Time `json:"time,omitempty"`
+	// Fields are the paths of the fields owned by the manager, e.g. spec.replicas, or
+	// spec.template.spec.containers[name="web"].image for the items of the lists keyed by name
+	Fields []string `json:"fields"`
+}
+
+// DeploymentManagersResponse is the response object for the managers API
+type DeploymentManagersResponse struct {
+	DeploymentResponse
+	Managers []FieldManager `json:"managers"`
+}
+
+// GetDeploymentManagers handles the "/deployments/{namespace}/{deployment}/managers" endpoint (and its namespace
+// scoped equivalent) for GET method.
+// It summarizes the managedFields of the deployment into the fields owned by each of its field managers, which helps
+// debugging the fights over fields between e.g. an HPA, a GitOps tool and the clients of the API.
+func (h *DeploymentsHandler) GetDeploymentManagers(w http.ResponseWriter, r *http.Request) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	// The managedFields are stripped from the cached deployments by default, so they're read directly from the API
+	// server
+	if h.LiveReader != nil {
+		reader.useLive = true
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+	managers, err := fieldManagers(d)
+	if err != nil {
+		logger.Error(err, "Error parsing the managed fields")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.DeploymentManagedFieldsFailed, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(DeploymentManagersResponse{
+		DeploymentResponse: DeploymentResponse{Name: deployment, Namespace: namespace},
+		Managers:           managers,
+	})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// fieldManagers returns the field managers of the deployment, in the order of its managedFields, along with the leaf
+// fields each of them owns
+func fieldManagers(d *appsv1.Deployment) ([]FieldManager, error) {
+	managers := make([]FieldManager, 0, len(d.ManagedFields))
+	for _, entry := range d.ManagedFields {
+		manager := FieldManager{Manager: entry.Manager, Operation: string(entry.Operation), Subresource: entry.Subresource, Fields: []string{}}
+		if entry.Time != nil {
+			t := entry.Time.UTC()
+			manager.Time = &t
+		}
+		if entry.FieldsV1 != nil {
+			set := &fieldpath.Set{}
+			if err := set.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+				return nil, fmt.Errorf("invalid fields of manager %s: %w", entry.Manager, err)
+			}
+			set.Leaves().Iterate(func(path fieldpath.Path) {
+				manager.Fields = append(manager.Fields, strings.TrimPrefix(path.String(), "."))
+			})
+		}
+		managers = append(managers, manager)
+	}
+	return managers, nil
+}
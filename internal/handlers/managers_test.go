@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeploymentsHandler_GetDeploymentManagers(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	at := metav1.NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	managed := func(fields ...metav1.ManagedFieldsEntry) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo", ManagedFields: fields}}
+	}
+
+	tests := []struct {
+		name             string
+		deployment       *appsv1.Deployment
+		url              string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			"Test Managers",
+			managed(
+				metav1.ManagedFieldsEntry{Manager: "argocd-controller", Operation: metav1.ManagedFieldsOperationApply, Time: &at, FieldsType: "FieldsV1",
+					FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"web\"}":{".":{},"f:image":{},"f:name":{}}}}}}}`)}},
+				metav1.ManagedFieldsEntry{Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate, Subresource: "scale", Time: &at, FieldsType: "FieldsV1",
+					FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)}},
+			),
+			"/deployments/foo/bar/managers",
+			http.StatusOK,
+			"{\"name\":\"bar\",\"namespace\":\"foo\",\"managers\":[" +
+				"{\"manager\":\"argocd-controller\",\"operation\":\"Apply\",\"time\":\"2024-05-01T12:00:00Z\",\"fields\":[\"spec.template.spec.containers[name=\\\"web\\\"].image\",\"spec.template.spec.containers[name=\\\"web\\\"].name\"]}," +
+				"{\"manager\":\"kube-controller-manager\",\"operation\":\"Update\",\"subresource\":\"scale\",\"time\":\"2024-05-01T12:00:00Z\",\"fields\":[\"spec.replicas\"]}]}\n",
+		},
+		{
+			"Test No Managers",
+			managed(),
+			"/deployments/foo/bar/managers",
+			http.StatusOK,
+			"{\"name\":\"bar\",\"namespace\":\"foo\",\"managers\":[]}\n",
+		},
+		{
+			"Test Invalid Fields",
+			managed(metav1.ManagedFieldsEntry{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1",
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"spec":{}}`)}}),
+			"/deployments/foo/bar/managers",
+			http.StatusInternalServerError,
+			errorBody(http.StatusInternalServerError, messages.DeploymentManagedFieldsFailed, "namespace", "foo", "name", "bar"),
+		},
+		{
+			"Test Deployment Not Found",
+			managed(),
+			"/deployments/foo/baz/managers",
+			http.StatusNotFound,
+			errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "foo", "name", "baz"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The managed fields are read directly from the API server
+			live := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(tt.deployment).Build()
+			h := &DeploymentsHandler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build(), LiveReader: live}
+
+			w := newResponseRecorder()
+			h.GetDeploymentManagers(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("GetDeploymentManagers() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if w.Body.String() != tt.expectedResponse {
+				t.Errorf("GetDeploymentManagers() = %v, want %v", w.Body.String(), tt.expectedResponse)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assertMatchesSchema(t, schema.ManagersResponse, w)
+				if source := w.Header().Get(DataSourceHeader); source != DataSourceLive {
+					t.Errorf("GetDeploymentManagers() data source = %v, want %v", source, DataSourceLive)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/redaction"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Formats of the exported manifests
+const (
+	ManifestFormatYAML = "yaml"
+	ManifestFormatJSON = "json"
+)
+
+// serverPopulatedMetadata are the metadata fields populated by the API server, which can't be part of a re-applyable
+// manifest
+var serverPopulatedMetadata = []string{
+	"managedFields", "resourceVersion", "uid", "creationTimestamp", "generation", "selfLink",
+	"deletionTimestamp", "deletionGracePeriodSeconds",
+}
+
+// serverPopulatedAnnotations are the annotations set by controllers and clients rather than by the object's owner
+var serverPopulatedAnnotations = []string{
+	"deployment.kubernetes.io/revision",
+	"kubectl.kubernetes.io/last-applied-configuration",
+}
+
+// GetDeploymentManifest handles the "/deployments/{namespace}/{deployment}/manifest" endpoint (and its namespace scoped
+// equivalent) for GET method.
+// It returns the deployment as a re-applyable manifest, stripped of its status and of the fields populated by the API
+// server, as YAML unless ?format=json is passed.
+func (h *DeploymentsHandler) GetDeploymentManifest(w http.ResponseWriter, r *http.Request) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = ManifestFormatYAML
+	}
+	if format != ManifestFormatYAML && format != ManifestFormatJSON {
+		writeBadRequest(w, r, messages.New(messages.UnsupportedParameterValue, "parameter", "format", "value", format, "allowed", ManifestFormatYAML+", "+ManifestFormatJSON))
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	manifest, err := toUnstructured(d)
+	if err != nil {
+		logger.Error(err, "Error converting the deployment")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	cleanManifest(manifest)
+	// The sensitive fields are redacted for the clients which aren't privileged
+	if policy := redaction.From(r.Context()); policy != nil {
+		policy.Redact(manifest)
+	}
+
+	var body []byte
+	if format == ManifestFormatJSON {
+		w.Header().Set("Content-Type", "application/json")
+		body, err = json.MarshalIndent(manifest, "", "  ")
+	} else {
+		w.Header().Set("Content-Type", "application/yaml")
+		body, err = yaml.Marshal(manifest)
+	}
+	if err != nil {
+		logger.Error(err, "Error encoding the manifest")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		logger.Error(err, "Error writing response")
+	}
+}
+
+// cleanManifest strips the status and the server populated fields of the object, along with the null fields left by
+// the conversion from a typed object (e.g. the creationTimestamp of the pod template)
+func cleanManifest(manifest map[string]any) {
+	unstructured.RemoveNestedField(manifest, "status")
+	for _, field := range serverPopulatedMetadata {
+		unstructured.RemoveNestedField(manifest, "metadata", field)
+	}
+	for _, annotation := range serverPopulatedAnnotations {
+		unstructured.RemoveNestedField(manifest, "metadata", "annotations", annotation)
+	}
+	if annotations, found, _ := unstructured.NestedMap(manifest, "metadata", "annotations"); found && len(annotations) == 0 {
+		unstructured.RemoveNestedField(manifest, "metadata", "annotations")
+	}
+	removeEmptyFields(manifest)
+}
+
+// removeEmptyFields recursively removes the null values of the map, along with the objects left empty once their null
+// values are removed. Objects which were empty to begin with are kept, since some of them are meaningful (e.g. an
+// emptyDir volume source).
+func removeEmptyFields(object map[string]any) {
+	for key, value := range object {
+		switch v := value.(type) {
+		case nil:
+			delete(object, key)
+		case map[string]any:
+			if len(v) == 0 {
+				continue
+			}
+			removeEmptyFields(v)
+			if len(v) == 0 {
+				delete(object, key)
+			}
+		case []any:
+			for _, item := range v {
+				if itemMap, ok := item.(map[string]any); ok {
+					removeEmptyFields(itemMap)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/redaction"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeploymentsHandler_GetDeploymentManifest(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "bar",
+			Namespace:         "foo",
+			UID:               "6f1c3c8e-5b0a-4d39-9d0e-1b5f0d1c2a3b",
+			Generation:        4,
+			CreationTimestamp: metav1.Now(),
+			Labels:            map[string]string{"app": "bar"},
+			Annotations:       map[string]string{"deployment.kubernetes.io/revision": "4"},
+			ManagedFields:     []metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(int32(2)),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "bar"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "bar"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Image: "app:1"}},
+					Volumes:    []corev1.Volume{{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+				},
+			},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 2},
+	}).Build()
+	h := &DeploymentsHandler{Client: c}
+
+	tests := []struct {
+		name                string
+		url                 string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "YAML manifest",
+			url:                 "/deployments/foo/bar/manifest",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/yaml",
+			expectedBody: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    app: bar
+  name: bar
+  namespace: foo
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: bar
+  strategy: {}
+  template:
+    metadata:
+      labels:
+        app: bar
+    spec:
+      containers:
+      - image: app:1
+        name: app
+        resources: {}
+      volumes:
+      - emptyDir: {}
+        name: tmp
+`,
+		},
+		{
+			name:                "JSON manifest",
+			url:                 "/deployments/foo/bar/manifest?format=json",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/json",
+		},
+		{
+			name:         "invalid format",
+			url:          "/deployments/foo/bar/manifest?format=toml",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: errorBody(http.StatusBadRequest, messages.UnsupportedParameterValue, "parameter", "format", "value", "toml", "allowed", "yaml, json"),
+		},
+		{
+			name:         "deployment not found",
+			url:          "/deployments/foo/baz/manifest",
+			expectedCode: http.StatusNotFound,
+			expectedBody: errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "foo", "name", "baz"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			h.GetDeploymentManifest(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedCode {
+				t.Fatalf("GetDeploymentManifest() status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if tt.expectedContentType != "" && w.Header().Get("Content-Type") != tt.expectedContentType {
+				t.Errorf("GetDeploymentManifest() Content-Type = %v, want %v", w.Header().Get("Content-Type"), tt.expectedContentType)
+			}
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("GetDeploymentManifest() body = %v, want %v", w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestDeploymentsHandler_GetDeploymentManifestRedaction(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Image: "app:1", Env: []corev1.EnvVar{{Name: "API_KEY", Value: "s3cr3t"}}}},
+				},
+			},
+		},
+	}).Build()
+	h := &DeploymentsHandler{Client: c}
+	policy, err := redaction.New(redaction.Config{EnvValues: true})
+	if err != nil {
+		t.Fatalf("redaction.New() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		policy        *redaction.Policy
+		expectedValue string
+	}{
+		{"Test GetDeploymentManifest Redacted", policy, "value: " + redaction.Redacted},
+		{"Test GetDeploymentManifest Not Redacted", nil, "value: s3cr3t"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			r := newHttpTestRequest("GET", "/deployments/foo/bar/manifest", nil)
+			if tt.policy != nil {
+				r = r.WithContext(redaction.WithPolicy(r.Context(), tt.policy))
+			}
+			h.GetDeploymentManifest(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GetDeploymentManifest() status code = %v, want %v: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.expectedValue) {
+				t.Errorf("GetDeploymentManifest() body = %v, want it to contain %q", w.Body.String(), tt.expectedValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/bounds"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/notify"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/ownership"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// recordDeploymentChange notifies the change of the deployment made by the request to chat channels, and records it
+// as an event on the deployment, unless nothing changed. The action describes the change in the notifications, e.g.
+// "scaled", and event in the events, e.g. "Scaled".
+func (h *DeploymentsHandler) recordDeploymentChange(r *http.Request, operation, action, event string, d *appsv1.Deployment, from, to string) {
+	if from == to {
+		return
+	}
+	h.Events.Record(d, operation, event, auth.Identity(r), from, to)
+	h.Notifier.Notify(r.Context(), notify.Change{
+		Identity:  auth.Identity(r),
+		Operation: operation,
+		Action:    action,
+		Kind:      "Deployment",
+		Namespace: d.Namespace,
+		Name:      d.Name,
+		From:      from,
+		To:        to,
+	})
+}
+
+// replicasString returns the replicas of a deployment spec, which default to 1
+func replicasString(replicas *int32) string {
+	if replicas == nil {
+		return "1"
+	}
+	return strconv.Itoa(int(*replicas))
+}
+
+// boundsString returns the bounds declared in the annotations, or an empty string if there are none
+func boundsString(annotations map[string]string) string {
+	b, err := bounds.FromAnnotations(annotations)
+	if err != nil || b.IsZero() {
+		return ""
+	}
+	return b.String()
+}
+
+// ownerString returns the team declared in the annotations, or the owner if there's no team
+func ownerString(annotations map[string]string) string {
+	o := ownership.FromAnnotations(annotations)
+	if o.Team != "" {
+		return o.Team
+	}
+	return o.Owner
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/bounds"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/events"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/notify"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeploymentsHandler_Notifications(t *testing.T) {
+	messages := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		messages <- payload.Text
+	}))
+	defer server.Close()
+	notifier, err := notify.New(notify.Config{Routes: []notify.RouteConfig{{Type: notify.TypeSlack, URL: server.URL}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go notifier.Run(ctx)
+
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "bar",
+			Namespace:   "foo",
+			Annotations: map[string]string{bounds.MinReplicasAnnotation: "1", bounds.MaxReplicasAnnotation: "10"},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+	}).Build()
+	recorder := record.NewFakeRecorder(10)
+	h := &DeploymentsHandler{Client: c, Notifier: notifier, Events: &events.Recorder{Recorder: recorder, Component: DefaultFieldManager}}
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		method   string
+		url      string
+		body     string
+		expected string
+		// expectedEvent is the event recorded on the deployment, if any
+		expectedEvent string
+	}{
+		{"Test Scale", h.SetDeploymentReplicas, "PUT", "/deployments/foo/bar/replicas", `{"replicas":5}`, "scaled Deployment foo/bar from 3 to 5",
+			"Normal SetDeploymentReplicas Scaled by go-k8s-http-api from 3 to 5"},
+		{"Test Scale Unchanged", h.SetDeploymentReplicas, "PUT", "/deployments/foo/bar/replicas", `{"replicas":5}`, "", ""},
+		{"Test Set Bounds", h.SetDeploymentBounds, "PUT", "/deployments/foo/bar/bounds", `{"min":2,"max":8}`, "set the replica bounds of Deployment foo/bar from [1, 10] to [2, 8]",
+			"Normal SetDeploymentBounds Replica bounds set by go-k8s-http-api from [1, 10] to [2, 8]"},
+		{"Test Delete Bounds", h.DeleteDeploymentBounds, "DELETE", "/deployments/foo/bar/bounds", "", "removed the replica bounds of Deployment foo/bar from [2, 8]",
+			"Normal DeleteDeploymentBounds Replica bounds removed by go-k8s-http-api from [2, 8]"},
+		{"Test Set Ownership", h.SetDeploymentOwnership, "PUT", "/deployments/foo/bar/ownership", `{"team":"payments"}`, "changed the owner of Deployment foo/bar to payments",
+			"Normal SetDeploymentOwnership Owner changed by go-k8s-http-api to payments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			tt.handler(w, newHttpTestRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+			if w.Code >= 300 {
+				t.Fatalf("handler status code = %v: %v", w.Code, w.Body.String())
+			}
+			select {
+			case message := <-messages:
+				if message != "anonymous "+tt.expected {
+					t.Errorf("notification = %q, want %q", message, "anonymous "+tt.expected)
+				}
+			case <-time.After(time.Second):
+				if tt.expected != "" {
+					t.Errorf("expected a notification %q", tt.expected)
+				}
+			}
+			select {
+			case event := <-recorder.Events:
+				if event != tt.expectedEvent {
+					t.Errorf("event = %q, want %q", event, tt.expectedEvent)
+				}
+			default:
+				if tt.expectedEvent != "" {
+					t.Errorf("expected an event %q", tt.expectedEvent)
+				}
+			}
+		})
+	}
+}
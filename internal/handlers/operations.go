@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/operations"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// OperationScaleDeployment is the type of the operation scaling a deployment and waiting for its rollout
+const OperationScaleDeployment = "ScaleDeployment"
+
+// rolloutPollInterval is how often the rollout of an async scale operation is checked
+var rolloutPollInterval = 2 * time.Second
+
+// ScaleResult is the result of a completed scale operation
+type ScaleResult struct {
+	DeploymentResponseWithReplicas
+	ReadyReplicas int32 `json:"readyReplicas"`
+}
+
+// OperationsHandler is the handler for the operations API, reporting the progress of async operations
+type OperationsHandler struct {
+	Operations *operations.Manager
+}
+
+// GetOperation handles the "/operations/{id}" endpoint for GET method
+func (h *OperationsHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logger := klog.FromContext(r.Context()).WithValues("operation", id)
+
+	op, ok := h.Operations.Get(r.Context(), id)
+	// Operations outside of the scope of the client are reported as not found, like the deployments
+	if scope, scoped := tenancy.ScopeFrom(r.Context()); ok && scoped && !scope.Allows(op.Namespace()) {
+		ok = false
+	}
+	if !ok {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.OperationNotFound, "id", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(op); err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// async returns whether the request asks to run in the background, via the async query parameter
+func (h *DeploymentsHandler) async(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("async")
+	if value == "" {
+		return false, nil
+	}
+	async, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, messages.New(messages.InvalidBooleanParameter, "parameter", "async", "value", value)
+	}
+	if async && h.Operations == nil {
+		return false, fmt.Errorf("async mode is not supported by this endpoint")
+	}
+	return async, nil
+}
+
+// startScaleOperation starts an operation waiting for the rollout of the scaled deployment, and returns a 202 Accepted
+// pointing at the operation
+func (h *DeploymentsHandler) startScaleOperation(w http.ResponseWriter, r *http.Request, d *appsv1.Deployment) {
+	reader := h.liveReader()
+	key := client.ObjectKeyFromObject(d)
+	generation := d.Generation
+
+	op := h.Operations.Start(r.Context(), OperationScaleDeployment, key.String(), func(ctx context.Context, progress func(string)) (any, error) {
+		return waitForRollout(ctx, reader, key, generation, progress)
+	})
+
+	w.Header().Set("Location", "/operations/"+op.ID)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(op); err != nil {
+		klog.FromContext(r.Context()).Error(err, "Error encoding response")
+	}
+}
+
+// liveReader returns the reader of the operations watching deployments, which reads directly from the API server
+// unless no live reader is configured
+func (h *DeploymentsHandler) liveReader() client.Reader {
+	if h.LiveReader == nil {
+		return h.Client
+	}
+	return h.LiveReader
+}
+
+// waitForRollout waits until the deployment controller observed the given generation of the deployment, and all of
+// its replicas are updated and ready
+func waitForRollout(ctx context.Context, reader client.Reader, key client.ObjectKey, generation int64, progress func(string)) (*ScaleResult, error) {
+	d := &appsv1.Deployment{}
+	err := wait.PollUntilContextCancel(ctx, rolloutPollInterval, true, func(ctx context.Context) (bool, error) {
+		if err := reader.Get(ctx, key, d); err != nil {
+			return false, err
+		}
+		desired := int32(1)
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		progress(fmt.Sprintf("%d of %d replicas updated and ready", min(d.Status.UpdatedReplicas, d.Status.ReadyReplicas), desired))
+		return d.Status.ObservedGeneration >= generation &&
+			d.Status.UpdatedReplicas == desired &&
+			d.Status.ReadyReplicas == desired &&
+			d.Status.Replicas == desired, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rollout of deployment %s did not complete: %w", key, err)
+	}
+
+	return &ScaleResult{
+		DeploymentResponseWithReplicas: DeploymentResponseWithReplicas{
+			DeploymentResponse: DeploymentResponse{Name: key.Name, Namespace: key.Namespace},
+			Replicas:           Replicas{d.Spec.Replicas},
+		},
+		ReadyReplicas: d.Status.ReadyReplicas,
+	}, nil
+}
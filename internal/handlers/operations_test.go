@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/operations"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeploymentsHandler_SetDeploymentReplicasAsync(t *testing.T) {
+	rolloutPollInterval = 10 * time.Millisecond
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	// The fake client doesn't run the deployment controller, so the deployment is already rolled out
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+		Status:     appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, ReadyReplicas: 3},
+	}).Build()
+	manager := operations.NewManager(time.Hour, time.Minute, nil)
+	h := &DeploymentsHandler{Client: c, Operations: manager}
+
+	w := newResponseRecorder()
+	h.SetDeploymentReplicas(w, newHttpTestRequest("PUT", "/deployments/foo/bar/replicas?async=true", strings.NewReader("{\"replicas\":3}")))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("SetDeploymentReplicas() status code = %v, want %v", w.Code, http.StatusAccepted)
+	}
+	assertMatchesSchema(t, schema.ReplicasResponse, w)
+	var op operations.Operation
+	if err := json.NewDecoder(w.Body).Decode(&op); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if location := w.Header().Get("Location"); location != "/operations/"+op.ID {
+		t.Errorf("SetDeploymentReplicas() Location = %v, want /operations/%v", location, op.ID)
+	}
+
+	// Poll the operation until it completes
+	oh := &OperationsHandler{Operations: manager}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /operations/{id}", oh.GetOperation)
+	deadline := time.Now().Add(5 * time.Second)
+	for !op.Done() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		w = newResponseRecorder()
+		mux.ServeHTTP(w, newHttpTestRequest("GET", "/operations/"+op.ID, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GetOperation() status code = %v, want %v", w.Code, http.StatusOK)
+		}
+		assertMatchesSchema(t, schema.Operation, w)
+		op = operations.Operation{}
+		if err := json.NewDecoder(w.Body).Decode(&op); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+	if op.Status != operations.StatusSucceeded {
+		t.Errorf("expected the operation to succeed, got %+v", op)
+	}
+}
+
+func TestDeploymentsHandler_SetDeploymentReplicasAsyncUnsupported(t *testing.T) {
+	h := &DeploymentsHandler{}
+	w := newResponseRecorder()
+	h.SetDeploymentReplicas(w, newHttpTestRequest("PUT", "/deployments/foo/bar/replicas?async=true", strings.NewReader("{\"replicas\":3}")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("SetDeploymentReplicas() status code = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestOperationsHandler_GetOperationNotFound(t *testing.T) {
+	h := &OperationsHandler{Operations: operations.NewManager(time.Hour, time.Minute, nil)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /operations/{id}", h.GetOperation)
+
+	w := newResponseRecorder()
+	mux.ServeHTTP(w, newHttpTestRequest("GET", "/operations/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GetOperation() status code = %v, want %v", w.Code, http.StatusNotFound)
+	}
+	if rb, expected := w.Body.String(), errorBody(http.StatusNotFound, messages.OperationNotFound, "id", "nope"); rb != expected {
+		t.Errorf("GetOperation() response body = %v, want %v", rb, expected)
+	}
+}
+
+func TestOperationsHandler_GetOperationScope(t *testing.T) {
+	manager := operations.NewManager(time.Hour, time.Minute, nil)
+	op := manager.Start(context.Background(), OperationScaleDeployment, "foo/bar", func(ctx context.Context, progress func(string)) (any, error) {
+		return nil, nil
+	})
+	h := &OperationsHandler{Operations: manager}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /operations/{id}", h.GetOperation)
+
+	tests := []struct {
+		name     string
+		scope    *tenancy.Scope
+		wantCode int
+	}{
+		{name: "tenancy disabled", wantCode: http.StatusOK},
+		{name: "all namespaces", scope: &tenancy.Scope{All: true}, wantCode: http.StatusOK},
+		{name: "namespace of the target", scope: &tenancy.Scope{Namespaces: map[string]bool{"foo": true}}, wantCode: http.StatusOK},
+		{name: "other namespace", scope: &tenancy.Scope{Namespaces: map[string]bool{"other": true}}, wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newHttpTestRequest("GET", "/operations/"+op.ID, nil)
+			if tt.scope != nil {
+				r = r.WithContext(tenancy.WithScope(r.Context(), *tt.scope))
+			}
+			w := newResponseRecorder()
+			mux.ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("GetOperation() status code = %v, want %v", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiwarnings"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/ownership"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeploymentOwnershipResponse is the response object for the ownership API
+type DeploymentOwnershipResponse struct {
+	DeploymentResponse
+	ownership.Ownership
+	// Warnings are the warnings of the admission plugins, only set on writes
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// GetDeploymentOwnership handles the "/deployments/{namespace}/{deployment}/ownership" endpoint (and its namespace
+// scoped equivalent) for GET method
+func (h *DeploymentsHandler) GetDeploymentOwnership(w http.ResponseWriter, r *http.Request) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(DeploymentOwnershipResponse{
+		DeploymentResponse: DeploymentResponse{Name: deployment, Namespace: namespace},
+		Ownership:          ownership.FromAnnotations(d.Annotations),
+	})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// SetDeploymentOwnership handles the "/deployments/{namespace}/{deployment}/ownership" endpoint (and its namespace
+// scoped equivalent) for PUT method.
+// The ownership replaces the previous one as a whole: the fields left out are removed, so an empty object clears it.
+func (h *DeploymentsHandler) SetDeploymentOwnership(w http.ResponseWriter, r *http.Request) {
+	var o ownership.Ownership
+	if err := decodeJSONBody(r, &o); err != nil {
+		writeBadRequest(w, r, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
+		return
+	}
+	if err := o.Validate(); err != nil {
+		writeBadRequest(w, r, messages.New(messages.ValidationFailed, "reason", err.Error()))
+		return
+	}
+
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	patch := client.MergeFrom(d.DeepCopy())
+	proposed := d.DeepCopy()
+	proposed.Annotations = ownership.SetAnnotations(proposed.Annotations, o)
+	warnings, admitted := h.admit(w, r, d, proposed)
+	if !admitted {
+		return
+	}
+	previous := ownerString(d.Annotations)
+	d = proposed
+	if err := h.Patch(r.Context(), d, patch); err != nil {
+		logger.Error(err, "Error patching deployment")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.DeploymentPatchFailed, "namespace", namespace, "name", deployment))
+		return
+	}
+	logger.Info("Updated ownership", "team", o.Team, "owner", o.Owner)
+	h.recordDeploymentChange(r, approvals.OperationSetDeploymentOwnership, "changed the owner of", "Owner changed", d, previous, ownerString(d.Annotations))
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(DeploymentOwnershipResponse{
+		DeploymentResponse: DeploymentResponse{Name: deployment, Namespace: namespace},
+		Ownership:          o,
+		Warnings:           append(warnings, apiwarnings.Collected(r.Context())...),
+	})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/ownership"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeploymentsHandler_DeploymentOwnership(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+
+	tests := []struct {
+		name                string
+		annotations         map[string]string
+		method              string
+		url                 string
+		body                string
+		expectedCode        int
+		expectedResponse    string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:             "get",
+			annotations:      map[string]string{ownership.TeamAnnotation: "team-a", ownership.SlackChannelAnnotation: "#team-a-oncall"},
+			method:           "GET",
+			url:              "/deployments/foo/bar/ownership",
+			expectedCode:     http.StatusOK,
+			expectedResponse: "{\"name\":\"bar\",\"namespace\":\"foo\",\"team\":\"team-a\",\"slackChannel\":\"#team-a-oncall\"}\n",
+		},
+		{
+			name:             "get without ownership",
+			method:           "GET",
+			url:              "/deployments/foo/bar/ownership",
+			expectedCode:     http.StatusOK,
+			expectedResponse: "{\"name\":\"bar\",\"namespace\":\"foo\"}\n",
+		},
+		{
+			name:             "get not found",
+			method:           "GET",
+			url:              "/deployments/foo/baz/ownership",
+			expectedCode:     http.StatusNotFound,
+			expectedResponse: errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "foo", "name", "baz"),
+		},
+		{
+			name:                "set",
+			annotations:         map[string]string{"foo": "bar", ownership.PagerAnnotation: "PXYZ123"},
+			method:              "PUT",
+			url:                 "/deployments/foo/bar/ownership",
+			body:                "{\"team\":\"team-a\",\"owner\":\"jane@example.com\"}",
+			expectedCode:        http.StatusOK,
+			expectedResponse:    "{\"name\":\"bar\",\"namespace\":\"foo\",\"team\":\"team-a\",\"owner\":\"jane@example.com\"}\n",
+			expectedAnnotations: map[string]string{"foo": "bar", ownership.TeamAnnotation: "team-a", ownership.OwnerAnnotation: "jane@example.com"},
+		},
+		{
+			name:                "clear",
+			annotations:         map[string]string{"foo": "bar", ownership.TeamAnnotation: "team-a"},
+			method:              "PUT",
+			url:                 "/deployments/foo/bar/ownership",
+			body:                "{}",
+			expectedCode:        http.StatusOK,
+			expectedResponse:    "{\"name\":\"bar\",\"namespace\":\"foo\"}\n",
+			expectedAnnotations: map[string]string{"foo": "bar"},
+		},
+		{
+			name:             "set invalid slack channel",
+			method:           "PUT",
+			url:              "/deployments/foo/bar/ownership",
+			body:             "{\"slackChannel\":\"team-a\"}",
+			expectedCode:     http.StatusBadRequest,
+			expectedResponse: errorBody(http.StatusBadRequest, messages.ValidationFailed, "reason", "invalid slackChannel \"team-a\", must be a channel name starting with #, e.g. #team-a-oncall"),
+		},
+		{
+			name:             "set not found",
+			method:           "PUT",
+			url:              "/deployments/foo/baz/ownership",
+			body:             "{\"team\":\"team-a\"}",
+			expectedCode:     http.StatusNotFound,
+			expectedResponse: errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "foo", "name", "baz"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo", Annotations: tt.annotations},
+			}).Build()
+			h := &DeploymentsHandler{Client: c}
+
+			w := newResponseRecorder()
+			r := newHttpTestRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			switch tt.method {
+			case "GET":
+				h.GetDeploymentOwnership(w, r)
+			case "PUT":
+				h.SetDeploymentOwnership(w, r)
+			}
+			if w.Code != tt.expectedCode {
+				t.Fatalf("status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("response body = %v, want %v", rb, tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.OwnershipResponse, w)
+
+			if tt.expectedAnnotations != nil {
+				d := &appsv1.Deployment{}
+				if err := c.Get(context.Background(), client.ObjectKey{Namespace: "foo", Name: "bar"}, d); err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				if !reflect.DeepEqual(d.Annotations, tt.expectedAnnotations) {
+					t.Errorf("annotations = %v, want %v", d.Annotations, tt.expectedAnnotations)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/events"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/notify"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EvictionResponse is the response object of the pod eviction API
+type EvictionResponse struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Node is the node the pod was evicted from, if it was scheduled
+	Node string `json:"node,omitempty"`
+	// DryRun is set when the eviction was only evaluated, and the pod is left running
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// PodsHandler is the handler for the pods API
+type PodsHandler struct {
+	// Client evicts the pods
+	Client client.Client
+	// LiveReader reads the pods directly from the API server, since they aren't cached
+	LiveReader client.Reader
+	// Notifier notifies the evictions to chat channels. They aren't notified when nil.
+	Notifier *notify.Notifier
+	// Events records the evictions as events on the pods. They aren't recorded when nil.
+	Events *events.Recorder
+}
+
+// EvictPod handles the "/pods/{namespace}/{pod}/evict" endpoint (and its namespace scoped equivalent) for POST method.
+// The pod is evicted with the Eviction API rather than deleted, so that its PodDisruptionBudgets are honored: the
+// evictions they block get a 429 Too Many Requests, to be retried later. With ?dryRun=true, the eviction is only
+// evaluated.
+func (h *PodsHandler) EvictPod(w http.ResponseWriter, r *http.Request) {
+	namespace, name := r.PathValue("namespace"), r.PathValue("pod")
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		writeBadRequest(w, r, messages.New(messages.InvalidNamespace, "namespace", namespace, "reason", strings.Join(errs, ", ")))
+		return
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		writeBadRequest(w, r, messages.New(messages.InvalidPodName, "name", name, "reason", strings.Join(errs, ", ")))
+		return
+	}
+	dryRun := false
+	if value := r.URL.Query().Get("dryRun"); value != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			writeBadRequest(w, r, messages.New(messages.InvalidBooleanParameter, "parameter", "dryRun", "value", value))
+			return
+		}
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "pod", name)
+
+	pod := &corev1.Pod{}
+	if err := h.LiveReader.Get(r.Context(), client.ObjectKey{Namespace: namespace, Name: name}, pod); err != nil {
+		logger.Error(err, "Error getting pod")
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.PodNotFound, "namespace", namespace, "name", name))
+		return
+	}
+
+	// The eviction is preconditioned on the UID of the pod, so that a pod recreated under the same name isn't evicted
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: name, Namespace: namespace},
+		DeleteOptions: &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &pod.UID}},
+	}
+	var opts []client.SubResourceCreateOption
+	if dryRun {
+		eviction.DeleteOptions.DryRun = []string{metav1.DryRunAll}
+		opts = append(opts, client.DryRunAll)
+	}
+	if err := h.Client.SubResource("eviction").Create(r.Context(), pod, eviction, opts...); err != nil {
+		writeEvictionError(w, r, namespace, name, err)
+		return
+	}
+
+	logger.Info("Evicted pod", "node", pod.Spec.NodeName, "dryRun", dryRun)
+	if !dryRun {
+		h.Events.Record(pod, approvals.OperationEvictPod, "Evicted", auth.Identity(r), "", "")
+		h.Notifier.Notify(r.Context(), notify.Change{
+			Identity:  auth.Identity(r),
+			Operation: approvals.OperationEvictPod,
+			Action:    "evicted",
+			Kind:      "Pod",
+			Namespace: namespace,
+			Name:      name,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(EvictionResponse{Name: name, Namespace: namespace, Node: pod.Spec.NodeName, DryRun: dryRun})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// writeEvictionError writes the response of an eviction which failed: a 429 Too Many Requests if it was blocked by a
+// PodDisruptionBudget, along with the delay suggested by the API server if any, a 404 Not Found or a 409 Conflict if
+// the pod is gone or was replaced, or a 500 Internal Server Error otherwise
+func writeEvictionError(w http.ResponseWriter, r *http.Request, namespace, name string, err error) {
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "pod", name)
+	switch {
+	case apierrors.IsTooManyRequests(err):
+		logger.Info("Eviction blocked", "reason", err.Error())
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+		}
+		writeError(w, logger, http.StatusTooManyRequests, messages.New(messages.PodEvictionBlocked, "namespace", namespace, "name", name, "reason", err.Error()))
+	case apierrors.IsNotFound(err):
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.PodNotFound, "namespace", namespace, "name", name))
+	case apierrors.IsConflict(err):
+		writeError(w, logger, http.StatusConflict, messages.New(messages.PodReplaced, "namespace", namespace, "name", name))
+	default:
+		logger.Error(err, "Error evicting pod")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.PodEvictionFailed, "namespace", namespace, "name", name))
+	}
+}
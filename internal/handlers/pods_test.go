@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestPodsHandler_EvictPod(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(testScheme)
+	_ = policyv1.AddToScheme(testScheme)
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bar-7d4b9", Namespace: "foo", UID: "1234"}, Spec: corev1.PodSpec{NodeName: "node-a"}}
+	// The fake client evicts the pods regardless of the dry run, which is evaluated by the API server
+	dryRun := func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+		eviction := subResource.(*policyv1.Eviction)
+		if eviction.DeleteOptions == nil || !slices.Equal(eviction.DeleteOptions.DryRun, []string{metav1.DryRunAll}) {
+			return fmt.Errorf("expected a dry run eviction, got %+v", eviction.DeleteOptions)
+		}
+		return nil
+	}
+	blocked := func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+		return apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10)
+	}
+	replaced := func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+		return apierrors.NewConflict(k8sschema.GroupResource{Resource: "pods"}, "bar-7d4b9", nil)
+	}
+
+	tests := []struct {
+		name               string
+		url                string
+		create             func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error
+		expectedStatus     int
+		expectedResponse   string
+		expectedRetryAfter string
+		expectedEvicted    bool
+	}{
+		{
+			"Test Evict",
+			"/pods/foo/bar-7d4b9/evict",
+			nil,
+			http.StatusOK,
+			"{\"name\":\"bar-7d4b9\",\"namespace\":\"foo\",\"node\":\"node-a\"}\n",
+			"",
+			true,
+		},
+		{
+			"Test Dry Run",
+			"/pods/foo/bar-7d4b9/evict?dryRun=true",
+			dryRun,
+			http.StatusOK,
+			"{\"name\":\"bar-7d4b9\",\"namespace\":\"foo\",\"node\":\"node-a\",\"dryRun\":true}\n",
+			"",
+			false,
+		},
+		{
+			"Test Blocked By PodDisruptionBudget",
+			"/pods/foo/bar-7d4b9/evict",
+			blocked,
+			http.StatusTooManyRequests,
+			errorBody(http.StatusTooManyRequests, messages.PodEvictionBlocked, "namespace", "foo", "name", "bar-7d4b9", "reason", "Cannot evict pod as it would violate the pod's disruption budget."),
+			"10",
+			false,
+		},
+		{
+			"Test Pod Replaced",
+			"/pods/foo/bar-7d4b9/evict",
+			replaced,
+			http.StatusConflict,
+			errorBody(http.StatusConflict, messages.PodReplaced, "namespace", "foo", "name", "bar-7d4b9"),
+			"",
+			false,
+		},
+		{
+			"Test Pod Not Found",
+			"/pods/foo/baz/evict",
+			nil,
+			http.StatusNotFound,
+			errorBody(http.StatusNotFound, messages.PodNotFound, "namespace", "foo", "name", "baz"),
+			"",
+			false,
+		},
+		{
+			"Test Invalid Pod Name",
+			"/pods/foo/Bar/evict",
+			nil,
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.InvalidPodName, "name", "Bar", "reason", "a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')"),
+			"",
+			false,
+		},
+		{
+			"Test Invalid Dry Run",
+			"/pods/foo/bar-7d4b9/evict?dryRun=maybe",
+			nil,
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.InvalidBooleanParameter, "parameter", "dryRun", "value", "maybe"),
+			"",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The fake client deletes the pods it evicts, unless the eviction is intercepted
+			c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(pod.DeepCopy()).
+				WithInterceptorFuncs(interceptor.Funcs{SubResourceCreate: tt.create}).Build()
+			h := &PodsHandler{Client: c, LiveReader: c}
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /pods/{namespace}/{pod}/evict", h.EvictPod)
+
+			w := newResponseRecorder()
+			mux.ServeHTTP(w, newHttpTestRequest("POST", tt.url, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("EvictPod() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if w.Body.String() != tt.expectedResponse {
+				t.Errorf("EvictPod() = %v, want %v", w.Body.String(), tt.expectedResponse)
+			}
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != tt.expectedRetryAfter {
+				t.Errorf("EvictPod() Retry-After = %q, want %q", retryAfter, tt.expectedRetryAfter)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assertMatchesSchema(t, schema.EvictionResponse, w)
+			}
+			err := c.Get(context.Background(), client.ObjectKeyFromObject(pod), &corev1.Pod{})
+			if evicted := apierrors.IsNotFound(err); evicted != tt.expectedEvicted {
+				t.Errorf("EvictPod() evicted = %v, want %v", evicted, tt.expectedEvicted)
+			}
+		})
+	}
+}
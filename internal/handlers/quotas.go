@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/quota"
+	"k8s.io/klog/v2"
+)
+
+// QuotasHandler is an HTTP handler for the usage of the operation quotas
+type QuotasHandler struct {
+	Quotas interface {
+		Usage(ctx context.Context, identity string) ([]quota.Usage, error)
+	}
+}
+
+// GetQuotas handles the "/quotas" endpoint for GET method, returning the usage of the quotas of the caller over their
+// current period
+func (h *QuotasHandler) GetQuotas(w http.ResponseWriter, r *http.Request) {
+	h.writeUsage(w, r, auth.Identity(r))
+}
+
+// ListQuotaUsage handles the "/admin/quotas" endpoint for GET method, returning the usage of the quotas by all the
+// identities which made operations in their current period, or by the identity of the identity query parameter
+func (h *QuotasHandler) ListQuotaUsage(w http.ResponseWriter, r *http.Request) {
+	h.writeUsage(w, r, r.URL.Query().Get("identity"))
+}
+
+func (h *QuotasHandler) writeUsage(w http.ResponseWriter, r *http.Request, identity string) {
+	logger := klog.FromContext(r.Context())
+	usage, err := h.Quotas.Usage(r.Context(), identity)
+	if err != nil {
+		logger.Error(err, "Error listing quota usage")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.QuotaUsageListFailed))
+		return
+	}
+	writeList(w, r, usage, ListMetadata{})
+}
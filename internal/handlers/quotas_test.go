@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/quota"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+)
+
+// staticQuotas returns the usage of a fixed list, filtered by identity
+type staticQuotas struct {
+	usage []quota.Usage
+	err   error
+}
+
+func (q staticQuotas) Usage(_ context.Context, identity string) ([]quota.Usage, error) {
+	usage := []quota.Usage{}
+	for _, u := range q.usage {
+		if identity == "" || u.Identity == identity {
+			usage = append(usage, u)
+		}
+	}
+	return usage, q.err
+}
+
+func TestQuotasHandler(t *testing.T) {
+	reset := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	ciBot := quota.Usage{Quota: "ci-daily", Identity: "ci-bot", Operations: []string{"SetDeploymentReplicas"}, Period: quota.PeriodDay, Limit: 100, Used: 40, Remaining: 60, Reset: reset}
+	alice := quota.Usage{Quota: "everyone-daily", Identity: "alice", Operations: []string{"ApplyManifests"}, Period: quota.PeriodDay, Limit: 10, Used: 10, Remaining: 0, Reset: reset}
+	quotas := staticQuotas{usage: []quota.Usage{ciBot, alice}}
+
+	tests := []struct {
+		name         string
+		quotas       staticQuotas
+		url          string
+		identity     string
+		admin        bool
+		expectedCode int
+		expectedBody string
+		expected     []quota.Usage
+	}{
+		{name: "caller's usage", quotas: quotas, url: "/quotas", identity: "ci-bot", expectedCode: http.StatusOK, expected: []quota.Usage{ciBot}},
+		{name: "all identities", quotas: quotas, url: "/admin/quotas", admin: true, expectedCode: http.StatusOK, expected: []quota.Usage{ciBot, alice}},
+		{name: "single identity", quotas: quotas, url: "/admin/quotas?identity=alice", admin: true, expectedCode: http.StatusOK, expected: []quota.Usage{alice}},
+		{name: "store failure", quotas: staticQuotas{err: errors.New("boom")}, url: "/admin/quotas", admin: true,
+			expectedCode: http.StatusInternalServerError, expectedBody: errorBody(http.StatusInternalServerError, messages.QuotaUsageListFailed)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &QuotasHandler{Quotas: tt.quotas}
+			w := newResponseRecorder()
+			r := newHttpTestRequest("GET", tt.url, nil)
+			if tt.admin {
+				h.ListQuotaUsage(w, r)
+			} else {
+				h.GetQuotas(w, withIdentity(r, tt.identity))
+			}
+			if w.Code != tt.expectedCode {
+				t.Fatalf("status code = %v, want %v", w.Code, tt.expectedCode)
+			}
+			if tt.expectedBody != "" {
+				if w.Body.String() != tt.expectedBody {
+					t.Errorf("body = %v, want %v", w.Body.String(), tt.expectedBody)
+				}
+				return
+			}
+			assertMatchesSchema(t, schema.QuotasResponse, w)
+			var response []quota.Usage
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(response, tt.expected) {
+				t.Errorf("usage = %+v, want %+v", response, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/projection"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DataSourceHeader is the response header reporting where the returned data was read from
+const DataSourceHeader = "X-Data-Source"
+
+// StaleHeader is the response header set to true when the returned data was read from the cache while the API server
+// is unavailable, in which case the cache may be lagging behind
+const StaleHeader = "X-Data-Stale"
+
+// Data sources reported in the DataSourceHeader response header
+const (
+	DataSourceCache = "cache"
+	DataSourceLive  = "live"
+)
+
+// ResponseMeta describes where the data of a read response was read from, so that clients can reason about its staleness
+type ResponseMeta struct {
+	// DataSource is where the data was read from, either DataSourceCache or DataSourceLive
+	DataSource string `json:"dataSource"`
+	// CacheLastSync is the last time the cache received data from the API server. Only set for data read from the cache.
+	CacheLastSync *time.Time `json:"cacheLastSync,omitempty"`
+	// Stale is set when the data was read from the cache while the API server is unavailable
+	Stale bool `json:"stale,omitempty"`
+}
+
+// SummaryLister lists the lightweight summaries of the cached deployments, which are maintained as the cache receives
+// them
+type SummaryLister interface {
+	// Synced returns whether the summaries are in sync with the cache
+	Synced() bool
+	// List returns the summaries of the deployments in the given namespace, or all namespaces when empty
+	List(namespace string) []*projection.Summary
+}
+
+// sourceReader is a client.Reader which reads from the cache, and falls back to reading directly from the API server
+// when the cache can't serve the read (the resource is not cached, or the cache hasn't started yet).
+// It reports the source of the data in the DataSourceHeader response header.
+type sourceReader struct {
+	cache   client.Reader
+	live    client.Reader
+	useLive bool
+	// stale reports the reads served from the cache as possibly stale
+	stale bool
+	w     http.ResponseWriter
+}
+
+// Get retrieves an obj for the given object key, from the cache or directly from the API server
+func (s *sourceReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return s.read(ctx, func(reader client.Reader) error {
+		return reader.Get(ctx, key, obj, opts...)
+	})
+}
+
+// List retrieves a list of objects for the given options, from the cache or directly from the API server
+func (s *sourceReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return s.read(ctx, func(reader client.Reader) error {
+		return reader.List(ctx, list, opts...)
+	})
+}
+
+// summaries returns the summaries of the deployments in the given namespace (or all namespaces when empty) if the read
+// is served from the cache and the summaries are in sync with it, reporting the cache as the source of the data
+func (s *sourceReader) summaries(lister SummaryLister, namespace string) ([]*projection.Summary, bool) {
+	if s.useLive || lister == nil || !lister.Synced() {
+		return nil, false
+	}
+	s.setCacheSource()
+	return lister.List(namespace), true
+}
+
+func (s *sourceReader) read(ctx context.Context, readFn func(client.Reader) error) error {
+	if !s.useLive {
+		err := readFn(s.cache)
+		if s.live == nil || !isCacheUnavailable(err) {
+			s.setCacheSource()
+			return err
+		}
+		klog.FromContext(ctx).V(5).Info("Cache unavailable, falling back to a live read", "reason", err.Error())
+	}
+	s.w.Header().Set(DataSourceHeader, DataSourceLive)
+	return readFn(s.live)
+}
+
+// setCacheSource reports the cache as the source of the data in the response headers
+func (s *sourceReader) setCacheSource() {
+	s.w.Header().Set(DataSourceHeader, DataSourceCache)
+	if s.stale {
+		s.w.Header().Set(StaleHeader, "true")
+	}
+}
+
+// isCacheUnavailable returns whether the error means the cache can't serve the read, as opposed to a failed read
+func isCacheUnavailable(err error) bool {
+	var notCached *cache.ErrResourceNotCached
+	var notStarted *cache.ErrCacheNotStarted
+	return errors.As(err, &notCached) || errors.As(err, &notStarted)
+}
+
+// reader returns the client to read from for the given request.
+// Reads are served from the cache, unless the request passes ?cache=false, or the cache hasn't synced yet,
+// in which case they are served directly from the API server.
+// While the API server is unavailable, reads are served from the synced cache even with ?cache=false, and flagged as
+// possibly stale.
+func (h *DeploymentsHandler) reader(w http.ResponseWriter, r *http.Request) (*sourceReader, error) {
+	useCache := true
+	if value := r.URL.Query().Get("cache"); value != "" {
+		var err error
+		useCache, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, messages.New(messages.InvalidBooleanParameter, "parameter", "cache", "value", value)
+		}
+	}
+
+	synced := h.CacheSynced == nil || h.CacheSynced()
+	unavailable := h.APIServerUnavailable != nil && h.APIServerUnavailable()
+	useLive := false
+	if h.LiveReader != nil {
+		if !useCache && unavailable && synced {
+			klog.FromContext(r.Context()).V(5).Info("API server unavailable, serving from the cache instead of bypassing it")
+		} else if !useCache {
+			klog.FromContext(r.Context()).V(5).Info("Bypassing the cache")
+			useLive = true
+		} else if !synced {
+			klog.FromContext(r.Context()).V(5).Info("Cache not synced yet, reading directly from the API server")
+			useLive = true
+		}
+	}
+
+	return &sourceReader{cache: h.Client, live: h.LiveReader, useLive: useLive, stale: unavailable, w: w}, nil
+}
+
+// responseMeta returns the metadata of a response whose data was read with the reader returned by h.reader, which
+// reports the source of the data in the response headers
+func (h *DeploymentsHandler) responseMeta(w http.ResponseWriter) *ResponseMeta {
+	meta := &ResponseMeta{DataSource: w.Header().Get(DataSourceHeader), Stale: w.Header().Get(StaleHeader) == "true"}
+	if meta.DataSource == DataSourceCache && h.CacheLastSync != nil {
+		if lastSync := h.CacheLastSync(); !lastSync.IsZero() {
+			lastSync = lastSync.UTC()
+			meta.CacheLastSync = &lastSync
+		}
+	}
+	return meta
+}
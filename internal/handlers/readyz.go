@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"k8s.io/klog/v2"
+)
+
+// ReadyzCheck is a named readiness check. Check returns whether the component is ready, along with optional details about its state.
+// Degraded optionally returns whether the component is degraded, which is reported without failing the check.
+type ReadyzCheck struct {
+	Name     string
+	Check    func() (bool, any)
+	Degraded func() bool
+}
+
+type readyzCheckResult struct {
+	Ready    bool `json:"ready"`
+	Degraded bool `json:"degraded,omitempty"`
+	Details  any  `json:"details,omitempty"`
+}
+
+type readyzResponse struct {
+	Status string                       `json:"status"`
+	Checks map[string]readyzCheckResult `json:"checks,omitempty"`
+}
+
+// ReadyzHandler is an HTTP handler for the readyz API.
+// It reports the instance as ready once all of its checks pass, and until SetDraining is called during shutdown. While
+// ready, its status is "degraded" when any of its checks is.
+type ReadyzHandler struct {
+	Checks   []ReadyzCheck
+	draining atomic.Bool
+}
+
+// SetDraining flips the readiness of the instance to failing, so that no new traffic is routed to it
+func (h *ReadyzHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+func (h *ReadyzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	response, code := readyzResponse{Status: "ok"}, http.StatusOK
+	if len(h.Checks) > 0 {
+		response.Checks = make(map[string]readyzCheckResult, len(h.Checks))
+	}
+	for _, check := range h.Checks {
+		ready, details := check.Check()
+		degraded := check.Degraded != nil && check.Degraded()
+		response.Checks[check.Name] = readyzCheckResult{Ready: ready, Degraded: degraded, Details: details}
+		switch {
+		case !ready:
+			response.Status, code = "not ready", http.StatusServiceUnavailable
+		case degraded && code == http.StatusOK:
+			response.Status = "degraded"
+		}
+	}
+	if h.draining.Load() {
+		response.Status, code = "draining", http.StatusServiceUnavailable
+	}
+
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		klog.FromContext(r.Context()).Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReadyzHandler_ServeHTTP(t *testing.T) {
+	h := &ReadyzHandler{}
+
+	// Before shutdown, the instance should report as ready
+	w := newResponseRecorder()
+	h.ServeHTTP(w, newHttpTestRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() status code = %v, want %v", w.Code, http.StatusOK)
+	}
+	if rb := w.Body.String(); rb != "{\"status\":\"ok\"}\n" {
+		t.Errorf("ServeHTTP() response body = %v, want %v", rb, "{\"status\":\"ok\"}\n")
+	}
+
+	// Once draining, the instance should report as not ready
+	h.SetDraining()
+	w = newResponseRecorder()
+	h.ServeHTTP(w, newHttpTestRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("ServeHTTP() status code = %v, want %v", w.Code, http.StatusServiceUnavailable)
+	}
+	if rb := w.Body.String(); rb != "{\"status\":\"draining\"}\n" {
+		t.Errorf("ServeHTTP() response body = %v, want %v", rb, "{\"status\":\"draining\"}\n")
+	}
+}
+
+func TestReadyzHandler_ServeHTTP_Checks(t *testing.T) {
+	synced := false
+	h := &ReadyzHandler{
+		Checks: []ReadyzCheck{
+			{Name: "cache", Check: func() (bool, any) { return synced, nil }},
+		},
+	}
+
+	// A failing check makes the instance not ready
+	w := newResponseRecorder()
+	h.ServeHTTP(w, newHttpTestRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("ServeHTTP() status code = %v, want %v", w.Code, http.StatusServiceUnavailable)
+	}
+	expected := "{\"status\":\"not ready\",\"checks\":{\"cache\":{\"ready\":false}}}\n"
+	if rb := w.Body.String(); rb != expected {
+		t.Errorf("ServeHTTP() response body = %v, want %v", rb, expected)
+	}
+
+	// Once all checks pass, the instance is ready
+	synced = true
+	w = newResponseRecorder()
+	h.ServeHTTP(w, newHttpTestRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() status code = %v, want %v", w.Code, http.StatusOK)
+	}
+	expected = "{\"status\":\"ok\",\"checks\":{\"cache\":{\"ready\":true}}}\n"
+	if rb := w.Body.String(); rb != expected {
+		t.Errorf("ServeHTTP() response body = %v, want %v", rb, expected)
+	}
+}
+
+func TestReadyzHandler_ServeHTTP_Degraded(t *testing.T) {
+	degraded := true
+	h := &ReadyzHandler{
+		Checks: []ReadyzCheck{
+			{Name: "apiserver", Check: func() (bool, any) { return true, nil }, Degraded: func() bool { return degraded }},
+		},
+	}
+
+	// A degraded check is reported without failing the readiness
+	w := newResponseRecorder()
+	h.ServeHTTP(w, newHttpTestRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() status code = %v, want %v", w.Code, http.StatusOK)
+	}
+	expected := "{\"status\":\"degraded\",\"checks\":{\"apiserver\":{\"ready\":true,\"degraded\":true}}}\n"
+	if rb := w.Body.String(); rb != expected {
+		t.Errorf("ServeHTTP() response body = %v, want %v", rb, expected)
+	}
+
+	// Once recovered, the instance is ready again
+	degraded = false
+	w = newResponseRecorder()
+	h.ServeHTTP(w, newHttpTestRequest("GET", "/readyz", nil))
+	expected = "{\"status\":\"ok\",\"checks\":{\"apiserver\":{\"ready\":true}}}\n"
+	if rb := w.Body.String(); w.Code != http.StatusOK || rb != expected {
+		t.Errorf("ServeHTTP() = %v %v, want %v %v", w.Code, rb, http.StatusOK, expected)
+	}
+}
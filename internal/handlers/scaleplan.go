@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// OperationScaleDeploymentInSteps is the type of the operation scaling a deployment in steps, following a scale plan
+const OperationScaleDeploymentInSteps = "ScaleDeploymentInSteps"
+
+// defaultStepTimeout is how long each step of a scale plan may take to become ready, unless requested otherwise
+const defaultStepTimeout = 5 * time.Minute
+
+// ScalePlan is the request object of the scale plan API, scaling a deployment to its replicas in steps
+type ScalePlan struct {
+	Replicas *int32 `json:"replicas"`
+	// Step is how many replicas are added or removed at each step
+	Step int32 `json:"step"`
+	// Pause is how long the readiness of each step is watched before the next one, e.g. 30s. Defaults to 0.
+	Pause string `json:"pause,omitempty"`
+	// StepTimeout is how long each step may take to become ready, e.g. 2m. Defaults to 5m.
+	StepTimeout string `json:"stepTimeout,omitempty"`
+
+	pause       time.Duration
+	stepTimeout time.Duration
+}
+
+// Validate validates the ScalePlan object and returns an error if it is invalid
+func (p *ScalePlan) Validate() error {
+	if err := (&Replicas{p.Replicas}).Validate(); err != nil {
+		return err
+	}
+	if p.Step <= 0 {
+		return fmt.Errorf("step field must be greater than 0")
+	}
+	p.stepTimeout = defaultStepTimeout
+	if p.StepTimeout != "" {
+		timeout, err := time.ParseDuration(p.StepTimeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("stepTimeout field must be a positive duration, e.g. 2m")
+		}
+		p.stepTimeout = timeout
+	}
+	if p.Pause != "" {
+		pause, err := time.ParseDuration(p.Pause)
+		if err != nil || pause < 0 {
+			return fmt.Errorf("pause field must be a non-negative duration, e.g. 30s")
+		}
+		p.pause = pause
+	}
+	return nil
+}
+
+// steps returns the replicas of each step of the plan, scaling from the given replicas
+func (p *ScalePlan) steps(from int32) []int32 {
+	var steps []int32
+	for current, to := from, *p.Replicas; current != to; {
+		if current < to {
+			current = min(current+p.Step, to)
+		} else {
+			current = max(current-p.Step, to)
+		}
+		steps = append(steps, current)
+	}
+	return steps
+}
+
+// ScaleDeploymentInSteps handles the "/deployments/{namespace}/{deployment}/scale-plan" endpoint (and its namespace
+// scoped equivalent) for POST method. The deployment is scaled in the background, one step at a time, waiting for
+// each step to be ready and to stay ready for the pause of the plan. It's scaled back to the replicas it had if a step
+// doesn't become ready in time, or if its readiness degrades. The target replicas go through the admission plugins up
+// front, and the request returns a 202 Accepted pointing at the operation.
+func (h *DeploymentsHandler) ScaleDeploymentInSteps(w http.ResponseWriter, r *http.Request) {
+	if h.Operations == nil {
+		writeBadRequest(w, r, fmt.Errorf("scale plans are not supported by this endpoint"))
+		return
+	}
+
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	var plan ScalePlan
+	if err := decodeJSONBody(r, &plan); err != nil {
+		logger.Error(err, "Error parsing request body")
+		writeError(w, logger, http.StatusBadRequest, messages.New(messages.InvalidRequestBody, "reason", err.Error()))
+		return
+	}
+	if err := plan.Validate(); err != nil {
+		logger.Error(err, "Validation error")
+		writeError(w, logger, http.StatusBadRequest, messages.New(messages.ValidationFailed, "reason", err.Error()))
+		return
+	}
+
+	// Only the target replicas are admitted, the steps in between being how the deployment gets there
+	proposed := d.DeepCopy()
+	proposed.Spec.Replicas = plan.Replicas
+	if _, admitted := h.admit(w, r, d, proposed); !admitted {
+		return
+	}
+	plan.Replicas = ptr.To(ptr.Deref(proposed.Spec.Replicas, 1))
+	from := ptr.Deref(d.Spec.Replicas, 1)
+	steps := plan.steps(from)
+	logger.Info("Scaling deployment in steps", "from", from, "steps", steps)
+	h.recordDeploymentChange(r, approvals.OperationScaleDeploymentInSteps, "started scaling", "Scaling in steps started", d, replicasString(d.Spec.Replicas), replicasString(plan.Replicas))
+
+	key := client.ObjectKeyFromObject(d)
+	op := h.Operations.Start(r.Context(), OperationScaleDeploymentInSteps, key.String(), func(ctx context.Context, progress func(string)) (any, error) {
+		return h.runScalePlan(ctx, key, from, steps, plan, progress)
+	})
+
+	w.Header().Set("Location", "/operations/"+op.ID)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(op); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// runScalePlan scales the deployment through the steps, and scales it back to the replicas it had when a step fails
+func (h *DeploymentsHandler) runScalePlan(ctx context.Context, key client.ObjectKey, from int32, steps []int32, plan ScalePlan, progress func(string)) (*ScaleResult, error) {
+	reader := h.liveReader()
+	if len(steps) == 0 {
+		// The deployment already has the target replicas, so there's only its current rollout to wait for
+		steps = []int32{from}
+	}
+
+	var result *ScaleResult
+	for i, replicas := range steps {
+		stepProgress := func(message string) {
+			progress(fmt.Sprintf("step %d of %d (%d replicas): %s", i+1, len(steps), replicas, message))
+		}
+		generation, err := h.scaleTo(ctx, reader, key, replicas)
+		if err != nil {
+			return nil, h.revertScalePlan(ctx, reader, key, from, fmt.Errorf("failed to scale deployment %s to %d replicas: %w", key, replicas, err))
+		}
+
+		stepCtx, cancel := context.WithTimeout(ctx, plan.stepTimeout)
+		result, err = waitForRollout(stepCtx, reader, key, generation, stepProgress)
+		cancel()
+		if err != nil {
+			return nil, h.revertScalePlan(ctx, reader, key, from, fmt.Errorf("step %d of %d: %w", i+1, len(steps), err))
+		}
+		if plan.pause > 0 {
+			if err := watchReadiness(ctx, reader, key, replicas, plan.pause, stepProgress); err != nil {
+				return nil, h.revertScalePlan(ctx, reader, key, from, fmt.Errorf("step %d of %d: %w", i+1, len(steps), err))
+			}
+		}
+	}
+	return result, nil
+}
+
+// scaleTo patches the replicas of the deployment, and returns its generation
+func (h *DeploymentsHandler) scaleTo(ctx context.Context, reader client.Reader, key client.ObjectKey, replicas int32) (int64, error) {
+	d := &appsv1.Deployment{}
+	if err := reader.Get(ctx, key, d); err != nil {
+		return 0, err
+	}
+	if ptr.Deref(d.Spec.Replicas, 1) == replicas {
+		return d.Generation, nil
+	}
+	patch := client.MergeFrom(d.DeepCopy())
+	d.Spec.Replicas = ptr.To(replicas)
+	if err := h.Patch(ctx, d, patch); err != nil {
+		return 0, err
+	}
+	return d.Generation, nil
+}
+
+// revertScalePlan scales the deployment back to the replicas it had before the plan, and returns the cause of the
+// revert along with its outcome. The deployment is reverted even when the operation timed out.
+func (h *DeploymentsHandler) revertScalePlan(ctx context.Context, reader client.Reader, key client.ObjectKey, from int32, cause error) error {
+	logger := klog.FromContext(ctx)
+	logger.Error(cause, "Scale plan failed, reverting", "deployment", key, "replicas", from)
+	if _, err := h.scaleTo(context.WithoutCancel(ctx), reader, key, from); err != nil {
+		logger.Error(err, "Error reverting deployment", "deployment", key)
+		return fmt.Errorf("%w, and failed to revert to %d replicas: %v", cause, from, err)
+	}
+	return fmt.Errorf("%w, reverted to %d replicas", cause, from)
+}
+
+// watchReadiness watches the deployment for the given duration, and returns an error as soon as fewer of its replicas
+// than the given ones are ready
+func watchReadiness(ctx context.Context, reader client.Reader, key client.ObjectKey, replicas int32, duration time.Duration, progress func(string)) error {
+	d := &appsv1.Deployment{}
+	err := wait.PollUntilContextTimeout(ctx, rolloutPollInterval, duration, true, func(ctx context.Context) (bool, error) {
+		if err := reader.Get(ctx, key, d); err != nil {
+			return false, err
+		}
+		if d.Status.ReadyReplicas < replicas {
+			return false, fmt.Errorf("readiness of deployment %s degraded to %d of %d replicas", key, d.Status.ReadyReplicas, replicas)
+		}
+		progress(fmt.Sprintf("%d of %d replicas ready, pausing for %s", d.Status.ReadyReplicas, replicas, duration))
+		return false, nil
+	})
+	if wait.Interrupted(err) && ctx.Err() == nil {
+		// The deployment stayed ready for the whole duration
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/operations"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestScalePlan_Steps(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     int32
+		to       int32
+		step     int32
+		expected []int32
+	}{
+		{"Test Scale Up", 2, 7, 2, []int32{4, 6, 7}},
+		{"Test Scale Down", 7, 2, 3, []int32{4, 2}},
+		{"Test Single Step", 1, 3, 5, []int32{3}},
+		{"Test No Change", 3, 3, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := ScalePlan{Replicas: ptr.To(tt.to), Step: tt.step}
+			if steps := plan.steps(tt.from); !reflect.DeepEqual(steps, tt.expected) {
+				t.Errorf("steps() = %v, want %v", steps, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDeploymentsHandler_ScaleDeploymentInSteps(t *testing.T) {
+	rolloutPollInterval = 5 * time.Millisecond
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+
+	tests := []struct {
+		name string
+		body string
+		// readyCap is the most replicas which become ready
+		readyCap         int32
+		expectedStatus   int
+		expectedOutcome  operations.Status
+		expectedReplicas int32
+		expectedScales   []int32
+	}{
+		{"Test OK", `{"replicas": 7, "step": 2, "pause": "20ms"}`, 100, http.StatusAccepted, operations.StatusSucceeded, 7, []int32{4, 6, 7}},
+		{"Test Reverted On Step Timeout", `{"replicas": 7, "step": 2, "stepTimeout": "50ms"}`, 4, http.StatusAccepted, operations.StatusFailed, 2, []int32{4, 6, 2}},
+		{"Test Invalid Step", `{"replicas": 7, "step": 0}`, 100, http.StatusBadRequest, "", 2, nil},
+		{"Test Invalid Pause", `{"replicas": 7, "step": 1, "pause": "soon"}`, 100, http.StatusBadRequest, "", 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scales []int32
+			// The fake client doesn't run the deployment controller, so the patches roll the replicas out themselves
+			c := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(2))},
+				Status:     appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2},
+			}).WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					if err := c.Patch(ctx, obj, patch, opts...); err != nil {
+						return err
+					}
+					d := obj.(*appsv1.Deployment)
+					replicas := *d.Spec.Replicas
+					scales = append(scales, replicas)
+					d.Status = appsv1.DeploymentStatus{ObservedGeneration: d.Generation, Replicas: replicas, UpdatedReplicas: replicas, ReadyReplicas: min(replicas, tt.readyCap)}
+					return c.Status().Update(ctx, d)
+				},
+			}).Build()
+			manager := operations.NewManager(time.Hour, time.Minute, nil)
+			h := &DeploymentsHandler{Client: c, Operations: manager}
+
+			w := newResponseRecorder()
+			h.ScaleDeploymentInSteps(w, newHttpTestRequest("POST", "/deployments/foo/bar/scale-plan", strings.NewReader(tt.body)))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("ScaleDeploymentInSteps() status code = %v, want %v: %s", w.Code, tt.expectedStatus, w.Body.String())
+			}
+			assertMatchesSchema(t, schema.Operation, w)
+
+			if tt.expectedOutcome != "" {
+				var op operations.Operation
+				if err := json.NewDecoder(w.Body).Decode(&op); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				deadline := time.Now().Add(5 * time.Second)
+				for !op.Done() && time.Now().Before(deadline) {
+					time.Sleep(5 * time.Millisecond)
+					op, _ = manager.Get(context.Background(), op.ID)
+				}
+				if op.Status != tt.expectedOutcome {
+					t.Errorf("operation status = %v, want %v: %+v", op.Status, tt.expectedOutcome, op)
+				}
+				if tt.expectedOutcome == operations.StatusFailed && !strings.Contains(op.Message, "reverted to 2 replicas") {
+					t.Errorf("operation error = %v, want the deployment reverted", op.Message)
+				}
+			}
+
+			d := &appsv1.Deployment{}
+			if err := c.Get(context.Background(), client.ObjectKey{Namespace: "foo", Name: "bar"}, d); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if *d.Spec.Replicas != tt.expectedReplicas {
+				t.Errorf("deployment has %d replicas, want %d", *d.Spec.Replicas, tt.expectedReplicas)
+			}
+			if !reflect.DeepEqual(scales, tt.expectedScales) {
+				t.Errorf("deployment was scaled to %v, want %v", scales, tt.expectedScales)
+			}
+		})
+	}
+}
+
+func TestWatchReadiness(t *testing.T) {
+	rolloutPollInterval = 5 * time.Millisecond
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(4))},
+		Status:     appsv1.DeploymentStatus{Replicas: 4, UpdatedReplicas: 4, ReadyReplicas: 3},
+	}).Build()
+	key := client.ObjectKey{Namespace: "foo", Name: "bar"}
+
+	if err := watchReadiness(context.Background(), c, key, 3, 20*time.Millisecond, func(string) {}); err != nil {
+		t.Errorf("watchReadiness() error = %v, want the deployment to stay ready", err)
+	}
+	err := watchReadiness(context.Background(), c, key, 4, 20*time.Millisecond, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "degraded to 3 of 4 replicas") {
+		t.Errorf("watchReadiness() error = %v, want the readiness degraded", err)
+	}
+}
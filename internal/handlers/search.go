@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/search"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultSearchLimit is the number of results returned when the limit query parameter isn't set
+const defaultSearchLimit = 50
+
+// SearchKind is a kind of objects searched, along with the list type they're listed as
+type SearchKind struct {
+	// Kind is the name of the kind, as reported in the results and matched by the kind query parameter, e.g. Deployment
+	Kind string
+	// List is an empty list of the kind, copied for every search
+	List client.ObjectList
+}
+
+// SearchHandler is the handler for the search API, matching the cached objects against a free text query
+type SearchHandler struct {
+	// Client reads the objects, from the cache
+	Client client.Reader
+	// Kinds are the kinds of objects searched, i.e. the ones served from the cache
+	Kinds []SearchKind
+}
+
+// Search handles the "/search" endpoint (and its namespace scoped equivalent) for GET method.
+// It returns the objects whose name, labels or annotations match the q query parameter, from the best match,
+// optionally filtered by the kind and namespace query parameters, and capped to the limit query parameter.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		writeBadRequest(w, r, messages.New(messages.MissingQueryParameter, "parameter", "q"))
+		return
+	}
+	kinds := h.Kinds
+	if kind := query.Get("kind"); kind != "" {
+		kinds = nil
+		for _, k := range h.Kinds {
+			if strings.EqualFold(k.Kind, kind) {
+				kinds = append(kinds, k)
+			}
+		}
+		if len(kinds) == 0 {
+			writeBadRequest(w, r, messages.New(messages.UnsupportedParameterValue, "parameter", "kind", "value", kind, "allowed", strings.Join(h.kindNames(), ", ")))
+			return
+		}
+	}
+	limit := defaultSearchLimit
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			writeBadRequest(w, r, messages.New(messages.InvalidPositiveIntParameter, "parameter", "limit", "value", value))
+			return
+		}
+	}
+	namespace := r.PathValue("namespace")
+	if namespace == "" {
+		namespace = query.Get("namespace")
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace)
+
+	var opts []client.ListOption
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	scope, scoped := tenancy.ScopeFrom(r.Context())
+	results := []search.Result{}
+	for _, kind := range kinds {
+		list := kind.List.DeepCopyObject().(client.ObjectList)
+		if err := h.Client.List(r.Context(), list, opts...); err != nil {
+			logger.Error(err, "Error listing objects", "kind", kind.Kind)
+			writeError(w, logger, http.StatusInternalServerError, messages.New(messages.SearchFailed))
+			return
+		}
+		err := meta.EachListItem(list, func(item runtime.Object) error {
+			obj, err := meta.Accessor(item)
+			if err != nil {
+				return err
+			}
+			// With tenancy enabled, only the objects in the caller's namespaces are returned
+			if scoped && !scope.All && !scope.Allows(obj.GetNamespace()) {
+				return nil
+			}
+			if result, ok := search.Match(q, kind.Kind, obj); ok {
+				results = append(results, result)
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Error(err, "Error matching objects", "kind", kind.Kind)
+			writeError(w, logger, http.StatusInternalServerError, messages.New(messages.SearchFailed))
+			return
+		}
+	}
+	search.Rank(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	// The objects are always read from the cache
+	writeList(w, r, results, ListMetadata{DataSource: DataSourceCache})
+}
+
+// kindNames returns the names of the kinds searched
+func (h *SearchHandler) kindNames() []string {
+	names := make([]string, 0, len(h.Kinds))
+	for _, k := range h.Kinds {
+		names = append(names, k.Kind)
+	}
+	return names
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSearchHandler_Search(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	deployment := func(namespace, name string, labels map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels}}
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		deployment("team-a", "shop", nil),
+		deployment("team-a", "shop-worker", nil),
+		deployment("team-b", "checkout", map[string]string{"app.kubernetes.io/part-of": "shop"}),
+		deployment("team-b", "blog", nil),
+	).Build()
+	h := &SearchHandler{Client: c, Kinds: []SearchKind{{Kind: "Deployment", List: &appsv1.DeploymentList{}}}}
+	result := func(namespace, name string, score int, matches string) string {
+		return "{\"kind\":\"Deployment\",\"namespace\":\"" + namespace + "\",\"name\":\"" + name + "\",\"score\":" + strconv.Itoa(score) + ",\"matches\":[\"" + matches + "\"]}"
+	}
+
+	tests := []struct {
+		name             string
+		url              string
+		scope            *tenancy.Scope
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			"Test Search",
+			"/search?q=shop",
+			nil,
+			http.StatusOK,
+			"[" + result("team-a", "shop", 100, "name") + "," + result("team-a", "shop-worker", 75, "name") + "," +
+				result("team-b", "checkout", 20, "labels.app.kubernetes.io/part-of") + "]\n",
+		},
+		{
+			"Test Search In Namespace Of Kind",
+			"/search?q=shop&namespace=team-b&kind=deployment",
+			nil,
+			http.StatusOK,
+			"[" + result("team-b", "checkout", 20, "labels.app.kubernetes.io/part-of") + "]\n",
+		},
+		{
+			"Test Search Limit",
+			"/search?q=shop&limit=1",
+			nil,
+			http.StatusOK,
+			"[" + result("team-a", "shop", 100, "name") + "]\n",
+		},
+		{
+			"Test Search Filtered To The Tenant's Namespaces",
+			"/search?q=shop",
+			&tenancy.Scope{Namespaces: map[string]bool{"team-b": true}},
+			http.StatusOK,
+			"[" + result("team-b", "checkout", 20, "labels.app.kubernetes.io/part-of") + "]\n",
+		},
+		{
+			"Test Search No Results",
+			"/search?q=cart",
+			nil,
+			http.StatusOK,
+			"[]\n",
+		},
+		{
+			"Test Search Missing Query",
+			"/search?q=%20",
+			nil,
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.MissingQueryParameter, "parameter", "q"),
+		},
+		{
+			"Test Search Unknown Kind",
+			"/search?q=shop&kind=Pod",
+			nil,
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.UnsupportedParameterValue, "parameter", "kind", "value", "Pod", "allowed", "Deployment"),
+		},
+		{
+			"Test Search Invalid Limit",
+			"/search?q=shop&limit=0",
+			nil,
+			http.StatusBadRequest,
+			errorBody(http.StatusBadRequest, messages.InvalidPositiveIntParameter, "parameter", "limit", "value", "0"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			r := newHttpTestRequest("GET", tt.url, nil)
+			if tt.scope != nil {
+				r = r.WithContext(tenancy.WithScope(r.Context(), *tt.scope))
+			}
+			h.Search(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if rb := w.Body.String(); rb != tt.expectedResponse {
+				t.Errorf("response body = %v, want %v", rb, tt.expectedResponse)
+			}
+			assertMatchesSchema(t, schema.SearchResponse, w)
+		})
+	}
+}
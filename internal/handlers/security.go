@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+)
+
+// SecurityEventsHandler is an HTTP handler for the feed of the security events of the authentication, e.g. lockouts
+type SecurityEventsHandler struct {
+	Guard *auth.Guard
+}
+
+// GetSecurityEvents handles the "/admin/security/events" endpoint for GET method, returning the events kept in memory,
+// oldest first. The events after a given ID are returned with ?after=, so that the feed can be polled, and the ones of
+// a given type with ?type=.
+func (h *SecurityEventsHandler) GetSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	if value := r.URL.Query().Get("after"); value != "" {
+		var err error
+		if after, err = strconv.ParseInt(value, 10, 64); err != nil || after < 0 {
+			writeBadRequest(w, r, errors.New("after must be the ID of an event"))
+			return
+		}
+	}
+	writeList(w, r, h.Guard.Events(after, r.URL.Query().Get("type")), ListMetadata{})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+)
+
+func TestSecurityEventsHandler_GetSecurityEvents(t *testing.T) {
+	guard := auth.NewGuard(auth.GuardConfig{Threshold: 2, Window: 30 * time.Second, Duration: 30 * time.Second, EventsSize: 10})
+	guard.Failure("203.0.113.7", "", "", auth.TokenAuthenticator, auth.FailureInvalidCredentials, errors.New("unknown token"))
+	guard.Failure("203.0.113.7", "", "", auth.TokenAuthenticator, auth.FailureInvalidCredentials, errors.New("unknown token"))
+	h := &SecurityEventsHandler{Guard: guard}
+
+	tests := []struct {
+		name          string
+		url           string
+		expectedCode  int
+		expectedTypes []string
+	}{
+		{name: "all events", url: "/admin/security/events", expectedCode: http.StatusOK, expectedTypes: []string{auth.EventAuthFailure, auth.EventAuthFailure, auth.EventLockout}},
+		{name: "after an event", url: "/admin/security/events?after=2", expectedCode: http.StatusOK, expectedTypes: []string{auth.EventLockout}},
+		{name: "of a type", url: "/admin/security/events?type=lockout", expectedCode: http.StatusOK, expectedTypes: []string{auth.EventLockout}},
+		{name: "none after the latest", url: "/admin/security/events?after=3", expectedCode: http.StatusOK, expectedTypes: []string{}},
+		{name: "invalid after", url: "/admin/security/events?after=latest", expectedCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			h.GetSecurityEvents(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedCode {
+				t.Fatalf("status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			assertMatchesSchema(t, schema.SecurityEvents, w)
+			if tt.expectedTypes == nil {
+				return
+			}
+			var events []auth.SecurityEvent
+			if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil {
+				t.Fatalf("error decoding response: %v", err)
+			}
+			if len(events) != len(tt.expectedTypes) {
+				t.Fatalf("events = %+v, want %v", events, tt.expectedTypes)
+			}
+			for i, event := range events {
+				if event.Type != tt.expectedTypes[i] {
+					t.Errorf("events[%d].type = %v, want %v", i, event.Type, tt.expectedTypes[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/slo"
+)
+
+// SLOHandler is an HTTP handler for the service level objectives of the API
+type SLOHandler struct {
+	SLOs interface{ Statuses() []slo.Status }
+}
+
+// ListSLOs handles the "/admin/slo" endpoint for GET method, returning the objectives along with their remaining error
+// budget and burn rates, as counted by the instance serving the request
+func (h *SLOHandler) ListSLOs(w http.ResponseWriter, r *http.Request) {
+	writeList(w, r, h.SLOs.Statuses(), ListMetadata{})
+}
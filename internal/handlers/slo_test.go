@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/slo"
+)
+
+// staticSLOs returns a fixed list of objective statuses
+type staticSLOs []slo.Status
+
+func (s staticSLOs) Statuses() []slo.Status {
+	return append([]slo.Status{}, s...)
+}
+
+func TestSLOHandler_ListSLOs(t *testing.T) {
+	since := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	statuses := staticSLOs{
+		{Name: "availability", Objective: 99.9, Window: "720h0m0s", Requests: 1000, BadRequests: 0, ErrorBudgetRemaining: 1,
+			BurnRates: map[string]float64{"5m": 0, "30m": 0, "1h": 0, "6h": 0}},
+		{Name: "reads", Description: "Reads are fast", Routes: []string{"GET /*"}, Objective: 99, Latency: "500ms", Window: "168h0m0s",
+			Requests: 100, BadRequests: 20, ErrorBudgetRemaining: -19, BurnRates: map[string]float64{"5m": 20, "30m": 20, "1h": 20, "6h": 20},
+			FastBurnSince: &since},
+	}
+
+	w := newResponseRecorder()
+	(&SLOHandler{SLOs: statuses}).ListSLOs(w, newHttpTestRequest("GET", "/admin/slo", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ListSLOs() status code = %v, want %v", w.Code, http.StatusOK)
+	}
+	assertMatchesSchema(t, schema.SLOs, w)
+
+	var response []slo.Status
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !reflect.DeepEqual(response, []slo.Status(statuses)) {
+		t.Errorf("ListSLOs() = %+v, want %+v", response, statuses)
+	}
+}
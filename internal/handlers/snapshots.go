@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiwarnings"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/snapshots"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeploymentRestoreResponse is the response object of the restores of deployment snapshots
+type DeploymentRestoreResponse struct {
+	DeploymentResponse
+	// Snapshot is the ID of the restored snapshot
+	Snapshot string `json:"snapshot"`
+	// Warnings are the warnings of the admission plugins
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// CreateDeploymentSnapshot handles the "/deployments/{namespace}/{deployment}/snapshot" endpoint (and its namespace
+// scoped equivalent) for POST method, storing the current spec of the deployment
+func (h *DeploymentsHandler) CreateDeploymentSnapshot(w http.ResponseWriter, r *http.Request) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	snapshot, err := h.Snapshots.Create(r.Context(), auth.Identity(r), d)
+	if err != nil {
+		logger.Error(err, "Error creating snapshot")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.SnapshotCreateFailed, "namespace", namespace, "name", deployment))
+		return
+	}
+	logger.Info("Created snapshot", "snapshot", snapshot.ID)
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(snapshot.Summary); err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// ListDeploymentSnapshots handles the "/deployments/{namespace}/{deployment}/snapshots" endpoint (and its namespace
+// scoped equivalent) for GET method, newest first. The specs of the snapshots are left out, since they may hold
+// sensitive values.
+func (h *DeploymentsHandler) ListDeploymentSnapshots(w http.ResponseWriter, r *http.Request) {
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	summaries, err := h.Snapshots.List(r.Context(), namespace, deployment)
+	if err != nil {
+		logger.Error(err, "Error listing snapshots")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.SnapshotsListFailed, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	writeList(w, r, summaries, ListMetadata{})
+}
+
+// RestoreDeploymentSnapshot handles the "/deployments/{namespace}/{deployment}/restore/{snapshot}" endpoint (and its
+// namespace scoped equivalent) for POST method, replacing the spec of the deployment with the one of the snapshot.
+// The restored spec goes through the admission plugins like any other change.
+func (h *DeploymentsHandler) RestoreDeploymentSnapshot(w http.ResponseWriter, r *http.Request) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	id := r.PathValue("snapshot")
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment, "snapshot", id)
+
+	snapshot, err := h.Snapshots.Get(r.Context(), namespace, deployment, id)
+	if errors.Is(err, snapshots.ErrNotFound) {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.SnapshotNotFound, "id", id, "namespace", namespace, "name", deployment))
+		return
+	}
+	if err != nil {
+		logger.Error(err, "Error getting snapshot")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.SnapshotGetFailed, "id", id, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+	// The selector of a deployment is immutable, so the snapshots taken before it was recreated with another one
+	// can't be restored
+	if !apiequality.Semantic.DeepEqual(d.Spec.Selector, snapshot.Spec.Selector) {
+		writeError(w, logger, http.StatusConflict, messages.New(messages.SnapshotSelectorChanged, "id", id, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	patch := client.MergeFrom(d.DeepCopy())
+	proposed := d.DeepCopy()
+	proposed.Spec = *snapshot.Spec.DeepCopy()
+	warnings, admitted := h.admit(w, r, d, proposed)
+	if !admitted {
+		return
+	}
+	d = proposed
+	if err := h.Patch(r.Context(), d, patch); err != nil {
+		logger.Error(err, "Error patching deployment")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.DeploymentPatchFailed, "namespace", namespace, "name", deployment))
+		return
+	}
+	logger.Info("Restored snapshot")
+	h.recordDeploymentChange(r, approvals.OperationRestoreDeploymentSnapshot, "restored", "Restored", d, "", "snapshot "+id)
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(DeploymentRestoreResponse{
+		DeploymentResponse: DeploymentResponse{Name: deployment, Namespace: namespace},
+		Snapshot:           id,
+		Warnings:           append(warnings, apiwarnings.Collected(r.Context())...),
+	})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/snapshots"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeploymentsHandler_Snapshots(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme) // Register apps/v1 types
+
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(int32(3)),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "bar"}},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:v1"}}}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(d).Build()
+	h := &DeploymentsHandler{Client: c, Snapshots: snapshots.New(store.NewMemory(), 0)}
+
+	// Snapshot the deployment, then change it
+	w := newResponseRecorder()
+	h.CreateDeploymentSnapshot(w, withIdentity(newHttpTestRequest("POST", "/deployments/foo/bar/snapshot", nil), "alice"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateDeploymentSnapshot() status code = %v, want %v: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	assertMatchesSchema(t, schema.Snapshot, w)
+	var created snapshots.Summary
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.CreatedBy != "alice" || *created.Replicas != 3 || created.Images["app"] != "app:v1" {
+		t.Errorf("CreateDeploymentSnapshot() = %+v, want the replicas and images of the deployment", created)
+	}
+
+	live := &appsv1.Deployment{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(d), live); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	live.Spec.Replicas = ptr.To(int32(10))
+	live.Spec.Template.Spec.Containers[0].Image = "app:v2"
+	if err := c.Update(context.Background(), live); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	w = newResponseRecorder()
+	h.ListDeploymentSnapshots(w, newHttpTestRequest("GET", "/deployments/foo/bar/snapshots", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ListDeploymentSnapshots() status code = %v, want %v", w.Code, http.StatusOK)
+	}
+	assertMatchesSchema(t, schema.SnapshotsResponse, w)
+	var listed []snapshots.Summary
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != created.ID {
+		t.Errorf("ListDeploymentSnapshots() = %+v, want the snapshot %s", listed, created.ID)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		snapshot       string
+		expectedStatus int
+	}{
+		{"Test RestoreDeploymentSnapshot Not Found", "/deployments/foo/bar/restore/missing", "missing", http.StatusNotFound},
+		{"Test RestoreDeploymentSnapshot Of Another Deployment", "/deployments/foo/baz/restore/" + created.ID, created.ID, http.StatusNotFound},
+		{"Test RestoreDeploymentSnapshot OK", "/deployments/foo/bar/restore/" + created.ID, created.ID, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newHttpTestRequest("POST", tt.path, nil)
+			r.SetPathValue("snapshot", tt.snapshot)
+			w := newResponseRecorder()
+			h.RestoreDeploymentSnapshot(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("RestoreDeploymentSnapshot() status code = %v, want %v: %s", w.Code, tt.expectedStatus, w.Body.String())
+			}
+			assertMatchesSchema(t, schema.RestoreResponse, w)
+		})
+	}
+
+	restored := &appsv1.Deployment{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(d), restored); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if *restored.Spec.Replicas != 3 || restored.Spec.Template.Spec.Containers[0].Image != "app:v1" {
+		t.Errorf("RestoreDeploymentSnapshot() restored %d replicas of %s, want 3 of app:v1", *restored.Spec.Replicas, restored.Spec.Template.Spec.Containers[0].Image)
+	}
+
+	// Snapshots taken with another selector can't be restored
+	restored.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "bar-v2"}}
+	if err := c.Update(context.Background(), restored); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	r := newHttpTestRequest("POST", "/deployments/foo/bar/restore/"+created.ID, nil)
+	r.SetPathValue("snapshot", created.ID)
+	w = newResponseRecorder()
+	h.RestoreDeploymentSnapshot(w, r)
+	if w.Code != http.StatusConflict {
+		t.Errorf("RestoreDeploymentSnapshot() status code = %v, want %v", w.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"k8s.io/klog/v2"
+)
+
+// StartupzHandler is an HTTP handler for the startupz API, meant for the startup probe of the kubelet.
+// It reports the instance as started once all of its checks pass, e.g. the informers synced and the TLS material
+// loaded, and keeps reporting it as started from then on, so that only /readyz and /livez matter after startup. Until
+// then, it reports the details of every check, e.g. the sync progress of the informers on large clusters.
+type StartupzHandler struct {
+	Checks  []ReadyzCheck
+	started atomic.Bool
+}
+
+func (h *StartupzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	response, started := readyzResponse{Status: "ok"}, h.started.Load()
+	if !started {
+		started = true
+		response.Checks = make(map[string]readyzCheckResult, len(h.Checks))
+		for _, check := range h.Checks {
+			ready, details := check.Check()
+			response.Checks[check.Name] = readyzCheckResult{Ready: ready, Details: details}
+			started = started && ready
+		}
+		if started {
+			h.started.Store(true)
+			klog.FromContext(r.Context()).V(2).Info("Startup checks passed")
+		}
+	}
+	code := http.StatusOK
+	if !started {
+		response.Status, code = "starting", http.StatusServiceUnavailable
+	}
+
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		klog.FromContext(r.Context()).Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestStartupzHandler_ServeHTTP(t *testing.T) {
+	synced := false
+	h := &StartupzHandler{
+		Checks: []ReadyzCheck{
+			{Name: "informers", Check: func() (bool, any) { return synced, map[string]int{"progress": 40} }},
+			{Name: "tls", Check: func() (bool, any) { return true, nil }},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		synced         bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Test Informers Syncing",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "{\"status\":\"starting\",\"checks\":{\"informers\":{\"ready\":false,\"details\":{\"progress\":40}},\"tls\":{\"ready\":true}}}\n",
+		},
+		{
+			name:           "Test Started",
+			synced:         true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"status\":\"ok\",\"checks\":{\"informers\":{\"ready\":true,\"details\":{\"progress\":40}},\"tls\":{\"ready\":true}}}\n",
+		},
+		{
+			// Once started, the checks aren't run anymore
+			name:           "Test Still Started",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"status\":\"ok\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synced = tt.synced
+			w := newResponseRecorder()
+			h.ServeHTTP(w, newHttpTestRequest("GET", "/startupz", nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("ServeHTTP() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if rb := w.Body.String(); rb != tt.expectedBody {
+				t.Errorf("ServeHTTP() response body = %v, want %v", rb, tt.expectedBody)
+			}
+		})
+	}
+}
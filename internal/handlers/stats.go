@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/stats"
+	"k8s.io/klog/v2"
+)
+
+// StatsResponse is the response object for the request statistics API
+type StatsResponse struct {
+	// Since is when the statistics started being recorded, i.e. when the server started
+	Since time.Time `json:"since"`
+	// Window is the number of most recent requests the latency percentiles of every entry are computed over
+	Window  int           `json:"window"`
+	Entries []stats.Entry `json:"entries"`
+}
+
+// StatsHandler is the handler for the request statistics API
+type StatsHandler struct {
+	Stats interface {
+		Since() time.Time
+		Window() int
+		Entries() []stats.Entry
+	}
+}
+
+// GetStats handles the "/admin/stats" endpoint for GET method.
+// It returns the number of requests and the latency percentiles of every client identity and route, from the most to
+// the least requested, optionally filtered by the identity query parameter and truncated to the limit query parameter.
+func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+
+	entries := h.Stats.Entries()
+	query := r.URL.Query()
+	if query.Has("identity") {
+		identity := query.Get("identity")
+		entries = slices.DeleteFunc(entries, func(entry stats.Entry) bool { return entry.Identity != identity })
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			writeBadRequest(w, r, messages.New(messages.InvalidPositiveIntParameter, "parameter", "limit", "value", value))
+			return
+		}
+		entries = entries[:min(limit, len(entries))]
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(StatsResponse{Since: h.Stats.Since(), Window: h.Stats.Window(), Entries: entries})
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/stats"
+)
+
+// staticStats returns fixed request statistics
+type staticStats []stats.Entry
+
+func (s staticStats) Since() time.Time       { return time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC) }
+func (s staticStats) Window() int            { return 1000 }
+func (s staticStats) Entries() []stats.Entry { return append([]stats.Entry{}, s...) }
+
+func TestStatsHandler_GetStats(t *testing.T) {
+	lastSeen := time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)
+	storm := stats.Entry{Identity: "ci-bot", Route: "GET /deployments", Count: 5000, Statuses: map[string]int64{"2xx": 5000},
+		Latency: stats.Latency{P50: 12.5, P90: 40, P99: 120.3, Max: 250}, LastSeen: lastSeen}
+	portal := stats.Entry{Identity: "portal", Route: "GET /deployments/{namespace}/{deployment}/replicas", Count: 20,
+		Statuses: map[string]int64{"2xx": 18, "4xx": 2}, Latency: stats.Latency{P50: 1, P90: 2, P99: 3, Max: 3}, LastSeen: lastSeen}
+	h := &StatsHandler{Stats: staticStats{storm, portal}}
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+		expected     []stats.Entry
+	}{
+		{name: "all entries", url: "/admin/stats", expectedCode: http.StatusOK, expected: []stats.Entry{storm, portal}},
+		{name: "filtered by identity", url: "/admin/stats?identity=portal", expectedCode: http.StatusOK, expected: []stats.Entry{portal}},
+		{name: "limited", url: "/admin/stats?limit=1", expectedCode: http.StatusOK, expected: []stats.Entry{storm}},
+		{name: "invalid limit", url: "/admin/stats?limit=0", expectedCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResponseRecorder()
+			h.GetStats(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedCode {
+				t.Fatalf("status code = %v, want %v: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			assertMatchesSchema(t, schema.StatsResponse, w)
+			if tt.expected == nil {
+				return
+			}
+			var response StatsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("error decoding response: %v", err)
+			}
+			if response.Window != 1000 || !reflect.DeepEqual(response.Entries, tt.expected) {
+				t.Errorf("response = %+v, want %+v", response, tt.expected)
+			}
+		})
+	}
+}
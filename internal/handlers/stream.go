@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// streamBufferSize is the size of the buffer the streamed responses are written through, so that they are sent in
+// chunks rather than with a write per element
+const streamBufferSize = 32 << 10
+
+// jsonArrayWriter streams a JSON array to the underlying writer element by element, so that only a single element is
+// held encoded in memory at a time rather than the whole array. The elements are encoded the same way as with a
+// json.Encoder, so the array is identical to encoding a slice of them.
+type jsonArrayWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+	enc *json.Encoder
+	n   int
+}
+
+// newJSONArrayWriter returns a jsonArrayWriter writing to w
+func newJSONArrayWriter(w io.Writer) *jsonArrayWriter {
+	a := &jsonArrayWriter{w: w}
+	a.enc = json.NewEncoder(&a.buf)
+	return a
+}
+
+// Write encodes v as the next element of the array
+func (a *jsonArrayWriter) Write(v any) error {
+	a.buf.Reset()
+	if a.n == 0 {
+		a.buf.WriteByte('[')
+	} else {
+		a.buf.WriteByte(',')
+	}
+	if err := a.enc.Encode(v); err != nil {
+		return err
+	}
+	a.n++
+	// Drop the newline the encoder terminates every value with
+	_, err := a.w.Write(bytes.TrimSuffix(a.buf.Bytes(), []byte("\n")))
+	return err
+}
+
+// Close terminates the array, which is empty if no element was written
+func (a *jsonArrayWriter) Close() error {
+	end := "]"
+	if a.n == 0 {
+		end = "[]"
+	}
+	_, err := io.WriteString(a.w, end)
+	return err
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONArrayWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements []any
+	}{
+		{"empty", []any{}},
+		{"single element", []any{DeploymentResponse{Name: "foo", Namespace: "bar"}}},
+		{"multiple elements", []any{DeploymentResponse{Name: "foo", Namespace: "bar"}, DeploymentResponse{Name: "baz", Namespace: "bar"}}},
+		{"escaped characters", []any{"<a&b>", "line\nbreak", map[string]int{"z": 1, "a": 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			a := newJSONArrayWriter(&got)
+			for _, element := range tt.elements {
+				if err := a.Write(element); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := a.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			// The streamed array is identical to the encoded slice, without its trailing newline
+			want, err := json.Marshal(tt.elements)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if got.String() != string(want) {
+				t.Errorf("streamed array = %s, want %s", got.String(), want)
+			}
+		})
+	}
+}
+
+func TestJSONArrayWriter_EncodingError(t *testing.T) {
+	var got bytes.Buffer
+	a := newJSONArrayWriter(&got)
+	if err := a.Write(func() {}); err == nil {
+		t.Errorf("Write() of an unsupported value error = nil, want an error")
+	}
+	if got.Len() != 0 {
+		t.Errorf("Write() of an unsupported value wrote %q, want nothing", got.String())
+	}
+}
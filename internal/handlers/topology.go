@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Types of the edges of a topology
+const (
+	// EdgeOwns links a deployment to its pods
+	EdgeOwns = "owns"
+	// EdgeSelects links a service to the pods it selects, or to the deployment when it has no pods
+	EdgeSelects = "selects"
+	// EdgeRoutes links an ingress or an HTTPRoute to the services it routes to
+	EdgeRoutes = "routes"
+)
+
+// httpRouteListGVK is the kind of the lists of Gateway API HTTPRoutes, which are read as unstructured objects since
+// their CRDs may not be installed
+var httpRouteListGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRouteList"}
+
+// TopologyNode is a node of the topology of a deployment
+type TopologyNode struct {
+	// ID identifies the node in the edges, as Kind/name
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Ready is whether a pod is ready
+	Ready *bool `json:"ready,omitempty"`
+	// Type is the type of a service, e.g. ClusterIP or LoadBalancer
+	Type string `json:"type,omitempty"`
+	// Hosts are the hosts an ingress or an HTTPRoute is served on
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// TopologyEdge is an edge of the topology of a deployment
+type TopologyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+	// Paths are the host and path prefixes an ingress routes to a service, e.g. example.com/api
+	Paths []string `json:"paths,omitempty"`
+}
+
+// DeploymentTopologyResponse is the response object for the topology API
+type DeploymentTopologyResponse struct {
+	DeploymentResponse
+	Nodes []TopologyNode `json:"nodes"`
+	Edges []TopologyEdge `json:"edges"`
+}
+
+// GetDeploymentTopology handles the "/topology/{namespace}/{deployment}" endpoint (and its namespace scoped
+// equivalent) for GET method.
+// It resolves how the deployment is exposed, as a graph of the deployment, its pods, the services selecting them,
+// and the ingresses and Gateway API HTTPRoutes routing to those services, so that clients need a single request
+// rather than one per kind. Pods, services, ingresses and HTTPRoutes aren't cached, so they are always listed
+// directly from the API server. HTTPRoutes are left out when their CRD isn't installed, or they can't be listed.
+func (h *DeploymentsHandler) GetDeploymentTopology(w http.ResponseWriter, r *http.Request) {
+	reader, err := h.reader(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	namespace, deployment, err := parseNamespaceAndDeploymentNameFromURL(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	logger := klog.FromContext(r.Context()).WithValues("namespace", namespace, "deployment", deployment)
+
+	d, err := h.getDeployment(r.Context(), reader, namespace, deployment)
+	if err != nil {
+		writeError(w, logger, http.StatusNotFound, messages.New(messages.DeploymentNotFound, "namespace", namespace, "name", deployment))
+		return
+	}
+	pods, err := h.listPods(r, d)
+	if err != nil {
+		logger.Error(err, "Error listing pods")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.DeploymentPodsListFailed, "namespace", namespace, "name", deployment))
+		return
+	}
+	response, err := h.topology(r.Context(), d, pods)
+	if err != nil {
+		logger.Error(err, "Error resolving the topology")
+		writeError(w, logger, http.StatusInternalServerError, messages.New(messages.DeploymentTopologyFailed, "namespace", namespace, "name", deployment))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Error(err, "Error encoding response")
+	}
+}
+
+// topology returns the graph of the deployment, its pods, the services selecting them, and the ingresses and
+// HTTPRoutes routing to those services
+func (h *DeploymentsHandler) topology(ctx context.Context, d *appsv1.Deployment, pods []corev1.Pod) (*DeploymentTopologyResponse, error) {
+	response := &DeploymentTopologyResponse{
+		DeploymentResponse: DeploymentResponse{Name: d.Name, Namespace: d.Namespace},
+		Nodes:              []TopologyNode{},
+		Edges:              []TopologyEdge{},
+	}
+	deploymentID := nodeID("Deployment", d.Name)
+	response.Nodes = append(response.Nodes, TopologyNode{ID: deploymentID, Kind: "Deployment", Name: d.Name})
+	for i := range pods {
+		ready := podReady(&pods[i])
+		response.Nodes = append(response.Nodes, TopologyNode{ID: nodeID("Pod", pods[i].Name), Kind: "Pod", Name: pods[i].Name, Ready: &ready})
+		response.Edges = append(response.Edges, TopologyEdge{From: deploymentID, To: nodeID("Pod", pods[i].Name), Type: EdgeOwns})
+	}
+
+	// Services select the pods by their labels, so the ones selecting the pod template of the deployment are kept
+	services := &corev1.ServiceList{}
+	if err := h.liveReader().List(ctx, services, client.InNamespace(d.Namespace)); err != nil {
+		return nil, fmt.Errorf("error listing services: %w", err)
+	}
+	selected := map[string]bool{}
+	for _, service := range services.Items {
+		if len(service.Spec.Selector) == 0 || !labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(d.Spec.Template.Labels)) {
+			continue
+		}
+		serviceID := nodeID("Service", service.Name)
+		selected[service.Name] = true
+		response.Nodes = append(response.Nodes, TopologyNode{ID: serviceID, Kind: "Service", Name: service.Name, Type: string(service.Spec.Type)})
+		matched := false
+		for _, pod := range pods {
+			if labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+				response.Edges = append(response.Edges, TopologyEdge{From: serviceID, To: nodeID("Pod", pod.Name), Type: EdgeSelects})
+				matched = true
+			}
+		}
+		if !matched {
+			response.Edges = append(response.Edges, TopologyEdge{From: serviceID, To: deploymentID, Type: EdgeSelects})
+		}
+	}
+	if len(selected) == 0 {
+		return response, nil
+	}
+
+	ingresses := &networkingv1.IngressList{}
+	if err := h.liveReader().List(ctx, ingresses, client.InNamespace(d.Namespace)); err != nil {
+		return nil, fmt.Errorf("error listing ingresses: %w", err)
+	}
+	for _, ingress := range ingresses.Items {
+		paths := ingressPaths(&ingress)
+		var hosts []string
+		for _, rule := range ingress.Spec.Rules {
+			if rule.Host != "" && !slices.Contains(hosts, rule.Host) {
+				hosts = append(hosts, rule.Host)
+			}
+		}
+		response.addRoutes(TopologyNode{ID: nodeID("Ingress", ingress.Name), Kind: "Ingress", Name: ingress.Name, Hosts: hosts}, paths, selected)
+	}
+
+	routes := &unstructured.UnstructuredList{}
+	routes.SetGroupVersionKind(httpRouteListGVK)
+	if err := h.liveReader().List(ctx, routes, client.InNamespace(d.Namespace)); err != nil {
+		// The Gateway API is optional, as are the permissions on its HTTPRoutes
+		if !meta.IsNoMatchError(err) && !apierrors.IsForbidden(err) {
+			return nil, fmt.Errorf("error listing HTTPRoutes: %w", err)
+		}
+		klog.FromContext(ctx).V(4).Info("Can't list HTTPRoutes, skipping them", "reason", err.Error())
+		return response, nil
+	}
+	for _, route := range routes.Items {
+		hosts, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+		response.addRoutes(TopologyNode{ID: nodeID("HTTPRoute", route.GetName()), Kind: "HTTPRoute", Name: route.GetName(), Hosts: hosts}, httpRouteServices(&route), selected)
+	}
+	return response, nil
+}
+
+// addRoutes adds the node of an ingress or an HTTPRoute, along with its edges to the selected services, if it routes
+// to any of them. paths holds the paths routed to each service.
+func (t *DeploymentTopologyResponse) addRoutes(node TopologyNode, paths map[string][]string, selected map[string]bool) {
+	var edges []TopologyEdge
+	for service, servicePaths := range paths {
+		if selected[service] {
+			edges = append(edges, TopologyEdge{From: node.ID, To: nodeID("Service", service), Type: EdgeRoutes, Paths: servicePaths})
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+	slices.SortFunc(edges, func(a, b TopologyEdge) int { return strings.Compare(a.To, b.To) })
+	t.Nodes = append(t.Nodes, node)
+	t.Edges = append(t.Edges, edges...)
+}
+
+// ingressPaths returns the paths the ingress routes to each service, as host and path, e.g. example.com/api. The
+// default backend is reported with the * path.
+func ingressPaths(ingress *networkingv1.Ingress) map[string][]string {
+	paths := map[string][]string{}
+	add := func(backend *networkingv1.IngressBackend, path string) {
+		if backend == nil || backend.Service == nil || slices.Contains(paths[backend.Service.Name], path) {
+			return
+		}
+		paths[backend.Service.Name] = append(paths[backend.Service.Name], path)
+	}
+	add(ingress.Spec.DefaultBackend, "*")
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			add(&path.Backend, rule.Host+path.Path)
+		}
+	}
+	return paths
+}
+
+// httpRouteServices returns the services the HTTPRoute routes to in its own namespace, along with no paths, since the
+// matches of HTTPRoutes aren't limited to path prefixes
+func httpRouteServices(route *unstructured.Unstructured) map[string][]string {
+	services := map[string][]string{}
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	for _, rule := range rules {
+		rule, ok := rule.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		refs, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+		for _, ref := range refs {
+			ref, ok := ref.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			group, _, _ := unstructured.NestedString(ref, "group")
+			kind, _, _ := unstructured.NestedString(ref, "kind")
+			namespace, _, _ := unstructured.NestedString(ref, "namespace")
+			name, _, _ := unstructured.NestedString(ref, "name")
+			// The backends default to services in the namespace of the route
+			if group == "" && (kind == "" || kind == "Service") && (namespace == "" || namespace == route.GetNamespace()) && name != "" {
+				services[name] = nil
+			}
+		}
+	}
+	return services
+}
+
+// nodeID returns the ID of the node of the given kind and name in a topology
+func nodeID(kind, name string) string {
+	return kind + "/" + name
+}
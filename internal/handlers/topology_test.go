@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/messages"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/schema"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeploymentsHandler_GetDeploymentTopology(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	_ = corev1.AddToScheme(testScheme)
+	_ = networkingv1.AddToScheme(testScheme)
+	appLabels := map[string]string{"app": "web"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "foo"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: appLabels},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: appLabels}},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-7d4b9-x2kqz", Namespace: "foo", Labels: appLabels,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7d4b9", Controller: ptr.To(true)}}},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+	}
+	service := func(name string, selector map[string]string) *corev1.Service {
+		return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "foo"}, Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP, Selector: selector}}
+	}
+	backend := func(service string) networkingv1.IngressBackend {
+		return networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: service, Port: networkingv1.ServiceBackendPort{Number: 80}}}
+	}
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "foo"},
+		Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "web.example.com", IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+			Paths: []networkingv1.HTTPIngressPath{{Path: "/", Backend: backend("web")}, {Path: "/api", Backend: backend("api")}},
+		}}}}},
+	}
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "foo"},
+		"spec": map[string]interface{}{
+			"hostnames": []interface{}{"web.example.org"},
+			"rules":     []interface{}{map[string]interface{}{"backendRefs": []interface{}{map[string]interface{}{"name": "web", "port": int64(80)}}}},
+		},
+	}}
+
+	tests := []struct {
+		name             string
+		objects          []client.Object
+		httpRoutes       bool
+		url              string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			"Test Topology",
+			[]client.Object{deployment, pod, service("web", appLabels), service("api", map[string]string{"app": "api"}), ingress, route},
+			true,
+			"/topology/foo/web",
+			http.StatusOK,
+			"{\"name\":\"web\",\"namespace\":\"foo\",\"nodes\":[" +
+				"{\"id\":\"Deployment/web\",\"kind\":\"Deployment\",\"name\":\"web\"}," +
+				"{\"id\":\"Pod/web-7d4b9-x2kqz\",\"kind\":\"Pod\",\"name\":\"web-7d4b9-x2kqz\",\"ready\":true}," +
+				"{\"id\":\"Service/web\",\"kind\":\"Service\",\"name\":\"web\",\"type\":\"ClusterIP\"}," +
+				"{\"id\":\"Ingress/web\",\"kind\":\"Ingress\",\"name\":\"web\",\"hosts\":[\"web.example.com\"]}," +
+				"{\"id\":\"HTTPRoute/web\",\"kind\":\"HTTPRoute\",\"name\":\"web\",\"hosts\":[\"web.example.org\"]}],\"edges\":[" +
+				"{\"from\":\"Deployment/web\",\"to\":\"Pod/web-7d4b9-x2kqz\",\"type\":\"owns\"}," +
+				"{\"from\":\"Service/web\",\"to\":\"Pod/web-7d4b9-x2kqz\",\"type\":\"selects\"}," +
+				"{\"from\":\"Ingress/web\",\"to\":\"Service/web\",\"type\":\"routes\",\"paths\":[\"web.example.com/\"]}," +
+				"{\"from\":\"HTTPRoute/web\",\"to\":\"Service/web\",\"type\":\"routes\"}]}\n",
+		},
+		{
+			"Test No Pods And No Gateway API",
+			[]client.Object{deployment, service("web", appLabels), ingress},
+			false,
+			"/topology/foo/web",
+			http.StatusOK,
+			"{\"name\":\"web\",\"namespace\":\"foo\",\"nodes\":[" +
+				"{\"id\":\"Deployment/web\",\"kind\":\"Deployment\",\"name\":\"web\"}," +
+				"{\"id\":\"Service/web\",\"kind\":\"Service\",\"name\":\"web\",\"type\":\"ClusterIP\"}," +
+				"{\"id\":\"Ingress/web\",\"kind\":\"Ingress\",\"name\":\"web\",\"hosts\":[\"web.example.com\"]}],\"edges\":[" +
+				"{\"from\":\"Service/web\",\"to\":\"Deployment/web\",\"type\":\"selects\"}," +
+				"{\"from\":\"Ingress/web\",\"to\":\"Service/web\",\"type\":\"routes\",\"paths\":[\"web.example.com/\"]}]}\n",
+		},
+		{
+			"Test Not Exposed",
+			[]client.Object{deployment, pod, service("api", map[string]string{"app": "api"})},
+			false,
+			"/topology/foo/web",
+			http.StatusOK,
+			"{\"name\":\"web\",\"namespace\":\"foo\",\"nodes\":[" +
+				"{\"id\":\"Deployment/web\",\"kind\":\"Deployment\",\"name\":\"web\"}," +
+				"{\"id\":\"Pod/web-7d4b9-x2kqz\",\"kind\":\"Pod\",\"name\":\"web-7d4b9-x2kqz\",\"ready\":true}],\"edges\":[" +
+				"{\"from\":\"Deployment/web\",\"to\":\"Pod/web-7d4b9-x2kqz\",\"type\":\"owns\"}]}\n",
+		},
+		{
+			"Test Deployment Not Found",
+			[]client.Object{deployment},
+			false,
+			"/topology/foo/api",
+			http.StatusNotFound,
+			errorBody(http.StatusNotFound, messages.DeploymentNotFound, "namespace", "foo", "name", "api"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The Gateway API kinds are only known to the API server when their CRDs are installed
+			mapper := meta.NewDefaultRESTMapper(nil)
+			for gvk := range testScheme.AllKnownTypes() {
+				mapper.Add(gvk, meta.RESTScopeNamespace)
+			}
+			if tt.httpRoutes {
+				mapper.Add(k8sschema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}, meta.RESTScopeNamespace)
+			}
+			c := fake.NewClientBuilder().WithScheme(testScheme).WithRESTMapper(mapper).WithObjects(tt.objects...).Build()
+			h := &DeploymentsHandler{Client: c, LiveReader: c}
+
+			w := newResponseRecorder()
+			h.GetDeploymentTopology(w, newHttpTestRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("GetDeploymentTopology() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if w.Body.String() != tt.expectedResponse {
+				t.Errorf("GetDeploymentTopology() = %v, want %v", w.Body.String(), tt.expectedResponse)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assertMatchesSchema(t, schema.TopologyResponse, w)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/version"
+	"k8s.io/klog/v2"
+)
+
+// VersionHandler is an HTTP handler for the version API
+type VersionHandler struct {
+	// KubeContext is the context of the kubeconfig the server uses, empty in-cluster
+	KubeContext string
+}
+
+// versionResponse is the version of the server, along with the kubeconfig context it uses
+type versionResponse struct {
+	version.Info
+	KubeContext string `json:"kubeContext,omitempty"`
+}
+
+func (h *VersionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(versionResponse{Info: version.Get(), KubeContext: h.KubeContext})
+	if err != nil {
+		klog.FromContext(r.Context()).Error(err, "Error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
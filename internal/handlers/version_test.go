@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		kubeContext string
+	}{
+		{name: "kubeconfig context", kubeContext: "prod-eks"},
+		{name: "in-cluster"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			(&VersionHandler{KubeContext: tt.kubeContext}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if body["version"] == "" || body["goVersion"] == "" {
+				t.Errorf("body = %v, want the version information", body)
+			}
+			kubeContext, ok := body["kubeContext"]
+			if (tt.kubeContext == "" && ok) || (tt.kubeContext != "" && kubeContext != tt.kubeContext) {
+				t.Errorf("kubeContext = %v, want %q", kubeContext, tt.kubeContext)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Package healthhistory keeps a short in-memory history of the health probes of the API server, and tells a flapping
+// API server, whose probes keep alternating between healthy and unhealthy, from a sustained outage.
+package healthhistory
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the health of the API server, as told by its latest probes
+type State string
+
+// States of the API server
+const (
+	// StateUnknown is the state before the first probe
+	StateUnknown State = "unknown"
+	// StateHealthy is the state once the latest probe succeeded, and the probes aren't flapping
+	StateHealthy State = "healthy"
+	// StateUnhealthy is the state once the latest probe failed, and the probes aren't flapping, i.e. a sustained outage
+	StateUnhealthy State = "unhealthy"
+	// StateFlapping is the state of the probes alternating between healthy and unhealthy within the history
+	StateFlapping State = "flapping"
+)
+
+// Probe is the result of a health probe of the API server
+type Probe struct {
+	Time    time.Time `json:"time"`
+	Healthy bool      `json:"healthy"`
+	// Status is ok for the healthy probes, or the reason of the failure
+	Status string `json:"status"`
+	// Duration is how long the probe took, e.g. 12ms
+	Duration string `json:"duration"`
+}
+
+// Report is the summary of the history, along with its probes
+type Report struct {
+	State State `json:"state"`
+	// Flapping is whether the probes changed between healthy and unhealthy at least the flap threshold times within
+	// the history
+	Flapping bool `json:"flapping"`
+	// Transitions is the number of times the probes changed between healthy and unhealthy within the history
+	Transitions int `json:"transitions"`
+	// ConsecutiveFailures is the number of failed probes since the latest healthy one
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	// UnhealthySince is the time of the first of the consecutive failures, if the latest probe failed
+	UnhealthySince *time.Time `json:"unhealthySince,omitempty"`
+	// Probes are the probes of the history, from the oldest
+	Probes []Probe `json:"probes"`
+}
+
+// History keeps the latest probes of the API server, up to its size
+type History struct {
+	size          int
+	flapThreshold int
+	now           func() time.Time
+
+	mu     sync.Mutex
+	probes []Probe
+	// failures is the number of consecutive failures, which may predate the probes still in the history
+	failures       int
+	unhealthySince time.Time
+}
+
+// New returns a new empty History keeping the given number of probes, which reports the probes as flapping once they
+// changed between healthy and unhealthy at least flapThreshold times within the history
+func New(size, flapThreshold int) *History {
+	return &History{size: size, flapThreshold: flapThreshold, now: time.Now, probes: make([]Probe, 0, size)}
+}
+
+// Record adds the result of a probe which took the given duration to the history, evicting the oldest probe once full
+func (h *History) Record(healthy bool, status string, duration time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	if healthy {
+		h.failures = 0
+	} else {
+		if h.failures == 0 {
+			h.unhealthySince = now
+		}
+		h.failures++
+	}
+	if len(h.probes) == h.size {
+		copy(h.probes, h.probes[1:])
+		h.probes = h.probes[:len(h.probes)-1]
+	}
+	h.probes = append(h.probes, Probe{Time: now, Healthy: healthy, Status: status, Duration: duration.String()})
+}
+
+// Report returns the summary of the history, along with a copy of its probes
+func (h *History) Report() Report {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	report := Report{State: StateUnknown, ConsecutiveFailures: h.failures, Probes: append([]Probe{}, h.probes...)}
+	for i := 1; i < len(h.probes); i++ {
+		if h.probes[i].Healthy != h.probes[i-1].Healthy {
+			report.Transitions++
+		}
+	}
+	report.Flapping = h.flapThreshold > 0 && report.Transitions >= h.flapThreshold
+	if h.failures > 0 {
+		since := h.unhealthySince
+		report.UnhealthySince = &since
+	}
+	switch {
+	case len(h.probes) == 0:
+	case report.Flapping:
+		report.State = StateFlapping
+	case h.probes[len(h.probes)-1].Healthy:
+		report.State = StateHealthy
+	default:
+		report.State = StateUnhealthy
+	}
+	return report
+}
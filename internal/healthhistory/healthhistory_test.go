@@ -0,0 +1,99 @@
+package healthhistory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistory_Report(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                string
+		probes              []bool
+		expectedState       State
+		expectedTransitions int
+		expectedFailures    int
+		expectedProbes      int
+		// expectedSince is the index of the probe the API server is unhealthy since, or -1
+		expectedSince int
+	}{
+		{name: "no probes", probes: nil, expectedState: StateUnknown, expectedSince: -1},
+		{name: "healthy", probes: []bool{true, true, true}, expectedState: StateHealthy, expectedProbes: 3, expectedSince: -1},
+		{
+			name:                "blip",
+			probes:              []bool{true, false, true, true},
+			expectedState:       StateHealthy,
+			expectedTransitions: 2,
+			expectedProbes:      4,
+			expectedSince:       -1,
+		},
+		{
+			name:                "sustained outage",
+			probes:              []bool{true, true, false, false, false},
+			expectedState:       StateUnhealthy,
+			expectedTransitions: 1,
+			expectedFailures:    3,
+			expectedProbes:      5,
+			expectedSince:       2,
+		},
+		{
+			name:                "flapping",
+			probes:              []bool{true, false, true, false, true},
+			expectedState:       StateFlapping,
+			expectedTransitions: 4,
+			expectedProbes:      5,
+			expectedSince:       -1,
+		},
+		{
+			// The oldest probes are evicted, so that the transitions only count within the history, while the
+			// consecutive failures predating the history are still accounted for
+			name:             "outage longer than the history",
+			probes:           []bool{true, false, true, false, true, false, false, false, false, false, false},
+			expectedState:    StateUnhealthy,
+			expectedFailures: 6,
+			expectedProbes:   5,
+			expectedSince:    5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(5, 4)
+			now := start
+			h.now = func() time.Time { return now }
+			for _, healthy := range tt.probes {
+				status := "ok"
+				if !healthy {
+					status = "connection refused"
+				}
+				h.Record(healthy, status, 10*time.Millisecond)
+				now = now.Add(10 * time.Second)
+			}
+
+			report := h.Report()
+			if report.State != tt.expectedState {
+				t.Errorf("State = %v, want %v", report.State, tt.expectedState)
+			}
+			if report.Flapping != (tt.expectedState == StateFlapping) {
+				t.Errorf("Flapping = %v, want %v", report.Flapping, tt.expectedState == StateFlapping)
+			}
+			if report.Transitions != tt.expectedTransitions {
+				t.Errorf("Transitions = %v, want %v", report.Transitions, tt.expectedTransitions)
+			}
+			if report.ConsecutiveFailures != tt.expectedFailures {
+				t.Errorf("ConsecutiveFailures = %v, want %v", report.ConsecutiveFailures, tt.expectedFailures)
+			}
+			if len(report.Probes) != tt.expectedProbes {
+				t.Errorf("len(Probes) = %v, want %v", len(report.Probes), tt.expectedProbes)
+			}
+			switch {
+			case tt.expectedSince < 0 && report.UnhealthySince != nil:
+				t.Errorf("UnhealthySince = %v, want nil", report.UnhealthySince)
+			case tt.expectedSince >= 0:
+				expected := start.Add(time.Duration(tt.expectedSince) * 10 * time.Second)
+				if report.UnhealthySince == nil || !report.UnhealthySince.Equal(expected) {
+					t.Errorf("UnhealthySince = %v, want %v", report.UnhealthySince, expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package hibernation scales all the deployments and statefulsets of a namespace to zero, and later back to the replicas
+// they had, e.g. to save the costs of development environments outside of working hours. The replicas of the hibernated
+// workloads are declared in an annotation, so that they live and die with them and need no extra storage.
+package hibernation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/admission"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/events"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReplicasAnnotation declares the replicas a hibernated workload is scaled back to when woken up
+const ReplicasAnnotation = "go-k8s-http-api.io/hibernated-replicas"
+
+// Statuses of the workloads
+const (
+	StatusHibernated = "hibernated"
+	StatusWoken      = "woken"
+	StatusSkipped    = "skipped"
+	StatusFailed     = "failed"
+)
+
+// Kinds are the kinds of the workloads which are hibernated
+var Kinds = []schema.GroupVersionKind{appsv1.SchemeGroupVersion.WithKind("Deployment"), appsv1.SchemeGroupVersion.WithKind("StatefulSet")}
+
+// Workload is the outcome of hibernating or waking up a workload
+type Workload struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Replicas are the replicas of the workload before the change
+	Replicas int32  `json:"replicas"`
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"`
+	// Warnings are the warnings of the admission plugins about the change
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Hibernator hibernates and wakes up the workloads of namespaces
+type Hibernator struct {
+	// Client patches the workloads
+	Client client.Client
+	// Reader lists the workloads. It should read directly from the API server, since statefulsets aren't cached.
+	Reader client.Reader
+	// Admission runs the changes of the workloads through the admission plugins. Every change is admitted when nil.
+	Admission *admission.Chain
+	// Events records the hibernated and woken up workloads as events on them. Changes aren't recorded when nil.
+	Events *events.Recorder
+}
+
+// Hibernate scales the workloads of the namespace to zero, recording their replicas. The workloads already scaled to
+// zero are skipped, so that they stay scaled to zero once woken up. Failing to change a workload doesn't prevent the
+// others from being changed, and is reported in its status instead.
+func (h *Hibernator) Hibernate(ctx context.Context, identity, namespace string) ([]Workload, error) {
+	return h.each(ctx, identity, namespace, func(obj *unstructured.Unstructured, replicas int32) (Workload, *unstructured.Unstructured) {
+		w := Workload{Kind: obj.GetKind(), Name: obj.GetName(), Replicas: replicas}
+		if _, hibernated := obj.GetAnnotations()[ReplicasAnnotation]; hibernated {
+			w.Status, w.Message = StatusSkipped, "already hibernated"
+			return w, nil
+		}
+		if replicas == 0 {
+			w.Status, w.Message = StatusSkipped, "already scaled to zero"
+			return w, nil
+		}
+		proposed := obj.DeepCopy()
+		setAnnotation(proposed, ReplicasAnnotation, strconv.Itoa(int(replicas)))
+		if err := unstructured.SetNestedField(proposed.Object, int64(0), "spec", "replicas"); err != nil {
+			w.Status, w.Message = StatusFailed, err.Error()
+			return w, nil
+		}
+		w.Status = StatusHibernated
+		return w, proposed
+	})
+}
+
+// Wake scales the hibernated workloads of the namespace back to the replicas they had. The workloads scaled by someone
+// else while hibernated keep their replicas. Failing to change a workload doesn't prevent the others from being
+// changed, and is reported in its status instead.
+func (h *Hibernator) Wake(ctx context.Context, identity, namespace string) ([]Workload, error) {
+	return h.each(ctx, identity, namespace, func(obj *unstructured.Unstructured, replicas int32) (Workload, *unstructured.Unstructured) {
+		value, hibernated := obj.GetAnnotations()[ReplicasAnnotation]
+		if !hibernated {
+			return Workload{}, nil
+		}
+		w := Workload{Kind: obj.GetKind(), Name: obj.GetName(), Replicas: replicas, Status: StatusWoken}
+		proposed := obj.DeepCopy()
+		setAnnotation(proposed, ReplicasAnnotation, "")
+		previous, err := strconv.ParseInt(value, 10, 32)
+		switch {
+		case err != nil || previous < 0:
+			// The annotation was edited by hand, so it's removed without changing the replicas
+			w.Status, w.Message = StatusSkipped, fmt.Sprintf("invalid %s annotation %q", ReplicasAnnotation, value)
+		case replicas != 0:
+			w.Status, w.Message = StatusSkipped, fmt.Sprintf("scaled to %d while hibernated", replicas)
+		default:
+			if err := unstructured.SetNestedField(proposed.Object, previous, "spec", "replicas"); err != nil {
+				w.Status, w.Message = StatusFailed, err.Error()
+				return w, nil
+			}
+		}
+		return w, proposed
+	})
+}
+
+// each lists the workloads of the namespace, and patches the ones change returns a proposed object for, once admitted.
+// The workloads change returns an empty Workload for are left out.
+func (h *Hibernator) each(ctx context.Context, identity, namespace string, change func(*unstructured.Unstructured, int32) (Workload, *unstructured.Unstructured)) ([]Workload, error) {
+	var results []Workload
+	for _, gvk := range Kinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := h.Reader.List(ctx, list, client.InNamespace(namespace)); err != nil {
+			return nil, fmt.Errorf("failed to list %ss: %w", gvk.Kind, err)
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			obj.SetGroupVersionKind(gvk)
+			replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the replicas of %s %s: %w", gvk.Kind, obj.GetName(), err)
+			}
+			if !found {
+				replicas = 1
+			}
+			w, proposed := change(obj, int32(replicas))
+			if w.Kind == "" {
+				continue
+			}
+			if proposed != nil {
+				w = h.patch(ctx, identity, obj, proposed, w)
+			}
+			results = append(results, w)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Kind != results[j].Kind {
+			return results[i].Kind < results[j].Kind
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
+
+// patch admits the proposed object, and patches the live one with it
+func (h *Hibernator) patch(ctx context.Context, identity string, obj, proposed *unstructured.Unstructured, w Workload) Workload {
+	req := &admission.Request{Object: proposed, OldObject: obj, Identity: identity}
+	if err := h.Admission.Admit(ctx, req); err != nil {
+		var rejected *admission.Error
+		if !errors.As(err, &rejected) {
+			err = fmt.Errorf("failed to evaluate the admission plugins: %w", err)
+		}
+		w.Status, w.Message = StatusFailed, err.Error()
+		return w
+	}
+	if err := h.Client.Patch(ctx, req.Object, client.MergeFrom(obj)); err != nil {
+		w.Status, w.Message = StatusFailed, err.Error()
+		return w
+	}
+	w.Warnings = req.Warnings
+	replicas, _, _ := unstructured.NestedInt64(req.Object.Object, "spec", "replicas")
+	switch w.Status {
+	case StatusHibernated:
+		h.Events.Record(req.Object, approvals.OperationHibernateNamespace, "Hibernated", identity, strconv.Itoa(int(w.Replicas)), "0")
+	case StatusWoken:
+		h.Events.Record(req.Object, approvals.OperationWakeNamespace, "Woken up", identity, "0", strconv.Itoa(int(replicas)))
+	}
+	return w
+}
+
+// setAnnotation sets the annotation of the object, or removes it when the value is empty
+func setAnnotation(obj *unstructured.Unstructured, key, value string) {
+	annotations := obj.GetAnnotations()
+	if value == "" {
+		delete(annotations, key)
+	} else {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[key] = value
+	}
+	obj.SetAnnotations(annotations)
+}
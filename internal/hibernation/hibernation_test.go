@@ -0,0 +1,134 @@
+package hibernation
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/admission"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/bounds"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/events"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHibernator(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme) // Register apps/v1 types
+
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "dev"}, Spec: appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "idle", Namespace: "dev"}, Spec: appsv1.DeploymentSpec{Replicas: ptr.To(int32(0))}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "bounded", Namespace: "dev", Annotations: map[string]string{bounds.MinReplicasAnnotation: "1"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(2))},
+		},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "dev"}, Spec: appsv1.StatefulSetSpec{Replicas: ptr.To(int32(1))}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"}, Spec: appsv1.DeploymentSpec{Replicas: ptr.To(int32(5))}},
+	).Build()
+	chain, err := admission.NewChain([]string{admission.ReplicaBoundsPlugin}, admission.Options{})
+	if err != nil {
+		t.Fatalf("NewChain() error = %v", err)
+	}
+	recorder := record.NewFakeRecorder(10)
+	h := &Hibernator{Client: c, Reader: c, Admission: chain, Events: &events.Recorder{Recorder: recorder, Component: "go-k8s-http-api"}}
+
+	workloads, err := h.Hibernate(ctx, "alice", "dev")
+	if err != nil {
+		t.Fatalf("Hibernate() error = %v", err)
+	}
+	expected := []Workload{
+		{Kind: "Deployment", Name: "bounded", Replicas: 2, Status: StatusFailed, Message: "Replicas must be within the bounds [1, ∞) of deployment bounded in namespace dev"},
+		{Kind: "Deployment", Name: "idle", Replicas: 0, Status: StatusSkipped, Message: "already scaled to zero"},
+		{Kind: "Deployment", Name: "web", Replicas: 3, Status: StatusHibernated},
+		{Kind: "StatefulSet", Name: "db", Replicas: 1, Status: StatusHibernated},
+	}
+	if !reflect.DeepEqual(workloads, expected) {
+		t.Errorf("Hibernate() = %+v, want %+v", workloads, expected)
+	}
+	assertReplicas(t, c, &appsv1.Deployment{}, "dev", "web", 0)
+	assertReplicas(t, c, &appsv1.StatefulSet{}, "dev", "db", 0)
+	assertReplicas(t, c, &appsv1.Deployment{}, "prod", "web", 5)
+	assertEvents(t, recorder,
+		"Normal HibernateNamespace Hibernated by go-k8s-http-api on behalf of CN=alice from 3 to 0",
+		"Normal HibernateNamespace Hibernated by go-k8s-http-api on behalf of CN=alice from 1 to 0")
+
+	// Hibernating twice keeps the recorded replicas
+	workloads, err = h.Hibernate(ctx, "alice", "dev")
+	if err != nil {
+		t.Fatalf("Hibernate() error = %v", err)
+	}
+	if workloads[2].Status != StatusSkipped || workloads[2].Message != "already hibernated" {
+		t.Errorf("Hibernate() = %+v, want the hibernated deployment skipped", workloads[2])
+	}
+
+	// Workloads scaled while hibernated keep their replicas
+	db := &appsv1.StatefulSet{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "dev", Name: "db"}, db); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	db.Spec.Replicas = ptr.To(int32(2))
+	if err := c.Update(ctx, db); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	workloads, err = h.Wake(ctx, "alice", "dev")
+	if err != nil {
+		t.Fatalf("Wake() error = %v", err)
+	}
+	expected = []Workload{
+		{Kind: "Deployment", Name: "web", Replicas: 0, Status: StatusWoken},
+		{Kind: "StatefulSet", Name: "db", Replicas: 2, Status: StatusSkipped, Message: "scaled to 2 while hibernated"},
+	}
+	if !reflect.DeepEqual(workloads, expected) {
+		t.Errorf("Wake() = %+v, want %+v", workloads, expected)
+	}
+	web := assertReplicas(t, c, &appsv1.Deployment{}, "dev", "web", 3)
+	if _, found := web.GetAnnotations()[ReplicasAnnotation]; found {
+		t.Errorf("Wake() kept the %s annotation", ReplicasAnnotation)
+	}
+	assertReplicas(t, c, &appsv1.StatefulSet{}, "dev", "db", 2)
+	assertEvents(t, recorder, "Normal WakeNamespace Woken up by go-k8s-http-api on behalf of CN=alice from 0 to 3")
+
+	// Nothing is left to wake up
+	if workloads, err := h.Wake(ctx, "alice", "dev"); err != nil || len(workloads) != 0 {
+		t.Errorf("Wake() = %+v, %v, want nothing woken up", workloads, err)
+	}
+}
+
+// assertEvents checks the events recorded since the last check
+func assertEvents(t *testing.T, recorder *record.FakeRecorder, expected ...string) {
+	t.Helper()
+	var recorded []string
+	for len(recorder.Events) > 0 {
+		recorded = append(recorded, <-recorder.Events)
+	}
+	if !reflect.DeepEqual(recorded, expected) {
+		t.Errorf("recorded events %q, want %q", recorded, expected)
+	}
+}
+
+// assertReplicas checks the replicas of the workload, and returns it
+func assertReplicas(t *testing.T, c client.Client, obj client.Object, namespace, name string, replicas int32) client.Object {
+	t.Helper()
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	var got *int32
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		got = o.Spec.Replicas
+	case *appsv1.StatefulSet:
+		got = o.Spec.Replicas
+	}
+	if got == nil || *got != replicas {
+		t.Errorf("%s %s/%s has %v replicas, want %d", obj.GetObjectKind().GroupVersionKind().Kind, namespace, name, got, replicas)
+	}
+	return obj
+}
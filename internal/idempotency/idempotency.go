@@ -0,0 +1,219 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// KeyHeader is the request header holding the idempotency key
+	KeyHeader = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from the store
+	ReplayedHeader = "Idempotent-Replayed"
+	// maxKeyLength is the maximum length of an idempotency key
+	maxKeyLength = 255
+	// maxBodyBytes is the maximum size of the request bodies, which are read in memory to be fingerprinted. It's the
+	// limit of the API server on the size of its requests.
+	maxBodyBytes = 3 << 20
+)
+
+// perRequestHeaders are the response headers describing the request they were served for, rather than its outcome.
+// They're never stored, so that the replayed responses carry the ones of the retries.
+var perRequestHeaders = []string{logging.RequestIDHeader}
+
+// response is a stored response, along with a fingerprint of the request it was served for
+type response struct {
+	fingerprint [sha256.Size]byte
+	inFlight    bool
+	status      int
+	header      http.Header
+	body        []byte
+	expiresAt   time.Time
+}
+
+// Store holds the responses of the requests sent with an idempotency key, for the duration of its TTL
+type Store struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	now       func() time.Time
+	responses map[string]*response
+	lastPurge time.Time
+}
+
+// NewStore returns a new in-memory Store keeping responses for the given TTL
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		ttl:       ttl,
+		now:       time.Now,
+		responses: map[string]*response{},
+	}
+}
+
+// begin looks up the given key. If it isn't known, it is marked as in flight and nil is returned, in which case the
+// caller must call either finish or abort once done.
+func (s *Store) begin(key string, fingerprint [sha256.Size]byte) *response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.purgeExpired(now)
+	if existing, ok := s.responses[key]; ok && now.Before(existing.expiresAt) {
+		return existing
+	}
+	s.responses[key] = &response{fingerprint: fingerprint, inFlight: true, expiresAt: now.Add(s.ttl)}
+	return nil
+}
+
+// finish stores the response served for the given key
+func (s *Store) finish(key string, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.responses[key]; ok {
+		stored.inFlight = false
+		stored.status, stored.header, stored.body = status, header, body
+		stored.expiresAt = s.now().Add(s.ttl)
+	}
+}
+
+// abort forgets the given key, so that the request can be retried
+func (s *Store) abort(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.responses, key)
+}
+
+// purgeExpired removes the expired responses, at most once a minute. Must be called with the lock held.
+func (s *Store) purgeExpired(now time.Time) {
+	if now.Sub(s.lastPurge) < time.Minute {
+		return
+	}
+	s.lastPurge = now
+	for key, stored := range s.responses {
+		if !now.Before(stored.expiresAt) {
+			delete(s.responses, key)
+		}
+	}
+}
+
+// recorder passes the response through, while keeping a copy of its status code and body
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Middleware returns a new http.HandlerFunc which makes the provided handler idempotent for requests carrying an
+// Idempotency-Key header: the response to the first request is stored, and replayed to retries with the same key
+// instead of calling the handler again. Keys are scoped to the client identity, method and path.
+// Server errors (5xx) aren't stored, so that such requests can be retried.
+func Middleware(store *Store, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(KeyHeader)
+		if idempotencyKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logger := klog.FromContext(r.Context()).WithValues("idempotencyKey", idempotencyKey)
+		if len(idempotencyKey) > maxKeyLength {
+			writeError(w, logger, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters long")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeError(w, logger, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must be at most %d bytes", maxBodyBytes))
+				return
+			}
+			writeError(w, logger, http.StatusBadRequest, "Error reading request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := sha256.Sum256(body)
+		key := strings.Join([]string{auth.Identity(r), r.Method, r.URL.Path, idempotencyKey}, "\x00")
+
+		if stored := store.begin(key, fingerprint); stored != nil {
+			switch {
+			case stored.fingerprint != fingerprint:
+				writeError(w, logger, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request body")
+			case stored.inFlight:
+				writeError(w, logger, http.StatusConflict, "A request with the same Idempotency-Key is still in progress")
+			default:
+				logger.V(5).Info("Replaying stored response")
+				for name, values := range stored.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set(ReplayedHeader, "true")
+				w.WriteHeader(stored.status)
+				if _, err := w.Write(stored.body); err != nil {
+					logger.Error(err, "Error writing response")
+				}
+			}
+			return
+		}
+
+		// The headers set before the request reaches the middleware (e.g. its ID) are set on the retries too, so only the
+		// ones set while serving it are stored
+		inherited := w.Header().Clone()
+		rec := &recorder{ResponseWriter: w}
+		defer func() {
+			if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+				store.abort(key)
+				return
+			}
+			store.finish(key, rec.status, servedHeader(inherited, w.Header()), rec.body.Bytes())
+		}()
+		next.ServeHTTP(rec, r)
+	}
+}
+
+// servedHeader returns the response headers set or changed since the inherited ones, apart from the per-request ones
+func servedHeader(inherited, header http.Header) http.Header {
+	served := http.Header{}
+	for name, values := range header {
+		if !slices.Equal(inherited[name], values) {
+			served[name] = slices.Clone(values)
+		}
+	}
+	for _, name := range perRequestHeaders {
+		served.Del(name)
+	}
+	return served
+}
+
+func writeError(w http.ResponseWriter, logger klog.Logger, status int, message string) {
+	logger.Info("Idempotency check failed", "status", status, "reason", message)
+	if encErr := problem.Write(w, status, message); encErr != nil {
+		logger.Error(encErr, "Error encoding response")
+	}
+}
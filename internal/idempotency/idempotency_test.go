@@ -0,0 +1,185 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+)
+
+// countingHandler returns a handler responding with the given status, along with a pointer to the number of calls
+func countingHandler(status int) (http.HandlerFunc, *int) {
+	calls := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"replicas":3}`))
+	}, &calls
+}
+
+func newRequest(key, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/deployments/foo/bar/replicas", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(KeyHeader, key)
+	}
+	return r
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		requests     []*http.Request
+		wantCodes    []int
+		wantCalls    int
+		wantReplayed []bool
+	}{
+		{
+			name:         "without a key every request is served",
+			status:       http.StatusOK,
+			requests:     []*http.Request{newRequest("", `{"replicas":3}`), newRequest("", `{"replicas":3}`)},
+			wantCodes:    []int{http.StatusOK, http.StatusOK},
+			wantCalls:    2,
+			wantReplayed: []bool{false, false},
+		},
+		{
+			name:         "retries with the same key are replayed",
+			status:       http.StatusOK,
+			requests:     []*http.Request{newRequest("a", `{"replicas":3}`), newRequest("a", `{"replicas":3}`)},
+			wantCodes:    []int{http.StatusOK, http.StatusOK},
+			wantCalls:    1,
+			wantReplayed: []bool{false, true},
+		},
+		{
+			name:         "different keys are served separately",
+			status:       http.StatusOK,
+			requests:     []*http.Request{newRequest("a", `{"replicas":3}`), newRequest("b", `{"replicas":3}`)},
+			wantCodes:    []int{http.StatusOK, http.StatusOK},
+			wantCalls:    2,
+			wantReplayed: []bool{false, false},
+		},
+		{
+			name:         "reusing a key with a different body is rejected",
+			status:       http.StatusOK,
+			requests:     []*http.Request{newRequest("a", `{"replicas":3}`), newRequest("a", `{"replicas":4}`)},
+			wantCodes:    []int{http.StatusOK, http.StatusUnprocessableEntity},
+			wantCalls:    1,
+			wantReplayed: []bool{false, false},
+		},
+		{
+			name:         "server errors are not stored",
+			status:       http.StatusInternalServerError,
+			requests:     []*http.Request{newRequest("a", `{"replicas":3}`), newRequest("a", `{"replicas":3}`)},
+			wantCodes:    []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			wantCalls:    2,
+			wantReplayed: []bool{false, false},
+		},
+		{
+			name:         "client errors are stored",
+			status:       http.StatusBadRequest,
+			requests:     []*http.Request{newRequest("a", `{"replicas":3}`), newRequest("a", `{"replicas":3}`)},
+			wantCodes:    []int{http.StatusBadRequest, http.StatusBadRequest},
+			wantCalls:    1,
+			wantReplayed: []bool{false, true},
+		},
+		{
+			name:         "too large body",
+			status:       http.StatusOK,
+			requests:     []*http.Request{newRequest("a", strings.Repeat("a", maxBodyBytes+1))},
+			wantCodes:    []int{http.StatusRequestEntityTooLarge},
+			wantCalls:    0,
+			wantReplayed: []bool{false},
+		},
+		{
+			name:         "too long key",
+			status:       http.StatusOK,
+			requests:     []*http.Request{newRequest(strings.Repeat("a", 256), `{"replicas":3}`)},
+			wantCodes:    []int{http.StatusBadRequest},
+			wantCalls:    0,
+			wantReplayed: []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, calls := countingHandler(tt.status)
+			middleware := Middleware(NewStore(time.Hour), handler)
+
+			for i, r := range tt.requests {
+				w := httptest.NewRecorder()
+				middleware(w, r)
+				if w.Code != tt.wantCodes[i] {
+					t.Errorf("request %d: expected status %d, got %d", i, tt.wantCodes[i], w.Code)
+				}
+				if replayed := w.Header().Get(ReplayedHeader) == "true"; replayed != tt.wantReplayed[i] {
+					t.Errorf("request %d: expected replayed %v, got %v", i, tt.wantReplayed[i], replayed)
+				}
+				if tt.wantReplayed[i] && w.Body.String() != `{"replicas":3}` {
+					t.Errorf("request %d: unexpected replayed body %s", i, w.Body.String())
+				}
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("expected %d calls to the handler, got %d", tt.wantCalls, *calls)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Headers(t *testing.T) {
+	handler, _ := countingHandler(http.StatusCreated)
+	middleware := Middleware(NewStore(time.Hour), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/operations/foo")
+		handler(w, r)
+	})
+
+	// The request ID is set before the middleware, and the retry carries its own
+	for i, requestID := range []string{"first", "retry"} {
+		w := httptest.NewRecorder()
+		w.Header().Set(logging.RequestIDHeader, requestID)
+		middleware(w, newRequest("a", `{"replicas":3}`))
+		if got := w.Header().Get(logging.RequestIDHeader); got != requestID {
+			t.Errorf("request %d: expected the request ID %q, got %q", i, requestID, got)
+		}
+		if got := w.Header().Get("Location"); got != "/operations/foo" {
+			t.Errorf("request %d: expected the Location header set by the handler, got %q", i, got)
+		}
+	}
+}
+
+func TestMiddleware_InFlight(t *testing.T) {
+	store := NewStore(time.Hour)
+	var inner *httptest.ResponseRecorder
+	middleware := Middleware(store, func(w http.ResponseWriter, r *http.Request) {
+		// A retry arriving while the first request is still being served
+		inner = httptest.NewRecorder()
+		Middleware(store, func(w http.ResponseWriter, r *http.Request) {})(inner, newRequest("a", ""))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	middleware(httptest.NewRecorder(), newRequest("a", ""))
+	if inner.Code != http.StatusConflict {
+		t.Errorf("expected status %d for the concurrent retry, got %d", http.StatusConflict, inner.Code)
+	}
+}
+
+func TestStore_Expiry(t *testing.T) {
+	now := time.Now()
+	store := NewStore(time.Minute)
+	store.now = func() time.Time { return now }
+	handler, calls := countingHandler(http.StatusOK)
+	middleware := Middleware(store, handler)
+
+	middleware(httptest.NewRecorder(), newRequest("a", ""))
+	now = now.Add(2 * time.Minute)
+	middleware(httptest.NewRecorder(), newRequest("a", ""))
+	if *calls != 2 {
+		t.Errorf("expected the key to expire, and the handler to be called twice, got %d calls", *calls)
+	}
+	if len(store.responses) != 1 {
+		t.Errorf("expected the expired response to be purged, got %d stored responses", len(store.responses))
+	}
+}
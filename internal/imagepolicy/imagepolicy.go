@@ -0,0 +1,170 @@
+// Package imagepolicy validates the container images of the objects changed through the API against a registry
+// allow-list / deny-list and tag policies, e.g. so that only images from the company's registries, pinned to a
+// version, are ever deployed.
+package imagepolicy
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultRegistry is the registry of the images which don't name one
+const defaultRegistry = "docker.io"
+
+// Config is the image policy configuration file
+type Config struct {
+	// AllowedRegistries are the patterns of the only registries images may be pulled from, in the path.Match syntax
+	// (e.g. "*.dkr.ecr.us-east-1.amazonaws.com"). Patterns with a slash are matched against the repository instead
+	// (e.g. "registry.example.com/team-a/*"). All registries are allowed when empty.
+	AllowedRegistries []string `json:"allowedRegistries"`
+	// DeniedRegistries are the patterns of the registries images may not be pulled from, even if allowed
+	DeniedRegistries []string `json:"deniedRegistries"`
+	// DenyLatest rejects the latest tag, including the images with neither a tag nor a digest, which default to it
+	DenyLatest bool `json:"denyLatest"`
+	// DeniedTags are the patterns of the tags which are rejected, e.g. "*-SNAPSHOT"
+	DeniedTags []string `json:"deniedTags"`
+	// RequireDigest rejects the images which aren't pinned to a digest
+	RequireDigest bool `json:"requireDigest"`
+}
+
+// Policy validates images against its configuration
+type Policy struct {
+	config Config
+}
+
+// New returns a new Policy from the given configuration
+func New(config Config) (*Policy, error) {
+	for _, patterns := range [][]string{config.AllowedRegistries, config.DeniedRegistries, config.DeniedTags} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return &Policy{config: config}, nil
+}
+
+// LoadFile returns a new Policy from the given YAML or JSON configuration file
+func LoadFile(path string) (*Policy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image policy file: %w", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse image policy file %s: %w", path, err)
+	}
+	return New(config)
+}
+
+// Reference is a parsed image reference
+type Reference struct {
+	Registry string
+	// Repository is the repository of the image, without its registry
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses the image reference. Images which name neither a tag nor a digest get the latest tag.
+func ParseReference(image string) Reference {
+	var ref Reference
+	name, digest, _ := strings.Cut(image, "@")
+	ref.Digest = digest
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	ref.Registry = defaultRegistry
+	if first, rest, found := strings.Cut(name, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, name = first, rest
+	}
+	ref.Repository = name
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref
+}
+
+// matchesRegistry returns whether the reference matches any of the registry patterns
+func (r Reference) matchesRegistry(patterns []string) bool {
+	for _, pattern := range patterns {
+		subject := r.Registry
+		if strings.Contains(pattern, "/") {
+			subject = r.Registry + "/" + r.Repository
+		}
+		if matched, _ := path.Match(pattern, subject); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns the reasons the image is rejected by the policy, or none if it's allowed
+func (p *Policy) Check(image string) []string {
+	ref := ParseReference(image)
+	var reasons []string
+	if len(p.config.AllowedRegistries) > 0 && !ref.matchesRegistry(p.config.AllowedRegistries) {
+		reasons = append(reasons, fmt.Sprintf("registry %s is not in the allowed registries %s", ref.Registry, strings.Join(p.config.AllowedRegistries, ", ")))
+	}
+	if ref.matchesRegistry(p.config.DeniedRegistries) {
+		reasons = append(reasons, fmt.Sprintf("registry %s is denied", ref.Registry))
+	}
+	if ref.Tag == "latest" && p.config.DenyLatest {
+		reasons = append(reasons, "the latest tag is not allowed, images must be pinned to a version")
+	} else if ref.Tag != "" {
+		for _, pattern := range p.config.DeniedTags {
+			if matched, _ := path.Match(pattern, ref.Tag); matched {
+				reasons = append(reasons, fmt.Sprintf("tag %s is denied", ref.Tag))
+				break
+			}
+		}
+	}
+	if ref.Digest == "" && p.config.RequireDigest {
+		reasons = append(reasons, "images must be pinned to a digest")
+	}
+	return reasons
+}
+
+// Container is a container of an object, as found by Containers
+type Container struct {
+	Name  string
+	Image string
+}
+
+// podSpecPaths are the paths of the pod specs of the kinds holding pods, by kind
+var podSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+}
+
+// Containers returns the init containers and containers of the unstructured object, or none if it doesn't hold pods
+func Containers(object *unstructured.Unstructured) []Container {
+	fields, ok := podSpecPaths[object.GetKind()]
+	if !ok {
+		return nil
+	}
+	var containers []Container
+	for _, field := range []string{"initContainers", "containers"} {
+		list, _, _ := unstructured.NestedSlice(object.Object, append(append([]string{}, fields...), field)...)
+		for _, item := range list {
+			container, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			name, _ := container["name"].(string)
+			image, _ := container["image"].(string)
+			containers = append(containers, Container{Name: name, Image: image})
+		}
+	}
+	return containers
+}
@@ -0,0 +1,103 @@
+package imagepolicy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected Reference
+	}{
+		{"nginx", Reference{Registry: "docker.io", Repository: "nginx", Tag: "latest"}},
+		{"library/nginx:1.27", Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.27"}},
+		{"registry.example.com/team/app:v1", Reference{Registry: "registry.example.com", Repository: "team/app", Tag: "v1"}},
+		{"localhost:5000/app", Reference{Registry: "localhost:5000", Repository: "app", Tag: "latest"}},
+		{"localhost/app@sha256:abc", Reference{Registry: "localhost", Repository: "app", Digest: "sha256:abc"}},
+		{"ghcr.io/org/app:v2@sha256:abc", Reference{Registry: "ghcr.io", Repository: "org/app", Tag: "v2", Digest: "sha256:abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if ref := ParseReference(tt.image); ref != tt.expected {
+				t.Errorf("ParseReference() = %+v, want %+v", ref, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		image    string
+		expected []string
+	}{
+		{"no policy", Config{}, "nginx", nil},
+		{"allowed registry", Config{AllowedRegistries: []string{"*.example.com"}}, "registry.example.com/app:v1", nil},
+		{"registry not allowed", Config{AllowedRegistries: []string{"*.example.com"}}, "nginx:1.27",
+			[]string{"registry docker.io is not in the allowed registries *.example.com"}},
+		{"allowed repository", Config{AllowedRegistries: []string{"registry.example.com/team-a/*"}}, "registry.example.com/team-a/app:v1", nil},
+		{"repository not allowed", Config{AllowedRegistries: []string{"registry.example.com/team-a/*"}}, "registry.example.com/team-b/app:v1",
+			[]string{"registry registry.example.com is not in the allowed registries registry.example.com/team-a/*"}},
+		{"denied registry", Config{DeniedRegistries: []string{"docker.io"}}, "nginx:1.27", []string{"registry docker.io is denied"}},
+		{"latest tag", Config{DenyLatest: true}, "nginx:latest", []string{"the latest tag is not allowed, images must be pinned to a version"}},
+		{"implicit latest tag", Config{DenyLatest: true}, "nginx", []string{"the latest tag is not allowed, images must be pinned to a version"}},
+		{"digest without tag", Config{DenyLatest: true}, "nginx@sha256:abc", nil},
+		{"denied tag", Config{DeniedTags: []string{"*-SNAPSHOT"}}, "app:1.0-SNAPSHOT", []string{"tag 1.0-SNAPSHOT is denied"}},
+		{"missing digest", Config{RequireDigest: true, DeniedRegistries: []string{"docker.io"}}, "nginx:1.27",
+			[]string{"registry docker.io is denied", "images must be pinned to a digest"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.config)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if reasons := p.Check(tt.image); !reflect.DeepEqual(reasons, tt.expected) {
+				t.Errorf("Check() = %q, want %q", reasons, tt.expected)
+			}
+		})
+	}
+}
+
+func TestContainers(t *testing.T) {
+	cronJob := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "batch/v1",
+		"kind":       "CronJob",
+		"metadata":   map[string]any{"name": "backup", "namespace": "foo"},
+		"spec": map[string]any{"jobTemplate": map[string]any{"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+			"initContainers": []any{map[string]any{"name": "wait", "image": "busybox:1.36"}},
+			"containers":     []any{map[string]any{"name": "backup", "image": "backup"}},
+		}}}}},
+	}}
+	expected := []Container{{Name: "wait", Image: "busybox:1.36"}, {Name: "backup", Image: "backup"}}
+	if containers := Containers(cronJob); !reflect.DeepEqual(containers, expected) {
+		t.Errorf("Containers() = %+v, want %+v", containers, expected)
+	}
+
+	configMap := &unstructured.Unstructured{Object: map[string]any{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]any{"name": "foo"}}}
+	if containers := Containers(configMap); containers != nil {
+		t.Errorf("Containers() = %+v, want none for objects without pods", containers)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("allowedRegistries: [\"[\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile() error = nil, want an error for an invalid pattern")
+	}
+	if err := os.WriteFile(path, []byte("denyLatest: true\nunknown: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile() error = nil, want an error for an unknown field")
+	}
+}
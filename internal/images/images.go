@@ -0,0 +1,65 @@
+// Package images inventories the container images run by the deployments, and optionally enriches them with the
+// vulnerability scan results of an external scanner.
+package images
+
+import (
+	"slices"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// Image is a unique container image, along with the deployments running it
+type Image struct {
+	Image string `json:"image"`
+	// Deployments is the number of deployments running the image, in any of their containers
+	Deployments int      `json:"deployments"`
+	Namespaces  []string `json:"namespaces"`
+	// Vulnerabilities are the scan results of the image, when a scanner is configured and the scan succeeded
+	Vulnerabilities *Vulnerabilities `json:"vulnerabilities,omitempty"`
+	// ScanError is the reason the image couldn't be scanned
+	ScanError string `json:"scanError,omitempty"`
+}
+
+// Inventory returns the unique images of the containers, init containers and ephemeral containers of the deployments,
+// sorted by image
+func Inventory(deployments []appsv1.Deployment) []Image {
+	byImage := map[string]*Image{}
+	for i := range deployments {
+		d := &deployments[i]
+		spec := &d.Spec.Template.Spec
+		seen := map[string]bool{}
+		add := func(image string) {
+			if image == "" || seen[image] {
+				return
+			}
+			seen[image] = true
+			entry, ok := byImage[image]
+			if !ok {
+				entry = &Image{Image: image, Namespaces: []string{}}
+				byImage[image] = entry
+			}
+			entry.Deployments++
+			if !slices.Contains(entry.Namespaces, d.Namespace) {
+				entry.Namespaces = append(entry.Namespaces, d.Namespace)
+			}
+		}
+		for _, c := range spec.InitContainers {
+			add(c.Image)
+		}
+		for _, c := range spec.Containers {
+			add(c.Image)
+		}
+		for _, c := range spec.EphemeralContainers {
+			add(c.Image)
+		}
+	}
+
+	inventory := make([]Image, 0, len(byImage))
+	for _, entry := range byImage {
+		sort.Strings(entry.Namespaces)
+		inventory = append(inventory, *entry)
+	}
+	sort.Slice(inventory, func(i, j int) bool { return inventory[i].Image < inventory[j].Image })
+	return inventory
+}
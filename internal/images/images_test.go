@@ -0,0 +1,90 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func deployment(namespace, name string, initImages []string, images ...string) appsv1.Deployment {
+	d := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	for _, image := range initImages {
+		d.Spec.Template.Spec.InitContainers = append(d.Spec.Template.Spec.InitContainers, corev1.Container{Image: image})
+	}
+	for _, image := range images {
+		d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, corev1.Container{Image: image})
+	}
+	return d
+}
+
+func TestInventory(t *testing.T) {
+	inventory := Inventory([]appsv1.Deployment{
+		deployment("b", "web", nil, "nginx:1.25", "envoy:1.30"),
+		deployment("a", "web", []string{"busybox:1.36"}, "nginx:1.25"),
+		// Images run by several containers of a deployment are only counted once
+		deployment("a", "api", nil, "envoy:1.30", "envoy:1.30"),
+	})
+	expected := []Image{
+		{Image: "busybox:1.36", Deployments: 1, Namespaces: []string{"a"}},
+		{Image: "envoy:1.30", Deployments: 2, Namespaces: []string{"a", "b"}},
+		{Image: "nginx:1.25", Deployments: 2, Namespaces: []string{"a", "b"}},
+	}
+	if !reflect.DeepEqual(inventory, expected) {
+		t.Errorf("Inventory() = %+v, want %+v", inventory, expected)
+	}
+}
+
+const trivyReport = `{"Results": [
+	{"Vulnerabilities": [{"VulnerabilityID": "CVE-1", "Severity": "CRITICAL"}, {"VulnerabilityID": "CVE-2", "Severity": "HIGH"}]},
+	{"Vulnerabilities": [{"VulnerabilityID": "CVE-3", "Severity": "LOW"}, {"VulnerabilityID": "CVE-4", "Severity": "UNKNOWN"}]}
+]}`
+
+const clairReport = `{"vulnerabilities": {
+	"1": {"name": "CVE-1", "normalized_severity": "Medium"},
+	"2": {"name": "CVE-2", "normalized_severity": "Negligible"}
+}}`
+
+func TestScanner_Enrich(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Query().Get("image") {
+		case "nginx:1.25":
+			fmt.Fprint(w, trivyReport)
+		case "registry.example.com/team/api@sha256:abc":
+			fmt.Fprint(w, clairReport)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	s := &Scanner{URL: server.URL + "/report?image={image}", TTL: time.Hour, Concurrency: 2}
+	for range 2 {
+		inventory := []Image{{Image: "nginx:1.25"}, {Image: "registry.example.com/team/api@sha256:abc"}, {Image: "unknown:1"}}
+		s.Enrich(context.Background(), inventory)
+
+		if expected := (Vulnerabilities{Critical: 1, High: 1, Low: 1, Unknown: 1}); inventory[0].Vulnerabilities == nil || *inventory[0].Vulnerabilities != expected {
+			t.Errorf("Trivy vulnerabilities = %+v, want %+v", inventory[0].Vulnerabilities, expected)
+		}
+		if expected := (Vulnerabilities{Medium: 1, Low: 1}); inventory[1].Vulnerabilities == nil || *inventory[1].Vulnerabilities != expected {
+			t.Errorf("Clair vulnerabilities = %+v, want %+v", inventory[1].Vulnerabilities, expected)
+		}
+		if inventory[2].Vulnerabilities != nil || inventory[2].ScanError != "scanner returned status 404" {
+			t.Errorf("unknown image = %+v, want the scan error", inventory[2])
+		}
+	}
+	// The second inventory is served from the cache, including the failures
+	if got := calls.Load(); got != 3 {
+		t.Errorf("scanner calls = %d, want 3", got)
+	}
+}
@@ -0,0 +1,162 @@
+package images
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImagePlaceholder is replaced by the URL-escaped image in the URL of the scanner
+const ImagePlaceholder = "{image}"
+
+// maxReportBytes is the maximum size of the scan reports read from the scanner
+const maxReportBytes = 32 << 20
+
+// Vulnerabilities are the number of vulnerabilities of an image, by severity
+type Vulnerabilities struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Unknown  int `json:"unknown"`
+}
+
+// add counts a vulnerability of the given severity, as reported by either Trivy or Clair
+func (v *Vulnerabilities) add(severity string) {
+	switch strings.ToLower(severity) {
+	case "critical":
+		v.Critical++
+	case "high":
+		v.High++
+	case "medium":
+		v.Medium++
+	case "low", "negligible":
+		v.Low++
+	default:
+		v.Unknown++
+	}
+}
+
+// report is the subset of the Trivy JSON report and of the Clair v4 vulnerability report needed to count the
+// vulnerabilities by severity
+type report struct {
+	// Results are the results of a Trivy report
+	Results []struct {
+		Vulnerabilities []struct {
+			Severity string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+	// Vulnerabilities are the vulnerabilities of a Clair report, by ID
+	Vulnerabilities map[string]struct {
+		NormalizedSeverity string `json:"normalized_severity"`
+	} `json:"vulnerabilities"`
+}
+
+// Scanner fetches the scan results of images from a Trivy or Clair compatible endpoint, and caches them
+type Scanner struct {
+	// URL is the URL of the scan report of an image, where ImagePlaceholder is replaced by the URL-escaped image, e.g.
+	// http://scanner.security.svc/report?image={image}
+	URL    string
+	Client *http.Client
+	// TTL is how long the scan results (and failures) are cached
+	TTL time.Duration
+	// Concurrency is the maximum number of scan reports fetched at once. Defaults to 1 when unset.
+	Concurrency int
+
+	mu    sync.Mutex
+	cache map[string]scanResult
+}
+
+type scanResult struct {
+	vulnerabilities *Vulnerabilities
+	err             error
+	fetched         time.Time
+}
+
+// Enrich sets the scan results of the images, fetching the ones which aren't cached. Failures are reported in the
+// ScanError of the image, rather than failing the whole inventory.
+func (s *Scanner) Enrich(ctx context.Context, inventory []Image) {
+	concurrency := max(s.Concurrency, 1)
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range inventory {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(image *Image) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			vulnerabilities, err := s.scan(ctx, image.Image)
+			if err != nil {
+				image.ScanError = err.Error()
+				return
+			}
+			image.Vulnerabilities = vulnerabilities
+		}(&inventory[i])
+	}
+	wg.Wait()
+}
+
+// scan returns the cached scan results of the image, or fetches them
+func (s *Scanner) scan(ctx context.Context, image string) (*Vulnerabilities, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[image]
+	s.mu.Unlock()
+	if ok && time.Since(cached.fetched) < s.TTL {
+		return cached.vulnerabilities, cached.err
+	}
+
+	vulnerabilities, err := s.fetch(ctx, image)
+	// Requests which were cancelled say nothing about the image, so they aren't cached
+	if ctx.Err() == nil {
+		s.mu.Lock()
+		if s.cache == nil {
+			s.cache = map[string]scanResult{}
+		}
+		s.cache[image] = scanResult{vulnerabilities: vulnerabilities, err: err, fetched: time.Now()}
+		s.mu.Unlock()
+	}
+	return vulnerabilities, err
+}
+
+// fetch fetches the scan report of the image, and counts its vulnerabilities by severity
+func (s *Scanner) fetch(ctx context.Context, image string) (*Vulnerabilities, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(s.URL, ImagePlaceholder, url.QueryEscape(image)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scan request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	httpClient := s.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call scanner: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("scanner returned status %d", resp.StatusCode)
+	}
+
+	var r report
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxReportBytes)).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode scan report: %w", err)
+	}
+	v := &Vulnerabilities{}
+	for _, result := range r.Results {
+		for _, vulnerability := range result.Vulnerabilities {
+			v.add(vulnerability.Severity)
+		}
+	}
+	for _, vulnerability := range r.Vulnerabilities {
+		v.add(vulnerability.NormalizedSeverity)
+	}
+	return v, nil
+}
@@ -0,0 +1,222 @@
+// Package ipfilter restricts the source IPs of the requests to allow-lists of CIDRs, configured globally and per group
+// of routes, e.g. so that the mutating endpoints are only reachable from the CI subnet. Behind trusted proxies, the
+// source IP is taken from the X-Forwarded-For header.
+package ipfilter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// GroupConfig is a group of routes, only reachable from its allow-list
+type GroupConfig struct {
+	Name string `json:"name"`
+	// Methods are the HTTP methods of the routes of the group. All methods are matched when empty.
+	Methods []string `json:"methods,omitempty"`
+	// Paths are the request paths of the routes of the group, where "*" matches a single path segment (e.g.
+	// /deployments/*/*/replicas). The /v1 prefix of the versioned API is ignored.
+	Paths []string `json:"paths"`
+	// Allow are the CIDRs the routes of the group may be reached from, e.g. 10.20.0.0/16, or single IPs
+	Allow []string `json:"allow"`
+}
+
+// Config is the IP filtering configuration file
+type Config struct {
+	// TrustedProxies are the CIDRs of the proxies whose X-Forwarded-For header is trusted, e.g. an ingress controller
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+	// Allow are the CIDRs every route may be reached from. All the source IPs are allowed when empty.
+	Allow []string `json:"allow,omitempty"`
+	// Groups restrict groups of routes further, a request having to be allowed by every group it's part of
+	Groups []GroupConfig `json:"groups,omitempty"`
+}
+
+// group is a configured group of routes
+type group struct {
+	name    string
+	methods []string
+	paths   []string
+	allow   []netip.Prefix
+}
+
+// matches returns whether the request is part of the group
+func (g group) matches(method, requestPath string) bool {
+	if len(g.methods) > 0 && !slices.ContainsFunc(g.methods, func(m string) bool { return strings.EqualFold(m, method) }) {
+		return false
+	}
+	return slices.ContainsFunc(g.paths, func(pattern string) bool {
+		matched, _ := path.Match(pattern, requestPath)
+		return matched
+	})
+}
+
+// Filter rejects the requests from the source IPs outside of the allow-lists with a 403 Forbidden
+type Filter struct {
+	trustedProxies []netip.Prefix
+	allow          []netip.Prefix
+	groups         []group
+}
+
+// New returns a new Filter of the given configuration
+func New(config Config) (*Filter, error) {
+	f := &Filter{}
+	var err error
+	if f.trustedProxies, err = parsePrefixes(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	if f.allow, err = parsePrefixes(config.Allow); err != nil {
+		return nil, fmt.Errorf("invalid allow-list: %w", err)
+	}
+	for _, g := range config.Groups {
+		if g.Name == "" || len(g.Paths) == 0 || len(g.Allow) == 0 {
+			return nil, fmt.Errorf("group %q must have a name, at least one path and an allow-list", g.Name)
+		}
+		for _, pattern := range g.Paths {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid path %q in group %s: %w", pattern, g.Name, err)
+			}
+		}
+		allow, err := parsePrefixes(g.Allow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow-list of group %s: %w", g.Name, err)
+		}
+		f.groups = append(f.groups, group{name: g.Name, methods: g.Methods, paths: g.Paths, allow: allow})
+	}
+	return f, nil
+}
+
+// LoadFile returns a new Filter of the configuration in the YAML file at the given path
+func LoadFile(path string) (*Filter, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IP filter file: %w", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse IP filter file %s: %w", path, err)
+	}
+	return New(config)
+}
+
+// parsePrefixes parses CIDRs, or single IPs
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	return slices.ContainsFunc(prefixes, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
+
+// ClientIP returns the IP address of the client of the request: the peer address of the connection, or, when the peer
+// is a trusted proxy, the rightmost address of the X-Forwarded-For header which isn't a trusted proxy itself. It
+// returns false for the requests without a peer IP, e.g. over a Unix domain socket.
+func (f *Filter) ClientIP(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr = addr.Unmap()
+	if !contains(f.trustedProxies, addr) {
+		return addr, true
+	}
+	var forwarded []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(value, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			// The hops left of a malformed one can't be trusted
+			break
+		}
+		addr = hop.Unmap()
+		if !contains(f.trustedProxies, addr) {
+			break
+		}
+	}
+	return addr, true
+}
+
+// allowed returns whether the client IP may make the request, and else the name of the allow-list rejecting it
+func (f *Filter) allowed(addr netip.Addr, method, requestPath string) (string, bool) {
+	if len(f.allow) > 0 && !contains(f.allow, addr) {
+		return "global", false
+	}
+	for _, g := range f.groups {
+		if g.matches(method, requestPath) && !contains(g.allow, addr) {
+			return g.name, false
+		}
+	}
+	return "", true
+}
+
+// Middleware returns a new http.Handler that rejects the requests from the client IPs outside of the allow-lists with
+// a 403 Forbidden, and adds the client IP to the context of the others before calling the provided handler. The
+// requests without a peer IP, e.g. over a Unix domain socket, aren't filtered.
+func (f *Filter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr, ok := f.ClientIP(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		requestPath := r.URL.Path
+		if trimmed := strings.TrimPrefix(requestPath, apiversion.Prefix); strings.HasPrefix(trimmed, "/") {
+			requestPath = trimmed
+		}
+		if list, allowed := f.allowed(addr, r.Method, requestPath); !allowed {
+			logger := klog.FromContext(r.Context())
+			logger.Info("Forbidden, the client IP isn't allowed", "clientIP", addr, "allowList", list, "method", r.Method, "path", r.URL.Path)
+			if encErr := problem.Write(w, http.StatusForbidden, "Forbidden"); encErr != nil {
+				logger.Error(encErr, "Error encoding response")
+			}
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithClientIP(r.Context(), addr)))
+	})
+}
+
+// clientIPKey is the context key of the client IP of a request
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of the context holding the given client IP
+func WithClientIP(ctx context.Context, addr netip.Addr) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, addr)
+}
+
+// ClientIPFrom returns the client IP held by the context, if any
+func ClientIPFrom(ctx context.Context) (netip.Addr, bool) {
+	addr, ok := ctx.Value(clientIPKey{}).(netip.Addr)
+	return addr, ok
+}
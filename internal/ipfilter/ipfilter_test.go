@@ -0,0 +1,133 @@
+package ipfilter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilter_ClientIP(t *testing.T) {
+	f, err := New(Config{TrustedProxies: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		forwardedFor  []string
+		expectedIP    string
+		expectedFound bool
+	}{
+		{name: "direct", remoteAddr: "203.0.113.7:1234", expectedIP: "203.0.113.7", expectedFound: true},
+		{name: "forwarded by an untrusted peer", remoteAddr: "203.0.113.7:1234", forwardedFor: []string{"198.51.100.1"}, expectedIP: "203.0.113.7", expectedFound: true},
+		{name: "forwarded by a trusted proxy", remoteAddr: "10.0.0.5:1234", forwardedFor: []string{"198.51.100.1"}, expectedIP: "198.51.100.1", expectedFound: true},
+		{name: "spoofed hop left of the client", remoteAddr: "10.0.0.5:1234", forwardedFor: []string{"192.0.2.1, 198.51.100.1"}, expectedIP: "198.51.100.1", expectedFound: true},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.5:1234", forwardedFor: []string{"198.51.100.1", "10.0.0.9"}, expectedIP: "198.51.100.1", expectedFound: true},
+		{name: "malformed hop", remoteAddr: "10.0.0.5:1234", forwardedFor: []string{"198.51.100.1, unknown"}, expectedIP: "10.0.0.5", expectedFound: true},
+		{name: "IPv4-mapped IPv6", remoteAddr: "[::ffff:203.0.113.7]:1234", expectedIP: "203.0.113.7", expectedFound: true},
+		{name: "Unix domain socket", remoteAddr: "@", expectedFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/deployments", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			addr, found := f.ClientIP(r)
+			if found != tt.expectedFound {
+				t.Fatalf("ClientIP() found = %v, want %v", found, tt.expectedFound)
+			}
+			if found && addr.String() != tt.expectedIP {
+				t.Errorf("ClientIP() = %v, want %v", addr, tt.expectedIP)
+			}
+		})
+	}
+}
+
+func TestFilter_Middleware(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ip-filter.yaml")
+	config := `
+trustedProxies: [10.0.0.0/8]
+allow: [198.51.100.0/24, 203.0.113.0/24, 192.0.2.10]
+groups:
+- name: ci-only
+  methods: [PUT, POST, DELETE]
+  paths: [/deployments/*/*/replicas, /apply]
+  allow: [203.0.113.0/24]
+`
+	if err := os.WriteFile(file, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	f, err := LoadFile(file)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	var clientIP string
+	handler := f.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if addr, ok := ClientIPFrom(r.Context()); ok {
+			clientIP = addr.String()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		remoteAddr     string
+		forwardedFor   string
+		expectedStatus int
+	}{
+		{"Test Read From Allowed", "GET", "/deployments", "198.51.100.1:1234", "", http.StatusOK},
+		{"Test Single IP", "GET", "/deployments", "192.0.2.10:1234", "", http.StatusOK},
+		{"Test Outside Global", "GET", "/deployments", "192.0.2.11:1234", "", http.StatusForbidden},
+		{"Test Mutation From CI", "PUT", "/deployments/default/web/replicas", "203.0.113.7:1234", "", http.StatusOK},
+		{"Test Mutation Outside CI", "PUT", "/deployments/default/web/replicas", "198.51.100.1:1234", "", http.StatusForbidden},
+		{"Test Versioned Mutation Outside CI", "PUT", "/v1/deployments/default/web/replicas", "198.51.100.1:1234", "", http.StatusForbidden},
+		{"Test Read Of Group Path", "GET", "/deployments/default/web/replicas", "198.51.100.1:1234", "", http.StatusOK},
+		{"Test Mutation From CI Via Proxy", "POST", "/apply", "10.0.0.5:1234", "203.0.113.7", http.StatusOK},
+		{"Test Mutation Via Proxy", "POST", "/apply", "10.0.0.5:1234", "198.51.100.1", http.StatusForbidden},
+		{"Test Unix Domain Socket", "POST", "/apply", "@", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			clientIP = ""
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if tt.forwardedFor != "" && tt.expectedStatus == http.StatusOK && clientIP != tt.forwardedFor {
+				t.Errorf("client IP = %v, want %v", clientIP, tt.forwardedFor)
+			}
+		})
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"invalid CIDR", Config{Allow: []string{"10.0.0.0/33"}}},
+		{"invalid trusted proxy", Config{TrustedProxies: []string{"proxy"}}},
+		{"group without allow-list", Config{Groups: []GroupConfig{{Name: "ci", Paths: []string{"/apply"}}}}},
+		{"group without paths", Config{Groups: []GroupConfig{{Name: "ci", Allow: []string{"10.0.0.0/8"}}}}},
+		{"invalid path", Config{Groups: []GroupConfig{{Name: "ci", Paths: []string{"/deployments/["}, Allow: []string{"10.0.0.0/8"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Error("New() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package kubecredentials loads the configuration of the Kubernetes API server client out of the kubeconfig files, like
+// kubectl does, and tracks the health of its credentials, e.g. of the exec credential plugins of the cloud providers
+// (aws eks get-token, gke-gcloud-auth-plugin, kubelogin...) which otherwise only fail as errors of the API calls.
+package kubecredentials
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Sources of the credentials
+const (
+	// SourceExec is the source of the credentials of an exec credential plugin
+	SourceExec = "exec"
+	// SourceAuthProvider is the source of the credentials of a legacy auth provider
+	SourceAuthProvider = "auth-provider"
+	// SourceClientCertificate is the source of the client certificates, which are reloaded when they're files
+	SourceClientCertificate = "client-cert"
+	// SourceTokenFile is the source of the bearer tokens read from a file, e.g. the projected service account token
+	// in-cluster, which is reloaded as it's rotated
+	SourceTokenFile = "token-file"
+	// SourceToken is the source of the static bearer tokens
+	SourceToken = "token"
+	// SourceNone is the source of the anonymous clients
+	SourceNone = "none"
+)
+
+// Reasons of the credential failures
+const (
+	// ReasonPlugin is the reason of the failures of the credential plugin, e.g. when its command is missing or fails
+	ReasonPlugin = "plugin"
+	// ReasonRejected is the reason of the credentials rejected by the API server with a 401, e.g. once expired
+	ReasonRejected = "rejected"
+)
+
+var failuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "k8s_api_proxy_kube_credential_failures_total",
+	Help: "Number of API server calls which failed because of the credentials of the server, by reason",
+}, []string{"reason"})
+
+func init() {
+	metrics.Registry.MustRegister(failuresTotal)
+}
+
+// LoadConfig loads the configuration of the API server out of the kubeconfig files with the precedence rules of
+// kubectl: the given path if any, or else the files of $KUBECONFIG, or else ~/.kube/config, using the given context, or
+// else their current context. It returns the configuration along with the name of the context it was loaded from, and
+// an error if the given context doesn't exist. The credentials are kept as referenced by the kubeconfig, e.g. the exec
+// credential plugins are run whenever their credentials expire, and the client certificate files are reloaded as
+// they're rotated.
+func LoadConfig(kubeconfig, context string) (*rest.Config, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	raw, err := clientConfig.RawConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	if context == "" {
+		context = raw.CurrentContext
+	} else if _, ok := raw.Contexts[context]; !ok {
+		contexts := make([]string, 0, len(raw.Contexts))
+		for name := range raw.Contexts {
+			contexts = append(contexts, name)
+		}
+		slices.Sort(contexts)
+		return nil, "", fmt.Errorf("context %q not found in the kubeconfig, out of %v", context, contexts)
+	}
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	return config, context, nil
+}
+
+// Status is the health of the credentials of the server, as of the latest API server calls
+type Status struct {
+	Source string `json:"source"`
+	// Command is the command of the exec credential plugin
+	Command string `json:"command,omitempty"`
+	Healthy bool   `json:"healthy"`
+	// ConsecutiveFailures is the number of API server calls which failed because of the credentials since the latest
+	// successful one
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure         *time.Time `json:"lastFailure,omitempty"`
+	// Error is the error of the latest failure, while unhealthy
+	Error string `json:"error,omitempty"`
+}
+
+// Tracker tracks the health of the credentials of the server out of the outcome of its API server calls
+type Tracker struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewTracker returns a new Tracker of the credentials of the given configuration
+func NewTracker(config *rest.Config) *Tracker {
+	status := Status{Source: Source(config), Healthy: true}
+	if config.ExecProvider != nil {
+		status.Command = config.ExecProvider.Command
+	}
+	return &Tracker{now: time.Now, status: status}
+}
+
+// Source returns the source of the credentials of the configuration
+func Source(config *rest.Config) string {
+	switch {
+	case config.ExecProvider != nil:
+		return SourceExec
+	case config.AuthProvider != nil:
+		return SourceAuthProvider
+	case config.CertFile != "" || len(config.CertData) > 0:
+		return SourceClientCertificate
+	case config.BearerTokenFile != "":
+		return SourceTokenFile
+	case config.BearerToken != "":
+		return SourceToken
+	default:
+		return SourceNone
+	}
+}
+
+// pluginErrors are the fragments of the errors of the exec credential plugins, as returned by client-go
+var pluginErrors = []string{"getting credentials", "exec: ", "exec plugin"}
+
+// WrapTransport returns a new http.RoundTripper recording the outcome of the API server calls. It must wrap the
+// transport of the client as a whole, since the credentials are added by its outermost round tripper, e.g. through the
+// Transport of the http.Client.
+func (t *Tracker) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		resp, err := rt.RoundTrip(req)
+		switch {
+		case err != nil:
+			for _, fragment := range pluginErrors {
+				if strings.Contains(err.Error(), fragment) {
+					t.failure(ReasonPlugin, err.Error())
+					break
+				}
+			}
+		case resp.StatusCode == http.StatusUnauthorized:
+			t.failure(ReasonRejected, "the API server rejected the credentials with a 401 Unauthorized")
+		default:
+			t.success()
+		}
+		return resp, err
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func (t *Tracker) failure(reason, message string) {
+	failuresTotal.WithLabelValues(reason).Inc()
+	now := t.now().UTC()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.status.Healthy {
+		klog.Background().Error(nil, "The credentials of the API server are failing", "source", t.status.Source, "reason", reason, "err", message)
+	}
+	t.status.Healthy = false
+	t.status.ConsecutiveFailures++
+	t.status.LastFailure = &now
+	t.status.Error = message
+}
+
+func (t *Tracker) success() {
+	now := t.now().UTC()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.status.Healthy {
+		klog.Background().Info("The credentials of the API server recovered", "source", t.status.Source, "failures", t.status.ConsecutiveFailures)
+	}
+	t.status.Healthy = true
+	t.status.ConsecutiveFailures = 0
+	t.status.LastSuccess = &now
+	t.status.Error = ""
+}
+
+// Status returns the health of the credentials
+func (t *Tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+// ReadyzCheck reports the health of the credentials. The instance stays ready while they fail, since reads are still
+// served from the cache.
+func (t *Tracker) ReadyzCheck() (bool, any) {
+	return true, t.Status()
+}
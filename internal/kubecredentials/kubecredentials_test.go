@@ -0,0 +1,145 @@
+package kubecredentials
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: eks
+  cluster:
+    server: https://eks.example.com
+- name: kind
+  cluster:
+    server: https://127.0.0.1:6443
+users:
+- name: eks
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args: [eks, get-token, --cluster-name, prod]
+      interactiveMode: Never
+- name: kind
+  user:
+    token: s3cr3t
+contexts:
+- name: eks
+  context: {cluster: eks, user: eks}
+- name: kind
+  context: {cluster: kind, user: kind}
+current-context: kind
+`
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name            string
+		kubeconfig      string
+		env             string
+		context         string
+		expectedContext string
+		expectedHost    string
+		expectedSource  string
+	}{
+		{name: "current context", kubeconfig: path, expectedContext: "kind", expectedHost: "https://127.0.0.1:6443", expectedSource: SourceToken},
+		{name: "given context", kubeconfig: path, context: "eks", expectedContext: "eks", expectedHost: "https://eks.example.com", expectedSource: SourceExec},
+		{name: "$KUBECONFIG", env: path, context: "eks", expectedContext: "eks", expectedHost: "https://eks.example.com", expectedSource: SourceExec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KUBECONFIG", tt.env)
+			config, context, err := LoadConfig(tt.kubeconfig, tt.context)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if context != tt.expectedContext || config.Host != tt.expectedHost || Source(config) != tt.expectedSource {
+				t.Errorf("LoadConfig() = context %q, host %q and source %q, want %q, %q and %q", context, config.Host, Source(config),
+					tt.expectedContext, tt.expectedHost, tt.expectedSource)
+			}
+		})
+	}
+
+	_, _, err := LoadConfig(path, "gke")
+	if err == nil || !strings.Contains(err.Error(), `context "gke" not found in the kubeconfig, out of [eks kind]`) {
+		t.Errorf("LoadConfig() error = %v, want an error for the unknown context", err)
+	}
+}
+
+func TestTracker_WrapTransport(t *testing.T) {
+	tracker := NewTracker(&rest.Config{BearerTokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token"})
+	var status int
+	var err error
+	rt := tracker.WrapTransport(roundTripper(func(*http.Request) (*http.Response, error) {
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}))
+
+	tests := []struct {
+		name                string
+		status              int
+		err                 error
+		expectedHealthy     bool
+		expectedFailures    int
+		expectedErrorPrefix string
+	}{
+		{name: "success", status: http.StatusOK, expectedHealthy: true},
+		{name: "rejected credentials", status: http.StatusUnauthorized, expectedFailures: 1, expectedErrorPrefix: "the API server rejected"},
+		{name: "plugin failure", err: errors.New("getting credentials: exec: executable aws not found"), expectedFailures: 2, expectedErrorPrefix: "getting credentials"},
+		{name: "network errors aren't counted", err: errors.New("dial tcp 10.96.0.1:443: connect: connection refused"), expectedFailures: 2, expectedErrorPrefix: "getting credentials"},
+		{name: "other statuses are successes", status: http.StatusForbidden, expectedHealthy: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err = tt.status, tt.err
+			if resp, err := rt.RoundTrip(httptest.NewRequest("GET", "/api", nil)); err == nil {
+				resp.Body.Close()
+			}
+			got := tracker.Status()
+			if got.Source != SourceTokenFile || got.Healthy != tt.expectedHealthy || got.ConsecutiveFailures != tt.expectedFailures ||
+				!strings.HasPrefix(got.Error, tt.expectedErrorPrefix) {
+				t.Errorf("Status() = %+v, want healthy %v with %d failures", got, tt.expectedHealthy, tt.expectedFailures)
+			}
+		})
+	}
+}
+
+func TestTracker_ExecPlugin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	config := &rest.Config{Host: server.URL, ExecProvider: &clientcmdapi.ExecConfig{
+		APIVersion:      "client.authentication.k8s.io/v1",
+		Command:         filepath.Join(t.TempDir(), "missing-plugin"),
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}}
+	tracker := NewTracker(config)
+	client, err := rest.HTTPClientFor(config)
+	if err != nil {
+		t.Fatalf("HTTPClientFor() error = %v", err)
+	}
+	client.Transport = tracker.WrapTransport(client.Transport)
+
+	if _, err := client.Get(server.URL + "/api"); err == nil {
+		t.Fatalf("Get() expected an error")
+	}
+	got := tracker.Status()
+	if got.Source != SourceExec || got.Command != config.ExecProvider.Command || got.Healthy || !strings.HasPrefix(got.Error, "getting credentials: exec") {
+		t.Errorf("Status() = %+v, want the failure of the plugin", got)
+	}
+}
@@ -0,0 +1,192 @@
+// Package lifecycle runs the components of the server, e.g. the controller manager, its background loops and the HTTP
+// servers, in order: they're started one after the other, possibly waiting for a condition in between such as the sync
+// of the cache before the listeners, and stopped in the reverse order, each once the ones started after it stopped.
+// The first failure of a component stops the server, and is returned once all of them stopped. The HTTP servers track
+// their requests in flight, so that their shutdown reports how many of them it cut off.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Outcomes of the requests in flight when a server shuts down
+const (
+	// OutcomeCompleted is the outcome of the requests which completed within the shutdown timeout
+	OutcomeCompleted = "completed"
+	// OutcomeCutOff is the outcome of the requests cut off once the shutdown timeout elapsed
+	OutcomeCutOff = "cut_off"
+)
+
+var shutdownRequests = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "k8s_api_proxy_shutdown_requests",
+	Help: "Number of requests in flight when the servers shut down, by server and outcome",
+}, []string{"server", "outcome"})
+
+func init() {
+	metrics.Registry.MustRegister(shutdownRequests)
+}
+
+// Lifecycle runs the components of the server
+type Lifecycle struct {
+	// base holds the values of the parent context, e.g. its logger, without its cancellation
+	base   context.Context
+	group  *errgroup.Group
+	failed context.Context
+
+	mu         sync.Mutex
+	components []*component
+}
+
+// component is a running component
+type component struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New returns a new Lifecycle, whose components get the values of the given context, e.g. its logger. They're only
+// stopped by Stop.
+func New(ctx context.Context) *Lifecycle {
+	base := context.WithoutCancel(ctx)
+	group, failed := errgroup.WithContext(base)
+	return &Lifecycle{base: base, group: group, failed: failed}
+}
+
+// Go starts the component, which runs until its context is done. An error returned before then fails the lifecycle.
+func (l *Lifecycle) Go(name string, run func(ctx context.Context) error) {
+	ctx, cancel := context.WithCancel(l.base)
+	c := &component{name: name, cancel: cancel, done: make(chan struct{})}
+	l.mu.Lock()
+	l.components = append(l.components, c)
+	l.mu.Unlock()
+
+	klog.FromContext(ctx).V(2).Info("Starting component", "component", name)
+	l.group.Go(func() error {
+		defer close(c.done)
+		err := run(ctx)
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+		klog.FromContext(ctx).Error(err, "Component failed", "component", name)
+		return fmt.Errorf("the %s failed: %w", name, err)
+	})
+}
+
+// Failed returns a channel closed once a component failed
+func (l *Lifecycle) Failed() <-chan struct{} {
+	return l.failed.Done()
+}
+
+// WaitFor waits for the condition, e.g. the sync of the cache, which must return once its context is done. It returns
+// an error if the context is done, or a component fails, first.
+func (l *Lifecycle) WaitFor(ctx context.Context, name string, condition func(ctx context.Context) bool) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := context.AfterFunc(l.failed, func() {
+		cancel(errors.New("a component failed"))
+	})
+	defer stop()
+
+	klog.FromContext(ctx).V(2).Info("Waiting", "for", name)
+	if !condition(ctx) {
+		return fmt.Errorf("stopped waiting for the %s: %w", name, context.Cause(ctx))
+	}
+	return nil
+}
+
+// Stop stops the components in the reverse order of their start, each once the ones started after it stopped, and
+// returns the first failure of a component, if any
+func (l *Lifecycle) Stop() error {
+	l.mu.Lock()
+	components := l.components
+	l.mu.Unlock()
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		klog.FromContext(l.base).V(2).Info("Stopping component", "component", c.name)
+		c.cancel()
+		<-c.done
+	}
+	return l.group.Wait()
+}
+
+// Loop returns the run function of a background loop, which runs until its context is done
+func Loop(loop func(ctx context.Context)) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		loop(ctx)
+		return nil
+	}
+}
+
+// Server returns the run function of an HTTP server, which serves with serve (e.g. its ListenAndServe method) until
+// its context is done. It is then shut down gracefully, letting the requests in flight complete within the timeout,
+// past which the requests still in flight are cut off. The handler of the server is wrapped to track its requests.
+func Server(name string, server *http.Server, serve func() error, timeout time.Duration) func(ctx context.Context) error {
+	requests := &inFlight{}
+	server.Handler = requests.middleware(server.Handler)
+	return func(ctx context.Context) error {
+		served := make(chan error, 1)
+		go func() {
+			served <- serve()
+		}()
+		select {
+		case err := <-served:
+			// The server failed to listen, or stopped serving on its own
+			return err
+		case <-ctx.Done():
+		}
+
+		logger := klog.FromContext(ctx)
+		start, pending := time.Now(), requests.count()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		var cutOff int64
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			// The connections of the requests still in flight are closed, cutting them off
+			cutOff = requests.count()
+			if err := server.Close(); err != nil {
+				logger.Error(err, "Error closing server", "server", name)
+			}
+		}
+
+		shutdownRequests.WithLabelValues(name, OutcomeCompleted).Set(float64(max(pending-cutOff, 0)))
+		shutdownRequests.WithLabelValues(name, OutcomeCutOff).Set(float64(cutOff))
+		keysAndValues := []any{"server", name, "inFlight", pending, "cutOff", cutOff, "duration", time.Since(start)}
+		if cutOff > 0 {
+			logger.Error(nil, "Server shut down, cutting off the requests still in flight past the shutdown timeout", append(keysAndValues, "timeout", timeout)...)
+		} else {
+			logger.Info("Server shut down", keysAndValues...)
+		}
+		return nil
+	}
+}
+
+// inFlight tracks the requests in flight of a server
+type inFlight struct {
+	requests atomic.Int64
+}
+
+func (f *inFlight) middleware(next http.Handler) http.Handler {
+	if next == nil {
+		next = http.DefaultServeMux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.requests.Add(1)
+		defer f.requests.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *inFlight) count() int64 {
+	return f.requests.Load()
+}
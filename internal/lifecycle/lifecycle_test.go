@@ -0,0 +1,191 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLifecycle_Stop(t *testing.T) {
+	lc := New(context.Background())
+	var mu sync.Mutex
+	var stopped []string
+	for _, name := range []string{"manager", "healthz server", "main server"} {
+		lc.Go(name, func(ctx context.Context) error {
+			<-ctx.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			stopped = append(stopped, name)
+			// Errors returned once stopped aren't failures
+			return errors.New("stopped")
+		})
+	}
+
+	if err := lc.Stop(); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if expected := []string{"main server", "healthz server", "manager"}; !slices.Equal(stopped, expected) {
+		t.Errorf("stopped = %v, want %v", stopped, expected)
+	}
+}
+
+func TestLifecycle_Failure(t *testing.T) {
+	lc := New(context.Background())
+	managerStopped := false
+	lc.Go("manager", func(ctx context.Context) error {
+		<-ctx.Done()
+		managerStopped = true
+		return nil
+	})
+	lc.Go("healthz server", func(ctx context.Context) error {
+		return errors.New("listen tcp :8080: bind: address already in use")
+	})
+
+	// The cache sync isn't waited for anymore once a component failed
+	err := lc.WaitFor(context.Background(), "cache sync", func(ctx context.Context) bool {
+		<-ctx.Done()
+		return false
+	})
+	if err == nil || !strings.Contains(err.Error(), "a component failed") {
+		t.Errorf("WaitFor() error = %v, want the failure of a component", err)
+	}
+	select {
+	case <-lc.Failed():
+	default:
+		t.Errorf("Failed() not closed after a failure")
+	}
+
+	err = lc.Stop()
+	if err == nil || err.Error() != "the healthz server failed: listen tcp :8080: bind: address already in use" {
+		t.Errorf("Stop() error = %v, want the failure of the healthz server", err)
+	}
+	if !managerStopped {
+		t.Errorf("the manager wasn't stopped")
+	}
+}
+
+func TestLifecycle_WaitFor(t *testing.T) {
+	lc := New(context.Background())
+	defer lc.Stop()
+
+	if err := lc.WaitFor(context.Background(), "cache sync", func(context.Context) bool { return true }); err != nil {
+		t.Errorf("WaitFor() error = %v", err)
+	}
+
+	// The wait stops with its context, e.g. on a termination signal
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := lc.WaitFor(ctx, "cache sync", func(ctx context.Context) bool {
+		<-ctx.Done()
+		return false
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitFor() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestServer(t *testing.T) {
+	// A port already taken
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	tests := []struct {
+		name        string
+		addr        string
+		expectedErr bool
+	}{
+		{name: "shut down once stopped", addr: "127.0.0.1:0"},
+		{name: "failing to listen", addr: listener.Addr().String(), expectedErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &http.Server{Addr: tt.addr}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- Server("test", server, server.ListenAndServe, time.Second)(ctx)
+			}()
+			if !tt.expectedErr {
+				cancel()
+			}
+			select {
+			case err := <-done:
+				if (err != nil) != tt.expectedErr {
+					t.Errorf("Server() error = %v, want error %v", err, tt.expectedErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Server() didn't return")
+			}
+			cancel()
+		})
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	tests := []struct {
+		name              string
+		requestDuration   time.Duration
+		timeout           time.Duration
+		expectedCompleted float64
+		expectedCutOff    float64
+	}{
+		{name: "completed within the timeout", requestDuration: 50 * time.Millisecond, timeout: 5 * time.Second, expectedCompleted: 1},
+		{name: "cut off past the timeout", requestDuration: 10 * time.Second, timeout: 50 * time.Millisecond, expectedCutOff: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+			started := make(chan struct{})
+			server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-time.After(tt.requestDuration):
+				case <-r.Context().Done():
+				}
+			})}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan error, 1)
+			go func() {
+				done <- Server(tt.name, server, func() error { return server.Serve(listener) }, tt.timeout)(ctx)
+			}()
+
+			go func() {
+				if resp, err := http.Get("http://" + listener.Addr().String()); err == nil {
+					resp.Body.Close()
+				}
+			}()
+			<-started
+			cancel()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Server() error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Server() didn't return")
+			}
+
+			if got := testutil.ToFloat64(shutdownRequests.WithLabelValues(tt.name, OutcomeCompleted)); got != tt.expectedCompleted {
+				t.Errorf("completed requests = %v, want %v", got, tt.expectedCompleted)
+			}
+			if got := testutil.ToFloat64(shutdownRequests.WithLabelValues(tt.name, OutcomeCutOff)); got != tt.expectedCutOff {
+				t.Errorf("cut off requests = %v, want %v", got, tt.expectedCutOff)
+			}
+		})
+	}
+}
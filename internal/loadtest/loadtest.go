@@ -0,0 +1,110 @@
+// Package loadtest generates the fake clusters the benchmarks and the loadtest server are run against, so that the
+// throughput and allocations of the endpoints can be measured with a large number of objects and without a cluster.
+package loadtest
+
+import (
+	"fmt"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/projection"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Cluster describes the fake cluster to generate
+type Cluster struct {
+	// Deployments is the total number of deployments
+	Deployments int
+	// Namespaces is the number of namespaces the deployments are spread over, evenly
+	Namespaces int
+}
+
+// Namespace returns the name of the i-th namespace of the cluster
+func Namespace(i int) string {
+	return fmt.Sprintf("loadtest-%d", i)
+}
+
+// Deployment returns the namespace and name of the i-th deployment of the cluster
+func (c Cluster) Deployment(i int) (string, string) {
+	return Namespace(i % max(c.Namespaces, 1)), fmt.Sprintf("deployment-%d", i)
+}
+
+// Objects returns the deployments of the cluster, shaped like the ones of a real cluster (labels, selector, a pod
+// template and a status) so that the size of the objects copied and encoded by the endpoints is realistic
+func (c Cluster) Objects() []client.Object {
+	objects := make([]client.Object, 0, c.Deployments)
+	for i := range c.Deployments {
+		namespace, name := c.Deployment(i)
+		labels := map[string]string{"app.kubernetes.io/name": name, "app.kubernetes.io/part-of": "loadtest"}
+		objects = append(objects, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    labels,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To(int32(i%5 + 1)),
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name:  "app",
+							Image: "registry.example.com/loadtest/app:1.0.0",
+							Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+						}},
+					},
+				},
+			},
+			Status: appsv1.DeploymentStatus{
+				Replicas:          int32(i%5 + 1),
+				ReadyReplicas:     int32(i%5 + 1),
+				UpdatedReplicas:   int32(i%5 + 1),
+				AvailableReplicas: int32(i%5 + 1),
+			},
+		})
+	}
+	return objects
+}
+
+// NewClient returns a fake client serving the deployments of the cluster
+func (c Cluster) NewClient() (client.WithWatch, error) {
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add apps/v1 to scheme: %w", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add core/v1 to scheme: %w", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(c.Objects()...).Build(), nil
+}
+
+// NewSummaries returns the summaries of the deployments of the cluster, as maintained for the informer cache
+func (c Cluster) NewSummaries() (*projection.Store, error) {
+	summaries := projection.NewStore()
+	if err := summaries.Track(&staticInformer{objects: c.Objects()}); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// staticInformer is an informer which has synced the given objects, and never receives any update
+type staticInformer struct {
+	objects []client.Object
+}
+
+func (s *staticInformer) AddEventHandler(handler toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error) {
+	for _, obj := range s.objects {
+		handler.OnAdd(obj, true)
+	}
+	return nil, nil
+}
+
+func (s *staticInformer) HasSynced() bool {
+	return true
+}
@@ -0,0 +1,38 @@
+package loadtest
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestCluster_NewClient(t *testing.T) {
+	cluster := Cluster{Deployments: 25, Namespaces: 10}
+	c, err := cluster.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	all := &appsv1.DeploymentList{}
+	if err := c.List(context.Background(), all); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(all.Items) != cluster.Deployments {
+		t.Errorf("List() returned %d deployments, want %d", len(all.Items), cluster.Deployments)
+	}
+
+	// The deployments are spread evenly over the namespaces, and can be looked up by their index
+	namespaced := &appsv1.DeploymentList{}
+	if err := c.List(context.Background(), namespaced, client.InNamespace(Namespace(4))); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(namespaced.Items) != 3 {
+		t.Errorf("List() in %s returned %d deployments, want 3", Namespace(4), len(namespaced.Items))
+	}
+	namespace, name := cluster.Deployment(24)
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, &appsv1.Deployment{}); err != nil {
+		t.Errorf("Get(%s/%s) error = %v", namespace, name, err)
+	}
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/google/uuid"
+	"k8s.io/klog/v2"
+)
+
+// RequestIDHeader is the header carrying the ID of a request, which is logged with every line logged while serving it
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of a request ID passed in by the client, longer ones are replaced
+const maxRequestIDLength = 128
+
+// TraceparentHeader is the W3C Trace Context header carrying the trace a request is part of, e.g. as set by an
+// instrumented client, ingress controller or service mesh
+const TraceparentHeader = "traceparent"
+
+// Supported logging formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// RequestID returns the ID of the request. The ID passed in by the client in the RequestIDHeader header is used
+// if present, so that requests can be correlated across services. Otherwise a new random ID is generated.
+func RequestID(r *http.Request) string {
+	if requestID := r.Header.Get(RequestIDHeader); requestID != "" && len(requestID) <= maxRequestIDLength {
+		return requestID
+	}
+	return uuid.NewString()
+}
+
+// TraceID returns the trace ID of the traceparent header of the request, and false when the header is missing or
+// invalid. The header is of the version-traceID-parentID-flags format, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func TraceID(r *http.Request) (string, bool) {
+	fields := strings.Split(strings.TrimSpace(r.Header.Get(TraceparentHeader)), "-")
+	// Future versions may append fields, which are ignored
+	if len(fields) < 4 || len(fields[0]) != 2 || fields[0] == "ff" || (fields[0] == "00" && len(fields) != 4) {
+		return "", false
+	}
+	traceID, parentID := fields[1], fields[2]
+	if len(traceID) != 32 || !isLowerHex(traceID) || strings.Trim(traceID, "0") == "" ||
+		len(parentID) != 16 || !isLowerHex(parentID) || !isLowerHex(fields[0]) {
+		return "", false
+	}
+	return traceID, true
+}
+
+func isLowerHex(s string) bool {
+	return strings.Trim(s, "0123456789abcdef") == ""
+}
+
+// NewJSONLogger returns a new logger writing one JSON object per line to the given writer.
+// Its verbosity follows klog's verbosity, so that the -v flag and runtime log level changes apply to it as well.
+func NewJSONLogger(w io.Writer) logr.Logger {
+	sink := funcr.NewJSON(func(obj string) {
+		fmt.Fprintln(w, obj)
+	}, funcr.Options{
+		LogTimestamp: true,
+		LogCaller:    funcr.Error,
+	}).GetSink()
+	return logr.New(klogVerbositySink{sink})
+}
+
+// Setup configures klog to log in the given format
+func Setup(format string, w io.Writer) error {
+	switch format {
+	case FormatText:
+		// klog logs in text format by default
+	case FormatJSON:
+		klog.SetLogger(NewJSONLogger(w))
+	default:
+		return fmt.Errorf("unsupported logging format %q, must be one of %q or %q", format, FormatText, FormatJSON)
+	}
+	return nil
+}
+
+// klogVerbositySink is a logr.LogSink which filters log lines using klog's verbosity
+type klogVerbositySink struct {
+	logr.LogSink
+}
+
+// Enabled returns whether a log line with the given level should be logged, according to klog's verbosity
+func (s klogVerbositySink) Enabled(level int) bool {
+	return klog.V(klog.Level(level)).Enabled()
+}
+
+// WithValues returns a new sink with additional key / value pairs
+func (s klogVerbositySink) WithValues(keysAndValues ...any) logr.LogSink {
+	return klogVerbositySink{s.LogSink.WithValues(keysAndValues...)}
+}
+
+// WithName returns a new sink with the given name appended
+func (s klogVerbositySink) WithName(name string) logr.LogSink {
+	return klogVerbositySink{s.LogSink.WithName(name)}
+}
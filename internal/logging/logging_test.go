@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		expectNew bool
+	}{
+		{"Test RequestID From Header", "abc-123", false},
+		{"Test RequestID Generated", "", true},
+		{"Test RequestID Too Long", strings.Repeat("a", maxRequestIDLength+1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/deployments", nil)
+			if tt.header != "" {
+				r.Header.Set(RequestIDHeader, tt.header)
+			}
+			requestID := RequestID(r)
+			if requestID == "" {
+				t.Fatalf("RequestID() returned an empty ID")
+			}
+			if (requestID != tt.header) != tt.expectNew {
+				t.Errorf("RequestID() = %v, expectNew %v", requestID, tt.expectNew)
+			}
+		})
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	tests := []struct {
+		name            string
+		header          string
+		expectedTraceID string
+	}{
+		{name: "sampled", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "not sampled", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "future version with more fields", header: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "missing"},
+		{name: "version 00 with more fields", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "invalid version", header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "zero trace ID", header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "uppercase trace ID", header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "short parent ID", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/deployments", nil)
+			if tt.header != "" {
+				r.Header.Set(TraceparentHeader, tt.header)
+			}
+			traceID, ok := TraceID(r)
+			if traceID != tt.expectedTraceID || ok != (tt.expectedTraceID != "") {
+				t.Errorf("TraceID() = %q, %v, want %q", traceID, ok, tt.expectedTraceID)
+			}
+		})
+	}
+}
+
+func TestNewJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf).WithValues("requestID", "abc-123")
+	logger.Info("Listed deployments", "namespace", "foo")
+
+	// Verbose lines are filtered according to klog's verbosity, which defaults to 0
+	logger.V(5).Info("Should not be logged")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("NewJSONLogger() logged %d lines, want 1: %v", len(lines), lines)
+	}
+	var line map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("NewJSONLogger() logged invalid JSON: %v", err)
+	}
+	if line["msg"] != "Listed deployments" || line["requestID"] != "abc-123" || line["namespace"] != "foo" {
+		t.Errorf("NewJSONLogger() logged %v, want msg, requestID and namespace fields", line)
+	}
+}
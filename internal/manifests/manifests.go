@@ -0,0 +1,146 @@
+package manifests
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/features"
+)
+
+//go:embed templates/*.yaml
+var templatesFS embed.FS
+
+// templateOrder is the order in which the manifests are rendered, so that dependencies are applied first
+var templateOrder = []string{
+	"serviceaccount.yaml",
+	"rbac.yaml",
+	"certificate.yaml",
+	"deployment.yaml",
+	"service.yaml",
+	"networkpolicy.yaml",
+}
+
+// Config holds the parameters of the rendered manifests
+type Config struct {
+	// Name is the name of every rendered object
+	Name      string
+	Namespace string
+	Image     string
+	Replicas  int
+	// LeaderElection enables leader election, and grants the permissions it requires
+	LeaderElection bool
+	// Gates are the feature gates of the server. The RBAC rules only grant the permissions the enabled endpoints need.
+	Gates *features.Gates
+	// CertManagerIssuer is the name of the cert-manager issuer of the server certificate. When empty, no Certificate
+	// is rendered, and the <name>-certs Secret has to be provided separately.
+	CertManagerIssuer     string
+	CertManagerIssuerKind string
+	// NetworkPolicy renders a NetworkPolicy only allowing ingress traffic to the API and healthz ports
+	NetworkPolicy bool
+	// ExtraArgs are additional command line arguments passed to the server
+	ExtraArgs []string
+}
+
+// Validate validates the configuration
+func (c Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if c.Namespace == "" {
+		return fmt.Errorf("namespace is required")
+	}
+	if c.Image == "" {
+		return fmt.Errorf("image is required")
+	}
+	if c.Replicas < 1 {
+		return fmt.Errorf("replicas must be at least 1")
+	}
+	if c.Replicas > 1 && !c.LeaderElection {
+		return fmt.Errorf("leader election must be enabled to run more than 1 replica")
+	}
+	if c.CertManagerIssuer != "" && c.CertManagerIssuerKind != "Issuer" && c.CertManagerIssuerKind != "ClusterIssuer" {
+		return fmt.Errorf("invalid cert-manager issuer kind %q, must be either Issuer or ClusterIssuer", c.CertManagerIssuerKind)
+	}
+	return nil
+}
+
+// patchesDeployments returns whether any of the enabled endpoints patches deployments
+func (c Config) patchesDeployments() bool {
+	// Diffs are computed with a server-side dry-run patch, and applies are patches as well, which may create objects.
+	// Replica bounds are declared in annotations, and enforced by scaling deployments. Ownership is declared in
+	// annotations as well, and snapshots are restored by patching the spec. Hibernation scales deployments too.
+	return c.Gates.Enabled(features.SetDeploymentReplicas) || c.Gates.Enabled(features.DiffDeployment) || c.Gates.Enabled(features.ApplyManifests) ||
+		c.Gates.Enabled(features.ReplicaBounds) || c.Gates.Enabled(features.DeploymentOwnership) || c.Gates.Enabled(features.DeploymentSnapshots) ||
+		c.Gates.Enabled(features.NamespaceHibernation)
+}
+
+// deploymentVerbs returns the verbs required on deployments by the enabled endpoints
+func (c Config) deploymentVerbs() []string {
+	// The cache lists and watches deployments regardless of the enabled endpoints, and get is used for live reads
+	verbs := []string{"get", "list", "watch"}
+	if c.patchesDeployments() {
+		verbs = append(verbs, "patch")
+	}
+	if c.Gates.Enabled(features.ApplyManifests) {
+		verbs = append(verbs, "create")
+	}
+	return verbs
+}
+
+// args returns the command line arguments of the server container
+func (c Config) args() []string {
+	args := []string{"--feature-gates=" + c.Gates.String()}
+	if c.LeaderElection {
+		args = append(args, "--leader-elect", "--leader-election-namespace="+c.Namespace)
+	}
+	return append(args, c.ExtraArgs...)
+}
+
+// Render renders the manifests needed to run the server in-cluster as a multi-document YAML stream
+func Render(w io.Writer, cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	tmpl, err := template.New("manifests").Funcs(template.FuncMap{
+		"quote": func(s string) string { return fmt.Sprintf("%q", s) },
+	}).ParseFS(templatesFS, "templates/*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to parse manifest templates: %w", err)
+	}
+
+	data := struct {
+		Config
+		DeploymentVerbs []string
+		ListPods        bool
+		RecordEvents    bool
+		StatefulSets    bool
+		EvictPods       bool
+		Topology        bool
+		Applications    bool
+		Args            []string
+	}{Config: cfg, DeploymentVerbs: cfg.deploymentVerbs(), ListPods: cfg.Gates.Enabled(features.GetDeploymentHealth) || cfg.Gates.Enabled(features.SchedulingInsights) ||
+		cfg.Gates.Enabled(features.DeploymentTopology),
+		RecordEvents: cfg.patchesDeployments() || cfg.Gates.Enabled(features.EvictPods), StatefulSets: cfg.Gates.Enabled(features.NamespaceHibernation),
+		EvictPods: cfg.Gates.Enabled(features.EvictPods), Topology: cfg.Gates.Enabled(features.DeploymentTopology),
+		Applications: cfg.Gates.Enabled(features.Applications), Args: cfg.args()}
+
+	var documents []string
+	for _, name := range templateOrder {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", name, err)
+		}
+		// Templates which are disabled by the config render to nothing
+		if document := strings.TrimSpace(buf.String()); document != "" {
+			documents = append(documents, document)
+		}
+	}
+
+	_, err = io.WriteString(w, strings.Join(documents, "\n---\n")+"\n")
+	return err
+}
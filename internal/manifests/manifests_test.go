@@ -0,0 +1,221 @@
+package manifests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/features"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func newConfig() Config {
+	return Config{
+		Name:                  "api",
+		Namespace:             "gateway",
+		Image:                 "registry/api:v1",
+		Replicas:              1,
+		Gates:                 features.NewGates(),
+		CertManagerIssuerKind: "Issuer",
+	}
+}
+
+// render renders the manifests and returns them indexed by kind/name
+func render(t *testing.T, cfg Config) map[string]*unstructured.Unstructured {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Render(&buf, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	objects := map[string]*unstructured.Unstructured{}
+	for _, document := range strings.Split(buf.String(), "\n---\n") {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(document), &obj.Object); err != nil {
+			t.Fatalf("failed to parse rendered manifest: %v\n%s", err, document)
+		}
+		objects[obj.GetKind()+"/"+obj.GetName()] = obj
+	}
+	return objects
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name         string
+		modify       func(cfg *Config)
+		wantObjects  []string
+		wantVerbs    []interface{}
+		wantNoPods   bool
+		wantNoEvents bool
+		// wantStatefulSets expects a statefulsets rule, which is only granted for hibernation
+		wantStatefulSets bool
+		// wantEvictions expects a pods/eviction rule, which is only granted for evicting pods
+		wantEvictions bool
+		wantArgs      []interface{}
+	}{
+		{
+			name: "defaults",
+			wantObjects: []string{
+				"ServiceAccount/api", "ClusterRole/api", "ClusterRoleBinding/api", "Deployment/api", "Service/api",
+			},
+			wantVerbs: []interface{}{"get", "list", "watch", "patch"},
+			wantArgs:  []interface{}{"--feature-gates=Applications=false,ApplyManifests=false,CostEstimation=false,DeploymentManagers=true,DeploymentOwnership=true,DeploymentSnapshots=true,DeploymentTopology=false,DiffDeployment=true,EvictPods=false,GetDeployment=true,GetDeploymentHealth=true,GetDeploymentManifest=true,GetDeploymentReplicas=true,ImageInventory=true,ListDeployments=true,NamespaceHibernation=false,ReplicaBounds=true,RolloutAlerts=true,SchedulingInsights=true,Search=true,SetDeploymentReplicas=true"},
+		},
+		{
+			name: "everything enabled",
+			modify: func(cfg *Config) {
+				cfg.Replicas = 2
+				cfg.LeaderElection = true
+				cfg.CertManagerIssuer = "ca"
+				cfg.NetworkPolicy = true
+				cfg.ExtraArgs = []string{"--cache-namespaces=a,b"}
+			},
+			wantObjects: []string{
+				"ServiceAccount/api", "ClusterRole/api", "ClusterRoleBinding/api", "Role/api-leader-election",
+				"RoleBinding/api-leader-election", "Certificate/api", "Deployment/api", "Service/api", "NetworkPolicy/api",
+			},
+			wantVerbs: []interface{}{"get", "list", "watch", "patch"},
+			wantArgs: []interface{}{
+				"--feature-gates=Applications=false,ApplyManifests=false,CostEstimation=false,DeploymentManagers=true,DeploymentOwnership=true,DeploymentSnapshots=true,DeploymentTopology=false,DiffDeployment=true,EvictPods=false,GetDeployment=true,GetDeploymentHealth=true,GetDeploymentManifest=true,GetDeploymentReplicas=true,ImageInventory=true,ListDeployments=true,NamespaceHibernation=false,ReplicaBounds=true,RolloutAlerts=true,SchedulingInsights=true,Search=true,SetDeploymentReplicas=true",
+				"--leader-elect", "--leader-election-namespace=gateway", "--cache-namespaces=a,b",
+			},
+		},
+		{
+			name: "scaling disabled",
+			modify: func(cfg *Config) {
+				_ = cfg.Gates.Set("SetDeploymentReplicas=false,DiffDeployment=false,ReplicaBounds=false,DeploymentOwnership=false,DeploymentSnapshots=false")
+			},
+			wantObjects: []string{
+				"ServiceAccount/api", "ClusterRole/api", "ClusterRoleBinding/api", "Deployment/api", "Service/api",
+			},
+			wantVerbs:    []interface{}{"get", "list", "watch"},
+			wantNoEvents: true,
+			wantArgs:     []interface{}{"--feature-gates=Applications=false,ApplyManifests=false,CostEstimation=false,DeploymentManagers=true,DeploymentOwnership=false,DeploymentSnapshots=false,DeploymentTopology=false,DiffDeployment=false,EvictPods=false,GetDeployment=true,GetDeploymentHealth=true,GetDeploymentManifest=true,GetDeploymentReplicas=true,ImageInventory=true,ListDeployments=true,NamespaceHibernation=false,ReplicaBounds=false,RolloutAlerts=true,SchedulingInsights=true,Search=true,SetDeploymentReplicas=false"},
+		},
+		{
+			name: "apply enabled",
+			modify: func(cfg *Config) {
+				_ = cfg.Gates.Set("SetDeploymentReplicas=false,DiffDeployment=false,ApplyManifests=true,GetDeploymentHealth=false,SchedulingInsights=false")
+			},
+			wantNoPods: true,
+			wantObjects: []string{
+				"ServiceAccount/api", "ClusterRole/api", "ClusterRoleBinding/api", "Deployment/api", "Service/api",
+			},
+			wantVerbs: []interface{}{"get", "list", "watch", "patch", "create"},
+			wantArgs:  []interface{}{"--feature-gates=Applications=false,ApplyManifests=true,CostEstimation=false,DeploymentManagers=true,DeploymentOwnership=true,DeploymentSnapshots=true,DeploymentTopology=false,DiffDeployment=false,EvictPods=false,GetDeployment=true,GetDeploymentHealth=false,GetDeploymentManifest=true,GetDeploymentReplicas=true,ImageInventory=true,ListDeployments=true,NamespaceHibernation=false,ReplicaBounds=true,RolloutAlerts=true,SchedulingInsights=false,Search=true,SetDeploymentReplicas=false"},
+		},
+		{
+			name: "hibernation enabled",
+			modify: func(cfg *Config) {
+				_ = cfg.Gates.Set("SetDeploymentReplicas=false,DiffDeployment=false,ReplicaBounds=false,DeploymentOwnership=false,DeploymentSnapshots=false,NamespaceHibernation=true")
+			},
+			wantObjects: []string{
+				"ServiceAccount/api", "ClusterRole/api", "ClusterRoleBinding/api", "Deployment/api", "Service/api",
+			},
+			wantVerbs:        []interface{}{"get", "list", "watch", "patch"},
+			wantStatefulSets: true,
+			wantArgs:         []interface{}{"--feature-gates=Applications=false,ApplyManifests=false,CostEstimation=false,DeploymentManagers=true,DeploymentOwnership=false,DeploymentSnapshots=false,DeploymentTopology=false,DiffDeployment=false,EvictPods=false,GetDeployment=true,GetDeploymentHealth=true,GetDeploymentManifest=true,GetDeploymentReplicas=true,ImageInventory=true,ListDeployments=true,NamespaceHibernation=true,ReplicaBounds=false,RolloutAlerts=true,SchedulingInsights=true,Search=true,SetDeploymentReplicas=false"},
+		},
+		{
+			name: "evictions enabled",
+			modify: func(cfg *Config) {
+				_ = cfg.Gates.Set("SetDeploymentReplicas=false,DiffDeployment=false,ReplicaBounds=false,DeploymentOwnership=false,DeploymentSnapshots=false,EvictPods=true")
+			},
+			wantObjects: []string{
+				"ServiceAccount/api", "ClusterRole/api", "ClusterRoleBinding/api", "Deployment/api", "Service/api",
+			},
+			wantVerbs:     []interface{}{"get", "list", "watch"},
+			wantEvictions: true,
+			wantArgs:      []interface{}{"--feature-gates=Applications=false,ApplyManifests=false,CostEstimation=false,DeploymentManagers=true,DeploymentOwnership=false,DeploymentSnapshots=false,DeploymentTopology=false,DiffDeployment=false,EvictPods=true,GetDeployment=true,GetDeploymentHealth=true,GetDeploymentManifest=true,GetDeploymentReplicas=true,ImageInventory=true,ListDeployments=true,NamespaceHibernation=false,ReplicaBounds=false,RolloutAlerts=true,SchedulingInsights=true,Search=true,SetDeploymentReplicas=false"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newConfig()
+			if tt.modify != nil {
+				tt.modify(&cfg)
+			}
+			objects := render(t, cfg)
+
+			if len(objects) != len(tt.wantObjects) {
+				t.Errorf("expected %d objects, got %d", len(tt.wantObjects), len(objects))
+			}
+			for _, name := range tt.wantObjects {
+				if _, ok := objects[name]; !ok {
+					t.Errorf("expected %s to be rendered", name)
+				}
+			}
+
+			rules, _, _ := unstructured.NestedSlice(objects["ClusterRole/api"].Object, "rules")
+			verbs := rules[0].(map[string]interface{})["verbs"].([]interface{})
+			if !equal(verbs, tt.wantVerbs) {
+				t.Errorf("expected verbs %v, got %v", tt.wantVerbs, verbs)
+			}
+			podsRule, eventsRule, statefulSetsRule, evictionsRule := false, false, false, false
+			for _, rule := range rules {
+				resources := rule.(map[string]interface{})["resources"].([]interface{})
+				podsRule = podsRule || equal(resources, []interface{}{"pods"})
+				eventsRule = eventsRule || equal(resources, []interface{}{"events"})
+				statefulSetsRule = statefulSetsRule || equal(resources, []interface{}{"statefulsets"})
+				evictionsRule = evictionsRule || equal(resources, []interface{}{"pods/eviction"})
+			}
+			if evictionsRule != tt.wantEvictions {
+				t.Errorf("expected a pods/eviction rule: %v, got: %v", tt.wantEvictions, evictionsRule)
+			}
+			if statefulSetsRule != tt.wantStatefulSets {
+				t.Errorf("expected a statefulsets rule: %v, got: %v", tt.wantStatefulSets, statefulSetsRule)
+			}
+			if podsRule == tt.wantNoPods {
+				t.Errorf("expected a pods rule: %v, got: %v", !tt.wantNoPods, podsRule)
+			}
+			if eventsRule == tt.wantNoEvents {
+				t.Errorf("expected an events rule: %v, got: %v", !tt.wantNoEvents, eventsRule)
+			}
+
+			containers, _, _ := unstructured.NestedSlice(objects["Deployment/api"].Object, "spec", "template", "spec", "containers")
+			args := containers[0].(map[string]interface{})["args"].([]interface{})
+			if !equal(args, tt.wantArgs) {
+				t.Errorf("expected args %v, got %v", tt.wantArgs, args)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(cfg *Config) {}},
+		{name: "missing namespace", modify: func(cfg *Config) { cfg.Namespace = "" }, wantErr: true},
+		{name: "missing image", modify: func(cfg *Config) { cfg.Image = "" }, wantErr: true},
+		{name: "multiple replicas without leader election", modify: func(cfg *Config) { cfg.Replicas = 3 }, wantErr: true},
+		{name: "multiple replicas with leader election", modify: func(cfg *Config) { cfg.Replicas = 3; cfg.LeaderElection = true }},
+		{name: "invalid issuer kind", modify: func(cfg *Config) { cfg.CertManagerIssuer = "ca"; cfg.CertManagerIssuerKind = "Foo" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newConfig()
+			tt.modify(&cfg)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func equal(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
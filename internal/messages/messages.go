@@ -0,0 +1,233 @@
+// Package messages is the catalog of the user facing error messages. Every message has a stable code and named
+// parameters (e.g. the namespace and name of a deployment), so that clients can localize it from the code and the
+// parameters, and tests can assert on the code rather than on the English sentence.
+package messages
+
+import (
+	"sort"
+	"strings"
+)
+
+// Code identifies a message of the catalog. Codes are part of the API, so they are never renamed nor reused.
+type Code string
+
+// The codes of the messages. Their parameters are documented by the templates of the catalog.
+const (
+	// BadRequest is the code of the invalid requests which have no dedicated code yet, with the reason in English
+	BadRequest Code = "BadRequest"
+
+	// Validation of the path and query parameters
+	InvalidNamespace             Code = "InvalidNamespace"
+	InvalidDeploymentName        Code = "InvalidDeploymentName"
+	InvalidPodName               Code = "InvalidPodName"
+	InvalidApplicationName       Code = "InvalidApplicationName"
+	MissingQueryParameter        Code = "MissingQueryParameter"
+	InvalidBooleanParameter      Code = "InvalidBooleanParameter"
+	InvalidPositiveIntParameter  Code = "InvalidPositiveIntParameter"
+	UnsupportedParameterValue    Code = "UnsupportedParameterValue"
+	InvalidTimeParameter         Code = "InvalidTimeParameter"
+	InvalidContinueToken         Code = "InvalidContinueToken"
+	ResourceVersionRequired      Code = "ResourceVersionRequired"
+	InvalidRequestBody           Code = "InvalidRequestBody"
+	ValidationFailed             Code = "ValidationFailed"
+	ObjectOutsideTenantNamespace Code = "ObjectOutsideTenantNamespace"
+
+	// Deployments
+	DeploymentNotFound            Code = "DeploymentNotFound"
+	DeploymentPatchFailed         Code = "DeploymentPatchFailed"
+	DeploymentPodsListFailed      Code = "DeploymentPodsListFailed"
+	DeploymentManagedFieldsFailed Code = "DeploymentManagedFieldsFailed"
+	DeploymentTopologyFailed      Code = "DeploymentTopologyFailed"
+	DeploymentDiffFailed          Code = "DeploymentDiffFailed"
+	InvalidReplicaBounds          Code = "InvalidReplicaBounds"
+	ChangeRejected                Code = "ChangeRejected"
+	ChangeConflict                Code = "ChangeConflict"
+	AdmissionFailed               Code = "AdmissionFailed"
+	NamespaceWorkloadsListFailed  Code = "NamespaceWorkloadsListFailed"
+
+	// Pods
+	PodNotFound           Code = "PodNotFound"
+	PodReplaced           Code = "PodReplaced"
+	PodEvictionBlocked    Code = "PodEvictionBlocked"
+	PodEvictionFailed     Code = "PodEvictionFailed"
+	PendingPodsListFailed Code = "PendingPodsListFailed"
+
+	// Applications and search
+	ApplicationNotFound    Code = "ApplicationNotFound"
+	ApplicationGetFailed   Code = "ApplicationGetFailed"
+	ApplicationsListFailed Code = "ApplicationsListFailed"
+	SearchFailed           Code = "SearchFailed"
+
+	// Snapshots
+	SnapshotNotFound        Code = "SnapshotNotFound"
+	SnapshotGetFailed       Code = "SnapshotGetFailed"
+	SnapshotCreateFailed    Code = "SnapshotCreateFailed"
+	SnapshotsListFailed     Code = "SnapshotsListFailed"
+	SnapshotSelectorChanged Code = "SnapshotSelectorChanged"
+
+	// API keys
+	APIKeyNotFound            Code = "APIKeyNotFound"
+	APIKeyGetFailed           Code = "APIKeyGetFailed"
+	APIKeyCreateFailed        Code = "APIKeyCreateFailed"
+	APIKeyDeleteFailed        Code = "APIKeyDeleteFailed"
+	APIKeysListFailed         Code = "APIKeysListFailed"
+	APIKeyManagementForbidden Code = "APIKeyManagementForbidden"
+
+	// Approvals and operations
+	ApprovalNotFound     Code = "ApprovalNotFound"
+	ApprovalNotPending   Code = "ApprovalNotPending"
+	ApprovalExpired      Code = "ApprovalExpired"
+	ApprovalSelfApproved Code = "ApprovalSelfApproved"
+	ApprovalFailed       Code = "ApprovalFailed"
+	ApprovalGetFailed    Code = "ApprovalGetFailed"
+	ApprovalsListFailed  Code = "ApprovalsListFailed"
+	OperationNotFound    Code = "OperationNotFound"
+
+	// Audit and observability
+	AuditEventsListFailed Code = "AuditEventsListFailed"
+	DashboardNotFound     Code = "DashboardNotFound"
+	QuotaUsageListFailed  Code = "QuotaUsageListFailed"
+
+	// Feature gates
+	FeatureNotFound      Code = "FeatureNotFound"
+	FeatureNotRegistered Code = "FeatureNotRegistered"
+
+	// Captures
+	CaptureNotStarted Code = "CaptureNotStarted"
+
+	// NotLeader is returned for the mutating requests sent to a replica which isn't the leader
+	NotLeader Code = "NotLeader"
+)
+
+// catalog holds the English templates of the messages, whose parameters are written as {name}
+var catalog = map[Code]string{
+	BadRequest: "{reason}",
+
+	InvalidNamespace:             `invalid namespace "{namespace}": {reason}`,
+	InvalidDeploymentName:        `invalid deployment name "{name}": {reason}`,
+	InvalidPodName:               `invalid pod name "{name}": {reason}`,
+	InvalidApplicationName:       `invalid application name "{name}": {reason}`,
+	MissingQueryParameter:        "missing the {parameter} query parameter",
+	InvalidBooleanParameter:      `invalid value "{value}" for the {parameter} query parameter, must be a boolean`,
+	InvalidPositiveIntParameter:  `invalid value "{value}" for the {parameter} query parameter, must be a positive integer`,
+	UnsupportedParameterValue:    `invalid value "{value}" for the {parameter} query parameter, must be one of {allowed}`,
+	InvalidTimeParameter:         `invalid value "{value}" for the {parameter} query parameter, must be an RFC 3339 time`,
+	InvalidContinueToken:         "invalid continue token, it must be one returned by the previous page",
+	ResourceVersionRequired:      "the resourceVersion query parameter is required with watch=true",
+	InvalidRequestBody:           "Error parsing request body: {reason}",
+	ValidationFailed:             "Validation error: {reason}",
+	ObjectOutsideTenantNamespace: "Forbidden, object {index}: {reason}",
+
+	DeploymentNotFound:            "Error getting deployment {name} in namespace {namespace}",
+	DeploymentPatchFailed:         "Error patching deployment {name} in namespace {namespace}",
+	DeploymentPodsListFailed:      "Error listing the pods of deployment {name} in namespace {namespace}",
+	DeploymentManagedFieldsFailed: "Error parsing the managed fields of deployment {name} in namespace {namespace}",
+	DeploymentTopologyFailed:      "Error resolving the topology of deployment {name} in namespace {namespace}",
+	DeploymentDiffFailed:          "Error computing the deployment diff",
+	InvalidReplicaBounds:          "Invalid replica bounds on deployment {name} in namespace {namespace}: {reason}",
+	ChangeRejected:                "Change rejected by the API server: {reason}",
+	ChangeConflict:                "Conflict: {reason}",
+	AdmissionFailed:               "Error evaluating the admission plugins: {reason}",
+	NamespaceWorkloadsListFailed:  "Error listing the workloads of namespace {namespace}",
+
+	PodNotFound:           "Pod {name} in namespace {namespace} not found",
+	PodReplaced:           "Pod {name} in namespace {namespace} was replaced while evicting it",
+	PodEvictionBlocked:    "Eviction of pod {name} in namespace {namespace} blocked: {reason}",
+	PodEvictionFailed:     "Error evicting pod {name} in namespace {namespace}",
+	PendingPodsListFailed: "Error listing the pending pods",
+
+	ApplicationNotFound:    "Application {name} not found in namespace {namespace}",
+	ApplicationGetFailed:   "Error getting application {name} in namespace {namespace}",
+	ApplicationsListFailed: "Error listing the applications",
+	SearchFailed:           "Error searching the objects",
+
+	SnapshotNotFound:        "Snapshot {id} of deployment {name} in namespace {namespace} not found",
+	SnapshotGetFailed:       "Error getting snapshot {id} of deployment {name} in namespace {namespace}",
+	SnapshotCreateFailed:    "Error creating snapshot of deployment {name} in namespace {namespace}",
+	SnapshotsListFailed:     "Error listing snapshots of deployment {name} in namespace {namespace}",
+	SnapshotSelectorChanged: "Snapshot {id} can't be restored, since the selector of deployment {name} in namespace {namespace} has changed since",
+
+	APIKeyNotFound:            "API key {id} not found",
+	APIKeyGetFailed:           "Error getting API key {id}",
+	APIKeyCreateFailed:        "Error creating API key",
+	APIKeyDeleteFailed:        "Error deleting API key {id}",
+	APIKeysListFailed:         "Error listing API keys",
+	APIKeyManagementForbidden: "Forbidden, API keys can't be managed by the requests authenticated with an API key",
+
+	ApprovalNotFound:     "Approval {id} not found",
+	ApprovalNotPending:   "Approval {id} is not pending",
+	ApprovalExpired:      "Approval {id} has expired",
+	ApprovalSelfApproved: "Changes must be approved by an approver other than their requester",
+	ApprovalFailed:       "Error approving change",
+	ApprovalGetFailed:    "Error getting approval",
+	ApprovalsListFailed:  "Error listing approvals",
+	OperationNotFound:    "Operation {id} not found",
+
+	AuditEventsListFailed: "Error listing the audit events",
+	DashboardNotFound:     "Dashboard {name} not found",
+	QuotaUsageListFailed:  "Error listing the usage of the quotas",
+
+	FeatureNotFound:      "Feature gate {name} not found",
+	FeatureNotRegistered: "Feature gate {name} is disabled by --feature-gates, only the feature gates enabled at startup can be switched at runtime",
+
+	CaptureNotStarted: "No capture was started",
+
+	NotLeader: "This instance is not the leader, mutating requests are served by the leader only",
+}
+
+// Message is a message of the catalog, along with the values of its parameters. It implements error, so that the
+// validation functions can return it as is.
+type Message struct {
+	Code   Code
+	Params map[string]string
+}
+
+// New returns the message of the given code, with its parameters given as name, value pairs
+func New(code Code, params ...string) Message {
+	m := Message{Code: code}
+	if len(params) > 0 {
+		m.Params = make(map[string]string, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			m.Params[params[i]] = params[i+1]
+		}
+	}
+	return m
+}
+
+// String renders the message in English. The parameters missing from the message are left as is, e.g. {name}, and the
+// messages of an unknown code are rendered as their code.
+func (m Message) String() string {
+	template, ok := catalog[m.Code]
+	if !ok {
+		return string(m.Code)
+	}
+	if len(m.Params) == 0 {
+		return template
+	}
+	oldnew := make([]string, 0, 2*len(m.Params))
+	for name, value := range m.Params {
+		oldnew = append(oldnew, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(oldnew...).Replace(template)
+}
+
+// Error returns the message rendered in English
+func (m Message) Error() string {
+	return m.String()
+}
+
+// Template returns the English template of the given code, and whether it's in the catalog
+func Template(code Code) (string, bool) {
+	template, ok := catalog[code]
+	return template, ok
+}
+
+// Codes returns the codes of the catalog, sorted
+func Codes() []Code {
+	codes := make([]Code, 0, len(catalog))
+	for code := range catalog {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
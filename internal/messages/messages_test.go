@@ -0,0 +1,72 @@
+package messages
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestMessage_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  Message
+		expected string
+	}{
+		{
+			"Test Message With Parameters",
+			New(DeploymentNotFound, "namespace", "foo", "name", "bar"),
+			"Error getting deployment bar in namespace foo",
+		},
+		{
+			"Test Message Without Parameters",
+			New(ApprovalSelfApproved),
+			"Changes must be approved by an approver other than their requester",
+		},
+		{
+			"Test Message Missing A Parameter",
+			New(DeploymentNotFound, "name", "bar"),
+			"Error getting deployment bar in namespace {namespace}",
+		},
+		{
+			"Test Parameter Values Aren't Substituted",
+			New(InvalidRequestBody, "reason", "unknown field {name}", "name", "bar"),
+			"Error parsing request body: unknown field {name}",
+		},
+		{
+			"Test Odd Parameter Ignored",
+			New(OperationNotFound, "id", "42", "extra"),
+			"Operation 42 not found",
+		},
+		{
+			"Test Unknown Code",
+			New(Code("Unknown")),
+			"Unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.message.String(); got != tt.expected {
+				t.Errorf("String() = %q, want %q", got, tt.expected)
+			}
+			if got := tt.message.Error(); got != tt.expected {
+				t.Errorf("Error() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	param := regexp.MustCompile(`\{[^}]*\}`)
+	name := regexp.MustCompile(`^\{[a-z]+\}$`)
+	for _, code := range Codes() {
+		template, ok := Template(code)
+		if !ok || template == "" {
+			t.Errorf("code %s has no template", code)
+		}
+		// Parameters are lower case words, so that they can be reliably substituted by the clients localizing them
+		for _, p := range param.FindAllString(template, -1) {
+			if !name.MatchString(p) {
+				t.Errorf("template of code %s has an invalid parameter %s", code, p)
+			}
+		}
+	}
+}
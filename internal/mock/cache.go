@@ -0,0 +1,190 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// informerCache is a cache.Cache whose informers list and watch the objects of the fake client instead of an API
+// server, so that the changes made through the client reach their event handlers, e.g. the deployment summaries and
+// the controllers. Reads are served by the fake client directly.
+type informerCache struct {
+	client.Reader
+	client client.WithWatch
+	scheme *runtime.Scheme
+
+	mu sync.Mutex
+	// ctx is the context the cache was started with, nil until started
+	ctx       context.Context
+	informers map[informerKey]*informer
+}
+
+type informerKey struct {
+	gvk          schema.GroupVersionKind
+	unstructured bool
+}
+
+type informer struct {
+	toolscache.SharedIndexInformer
+	cancel context.CancelFunc
+}
+
+var _ cache.Cache = &informerCache{}
+
+func newInformerCache(c client.WithWatch, scheme *runtime.Scheme) *informerCache {
+	return &informerCache{Reader: c, client: c, scheme: scheme, informers: map[informerKey]*informer{}}
+}
+
+// GetInformer returns the informer of the kind of the object, creating it if needed. It doesn't wait for the
+// informer to sync.
+func (c *informerCache) GetInformer(_ context.Context, obj client.Object, _ ...cache.InformerGetOption) (cache.Informer, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return nil, err
+	}
+	_, isUnstructured := obj.(runtime.Unstructured)
+	return c.informerFor(informerKey{gvk: gvk, unstructured: isUnstructured})
+}
+
+// GetInformerForKind returns the informer of the kind, creating it if needed
+func (c *informerCache) GetInformerForKind(_ context.Context, gvk schema.GroupVersionKind, _ ...cache.InformerGetOption) (cache.Informer, error) {
+	return c.informerFor(informerKey{gvk: gvk})
+}
+
+func (c *informerCache) informerFor(key informerKey) (cache.Informer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i, ok := c.informers[key]; ok {
+		return i, nil
+	}
+
+	example, newList, err := c.newObjects(key)
+	if err != nil {
+		return nil, err
+	}
+	lw := &toolscache.ListWatch{
+		ListFunc: func(metav1.ListOptions) (runtime.Object, error) {
+			list := newList()
+			if err := c.client.List(context.Background(), list); err != nil {
+				return nil, err
+			}
+			return list, nil
+		},
+		WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+			w, err := c.client.Watch(context.Background(), newList())
+			if err != nil || !key.unstructured {
+				return w, err
+			}
+			// The fake client watches the typed objects of the scheme, even through unstructured lists
+			return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+				content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(event.Object)
+				if err != nil {
+					return event, false
+				}
+				u := &unstructured.Unstructured{Object: content}
+				u.SetGroupVersionKind(key.gvk)
+				event.Object = u
+				return event, true
+			}), nil
+		},
+	}
+	i := &informer{SharedIndexInformer: toolscache.NewSharedIndexInformer(lw, example, 0,
+		toolscache.Indexers{toolscache.NamespaceIndex: toolscache.MetaNamespaceIndexFunc})}
+	c.informers[key] = i
+	// The informers requested once started are run right away
+	if c.ctx != nil {
+		c.run(i)
+	}
+	return i, nil
+}
+
+// newObjects returns an object of the kind of the informer, along with a function returning a new list of the kind
+func (c *informerCache) newObjects(key informerKey) (runtime.Object, func() client.ObjectList, error) {
+	listGVK := key.gvk.GroupVersion().WithKind(key.gvk.Kind + "List")
+	if key.unstructured {
+		example := &unstructured.Unstructured{}
+		example.SetGroupVersionKind(key.gvk)
+		return example, func() client.ObjectList {
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(listGVK)
+			return list
+		}, nil
+	}
+	example, err := c.scheme.New(key.gvk)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unsupported kind %s: %w", key.gvk, err)
+	}
+	if _, err := c.scheme.New(listGVK); err != nil {
+		return nil, nil, fmt.Errorf("unsupported kind %s: %w", listGVK, err)
+	}
+	return example, func() client.ObjectList {
+		list, _ := c.scheme.New(listGVK)
+		return list.(client.ObjectList)
+	}, nil
+}
+
+func (c *informerCache) run(i *informer) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	i.cancel = cancel
+	go i.Run(ctx.Done())
+}
+
+// RemoveInformer stops the informer of the kind of the object, and removes it from the cache
+func (c *informerCache) RemoveInformer(_ context.Context, obj client.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+	_, isUnstructured := obj.(runtime.Unstructured)
+	key := informerKey{gvk: gvk, unstructured: isUnstructured}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i, ok := c.informers[key]; ok {
+		if i.cancel != nil {
+			i.cancel()
+		}
+		delete(c.informers, key)
+	}
+	return nil
+}
+
+// Start runs the informers, including the ones requested later on, until the context is done
+func (c *informerCache) Start(ctx context.Context) error {
+	c.mu.Lock()
+	c.ctx = ctx
+	for _, i := range c.informers {
+		c.run(i)
+	}
+	c.mu.Unlock()
+
+	<-ctx.Done()
+	return nil
+}
+
+// WaitForCacheSync waits for the informers to sync, returning false if the context is done first
+func (c *informerCache) WaitForCacheSync(ctx context.Context) bool {
+	c.mu.Lock()
+	synced := make([]toolscache.InformerSynced, 0, len(c.informers))
+	for _, i := range c.informers {
+		synced = append(synced, i.HasSynced)
+	}
+	c.mu.Unlock()
+	return toolscache.WaitForCacheSync(ctx.Done(), synced...)
+}
+
+// IndexField isn't supported, since the reads are served by the fake client, whose indexes are set when it's built
+func (c *informerCache) IndexField(context.Context, client.Object, string, client.IndexerFunc) error {
+	return fmt.Errorf("the mock cache doesn't support field indexes")
+}
@@ -0,0 +1,133 @@
+package mock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// LoadFixtures reads the objects of the YAML and JSON files of a directory, in the order of their names. A file may
+// hold several YAML documents, and lists of objects, e.g. the output of kubectl get -o yaml. The objects are decoded
+// into the types of the scheme, so that their kinds must be registered with it, and their fields must be known.
+func LoadFixtures(dir string, scheme *runtime.Scheme) ([]client.Object, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the fixtures directory: %w", err)
+	}
+	var objects []client.Object
+	for _, entry := range entries {
+		if entry.IsDir() || !isFixture(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the fixtures: %w", err)
+		}
+		fileObjects, err := DecodeFixtures(data, scheme)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the fixtures of %s: %w", path, err)
+		}
+		objects = append(objects, fileObjects...)
+	}
+	return objects, nil
+}
+
+// DecodeFixtures decodes the objects of YAML documents, or of JSON objects, into the types of the scheme
+func DecodeFixtures(data []byte, scheme *runtime.Scheme) ([]client.Object, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	var objects []client.Object
+	for {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+		// Empty documents, e.g. after a trailing ---, are skipped
+		if len(bytes.TrimSpace(raw.Raw)) == 0 || bytes.Equal(raw.Raw, []byte("null")) {
+			continue
+		}
+		u := &unstructured.Unstructured{}
+		if err := u.UnmarshalJSON(raw.Raw); err != nil {
+			return nil, err
+		}
+		items := []unstructured.Unstructured{*u}
+		if u.IsList() {
+			list, err := u.ToList()
+			if err != nil {
+				return nil, err
+			}
+			items = list.Items
+		}
+		for i := range items {
+			obj, err := toTyped(&items[i], scheme)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, obj)
+		}
+	}
+}
+
+// EncodeFixtures writes the objects as YAML documents, with their kinds set from the scheme, so that DecodeFixtures
+// decodes them back
+func EncodeFixtures(w io.Writer, scheme *runtime.Scheme, objects ...client.Object) error {
+	for i, obj := range objects {
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			return fmt.Errorf("unsupported kind of %s: %w", obj.GetName(), err)
+		}
+		obj = obj.DeepCopyObject().(client.Object)
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		data, err := sigsyaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s %s: %w", gvk.Kind, obj.GetName(), err)
+		}
+		if i > 0 {
+			data = append([]byte("---\n"), data...)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toTyped converts an object into the type of its kind in the scheme, rejecting the fields unknown to the type so that
+// the typos of the fixtures don't go unnoticed
+func toTyped(u *unstructured.Unstructured, scheme *runtime.Scheme) (client.Object, error) {
+	gvk := u.GroupVersionKind()
+	obj, err := scheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported kind %s of %s: %w", gvk, u.GetName(), err)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructuredWithValidation(u.UnstructuredContent(), obj, true); err != nil {
+		return nil, fmt.Errorf("invalid %s %s: %w", gvk.Kind, u.GetName(), err)
+	}
+	typed, ok := obj.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind %s of %s, which isn't an object", gvk, u.GetName())
+	}
+	return typed, nil
+}
+
+func isFixture(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package mock
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	return scheme
+}
+
+func TestDecodeFixtures(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		expectedNames []string
+		expectedErr   string
+	}{
+		{
+			name: "yaml documents",
+			data: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: default
+spec:
+  replicas: 3
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: foo-abc
+  namespace: default
+---
+`,
+			expectedNames: []string{"foo", "foo-abc"},
+		},
+		{
+			name: "list",
+			data: `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: foo
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: bar
+`,
+			expectedNames: []string{"foo", "bar"},
+		},
+		{
+			name:          "json",
+			data:          `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "foo"}}`,
+			expectedNames: []string{"foo"},
+		},
+		{
+			name:        "unsupported kind",
+			data:        `{"apiVersion": "batch/v1", "kind": "Job", "metadata": {"name": "foo"}}`,
+			expectedErr: "unsupported kind batch/v1, Kind=Job of foo",
+		},
+		{
+			name:        "unknown field",
+			data:        `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "foo"}, "spec": {"replica": 3}}`,
+			expectedErr: `invalid Deployment foo: strict decoding error: unknown field "spec.replica"`,
+		},
+		{
+			name:        "missing kind",
+			data:        `{"apiVersion": "apps/v1", "metadata": {"name": "foo"}}`,
+			expectedErr: "Object 'Kind' is missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := DecodeFixtures([]byte(tt.data), newTestScheme(t))
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("DecodeFixtures() error = %v, want %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeFixtures() error = %v", err)
+			}
+			var names []string
+			for _, obj := range objects {
+				names = append(names, obj.GetName())
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expectedNames, ",") {
+				t.Errorf("DecodeFixtures() = %v, want %v", names, tt.expectedNames)
+			}
+		})
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"1-deployments.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: foo\nspec:\n  replicas: 3\n",
+		"2-pods.json":        `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "foo-abc", "namespace": "default"}}`,
+		"README.md":          "not a fixture",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	objects, err := LoadFixtures(dir, newTestScheme(t))
+	if err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("LoadFixtures() = %d objects, want 2", len(objects))
+	}
+	deployment, ok := objects[0].(*appsv1.Deployment)
+	if !ok || *deployment.Spec.Replicas != 3 {
+		t.Errorf("LoadFixtures()[0] = %#v, want the deployment with 3 replicas", objects[0])
+	}
+	if _, ok := objects[1].(*corev1.Pod); !ok {
+		t.Errorf("LoadFixtures()[1] = %T, want a pod", objects[1])
+	}
+
+	if _, err := LoadFixtures(filepath.Join(dir, "missing"), newTestScheme(t)); err == nil {
+		t.Errorf("LoadFixtures() of a missing directory succeeded, want an error")
+	}
+}
+
+func TestEncodeFixtures(t *testing.T) {
+	scheme := newTestScheme(t)
+	objects := []client.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}, Spec: appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo-abc", Namespace: "default"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+	}
+	var buf bytes.Buffer
+	if err := EncodeFixtures(&buf, scheme, objects...); err != nil {
+		t.Fatalf("EncodeFixtures() error = %v", err)
+	}
+	// The objects themselves are left without their kinds
+	if kind := objects[0].GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		t.Errorf("Kind = %q, want it unset", kind)
+	}
+
+	decoded, err := DecodeFixtures(buf.Bytes(), scheme)
+	if err != nil {
+		t.Fatalf("DecodeFixtures() error = %v\n%s", err, buf.String())
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("DecodeFixtures() = %d objects, want 2", len(decoded))
+	}
+	if deployment, ok := decoded[0].(*appsv1.Deployment); !ok || *deployment.Spec.Replicas != 3 {
+		t.Errorf("DecodeFixtures()[0] = %#v, want the deployment with 3 replicas", decoded[0])
+	}
+	if pod, ok := decoded[1].(*corev1.Pod); !ok || pod.Status.Phase != corev1.PodRunning {
+		t.Errorf("DecodeFixtures()[1] = %#v, want the running pod", decoded[1])
+	}
+
+	if err := EncodeFixtures(&buf, runtime.NewScheme(), objects...); err == nil {
+		t.Errorf("EncodeFixtures() of a kind unknown to the scheme succeeded, want an error")
+	}
+}
@@ -0,0 +1,201 @@
+// Package mock serves the API from an in-memory fake cluster instead of a real one, seeded with fixtures, so that the
+// clients (e.g. frontends) can be developed against the API locally without any access to Kubernetes.
+//
+// The fake cluster backs the manager (its clients, its cache and the informers, whose event handlers see the changes
+// made through the API), the health probes of the API server, and the access reviews, which are all allowed. It has
+// no controllers: the status of the objects is kept as it was loaded, or as it's written through the API, unless the
+// rollouts of the deployments are simulated (see Rollouts).
+package mock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	authorizationfake "k8s.io/client-go/kubernetes/typed/authorization/v1/fake"
+	"k8s.io/client-go/rest"
+	restfake "k8s.io/client-go/rest/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Cluster is an in-memory fake cluster, whose objects are held by a fake client
+type Cluster struct {
+	client client.WithWatch
+	mapper meta.RESTMapper
+	cache  *informerCache
+}
+
+// NewCluster returns a fake cluster holding the given objects, of the kinds of the scheme. The namespaced objects
+// without a namespace are put in the default namespace, like kubectl does, and the objects without a creation
+// timestamp are created now.
+func NewCluster(scheme *runtime.Scheme, objects ...client.Object) (*Cluster, error) {
+	mapper := testrestmapper.TestOnlyStaticRESTMapper(scheme)
+	builder := fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper)
+	now := metav1.Now()
+	for _, obj := range objects {
+		if obj.GetCreationTimestamp().Time.IsZero() {
+			obj.SetCreationTimestamp(now)
+		}
+		if obj.GetNamespace() == "" {
+			namespaced, err := apiutil.IsObjectNamespaced(obj, scheme, mapper)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the scope of %s: %w", obj.GetName(), err)
+			}
+			if namespaced {
+				obj.SetNamespace(corev1.NamespaceDefault)
+			}
+		}
+		builder = builder.WithObjects(obj)
+	}
+	// The pods are listed by phase, e.g. by the scheduling insights, which the API server supports as a field selector
+	if scheme.Recognizes(corev1.SchemeGroupVersion.WithKind("Pod")) {
+		builder = builder.WithIndex(&corev1.Pod{}, "status.phase", func(obj client.Object) []string {
+			return []string{string(obj.(*corev1.Pod).Status.Phase)}
+		})
+	}
+
+	c := &fakeClient{WithWatch: builder.Build(), mapper: mapper}
+	return &Cluster{client: c, mapper: mapper, cache: newInformerCache(c, scheme)}, nil
+}
+
+// fakeClient fails the lists of the unstructured kinds unknown to the scheme, e.g. the HTTPRoutes, like a cluster
+// without their CRD does. The fake client would otherwise register them in the scheme on every list, racing with the
+// concurrent readers of the scheme.
+type fakeClient struct {
+	client.WithWatch
+	mapper meta.RESTMapper
+}
+
+// List implements client.Reader
+func (c *fakeClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if _, ok := list.(runtime.Unstructured); ok {
+		gvk := list.GetObjectKind().GroupVersionKind()
+		if _, err := c.mapper.RESTMapping(gvk.GroupVersion().WithKind(strings.TrimSuffix(gvk.Kind, "List")).GroupKind(), gvk.Version); err != nil {
+			return err
+		}
+	}
+	return c.WithWatch.List(ctx, list, opts...)
+}
+
+// Client returns the client of the cluster
+func (c *Cluster) Client() client.WithWatch {
+	return c.client
+}
+
+// Config returns the configuration of the manager of the cluster. It never reaches a server, since the manager is
+// backed by the fake client.
+func (c *Cluster) Config() *rest.Config {
+	return &rest.Config{Host: "https://mock.invalid"}
+}
+
+// NewCache returns the cache of the cluster, as the NewCache option of the manager
+func (c *Cluster) NewCache(*rest.Config, cache.Options) (cache.Cache, error) {
+	return c.cache, nil
+}
+
+// NewClient returns the client of the cluster, as the NewClient option of the manager
+func (c *Cluster) NewClient(*rest.Config, client.Options) (client.Client, error) {
+	return c.client, nil
+}
+
+// NewRESTMapper returns the REST mapper of the kinds of the scheme of the cluster, as the MapperProvider option of the
+// manager
+func (c *Cluster) NewRESTMapper(*rest.Config, *http.Client) (meta.RESTMapper, error) {
+	return c.mapper, nil
+}
+
+// Manager returns the manager, set up with the options of the cluster, with its API reader and event recorders backed
+// by the cluster too. The events are logged rather than recorded.
+func (c *Cluster) Manager(mgr manager.Manager) manager.Manager {
+	return &clusterManager{Manager: mgr, cluster: c}
+}
+
+type clusterManager struct {
+	manager.Manager
+	cluster *Cluster
+}
+
+func (m *clusterManager) GetAPIReader() client.Reader {
+	return m.cluster.client
+}
+
+func (m *clusterManager) GetEventRecorderFor(name string) record.EventRecorder {
+	return &eventLogger{logger: klog.Background().WithName("events").WithValues("component", name)}
+}
+
+// eventLogger is an event recorder logging the events
+type eventLogger struct {
+	logger klog.Logger
+}
+
+func (e *eventLogger) Event(object runtime.Object, eventType, reason, message string) {
+	kind := object.GetObjectKind().GroupVersionKind().Kind
+	if obj, ok := object.(client.Object); ok {
+		e.logger.Info("Event", "type", eventType, "reason", reason, "message", message, "kind", kind,
+			"object", klog.KObj(obj))
+		return
+	}
+	e.logger.Info("Event", "type", eventType, "reason", reason, "message", message, "kind", kind)
+}
+
+func (e *eventLogger) Eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	e.Event(object, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (e *eventLogger) AnnotatedEventf(object runtime.Object, _ map[string]string, eventType, reason, messageFmt string, args ...interface{}) {
+	e.Eventf(object, eventType, reason, messageFmt, args...)
+}
+
+// HealthzClient returns a client of the API server health endpoints, which are always healthy
+func (c *Cluster) HealthzClient() rest.Interface {
+	return &restfake.RESTClient{
+		NegotiatedSerializer: serializer.NewCodecFactory(runtime.NewScheme()).WithoutConversion(),
+		Client: restfake.CreateHTTPClient(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/plain"}},
+				Body:       io.NopCloser(strings.NewReader("ok")),
+			}, nil
+		}),
+	}
+}
+
+// AuthorizationClient returns a client of the access reviews, which allows every access
+func (c *Cluster) AuthorizationClient() authorizationv1client.AuthorizationV1Interface {
+	fakeClient := &k8stesting.Fake{}
+	fakeClient.AddReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		switch review := action.(k8stesting.CreateAction).GetObject().(type) {
+		case *authorizationv1.SubjectAccessReview:
+			review = review.DeepCopy()
+			review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: true, Reason: "mock"}
+			return true, review, nil
+		case *authorizationv1.SelfSubjectAccessReview:
+			review = review.DeepCopy()
+			review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: true, Reason: "mock"}
+			return true, review, nil
+		case *authorizationv1.LocalSubjectAccessReview:
+			review = review.DeepCopy()
+			review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: true, Reason: "mock"}
+			return true, review, nil
+		}
+		return false, nil, nil
+	})
+	return &authorizationfake.FakeAuthorizationV1{Fake: fakeClient}
+}
@@ -0,0 +1,159 @@
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestNewCluster(t *testing.T) {
+	cluster, err := NewCluster(newTestScheme(t),
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo-abc", Namespace: "apps"}, Status: corev1.PodStatus{Phase: corev1.PodPending}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+	)
+	if err != nil {
+		t.Fatalf("NewCluster() error = %v", err)
+	}
+	ctx := context.Background()
+
+	// The namespaced objects without a namespace are in the default one, created now
+	var deployment appsv1.Deployment
+	if err := cluster.Client().Get(ctx, client.ObjectKey{Namespace: "default", Name: "foo"}, &deployment); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+	if time.Since(deployment.CreationTimestamp.Time) > time.Minute {
+		t.Errorf("CreationTimestamp = %v, want now", deployment.CreationTimestamp)
+	}
+	if err := cluster.Client().Get(ctx, client.ObjectKey{Name: "apps"}, &corev1.Namespace{}); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+	// The pods are listed by phase
+	var pods corev1.PodList
+	if err := cluster.Client().List(ctx, &pods, client.MatchingFields{"status.phase": string(corev1.PodPending)}); err != nil || len(pods.Items) != 1 {
+		t.Errorf("List() = %d pods, error = %v, want the pending pod", len(pods.Items), err)
+	}
+
+	// The kinds unknown to the scheme aren't served, as without their CRD
+	routes := &unstructured.UnstructuredList{}
+	routes.SetGroupVersionKind(schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRouteList"})
+	if err := cluster.Client().List(ctx, routes); !meta.IsNoMatchError(err) {
+		t.Errorf("List() error = %v, want a no match error", err)
+	}
+	deployments := &unstructured.UnstructuredList{}
+	deployments.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("DeploymentList"))
+	if err := cluster.Client().List(ctx, deployments); err != nil || len(deployments.Items) != 1 {
+		t.Errorf("List() = %d deployments, error = %v, want the deployment", len(deployments.Items), err)
+	}
+
+	// The API server is healthy, and every access is allowed
+	if body, err := cluster.HealthzClient().Get().AbsPath("/healthz").Do(ctx).Raw(); err != nil || string(body) != "ok" {
+		t.Errorf("healthz = %s, error = %v, want ok", body, err)
+	}
+	review, err := cluster.AuthorizationClient().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{}, metav1.CreateOptions{})
+	if err != nil || !review.Status.Allowed {
+		t.Errorf("SubjectAccessReviews().Create() = %+v, error = %v, want allowed", review, err)
+	}
+	selfReview, err := cluster.AuthorizationClient().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{}, metav1.CreateOptions{})
+	if err != nil || !selfReview.Status.Allowed {
+		t.Errorf("SelfSubjectAccessReviews().Create() = %+v, error = %v, want allowed", selfReview, err)
+	}
+}
+
+func TestInformerCache(t *testing.T) {
+	cluster, err := NewCluster(newTestScheme(t), &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(1))},
+	})
+	if err != nil {
+		t.Fatalf("NewCluster() error = %v", err)
+	}
+	c, err := cluster.NewCache(nil, cache.Options{})
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	informer, err := c.GetInformer(ctx, &appsv1.Deployment{})
+	if err != nil {
+		t.Fatalf("GetInformer() error = %v", err)
+	}
+	events := make(chan string, 100)
+	_, err = informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			events <- "add " + obj.(*appsv1.Deployment).Name
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			events <- "update " + obj.(*appsv1.Deployment).Name
+		},
+	})
+	if err != nil {
+		t.Fatalf("AddEventHandler() error = %v", err)
+	}
+	go func() {
+		_ = c.Start(ctx)
+	}()
+	syncCtx, syncCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer syncCancel()
+	if !c.WaitForCacheSync(syncCtx) {
+		t.Fatalf("WaitForCacheSync() = false, want the informers synced")
+	}
+
+	// The changes made through the client reach the informers, including the ones requested once started
+	unstructuredInformer, err := c.GetInformer(ctx, &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment"}})
+	if err != nil {
+		t.Fatalf("GetInformer() error = %v", err)
+	}
+	_, err = unstructuredInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, obj interface{}) {
+			events <- "unstructured update " + obj.(*unstructured.Unstructured).GetName()
+		},
+	})
+	if err != nil {
+		t.Fatalf("AddEventHandler() error = %v", err)
+	}
+	for !unstructuredInformer.HasSynced() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The deployment is updated until the updates are received, since the informers only watch once they've listed
+	update := func() {
+		var deployment appsv1.Deployment
+		if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "foo"}, &deployment); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		deployment.Spec.Replicas = ptr.To(*deployment.Spec.Replicas + 1)
+		if err := cluster.Client().Update(ctx, &deployment); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	update()
+	expected := map[string]bool{"add foo": true, "update foo": true, "unstructured update foo": true}
+	timeout := time.After(10 * time.Second)
+	for len(expected) > 0 {
+		select {
+		case event := <-events:
+			delete(expected, event)
+		case <-time.After(100 * time.Millisecond):
+			update()
+		case <-timeout:
+			t.Fatalf("events %v weren't received", expected)
+		}
+	}
+
+	if err := c.IndexField(ctx, &appsv1.Deployment{}, "spec.replicas", nil); err == nil {
+		t.Errorf("IndexField() succeeded, want an error")
+	}
+}
@@ -0,0 +1,290 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Rollouts stands in for the deployment controller the fake cluster lacks: a step at a time, it creates or deletes a
+// pod of each deployment whose pods don't match its replicas or its pod template, and updates its status accordingly,
+// so that the changes made through the API roll out over time like they do in a cluster. The paused deployments, and
+// the ones whose rollout exceeded its progress deadline, are left as they are.
+type Rollouts struct {
+	client client.Client
+	// clock returns the current time, time.Now if nil
+	clock func() time.Time
+}
+
+// Rollouts returns the rollouts of the deployments of the cluster
+func (c *Cluster) Rollouts() *Rollouts {
+	return &Rollouts{client: c.client}
+}
+
+// Run steps the rollouts at the given interval until the context is done
+func (r *Rollouts) Run(ctx context.Context, interval time.Duration) {
+	logger := klog.FromContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.Step(ctx); err != nil {
+			logger.Error(err, "Error stepping the rollouts of the fake cluster")
+		}
+	}
+}
+
+// Step moves every deployment a pod closer to its replicas of its current pod template
+func (r *Rollouts) Step(ctx context.Context) error {
+	var deployments appsv1.DeploymentList
+	if err := r.client.List(ctx, &deployments); err != nil {
+		return fmt.Errorf("failed to list the deployments: %w", err)
+	}
+	var errs []error
+	for i := range deployments.Items {
+		if err := r.step(ctx, &deployments.Items[i]); err != nil {
+			errs = append(errs, fmt.Errorf("failed to step the rollout of %s/%s: %w", deployments.Items[i].Namespace, deployments.Items[i].Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Rollouts) step(ctx context.Context, d *appsv1.Deployment) error {
+	if d.Spec.Paused || deadlineExceeded(d) {
+		return nil
+	}
+	pods, err := r.pods(ctx, d)
+	if err != nil {
+		return err
+	}
+	rsName := replicaSetName(d)
+	desired := int(ptr.Deref(d.Spec.Replicas, 1))
+	switch {
+	// The outdated pods are replaced by surging a new pod first, so that the availability doesn't drop meanwhile
+	case len(pods) < desired, len(pods) == desired && outdatedPod(pods, rsName) >= 0:
+		pod := NewPod(d, r.now())
+		if err := r.client.Create(ctx, pod); err != nil {
+			return fmt.Errorf("failed to create a pod: %w", err)
+		}
+		pods = append(pods, *pod)
+	case len(pods) > desired:
+		victim := victimPod(pods, rsName)
+		if err := r.client.Delete(ctx, &pods[victim]); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete pod %s: %w", pods[victim].Name, err)
+		}
+		pods = append(pods[:victim], pods[victim+1:]...)
+	}
+
+	status := rolloutStatus(d, pods, rsName, r.now())
+	if equality.Semantic.DeepEqual(status, d.Status) {
+		return nil
+	}
+	d.Status = status
+	return r.client.Status().Update(ctx, d)
+}
+
+// pods returns the pods of the deployment which aren't being deleted, oldest first
+func (r *Rollouts) pods(ctx context.Context, d *appsv1.Deployment) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	var list corev1.PodList
+	if err := r.client.List(ctx, &list, client.InNamespace(d.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list the pods: %w", err)
+	}
+	pods := make([]corev1.Pod, 0, len(list.Items))
+	for _, pod := range list.Items {
+		owner := metav1.GetControllerOf(&pod)
+		if pod.DeletionTimestamp == nil && owner != nil && owner.Kind == "ReplicaSet" && strings.HasPrefix(owner.Name, d.Name+"-") {
+			pods = append(pods, pod)
+		}
+	}
+	// The fake client lists by name, which the random suffixes don't order by age
+	sort.SliceStable(pods, func(i, j int) bool {
+		return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
+	})
+	return pods, nil
+}
+
+// victimPod returns the index of the pod to delete when scaling down: the pods that aren't ready go first, then the
+// outdated ones, then the newest ones, like the ReplicaSet controllers do
+func victimPod(pods []corev1.Pod, rsName string) int {
+	for i := range pods {
+		if !podReady(&pods[i]) {
+			return i
+		}
+	}
+	if i := outdatedPod(pods, rsName); i >= 0 {
+		return i
+	}
+	return len(pods) - 1
+}
+
+// outdatedPod returns the index of the oldest pod of a previous ReplicaSet, or -1 if they are all up to date
+func outdatedPod(pods []corev1.Pod, rsName string) int {
+	for i := range pods {
+		if metav1.GetControllerOf(&pods[i]).Name != rsName {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Rollouts) now() time.Time {
+	if r.clock != nil {
+		return r.clock()
+	}
+	return time.Now()
+}
+
+// rolloutStatus returns the status of the deployment given its pods, the updated ones being the pods of its current
+// ReplicaSet
+func rolloutStatus(d *appsv1.Deployment, pods []corev1.Pod, rsName string, now time.Time) appsv1.DeploymentStatus {
+	desired := ptr.Deref(d.Spec.Replicas, 1)
+	status := *d.Status.DeepCopy()
+	status.ObservedGeneration = d.Generation
+	status.Replicas = int32(len(pods))
+	status.ReadyReplicas, status.UpdatedReplicas = 0, 0
+	for i := range pods {
+		if podReady(&pods[i]) {
+			status.ReadyReplicas++
+		}
+		if metav1.GetControllerOf(&pods[i]).Name == rsName {
+			status.UpdatedReplicas++
+		}
+	}
+	status.AvailableReplicas = status.ReadyReplicas
+	status.UnavailableReplicas = max(desired-status.AvailableReplicas, 0)
+
+	if status.AvailableReplicas >= desired {
+		setCondition(&status, appsv1.DeploymentAvailable, corev1.ConditionTrue, "MinimumReplicasAvailable", "Deployment has minimum availability.", now)
+	} else {
+		setCondition(&status, appsv1.DeploymentAvailable, corev1.ConditionFalse, "MinimumReplicasUnavailable", "Deployment does not have minimum availability.", now)
+	}
+	if status.UpdatedReplicas == desired && status.Replicas == desired && status.AvailableReplicas == desired {
+		setCondition(&status, appsv1.DeploymentProgressing, corev1.ConditionTrue, "NewReplicaSetAvailable", fmt.Sprintf("ReplicaSet %q has successfully progressed.", rsName), now)
+	} else {
+		setCondition(&status, appsv1.DeploymentProgressing, corev1.ConditionTrue, "ReplicaSetUpdated", fmt.Sprintf("ReplicaSet %q is progressing.", rsName), now)
+	}
+	return status
+}
+
+// setCondition sets a condition of the deployment status, updated now if it changed
+func setCondition(status *appsv1.DeploymentStatus, conditionType appsv1.DeploymentConditionType, conditionStatus corev1.ConditionStatus, reason, message string, now time.Time) {
+	condition := appsv1.DeploymentCondition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		Reason:             reason,
+		Message:            message,
+		LastUpdateTime:     metav1.NewTime(now),
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	for i, existing := range status.Conditions {
+		if existing.Type != conditionType {
+			continue
+		}
+		if existing.Status == conditionStatus && existing.Reason == reason && existing.Message == message {
+			return
+		}
+		if existing.Status == conditionStatus {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		status.Conditions[i] = condition
+		return
+	}
+	status.Conditions = append(status.Conditions, condition)
+}
+
+// deadlineExceeded returns whether the deployment controller would have given up on the rollout of the deployment
+func deadlineExceeded(d *appsv1.Deployment) bool {
+	for _, condition := range d.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing {
+			return condition.Status == corev1.ConditionFalse && condition.Reason == "ProgressDeadlineExceeded"
+		}
+	}
+	return false
+}
+
+// NewPod returns a new pod of the current ReplicaSet of the deployment, scheduled and ready since the given time, as
+// the deployment controller and the kubelet would eventually make it
+func NewPod(d *appsv1.Deployment, now time.Time) *corev1.Pod {
+	rsName := replicaSetName(d)
+	labels := map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: strings.TrimPrefix(rsName, d.Name+"-")}
+	for key, value := range d.Spec.Template.Labels {
+		labels[key] = value
+	}
+	since := metav1.NewTime(now)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              rsName + "-" + rand.String(5),
+			Namespace:         d.Namespace,
+			Labels:            labels,
+			Annotations:       d.Spec.Template.Annotations,
+			CreationTimestamp: since,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       "ReplicaSet",
+				Name:       rsName,
+				Controller: ptr.To(true),
+			}},
+		},
+		Spec: *d.Spec.Template.Spec.DeepCopy(),
+		Status: corev1.PodStatus{
+			Phase:     corev1.PodRunning,
+			StartTime: &since,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: since},
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: since},
+			},
+		},
+	}
+	pod.Spec.NodeName = "mock-node"
+	for _, container := range pod.Spec.Containers {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name:    container.Name,
+			Image:   container.Image,
+			Ready:   true,
+			Started: ptr.To(true),
+			State:   corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: since}},
+		})
+	}
+	return pod
+}
+
+// replicaSetName returns the name of the ReplicaSet of the current pod template of the deployment, suffixed with a hash
+// of the template like the deployment controller does
+func replicaSetName(d *appsv1.Deployment) string {
+	template, _ := json.Marshal(d.Spec.Template)
+	hasher := fnv.New32a()
+	_, _ = hasher.Write(template)
+	return d.Name + "-" + rand.SafeEncodeString(strconv.FormatUint(uint64(hasher.Sum32()), 10))
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
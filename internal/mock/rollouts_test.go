@@ -0,0 +1,208 @@
+package mock
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestDeployment(replicas int32, image string) *appsv1.Deployment {
+	labels := map[string]string{"app": "foo"}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(replicas),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "foo", Image: image}}},
+			},
+		},
+	}
+}
+
+func TestRollouts_Step(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pendingPod := func(d *appsv1.Deployment) *corev1.Pod {
+		pod := NewPod(d, now.Add(-time.Hour))
+		pod.Status = corev1.PodStatus{Phase: corev1.PodPending}
+		return pod
+	}
+	tests := []struct {
+		name       string
+		deployment func() *appsv1.Deployment
+		pods       func(d *appsv1.Deployment) []client.Object
+		steps      int
+		// expectedPods are the images of the pods left, with the pending ones suffixed with " pending"
+		expectedPods   []string
+		expectedStatus appsv1.DeploymentStatus
+	}{
+		{
+			name:           "scale up a pod at a time",
+			deployment:     func() *appsv1.Deployment { return newTestDeployment(3, "foo:1") },
+			steps:          2,
+			expectedPods:   []string{"foo:1", "foo:1"},
+			expectedStatus: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2, AvailableReplicas: 2, UnavailableReplicas: 1},
+		},
+		{
+			name:       "scale down the pods that aren't ready first",
+			deployment: func() *appsv1.Deployment { return newTestDeployment(1, "foo:1") },
+			pods: func(d *appsv1.Deployment) []client.Object {
+				return []client.Object{NewPod(d, now.Add(-2*time.Hour)), pendingPod(d), NewPod(d, now.Add(-time.Minute))}
+			},
+			steps:          2,
+			expectedPods:   []string{"foo:1"},
+			expectedStatus: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, ReadyReplicas: 1, AvailableReplicas: 1},
+		},
+		{
+			name:       "replace the outdated pods by surging",
+			deployment: func() *appsv1.Deployment { return newTestDeployment(2, "foo:2") },
+			pods: func(d *appsv1.Deployment) []client.Object {
+				previous := newTestDeployment(2, "foo:1")
+				return []client.Object{NewPod(previous, now.Add(-time.Hour)), NewPod(previous, now.Add(-time.Hour))}
+			},
+			steps:          4,
+			expectedPods:   []string{"foo:2", "foo:2"},
+			expectedStatus: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2, AvailableReplicas: 2},
+		},
+		{
+			name:           "complete the rollout",
+			deployment:     func() *appsv1.Deployment { return newTestDeployment(2, "foo:1") },
+			steps:          5,
+			expectedPods:   []string{"foo:1", "foo:1"},
+			expectedStatus: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2, AvailableReplicas: 2},
+		},
+		{
+			name:       "keep the pending pods of the current template",
+			deployment: func() *appsv1.Deployment { return newTestDeployment(2, "foo:1") },
+			pods: func(d *appsv1.Deployment) []client.Object {
+				return []client.Object{NewPod(d, now.Add(-time.Hour)), pendingPod(d)}
+			},
+			steps:          2,
+			expectedPods:   []string{"foo:1", "foo:1 pending"},
+			expectedStatus: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 1, AvailableReplicas: 1, UnavailableReplicas: 1},
+		},
+		{
+			name: "leave the paused deployments as they are",
+			deployment: func() *appsv1.Deployment {
+				d := newTestDeployment(2, "foo:1")
+				d.Spec.Paused = true
+				return d
+			},
+			steps: 2,
+		},
+		{
+			name: "leave the deployments past their progress deadline as they are",
+			deployment: func() *appsv1.Deployment {
+				d := newTestDeployment(2, "foo:1")
+				d.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"}}
+				return d
+			},
+			steps:          2,
+			expectedStatus: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.deployment()
+			objects := []client.Object{d}
+			if tt.pods != nil {
+				objects = append(objects, tt.pods(d)...)
+			}
+			cluster, err := NewCluster(newTestScheme(t), objects...)
+			if err != nil {
+				t.Fatalf("NewCluster() error = %v", err)
+			}
+			ctx := context.Background()
+			clock := now
+			rollouts := cluster.Rollouts()
+			rollouts.clock = func() time.Time {
+				clock = clock.Add(time.Second)
+				return clock
+			}
+			for i := 0; i < tt.steps; i++ {
+				if err := rollouts.Step(ctx); err != nil {
+					t.Fatalf("Step() error = %v", err)
+				}
+			}
+
+			var pods corev1.PodList
+			if err := cluster.Client().List(ctx, &pods); err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var images []string
+			for _, pod := range pods.Items {
+				image := pod.Spec.Containers[0].Image
+				if pod.Status.Phase == corev1.PodPending {
+					image += " pending"
+				}
+				images = append(images, image)
+			}
+			sort.Strings(images)
+			if strings.Join(images, ",") != strings.Join(tt.expectedPods, ",") {
+				t.Errorf("pods = %v, want %v", images, tt.expectedPods)
+			}
+
+			var updated appsv1.Deployment
+			if err := cluster.Client().Get(ctx, client.ObjectKeyFromObject(d), &updated); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			status := updated.Status
+			// The conditions of the deployments stepped are checked on their own
+			if tt.expectedStatus.Conditions == nil {
+				status.Conditions = nil
+			}
+			if status.Replicas != tt.expectedStatus.Replicas || status.UpdatedReplicas != tt.expectedStatus.UpdatedReplicas ||
+				status.ReadyReplicas != tt.expectedStatus.ReadyReplicas || status.AvailableReplicas != tt.expectedStatus.AvailableReplicas ||
+				status.UnavailableReplicas != tt.expectedStatus.UnavailableReplicas || len(status.Conditions) != len(tt.expectedStatus.Conditions) {
+				t.Errorf("status = %+v, want %+v", status, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestRolloutStatus_Conditions(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	d := newTestDeployment(2, "foo:1")
+	pods := []corev1.Pod{*NewPod(d, now)}
+
+	// A rollout in progress isn't available yet
+	status := rolloutStatus(d, pods, replicaSetName(d), now)
+	progressing := conditionOf(status, appsv1.DeploymentProgressing)
+	if available := conditionOf(status, appsv1.DeploymentAvailable); available.Status != corev1.ConditionFalse {
+		t.Errorf("Available = %+v, want false", available)
+	}
+	if progressing.Reason != "ReplicaSetUpdated" {
+		t.Errorf("Progressing = %+v, want ReplicaSetUpdated", progressing)
+	}
+
+	// Once complete, the conditions are updated, but their transition times are kept while their status doesn't change
+	d.Status = status
+	pods = append(pods, *NewPod(d, now))
+	later := now.Add(time.Minute)
+	status = rolloutStatus(d, pods, replicaSetName(d), later)
+	if available := conditionOf(status, appsv1.DeploymentAvailable); available.Status != corev1.ConditionTrue || !available.LastTransitionTime.Time.Equal(later) {
+		t.Errorf("Available = %+v, want true since %v", available, later)
+	}
+	completed := conditionOf(status, appsv1.DeploymentProgressing)
+	if completed.Reason != "NewReplicaSetAvailable" || !completed.LastUpdateTime.Time.Equal(later) || !completed.LastTransitionTime.Time.Equal(now) {
+		t.Errorf("Progressing = %+v, want NewReplicaSetAvailable updated at %v and transitioned at %v", completed, later, now)
+	}
+}
+
+func conditionOf(status appsv1.DeploymentStatus, conditionType appsv1.DeploymentConditionType) appsv1.DeploymentCondition {
+	for _, condition := range status.Conditions {
+		if condition.Type == conditionType {
+			return condition
+		}
+	}
+	return appsv1.DeploymentCondition{}
+}
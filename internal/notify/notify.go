@@ -0,0 +1,249 @@
+// Package notify posts chat notifications (to Slack or Microsoft Teams incoming webhooks) summarizing the changes made
+// through the API, e.g. "ci-bot scaled Deployment team-a/web from 3 to 10", to the channel of the changed namespace.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Types of the webhooks the notifications are posted to
+const (
+	TypeSlack = "slack"
+	TypeTeams = "teams"
+)
+
+// DefaultTemplate is the template of the notifications, unless configured otherwise
+const DefaultTemplate = "{{.Identity}} {{.Action}} {{.Kind}} {{if .Namespace}}{{.Namespace}}/{{end}}{{.Name}}" +
+	"{{if .From}} from {{.From}}{{end}}{{if .To}} to {{.To}}{{end}}"
+
+// queueSize is how many notifications may wait to be posted before new ones are dropped
+const queueSize = 100
+
+// Change is a change made through the API, as passed to the templates of the notifications
+type Change struct {
+	Time time.Time
+	// Identity is the identity of the client which made the change
+	Identity string
+	// Operation is the name of the handler which made the change, e.g. SetDeploymentReplicas
+	Operation string
+	// Action describes the change, e.g. "scaled" or "set the replica bounds of"
+	Action    string
+	Kind      string
+	Namespace string
+	Name      string
+	// From and To are the values before and after the change, e.g. the replicas, if any
+	From string
+	To   string
+}
+
+// RouteConfig is a webhook the changes of the matching namespaces are posted to
+type RouteConfig struct {
+	// Type is the type of the webhook, slack or teams
+	Type string `json:"type"`
+	URL  string `json:"url"`
+	// Namespaces are the patterns of the namespaces of the route, in the path.Match syntax. Matches all namespaces, and
+	// the cluster scoped objects, when empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Operations are the operations notified to the route. All of them are notified when empty.
+	Operations []string `json:"operations,omitempty"`
+	// Template is the text/template of the notifications of the route, executed with the Change. Defaults to the
+	// template of the configuration.
+	Template string `json:"template,omitempty"`
+}
+
+// Config is the notifications configuration file
+type Config struct {
+	// Template is the text/template of the notifications, executed with the Change. Defaults to DefaultTemplate.
+	Template string `json:"template,omitempty"`
+	// Routes are evaluated in order, and each change is posted to the first route matching it
+	Routes []RouteConfig `json:"routes"`
+}
+
+// route is a configured route
+type route struct {
+	RouteConfig
+	template *template.Template
+}
+
+// matches returns whether the change is notified to the route
+func (r *route) matches(change Change) bool {
+	if len(r.Operations) > 0 && !slices.Contains(r.Operations, change.Operation) {
+		return false
+	}
+	if len(r.Namespaces) == 0 {
+		return true
+	}
+	for _, pattern := range r.Namespaces {
+		if matched, _ := path.Match(pattern, change.Namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// notification is a message waiting to be posted to a route
+type notification struct {
+	route   *route
+	message string
+}
+
+// Notifier posts the notifications of the changes to the webhooks of their route, in the background
+type Notifier struct {
+	routes []*route
+	client *http.Client
+	queue  chan notification
+}
+
+// New returns a new Notifier from the given configuration
+func New(config Config) (*Notifier, error) {
+	defaultTemplate, err := parseTemplate(config.Template, DefaultTemplate)
+	if err != nil {
+		return nil, err
+	}
+	n := &Notifier{client: &http.Client{Timeout: 10 * time.Second}, queue: make(chan notification, queueSize)}
+	for i, rc := range config.Routes {
+		if rc.Type != TypeSlack && rc.Type != TypeTeams {
+			return nil, fmt.Errorf("invalid type %q of route %d, must be %s or %s", rc.Type, i, TypeSlack, TypeTeams)
+		}
+		if rc.URL == "" {
+			return nil, fmt.Errorf("the url of route %d is required", i)
+		}
+		for _, pattern := range rc.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid namespace pattern %q of route %d: %w", pattern, i, err)
+			}
+		}
+		r := &route{RouteConfig: rc, template: defaultTemplate}
+		if rc.Template != "" {
+			if r.template, err = parseTemplate(rc.Template, ""); err != nil {
+				return nil, fmt.Errorf("invalid template of route %d: %w", i, err)
+			}
+		}
+		n.routes = append(n.routes, r)
+	}
+	return n, nil
+}
+
+func parseTemplate(text, defaultText string) (*template.Template, error) {
+	if text == "" {
+		text = defaultText
+	}
+	t, err := template.New("notification").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	// Templates referring to unknown fields are rejected right away rather than on the first change
+	if err := t.Execute(&bytes.Buffer{}, Change{}); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return t, nil
+}
+
+// LoadFile returns a new Notifier from the given YAML or JSON configuration file
+func LoadFile(path string) (*Notifier, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notifications file: %w", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse notifications file %s: %w", path, err)
+	}
+	return New(config)
+}
+
+// Notify queues the notification of the change to the first route matching it, without waiting for it to be posted.
+// Notifications are dropped when the queue is full, so that a slow webhook never delays the API. A nil Notifier
+// doesn't notify anything.
+func (n *Notifier) Notify(ctx context.Context, change Change) {
+	if n == nil {
+		return
+	}
+	if change.Time.IsZero() {
+		change.Time = time.Now()
+	}
+	// Requests without a client certificate, e.g. over the Unix socket, are anonymous
+	if change.Identity == "" {
+		change.Identity = "anonymous"
+	}
+	logger := klog.FromContext(ctx)
+	for _, r := range n.routes {
+		if !r.matches(change) {
+			continue
+		}
+		var message strings.Builder
+		if err := r.template.Execute(&message, change); err != nil {
+			logger.Error(err, "Error rendering notification")
+			return
+		}
+		select {
+		case n.queue <- notification{route: r, message: message.String()}:
+		default:
+			logger.Info("Notification dropped, too many notifications are waiting to be posted", "message", message.String())
+		}
+		return
+	}
+}
+
+// Run posts the queued notifications until the context is cancelled
+func (n *Notifier) Run(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notif := <-n.queue:
+			if err := n.post(ctx, notif); err != nil {
+				logger.Error(err, "Error posting notification", "type", notif.route.Type)
+			}
+		}
+	}
+}
+
+// post posts the notification to the webhook of its route, failing on any non 2xx response
+func (n *Notifier) post(ctx context.Context, notif notification) error {
+	var payload any
+	switch notif.route.Type {
+	case TypeSlack:
+		payload = map[string]string{"text": notif.message}
+	case TypeTeams:
+		// Teams connectors expect a legacy actionable message card
+		payload = map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  notif.message,
+			"text":     notif.message,
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notif.route.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
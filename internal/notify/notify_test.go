@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"Test Invalid Type", Config{Routes: []RouteConfig{{Type: "discord", URL: "http://chat"}}}},
+		{"Test Missing URL", Config{Routes: []RouteConfig{{Type: TypeSlack}}}},
+		{"Test Invalid Namespace Pattern", Config{Routes: []RouteConfig{{Type: TypeSlack, URL: "http://chat", Namespaces: []string{"["}}}}},
+		{"Test Invalid Template", Config{Template: "{{.Identity"}},
+		{"Test Unknown Template Field", Config{Template: "{{.User}} changed {{.Name}}"}},
+		{"Test Invalid Route Template", Config{Routes: []RouteConfig{{Type: TypeSlack, URL: "http://chat", Template: "{{.Replicas}}"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Errorf("New() expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.yaml")
+	config := "routes:\n  - type: slack\n    url: https://hooks.slack.com/services/T/B/X\n    namespaces: [\"team-a*\"]\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	n, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if len(n.routes) != 1 || n.routes[0].Type != TypeSlack {
+		t.Errorf("LoadFile() = %+v", n)
+	}
+
+	if err := os.WriteFile(path, []byte("routes:\n  - type: slack\n    webhook: https://hooks.slack.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Errorf("LoadFile() expected an error for an unknown field")
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	received := make(chan map[string]any, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed to decode notification: %v", err)
+		}
+		payload["path"] = r.URL.Path
+		received <- payload
+	}))
+	defer server.Close()
+
+	n, err := New(Config{Routes: []RouteConfig{
+		{Type: TypeSlack, URL: server.URL + "/apply", Operations: []string{"ApplyManifests"}, Template: "{{.Identity}} {{.Action}} {{.Name}}"},
+		{Type: TypeSlack, URL: server.URL + "/team-a", Namespaces: []string{"team-a*"}},
+		{Type: TypeTeams, URL: server.URL + "/default"},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go n.Run(ctx)
+
+	tests := []struct {
+		name     string
+		change   Change
+		path     string
+		field    string
+		expected string
+	}{
+		{
+			"Test Slack",
+			Change{Identity: "ci-bot", Operation: "SetDeploymentReplicas", Action: "scaled", Kind: "Deployment", Namespace: "team-a-prod", Name: "web", From: "3", To: "10"},
+			"/team-a", "text", "ci-bot scaled Deployment team-a-prod/web from 3 to 10",
+		},
+		{
+			"Test Teams",
+			Change{Identity: "ci-bot", Operation: "DeleteDeploymentBounds", Action: "removed the replica bounds of", Kind: "Deployment", Namespace: "team-b", Name: "web", From: "[2, 5]"},
+			"/default", "text", "ci-bot removed the replica bounds of Deployment team-b/web from [2, 5]",
+		},
+		{
+			"Test Operation Route",
+			Change{Identity: "ci-bot", Operation: "ApplyManifests", Action: "created", Kind: "Namespace", Name: "team-c"},
+			"/apply", "text", "ci-bot created team-c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n.Notify(context.Background(), tt.change)
+			select {
+			case payload := <-received:
+				if payload["path"] != tt.path || payload[tt.field] != tt.expected {
+					t.Errorf("Notify() posted %v, want %q to %s", payload, tt.expected, tt.path)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Notify() posted nothing")
+			}
+		})
+	}
+
+	var nilNotifier *Notifier
+	nilNotifier.Notify(context.Background(), Change{})
+}
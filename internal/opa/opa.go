@@ -0,0 +1,200 @@
+// Package opa evaluates the mutating requests against the decisions of an Open Policy Agent (OPA) server, typically
+// running as a sidecar, before they are served. This lets platform teams write their guardrails in Rego, and change
+// them without a release of the API.
+package opa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+)
+
+// Input is the input document of the policy decisions
+type Input struct {
+	Method     string              `json:"method"`
+	Path       string              `json:"path"`
+	Query      map[string][]string `json:"query,omitempty"`
+	Identity   string              `json:"identity"`
+	Namespace  string              `json:"namespace,omitempty"`
+	Deployment string              `json:"deployment,omitempty"`
+	// Body is the parsed request body, when it's a JSON or YAML document, or the list of its documents for the
+	// multi-document streams sent to /apply
+	Body any `json:"body,omitempty"`
+}
+
+// Decision is the decision of the policy on a request
+type Decision struct {
+	Allow bool `json:"allow"`
+	// Violations are the messages explaining why the request isn't allowed
+	Violations []string `json:"violations,omitempty"`
+}
+
+// UnmarshalJSON decodes either a boolean decision, or an object holding allow and violations. Objects without allow
+// (e.g. from a policy only made of deny rules) allow the requests without violations.
+func (d *Decision) UnmarshalJSON(data []byte) error {
+	var allow bool
+	if err := json.Unmarshal(data, &allow); err == nil {
+		*d = Decision{Allow: allow}
+		return nil
+	}
+	var decision struct {
+		Allow      *bool    `json:"allow"`
+		Violations []string `json:"violations"`
+	}
+	if err := json.Unmarshal(data, &decision); err != nil {
+		return fmt.Errorf("decision must be either a boolean or an object holding allow and violations: %w", err)
+	}
+	d.Violations = decision.Violations
+	d.Allow = len(decision.Violations) == 0
+	if decision.Allow != nil {
+		d.Allow = *decision.Allow
+	}
+	return nil
+}
+
+// Client evaluates the requests against the decision of the OPA Data API at its URL
+type Client struct {
+	// URL is the URL of the decision, e.g. http://localhost:8181/v1/data/k8sapi/decision
+	URL    string
+	Client *http.Client
+	// FailOpen allows the requests when the decision can't be evaluated, instead of rejecting them
+	FailOpen bool
+}
+
+// Evaluate returns the decision of the policy on the input
+func (c *Client) Evaluate(ctx context.Context, input *Input) (*Decision, error) {
+	body, err := json.Marshal(struct {
+		Input *Input `json:"input"`
+	}{input})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from the policy server", resp.StatusCode)
+	}
+	var result struct {
+		Result *Decision `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode the policy decision: %w", err)
+	}
+	if result.Result == nil {
+		return nil, fmt.Errorf("the policy decision at %s is undefined", c.URL)
+	}
+	return result.Result, nil
+}
+
+// DeniedError is the response object of the requests denied by the policy
+type DeniedError struct {
+	problem.Details
+	Message    string   `json:"message"`
+	Violations []string `json:"violations,omitempty"`
+}
+
+// Middleware returns a new http.HandlerFunc which evaluates the mutating requests against the policy before passing
+// them to the provided handler. Denied requests get a 403 Forbidden with the violations, and requests whose decision
+// can't be evaluated get a 503 Service Unavailable, unless the client fails open. Safe requests (GET, HEAD and
+// OPTIONS) aren't evaluated.
+func (c *Client) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		logger := klog.FromContext(r.Context())
+
+		input := &Input{
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Identity:   auth.Identity(r),
+			Namespace:  r.PathValue("namespace"),
+			Deployment: r.PathValue("deployment"),
+		}
+		if query := r.URL.Query(); len(query) > 0 {
+			input.Query = query
+		}
+		if r.Body != nil {
+			raw, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(w, logger, http.StatusBadRequest, DeniedError{Message: fmt.Sprintf("Error reading request body: %v", err)})
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(raw))
+			input.Body = parseBody(raw)
+		}
+
+		decision, err := c.Evaluate(r.Context(), input)
+		switch {
+		case err != nil && c.FailOpen:
+			logger.Error(err, "Error evaluating the policy decision, allowing the request")
+		case err != nil:
+			logger.Error(err, "Error evaluating the policy decision")
+			writeError(w, logger, http.StatusServiceUnavailable, DeniedError{Message: "Policy decision unavailable"})
+			return
+		case !decision.Allow:
+			logger.Info("Request denied by policy", "identity", input.Identity, "violations", decision.Violations)
+			message := "Denied by policy"
+			if len(decision.Violations) > 0 {
+				message += ": " + strings.Join(decision.Violations, "; ")
+			}
+			writeError(w, logger, http.StatusForbidden, DeniedError{Message: message, Violations: decision.Violations})
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// parseBody returns the JSON or YAML document of the body, the list of its documents if there are several, or nil if
+// it can't be parsed
+func parseBody(raw []byte) any {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(raw), 4096)
+	var documents []any
+	for {
+		var document any
+		if err := decoder.Decode(&document); err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil
+			}
+			break
+		}
+		if document != nil {
+			documents = append(documents, document)
+		}
+	}
+	switch len(documents) {
+	case 0:
+		return nil
+	case 1:
+		return documents[0]
+	}
+	return documents
+}
+
+func writeError(w http.ResponseWriter, logger klog.Logger, status int, resp DeniedError) {
+	resp.Details = problem.New(w, status, resp.Message)
+	w.WriteHeader(status)
+	if encErr := json.NewEncoder(w).Encode(resp); encErr != nil {
+		logger.Error(encErr, "Error encoding response")
+	}
+}
@@ -0,0 +1,132 @@
+package opa
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecision_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   string
+		expected Decision
+	}{
+		{"Test Decision Boolean", `true`, Decision{Allow: true}},
+		{"Test Decision Object", `{"allow": false, "violations": ["replicas must be at most 10"]}`, Decision{Violations: []string{"replicas must be at most 10"}}},
+		{"Test Decision Violations Only", `{"violations": ["replicas must be at most 10"]}`, Decision{Violations: []string{"replicas must be at most 10"}}},
+		{"Test Decision Empty Object", `{}`, Decision{Allow: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Decision
+			if err := json.Unmarshal([]byte(tt.result), &d); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(d, tt.expected) {
+				t.Errorf("Unmarshal() = %+v, want %+v", d, tt.expected)
+			}
+		})
+	}
+
+	var d Decision
+	if err := json.Unmarshal([]byte(`"allow"`), &d); err == nil {
+		t.Error("Unmarshal() error = nil, want an error for a string decision")
+	}
+}
+
+func TestClient_Middleware(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		body             string
+		policyStatus     int
+		policyResponse   string
+		failOpen         bool
+		expectedCode     int
+		expectedMessage  string
+		expectedEvaluate bool
+		expectedBody     any
+	}{
+		{"Test Middleware Allows", "PUT", `{"replicas": 3}`, http.StatusOK, `{"result": {"allow": true}}`, false, http.StatusAccepted, "", true,
+			map[string]any{"replicas": float64(3)}},
+		{"Test Middleware Denies", "PUT", `{"replicas": 30}`, http.StatusOK, `{"result": {"allow": false, "violations": ["replicas must be at most 10", "missing owner"]}}`, false,
+			http.StatusForbidden, "Denied by policy: replicas must be at most 10; missing owner", true, map[string]any{"replicas": float64(30)}},
+		{"Test Middleware Parses YAML Streams", "POST", "kind: Deployment\n---\nkind: Service\n", http.StatusOK, `{"result": true}`, false, http.StatusAccepted, "", true,
+			[]any{map[string]any{"kind": "Deployment"}, map[string]any{"kind": "Service"}}},
+		{"Test Middleware Undefined Decision", "PUT", `{"replicas": 3}`, http.StatusOK, `{}`, false, http.StatusServiceUnavailable, "Policy decision unavailable", true,
+			map[string]any{"replicas": float64(3)}},
+		{"Test Middleware Fails Open", "PUT", `{"replicas": 3}`, http.StatusInternalServerError, ``, true, http.StatusAccepted, "", true,
+			map[string]any{"replicas": float64(3)}},
+		{"Test Middleware Skips Reads", "GET", "", http.StatusOK, `{"result": false}`, false, http.StatusAccepted, "", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input *Input
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					Input *Input `json:"input"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode the policy request: %v", err)
+				}
+				input = req.Input
+				w.WriteHeader(tt.policyStatus)
+				_, _ = w.Write([]byte(tt.policyResponse))
+			}))
+			defer server.Close()
+
+			c := &Client{URL: server.URL + "/v1/data/k8sapi/decision", Client: server.Client(), FailOpen: tt.failOpen}
+			handler := c.Middleware(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tt.body {
+					t.Errorf("handler read body %q, want %q", body, tt.body)
+				}
+				w.WriteHeader(http.StatusAccepted)
+			})
+			mux := http.NewServeMux()
+			mux.HandleFunc("/deployments/{namespace}/{deployment}", handler)
+
+			r := httptest.NewRequest(tt.method, "/deployments/foo/bar?dryRun=true", strings.NewReader(tt.body))
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: "team-a-portal"}}
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("status code = %d, want %d: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if tt.expectedMessage != "" {
+				var resp DeniedError
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Message != tt.expectedMessage {
+					t.Errorf("message = %q, want %q", resp.Message, tt.expectedMessage)
+				}
+			}
+			if !tt.expectedEvaluate {
+				if input != nil {
+					t.Errorf("input = %+v, want no evaluation", input)
+				}
+				return
+			}
+			if input == nil {
+				t.Fatal("input = nil, want the request evaluated")
+			}
+			if input.Identity != "team-a-portal" || input.Method != tt.method || input.Namespace != "foo" || input.Deployment != "bar" || input.Query["dryRun"][0] != "true" {
+				t.Errorf("input = %+v, want the request", input)
+			}
+			if !reflect.DeepEqual(input.Body, tt.expectedBody) {
+				t.Errorf("input body = %#v, want %#v", input.Body, tt.expectedBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	"k8s.io/klog/v2"
+)
+
+// Status is the status of an operation
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Operation is a long-running action performed asynchronously
+type Operation struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	Status Status `json:"status"`
+	// Message describes the progress of a running operation, or the reason a failed operation failed
+	Message   string    `json:"message,omitempty"`
+	Result    any       `json:"result,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Namespace returns the namespace of the target of the operation, when it's a namespace/name key
+func (o Operation) Namespace() string {
+	if namespace, _, found := strings.Cut(o.Target, "/"); found {
+		return namespace
+	}
+	return ""
+}
+
+// Done returns whether the operation has completed
+func (o Operation) Done() bool {
+	return o.Status != StatusRunning
+}
+
+// Func performs an operation. It reports its progress through the given function, and returns its result.
+type Func func(ctx context.Context, progress func(message string)) (any, error)
+
+// Manager runs operations in the background, and keeps track of them until their TTL expires after they complete
+type Manager struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	timeout    time.Duration
+	now        func() time.Time
+	operations map[string]*Operation
+	// persisted holds the operations beyond the memory of the process, so that they survive restarts and can be read
+	// by other replicas. It is optional.
+	persisted *store.Typed[Operation]
+}
+
+// NewManager returns a new Manager. Operations are cancelled once they have run for the given timeout, and are kept
+// for the given TTL once completed. The store is optional.
+func NewManager(ttl, timeout time.Duration, s store.Store) *Manager {
+	m := &Manager{
+		ttl:        ttl,
+		timeout:    timeout,
+		now:        time.Now,
+		operations: map[string]*Operation{},
+	}
+	if s != nil {
+		m.persisted = &store.Typed[Operation]{Store: s}
+	}
+	return m
+}
+
+// Restore loads the persisted operations. Operations which were still running when they were persisted are marked
+// as failed, since they were interrupted by a restart.
+func (m *Manager) Restore(ctx context.Context) error {
+	if m.persisted == nil {
+		return nil
+	}
+	persisted, err := m.persisted.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to restore operations: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, op := range persisted {
+		if !op.Done() {
+			op.Status, op.Message, op.UpdatedAt = StatusFailed, "interrupted by a restart", m.now()
+			if err := m.persisted.Put(ctx, op.ID, op); err != nil {
+				klog.FromContext(ctx).Error(err, "Error persisting operation", "operation", op.ID)
+			}
+		}
+		m.operations[op.ID] = &op
+	}
+	return nil
+}
+
+// Start starts running the given function in the background as a new operation on the given target (e.g. the
+// namespace/name key of a deployment), and returns it. The operation keeps running once the given context is cancelled, but keeps its values (e.g. the logger).
+func (m *Manager) Start(ctx context.Context, opType, target string, fn Func) Operation {
+	now := m.now()
+	op := &Operation{
+		ID:        uuid.NewString(),
+		Type:      opType,
+		Target:    target,
+		Status:    StatusRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	logger := klog.FromContext(ctx).WithValues("operation", op.ID, "type", opType, "target", target)
+	ctx = klog.NewContext(context.WithoutCancel(ctx), logger)
+
+	m.mu.Lock()
+	m.purgeExpired(ctx)
+	m.operations[op.ID] = op
+	started := *op
+	m.mu.Unlock()
+	m.persist(ctx, started)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+
+		logger.Info("Operation started")
+		result, err := fn(ctx, func(message string) {
+			m.update(ctx, op.ID, func(op *Operation) { op.Message = message })
+		})
+		m.update(ctx, op.ID, func(op *Operation) {
+			if err != nil {
+				op.Status, op.Message = StatusFailed, err.Error()
+				return
+			}
+			op.Status, op.Message, op.Result = StatusSucceeded, "", result
+		})
+		logger.Info("Operation completed", "error", err)
+	}()
+
+	return started
+}
+
+// Get returns the operation with the given ID
+func (m *Manager) Get(ctx context.Context, id string) (Operation, bool) {
+	m.mu.Lock()
+	op, ok := m.operations[id]
+	if ok && m.expired(op) {
+		ok = false
+	}
+	var found Operation
+	if ok {
+		found = *op
+	}
+	m.mu.Unlock()
+	if ok || m.persisted == nil {
+		return found, ok
+	}
+
+	// The operation may have been started by another replica
+	persisted, ok, err := m.persisted.Get(ctx, id)
+	if err != nil {
+		klog.FromContext(ctx).Error(err, "Error loading persisted operation", "operation", id)
+		return Operation{}, false
+	}
+	if !ok || m.expired(&persisted) {
+		return Operation{}, false
+	}
+	return persisted, true
+}
+
+// update applies the given change to the operation, and persists it
+func (m *Manager) update(ctx context.Context, id string, change func(op *Operation)) {
+	m.mu.Lock()
+	op, ok := m.operations[id]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	change(op)
+	op.UpdatedAt = m.now()
+	updated := *op
+	m.mu.Unlock()
+	m.persist(ctx, updated)
+}
+
+func (m *Manager) persist(ctx context.Context, op Operation) {
+	if m.persisted == nil {
+		return
+	}
+	if err := m.persisted.Put(ctx, op.ID, op); err != nil {
+		klog.FromContext(ctx).Error(err, "Error persisting operation", "operation", op.ID)
+	}
+}
+
+// expired returns whether the operation completed more than the TTL ago
+func (m *Manager) expired(op *Operation) bool {
+	return op.Done() && m.now().Sub(op.UpdatedAt) > m.ttl
+}
+
+// purgeExpired removes the expired operations. Must be called with the lock held.
+func (m *Manager) purgeExpired(ctx context.Context) {
+	for id, op := range m.operations {
+		if !m.expired(op) {
+			continue
+		}
+		delete(m.operations, id)
+		if m.persisted != nil {
+			if err := m.persisted.Delete(ctx, id); err != nil {
+				klog.FromContext(ctx).Error(err, "Error deleting persisted operation", "operation", id)
+			}
+		}
+	}
+}
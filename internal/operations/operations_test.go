@@ -0,0 +1,135 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// waitDone polls the operation until it completes
+func waitDone(t *testing.T, m *Manager, id string) Operation {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		op, ok := m.Get(context.Background(), id)
+		if !ok {
+			t.Fatalf("operation %s not found", id)
+		}
+		if op.Done() {
+			return op
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("operation %s did not complete", id)
+	return Operation{}
+}
+
+func newStore() *store.ConfigMap {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	return &store.ConfigMap{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), Namespace: "default", Name: "operations"}
+}
+
+func TestManager(t *testing.T) {
+	tests := []struct {
+		name        string
+		fn          Func
+		wantStatus  Status
+		wantMessage string
+		wantResult  any
+	}{
+		{
+			name: "succeeded",
+			fn: func(ctx context.Context, progress func(string)) (any, error) {
+				progress("halfway")
+				return "done", nil
+			},
+			wantStatus: StatusSucceeded,
+			wantResult: "done",
+		},
+		{
+			name: "failed",
+			fn: func(ctx context.Context, progress func(string)) (any, error) {
+				return nil, errors.New("boom")
+			},
+			wantStatus:  StatusFailed,
+			wantMessage: "boom",
+		},
+		{
+			name: "timed out",
+			fn: func(ctx context.Context, progress func(string)) (any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			wantStatus:  StatusFailed,
+			wantMessage: context.DeadlineExceeded.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(time.Hour, 100*time.Millisecond, nil)
+			started := m.Start(context.Background(), "Test", "default/foo", tt.fn)
+			if started.Status != StatusRunning || started.ID == "" {
+				t.Errorf("expected a running operation with an ID, got %+v", started)
+			}
+
+			op := waitDone(t, m, started.ID)
+			if op.Status != tt.wantStatus || op.Message != tt.wantMessage || op.Result != tt.wantResult {
+				t.Errorf("expected status %s, message %q and result %v, got %+v", tt.wantStatus, tt.wantMessage, tt.wantResult, op)
+			}
+		})
+	}
+}
+
+func TestManager_TTL(t *testing.T) {
+	now := time.Now()
+	m := NewManager(time.Minute, time.Minute, nil)
+	m.now = func() time.Time { return now }
+
+	op := m.Start(context.Background(), "Test", "default/foo", func(ctx context.Context, progress func(string)) (any, error) {
+		return nil, nil
+	})
+	waitDone(t, m, op.ID)
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := m.Get(context.Background(), op.ID); ok {
+		t.Errorf("expected the operation to expire")
+	}
+}
+
+func TestManager_Persistence(t *testing.T) {
+	s := newStore()
+	m := NewManager(time.Hour, time.Minute, s)
+	op := m.Start(context.Background(), "Test", "default/foo", func(ctx context.Context, progress func(string)) (any, error) {
+		return "done", nil
+	})
+	waitDone(t, m, op.ID)
+
+	// Another replica reads the operation from the ConfigMap
+	other := NewManager(time.Hour, time.Minute, s)
+	found, ok := other.Get(context.Background(), op.ID)
+	if !ok || found.Status != StatusSucceeded {
+		t.Errorf("expected the persisted operation to be found, got %+v", found)
+	}
+
+	// An operation persisted as running was interrupted by a restart
+	running := Operation{ID: "interrupted", Type: "Test", Status: StatusRunning, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := (store.Typed[Operation]{Store: s}).Put(context.Background(), running.ID, running); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restarted := NewManager(time.Hour, time.Minute, s)
+	if err := restarted.Restore(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restored, ok := restarted.Get(context.Background(), "interrupted")
+	if !ok || restored.Status != StatusFailed {
+		t.Errorf("expected the interrupted operation to be failed, got %+v", restored)
+	}
+}
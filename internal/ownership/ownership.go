@@ -0,0 +1,84 @@
+// Package ownership declares who owns a deployment, and how to reach them, in well-known annotations of the deployment,
+// so that incident tooling can find the owners through the same API.
+package ownership
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Annotations declaring the ownership of a deployment
+const (
+	TeamAnnotation         = "go-k8s-http-api.io/team"
+	OwnerAnnotation        = "go-k8s-http-api.io/owner"
+	SlackChannelAnnotation = "go-k8s-http-api.io/slack-channel"
+	PagerAnnotation        = "go-k8s-http-api.io/pager"
+)
+
+// maxValueLength is the maximum length of every ownership field
+const maxValueLength = 256
+
+// slackChannelPattern matches the names of Slack channels, e.g. #team-a-oncall
+var slackChannelPattern = regexp.MustCompile(`^#[a-z0-9_-]{1,80}$`)
+
+// Ownership is the team owning a deployment, and its contacts. Unset fields are omitted.
+type Ownership struct {
+	Team         string `json:"team,omitempty"`
+	Owner        string `json:"owner,omitempty"`
+	SlackChannel string `json:"slackChannel,omitempty"`
+	// Pager is where the owners are paged, e.g. a PagerDuty service or escalation policy
+	Pager string `json:"pager,omitempty"`
+}
+
+// fields returns the fields of the ownership by annotation
+func (o *Ownership) fields() map[string]*string {
+	return map[string]*string{
+		TeamAnnotation:         &o.Team,
+		OwnerAnnotation:        &o.Owner,
+		SlackChannelAnnotation: &o.SlackChannel,
+		PagerAnnotation:        &o.Pager,
+	}
+}
+
+// IsZero returns whether no field is set
+func (o Ownership) IsZero() bool {
+	return o == Ownership{}
+}
+
+// Validate validates the Ownership object and returns an error if it is invalid
+func (o Ownership) Validate() error {
+	for name, value := range map[string]string{"team": o.Team, "owner": o.Owner, "slackChannel": o.SlackChannel, "pager": o.Pager} {
+		if len(value) > maxValueLength {
+			return fmt.Errorf("%s must be at most %d characters", name, maxValueLength)
+		}
+	}
+	if o.SlackChannel != "" && !slackChannelPattern.MatchString(o.SlackChannel) {
+		return fmt.Errorf("invalid slackChannel %q, must be a channel name starting with #, e.g. #team-a-oncall", o.SlackChannel)
+	}
+	return nil
+}
+
+// FromAnnotations returns the ownership declared in the given annotations
+func FromAnnotations(annotations map[string]string) Ownership {
+	var o Ownership
+	for name, field := range o.fields() {
+		*field = annotations[name]
+	}
+	return o
+}
+
+// SetAnnotations declares the ownership in the given annotations, removing the annotations of the unset fields, and
+// returns the updated annotations
+func SetAnnotations(annotations map[string]string, o Ownership) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for name, field := range o.fields() {
+		if *field == "" {
+			delete(annotations, name)
+		} else {
+			annotations[name] = *field
+		}
+	}
+	return annotations
+}
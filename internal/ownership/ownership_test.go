@@ -0,0 +1,46 @@
+package ownership
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOwnership_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		ownership     Ownership
+		expectedError bool
+	}{
+		{"empty", Ownership{}, false},
+		{"all fields", Ownership{Team: "team-a", Owner: "jane@example.com", SlackChannel: "#team-a-oncall", Pager: "PXYZ123"}, false},
+		{"slack channel without hash", Ownership{SlackChannel: "team-a-oncall"}, true},
+		{"slack channel with spaces", Ownership{SlackChannel: "#team a"}, true},
+		{"too long", Ownership{Owner: strings.Repeat("a", maxValueLength+1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ownership.Validate(); (err != nil) != tt.expectedError {
+				t.Errorf("Validate() error = %v, expectedError %v", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestSetAnnotations(t *testing.T) {
+	annotations := SetAnnotations(map[string]string{"foo": "bar", PagerAnnotation: "PXYZ123"}, Ownership{Team: "team-a", SlackChannel: "#team-a"})
+	expected := map[string]string{"foo": "bar", TeamAnnotation: "team-a", SlackChannelAnnotation: "#team-a"}
+	if !reflect.DeepEqual(annotations, expected) {
+		t.Errorf("SetAnnotations() = %v, want %v", annotations, expected)
+	}
+	if o := FromAnnotations(annotations); o != (Ownership{Team: "team-a", SlackChannel: "#team-a"}) {
+		t.Errorf("FromAnnotations() = %+v, want the ownership set", o)
+	}
+
+	if annotations := SetAnnotations(nil, Ownership{Owner: "jane"}); annotations[OwnerAnnotation] != "jane" {
+		t.Errorf("SetAnnotations() = %v, want the owner set on nil annotations", annotations)
+	}
+	if o := FromAnnotations(SetAnnotations(annotations, Ownership{})); !o.IsZero() {
+		t.Errorf("FromAnnotations() = %+v, want no ownership once cleared", o)
+	}
+}
@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Reconciler loads the APIGatewayPolicies into the engine whenever any of them changes, so that policy changes take
+// effect without restarting the server. It runs on every replica, since all of them enforce the policies.
+type Reconciler struct {
+	// Client reads the policies, typically from the manager's cache
+	Client client.Reader
+	Engine *Engine
+}
+
+// SetupWithManager registers the reconciler as a controller of APIGatewayPolicies on the manager
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("gateway-policies").
+		For(newPolicy()).
+		WithOptions(controller.Options{NeedLeaderElection: ptr.To(false), MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
+
+// Reconcile reloads all the policies, rather than only the changed one, so that the engine always holds a consistent
+// set. Invalid policies are skipped and logged, so that a single bad policy doesn't lock every client out.
+func (r *Reconciler) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	logger := klog.FromContext(ctx)
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(GVK.GroupVersion().WithKind(GVK.Kind + "List"))
+	if err := r.Client.List(ctx, list); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to list gateway policies: %w", err)
+	}
+
+	policies := make([]Policy, 0, len(list.Items))
+	for _, item := range list.Items {
+		p, err := fromUnstructured(&item)
+		if err == nil {
+			err = p.Validate()
+		}
+		if err != nil {
+			logger.Error(err, "Ignoring invalid gateway policy", "policy", item.GetName())
+			continue
+		}
+		policies = append(policies, p)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	r.Engine.Set(policies)
+	logger.V(2).Info("Loaded gateway policies", "count", len(policies))
+	return reconcile.Result{}, nil
+}
+
+func fromUnstructured(u *unstructured.Unstructured) (Policy, error) {
+	p := Policy{Name: u.GetName()}
+	raw, err := json.Marshal(u.Object["spec"])
+	if err != nil {
+		return p, fmt.Errorf("failed to encode the spec of policy %s: %w", p.Name, err)
+	}
+	if err := json.Unmarshal(raw, &p.Spec); err != nil {
+		return p, fmt.Errorf("failed to decode the spec of policy %s: %w", p.Name, err)
+	}
+	return p, nil
+}
+
+func newPolicy() *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(GVK)
+	return u
+}
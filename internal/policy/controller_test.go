@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newUnstructuredPolicy(name string, spec map[string]any) *unstructured.Unstructured {
+	u := newPolicy()
+	u.SetName(name)
+	u.Object["spec"] = spec
+	return u
+}
+
+func TestReconcile(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(GVK, meta.RESTScopeRoot)
+	c := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithRESTMapper(mapper).WithObjects(
+		newUnstructuredPolicy("team-a", map[string]any{
+			"subjects":   []any{"alice"},
+			"namespaces": []any{"team-a"},
+			"rateLimit":  map[string]any{"requestsPerSecond": int64(10), "burst": int64(20)},
+		}),
+		// Invalid, since it has no subjects
+		newUnstructuredPolicy("broken", map[string]any{"namespaces": []any{"team-b"}}),
+	).Build()
+
+	engine := NewEngine()
+	r := &Reconciler{Client: c, Engine: engine}
+	if _, err := r.Reconcile(context.Background(), reconcile.Request{}); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	policies := engine.Policies()
+	if len(policies) != 1 || policies[0].Name != "team-a" {
+		t.Fatalf("Policies() = %+v, want only the valid policy", policies)
+	}
+	if limit := policies[0].RateLimit; limit == nil || limit.RequestsPerSecond != 10 || limit.Burst != 20 {
+		t.Errorf("RateLimit = %+v, want 10 requests per second with a burst of 20", limit)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	engine := NewEngine()
+	engine.Set([]Policy{{Name: "team-a", Spec: Spec{Subjects: []string{AllSubjects}, Namespaces: []string{"team-a", "shared"}}}})
+
+	tests := []struct {
+		name        string
+		path        string
+		tenantScope *tenancy.Scope
+		wantStatus  int
+		wantScope   []string
+	}{
+		{name: "allowed namespace", path: "/namespaces/team-a/deployments", wantStatus: http.StatusOK, wantScope: []string{"team-a", "shared"}},
+		{name: "forbidden namespace", path: "/namespaces/team-b/deployments", wantStatus: http.StatusForbidden},
+		{name: "narrows the tenant scope", path: "/deployments", tenantScope: &tenancy.Scope{Namespaces: map[string]bool{"team-a": true, "team-b": true}}, wantStatus: http.StatusOK, wantScope: []string{"team-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scope tenancy.Scope
+			mux := http.NewServeMux()
+			handler := Middleware(engine, func(w http.ResponseWriter, r *http.Request) {
+				scope, _ = tenancy.ScopeFrom(r.Context())
+			})
+			mux.HandleFunc("GET /namespaces/{namespace}/deployments", handler)
+			mux.HandleFunc("GET /deployments", handler)
+
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.tenantScope != nil {
+				r = r.WithContext(tenancy.WithScope(r.Context(), *tt.tenantScope))
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if len(scope.Namespaces) != len(tt.wantScope) {
+				t.Errorf("scope = %+v, want %v", scope, tt.wantScope)
+			}
+			for _, namespace := range tt.wantScope {
+				if !scope.Allows(namespace) {
+					t.Errorf("scope = %+v, want %v", scope, tt.wantScope)
+				}
+			}
+		})
+	}
+}
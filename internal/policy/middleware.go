@@ -0,0 +1,45 @@
+package policy
+
+import (
+	"net/http"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	"k8s.io/klog/v2"
+)
+
+// Middleware returns a new http.HandlerFunc that enforces the gateway policies on the requests. Requests which aren't
+// allowed by the rules, or to a namespace outside of the allowed ones (via the namespace path wildcard) get a 403
+// Forbidden, and requests over the rate limit get a 429 Too Many Requests.
+// The allowed namespaces are passed to the provided handler through the request context, narrowing down the scope of
+// the tenant if tenancy is enabled, so that lists are filtered accordingly.
+func Middleware(e *Engine, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity := auth.Identity(r)
+		decision := e.Decide(identity, r.Method, r.URL.Path, r.PathValue("namespace"))
+		if decision.Status != 0 {
+			logger := klog.FromContext(r.Context())
+			logger.Info("Request rejected by gateway policy", "identity", identity, "policy", decision.Policy, "reason", decision.Reason)
+			if decision.Status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+			}
+			writeMessage(w, logger, decision.Status, http.StatusText(decision.Status))
+			return
+		}
+		if decision.Scope != nil {
+			scope := *decision.Scope
+			if tenantScope, ok := tenancy.ScopeFrom(r.Context()); ok {
+				scope = *intersect(&tenantScope, scope)
+			}
+			r = r.WithContext(tenancy.WithScope(r.Context(), scope))
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func writeMessage(w http.ResponseWriter, logger klog.Logger, status int, message string) {
+	if encErr := problem.Write(w, status, message); encErr != nil {
+		logger.Error(encErr, "Error encoding response")
+	}
+}
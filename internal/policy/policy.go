@@ -0,0 +1,238 @@
+package policy
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/tenancy"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GVK is the kind of the custom resources holding the gateway policies, as defined in
+// helm/crds/apigatewaypolicies.yaml
+var GVK = schema.GroupVersionKind{Group: "go-k8s-http-api.io", Version: "v1alpha1", Kind: "APIGatewayPolicy"}
+
+// AllSubjects selects every client identity
+const AllSubjects = "*"
+
+// Spec is the spec of an APIGatewayPolicy
+type Spec struct {
+	// Subjects are the client identities the policy applies to
+	Subjects []string `json:"subjects"`
+	// Rules are the requests the subjects may make. If empty, the policy doesn't restrict the requests.
+	Rules []Rule `json:"rules,omitempty"`
+	// Namespaces are the namespaces the subjects may access, "*" granting access to all of them. If empty, the policy
+	// doesn't restrict the namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// RateLimit limits the rate of the requests of every subject
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+}
+
+// Rule allows requests by method and path
+type Rule struct {
+	// Methods are the allowed HTTP methods. If empty, all methods are allowed.
+	Methods []string `json:"methods,omitempty"`
+	// Paths are the allowed request paths, where "*" matches a single path segment (e.g. /deployments/*/*/replicas)
+	Paths []string `json:"paths"`
+}
+
+// RateLimit is a token bucket, refilled at RequestsPerSecond up to Burst tokens
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	// Burst defaults to the requests per second, rounded up
+	Burst int `json:"burst,omitempty"`
+}
+
+// Policy is a named policy
+type Policy struct {
+	Name string
+	Spec
+}
+
+// Validate returns an error if the policy is invalid
+func (p Policy) Validate() error {
+	if len(p.Subjects) == 0 {
+		return fmt.Errorf("policy %s must have at least one subject", p.Name)
+	}
+	for _, rule := range p.Rules {
+		if len(rule.Paths) == 0 {
+			return fmt.Errorf("rules of policy %s must have at least one path", p.Name)
+		}
+		for _, pattern := range rule.Paths {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid path %q in policy %s: %w", pattern, p.Name, err)
+			}
+		}
+	}
+	if p.RateLimit != nil && (p.RateLimit.RequestsPerSecond <= 0 || p.RateLimit.Burst < 0) {
+		return fmt.Errorf("rate limit of policy %s must have a positive requestsPerSecond and burst", p.Name)
+	}
+	return nil
+}
+
+// appliesTo returns whether the policy selects the given identity
+func (p Policy) appliesTo(identity string) bool {
+	return slices.Contains(p.Subjects, identity) || slices.Contains(p.Subjects, AllSubjects)
+}
+
+// allows returns whether the rules of the policy allow the given request
+func (p Policy) allows(method, requestPath string) bool {
+	if len(p.Rules) == 0 {
+		return true
+	}
+	for _, rule := range p.Rules {
+		if len(rule.Methods) > 0 && !slices.ContainsFunc(rule.Methods, func(m string) bool { return strings.EqualFold(m, method) }) {
+			continue
+		}
+		for _, pattern := range rule.Paths {
+			if matched, _ := path.Match(pattern, requestPath); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Engine holds the current set of policies, which is replaced as a whole whenever the policies change
+type Engine struct {
+	mu       sync.RWMutex
+	synced   bool
+	policies []Policy
+	// limiters hold the token buckets by policy, subject and rate limit, so that changing the rate limit of a policy
+	// starts a new bucket
+	limiters map[limiterKey]*rate.Limiter
+}
+
+type limiterKey struct {
+	policy, identity string
+	limit            RateLimit
+}
+
+// NewEngine returns a new Engine, which rejects every request until its policies are first set
+func NewEngine() *Engine {
+	return &Engine{limiters: map[limiterKey]*rate.Limiter{}}
+}
+
+// Set replaces the policies
+func (e *Engine) Set(policies []Policy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.synced = true
+	e.policies = slices.Clone(policies)
+	// Drop the token buckets of the policies which were removed or changed
+	for key := range e.limiters {
+		if !slices.ContainsFunc(policies, func(p Policy) bool { return p.Name == key.policy && p.RateLimit != nil && *p.RateLimit == key.limit }) {
+			delete(e.limiters, key)
+		}
+	}
+}
+
+// Policies returns the current policies
+func (e *Engine) Policies() []Policy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return slices.Clone(e.policies)
+}
+
+// Decision is the outcome of evaluating the policies against a request
+type Decision struct {
+	// Status is the status code to reject the request with, or 0 if the request is allowed
+	Status int
+	// Reason explains why the request was rejected
+	Reason string
+	// Policy is the name of the policy which rejected the request
+	Policy string
+	// Scope is the set of namespaces the identity may access, if restricted by the policies
+	Scope *tenancy.Scope
+}
+
+// Decide evaluates the policies which apply to the given identity against a request. Identities which no policy
+// applies to aren't restricted.
+// A request must be allowed by the rules of every policy which applies to the identity, and may only access the
+// namespaces allowed by all of them.
+func (e *Engine) Decide(identity, method, requestPath, namespace string) Decision {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.synced {
+		return Decision{Status: http.StatusServiceUnavailable, Reason: "gateway policies aren't loaded yet"}
+	}
+
+	var scope *tenancy.Scope
+	var limited []Policy
+	for _, p := range e.policies {
+		if !p.appliesTo(identity) {
+			continue
+		}
+		if !p.allows(method, requestPath) {
+			return Decision{Status: http.StatusForbidden, Reason: "request isn't allowed by the policy rules", Policy: p.Name}
+		}
+		if len(p.Namespaces) > 0 {
+			scope = intersect(scope, scopeOf(p.Namespaces))
+			if namespace != "" && !scope.Allows(namespace) {
+				return Decision{Status: http.StatusForbidden, Reason: "namespace isn't allowed by the policy", Policy: p.Name}
+			}
+		}
+		if p.RateLimit != nil {
+			limited = append(limited, p)
+		}
+	}
+	// Only consume tokens once the request is otherwise allowed
+	for _, p := range limited {
+		if !e.limiter(p, identity).Allow() {
+			return Decision{Status: http.StatusTooManyRequests, Reason: "rate limit exceeded", Policy: p.Name}
+		}
+	}
+	return Decision{Scope: scope}
+}
+
+// limiter returns the token bucket of the given identity for the given policy. Must be called with the lock held.
+func (e *Engine) limiter(p Policy, identity string) *rate.Limiter {
+	key := limiterKey{policy: p.Name, identity: identity, limit: *p.RateLimit}
+	limiter, ok := e.limiters[key]
+	if !ok {
+		burst := p.RateLimit.Burst
+		if burst == 0 {
+			burst = int(p.RateLimit.RequestsPerSecond)
+			if float64(burst) < p.RateLimit.RequestsPerSecond {
+				burst++
+			}
+		}
+		limiter = rate.NewLimiter(rate.Limit(p.RateLimit.RequestsPerSecond), burst)
+		e.limiters[key] = limiter
+	}
+	return limiter
+}
+
+func scopeOf(namespaces []string) tenancy.Scope {
+	scope := tenancy.Scope{Namespaces: map[string]bool{}}
+	for _, namespace := range namespaces {
+		if namespace == tenancy.AllNamespaces {
+			scope.All = true
+			continue
+		}
+		scope.Namespaces[namespace] = true
+	}
+	return scope
+}
+
+// intersect returns the namespaces allowed by both scopes. A nil scope allows all namespaces.
+func intersect(a *tenancy.Scope, b tenancy.Scope) *tenancy.Scope {
+	if a == nil || a.All {
+		return &b
+	}
+	if b.All {
+		return a
+	}
+	scope := tenancy.Scope{Namespaces: map[string]bool{}}
+	for namespace := range a.Namespaces {
+		if b.Namespaces[namespace] {
+			scope.Namespaces[namespace] = true
+		}
+	}
+	return &scope
+}
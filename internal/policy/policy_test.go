@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDecide(t *testing.T) {
+	engine := NewEngine()
+	if d := engine.Decide("alice", http.MethodGet, "/deployments", ""); d.Status != http.StatusServiceUnavailable {
+		t.Fatalf("Decide() before the policies are loaded = %d, want %d", d.Status, http.StatusServiceUnavailable)
+	}
+	engine.Set([]Policy{
+		{Name: "read-only", Spec: Spec{
+			Subjects: []string{"ci-bot", "alice"},
+			Rules:    []Rule{{Methods: []string{"GET"}, Paths: []string{"/deployments", "/deployments/*/*/replicas", "/namespaces/*/deployments"}}},
+		}},
+		{Name: "team-a", Spec: Spec{
+			Subjects:   []string{"alice"},
+			Namespaces: []string{"team-a", "shared"},
+		}},
+		{Name: "everyone", Spec: Spec{
+			Subjects:   []string{AllSubjects},
+			Namespaces: []string{"*"},
+		}},
+	})
+
+	tests := []struct {
+		name       string
+		identity   string
+		method     string
+		path       string
+		namespace  string
+		wantStatus int
+		wantScope  []string
+	}{
+		{name: "allowed by the rules", identity: "ci-bot", method: "GET", path: "/deployments/default/app/replicas", namespace: "default", wantStatus: 0},
+		{name: "method not allowed", identity: "ci-bot", method: "PUT", path: "/deployments/default/app/replicas", namespace: "default", wantStatus: http.StatusForbidden},
+		{name: "path not allowed", identity: "ci-bot", method: "GET", path: "/deployments/default/app/manifest", namespace: "default", wantStatus: http.StatusForbidden},
+		{name: "namespace not allowed", identity: "alice", method: "GET", path: "/deployments/team-b/app/replicas", namespace: "team-b", wantStatus: http.StatusForbidden},
+		{name: "namespace allowed", identity: "alice", method: "GET", path: "/namespaces/team-a/deployments", namespace: "team-a", wantStatus: 0, wantScope: []string{"team-a", "shared"}},
+		{name: "only selected by the wildcard", identity: "bob", method: "PUT", path: "/apply", wantStatus: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := engine.Decide(tt.identity, tt.method, tt.path, tt.namespace)
+			if d.Status != tt.wantStatus {
+				t.Fatalf("Decide() status = %d (%s), want %d", d.Status, d.Reason, tt.wantStatus)
+			}
+			if tt.wantScope == nil {
+				return
+			}
+			if d.Scope == nil || d.Scope.All || len(d.Scope.Namespaces) != len(tt.wantScope) {
+				t.Fatalf("Decide() scope = %+v, want %v", d.Scope, tt.wantScope)
+			}
+			for _, namespace := range tt.wantScope {
+				if !d.Scope.Allows(namespace) {
+					t.Errorf("Decide() scope = %+v, want %v", d.Scope, tt.wantScope)
+				}
+			}
+		})
+	}
+}
+
+func TestDecideRateLimit(t *testing.T) {
+	engine := NewEngine()
+	limited := Policy{Name: "limited", Spec: Spec{Subjects: []string{AllSubjects}, RateLimit: &RateLimit{RequestsPerSecond: 0.001, Burst: 2}}}
+	engine.Set([]Policy{limited})
+
+	for i := 0; i < 2; i++ {
+		if d := engine.Decide("alice", "GET", "/deployments", ""); d.Status != 0 {
+			t.Fatalf("request %d within the burst = %d, want allowed", i, d.Status)
+		}
+	}
+	if d := engine.Decide("alice", "GET", "/deployments", ""); d.Status != http.StatusTooManyRequests {
+		t.Errorf("request over the burst = %d, want %d", d.Status, http.StatusTooManyRequests)
+	}
+	// Every identity has its own bucket
+	if d := engine.Decide("bob", "GET", "/deployments", ""); d.Status != 0 {
+		t.Errorf("request of another identity = %d, want allowed", d.Status)
+	}
+
+	// Reloading the same policy keeps the buckets, while changing its rate limit starts new ones
+	engine.Set([]Policy{limited})
+	if d := engine.Decide("alice", "GET", "/deployments", ""); d.Status != http.StatusTooManyRequests {
+		t.Errorf("request after reloading the policy = %d, want %d", d.Status, http.StatusTooManyRequests)
+	}
+	limited.RateLimit = &RateLimit{RequestsPerSecond: 0.001, Burst: 3}
+	engine.Set([]Policy{limited})
+	if d := engine.Decide("alice", "GET", "/deployments", ""); d.Status != 0 {
+		t.Errorf("request after raising the burst = %d, want allowed", d.Status)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    Spec
+		wantErr bool
+	}{
+		{name: "valid", spec: Spec{Subjects: []string{"alice"}, Rules: []Rule{{Paths: []string{"/deployments/*"}}}, RateLimit: &RateLimit{RequestsPerSecond: 5}}},
+		{name: "no subjects", spec: Spec{}, wantErr: true},
+		{name: "rule without paths", spec: Spec{Subjects: []string{"alice"}, Rules: []Rule{{Methods: []string{"GET"}}}}, wantErr: true},
+		{name: "malformed path", spec: Spec{Subjects: []string{"alice"}, Rules: []Rule{{Paths: []string{"/deployments/["}}}}, wantErr: true},
+		{name: "zero rate", spec: Spec{Subjects: []string{"alice"}, RateLimit: &RateLimit{}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Policy{Name: "p", Spec: tt.spec}.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Package problem stamps the error responses as problem details (RFC 7807), so that API gateways and client libraries
+// handle them generically. The error bodies embed Details, next to their own extension members, e.g. message.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+)
+
+// ContentType is the media type of the error responses
+const ContentType = "application/problem+json"
+
+// typePrefix is the prefix of the type URIs of the problems, followed by the class of the error
+const typePrefix = "urn:go-k8s-http-api:problem:"
+
+// classes are the classes of errors, by status code. The errors of the other status codes are typed about:blank, in
+// which case their title is the reason phrase of their status, as specified by the RFC.
+var classes = map[int]string{
+	http.StatusBadRequest:          "invalid-request",
+	http.StatusUnauthorized:        "unauthenticated",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not-found",
+	http.StatusConflict:            "conflict",
+	http.StatusGone:                "gone",
+	http.StatusUnprocessableEntity: "rejected",
+	http.StatusTooManyRequests:     "too-many-requests",
+	http.StatusInternalServerError: "internal",
+	http.StatusServiceUnavailable:  "unavailable",
+	http.StatusGatewayTimeout:      "timeout",
+}
+
+// Details are the standard members of a problem
+type Details struct {
+	// Type identifies the class of the error, e.g. urn:go-k8s-http-api:problem:not-found
+	Type string `json:"type"`
+	// Title is the summary of the class of the error, i.e. the reason phrase of its status
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	// Detail explains this occurrence of the error
+	Detail string `json:"detail,omitempty"`
+	// Instance identifies this occurrence of the error, from the ID of the request, e.g. urn:uuid:...
+	Instance string `json:"instance,omitempty"`
+}
+
+// Error is a problem whose only extension member is the message, i.e. its detail, kept for the clients predating
+// problem details
+type Error struct {
+	Details
+	Message string `json:"message"`
+}
+
+// Write writes the problem of the given status, with the given message as its detail
+func Write(w http.ResponseWriter, status int, message string) error {
+	resp := Error{Details: New(w, status, message), Message: message}
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// New returns the details of the problem responded to a request with the given status, and sets the content type of
+// the response. The instance is the request ID set on the response by the logging middleware, when set.
+func New(w http.ResponseWriter, status int, detail string) Details {
+	w.Header().Set("Content-Type", ContentType)
+	return Details{
+		Type:     TypeURI(status),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: Instance(w.Header().Get(logging.RequestIDHeader)),
+	}
+}
+
+// TypeURI returns the type URI of the errors of the given status
+func TypeURI(status int) string {
+	if class, ok := classes[status]; ok {
+		return typePrefix + class
+	}
+	return "about:blank"
+}
+
+// Instance returns the URI of the request of the given ID: a urn:uuid URI for the generated IDs, or a URN holding the
+// ID passed in by the client otherwise. It's empty when the request has no ID.
+func Instance(requestID string) string {
+	if requestID == "" {
+		return ""
+	}
+	if id, err := uuid.Parse(requestID); err == nil {
+		return id.URN()
+	}
+	return "urn:go-k8s-http-api:request:" + url.PathEscape(requestID)
+}
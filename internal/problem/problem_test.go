@@ -0,0 +1,63 @@
+package problem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		requestID string
+		expected  Details
+	}{
+		{
+			"Test Problem Of A Class",
+			http.StatusNotFound,
+			"8e5c0d6a-2f0b-4a3e-9d6f-1c2b3a4d5e6f",
+			Details{
+				Type:     "urn:go-k8s-http-api:problem:not-found",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   "detail",
+				Instance: "urn:uuid:8e5c0d6a-2f0b-4a3e-9d6f-1c2b3a4d5e6f",
+			},
+		},
+		{
+			"Test Problem Without A Class",
+			http.StatusTeapot,
+			"",
+			Details{Type: "about:blank", Title: "I'm a teapot", Status: http.StatusTeapot, Detail: "detail"},
+		},
+		{
+			"Test Problem Of A Client Request ID",
+			http.StatusBadRequest,
+			"trace 42/a",
+			Details{
+				Type:     "urn:go-k8s-http-api:problem:invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "detail",
+				Instance: "urn:go-k8s-http-api:request:trace%2042%2Fa",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if tt.requestID != "" {
+				w.Header().Set(logging.RequestIDHeader, tt.requestID)
+			}
+			if got := New(w, tt.status, "detail"); got != tt.expected {
+				t.Errorf("New() = %+v, want %+v", got, tt.expected)
+			}
+			if got := w.Header().Get("Content-Type"); got != ContentType {
+				t.Errorf("Content-Type = %q, want %q", got, ContentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Package projection maintains lightweight summaries of the cached deployments, projected once as the informer
+// receives them, so that the list endpoints only emitting names, namespaces and owners don't have to list (and deep copy) the
+// full objects from the cache on every request.
+package projection
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/ownership"
+	appsv1 "k8s.io/api/apps/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+// Names of the indexes of the summaries
+const (
+	// IndexNamespace indexes the summaries by namespace
+	IndexNamespace = "namespace"
+	// IndexLabelKey indexes the summaries by the keys of their labels, regardless of the values
+	IndexLabelKey = "labelKey"
+)
+
+// Summary is the projection of a deployment served by the list endpoints. Summaries are shared by all the requests,
+// so they must not be modified.
+type Summary struct {
+	Namespace string
+	Name      string
+	Labels    map[string]string
+	// Ownership is the ownership declared in the annotations of the deployment, nil when none is
+	Ownership *ownership.Ownership
+}
+
+// Informer is the subset of the controller-runtime cache.Informer interface needed to maintain the summaries
+type Informer interface {
+	AddEventHandler(handler toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error)
+	HasSynced() bool
+}
+
+// ByNamespace is the index function of IndexNamespace
+func ByNamespace(obj interface{}) ([]string, error) {
+	s, ok := obj.(*Summary)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object of type %T", obj)
+	}
+	return []string{s.Namespace}, nil
+}
+
+// ByLabelKey is the index function of IndexLabelKey
+func ByLabelKey(obj interface{}) ([]string, error) {
+	s, ok := obj.(*Summary)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object of type %T", obj)
+	}
+	keys := make([]string, 0, len(s.Labels))
+	for key := range s.Labels {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// summaryKey is the key function of the summaries, the same namespace/name key as the informer's
+func summaryKey(obj interface{}) (string, error) {
+	s, ok := obj.(*Summary)
+	if !ok {
+		return "", fmt.Errorf("unexpected object of type %T", obj)
+	}
+	return s.Namespace + "/" + s.Name, nil
+}
+
+// Store holds the summaries of the deployments of an informer, indexed by namespace and label key
+type Store struct {
+	indexer      toolscache.Indexer
+	informer     Informer
+	registration toolscache.ResourceEventHandlerRegistration
+}
+
+// NewStore returns a new, empty Store
+func NewStore() *Store {
+	return &Store{
+		indexer: toolscache.NewIndexer(summaryKey, toolscache.Indexers{
+			IndexNamespace: ByNamespace,
+			IndexLabelKey:  ByLabelKey,
+		}),
+	}
+}
+
+// Track maintains the summaries of the deployments received by the given informer
+func (s *Store) Track(informer Informer) error {
+	registration, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: s.upsert,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			s.upsert(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if d, ok := obj.(*appsv1.Deployment); ok {
+				_ = s.indexer.Delete(project(d))
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to track informer: %w", err)
+	}
+	s.informer, s.registration = informer, registration
+	return nil
+}
+
+func (s *Store) upsert(obj interface{}) {
+	if d, ok := obj.(*appsv1.Deployment); ok {
+		// The summaries are only ever replaced as a whole, so the readers holding a previous one are unaffected
+		_ = s.indexer.Update(project(d))
+	}
+}
+
+// project returns the summary of the deployment. Its labels are shared with the cached object, which is never modified.
+func project(d *appsv1.Deployment) *Summary {
+	s := &Summary{Namespace: d.Namespace, Name: d.Name, Labels: d.Labels}
+	if o := ownership.FromAnnotations(d.Annotations); !o.IsZero() {
+		s.Ownership = &o
+	}
+	return s
+}
+
+// Synced returns whether the summaries of the initial list of the informer have all been projected
+func (s *Store) Synced() bool {
+	if s.informer == nil {
+		return false
+	}
+	if s.registration != nil {
+		return s.registration.HasSynced()
+	}
+	return s.informer.HasSynced()
+}
+
+// List returns the summaries of the deployments in the given namespace, or in all namespaces when empty, sorted by
+// namespace and name
+func (s *Store) List(namespace string) []*Summary {
+	if namespace == "" {
+		return sorted(s.indexer.List())
+	}
+	objs, _ := s.indexer.ByIndex(IndexNamespace, namespace)
+	return sorted(objs)
+}
+
+// WithLabelKey returns the summaries of the deployments carrying a label with the given key, sorted by namespace and name
+func (s *Store) WithLabelKey(key string) []*Summary {
+	objs, _ := s.indexer.ByIndex(IndexLabelKey, key)
+	return sorted(objs)
+}
+
+func sorted(objs []interface{}) []*Summary {
+	summaries := make([]*Summary, 0, len(objs))
+	for _, obj := range objs {
+		summaries = append(summaries, obj.(*Summary))
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Namespace != summaries[j].Namespace {
+			return summaries[i].Namespace < summaries[j].Namespace
+		}
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
@@ -0,0 +1,105 @@
+package projection
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/ownership"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+// fakeInformer is a minimal Informer implementation for testing purposes
+type fakeInformer struct {
+	handler toolscache.ResourceEventHandler
+	synced  bool
+}
+
+func (f *fakeInformer) AddEventHandler(handler toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error) {
+	f.handler = handler
+	return nil, nil
+}
+
+func (f *fakeInformer) HasSynced() bool {
+	return f.synced
+}
+
+func deployment(namespace, name string, labels map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels}}
+}
+
+// names returns the namespace/name of the summaries
+func names(summaries []*Summary) []string {
+	names := []string{}
+	for _, s := range summaries {
+		names = append(names, s.Namespace+"/"+s.Name)
+	}
+	return names
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore()
+	if store.Synced() {
+		t.Errorf("Synced() before tracking an informer = true, want false")
+	}
+	informer := &fakeInformer{}
+	if err := store.Track(informer); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+
+	informer.handler.OnAdd(deployment("b", "foo", map[string]string{"app": "foo"}), true)
+	informer.handler.OnAdd(deployment("a", "foo", map[string]string{"app": "foo", "tier": "web"}), true)
+	informer.handler.OnAdd(deployment("a", "bar", nil), true)
+	if store.Synced() {
+		t.Errorf("Synced() before the informer synced = true, want false")
+	}
+	informer.synced = true
+	if !store.Synced() {
+		t.Errorf("Synced() after the informer synced = false, want true")
+	}
+
+	tests := []struct {
+		name string
+		got  []*Summary
+		want []string
+	}{
+		{"all namespaces", store.List(""), []string{"a/bar", "a/foo", "b/foo"}},
+		{"namespace", store.List("a"), []string{"a/bar", "a/foo"}},
+		{"unknown namespace", store.List("c"), []string{}},
+		{"label key", store.WithLabelKey("app"), []string{"a/foo", "b/foo"}},
+		{"unknown label key", store.WithLabelKey("team"), []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(tt.got); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("summaries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Updates replace the summaries, and their index entries
+	informer.handler.OnUpdate(deployment("a", "bar", nil), deployment("a", "bar", map[string]string{"app": "bar"}))
+	informer.handler.OnUpdate(deployment("b", "foo", map[string]string{"app": "foo"}), deployment("b", "foo", nil))
+	if got, want := names(store.WithLabelKey("app")), []string{"a/bar", "a/foo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WithLabelKey() after updates = %v, want %v", got, want)
+	}
+
+	// Deletions remove the summaries, including the ones whose final state is unknown
+	informer.handler.OnDelete(deployment("a", "bar", map[string]string{"app": "bar"}))
+	informer.handler.OnDelete(toolscache.DeletedFinalStateUnknown{Key: "b/foo", Obj: deployment("b", "foo", nil)})
+	if got, want := names(store.List("")), []string{"a/foo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() after deletions = %v, want %v", got, want)
+	}
+}
+
+func TestProject_Ownership(t *testing.T) {
+	d := deployment("a", "foo", nil)
+	if s := project(d); s.Ownership != nil {
+		t.Errorf("project() ownership = %+v, want nil without ownership annotations", s.Ownership)
+	}
+	d.Annotations = map[string]string{ownership.TeamAnnotation: "team-a"}
+	if s := project(d); s.Ownership == nil || *s.Ownership != (ownership.Ownership{Team: "team-a"}) {
+		t.Errorf("project() ownership = %+v, want the team", s.Ownership)
+	}
+}
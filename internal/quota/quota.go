@@ -0,0 +1,355 @@
+// Package quota enforces usage quotas on the gateway itself: the number of mutating operations each identity may make
+// per hour or per day, e.g. 100 scale operations a day for a CI bot. Unlike the rate limits, which smooth bursts out,
+// the quotas cap the total usage over their period.
+package quota
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/statusrecorder"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Periods the quotas reset after, at the start of every UTC hour or day
+const (
+	PeriodHour = "hour"
+	PeriodDay  = "day"
+)
+
+// AllIdentities selects every client identity, each of them having its own quota
+const AllIdentities = "*"
+
+// Headers of the responses to the operations subject to a quota, describing the quota with the fewest remaining
+// operations
+const (
+	HeaderLimit     = "X-Quota-Limit"
+	HeaderRemaining = "X-Quota-Remaining"
+	// HeaderReset is the RFC 3339 time the quota resets at
+	HeaderReset = "X-Quota-Reset"
+)
+
+// pruneInterval is how often the usage of the past periods is removed from the store
+const pruneInterval = time.Hour
+
+// QuotaConfig is a quota of some operations of some identities
+type QuotaConfig struct {
+	Name string `json:"name"`
+	// Identities are the client identities the quota applies to, each of them separately, "*" selecting all of them
+	Identities []string `json:"identities"`
+	// Operations are the operations counted against the quota, named after their handlers, e.g. SetDeploymentReplicas
+	Operations []string `json:"operations"`
+	// Limit is the number of operations allowed per period
+	Limit int `json:"limit"`
+	// Period is either hour or day
+	Period string `json:"period"`
+}
+
+// Config is the quotas configuration file
+type Config struct {
+	Quotas []QuotaConfig `json:"quotas"`
+}
+
+// Usage is the usage of a quota by an identity, over the current period
+type Usage struct {
+	Quota      string   `json:"quota"`
+	Identity   string   `json:"identity"`
+	Operations []string `json:"operations"`
+	Period     string   `json:"period"`
+	Limit      int      `json:"limit"`
+	Used       int      `json:"used"`
+	Remaining  int      `json:"remaining"`
+	// Reset is when the period ends, and the quota resets
+	Reset time.Time `json:"reset"`
+}
+
+// record is the stored usage of a quota by an identity
+type record struct {
+	Quota    string `json:"quota"`
+	Identity string `json:"identity"`
+	// Start is the start of the period the operations were counted in
+	Start time.Time `json:"start"`
+	Used  int       `json:"used"`
+}
+
+// key returns the key of the usage of the quota by the identity, which is a valid ConfigMap key whatever the identity
+func key(quota, identity string) string {
+	sum := sha256.Sum256([]byte(quota + "\x00" + identity))
+	return hex.EncodeToString(sum[:16])
+}
+
+// start returns the start of the period of the given time
+func start(period string, t time.Time) time.Time {
+	if period == PeriodHour {
+		return t.UTC().Truncate(time.Hour)
+	}
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// end returns the end of the period starting at the given time
+func end(period string, start time.Time) time.Time {
+	if period == PeriodHour {
+		return start.Add(time.Hour)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// appliesTo returns whether the quota applies to the identity
+func (c QuotaConfig) appliesTo(identity string) bool {
+	return slices.Contains(c.Identities, identity) || slices.Contains(c.Identities, AllIdentities)
+}
+
+// Manager counts the operations of the identities against their quotas. The usage is kept in the state store, so
+// that it survives restarts and leader elections.
+type Manager struct {
+	quotas []QuotaConfig
+	usage  store.Typed[record]
+	now    func() time.Time
+
+	// mu serializes the updates of the usage, which the leader makes since the mutating operations are leader only
+	mu sync.Mutex
+}
+
+// New returns a new Manager of the given configuration, keeping the usage in the given store
+func New(config Config, s store.Store) (*Manager, error) {
+	names := map[string]bool{}
+	for _, q := range config.Quotas {
+		if q.Name == "" {
+			return nil, errors.New("the name of every quota is required")
+		} else if names[q.Name] {
+			return nil, fmt.Errorf("duplicate quota %q", q.Name)
+		}
+		names[q.Name] = true
+		if len(q.Identities) == 0 {
+			return nil, fmt.Errorf("quota %q must have at least one identity", q.Name)
+		}
+		if len(q.Operations) == 0 {
+			return nil, fmt.Errorf("quota %q must have at least one operation", q.Name)
+		}
+		for _, operation := range q.Operations {
+			if !slices.Contains(approvals.Operations, operation) {
+				return nil, fmt.Errorf("unknown operation %q of quota %q, must be one of %v", operation, q.Name, approvals.Operations)
+			}
+		}
+		if q.Limit <= 0 {
+			return nil, fmt.Errorf("invalid limit %d of quota %q, must be positive", q.Limit, q.Name)
+		}
+		if q.Period != PeriodHour && q.Period != PeriodDay {
+			return nil, fmt.Errorf("invalid period %q of quota %q, must be %s or %s", q.Period, q.Name, PeriodHour, PeriodDay)
+		}
+	}
+	return &Manager{quotas: config.Quotas, usage: store.Typed[record]{Store: s}, now: time.Now}, nil
+}
+
+// LoadFile returns a new Manager from the given YAML or JSON configuration file, keeping the usage in the given store
+func LoadFile(path string, s store.Store) (*Manager, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quotas file: %w", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse quotas file %s: %w", path, err)
+	}
+	return New(config, s)
+}
+
+// usageOf returns the usage of the quota by the identity at the given time, from its record
+func usageOf(q QuotaConfig, identity string, rec record, found bool, now time.Time) Usage {
+	periodStart := start(q.Period, now)
+	u := Usage{Quota: q.Name, Identity: identity, Operations: q.Operations, Period: q.Period, Limit: q.Limit, Reset: end(q.Period, periodStart)}
+	// The operations of the past periods don't count
+	if found && rec.Start.Equal(periodStart) {
+		u.Used = rec.Used
+	}
+	u.Remaining = max(u.Limit-u.Used, 0)
+	return u
+}
+
+// consume counts an operation of the identity against the quotas covering it, unless one of them is exhausted, in which
+// case that quota is returned, with ok set to false. Otherwise, the usage of the quota with the fewest remaining
+// operations is returned, with ok set to true, or nothing when no quota covers the operation.
+func (m *Manager) consume(ctx context.Context, operation, identity string) (u *Usage, ok bool, err error) {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var covering []QuotaConfig
+	var usages []Usage
+	for _, q := range m.quotas {
+		if !slices.Contains(q.Operations, operation) || !q.appliesTo(identity) {
+			continue
+		}
+		rec, found, err := m.usage.Get(ctx, key(q.Name, identity))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read usage of quota %s: %w", q.Name, err)
+		}
+		usage := usageOf(q, identity, rec, found, now)
+		if usage.Remaining == 0 {
+			return &usage, false, nil
+		}
+		covering, usages = append(covering, q), append(usages, usage)
+	}
+	for i, q := range covering {
+		usages[i].Used++
+		usages[i].Remaining--
+		rec := record{Quota: q.Name, Identity: identity, Start: start(q.Period, now), Used: usages[i].Used}
+		if err := m.usage.Put(ctx, key(q.Name, identity), rec); err != nil {
+			return nil, false, fmt.Errorf("failed to save usage of quota %s: %w", q.Name, err)
+		}
+		if u == nil || usages[i].Remaining < u.Remaining {
+			u = &usages[i]
+		}
+	}
+	return u, true, nil
+}
+
+// release gives back an operation of the identity which failed, to the quotas it was counted against
+func (m *Manager) release(ctx context.Context, operation, identity string) error {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, q := range m.quotas {
+		if !slices.Contains(q.Operations, operation) || !q.appliesTo(identity) {
+			continue
+		}
+		rec, found, err := m.usage.Get(ctx, key(q.Name, identity))
+		if err != nil {
+			return fmt.Errorf("failed to read usage of quota %s: %w", q.Name, err)
+		}
+		// The operation may have been counted in the previous period
+		if !found || !rec.Start.Equal(start(q.Period, now)) || rec.Used == 0 {
+			continue
+		}
+		rec.Used--
+		if err := m.usage.Put(ctx, key(q.Name, identity), rec); err != nil {
+			return fmt.Errorf("failed to save usage of quota %s: %w", q.Name, err)
+		}
+	}
+	return nil
+}
+
+// Middleware returns a new http.HandlerFunc which counts the operation against the quotas of the identity, rejecting
+// it with a 429 Too Many Requests once one of them is exhausted. The operations which fail (with a status of 400 or
+// more) aren't counted. The handler is returned as is when no quota covers the operation.
+//
+// The quotas fail open: the operations are passed to the provided handler when their usage can't be read or saved.
+func (m *Manager) Middleware(operation string, next http.HandlerFunc) http.HandlerFunc {
+	if !slices.ContainsFunc(m.quotas, func(q QuotaConfig) bool { return slices.Contains(q.Operations, operation) }) {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := klog.FromContext(r.Context())
+		identity := auth.Identity(r)
+		usage, ok, err := m.consume(r.Context(), operation, identity)
+		if err != nil {
+			logger.Error(err, "Error counting operation against its quotas, letting it through", "operation", operation)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if usage == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set(HeaderLimit, strconv.Itoa(usage.Limit))
+		w.Header().Set(HeaderRemaining, strconv.Itoa(usage.Remaining))
+		w.Header().Set(HeaderReset, usage.Reset.Format(time.RFC3339))
+		if !ok {
+			logger.Info("Operation rejected by its quota", "quota", usage.Quota, "operation", operation, "identity", identity)
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(usage.Reset.Sub(m.now()).Round(time.Second).Seconds()), 1)))
+			message := fmt.Sprintf("Quota %s of %d operations per %s is exhausted, it resets at %s", usage.Quota, usage.Limit, usage.Period, usage.Reset.Format(time.RFC3339))
+			if encErr := problem.Write(w, http.StatusTooManyRequests, message); encErr != nil {
+				logger.Error(encErr, "Error encoding response")
+			}
+			return
+		}
+
+		sw := &statusrecorder.Recorder{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.Status >= 400 {
+			if err := m.release(r.Context(), operation, identity); err != nil {
+				logger.Error(err, "Error releasing failed operation from its quotas", "operation", operation)
+			}
+		}
+	}
+}
+
+// Usage returns the usage of the quotas of the given identity over their current period, or of all the identities
+// which made operations in the current period when empty, sorted by identity and quota
+func (m *Manager) Usage(ctx context.Context, identity string) ([]Usage, error) {
+	now := m.now()
+	records, err := m.usage.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quota usage: %w", err)
+	}
+	usages := []Usage{}
+	for _, q := range m.quotas {
+		if identity != "" {
+			if q.appliesTo(identity) {
+				rec, found := records[key(q.Name, identity)]
+				usages = append(usages, usageOf(q, identity, rec, found, now))
+			}
+			continue
+		}
+		for _, rec := range records {
+			if rec.Quota == q.Name && q.appliesTo(rec.Identity) && rec.Start.Equal(start(q.Period, now)) {
+				usages = append(usages, usageOf(q, rec.Identity, rec, true, now))
+			}
+		}
+	}
+	sort.SliceStable(usages, func(i, j int) bool { return usages[i].Identity < usages[j].Identity })
+	return usages, nil
+}
+
+// Run removes the usage of the past periods periodically, until the context is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.prune(ctx); err != nil {
+			logger.Error(err, "Error removing the expired quota usage")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune removes the usage of the past periods, and of the quotas which were removed from the configuration
+func (m *Manager) prune(ctx context.Context) error {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	records, err := m.usage.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list quota usage: %w", err)
+	}
+	for k, rec := range records {
+		i := slices.IndexFunc(m.quotas, func(q QuotaConfig) bool { return q.Name == rec.Quota })
+		if i >= 0 && rec.Start.Equal(start(m.quotas[i].Period, now)) {
+			continue
+		}
+		if err := m.usage.Delete(ctx, k); err != nil {
+			return fmt.Errorf("failed to remove quota usage %s: %w", k, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package quota
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/approvals"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+)
+
+var now = time.Date(2024, 5, 1, 10, 20, 0, 0, time.UTC)
+
+// newRequest returns a new request to the replicas of deployment foo/bar, authenticated as the given identity
+func newRequest(identity string) *http.Request {
+	r := httptest.NewRequest("PUT", "/deployments/foo/bar/replicas", nil)
+	if identity != "" {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: identity}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+	return r
+}
+
+func newManager(t *testing.T, config Config) *Manager {
+	t.Helper()
+	m, err := New(config, store.NewMemory())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	m.now = func() time.Time { return now }
+	return m
+}
+
+func TestNew_Invalid(t *testing.T) {
+	valid := QuotaConfig{Name: "a", Identities: []string{"ci-bot"}, Operations: []string{approvals.OperationSetDeploymentReplicas}, Limit: 10, Period: PeriodDay}
+	tests := []struct {
+		name   string
+		modify func(q *QuotaConfig)
+	}{
+		{"Test Missing Name", func(q *QuotaConfig) { q.Name = "" }},
+		{"Test Missing Identities", func(q *QuotaConfig) { q.Identities = nil }},
+		{"Test Missing Operations", func(q *QuotaConfig) { q.Operations = nil }},
+		{"Test Unknown Operation", func(q *QuotaConfig) { q.Operations = []string{"ListDeployments"} }},
+		{"Test Zero Limit", func(q *QuotaConfig) { q.Limit = 0 }},
+		{"Test Invalid Period", func(q *QuotaConfig) { q.Period = "week" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := valid
+			tt.modify(&q)
+			if _, err := New(Config{Quotas: []QuotaConfig{q}}, store.NewMemory()); err == nil {
+				t.Errorf("New() expected an error")
+			}
+		})
+	}
+	t.Run("Test Duplicate Name", func(t *testing.T) {
+		if _, err := New(Config{Quotas: []QuotaConfig{valid, valid}}, store.NewMemory()); err == nil {
+			t.Errorf("New() expected an error")
+		}
+	})
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotas.yaml")
+	config := "quotas:\n  - name: ci-scaling\n    identities: [ci-bot]\n    operations: [SetDeploymentReplicas, ScaleDeploymentInSteps]\n    limit: 100\n    period: day\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadFile(path, store.NewMemory())
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if len(m.quotas) != 1 || m.quotas[0].Limit != 100 || len(m.quotas[0].Operations) != 2 {
+		t.Errorf("LoadFile() = %+v", m.quotas)
+	}
+
+	if err := os.WriteFile(path, []byte("quotas:\n  - name: ci-scaling\n    max: 100\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path, store.NewMemory()); err == nil {
+		t.Errorf("LoadFile() expected an error for an unknown field")
+	}
+}
+
+func TestManager_Middleware(t *testing.T) {
+	m := newManager(t, Config{Quotas: []QuotaConfig{
+		{Name: "ci-daily", Identities: []string{"ci-bot"}, Operations: []string{approvals.OperationSetDeploymentReplicas}, Limit: 3, Period: PeriodDay},
+		{Name: "everyone-hourly", Identities: []string{AllIdentities}, Operations: []string{approvals.OperationSetDeploymentReplicas}, Limit: 2, Period: PeriodHour},
+	}})
+	current := now
+	m.now = func() time.Time { return current }
+	status := http.StatusOK
+	handler := m.Middleware(approvals.OperationSetDeploymentReplicas, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+
+	tests := []struct {
+		name              string
+		identity          string
+		handlerStatus     int
+		advance           time.Duration
+		expectedStatus    int
+		expectedRemaining string
+		expectedReset     string
+	}{
+		{name: "first operation", identity: "ci-bot", handlerStatus: http.StatusOK, expectedStatus: http.StatusOK, expectedRemaining: "1", expectedReset: "2024-05-01T11:00:00Z"},
+		{name: "failed operations aren't counted", identity: "ci-bot", handlerStatus: http.StatusConflict, expectedStatus: http.StatusConflict, expectedRemaining: "0", expectedReset: "2024-05-01T11:00:00Z"},
+		{name: "second operation", identity: "ci-bot", handlerStatus: http.StatusOK, expectedStatus: http.StatusOK, expectedRemaining: "0", expectedReset: "2024-05-01T11:00:00Z"},
+		{name: "hourly quota exhausted", identity: "ci-bot", handlerStatus: http.StatusOK, expectedStatus: http.StatusTooManyRequests, expectedRemaining: "0", expectedReset: "2024-05-01T11:00:00Z"},
+		{name: "other identities have their own quota", identity: "alice", handlerStatus: http.StatusOK, expectedStatus: http.StatusOK, expectedRemaining: "1", expectedReset: "2024-05-01T11:00:00Z"},
+		{name: "next hour", identity: "ci-bot", handlerStatus: http.StatusOK, advance: time.Hour, expectedStatus: http.StatusOK, expectedRemaining: "0", expectedReset: "2024-05-02T00:00:00Z"},
+		{name: "daily quota exhausted", identity: "ci-bot", handlerStatus: http.StatusOK, expectedStatus: http.StatusTooManyRequests, expectedRemaining: "0", expectedReset: "2024-05-02T00:00:00Z"},
+		{name: "next day", identity: "ci-bot", handlerStatus: http.StatusOK, advance: 14 * time.Hour, expectedStatus: http.StatusOK, expectedRemaining: "1", expectedReset: "2024-05-02T02:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current = current.Add(tt.advance)
+			status = tt.handlerStatus
+			w := httptest.NewRecorder()
+			handler(w, newRequest(tt.identity))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if remaining := w.Header().Get(HeaderRemaining); remaining != tt.expectedRemaining {
+				t.Errorf("%s = %q, want %q", HeaderRemaining, remaining, tt.expectedRemaining)
+			}
+			if reset := w.Header().Get(HeaderReset); reset != tt.expectedReset {
+				t.Errorf("%s = %q, want %q", HeaderReset, reset, tt.expectedReset)
+			}
+			if tt.expectedStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Errorf("expected a Retry-After header")
+			}
+		})
+	}
+
+	// Operations without quota are passed through as is
+	w := httptest.NewRecorder()
+	m.Middleware(approvals.OperationEvictPod, func(w http.ResponseWriter, r *http.Request) {})(w, newRequest("ci-bot"))
+	if w.Header().Get(HeaderLimit) != "" {
+		t.Errorf("expected no quota headers, got %v", w.Header())
+	}
+}
+
+func TestManager_Usage(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, Config{Quotas: []QuotaConfig{
+		{Name: "ci-daily", Identities: []string{"ci-bot"}, Operations: []string{approvals.OperationSetDeploymentReplicas}, Limit: 100, Period: PeriodDay},
+		{Name: "everyone-hourly", Identities: []string{AllIdentities}, Operations: []string{approvals.OperationApplyManifests}, Limit: 10, Period: PeriodHour},
+	}})
+	for _, op := range []struct{ operation, identity string }{
+		{approvals.OperationSetDeploymentReplicas, "ci-bot"},
+		{approvals.OperationSetDeploymentReplicas, "ci-bot"},
+		{approvals.OperationApplyManifests, "alice"},
+	} {
+		if _, _, err := m.consume(ctx, op.operation, op.identity); err != nil {
+			t.Fatalf("consume() error = %v", err)
+		}
+	}
+	daily := Usage{Quota: "ci-daily", Identity: "ci-bot", Operations: []string{approvals.OperationSetDeploymentReplicas}, Period: PeriodDay, Limit: 100,
+		Used: 2, Remaining: 98, Reset: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)}
+	hourly := Usage{Quota: "everyone-hourly", Identity: "alice", Operations: []string{approvals.OperationApplyManifests}, Period: PeriodHour, Limit: 10,
+		Used: 1, Remaining: 9, Reset: time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)}
+	ciBotHourly := hourly
+	ciBotHourly.Identity, ciBotHourly.Used, ciBotHourly.Remaining = "ci-bot", 0, 10
+
+	tests := []struct {
+		name     string
+		identity string
+		expected []Usage
+	}{
+		{name: "all identities", expected: []Usage{hourly, daily}},
+		{name: "single identity", identity: "ci-bot", expected: []Usage{daily, ciBotHourly}},
+		{name: "identity without usage", identity: "bob", expected: []Usage{{Quota: "everyone-hourly", Identity: "bob", Operations: hourly.Operations,
+			Period: PeriodHour, Limit: 10, Remaining: 10, Reset: hourly.Reset}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage, err := m.Usage(ctx, tt.identity)
+			if err != nil {
+				t.Fatalf("Usage() error = %v", err)
+			}
+			if !reflect.DeepEqual(usage, tt.expected) {
+				got, _ := json.Marshal(usage)
+				t.Errorf("Usage() = %s, want %+v", got, tt.expected)
+			}
+		})
+	}
+
+	// Once the hour is over, the hourly usage is pruned
+	m.now = func() time.Time { return now.Add(time.Hour) }
+	if err := m.prune(ctx); err != nil {
+		t.Fatalf("prune() error = %v", err)
+	}
+	records, err := m.usage.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(records) != 1 || records[key("ci-daily", "ci-bot")].Used != 2 {
+		t.Errorf("records = %+v, want the daily usage only", records)
+	}
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	waitSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "k8s_api_proxy_client_rate_limiter_wait_seconds",
+		Help:    "How long the requests to the API server waited for the client-side rate limiter.",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	})
+	throttledTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_client_rate_limiter_throttled_total",
+		Help: "Number of requests to the API server delayed by the client-side rate limiter.",
+	})
+	rejectedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_client_rate_limiter_rejected_total",
+		Help: "Number of requests to the API server given up on because their context would be done before the client-side rate limiter let them through.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(waitSeconds, throttledTotal, rejectedTotal)
+}
+
+// Limiter is a client-go rate limiter, limiting the requests to the API server to a number of queries per second with
+// bursts, which records how long requests are throttled. Requests whose context would be done before they get through
+// fail right away, rather than queueing for nothing.
+type Limiter struct {
+	limiter *rate.Limiter
+	qps     float32
+	stopped chan struct{}
+}
+
+var _ flowcontrol.RateLimiter = &Limiter{}
+
+// New returns a new Limiter allowing the given queries per second, with bursts of up to the given number of queries
+func New(qps float32, burst int) (*Limiter, error) {
+	if qps <= 0 || burst <= 0 {
+		return nil, fmt.Errorf("qps and burst must be positive, got %v and %d", qps, burst)
+	}
+	return &Limiter{
+		limiter: rate.NewLimiter(rate.Limit(qps), burst),
+		qps:     qps,
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// TryAccept returns true if a token is taken immediately. Otherwise, it returns false.
+func (l *Limiter) TryAccept() bool {
+	if l.isStopped() {
+		return false
+	}
+	return l.limiter.Allow()
+}
+
+// Accept returns once a token becomes available
+func (l *Limiter) Accept() {
+	_ = l.Wait(context.Background())
+}
+
+// Wait returns nil once a token is taken, or an error if the context would be done before a token becomes available
+func (l *Limiter) Wait(ctx context.Context) error {
+	reservation := l.limiter.Reserve()
+	delay := reservation.Delay()
+	if delay == 0 {
+		waitSeconds.Observe(0)
+		return nil
+	}
+	throttledTotal.Inc()
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		reservation.Cancel()
+		rejectedTotal.Inc()
+		return fmt.Errorf("client rate limiter Wait(n=1) would exceed context deadline")
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	start := time.Now()
+	select {
+	case <-timer.C:
+		waitSeconds.Observe(time.Since(start).Seconds())
+		return nil
+	case <-ctx.Done():
+		reservation.Cancel()
+		rejectedTotal.Inc()
+		return ctx.Err()
+	case <-l.stopped:
+		reservation.Cancel()
+		return fmt.Errorf("client rate limiter stopped")
+	}
+}
+
+// Stop stops the limiter, failing the pending and subsequent requests
+func (l *Limiter) Stop() {
+	if !l.isStopped() {
+		close(l.stopped)
+	}
+}
+
+// QPS returns the queries per second of the limiter
+func (l *Limiter) QPS() float32 {
+	return l.qps
+}
+
+func (l *Limiter) isStopped() bool {
+	select {
+	case <-l.stopped:
+		return true
+	default:
+		return false
+	}
+}
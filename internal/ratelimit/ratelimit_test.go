@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLimiter(t *testing.T) {
+	if _, err := New(0, 1); err == nil {
+		t.Errorf("expected an error for a zero QPS")
+	}
+
+	l, err := New(20, 1)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	throttled, rejected := testutil.ToFloat64(throttledTotal), testutil.ToFloat64(rejectedTotal)
+
+	// The burst goes through right away
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	// A context done before the next token is available fails right away
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.Wait(ctx); err == nil {
+		t.Errorf("Wait() with a short deadline expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Errorf("Wait() with a short deadline took %v, want it to fail right away", elapsed)
+	}
+	// Otherwise, the request waits for the next token, about 50ms later
+	start = time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Wait() took %v, want it to be throttled", elapsed)
+	}
+
+	if got := testutil.ToFloat64(throttledTotal) - throttled; got != 2 {
+		t.Errorf("throttled requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(rejectedTotal) - rejected; got != 1 {
+		t.Errorf("rejected requests = %v, want 1", got)
+	}
+
+	l.Stop()
+	if l.TryAccept() {
+		t.Errorf("TryAccept() after Stop() = true, want false")
+	}
+	if l.QPS() != 20 {
+		t.Errorf("QPS() = %v, want 20", l.QPS())
+	}
+}
@@ -0,0 +1,216 @@
+package rbaccheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/features"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/klog/v2"
+)
+
+// Modes of handling missing permissions at startup, set via the --rbac-check flag
+const (
+	// ModeFail fails the startup if any permission is missing
+	ModeFail = "fail"
+	// ModeDegrade disables the endpoints whose permissions are missing. Missing permissions which are required
+	// regardless of the enabled endpoints still fail the startup.
+	ModeDegrade = "degrade"
+	// ModeOff skips the check altogether
+	ModeOff = "off"
+)
+
+// ValidateMode validates the given mode
+func ValidateMode(mode string) error {
+	switch mode {
+	case ModeFail, ModeDegrade, ModeOff:
+		return nil
+	default:
+		return fmt.Errorf("invalid RBAC check mode %q, must be one of %s, %s or %s", mode, ModeFail, ModeDegrade, ModeOff)
+	}
+}
+
+// Permission is a single verb on a resource, in a namespace (or cluster-wide when the namespace is empty)
+type Permission struct {
+	Verb     string
+	Group    string
+	Resource string
+	// Subresource is e.g. eviction for pods/eviction. It is empty for the resource itself.
+	Subresource string
+	Namespace   string
+}
+
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Namespace == "" {
+		return fmt.Sprintf("%s %s (all namespaces)", p.Verb, resource)
+	}
+	return fmt.Sprintf("%s %s in namespace %s", p.Verb, resource, p.Namespace)
+}
+
+// Requirement is a permission needed by the server
+type Requirement struct {
+	Permission
+	// Feature is the endpoint needing the permission. It is empty for permissions needed regardless of the enabled endpoints.
+	Feature string
+}
+
+// Result is the outcome of checking a single requirement
+type Result struct {
+	Requirement
+	Allowed bool
+	Reason  string
+}
+
+// deployments returns the permission for the given verb on deployments
+func deployments(verb string) Permission {
+	return Permission{Verb: verb, Group: "apps", Resource: "deployments"}
+}
+
+// featurePermissions holds the permissions each endpoint needs, on top of the ones needed by the cache
+var featurePermissions = map[string][]Permission{
+	features.ListDeployments:       {deployments("list")},
+	features.GetDeployment:         {deployments("get")},
+	features.GetDeploymentReplicas: {deployments("get")},
+	features.GetDeploymentManifest: {deployments("get")},
+	// The health of a deployment is triaged from its pods, which are listed directly from the API server
+	features.GetDeploymentHealth:   {deployments("get"), {Verb: "list", Resource: "pods"}},
+	features.SetDeploymentReplicas: {deployments("patch")},
+	// Diffs are computed with a server-side dry-run patch of the live object
+	features.DiffDeployment: {deployments("get"), deployments("patch")},
+	// Applies create missing objects, and patch existing ones. Only the permissions on deployments are checked, while
+	// other allow-listed kinds have to be granted separately.
+	features.ApplyManifests: {deployments("create"), deployments("patch")},
+	// Rollout alerts are detected from the cached deployments, so they need no permissions on top of the cache's
+	features.RolloutAlerts: nil,
+	// Deployments are scaled back within their bounds, which is recorded in events
+	features.ReplicaBounds: {deployments("get"), deployments("patch"), {Verb: "create", Resource: "events"}},
+	// Field managers are read from the live deployments, since they're stripped from the cached ones by default
+	features.DeploymentManagers: {deployments("get")},
+	// Ownership is declared in annotations of the deployments
+	features.DeploymentOwnership: {deployments("get"), deployments("patch")},
+	// Costs are estimated from the cached deployments, so they need no permissions on top of the cache's
+	features.CostEstimation: nil,
+	// Images are inventoried from the cached deployments, and scanned by an external scanner
+	features.ImageInventory: nil,
+	// Search matches the cached objects only
+	features.Search: nil,
+	// Snapshots are taken of the live deployments, and restored by patching their spec
+	features.DeploymentSnapshots: {deployments("get"), deployments("patch")},
+	// Hibernation lists the workloads of the namespace directly from the API server, and scales them
+	features.NamespaceHibernation: {deployments("list"), deployments("patch"),
+		{Verb: "list", Group: "apps", Resource: "statefulsets"}, {Verb: "patch", Group: "apps", Resource: "statefulsets"}},
+	// The pods, services and ingresses of the topology are listed directly from the API server. HTTPRoutes are optional,
+	// and left out of the topology when they can't be listed.
+	features.DeploymentTopology: {deployments("get"), {Verb: "list", Resource: "pods"}, {Verb: "list", Resource: "services"},
+		{Verb: "list", Group: "networking.k8s.io", Resource: "ingresses"}},
+	// Applications are grouped from the cached deployments, and the statefulsets, services and HorizontalPodAutoscalers
+	// listed directly from the API server
+	features.Applications: {{Verb: "list", Group: "apps", Resource: "statefulsets"}, {Verb: "list", Resource: "services"},
+		{Verb: "list", Group: "autoscaling", Resource: "horizontalpodautoscalers"}},
+	// The pending pods are listed directly from the API server
+	features.SchedulingInsights: {{Verb: "list", Resource: "pods"}},
+	// Pods are read directly from the API server, and evicted through the Eviction API
+	features.EvictPods: {{Verb: "get", Resource: "pods"}, {Verb: "create", Resource: "pods", Subresource: "eviction"}},
+}
+
+// Requirements returns the permissions needed by the enabled endpoints, in each of the given namespaces
+// (or cluster-wide when no namespaces are given)
+func Requirements(gates *features.Gates, namespaces []string) []Requirement {
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	var requirements []Requirement
+	for _, namespace := range namespaces {
+		inNamespace := func(permission Permission, feature string) Requirement {
+			permission.Namespace = namespace
+			return Requirement{Permission: permission, Feature: feature}
+		}
+		// The cache lists and watches deployments regardless of the enabled endpoints
+		requirements = append(requirements, inNamespace(deployments("list"), ""), inNamespace(deployments("watch"), ""))
+		for _, feature := range []string{
+			features.ListDeployments, features.GetDeployment, features.GetDeploymentReplicas, features.GetDeploymentManifest, features.GetDeploymentHealth,
+			features.SetDeploymentReplicas, features.DiffDeployment, features.ApplyManifests, features.RolloutAlerts,
+			features.ReplicaBounds, features.DeploymentOwnership, features.DeploymentManagers, features.CostEstimation,
+			features.ImageInventory, features.DeploymentSnapshots, features.NamespaceHibernation,
+			features.EvictPods, features.SchedulingInsights, features.DeploymentTopology,
+			features.Applications, features.Search,
+		} {
+			if !gates.Enabled(feature) {
+				continue
+			}
+			for _, permission := range featurePermissions[feature] {
+				requirements = append(requirements, inNamespace(permission, feature))
+			}
+		}
+	}
+	return requirements
+}
+
+// Check runs a SelfSubjectAccessReview for every requirement
+func Check(ctx context.Context, client authorizationv1client.SelfSubjectAccessReviewsGetter, requirements []Requirement) ([]Result, error) {
+	results := make([]Result, 0, len(requirements))
+	for _, requirement := range requirements {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   requirement.Namespace,
+					Verb:        requirement.Verb,
+					Group:       requirement.Group,
+					Resource:    requirement.Resource,
+					Subresource: requirement.Subresource,
+				},
+			},
+		}
+		response, err := client.SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check whether the server may %s: %w", requirement.Permission, err)
+		}
+		results = append(results, Result{
+			Requirement: requirement,
+			Allowed:     response.Status.Allowed,
+			Reason:      response.Status.Reason,
+		})
+	}
+	return results, nil
+}
+
+// Enforce reports the missing permissions, and handles them according to the mode: in ModeDegrade, the endpoints
+// missing permissions are disabled in the given gates. An error listing the missing permissions is returned if the
+// server can't start.
+func Enforce(logger klog.Logger, results []Result, gates *features.Gates, mode string) error {
+	var fatal []string
+	for _, result := range results {
+		if result.Allowed {
+			logger.V(5).Info("Permission granted", "permission", result.Permission.String())
+			continue
+		}
+		logger.Error(nil, "Missing permission", "permission", result.Permission.String(), "feature", result.Feature, "reason", result.Reason)
+
+		if result.Feature == "" || mode == ModeFail {
+			fatal = append(fatal, result.Permission.String())
+			continue
+		}
+		if gates.Enabled(result.Feature) {
+			logger.Info("Disabling endpoint due to missing permissions", "feature", result.Feature)
+			if err := gates.Set(result.Feature + "=false"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(fatal) > 0 {
+		return fmt.Errorf("the server is missing the following permissions: %s", strings.Join(fatal, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package rbaccheck
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/features"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/klog/v2"
+)
+
+// newFakeClient returns a fake clientset allowing every verb except the denied ones
+func newFakeClient(denied ...string) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = true
+		for _, verb := range denied {
+			if review.Spec.ResourceAttributes.Verb == verb {
+				review.Status.Allowed = false
+				review.Status.Reason = "forbidden by test"
+			}
+		}
+		return true, review, nil
+	})
+	return client
+}
+
+func TestRequirements(t *testing.T) {
+	gates := features.NewGates()
+	if got := len(Requirements(gates, nil)); got != 20 {
+		t.Errorf("expected 20 cluster-wide requirements, got %d", got)
+	}
+	if got := len(Requirements(gates, []string{"a", "b"})); got != 40 {
+		t.Errorf("expected 40 requirements for 2 namespaces, got %d", got)
+	}
+
+	_ = gates.Set("SetDeploymentReplicas=false,DiffDeployment=false,ReplicaBounds=false,DeploymentOwnership=false,DeploymentSnapshots=false")
+	for _, requirement := range Requirements(gates, nil) {
+		if requirement.Verb == "patch" {
+			t.Errorf("expected no patch requirement when SetDeploymentReplicas, DiffDeployment, ReplicaBounds, DeploymentOwnership and DeploymentSnapshots are disabled")
+		}
+	}
+
+	_ = gates.Set("EvictPods=true")
+	var evictions []string
+	for _, requirement := range Requirements(gates, []string{"a"}) {
+		if requirement.Feature == features.EvictPods {
+			evictions = append(evictions, requirement.String())
+		}
+	}
+	if want := "get pods in namespace a, create pods/eviction in namespace a"; strings.Join(evictions, ", ") != want {
+		t.Errorf("expected the requirements %q for EvictPods, got %q", want, strings.Join(evictions, ", "))
+	}
+}
+
+func TestCheckAndEnforce(t *testing.T) {
+	tests := []struct {
+		name         string
+		denied       []string
+		mode         string
+		wantErr      bool
+		wantDisabled []string
+	}{
+		{
+			name: "all permissions granted",
+			mode: ModeDegrade,
+		},
+		{
+			name:         "missing endpoint permission degrades",
+			denied:       []string{"patch"},
+			mode:         ModeDegrade,
+			wantDisabled: []string{features.SetDeploymentReplicas, features.DiffDeployment, features.ReplicaBounds},
+		},
+		{
+			name:    "missing endpoint permission fails",
+			denied:  []string{"patch"},
+			mode:    ModeFail,
+			wantErr: true,
+		},
+		{
+			name:    "missing cache permission always fails",
+			denied:  []string{"watch"},
+			mode:    ModeDegrade,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gates := features.NewGates()
+			defaults := gates.All()
+			results, err := Check(context.Background(), newFakeClient(tt.denied...).AuthorizationV1(), Requirements(gates, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = Enforce(klog.Background(), results, gates, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			for _, feature := range tt.wantDisabled {
+				if gates.Enabled(feature) {
+					t.Errorf("expected %s to be disabled", feature)
+				}
+			}
+			if len(tt.wantDisabled) == 0 && !tt.wantErr {
+				for feature, enabled := range gates.All() {
+					if defaults[feature] && !enabled {
+						t.Errorf("expected %s to remain enabled", feature)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Package reconnect keeps the server running through the outages of the API server. The startup steps calling the API
+// server are retried with an exponential backoff instead of exiting, and the connectivity to the API server is tracked
+// out of the outcome of every API server call, including the watches of the informers which client-go reconnects on
+// its own, so that an outage is reported by /readyz as degraded rather than by a crash loop.
+package reconnect
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/breaker"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// State is the state of the connection to the API server
+type State string
+
+// States of the connection to the API server
+const (
+	// StateConnecting is the state until the first API server call succeeds
+	StateConnecting State = "connecting"
+	// StateConnected is the state while the API server calls succeed
+	StateConnected State = "connected"
+	// StateDisconnected is the state once the API server calls fail to reach it, until one of them succeeds again
+	StateDisconnected State = "disconnected"
+)
+
+var (
+	connected = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "k8s_api_proxy_apiserver_connected",
+		Help: "Whether the latest API server calls reached the API server (1) or not (0)",
+	})
+	disconnectionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_apiserver_disconnections_total",
+		Help: "Number of times the API server became unreachable",
+	})
+	startupRetriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_apiserver_startup_retries_total",
+		Help: "Number of retries of the startup steps calling the API server while it was unreachable, by step",
+	}, []string{"step"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(connected, disconnectionsTotal, startupRetriesTotal)
+}
+
+// Status is the state of the connection to the API server, as reported in /readyz
+type Status struct {
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	DisconnectedSince   *time.Time `json:"disconnectedSince,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+	// Step is the startup step being retried, if any
+	Step string `json:"step,omitempty"`
+}
+
+// Tracker tracks the connection to the API server, and retries the startup steps while it's unreachable
+type Tracker struct {
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	now            func() time.Time
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewTracker returns a new Tracker retrying the startup steps after initialBackoff, doubling it after every failure up
+// to maxBackoff
+func NewTracker(initialBackoff, maxBackoff time.Duration) *Tracker {
+	return &Tracker{initialBackoff: initialBackoff, maxBackoff: maxBackoff, now: time.Now, status: Status{State: StateConnecting}}
+}
+
+// Retry runs the startup step until it succeeds, retrying it with an exponential backoff as long as it fails because
+// the API server is unreachable (see breaker.IsUnavailable). Any other error is returned right away, as is the error of
+// the latest attempt once the context is done.
+func (t *Tracker) Retry(ctx context.Context, step string, fn func(context.Context) error) error {
+	logger := klog.FromContext(ctx)
+	delay := t.initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || !breaker.IsUnavailable(err) || ctx.Err() != nil {
+			t.setStep("")
+			return err
+		}
+		t.setStep(step)
+		startupRetriesTotal.WithLabelValues(step).Inc()
+		logger.Info("The API server is unreachable, retrying", "step", step, "attempt", attempt, "delay", delay, "err", err.Error())
+		select {
+		case <-ctx.Done():
+			t.setStep("")
+			return err
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, t.maxBackoff)
+	}
+}
+
+func (t *Tracker) setStep(step string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.Step = step
+}
+
+// WrapTransport returns a new http.RoundTripper recording whether the API server calls reach the API server. Calls
+// answered with any status count as connected, except the 502, 503 and 504 of the load balancers in front of it, while
+// the calls failing at the network level count as disconnected. Cancelled calls, and the calls past the deadline of
+// their context, aren't counted either way.
+func (t *Tracker) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		resp, err := rt.RoundTrip(req)
+		var netErr net.Error
+		switch {
+		case err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)):
+		case err != nil && errors.As(err, &netErr):
+			t.failure(err.Error())
+		case err != nil:
+		case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable ||
+			resp.StatusCode == http.StatusGatewayTimeout:
+			t.failure(resp.Status)
+		default:
+			t.success()
+		}
+		return resp, err
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func (t *Tracker) failure(message string) {
+	now := t.now().UTC()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.status.State != StateDisconnected {
+		klog.Background().Error(nil, "The API server is unreachable, reconnecting", "err", message)
+		disconnectionsTotal.Inc()
+		t.status.DisconnectedSince = &now
+	}
+	connected.Set(0)
+	t.status.State = StateDisconnected
+	t.status.ConsecutiveFailures++
+	t.status.LastError = message
+}
+
+func (t *Tracker) success() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch t.status.State {
+	case StateConnected:
+		return
+	case StateDisconnected:
+		klog.Background().Info("Reconnected to the API server", "disconnectedFor", t.now().Sub(*t.status.DisconnectedSince),
+			"failures", t.status.ConsecutiveFailures)
+	}
+	connected.Set(1)
+	t.status.State = StateConnected
+	t.status.ConsecutiveFailures = 0
+	t.status.DisconnectedSince = nil
+	t.status.LastError = ""
+}
+
+// Status returns the state of the connection
+func (t *Tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+// Degraded returns whether the API server is currently unreachable
+func (t *Tracker) Degraded() bool {
+	return t.Status().State == StateDisconnected
+}
+
+// ReadyzCheck reports the state of the connection. The instance stays ready while the API server is unreachable,
+// since reads are still served from the cache, and /readyz reports it as degraded instead.
+func (t *Tracker) ReadyzCheck() (bool, any) {
+	return true, t.Status()
+}
@@ -0,0 +1,112 @@
+package reconnect
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var errConnectionRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}
+
+func TestTracker_Retry(t *testing.T) {
+	tests := []struct {
+		name             string
+		errs             []error
+		expectedAttempts int
+		expectedErr      bool
+	}{
+		{name: "success", expectedAttempts: 1},
+		{name: "retried while unreachable", errs: []error{errConnectionRefused, apierrors.NewServiceUnavailable("starting")}, expectedAttempts: 3},
+		{name: "other errors aren't retried", errs: []error{apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "state", nil)},
+			expectedAttempts: 1, expectedErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(time.Millisecond, 2*time.Millisecond)
+			retries := testutil.ToFloat64(startupRetriesTotal.WithLabelValues(tt.name))
+			attempts := 0
+			err := tracker.Retry(context.Background(), tt.name, func(context.Context) error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+			if (err != nil) != tt.expectedErr || attempts != tt.expectedAttempts {
+				t.Errorf("Retry() = %v after %d attempts, want error %v after %d", err, attempts, tt.expectedErr, tt.expectedAttempts)
+			}
+			if got := testutil.ToFloat64(startupRetriesTotal.WithLabelValues(tt.name)); got != retries+float64(tt.expectedAttempts-1) {
+				t.Errorf("retries = %v, want %v", got, retries+float64(tt.expectedAttempts-1))
+			}
+			if step := tracker.Status().Step; step != "" {
+				t.Errorf("Status().Step = %q, want none once done", step)
+			}
+		})
+	}
+
+	// The retries stop once the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := NewTracker(time.Millisecond, 5*time.Millisecond).Retry(ctx, "cancelled", func(context.Context) error { return errConnectionRefused })
+	if !errors.Is(err, errConnectionRefused) {
+		t.Errorf("Retry() error = %v, want the error of the latest attempt", err)
+	}
+}
+
+func TestTracker_WrapTransport(t *testing.T) {
+	tracker := NewTracker(time.Second, time.Minute)
+	var status int
+	var err error
+	rt := tracker.WrapTransport(roundTripper(func(*http.Request) (*http.Response, error) {
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: io.NopCloser(strings.NewReader(""))}, nil
+	}))
+
+	tests := []struct {
+		name             string
+		status           int
+		err              error
+		expectedState    State
+		expectedFailures int
+		expectedDegraded bool
+	}{
+		{name: "connected", status: http.StatusOK, expectedState: StateConnected},
+		{name: "connection refused", err: errConnectionRefused, expectedState: StateDisconnected, expectedFailures: 1, expectedDegraded: true},
+		{name: "load balancer without backends", status: http.StatusServiceUnavailable, expectedState: StateDisconnected, expectedFailures: 2, expectedDegraded: true},
+		{name: "cancelled calls aren't counted", err: context.Canceled, expectedState: StateDisconnected, expectedFailures: 2, expectedDegraded: true},
+		{name: "other errors aren't counted", err: errors.New("getting credentials: exec: executable aws not found"), expectedState: StateDisconnected,
+			expectedFailures: 2, expectedDegraded: true},
+		{name: "reconnected", status: http.StatusNotFound, expectedState: StateConnected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err = tt.status, tt.err
+			if resp, err := rt.RoundTrip(httptest.NewRequest("GET", "/api", nil)); err == nil {
+				resp.Body.Close()
+			}
+			got := tracker.Status()
+			if got.State != tt.expectedState || got.ConsecutiveFailures != tt.expectedFailures || tracker.Degraded() != tt.expectedDegraded ||
+				(got.DisconnectedSince != nil) != tt.expectedDegraded {
+				t.Errorf("Status() = %+v, want %s with %d failures", got, tt.expectedState, tt.expectedFailures)
+			}
+			if ready, _ := tracker.ReadyzCheck(); !ready {
+				t.Errorf("ReadyzCheck() = not ready, want ready while degraded")
+			}
+		})
+	}
+	if got := testutil.ToFloat64(connected); got != 1 {
+		t.Errorf("connected = %v, want 1", got)
+	}
+}
@@ -0,0 +1,153 @@
+// Package redaction strips sensitive fields from the full-detail responses (e.g. exported manifests and diffs) served to
+// non-privileged client identities, so that read access can be granted to broader audiences.
+package redaction
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Redacted is the value the redacted values are replaced with
+const Redacted = "REDACTED"
+
+// Config is the redaction policy configuration file
+type Config struct {
+	// PrivilegedIdentities are the client identities whose responses are never redacted
+	PrivilegedIdentities []string `json:"privilegedIdentities"`
+	// EnvValues redacts the values of the environment variables of the containers. The references to Secrets and
+	// ConfigMaps (valueFrom) are kept, since they don't hold the values.
+	EnvValues bool `json:"envValues"`
+	// ImagePullSecrets removes the image pull secrets of the pod template
+	ImagePullSecrets bool `json:"imagePullSecrets"`
+	// Annotations are the patterns of the annotation keys to remove, from the object and its pod template, in the
+	// path.Match syntax (e.g. "vault.hashicorp.com/*")
+	Annotations []string `json:"annotations"`
+}
+
+// Policy redacts the sensitive fields of objects, according to its configuration
+type Policy struct {
+	privileged       map[string]bool
+	envValues        bool
+	imagePullSecrets bool
+	annotations      []string
+}
+
+// New returns a new Policy from the given configuration
+func New(config Config) (*Policy, error) {
+	for _, pattern := range config.Annotations {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid annotation pattern %q: %w", pattern, err)
+		}
+	}
+	p := &Policy{
+		privileged:       map[string]bool{},
+		envValues:        config.EnvValues,
+		imagePullSecrets: config.ImagePullSecrets,
+		annotations:      config.Annotations,
+	}
+	for _, identity := range config.PrivilegedIdentities {
+		p.privileged[identity] = true
+	}
+	return p, nil
+}
+
+// LoadFile returns a new Policy from the given YAML or JSON configuration file
+func LoadFile(path string) (*Policy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction policy file: %w", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction policy file %s: %w", path, err)
+	}
+	return New(config)
+}
+
+// Privileged returns whether the responses of the given identity are exempt from redaction
+func (p *Policy) Privileged(identity string) bool {
+	return p.privileged[identity]
+}
+
+// Redact redacts the sensitive fields of the unstructured workload object (e.g. a Deployment) in place
+func (p *Policy) Redact(object map[string]any) {
+	p.redactAnnotations(object, "metadata", "annotations")
+	p.redactAnnotations(object, "spec", "template", "metadata", "annotations")
+	if p.imagePullSecrets {
+		unstructured.RemoveNestedField(object, "spec", "template", "spec", "imagePullSecrets")
+	}
+	if p.envValues {
+		for _, field := range []string{"initContainers", "containers"} {
+			containers, _, _ := unstructured.NestedFieldNoCopy(object, "spec", "template", "spec", field)
+			for _, container := range asSlice(containers) {
+				env, _, _ := unstructured.NestedFieldNoCopy(asMap(container), "env")
+				for _, variable := range asSlice(env) {
+					if v := asMap(variable); v != nil && v["value"] != nil && v["value"] != "" {
+						v["value"] = Redacted
+					}
+				}
+			}
+		}
+	}
+}
+
+// redactAnnotations removes the annotations matching the patterns of the policy from the annotations at the given path
+func (p *Policy) redactAnnotations(object map[string]any, fields ...string) {
+	annotations, found, _ := unstructured.NestedFieldNoCopy(object, fields...)
+	annotationsMap := asMap(annotations)
+	if !found || annotationsMap == nil {
+		return
+	}
+	for key := range annotationsMap {
+		for _, pattern := range p.annotations {
+			if matched, _ := path.Match(pattern, key); matched {
+				delete(annotationsMap, key)
+				break
+			}
+		}
+	}
+	if len(annotationsMap) == 0 {
+		unstructured.RemoveNestedField(object, fields...)
+	}
+}
+
+func asMap(value any) map[string]any {
+	m, _ := value.(map[string]any)
+	return m
+}
+
+func asSlice(value any) []any {
+	s, _ := value.([]any)
+	return s
+}
+
+type policyKey struct{}
+
+// WithPolicy returns a copy of the context holding the policy the responses are redacted with
+func WithPolicy(ctx context.Context, p *Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, p)
+}
+
+// From returns the policy the responses of the request are redacted with, or nil if they aren't redacted
+func From(ctx context.Context) *Policy {
+	p, _ := ctx.Value(policyKey{}).(*Policy)
+	return p
+}
+
+// Middleware returns a new http.HandlerFunc that has the responses of the provided handler redacted with the policy,
+// unless the client identity is privileged
+func Middleware(p *Policy, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !p.Privileged(auth.Identity(r)) {
+			r = r.WithContext(WithPolicy(r.Context(), p))
+		}
+		next.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,145 @@
+package redaction
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const testConfig = `
+privilegedIdentities: [platform-admin]
+envValues: true
+imagePullSecrets: true
+annotations: ["vault.hashicorp.com/*", "secret-token"]
+`
+
+func newPolicy(t *testing.T) *Policy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "redaction.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o600); err != nil {
+		t.Fatalf("failed to write redaction policy file: %v", err)
+	}
+	p, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return p
+}
+
+func TestPolicy_Redact(t *testing.T) {
+	d := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "bar",
+			Namespace:   "foo",
+			Annotations: map[string]string{"secret-token": "s3cr3t", "owner": "team-a"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"vault.hashicorp.com/agent-inject-secret-db": "database/creds/app"},
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+					InitContainers: []corev1.Container{{
+						Name: "migrate",
+						Env:  []corev1.EnvVar{{Name: "DATABASE_URL", Value: "postgres://user:password@db"}},
+					}},
+					Containers: []corev1.Container{{
+						Name: "app",
+						Env: []corev1.EnvVar{
+							{Name: "API_KEY", Value: "s3cr3t"},
+							{Name: "EMPTY"},
+							{Name: "FROM_SECRET", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "app"}, Key: "token",
+							}}},
+						},
+					}},
+				},
+			},
+		},
+	}
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(d)
+	if err != nil {
+		t.Fatalf("ToUnstructured() error = %v", err)
+	}
+	newPolicy(t).Redact(object)
+
+	redacted := &appsv1.Deployment{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object, redacted); err != nil {
+		t.Fatalf("FromUnstructured() error = %v", err)
+	}
+	if want := map[string]string{"owner": "team-a"}; !reflect.DeepEqual(redacted.Annotations, want) {
+		t.Errorf("annotations = %v, want %v", redacted.Annotations, want)
+	}
+	podSpec := redacted.Spec.Template.Spec
+	if redacted.Spec.Template.Annotations != nil {
+		t.Errorf("pod template annotations = %v, want none", redacted.Spec.Template.Annotations)
+	}
+	if podSpec.ImagePullSecrets != nil {
+		t.Errorf("image pull secrets = %v, want none", podSpec.ImagePullSecrets)
+	}
+	if value := podSpec.InitContainers[0].Env[0].Value; value != Redacted {
+		t.Errorf("init container env value = %q, want %q", value, Redacted)
+	}
+	env := podSpec.Containers[0].Env
+	if env[0].Value != Redacted || env[1].Value != "" || env[2].ValueFrom == nil || env[2].ValueFrom.SecretKeyRef.Name != "app" {
+		t.Errorf("container env = %+v, want the values redacted and the references kept", env)
+	}
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	if _, err := New(Config{Annotations: []string{"["}}); err == nil {
+		t.Errorf("New() error = nil, want an error for the invalid pattern")
+	}
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redaction.yaml")
+	if err := os.WriteFile(path, []byte("envValue: true\n"), 0o600); err != nil {
+		t.Fatalf("failed to write redaction policy file: %v", err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Errorf("expected an error for the unknown envValue field")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	p := newPolicy(t)
+	tests := []struct {
+		name             string
+		identity         string
+		expectedRedacted bool
+	}{
+		{"Test Middleware Non Privileged Identity", "team-a-portal", true},
+		{"Test Middleware Privileged Identity", "platform-admin", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var policy *Policy
+			handler := Middleware(p, func(w http.ResponseWriter, r *http.Request) {
+				policy = From(r.Context())
+			})
+
+			r := httptest.NewRequest("GET", "/deployments/foo/bar/manifest", nil)
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: tt.identity}}
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+			handler(httptest.NewRecorder(), r)
+
+			if redacted := policy != nil; redacted != tt.expectedRedacted {
+				t.Errorf("redacted = %v, want %v", redacted, tt.expectedRedacted)
+			}
+		})
+	}
+}
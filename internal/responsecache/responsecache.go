@@ -0,0 +1,237 @@
+package responsecache
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/features"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/statusrecorder"
+	"k8s.io/klog/v2"
+)
+
+// StatusHeader is set on cached responses, to "HIT" when served from the cache or "MISS" otherwise
+const StatusHeader = "X-Cache"
+
+// Cacheable are the read endpoints whose responses may be cached
+var Cacheable = []string{
+	features.ListDeployments,
+	features.GetDeploymentReplicas,
+	features.GetDeploymentManifest,
+	features.GetDeploymentHealth,
+	features.ReplicaBounds,
+	features.DeploymentOwnership,
+	features.RolloutAlerts,
+	features.CostEstimation,
+	features.ImageInventory,
+}
+
+// TTLs holds how long the responses of every cached endpoint are kept.
+// It implements flag.Value so it can be populated from a "Name=duration,Name2=duration" command line flag.
+type TTLs map[string]time.Duration
+
+// Set parses a comma separated list of Name=duration pairs and applies them on top of the current TTLs
+func (t TTLs) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, rawValue, found := strings.Cut(pair, "=")
+		if !found {
+			return fmt.Errorf("missing TTL for endpoint %q", name)
+		}
+		name = strings.TrimSpace(name)
+		if !slices.Contains(Cacheable, name) {
+			return fmt.Errorf("endpoint %q can't be cached, must be one of %s", name, strings.Join(Cacheable, ", "))
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(rawValue))
+		if err != nil || ttl < 0 {
+			return fmt.Errorf("invalid TTL %q for endpoint %q", rawValue, name)
+		}
+		t[name] = ttl
+	}
+	return nil
+}
+
+// String returns the TTLs as a sorted, comma separated list of Name=duration pairs
+func (t TTLs) String() string {
+	pairs := make([]string, 0, len(t))
+	for name, ttl := range t {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, ttl))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// entry is a cached response
+type entry struct {
+	status int
+	header http.Header
+	body   []byte
+	// namespace is the namespace of the request path, or empty for requests spanning all namespaces
+	namespace string
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+// Cache holds the successful responses of read endpoints in memory, until their TTL expires or a write to their
+// namespace invalidates them
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	now        func() time.Time
+	entries    map[string]*entry
+}
+
+// New returns a new Cache holding at most the given number of responses
+func New(maxEntries int) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    map[string]*entry{},
+	}
+}
+
+func (c *Cache) get(key string) *entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !c.now().Before(cached.expiresAt) {
+		delete(c.entries, key)
+		return nil
+	}
+	return cached
+}
+
+func (c *Cache) put(key string, cached *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.maxEntries {
+		now := c.now()
+		for key, existing := range c.entries {
+			if !now.Before(existing.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		// Rather than evicting live responses, skip caching until some expire
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+	c.entries[key] = cached
+}
+
+// Invalidate removes the cached responses of the given namespace, and of the requests spanning all namespaces.
+// An empty namespace invalidates every cached response.
+func (c *Cache) Invalidate(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, cached := range c.entries {
+		if namespace == "" || cached.namespace == "" || cached.namespace == namespace {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// recorder passes the response through, while keeping a copy of its status code and body
+type recorder struct {
+	http.ResponseWriter
+	cacheControl string
+	status       int
+	body         bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		// Only successful responses are cached, by the server and the clients alike
+		if status == http.StatusOK {
+			r.Header().Set("Cache-Control", r.cacheControl)
+			r.Header().Set(StatusHeader, "MISS")
+		}
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Middleware returns a new http.HandlerFunc which caches the successful responses of the provided handler for the
+// given TTL, and serves them to the following identical requests. Responses are cached per client identity, since
+// they depend on the namespaces it may access, and per Accept header, since some endpoints support multiple formats.
+// Requests with a "Cache-Control: no-cache" header bypass the cache. A zero TTL disables caching.
+func (c *Cache) Middleware(ttl time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	if ttl <= 0 {
+		return next
+	}
+	cacheControl := fmt.Sprintf("private, max-age=%d", int(ttl.Seconds()))
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logger := klog.FromContext(r.Context())
+		// The versions of the API share their paths, but not the shape of all their responses
+		key := strings.Join([]string{auth.Identity(r), r.Header.Get("Accept"), apiversion.Version(r.Context()), r.URL.RequestURI()}, "\x00")
+
+		if !strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+			if cached := c.get(key); cached != nil {
+				logger.V(5).Info("Serving cached response")
+				for name, values := range cached.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set(StatusHeader, "HIT")
+				w.Header().Set("Age", strconv.Itoa(int(c.now().Sub(cached.storedAt).Seconds())))
+				w.WriteHeader(cached.status)
+				if _, err := w.Write(cached.body); err != nil {
+					logger.Error(err, "Error writing response")
+				}
+				return
+			}
+		}
+
+		rec := &recorder{ResponseWriter: w, cacheControl: cacheControl}
+		next.ServeHTTP(rec, r)
+		if rec.status != http.StatusOK {
+			return
+		}
+		now := c.now()
+		c.put(key, &entry{
+			status:    rec.status,
+			header:    w.Header().Clone(),
+			body:      slices.Clone(rec.body.Bytes()),
+			namespace: r.PathValue("namespace"),
+			storedAt:  now,
+			expiresAt: now.Add(ttl),
+		})
+	}
+}
+
+// BustOnWrite returns a new http.HandlerFunc which invalidates the cached responses affected by the provided mutating
+// handler once it succeeds: those of the namespace of the request path, or all of them if the path has no namespace.
+func (c *Cache) BustOnWrite(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusrecorder.Recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.Status < http.StatusBadRequest {
+			c.Invalidate(r.PathValue("namespace"))
+		}
+	}
+}
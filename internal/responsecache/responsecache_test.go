@@ -0,0 +1,164 @@
+package responsecache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+)
+
+// countingHandler returns a handler responding with the given status, along with a pointer to the number of calls
+func countingHandler(status int) (http.HandlerFunc, *int) {
+	calls := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"replicas":3}`))
+	}, &calls
+}
+
+func serve(mux *http.ServeMux, method, path string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Now()
+	cache := New(10)
+	cache.now = func() time.Time { return now }
+	get, calls := countingHandler(http.StatusOK)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /deployments/{namespace}/{deployment}/replicas", cache.Middleware(time.Minute, get))
+	mux.Handle(apiversion.Prefix+"/", apiversion.Handler(mux))
+
+	w := serve(mux, http.MethodGet, "/deployments/foo/bar/replicas", nil)
+	if w.Header().Get(StatusHeader) != "MISS" || w.Header().Get("Cache-Control") != "private, max-age=60" {
+		t.Errorf("first response headers = %v, want a cacheable miss", w.Header())
+	}
+	now = now.Add(5 * time.Second)
+	w = serve(mux, http.MethodGet, "/deployments/foo/bar/replicas", nil)
+	if w.Header().Get(StatusHeader) != "HIT" || w.Header().Get("Age") != "5" || w.Body.String() != `{"replicas":3}` {
+		t.Errorf("second response = %v %s, want a hit", w.Header(), w.Body)
+	}
+	if *calls != 1 {
+		t.Errorf("handler calls = %d, want 1", *calls)
+	}
+
+	// Other formats, other versions of the API, and requests bypassing the cache, are served by the handler
+	serve(mux, http.MethodGet, "/deployments/foo/bar/replicas", http.Header{"Accept": {"application/yaml"}})
+	serve(mux, http.MethodGet, "/v1/deployments/foo/bar/replicas", nil)
+	serve(mux, http.MethodGet, "/deployments/foo/bar/replicas", http.Header{"Cache-Control": {"no-cache"}})
+	if *calls != 4 {
+		t.Errorf("handler calls = %d, want 4", *calls)
+	}
+
+	// Expired responses are served by the handler
+	now = now.Add(time.Minute)
+	serve(mux, http.MethodGet, "/deployments/foo/bar/replicas", nil)
+	if *calls != 5 {
+		t.Errorf("handler calls after the TTL = %d, want 5", *calls)
+	}
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	cache := New(10)
+	get, calls := countingHandler(http.StatusNotFound)
+	handler := cache.Middleware(time.Minute, get)
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/deployments", nil))
+		if w.Header().Get("Cache-Control") != "" {
+			t.Errorf("Cache-Control of an error = %q, want none", w.Header().Get("Cache-Control"))
+		}
+	}
+	if *calls != 2 {
+		t.Errorf("handler calls = %d, want errors not to be cached", *calls)
+	}
+}
+
+func TestBustOnWrite(t *testing.T) {
+	tests := []struct {
+		name        string
+		writePath   string
+		writeStatus int
+		// wantCalls are the calls of the list, foo and bar handlers once all of them were requested again
+		wantCalls [3]int
+	}{
+		{name: "write to a namespace", writePath: "/deployments/foo/app/replicas", writeStatus: http.StatusOK, wantCalls: [3]int{2, 2, 1}},
+		{name: "write to all namespaces", writePath: "/apply", writeStatus: http.StatusOK, wantCalls: [3]int{2, 2, 2}},
+		{name: "failed write", writePath: "/deployments/foo/app/replicas", writeStatus: http.StatusConflict, wantCalls: [3]int{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := New(10)
+			list, listCalls := countingHandler(http.StatusOK)
+			get, getCalls := countingHandler(http.StatusOK)
+			write, _ := countingHandler(tt.writeStatus)
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /deployments", cache.Middleware(time.Minute, list))
+			mux.HandleFunc("GET /namespaces/{namespace}/deployments", cache.Middleware(time.Minute, get))
+			mux.HandleFunc("PUT /deployments/{namespace}/{deployment}/replicas", cache.BustOnWrite(write))
+			mux.HandleFunc("POST /apply", cache.BustOnWrite(write))
+
+			reads := func() {
+				serve(mux, http.MethodGet, "/deployments", nil)
+				serve(mux, http.MethodGet, "/namespaces/foo/deployments", nil)
+				serve(mux, http.MethodGet, "/namespaces/bar/deployments", nil)
+			}
+			reads()
+			method := http.MethodPut
+			if tt.writePath == "/apply" {
+				method = http.MethodPost
+			}
+			serve(mux, method, tt.writePath, nil)
+			// Count the foo and bar calls separately, since they share a handler
+			fooAndBarBefore := *getCalls
+			serve(mux, http.MethodGet, "/deployments", nil)
+			serve(mux, http.MethodGet, "/namespaces/foo/deployments", nil)
+			fooCalls := *getCalls - fooAndBarBefore
+			serve(mux, http.MethodGet, "/namespaces/bar/deployments", nil)
+			barCalls := *getCalls - fooAndBarBefore - fooCalls
+
+			got := [3]int{*listCalls, 1 + fooCalls, 1 + barCalls}
+			if got != tt.wantCalls {
+				t.Errorf("handler calls = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestTTLs(t *testing.T) {
+	ttls := TTLs{}
+	if err := ttls.Set("ListDeployments=5s, GetDeploymentHealth=1m"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := ttls.String(); got != "GetDeploymentHealth=1m0s,ListDeployments=5s" {
+		t.Errorf("String() = %q", got)
+	}
+	for _, invalid := range []string{"SetDeploymentReplicas=5s", "ListDeployments", "ListDeployments=soon", "ListDeployments=-1s"} {
+		if err := (TTLs{}).Set(invalid); err == nil {
+			t.Errorf("Set(%q) expected an error", invalid)
+		}
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	cache := New(1)
+	get, calls := countingHandler(http.StatusOK)
+	handler := cache.Middleware(time.Minute, get)
+	for _, path := range []string{"/a", "/b", "/a", "/b"} {
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// Only the first response fits in the cache
+	if *calls != 3 {
+		t.Errorf("handler calls = %d, want 3", *calls)
+	}
+}
@@ -0,0 +1,59 @@
+package rollouts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Statuses of the alert notifications
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Event is a notification about an alert firing or resolving
+type Event struct {
+	Status string `json:"status"`
+	Alert  Alert  `json:"alert"`
+}
+
+// Notifier sends notifications about alerts
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// WebhookNotifier posts every event as JSON to a webhook URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify posts the event to the webhook, failing on any non 2xx response
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := n.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
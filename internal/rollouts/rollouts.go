@@ -0,0 +1,224 @@
+package rollouts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Reasons of the rollout alerts
+const (
+	// ReasonProgressDeadlineExceeded means the deployment controller reported that the rollout exceeded its
+	// progressDeadlineSeconds
+	ReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	// ReasonStuck means the rollout made no progress for longer than the detector's StuckAfter threshold, e.g. for
+	// deployments with a very long progress deadline
+	ReasonStuck = "Stuck"
+)
+
+// Alert is a deployment whose rollout is stuck
+type Alert struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+	// Since is the last time the rollout made progress, or the time the progress deadline was exceeded
+	Since time.Time `json:"since"`
+	// Generation is the generation of the deployment being rolled out
+	Generation int64 `json:"generation"`
+}
+
+// Detector is a controller detecting stuck deployment rollouts. It runs on every replica, so that all of them
+// report the same alerts, while notifications are only sent by the leader.
+type Detector struct {
+	// Client reads the deployments, typically from the manager's cache
+	Client client.Reader
+	// StuckAfter is how long a rollout may make no progress before it's reported as stuck. Zero disables the check,
+	// leaving the progress deadline of each deployment as the only criteria.
+	StuckAfter time.Duration
+	// Notifier is notified when alerts fire and resolve. Optional.
+	Notifier Notifier
+	// Elected is closed once this instance is the leader. When nil, notifications are always sent.
+	Elected <-chan struct{}
+
+	mu     sync.Mutex
+	alerts map[types.NamespacedName]Alert
+	now    func() time.Time
+}
+
+// SetupWithManager registers the detector as a controller of deployments on the manager
+func (d *Detector) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("rollout-alerts").
+		For(&appsv1.Deployment{}).
+		WithOptions(controller.Options{NeedLeaderElection: ptr.To(false)}).
+		Complete(d)
+}
+
+// Reconcile evaluates the rollout of a single deployment, firing or resolving its alert as needed
+func (d *Detector) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	logger := klog.FromContext(ctx).WithValues("deployment", req.NamespacedName)
+
+	deployment := &appsv1.Deployment{}
+	if err := d.Client.Get(ctx, req.NamespacedName, deployment); err != nil {
+		if apierrors.IsNotFound(err) {
+			d.resolve(ctx, req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	alert, recheckAfter := evaluate(deployment, d.clock(), d.StuckAfter)
+	if alert == nil {
+		d.resolve(ctx, req.NamespacedName)
+		return reconcile.Result{RequeueAfter: recheckAfter}, nil
+	}
+
+	d.mu.Lock()
+	if d.alerts == nil {
+		d.alerts = map[types.NamespacedName]Alert{}
+	}
+	previous, existed := d.alerts[req.NamespacedName]
+	d.alerts[req.NamespacedName] = *alert
+	d.mu.Unlock()
+
+	// Only notify about new alerts, or alerts about a newer rollout, rather than on every update of the deployment
+	if !existed || previous.Reason != alert.Reason || previous.Generation != alert.Generation {
+		logger.Info("Rollout is stuck", "reason", alert.Reason, "message", alert.Message)
+		d.notify(ctx, Event{Status: StatusFiring, Alert: *alert})
+	}
+	return reconcile.Result{}, nil
+}
+
+// resolve removes the alert of the deployment, if any
+func (d *Detector) resolve(ctx context.Context, name types.NamespacedName) {
+	d.mu.Lock()
+	alert, existed := d.alerts[name]
+	delete(d.alerts, name)
+	d.mu.Unlock()
+
+	if existed {
+		klog.FromContext(ctx).Info("Rollout is no longer stuck", "deployment", name)
+		d.notify(ctx, Event{Status: StatusResolved, Alert: alert})
+	}
+}
+
+// notify sends the event to the notifier, if this instance is the leader
+func (d *Detector) notify(ctx context.Context, event Event) {
+	if d.Notifier == nil {
+		return
+	}
+	if d.Elected != nil {
+		select {
+		case <-d.Elected:
+		default:
+			return
+		}
+	}
+	if err := d.Notifier.Notify(ctx, event); err != nil {
+		klog.FromContext(ctx).Error(err, "Error sending rollout alert notification", "deployment", klog.KRef(event.Alert.Namespace, event.Alert.Name), "status", event.Status)
+	}
+}
+
+// Alerts returns the current alerts, sorted by namespace and name
+func (d *Detector) Alerts() []Alert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	alerts := make([]Alert, 0, len(d.alerts))
+	for _, alert := range d.alerts {
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Namespace != alerts[j].Namespace {
+			return alerts[i].Namespace < alerts[j].Namespace
+		}
+		return alerts[i].Name < alerts[j].Name
+	})
+	return alerts
+}
+
+func (d *Detector) clock() time.Time {
+	if d.now != nil {
+		return d.now()
+	}
+	return time.Now()
+}
+
+// evaluate returns the alert of the deployment if its rollout is stuck, or otherwise how long to wait before checking
+// it again (zero when the rollout isn't in progress)
+func evaluate(d *appsv1.Deployment, now time.Time, stuckAfter time.Duration) (*Alert, time.Duration) {
+	// The progress of paused deployments isn't tracked
+	if d.Spec.Paused {
+		return nil, 0
+	}
+	progressing := progressingCondition(d)
+	if progressing != nil && progressing.Status == corev1.ConditionFalse && progressing.Reason == "ProgressDeadlineExceeded" {
+		return &Alert{
+			Name:       d.Name,
+			Namespace:  d.Namespace,
+			Reason:     ReasonProgressDeadlineExceeded,
+			Message:    progressing.Message,
+			Since:      progressing.LastTransitionTime.Time,
+			Generation: d.Generation,
+		}, 0
+	}
+	if rolloutComplete(d) || stuckAfter <= 0 {
+		return nil, 0
+	}
+
+	lastProgress := d.CreationTimestamp.Time
+	if progressing != nil && !progressing.LastUpdateTime.IsZero() {
+		lastProgress = progressing.LastUpdateTime.Time
+	}
+	if elapsed := now.Sub(lastProgress); elapsed < stuckAfter {
+		return nil, stuckAfter - elapsed
+	}
+	return &Alert{
+		Name:      d.Name,
+		Namespace: d.Namespace,
+		Reason:    ReasonStuck,
+		Message: fmt.Sprintf("rollout made no progress for %s: %d of %d replicas updated, %d available",
+			now.Sub(lastProgress).Round(time.Second), d.Status.UpdatedReplicas, desiredReplicas(d), d.Status.AvailableReplicas),
+		Since:      lastProgress,
+		Generation: d.Generation,
+	}, 0
+}
+
+// rolloutComplete returns whether the latest generation of the deployment is fully rolled out and available
+func rolloutComplete(d *appsv1.Deployment) bool {
+	desired := desiredReplicas(d)
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == desired &&
+		d.Status.Replicas == desired &&
+		d.Status.AvailableReplicas == desired
+}
+
+func desiredReplicas(d *appsv1.Deployment) int32 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+func progressingCondition(d *appsv1.Deployment) *appsv1.DeploymentCondition {
+	for i := range d.Status.Conditions {
+		if d.Status.Conditions[i].Type == appsv1.DeploymentProgressing {
+			return &d.Status.Conditions[i]
+		}
+	}
+	return nil
+}
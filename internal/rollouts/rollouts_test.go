@@ -0,0 +1,220 @@
+package rollouts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// newDeployment returns a deployment of 3 replicas rolling out its 2nd generation, which last progressed at the given time
+func newDeployment(lastProgress time.Time, updated, available int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Generation: 2, CreationTimestamp: metav1.NewTime(now.Add(-24 * time.Hour))},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           3,
+			UpdatedReplicas:    updated,
+			AvailableReplicas:  available,
+			Conditions: []appsv1.DeploymentCondition{{
+				Type:           appsv1.DeploymentProgressing,
+				Status:         corev1.ConditionTrue,
+				Reason:         "ReplicaSetUpdated",
+				LastUpdateTime: metav1.NewTime(lastProgress),
+			}},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	deadlineExceeded := newDeployment(now.Add(-time.Hour), 1, 2)
+	deadlineExceeded.Status.Conditions[0] = appsv1.DeploymentCondition{
+		Type:               appsv1.DeploymentProgressing,
+		Status:             corev1.ConditionFalse,
+		Reason:             "ProgressDeadlineExceeded",
+		Message:            `ReplicaSet "foo-5d4f8c7b9" has timed out progressing.`,
+		LastTransitionTime: metav1.NewTime(now.Add(-50 * time.Minute)),
+	}
+	paused := deadlineExceeded.DeepCopy()
+	paused.Spec.Paused = true
+	notObserved := newDeployment(now.Add(-time.Hour), 3, 3)
+	notObserved.Generation = 3
+
+	tests := []struct {
+		name                 string
+		deployment           *appsv1.Deployment
+		stuckAfter           time.Duration
+		expectedAlert        *Alert
+		expectedRecheckAfter time.Duration
+	}{
+		{
+			name:       "progress deadline exceeded",
+			deployment: deadlineExceeded,
+			stuckAfter: 30 * time.Minute,
+			expectedAlert: &Alert{Name: "foo", Namespace: "default", Reason: ReasonProgressDeadlineExceeded,
+				Message: `ReplicaSet "foo-5d4f8c7b9" has timed out progressing.`, Since: now.Add(-50 * time.Minute), Generation: 2},
+		},
+		{
+			name:       "paused",
+			deployment: paused,
+			stuckAfter: 30 * time.Minute,
+		},
+		{
+			name:       "complete",
+			deployment: newDeployment(now.Add(-time.Hour), 3, 3),
+			stuckAfter: 30 * time.Minute,
+		},
+		{
+			name:                 "progressing",
+			deployment:           newDeployment(now.Add(-10*time.Minute), 1, 2),
+			stuckAfter:           30 * time.Minute,
+			expectedRecheckAfter: 20 * time.Minute,
+		},
+		{
+			name:       "stuck",
+			deployment: newDeployment(now.Add(-40*time.Minute), 1, 2),
+			stuckAfter: 30 * time.Minute,
+			expectedAlert: &Alert{Name: "foo", Namespace: "default", Reason: ReasonStuck,
+				Message: "rollout made no progress for 40m0s: 1 of 3 replicas updated, 2 available", Since: now.Add(-40 * time.Minute), Generation: 2},
+		},
+		{
+			name:       "generation not observed yet",
+			deployment: notObserved,
+			stuckAfter: 30 * time.Minute,
+			expectedAlert: &Alert{Name: "foo", Namespace: "default", Reason: ReasonStuck,
+				Message: "rollout made no progress for 1h0m0s: 3 of 3 replicas updated, 3 available", Since: now.Add(-time.Hour), Generation: 3},
+		},
+		{
+			name:       "stuck check disabled",
+			deployment: newDeployment(now.Add(-40*time.Minute), 1, 2),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert, recheckAfter := evaluate(tt.deployment, now, tt.stuckAfter)
+			if !reflect.DeepEqual(alert, tt.expectedAlert) {
+				t.Errorf("evaluate() alert = %+v, want %+v", alert, tt.expectedAlert)
+			}
+			if recheckAfter != tt.expectedRecheckAfter {
+				t.Errorf("evaluate() recheckAfter = %v, want %v", recheckAfter, tt.expectedRecheckAfter)
+			}
+		})
+	}
+}
+
+// recordingNotifier records the notified events
+type recordingNotifier struct {
+	events []Event
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, event Event) error {
+	n.events = append(n.events, event)
+	return nil
+}
+
+func TestDetector_Reconcile(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(testScheme)
+	ctx := context.Background()
+	name := types.NamespacedName{Namespace: "default", Name: "foo"}
+	request := reconcile.Request{NamespacedName: name}
+
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(newDeployment(now.Add(-40*time.Minute), 1, 2)).
+		WithStatusSubresource(&appsv1.Deployment{}).Build()
+	notifier := &recordingNotifier{}
+	elected := make(chan struct{})
+	d := &Detector{Client: c, StuckAfter: 30 * time.Minute, Notifier: notifier, Elected: elected, now: func() time.Time { return now }}
+
+	// Alerts fire on every replica, but only the leader notifies
+	if _, err := d.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if alerts := d.Alerts(); len(alerts) != 1 || alerts[0].Reason != ReasonStuck {
+		t.Fatalf("Alerts() = %+v, want a single %s alert", alerts, ReasonStuck)
+	}
+	if len(notifier.events) != 0 {
+		t.Fatalf("expected no notification before being elected, got %+v", notifier.events)
+	}
+
+	// Alerts are only notified once per rollout
+	d.alerts = nil
+	close(elected)
+	for i := 0; i < 2; i++ {
+		if _, err := d.Reconcile(ctx, request); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+	if len(notifier.events) != 1 || notifier.events[0].Status != StatusFiring {
+		t.Fatalf("expected a single firing notification, got %+v", notifier.events)
+	}
+
+	// Once the rollout completes, the alert resolves
+	deployment := &appsv1.Deployment{}
+	if err := c.Get(ctx, name, deployment); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	deployment.Status.UpdatedReplicas, deployment.Status.AvailableReplicas = 3, 3
+	if err := c.Status().Update(ctx, deployment); err != nil {
+		t.Fatalf("Status().Update() error = %v", err)
+	}
+	if _, err := d.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if alerts := d.Alerts(); len(alerts) != 0 {
+		t.Errorf("Alerts() = %+v, want none", alerts)
+	}
+	if len(notifier.events) != 2 || notifier.events[1].Status != StatusResolved {
+		t.Errorf("expected a resolved notification, got %+v", notifier.events)
+	}
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	event := Event{Status: StatusFiring, Alert: Alert{Name: "foo", Namespace: "default", Reason: ReasonStuck, Since: now, Generation: 2}}
+
+	tests := []struct {
+		name          string
+		status        int
+		expectedError bool
+	}{
+		{name: "success", status: http.StatusNoContent},
+		{name: "failure", status: http.StatusBadGateway, expectedError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received Event
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("unexpected Content-Type %q", r.Header.Get("Content-Type"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("failed to decode event: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := (&WebhookNotifier{URL: server.URL}).Notify(context.Background(), event)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Notify() error = %v, expectedError %v", err, tt.expectedError)
+			}
+			if !reflect.DeepEqual(received, event) {
+				t.Errorf("webhook received %+v, want %+v", received, event)
+			}
+		})
+	}
+}
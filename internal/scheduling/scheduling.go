@@ -0,0 +1,188 @@
+// Package scheduling explains why pods are pending, by grouping the scheduling failures reported by the scheduler by
+// their cause.
+package scheduling
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Causes of the scheduling failures
+const (
+	CauseInsufficientCPU       = "InsufficientCPU"
+	CauseInsufficientMemory    = "InsufficientMemory"
+	CauseInsufficientResources = "InsufficientResources"
+	// CauseTaints means nodes have taints the pod doesn't tolerate
+	CauseTaints = "Taints"
+	// CauseNodeAffinity means nodes don't match the node affinity or node selector of the pod
+	CauseNodeAffinity = "NodeAffinity"
+	// CausePodAffinity means nodes don't satisfy the pod affinity or anti-affinity rules of the pod, or of the pods
+	// running on them
+	CausePodAffinity = "PodAffinity"
+	// CauseTopologySpread means nodes would violate the topology spread constraints of the pod
+	CauseTopologySpread = "TopologySpread"
+	// CauseVolumes means the volumes of the pod can't be bound or attached to the nodes
+	CauseVolumes = "Volumes"
+	// CauseUnschedulableNodes means nodes are cordoned
+	CauseUnschedulableNodes = "UnschedulableNodes"
+	// CauseSchedulingGated means the pod has scheduling gates, so the scheduler doesn't try to schedule it yet
+	CauseSchedulingGated = "SchedulingGated"
+	CauseOther           = "Other"
+)
+
+// causePatterns maps the reasons reported by the scheduler to their cause, in order of precedence
+var causePatterns = []struct {
+	substring string
+	cause     string
+}{
+	{"Insufficient cpu", CauseInsufficientCPU},
+	{"Insufficient memory", CauseInsufficientMemory},
+	{"Insufficient ", CauseInsufficientResources},
+	{"Too many pods", CauseInsufficientResources},
+	{"taint", CauseTaints},
+	{"node affinity/selector", CauseNodeAffinity},
+	{"volume node affinity", CauseVolumes},
+	{"affinity", CausePodAffinity},
+	{"topology spread", CauseTopologySpread},
+	{"PersistentVolumeClaim", CauseVolumes},
+	{"volume", CauseVolumes},
+	{"were unschedulable", CauseUnschedulableNodes},
+}
+
+// nodesReason matches a reason of a node(s) count, e.g. "3 Insufficient cpu"
+var nodesReason = regexp.MustCompile(`^(\d+) (.+)$`)
+
+// Pod is a pod which can't be scheduled for a cause
+type Pod struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Deployment is the deployment the pod belongs to, if any
+	Deployment string `json:"deployment,omitempty"`
+	// Nodes is the number of nodes rejecting the pod for the cause, if reported by the scheduler
+	Nodes int `json:"nodes,omitempty"`
+	// Since is the time the scheduler first failed to schedule the pod
+	Since time.Time `json:"since"`
+	// Message is the full message of the last scheduling failure
+	Message string `json:"message,omitempty"`
+}
+
+// Cause is a cause of scheduling failures, along with the pods failing to be scheduled for it
+type Cause struct {
+	Cause string `json:"cause"`
+	Pods  []Pod  `json:"pods"`
+}
+
+// Insights groups the pending pods which failed to be scheduled by the causes of their last scheduling failure, from
+// the cause of the most pods to the cause of the fewest. A pod rejected by nodes for several causes is listed under
+// each of them. Pending pods which were scheduled, e.g. waiting for their images to be pulled, are ignored.
+func Insights(pods []corev1.Pod) []Cause {
+	byCause := map[string][]Pod{}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodPending || pod.Spec.NodeName != "" {
+			continue
+		}
+		condition := scheduledCondition(pod)
+		if condition == nil || condition.Status != corev1.ConditionFalse {
+			continue
+		}
+		entry := Pod{
+			Name:       pod.Name,
+			Namespace:  pod.Namespace,
+			Deployment: deploymentOf(pod),
+			Since:      condition.LastTransitionTime.UTC(),
+			Message:    condition.Message,
+		}
+		if condition.Reason == corev1.PodReasonSchedulingGated {
+			byCause[CauseSchedulingGated] = append(byCause[CauseSchedulingGated], entry)
+			continue
+		}
+		for cause, nodes := range Classify(condition.Message) {
+			entry.Nodes = nodes
+			byCause[cause] = append(byCause[cause], entry)
+		}
+	}
+
+	causes := make([]Cause, 0, len(byCause))
+	for cause, pods := range byCause {
+		sort.Slice(pods, func(i, j int) bool {
+			if pods[i].Namespace != pods[j].Namespace {
+				return pods[i].Namespace < pods[j].Namespace
+			}
+			return pods[i].Name < pods[j].Name
+		})
+		causes = append(causes, Cause{Cause: cause, Pods: pods})
+	}
+	sort.Slice(causes, func(i, j int) bool {
+		if len(causes[i].Pods) != len(causes[j].Pods) {
+			return len(causes[i].Pods) > len(causes[j].Pods)
+		}
+		return causes[i].Cause < causes[j].Cause
+	})
+	return causes
+}
+
+// Classify returns the causes of a scheduling failure message of the scheduler, along with the number of nodes
+// rejecting the pod for each of them, e.g. {"InsufficientCPU": 3, "Taints": 2} for
+// "0/5 nodes are available: 3 Insufficient cpu, 2 node(s) had untolerated taint {dedicated: gpu}. preemption: ...".
+// Messages without a breakdown per node, e.g. "pod has unbound immediate PersistentVolumeClaims", report no nodes.
+func Classify(message string) map[string]int {
+	causes := map[string]int{}
+	// The outcome of the preemption attempt repeats the nodes, so only the reasons of the scheduling failure are kept
+	message, _, _ = strings.Cut(message, ". preemption:")
+	message = strings.TrimSuffix(message, ".")
+	if _, reasons, ok := strings.Cut(message, "nodes are available: "); ok {
+		for _, reason := range strings.Split(reasons, ", ") {
+			nodes := 0
+			if match := nodesReason.FindStringSubmatch(reason); match != nil {
+				nodes, _ = strconv.Atoi(match[1])
+				reason = match[2]
+			}
+			causes[cause(reason)] += nodes
+		}
+		return causes
+	}
+	causes[cause(message)] = 0
+	return causes
+}
+
+// cause returns the cause of a single reason reported by the scheduler
+func cause(reason string) string {
+	for _, pattern := range causePatterns {
+		if strings.Contains(reason, pattern.substring) {
+			return pattern.cause
+		}
+	}
+	return CauseOther
+}
+
+// scheduledCondition returns the PodScheduled condition of the pod, if any
+func scheduledCondition(pod *corev1.Pod) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == corev1.PodScheduled {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// deploymentOf returns the deployment the pod belongs to, i.e. the deployment named after the ReplicaSet controlling
+// the pod, without its pod-template-hash suffix
+func deploymentOf(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return ""
+	}
+	hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	if hash == "" || !strings.HasSuffix(owner.Name, "-"+hash) {
+		return ""
+	}
+	return strings.TrimSuffix(owner.Name, "-"+hash)
+}
@@ -0,0 +1,97 @@
+package scheduling
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected map[string]int
+	}{
+		{
+			"Test Insufficient Resources",
+			"0/6 nodes are available: 3 Insufficient cpu, 2 Insufficient memory, 1 Insufficient nvidia.com/gpu. preemption: 0/6 nodes are available: 6 No preemption victims found for incoming pod.",
+			map[string]int{CauseInsufficientCPU: 3, CauseInsufficientMemory: 2, CauseInsufficientResources: 1},
+		},
+		{
+			"Test Taints And Affinity",
+			"0/5 nodes are available: 1 node(s) had untolerated taint {node-role.kubernetes.io/control-plane: }, 1 node(s) had untolerated taint {dedicated: gpu}, 2 node(s) didn't match Pod's node affinity/selector, 1 node(s) didn't match pod anti-affinity rules. preemption: 0/5 nodes are available: 5 Preemption is not helpful for scheduling.",
+			map[string]int{CauseTaints: 2, CauseNodeAffinity: 2, CausePodAffinity: 1},
+		},
+		{
+			"Test Topology Spread And Cordoned Nodes",
+			"0/3 nodes are available: 2 node(s) didn't match pod topology spread constraints, 1 node(s) were unschedulable.",
+			map[string]int{CauseTopologySpread: 2, CauseUnschedulableNodes: 1},
+		},
+		{
+			"Test Volumes",
+			"0/2 nodes are available: 2 node(s) had volume node affinity conflict. preemption: 0/2 nodes are available: 2 Preemption is not helpful for scheduling.",
+			map[string]int{CauseVolumes: 2},
+		},
+		{
+			"Test No Breakdown Per Node",
+			"pod has unbound immediate PersistentVolumeClaims. preemption: 0/3 nodes are available: 3 Preemption is not helpful for scheduling.",
+			map[string]int{CauseVolumes: 0},
+		},
+		{
+			"Test Unknown Reason",
+			"0/1 nodes are available: 1 node(s) didn't have free ports for the requested pod ports.",
+			map[string]int{CauseOther: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if causes := Classify(tt.message); !reflect.DeepEqual(causes, tt.expected) {
+				t.Errorf("Classify() = %v, want %v", causes, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInsights(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pending := func(namespace, name, reason, message string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status: corev1.PodStatus{Phase: corev1.PodPending, Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: reason, Message: message, LastTransitionTime: metav1.NewTime(since)},
+			}},
+		}
+	}
+	web := pending("team-a", "web-7d4b9c6f5-x2kqz", corev1.PodReasonUnschedulable, "0/3 nodes are available: 3 Insufficient cpu.")
+	web.Labels = map[string]string{"pod-template-hash": "7d4b9c6f5"}
+	web.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7d4b9c6f5", Controller: ptr.To(true)}}
+	// A pod which was scheduled, and is waiting for its images
+	pulling := pending("team-a", "api-0", corev1.PodReasonUnschedulable, "")
+	pulling.Spec.NodeName = "node-a"
+
+	causes := Insights([]corev1.Pod{
+		web,
+		pending("team-b", "batch", corev1.PodReasonUnschedulable, "0/3 nodes are available: 1 Insufficient cpu, 2 node(s) had untolerated taint {dedicated: gpu}."),
+		pending("team-b", "gated", corev1.PodReasonSchedulingGated, "Scheduling is blocked due to non-empty scheduling gates"),
+		pulling,
+	})
+	expected := []Cause{
+		{Cause: CauseInsufficientCPU, Pods: []Pod{
+			{Name: "web-7d4b9c6f5-x2kqz", Namespace: "team-a", Deployment: "web", Nodes: 3, Since: since, Message: "0/3 nodes are available: 3 Insufficient cpu."},
+			{Name: "batch", Namespace: "team-b", Nodes: 1, Since: since, Message: "0/3 nodes are available: 1 Insufficient cpu, 2 node(s) had untolerated taint {dedicated: gpu}."},
+		}},
+		{Cause: CauseSchedulingGated, Pods: []Pod{
+			{Name: "gated", Namespace: "team-b", Since: since, Message: "Scheduling is blocked due to non-empty scheduling gates"},
+		}},
+		{Cause: CauseTaints, Pods: []Pod{
+			{Name: "batch", Namespace: "team-b", Nodes: 2, Since: since, Message: "0/3 nodes are available: 1 Insufficient cpu, 2 node(s) had untolerated taint {dedicated: gpu}."},
+		}},
+	}
+	if !reflect.DeepEqual(causes, expected) {
+		t.Errorf("Insights() = %+v, want %+v", causes, expected)
+	}
+}
@@ -0,0 +1,210 @@
+package schema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"k8s.io/klog/v2"
+)
+
+// Names of the JSON Schemas of the request and response bodies, as found in the schemas directory
+const (
+	ReplicasRequest     = "replicas-request"
+	ReplicasResponse    = "replicas-response"
+	DeploymentsResponse = "deployments-response"
+	DeploymentResponse  = "deployment-response"
+	DiffResponse        = "diff-response"
+	ApplyResponse       = "apply-response"
+	HealthResponse      = "health-response"
+	RolloutAlerts       = "rollout-alerts-response"
+	BoundsRequest       = "bounds-request"
+	BoundsResponse      = "bounds-response"
+	OwnershipRequest    = "ownership-request"
+	OwnershipResponse   = "ownership-response"
+	ManagersResponse    = "managers-response"
+	CostResponse        = "cost-response"
+	ImagesResponse      = "images-response"
+	FeaturesResponse    = "features-response"
+	StatsResponse       = "stats-response"
+	LogLevel            = "loglevel"
+	Operation           = "operation"
+	Approval            = "approval"
+	ApprovalsResponse   = "approvals-response"
+	Snapshot            = "snapshot"
+	SnapshotsResponse   = "snapshots-response"
+	RestoreResponse     = "restore-response"
+	HibernationResponse = "hibernation-response"
+	ScalePlanRequest    = "scale-plan-request"
+	EvictionResponse    = "eviction-response"
+	SchedulingInsights  = "scheduling-insights-response"
+	TopologyResponse    = "topology-response"
+	App                 = "app"
+	AppsResponse        = "apps-response"
+	SearchResponse      = "search-response"
+	APIKeyRequest       = "api-key-request"
+	APIKey              = "api-key"
+	APIKeysResponse     = "api-keys-response"
+	SecurityEvents      = "security-events-response"
+	AuditEvents         = "audit-events-response"
+	DashboardsResponse  = "dashboards-response"
+	SLOs                = "slo-response"
+	QuotasResponse      = "quotas-response"
+	FeatureSwitch       = "feature-switch"
+	FeatureStatus       = "feature-status"
+	FeatureStatuses     = "feature-statuses-response"
+	CaptureRequest      = "capture-request"
+	CaptureSession      = "capture-session"
+	CapturedExchanges   = "captured-exchanges-response"
+	Error               = "error"
+)
+
+//go:embed schemas/*.json
+var schemasFS embed.FS
+
+// schemas holds the parsed JSON Schemas, by name
+var schemas = mustLoadSchemas()
+
+func mustLoadSchemas() map[string]*Schema {
+	entries, err := schemasFS.ReadDir("schemas")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]*Schema, len(entries))
+	for _, entry := range entries {
+		raw, err := schemasFS.ReadFile(path.Join("schemas", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		s := &Schema{}
+		if err := json.Unmarshal(raw, s); err != nil {
+			panic(fmt.Sprintf("invalid schema %s: %v", entry.Name(), err))
+		}
+		loaded[strings.TrimSuffix(entry.Name(), ".json")] = s
+	}
+	return loaded
+}
+
+// Validate validates the given JSON document against the named schema. Unknown fields are reported as such,
+// rather than as missing required fields.
+func Validate(name string, document []byte) error {
+	s, ok := schemas[name]
+	if !ok {
+		return fmt.Errorf("unknown schema %q", name)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(document, &data); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	var unknown, invalid []string
+	for _, err := range s.validate("", data) {
+		if err.unknownField {
+			unknown = append(unknown, err.Error())
+		} else {
+			invalid = append(invalid, err.Error())
+		}
+	}
+	// Only report unknown fields if there are any, since a misspelled field (e.g. "replikas") also causes the
+	// actual field to be missing
+	if len(unknown) > 0 {
+		return fmt.Errorf("%s", strings.Join(unknown, ", "))
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("%s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// ValidateRequest returns a new http.HandlerFunc that validates the request body against the named schema before
+// calling the provided handler, and returns a 400 Bad Request if it doesn't match
+func ValidateRequest(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := klog.FromContext(r.Context())
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBadRequest(w, logger, fmt.Sprintf("Error reading request body: %v", err))
+			return
+		}
+		if err := Validate(name, body); err != nil {
+			writeBadRequest(w, logger, fmt.Sprintf("Validation error: %v", err))
+			return
+		}
+		// Let the handler read the body again
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	}
+}
+
+func writeBadRequest(w http.ResponseWriter, logger klog.Logger, message string) {
+	logger.Info("Request body doesn't match its schema", "error", message)
+	if encErr := problem.Write(w, http.StatusBadRequest, message); encErr != nil {
+		logger.Error(encErr, "Error encoding response")
+	}
+}
+
+// responseRecorder passes the response through, while keeping a copy of its status code and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// ValidateResponse returns a new http.HandlerFunc that validates the response body of the provided handler against
+// the named schema (or the operation schema for 202 responses, and the error schema for non 2xx responses),
+// and logs an error if it doesn't match. The response itself is passed through unchanged.
+// It is meant to be used in tests and debug environments.
+func ValidateResponse(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		// Only the JSON responses are validated, e.g. not the lists exported as CSV
+		if recorder.body.Len() == 0 || !isJSON(recorder.Header().Get("Content-Type")) {
+			return
+		}
+		schemaName := name
+		switch {
+		case recorder.status == http.StatusAccepted:
+			// Requests accepted to run asynchronously return the operation to poll
+			schemaName = Operation
+		case recorder.status < 200 || recorder.status > 299:
+			schemaName = Error
+		}
+		if err := Validate(schemaName, recorder.body.Bytes()); err != nil {
+			klog.FromContext(r.Context()).Error(err, "Response body doesn't match its schema", "schema", schemaName, "status", recorder.status)
+		}
+	}
+}
+
+// isJSON returns whether the content type is JSON, which it defaults to when unset
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
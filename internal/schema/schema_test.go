@@ -0,0 +1,135 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		document string
+		wantErr  string
+	}{
+		{name: "valid replicas", schema: ReplicasRequest, document: `{"replicas": 3}`},
+		{name: "zero replicas", schema: ReplicasRequest, document: `{"replicas": 0}`},
+		{name: "misspelled replicas", schema: ReplicasRequest, document: `{"replikas": 3}`, wantErr: `unknown field "replikas"`},
+		{name: "extra field", schema: ReplicasRequest, document: `{"replicas": 3, "foo": "bar"}`, wantErr: `unknown field "foo"`},
+		{name: "missing replicas", schema: ReplicasRequest, document: `{}`, wantErr: "replicas field is required"},
+		{name: "negative replicas", schema: ReplicasRequest, document: `{"replicas": -1}`, wantErr: "replicas"},
+		{name: "string replicas", schema: ReplicasRequest, document: `{"replicas": "3"}`, wantErr: "replicas"},
+		{name: "invalid JSON", schema: ReplicasRequest, document: `{"replicas": `, wantErr: "invalid JSON"},
+		{name: "unknown schema", schema: "foo", document: `{}`, wantErr: `unknown schema "foo"`},
+		{name: "valid replicas response", schema: ReplicasResponse, document: `{"name": "foo", "namespace": "bar", "replicas": 2}`},
+		{name: "replicas response with null replicas", schema: ReplicasResponse, document: `{"name": "foo", "namespace": "bar", "replicas": null}`},
+		{name: "valid deployments response", schema: DeploymentsResponse, document: `[{"name": "foo", "namespace": "bar"}]`},
+		{name: "deployments response with unknown field", schema: DeploymentsResponse, document: `[{"name": "foo", "namespace": "bar", "uid": "1"}]`, wantErr: `unknown field "0.uid"`},
+		{name: "valid deployments response with meta", schema: DeploymentsResponse, document: `{"items": [{"name": "foo", "namespace": "bar"}], "meta": {"dataSource": "cache"}}`},
+		{name: "deployments response with meta missing items", schema: DeploymentsResponse, document: `{"meta": {"dataSource": "cache"}}`, wantErr: "items field is required"},
+		{name: "valid features response", schema: FeaturesResponse, document: `{"ListDeployments": true}`},
+		{name: "invalid features response", schema: FeaturesResponse, document: `{"ListDeployments": "yes"}`, wantErr: "ListDeployments"},
+		{name: "valid log level", schema: LogLevel, document: `{"verbosity": 5}`},
+		{name: "valid feature switch", schema: FeatureSwitch, document: `{"serving": false}`},
+		{name: "feature switch missing serving", schema: FeatureSwitch, document: `{}`, wantErr: "serving field is required"},
+		{name: "valid feature statuses", schema: FeatureStatuses, document: `[{"name": "Search", "enabled": true, "serving": false}]`},
+		{name: "valid error", schema: Error, document: `{"type": "about:blank", "title": "Teapot", "status": 418, "message": "oops"}`},
+		{name: "error without the problem members", schema: Error, document: `{"message": "oops"}`, wantErr: "type field is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.schema, []byte(tt.document))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	var handlerBody string
+	handler := ValidateRequest(ReplicasRequest, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		handlerBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    int
+		wantMessage string
+	}{
+		{name: "valid body is passed to the handler", body: `{"replicas": 3}`, wantCode: http.StatusOK},
+		{name: "unknown field", body: `{"replikas": 3}`, wantCode: http.StatusBadRequest, wantMessage: `Validation error: unknown field "replikas"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlerBody = ""
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(tt.body)))
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status code %d, got %d", tt.wantCode, w.Code)
+			}
+			if tt.wantCode == http.StatusOK && handlerBody != tt.body {
+				t.Errorf("expected the handler to read the body %q, got %q", tt.body, handlerBody)
+			}
+			if tt.wantMessage != "" {
+				var response struct {
+					Message string `json:"message"`
+				}
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if response.Message != tt.wantMessage {
+					t.Errorf("expected message %q, got %q", tt.wantMessage, response.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	handler := ValidateResponse(ReplicasResponse, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "not found"}`))
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != `{"message": "not found"}` {
+		t.Errorf("expected the response to be passed through, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"", true},
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"text/csv; charset=utf-8", false},
+		{"application/yaml", false},
+	}
+	for _, tt := range tests {
+		if got := isJSON(tt.contentType); got != tt.want {
+			t.Errorf("isJSON(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Schema is the subset of JSON Schema used to describe the request and response bodies of the API
+type Schema struct {
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	// AdditionalProperties is either a boolean, or the schema of the properties not listed in Properties
+	AdditionalProperties *AdditionalProperties `json:"additionalProperties,omitempty"`
+	Items                *Schema               `json:"items,omitempty"`
+	Minimum              *float64              `json:"minimum,omitempty"`
+	Nullable             bool                  `json:"nullable,omitempty"`
+	// AnyOf lists alternative schemas, of which the value must match at least one, e.g. for opt-in response shapes
+	AnyOf []*Schema `json:"anyOf,omitempty"`
+}
+
+// AdditionalProperties is the additionalProperties keyword of a schema, which is either a boolean or a schema
+type AdditionalProperties struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *AdditionalProperties) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	a.Schema = &Schema{}
+	return json.Unmarshal(data, a.Schema)
+}
+
+// validationError is a single mismatch between a document and its schema
+type validationError struct {
+	path         string
+	message      string
+	unknownField bool
+}
+
+func (e validationError) Error() string {
+	if e.unknownField {
+		return fmt.Sprintf("unknown field %q", e.path)
+	}
+	if e.path == "" {
+		return e.message
+	}
+	return fmt.Sprintf("%s field %s", e.path, e.message)
+}
+
+// validate validates the decoded JSON value found at path against the schema
+func (s *Schema) validate(path string, value interface{}) []validationError {
+	invalid := func(format string, args ...interface{}) []validationError {
+		return []validationError{{path: path, message: fmt.Sprintf(format, args...)}}
+	}
+
+	if value == nil {
+		if s.Nullable {
+			return nil
+		}
+		return invalid("must not be null")
+	}
+
+	if len(s.AnyOf) > 0 {
+		// Report the errors of the first alternative of the same type as the value, or else of the first alternative
+		var errs []validationError
+		sameType := false
+		for _, alternative := range s.AnyOf {
+			altErrs := alternative.validate(path, value)
+			if len(altErrs) == 0 {
+				return nil
+			}
+			if errs == nil || (!sameType && alternative.Type == typeOf(value)) {
+				errs, sameType = altErrs, alternative.Type == typeOf(value)
+			}
+		}
+		return errs
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid("must be an object")
+		}
+		return s.validateObject(path, object)
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return invalid("must be an array")
+		}
+		var errs []validationError
+		if s.Items != nil {
+			for i, item := range array {
+				errs = append(errs, s.Items.validate(join(path, fmt.Sprint(i)), item)...)
+			}
+		}
+		return errs
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok {
+			return invalid("must be a number")
+		}
+		if s.Type == "integer" && number != math.Trunc(number) {
+			return invalid("must be an integer")
+		}
+		if s.Format == "int32" && (number > math.MaxInt32 || number < math.MinInt32) {
+			return invalid("must be a 32 bit integer")
+		}
+		if s.Minimum != nil && number < *s.Minimum {
+			return invalid("must be greater than or equal to %v", *s.Minimum)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return invalid("must be a string")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid("must be a boolean")
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(path string, object map[string]interface{}) []validationError {
+	var errs []validationError
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			errs = append(errs, validationError{path: join(path, name), message: "is required"})
+		}
+	}
+
+	// Iterate in a stable order, so that the errors are reported consistently
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, ok := s.Properties[name]; ok {
+			errs = append(errs, property.validate(join(path, name), object[name])...)
+			continue
+		}
+		switch {
+		case s.AdditionalProperties == nil:
+			// Additional properties are allowed by default
+		case !s.AdditionalProperties.Allowed:
+			errs = append(errs, validationError{path: join(path, name), unknownField: true})
+		case s.AdditionalProperties.Schema != nil:
+			errs = append(errs, s.AdditionalProperties.Schema.validate(join(path, name), object[name])...)
+		}
+	}
+	return errs
+}
+
+// join appends a field name or array index to a dotted field path
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// typeOf returns the JSON Schema type of the decoded JSON value, telling integers apart from other numbers
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+// Package search matches the cached objects against a free text query, on their names, labels and annotations, and
+// ranks the results by how well they match.
+package search
+
+import (
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The scores of the fields matching the query. The name of an object is what a user most likely types, so it ranks
+// above its labels, which rank above its annotations.
+const (
+	scoreExactName   = 100
+	scoreNamePrefix  = 75
+	scoreName        = 50
+	scoreLabels      = 20
+	scoreAnnotations = 10
+)
+
+// Result is an object matching a query
+type Result struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Score is how well the object matches the query, the higher the better
+	Score int `json:"score"`
+	// Matches are the fields of the object matching the query, e.g. name, labels.app or annotations.owner
+	Matches []string `json:"matches"`
+}
+
+// Match returns whether the object matches the query, case insensitively, on its name, or the key or value of any of
+// its labels and annotations, along with the result to report it as. The last applied configuration annotation is
+// left out, since it holds the whole object and would match most queries.
+func Match(query, kind string, obj metav1.Object) (Result, bool) {
+	query = strings.ToLower(query)
+	result := Result{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName(), Matches: []string{}}
+
+	name := strings.ToLower(obj.GetName())
+	switch {
+	case name == query:
+		result.Score = scoreExactName
+	case strings.HasPrefix(name, query):
+		result.Score = scoreNamePrefix
+	case strings.Contains(name, query):
+		result.Score = scoreName
+	}
+	if result.Score > 0 {
+		result.Matches = append(result.Matches, "name")
+	}
+	if keys := matchingKeys(query, obj.GetLabels(), ""); len(keys) > 0 {
+		result.Score += scoreLabels
+		for _, key := range keys {
+			result.Matches = append(result.Matches, "labels."+key)
+		}
+	}
+	if keys := matchingKeys(query, obj.GetAnnotations(), corev1.LastAppliedConfigAnnotation); len(keys) > 0 {
+		result.Score += scoreAnnotations
+		for _, key := range keys {
+			result.Matches = append(result.Matches, "annotations."+key)
+		}
+	}
+	return result, result.Score > 0
+}
+
+// matchingKeys returns the sorted keys of the entries whose key or value contains the lower cased query, but the
+// skipped one
+func matchingKeys(query string, entries map[string]string, skip string) []string {
+	var keys []string
+	for key, value := range entries {
+		if key == skip {
+			continue
+		}
+		if strings.Contains(strings.ToLower(key), query) || strings.Contains(strings.ToLower(value), query) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Rank sorts the results from the best match, then by kind, namespace and name
+func Rank(results []Result) {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+}
@@ -0,0 +1,68 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatch(t *testing.T) {
+	obj := &metav1.ObjectMeta{
+		Namespace: "team-a",
+		Name:      "Shop-Web",
+		Labels:    map[string]string{"app.kubernetes.io/name": "shop", "tier": "frontend"},
+		Annotations: map[string]string{
+			"owner":                            "shop-team",
+			corev1.LastAppliedConfigAnnotation: `{"metadata":{"name":"shop-web"}}`,
+		},
+	}
+	tests := []struct {
+		name     string
+		query    string
+		expected *Result
+	}{
+		{"Test Exact Name", "shop-web", &Result{Score: 100, Matches: []string{"name"}}},
+		{"Test Name Prefix", "SHOP", &Result{Score: 105, Matches: []string{"name", "labels.app.kubernetes.io/name", "annotations.owner"}}},
+		{"Test Name Substring", "web", &Result{Score: 50, Matches: []string{"name"}}},
+		{"Test Label Key", "tier", &Result{Score: 20, Matches: []string{"labels.tier"}}},
+		{"Test Label Value", "front", &Result{Score: 20, Matches: []string{"labels.tier"}}},
+		{"Test Annotation", "team", &Result{Score: 10, Matches: []string{"annotations.owner"}}},
+		// The last applied configuration holds the whole object, so it isn't searched
+		{"Test Last Applied Configuration", "metadata", nil},
+		{"Test No Match", "blog", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := Match(tt.query, "Deployment", obj)
+			if ok != (tt.expected != nil) {
+				t.Fatalf("Match() matched = %v, want %v", ok, tt.expected != nil)
+			}
+			if !ok {
+				return
+			}
+			tt.expected.Kind, tt.expected.Namespace, tt.expected.Name = "Deployment", "team-a", "Shop-Web"
+			if !reflect.DeepEqual(result, *tt.expected) {
+				t.Errorf("Match() = %+v, want %+v", result, *tt.expected)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	results := []Result{
+		{Kind: "Deployment", Namespace: "b", Name: "web", Score: 50},
+		{Kind: "Deployment", Namespace: "a", Name: "web", Score: 50},
+		{Kind: "Deployment", Namespace: "b", Name: "shop", Score: 100},
+		{Kind: "Deployment", Namespace: "a", Name: "api", Score: 20},
+	}
+	Rank(results)
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Namespace+"/"+r.Name)
+	}
+	if expected := []string{"b/shop", "a/web", "b/web", "a/api"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Rank() = %v, want %v", names, expected)
+	}
+}
@@ -0,0 +1,94 @@
+// Package securityheaders adds the standard security headers to the responses, e.g. Strict-Transport-Security and
+// X-Content-Type-Options, along with a Cache-Control: no-store to the responses which don't set their own caching
+// policy, so that the responses holding cluster data aren't kept by browsers and intermediaries.
+package securityheaders
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// StrictTransportSecurity is only sent over TLS, browsers ignoring it otherwise
+const StrictTransportSecurity = "Strict-Transport-Security"
+
+// Headers are the security headers added to the responses, by canonical name. The headers of an empty value are left
+// out.
+type Headers map[string]string
+
+// Default returns the headers added by default
+func Default() Headers {
+	return Headers{
+		StrictTransportSecurity:   "max-age=31536000; includeSubDomains",
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'",
+		"Referrer-Policy":         "no-referrer",
+		"Cache-Control":           "no-store",
+	}
+}
+
+// Set overrides a header from a Name=Value string, e.g. Strict-Transport-Security=max-age=63072000. An empty value
+// disables the header.
+func (h Headers) Set(override string) error {
+	name, value, ok := strings.Cut(override, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t:") {
+		return fmt.Errorf("security header %q must be Name=Value", override)
+	}
+	h[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+	return nil
+}
+
+// String returns the headers as a comma separated list of Name=Value pairs, sorted by name
+func (h Headers) String() string {
+	pairs := make([]string, 0, len(h))
+	for name, value := range h {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// Middleware returns a new http.Handler adding the headers to the responses of the provided handler, right before
+// they are written. The headers the handler set itself are kept, e.g. the Cache-Control of the cacheable responses.
+func (h Headers) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&writer{ResponseWriter: w, headers: h, tls: r.TLS != nil}, r)
+	})
+}
+
+// writer adds the headers to the response right before they are written
+type writer struct {
+	http.ResponseWriter
+	headers     Headers
+	tls         bool
+	wroteHeader bool
+}
+
+func (w *writer) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		header := w.Header()
+		for name, value := range w.headers {
+			if value == "" || (name == StrictTransportSecurity && !w.tls) || header.Get(name) != "" {
+				continue
+			}
+			header.Set(name, value)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so that http.ResponseController can reach it
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,89 @@
+package securityheaders
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeaders_Middleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides []string
+		tls       bool
+		// cacheControl is the Cache-Control set by the handler, if any
+		cacheControl string
+		expected     map[string]string
+	}{
+		{
+			name: "defaults over TLS",
+			tls:  true,
+			expected: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"X-Content-Type-Options":    "nosniff",
+				"Cache-Control":             "no-store",
+			},
+		},
+		{
+			name:     "no HSTS without TLS",
+			expected: map[string]string{"Strict-Transport-Security": "", "X-Content-Type-Options": "nosniff"},
+		},
+		{
+			name:         "cacheable response",
+			tls:          true,
+			cacheControl: "private, max-age=5",
+			expected:     map[string]string{"Cache-Control": "private, max-age=5"},
+		},
+		{
+			name:      "overridden",
+			overrides: []string{"strict-transport-security=max-age=63072000; includeSubDomains; preload", "Permissions-Policy=camera=()"},
+			tls:       true,
+			expected: map[string]string{
+				"Strict-Transport-Security": "max-age=63072000; includeSubDomains; preload",
+				"Permissions-Policy":        "camera=()",
+			},
+		},
+		{
+			name:      "disabled",
+			overrides: []string{"X-Frame-Options="},
+			tls:       true,
+			expected:  map[string]string{"X-Frame-Options": "", "Referrer-Policy": "no-referrer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := Default()
+			for _, override := range tt.overrides {
+				if err := headers.Set(override); err != nil {
+					t.Fatalf("Set() error = %v", err)
+				}
+			}
+			handler := headers.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.cacheControl != "" {
+					w.Header().Set("Cache-Control", tt.cacheControl)
+				}
+				_, _ = w.Write([]byte("{}"))
+			}))
+			r := httptest.NewRequest("GET", "/deployments", nil)
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			for name, value := range tt.expected {
+				if got := w.Header().Get(name); got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestHeaders_Set_Invalid(t *testing.T) {
+	for _, override := range []string{"nosniff", "=nosniff", "X Frame=DENY"} {
+		if err := Default().Set(override); err == nil {
+			t.Errorf("Set(%q) error = nil, want an error", override)
+		}
+	}
+}
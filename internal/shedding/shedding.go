@@ -0,0 +1,148 @@
+// Package shedding sheds the requests of the lowest priorities first when the server is overloaded, similarly to the
+// API Priority and Fairness of the Kubernetes API server, so that the probes and the small reads stay responsive when
+// a client floods the expensive endpoints.
+package shedding
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/problem"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Priorities of the requests, from the highest to the lowest
+const (
+	// PriorityHealth is the priority of the probes, which are never shed
+	PriorityHealth = "health"
+	// PriorityReads is the priority of the reads of a single namespace or object
+	PriorityReads = "reads"
+	// PriorityWrites is the priority of the mutating requests
+	PriorityWrites = "writes"
+	// PriorityExpensive is the priority of the requests fanning out to all the namespaces, e.g. cluster-wide lists
+	PriorityExpensive = "expensive"
+)
+
+// DefaultExpensiveRoutes are the routes fanning out to all the namespaces of the cluster
+var DefaultExpensiveRoutes = []string{"GET /deployments", "GET /apps", "GET /search", "GET /images", "GET /insights/scheduling", "GET /audit"}
+
+// healthRoutes are the routes of the probes
+var healthRoutes = []string{"/healthz", "/healthz/history", "/readyz", "/startupz"}
+
+// shares are the shares of the in-flight limit the requests of each priority may use, so that the lower priorities
+// are shed first: the expensive requests once half of the limit is in use, and the writes at 80%. The probes aren't
+// limited, nor counted.
+var shares = map[string]float64{
+	PriorityReads:     1,
+	PriorityWrites:    0.8,
+	PriorityExpensive: 0.5,
+}
+
+var (
+	inflightRequests = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_api_proxy_inflight_requests",
+		Help: "Number of requests being served, by priority",
+	}, []string{"priority"})
+	shedRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_shed_requests_total",
+		Help: "Number of requests rejected with a 429 because the server was overloaded, by priority",
+	}, []string{"priority"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(inflightRequests, shedRequestsTotal)
+}
+
+// Shedder limits the number of requests served at once, shedding the requests of the lowest priorities first
+type Shedder struct {
+	mux       *http.ServeMux
+	limit     int
+	expensive []string
+
+	mu       sync.Mutex
+	inflight int
+}
+
+// New returns a new Shedder serving at most limit requests at once, out of which the requests are classified by the
+// route of the mux they match. The expensive routes are the patterns of the routes of the lowest priority, as
+// registered on the mux, e.g. "GET /deployments".
+func New(mux *http.ServeMux, limit int, expensive []string) (*Shedder, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("invalid in-flight limit %d, must be positive", limit)
+	}
+	return &Shedder{mux: mux, limit: limit, expensive: expensive}, nil
+}
+
+// Classify returns the priority of the request, by the route of the mux it matches. The requests to the versioned API
+// are classified by their unversioned route.
+func (s *Shedder) Classify(r *http.Request) string {
+	lookup := r
+	if path := strings.TrimPrefix(r.URL.Path, apiversion.Prefix); path != r.URL.Path && strings.HasPrefix(path, "/") {
+		lookup = &http.Request{Method: r.Method, Host: r.Host, URL: &url.URL{Path: path}}
+	}
+	_, pattern := s.mux.Handler(lookup)
+	switch {
+	case slices.Contains(healthRoutes, pattern):
+		return PriorityHealth
+	case slices.Contains(s.expensive, pattern):
+		return PriorityExpensive
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		return PriorityReads
+	default:
+		return PriorityWrites
+	}
+}
+
+// acquire returns whether a request of the priority may be served, counting it in flight if so
+func (s *Shedder) acquire(priority string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if float64(s.inflight) >= shares[priority]*float64(s.limit) {
+		return false
+	}
+	s.inflight++
+	return true
+}
+
+// release removes a request from the requests in flight
+func (s *Shedder) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inflight--
+}
+
+// Middleware returns a new http.Handler which rejects the requests with a 429 Too Many Requests once the share of the
+// in-flight limit of their priority is in use, and passes the others to the provided handler
+func (s *Shedder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priority := s.Classify(r)
+		if priority == PriorityHealth {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !s.acquire(priority) {
+			shedRequestsTotal.WithLabelValues(priority).Inc()
+			logger := klog.FromContext(r.Context())
+			logger.V(2).Info("Request shed", "priority", priority, "method", r.Method, "path", r.URL.Path)
+			w.Header().Set("Retry-After", "1")
+			message := fmt.Sprintf("The server is overloaded and sheds the %s requests, retry later", priority)
+			if encErr := problem.Write(w, http.StatusTooManyRequests, message); encErr != nil {
+				logger.Error(encErr, "Error encoding response")
+			}
+			return
+		}
+		inflightRequests.WithLabelValues(priority).Inc()
+		defer func() {
+			s.release()
+			inflightRequests.WithLabelValues(priority).Dec()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,110 @@
+package shedding
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/apiversion"
+)
+
+func newMux(handler http.HandlerFunc) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, pattern := range []string{"/healthz", "/readyz", "GET /deployments", "GET /deployments/{namespace}/{deployment}", "PUT /deployments/{namespace}/{deployment}/replicas"} {
+		mux.HandleFunc(pattern, handler)
+	}
+	mux.Handle(apiversion.Prefix+"/", apiversion.Handler(mux))
+	return mux
+}
+
+func TestShedder_Classify(t *testing.T) {
+	s, err := New(newMux(func(w http.ResponseWriter, r *http.Request) {}), 10, DefaultExpensiveRoutes)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		method   string
+		url      string
+		expected string
+	}{
+		{http.MethodGet, "/readyz", PriorityHealth},
+		{http.MethodGet, "/deployments/foo/bar", PriorityReads},
+		{http.MethodGet, "/v1/deployments/foo/bar", PriorityReads},
+		{http.MethodGet, "/unknown", PriorityReads},
+		{http.MethodPut, "/deployments/foo/bar/replicas", PriorityWrites},
+		{http.MethodGet, "/deployments", PriorityExpensive},
+		{http.MethodGet, "/v1/deployments?limit=10", PriorityExpensive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			if got := s.Classify(httptest.NewRequest(tt.method, tt.url, nil)); got != tt.expected {
+				t.Errorf("Classify() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestShedder_Middleware(t *testing.T) {
+	// The requests with ?block=true stay in flight until released
+	release := make(chan struct{})
+	var started, done sync.WaitGroup
+	s, err := New(newMux(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("block") == "true" {
+			started.Done()
+			<-release
+		}
+	}), 10, DefaultExpensiveRoutes)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	server := s.Middleware(s.mux)
+	block := func(method, url string, count int) {
+		for i := 0; i < count; i++ {
+			started.Add(1)
+			done.Add(1)
+			go func() {
+				defer done.Done()
+				server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, url+"?block=true", nil))
+			}()
+			started.Wait()
+		}
+	}
+
+	tests := []struct {
+		name string
+		// inflight are the requests put in flight before the test, in addition to the ones of the previous tests
+		inflight func()
+		method   string
+		url      string
+		expected int
+	}{
+		{name: "expensive requests are served", method: http.MethodGet, url: "/deployments", expected: http.StatusOK},
+		{name: "expensive requests are shed first, at half of the limit", inflight: func() { block(http.MethodGet, "/deployments", 5) },
+			method: http.MethodGet, url: "/v1/deployments", expected: http.StatusTooManyRequests},
+		{name: "writes are served below 80% of the limit", method: http.MethodPut, url: "/deployments/foo/bar/replicas", expected: http.StatusOK},
+		{name: "writes are shed at 80% of the limit", inflight: func() { block(http.MethodPut, "/deployments/foo/bar/replicas", 3) },
+			method: http.MethodPut, url: "/deployments/foo/bar/replicas", expected: http.StatusTooManyRequests},
+		{name: "reads are served below the limit", method: http.MethodGet, url: "/deployments/foo/bar", expected: http.StatusOK},
+		{name: "reads are shed at the limit", inflight: func() { block(http.MethodGet, "/deployments/foo/bar", 2) },
+			method: http.MethodGet, url: "/deployments/foo/bar", expected: http.StatusTooManyRequests},
+		{name: "probes are never shed", method: http.MethodGet, url: "/healthz", expected: http.StatusOK},
+		{name: "requests are served again once the others complete", inflight: func() { close(release); done.Wait() },
+			method: http.MethodGet, url: "/deployments", expected: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.inflight != nil {
+				tt.inflight()
+			}
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, nil))
+			if w.Code != tt.expected {
+				t.Fatalf("status code = %v, want %v", w.Code, tt.expected)
+			}
+			if tt.expected == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Errorf("expected a Retry-After header")
+			}
+		})
+	}
+}
@@ -0,0 +1,452 @@
+// Package slo tracks the service level objectives of the API routes: the availability and latency of the requests are
+// measured against their objectives, from which the remaining error budgets and the burn rates are computed, and the
+// fast burns are alerted on.
+package slo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/statusrecorder"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// defaultWindow is the compliance window of the objectives, unless configured otherwise
+	defaultWindow = 30 * 24 * time.Hour
+	// defaultFastBurnRate is the burn rate alerted on, unless configured otherwise: at this rate, 2% of a 30 days error
+	// budget is spent within an hour
+	defaultFastBurnRate = 14.4
+	// evaluateInterval is how often the gauges are updated and the alerts evaluated
+	evaluateInterval = 30 * time.Second
+	// bucketWidth is the resolution the requests are counted at
+	bucketWidth = time.Minute
+)
+
+// Results of the requests, as counted against the objectives
+const (
+	ResultGood = "good"
+	ResultBad  = "bad"
+)
+
+// BurnRateWindows are the windows the burn rates are computed over. A fast burn is alerted on when both the long and
+// the short window burn faster than the threshold, so that the alert fires quickly but also resolves quickly once
+// the errors stop.
+var BurnRateWindows = []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour}
+
+const (
+	fastBurnLongWindow  = time.Hour
+	fastBurnShortWindow = 5 * time.Minute
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_slo_requests_total",
+		Help: "Number of requests counted against the service level objectives, by objective and result (good or bad)",
+	}, []string{"slo", "result"})
+	errorBudgetRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_api_proxy_slo_error_budget_remaining",
+		Help: "Ratio of the error budget of the service level objectives which remains over their window, negative once exhausted",
+	}, []string{"slo"})
+	burnRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_api_proxy_slo_burn_rate",
+		Help: "Rate at which the error budget of the service level objectives is spent, by objective and window, 1 spending exactly the budget over the window of the objective",
+	}, []string{"slo", "window"})
+	fastBurning = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_api_proxy_slo_fast_burn",
+		Help: "Whether the error budget of the service level objectives is burning fast, i.e. whether their fast burn alert is firing",
+	}, []string{"slo"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(requestsTotal, errorBudgetRemaining, burnRate, fastBurning)
+}
+
+// ObjectiveConfig is a service level objective of some routes
+type ObjectiveConfig struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Routes are the patterns of the routes the objective covers, as registered on the mux, e.g.
+	// "GET /deployments/{namespace}/{deployment}", in which * matches any characters, e.g. "GET *" or
+	// "* /v1/namespaces/*". All routes are covered when empty.
+	Routes []string `json:"routes,omitempty"`
+	// Objective is the percentage of good requests, e.g. 99.9
+	Objective float64 `json:"objective"`
+	// Latency is the duration above which the requests are bad, even when successful, e.g. 500ms. Only the server errors
+	// are bad when empty.
+	Latency string `json:"latency,omitempty"`
+	// Window is the compliance window the error budget is spent over. Defaults to 720h (30 days).
+	Window string `json:"window,omitempty"`
+}
+
+// Config is the service level objectives configuration file
+type Config struct {
+	Objectives []ObjectiveConfig `json:"objectives"`
+	// FastBurnRate is the burn rate above which the fast burn alerts fire. Defaults to 14.4.
+	FastBurnRate float64 `json:"fastBurnRate,omitempty"`
+}
+
+// Status is the status of a service level objective, as of its last evaluation
+type Status struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Routes      []string `json:"routes,omitempty"`
+	Objective   float64  `json:"objective"`
+	Latency     string   `json:"latency,omitempty"`
+	Window      string   `json:"window"`
+	// Requests and BadRequests are counted over the window, since the instance started
+	Requests    int64 `json:"requests"`
+	BadRequests int64 `json:"badRequests"`
+	// ErrorBudgetRemaining is the ratio of the error budget which remains, negative once the budget is exhausted
+	ErrorBudgetRemaining float64 `json:"errorBudgetRemaining"`
+	// BurnRates are the burn rates by window, e.g. "1h"
+	BurnRates map[string]float64 `json:"burnRates"`
+	// FastBurnSince is when the fast burn alert fired, while it's firing
+	FastBurnSince *time.Time `json:"fastBurnSince,omitempty"`
+}
+
+// bucket counts the requests of a minute
+type bucket struct {
+	minute     int64
+	total, bad int64
+}
+
+// objective is a configured service level objective, along with the counts of its requests
+type objective struct {
+	config  ObjectiveConfig
+	target  float64
+	latency time.Duration
+	window  time.Duration
+	// buckets is a ring of the counts of the requests of the last minutes of the window
+	buckets       []bucket
+	fastBurnSince time.Time
+}
+
+// covers returns whether the objective covers the route
+func (o *objective) covers(route string) bool {
+	if len(o.config.Routes) == 0 {
+		return true
+	}
+	for _, pattern := range o.config.Routes {
+		if matchRoute(pattern, route) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRoute returns whether the route matches the pattern, in which * matches any sequence of characters, slashes
+// included
+func matchRoute(pattern, route string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(route, parts[0]) {
+		return false
+	}
+	route = route[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 {
+			return strings.HasSuffix(route, part)
+		}
+		index := strings.Index(route, part)
+		if index < 0 {
+			return false
+		}
+		route = route[index+len(part):]
+	}
+	return route == ""
+}
+
+// record counts a request in the bucket of its minute
+func (o *objective) record(t time.Time, bad bool) {
+	minute := t.Unix() / int64(bucketWidth/time.Second)
+	b := &o.buckets[minute%int64(len(o.buckets))]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.total++
+	if bad {
+		b.bad++
+	}
+}
+
+// counts returns the number of requests and bad requests of the given duration up to the given time
+func (o *objective) counts(t time.Time, d time.Duration) (total, bad int64) {
+	last := t.Unix() / int64(bucketWidth/time.Second)
+	first := last - int64(d/bucketWidth) + 1
+	for _, b := range o.buckets {
+		if b.minute >= first && b.minute <= last {
+			total += b.total
+			bad += b.bad
+		}
+	}
+	return total, bad
+}
+
+// burnRate returns the rate the error budget was spent at over the given duration up to the given time, which is 0
+// when there were no requests
+func (o *objective) burnRate(t time.Time, d time.Duration) float64 {
+	total, bad := o.counts(t, d)
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total) / (1 - o.target)
+}
+
+// Tracker counts the requests against the service level objectives. The counts are kept in memory by every instance,
+// covering the requests it served since it started.
+type Tracker struct {
+	// Notifier is sent the fast burn alerts as they fire and resolve. When nil, no notifications are sent.
+	Notifier     Notifier
+	fastBurnRate float64
+	hostname     string
+	now          func() time.Time
+
+	mu         sync.Mutex
+	objectives []*objective
+}
+
+// New returns a new Tracker from the given configuration
+func New(config Config) (*Tracker, error) {
+	t := &Tracker{fastBurnRate: defaultFastBurnRate, now: time.Now}
+	if config.FastBurnRate < 0 {
+		return nil, fmt.Errorf("invalid fast burn rate %v, must be positive", config.FastBurnRate)
+	} else if config.FastBurnRate > 0 {
+		t.fastBurnRate = config.FastBurnRate
+	}
+	if len(config.Objectives) == 0 {
+		return nil, errors.New("at least one objective must be configured")
+	}
+	names := map[string]bool{}
+	for _, oc := range config.Objectives {
+		if oc.Name == "" {
+			return nil, errors.New("the name of every objective is required")
+		} else if names[oc.Name] {
+			return nil, fmt.Errorf("duplicate objective %q", oc.Name)
+		}
+		names[oc.Name] = true
+		o, err := newObjective(oc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid objective %q: %w", oc.Name, err)
+		}
+		t.objectives = append(t.objectives, o)
+	}
+	var err error
+	if t.hostname, err = os.Hostname(); err != nil {
+		t.hostname = ""
+	}
+	return t, nil
+}
+
+func newObjective(config ObjectiveConfig) (*objective, error) {
+	if config.Objective <= 0 || config.Objective >= 100 {
+		return nil, fmt.Errorf("invalid objective %v, must be a percentage between 0 and 100, exclusive", config.Objective)
+	}
+	o := &objective{config: config, target: config.Objective / 100, window: defaultWindow}
+	if config.Window != "" {
+		window, err := time.ParseDuration(config.Window)
+		// The window must hold the longest burn rate window
+		if err != nil || window < BurnRateWindows[len(BurnRateWindows)-1] {
+			return nil, fmt.Errorf("invalid window %q, must be a duration of at least 6h", config.Window)
+		}
+		o.window = window.Truncate(bucketWidth)
+	}
+	o.config.Window = o.window.String()
+	if config.Latency != "" {
+		latency, err := time.ParseDuration(config.Latency)
+		if err != nil || latency <= 0 {
+			return nil, fmt.Errorf("invalid latency %q, must be a positive duration", config.Latency)
+		}
+		o.latency = latency
+	}
+	for _, pattern := range config.Routes {
+		if pattern == "" {
+			return nil, errors.New("invalid empty route pattern")
+		}
+	}
+	o.buckets = make([]bucket, o.window/bucketWidth)
+	return o, nil
+}
+
+// LoadFile returns a new Tracker from the given YAML or JSON configuration file
+func LoadFile(path string) (*Tracker, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SLO file: %w", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse SLO file %s: %w", path, err)
+	}
+	return New(config)
+}
+
+// Record counts a request of the route against the objectives covering it. The server errors are bad, as well as the
+// requests slower than the latency of the objective.
+func (t *Tracker) Record(route string, status int, duration time.Duration) {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, o := range t.objectives {
+		if !o.covers(route) {
+			continue
+		}
+		bad := status >= 500 || (o.latency > 0 && duration > o.latency)
+		o.record(now, bad)
+		result := ResultGood
+		if bad {
+			result = ResultBad
+		}
+		requestsTotal.WithLabelValues(o.config.Name, result).Inc()
+	}
+}
+
+// Middleware returns a new http.Handler which counts the requests served by the provided handler against the
+// objectives. The requests are counted under the pattern of the endpoint they matched, so the handler must pass the
+// request on to the mux unchanged. The requests which didn't match any endpoint aren't counted.
+func (t *Tracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusrecorder.Recorder{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		// The mux sets the pattern of the matched endpoint on the request
+		if r.Pattern == "" {
+			return
+		}
+		status := sw.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		t.Record(r.Pattern, status, time.Since(start))
+	})
+}
+
+// Statuses returns the statuses of the objectives, in the order of the configuration
+func (t *Tracker) Statuses() []Status {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	statuses := make([]Status, 0, len(t.objectives))
+	for _, o := range t.objectives {
+		statuses = append(statuses, o.status(now))
+	}
+	return statuses
+}
+
+// status returns the status of the objective at the given time
+func (o *objective) status(now time.Time) Status {
+	s := Status{
+		Name:                 o.config.Name,
+		Description:          o.config.Description,
+		Routes:               slices.Clone(o.config.Routes),
+		Objective:            o.config.Objective,
+		Latency:              o.config.Latency,
+		Window:               o.config.Window,
+		ErrorBudgetRemaining: 1,
+		BurnRates:            make(map[string]float64, len(BurnRateWindows)),
+	}
+	s.Requests, s.BadRequests = o.counts(now, o.window)
+	if s.Requests > 0 {
+		s.ErrorBudgetRemaining = 1 - float64(s.BadRequests)/float64(s.Requests)/(1-o.target)
+	}
+	for _, d := range BurnRateWindows {
+		s.BurnRates[windowLabel(d)] = o.burnRate(now, d)
+	}
+	if !o.fastBurnSince.IsZero() {
+		since := o.fastBurnSince
+		s.FastBurnSince = &since
+	}
+	return s
+}
+
+// windowLabel returns the label of a burn rate window, e.g. "5m" or "1h"
+func windowLabel(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	}
+	return strconv.Itoa(int(d/time.Minute)) + "m"
+}
+
+// Run evaluates the objectives periodically until the context is cancelled, updating the metrics and notifying the
+// fast burn alerts as they fire and resolve
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(evaluateInterval)
+	defer ticker.Stop()
+	for {
+		t.evaluate(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// evaluate updates the metrics of the objectives, and fires or resolves their fast burn alerts
+func (t *Tracker) evaluate(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	now := t.now()
+	var events []Event
+	t.mu.Lock()
+	for _, o := range t.objectives {
+		burning := o.burnRate(now, fastBurnLongWindow) > t.fastBurnRate && o.burnRate(now, fastBurnShortWindow) > t.fastBurnRate
+		switch {
+		case burning && o.fastBurnSince.IsZero():
+			o.fastBurnSince = now
+			events = append(events, Event{Status: StatusFiring, Alert: t.alert(o.status(now))})
+		case !burning && !o.fastBurnSince.IsZero():
+			status := o.status(now)
+			o.fastBurnSince = time.Time{}
+			events = append(events, Event{Status: StatusResolved, Alert: t.alert(status)})
+		}
+		status := o.status(now)
+		errorBudgetRemaining.WithLabelValues(o.config.Name).Set(status.ErrorBudgetRemaining)
+		for window, rate := range status.BurnRates {
+			burnRate.WithLabelValues(o.config.Name, window).Set(rate)
+		}
+		if burning {
+			fastBurning.WithLabelValues(o.config.Name).Set(1)
+		} else {
+			fastBurning.WithLabelValues(o.config.Name).Set(0)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, event := range events {
+		if event.Status == StatusFiring {
+			logger.Info("Error budget is burning fast", "slo", event.Alert.SLO, "burnRates", event.Alert.BurnRates)
+		} else {
+			logger.Info("Error budget is no longer burning fast", "slo", event.Alert.SLO)
+		}
+		if t.Notifier == nil {
+			continue
+		}
+		if err := t.Notifier.Notify(ctx, event); err != nil {
+			logger.Error(err, "Error sending SLO alert notification", "slo", event.Alert.SLO, "status", event.Status)
+		}
+	}
+}
+
+// alert returns the fast burn alert of the objective
+func (t *Tracker) alert(status Status) Alert {
+	return Alert{
+		SLO:                  status.Name,
+		Instance:             t.hostname,
+		Objective:            status.Objective,
+		Threshold:            t.fastBurnRate,
+		BurnRates:            status.BurnRates,
+		ErrorBudgetRemaining: status.ErrorBudgetRemaining,
+		Since:                status.FastBurnSince,
+	}
+}
@@ -0,0 +1,286 @@
+package slo
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 5, 1, 10, 0, 30, 0, time.UTC)
+
+// recordingNotifier records the events it's sent
+type recordingNotifier struct {
+	events []Event
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, event Event) error {
+	n.events = append(n.events, event)
+	return nil
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"Test No Objectives", Config{}},
+		{"Test Missing Name", Config{Objectives: []ObjectiveConfig{{Objective: 99.9}}}},
+		{"Test Duplicate Name", Config{Objectives: []ObjectiveConfig{{Name: "a", Objective: 99.9}, {Name: "a", Objective: 99}}}},
+		{"Test Objective Of 100", Config{Objectives: []ObjectiveConfig{{Name: "a", Objective: 100}}}},
+		{"Test Missing Objective", Config{Objectives: []ObjectiveConfig{{Name: "a"}}}},
+		{"Test Invalid Latency", Config{Objectives: []ObjectiveConfig{{Name: "a", Objective: 99.9, Latency: "fast"}}}},
+		{"Test Window Too Short", Config{Objectives: []ObjectiveConfig{{Name: "a", Objective: 99.9, Window: "1h"}}}},
+		{"Test Invalid Route Pattern", Config{Objectives: []ObjectiveConfig{{Name: "a", Objective: 99.9, Routes: []string{""}}}}},
+		{"Test Negative Fast Burn Rate", Config{Objectives: []ObjectiveConfig{{Name: "a", Objective: 99.9}}, FastBurnRate: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Errorf("New() expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slo.yaml")
+	config := "objectives:\n  - name: reads\n    routes: [\"GET /*\"]\n    objective: 99.9\n    latency: 500ms\n    window: 168h\nfastBurnRate: 10\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tracker, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if len(tracker.objectives) != 1 || tracker.fastBurnRate != 10 || tracker.objectives[0].window != 168*time.Hour {
+		t.Errorf("LoadFile() = %+v", tracker)
+	}
+
+	if err := os.WriteFile(path, []byte("objectives:\n  - name: reads\n    target: 99.9\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Errorf("LoadFile() expected an error for an unknown field")
+	}
+}
+
+func TestTracker_Statuses(t *testing.T) {
+	tracker, err := New(Config{Objectives: []ObjectiveConfig{
+		{Name: "availability", Objective: 99},
+		{Name: "reads", Routes: []string{"GET /*"}, Objective: 90, Latency: "500ms", Window: "24h"},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	current := now
+	tracker.now = func() time.Time { return current }
+
+	// Two hours ago: 100 successful writes
+	current = now.Add(-2 * time.Hour)
+	for i := 0; i < 100; i++ {
+		tracker.Record("POST /deployments", http.StatusOK, 10*time.Millisecond)
+	}
+	// Now: 8 fast reads, a slow read, and a failed read
+	current = now
+	for i := 0; i < 8; i++ {
+		tracker.Record("GET /deployments", http.StatusOK, 10*time.Millisecond)
+	}
+	tracker.Record("GET /pods", http.StatusOK, time.Second)
+	tracker.Record("GET /pods", http.StatusBadGateway, 10*time.Millisecond)
+	// A week later, the requests of the reads objective are out of its window
+	statuses := tracker.Statuses()
+	current = now.Add(7 * 24 * time.Hour)
+	later := tracker.Statuses()
+
+	tests := []struct {
+		name                 string
+		status               Status
+		requests, bad        int64
+		errorBudgetRemaining float64
+		burnRates            map[string]float64
+	}{
+		{
+			name:   "availability",
+			status: statuses[0],
+			// Only the failed read is bad: 1 of 110 requests against a 1% budget
+			requests: 110, bad: 1, errorBudgetRemaining: 1 - (1.0/110)/0.01,
+			burnRates: map[string]float64{"5m": 10, "30m": 10, "1h": 10, "6h": (1.0 / 110) / 0.01},
+		},
+		{
+			name:   "reads",
+			status: statuses[1],
+			// The slow read is bad too: 2 of 10 requests against a 10% budget
+			requests: 10, bad: 2, errorBudgetRemaining: -1,
+			burnRates: map[string]float64{"5m": 2, "30m": 2, "1h": 2, "6h": 2},
+		},
+		{
+			name:   "reads without requests",
+			status: later[1],
+			// Without requests, the whole budget remains
+			requests: 0, bad: 0, errorBudgetRemaining: 1,
+			burnRates: map[string]float64{"5m": 0, "30m": 0, "1h": 0, "6h": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.status.Requests != tt.requests || tt.status.BadRequests != tt.bad {
+				t.Errorf("requests = %d, bad requests = %d, want %d and %d", tt.status.Requests, tt.status.BadRequests, tt.requests, tt.bad)
+			}
+			if !approximately(tt.status.ErrorBudgetRemaining, tt.errorBudgetRemaining) {
+				t.Errorf("error budget remaining = %v, want %v", tt.status.ErrorBudgetRemaining, tt.errorBudgetRemaining)
+			}
+			for window, expected := range tt.burnRates {
+				if !approximately(tt.status.BurnRates[window], expected) {
+					t.Errorf("burn rate over %s = %v, want %v", window, tt.status.BurnRates[window], expected)
+				}
+			}
+		})
+	}
+}
+
+func TestTracker_Middleware(t *testing.T) {
+	tracker, err := New(Config{Objectives: []ObjectiveConfig{{Name: "availability", Objective: 99.9}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("GET /fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	handler := tracker.Middleware(mux)
+	for _, url := range []string{"/ok", "/ok", "/fail", "/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+
+	// The unmatched request isn't counted
+	status := tracker.Statuses()[0]
+	if status.Requests != 3 || status.BadRequests != 1 {
+		t.Errorf("requests = %d, bad requests = %d, want 3 and 1", status.Requests, status.BadRequests)
+	}
+}
+
+func TestTracker_Evaluate(t *testing.T) {
+	tracker, err := New(Config{Objectives: []ObjectiveConfig{{Name: "availability", Objective: 99}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	notifier := &recordingNotifier{}
+	tracker.Notifier = notifier
+	current := now
+	tracker.now = func() time.Time { return current }
+	ctx := context.Background()
+
+	// A 5% error rate burns the 1% budget 5 times too fast, below the threshold
+	for i := 0; i < 100; i++ {
+		status := http.StatusOK
+		if i%20 == 0 {
+			status = http.StatusInternalServerError
+		}
+		tracker.Record("GET /deployments", status, time.Millisecond)
+	}
+	tracker.evaluate(ctx)
+	if len(notifier.events) != 0 {
+		t.Fatalf("expected no notification, got %+v", notifier.events)
+	}
+
+	// A 50% error rate burns it 50 times too fast: the alert fires, once
+	for i := 0; i < 100; i++ {
+		tracker.Record("GET /deployments", http.StatusServiceUnavailable, time.Millisecond)
+	}
+	tracker.evaluate(ctx)
+	tracker.evaluate(ctx)
+	if len(notifier.events) != 1 || notifier.events[0].Status != StatusFiring || notifier.events[0].Alert.SLO != "availability" {
+		t.Fatalf("expected a single firing notification, got %+v", notifier.events)
+	}
+	if status := tracker.Statuses()[0]; status.FastBurnSince == nil || !status.FastBurnSince.Equal(now) {
+		t.Errorf("FastBurnSince = %v, want %v", status.FastBurnSince, now)
+	}
+
+	// Once the errors stop, the short window resolves the alert, while the long one still burns fast
+	current = now.Add(10 * time.Minute)
+	for i := 0; i < 10; i++ {
+		tracker.Record("GET /deployments", http.StatusOK, time.Millisecond)
+	}
+	tracker.evaluate(ctx)
+	if len(notifier.events) != 2 || notifier.events[1].Status != StatusResolved {
+		t.Fatalf("expected a resolved notification, got %+v", notifier.events)
+	}
+	if notifier.events[1].Alert.Since == nil || !notifier.events[1].Alert.Since.Equal(now) {
+		t.Errorf("resolved alert since = %v, want %v", notifier.events[1].Alert.Since, now)
+	}
+	if status := tracker.Statuses()[0]; status.FastBurnSince != nil || status.BurnRates["1h"] <= tracker.fastBurnRate {
+		t.Errorf("Statuses() = %+v, want a resolved alert with a fast burn over 1h", status)
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		route    string
+		expected bool
+	}{
+		{"GET /deployments", "GET /deployments", true},
+		{"GET /deployments", "GET /deployments/{namespace}/{deployment}", false},
+		{"GET *", "GET /deployments/{namespace}/{deployment}", true},
+		{"GET *", "POST /apply", false},
+		{"* /v1/*", "PUT /v1/deployments/{namespace}/{deployment}/replicas", true},
+		{"* /v1/*", "PUT /deployments/{namespace}/{deployment}/replicas", false},
+		{"*/replicas", "PUT /deployments/{namespace}/{deployment}/replicas", true},
+		{"GET */{deployment}*", "GET /deployments/{namespace}/{deployment}/health", true},
+		{"GET */{deployment}*", "GET /deployments/{namespace}", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.route, func(t *testing.T) {
+			if got := matchRoute(tt.pattern, tt.route); got != tt.expected {
+				t.Errorf("matchRoute() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	event := Event{Status: StatusFiring, Alert: Alert{SLO: "availability", Objective: 99.9, Threshold: 14.4, BurnRates: map[string]float64{"1h": 20}}}
+
+	tests := []struct {
+		name          string
+		status        int
+		expectedError bool
+	}{
+		{name: "success", status: http.StatusNoContent},
+		{name: "failure", status: http.StatusBadGateway, expectedError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received Event
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("failed to decode webhook body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := (&WebhookNotifier{URL: server.URL}).Notify(context.Background(), event)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Notify() error = %v, expectedError %v", err, tt.expectedError)
+			}
+			if received.Status != StatusFiring || received.Alert.SLO != "availability" || received.Alert.BurnRates["1h"] != 20 {
+				t.Errorf("webhook received %+v", received)
+			}
+		})
+	}
+}
+
+// approximately returns whether the floats are equal, give or take rounding errors
+func approximately(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,143 @@
+// Package snapshots keeps copies of the specs of deployments in the state store, which may later be restored as a
+// quick undo. Unlike the rollbacks of the ReplicaSet history, snapshots hold the whole spec, including the replicas
+// and the strategy, and are taken on demand.
+package snapshots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/klog/v2"
+)
+
+// DefaultMaxPerDeployment is how many snapshots are kept per deployment, unless configured otherwise
+const DefaultMaxPerDeployment = 10
+
+// ErrNotFound is returned for the snapshots which don't exist, or were taken of another deployment
+var ErrNotFound = errors.New("snapshot not found")
+
+// Summary describes a snapshot, without its spec
+type Summary struct {
+	ID        string    `json:"id"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+	Replicas  *int32    `json:"replicas"`
+	// Images are the images of the containers of the snapshot, by container name
+	Images map[string]string `json:"images"`
+}
+
+// Snapshot is the spec of a deployment, as it was when the snapshot was taken
+type Snapshot struct {
+	Summary
+	Spec appsv1.DeploymentSpec `json:"spec"`
+}
+
+// Manager takes, lists and retrieves the snapshots of deployments
+type Manager struct {
+	mu        sync.Mutex
+	max       int
+	snapshots store.Typed[Snapshot]
+	now       func() time.Time
+}
+
+// New returns a new Manager keeping up to max snapshots per deployment in the given store, the oldest ones being
+// removed first. It defaults to DefaultMaxPerDeployment when max isn't positive.
+func New(s store.Store, max int) *Manager {
+	if max <= 0 {
+		max = DefaultMaxPerDeployment
+	}
+	return &Manager{max: max, snapshots: store.Typed[Snapshot]{Store: s}, now: time.Now}
+}
+
+// Create takes a snapshot of the spec of the deployment on behalf of the given identity
+func (m *Manager) Create(ctx context.Context, identity string, d *appsv1.Deployment) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	images := make(map[string]string, len(d.Spec.Template.Spec.Containers))
+	for _, c := range d.Spec.Template.Spec.Containers {
+		images[c.Name] = c.Image
+	}
+	snapshot := Snapshot{
+		Summary: Summary{
+			ID:        uuid.NewString(),
+			Namespace: d.Namespace,
+			Name:      d.Name,
+			CreatedAt: m.now().UTC(),
+			CreatedBy: identity,
+			Replicas:  d.Spec.Replicas,
+			Images:    images,
+		},
+		Spec: *d.Spec.DeepCopy(),
+	}
+	if err := m.snapshots.Put(ctx, snapshot.ID, snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	// The oldest snapshots of the deployment beyond the limit are removed, so that the store doesn't grow unbounded
+	existing, err := m.list(ctx, d.Namespace, d.Name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for _, old := range existing[min(m.max, len(existing)):] {
+		if err := m.snapshots.Delete(ctx, old.ID); err != nil {
+			klog.FromContext(ctx).Error(err, "Error removing old snapshot", "snapshot", old.ID)
+		}
+	}
+	return snapshot, nil
+}
+
+// Get returns the snapshot of the deployment with the given ID
+func (m *Manager) Get(ctx context.Context, namespace, name, id string) (Snapshot, error) {
+	snapshot, found, err := m.snapshots.Get(ctx, id)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if !found || snapshot.Namespace != namespace || snapshot.Name != name {
+		return Snapshot{}, ErrNotFound
+	}
+	return snapshot, nil
+}
+
+// List returns the summaries of the snapshots of the deployment, newest first
+func (m *Manager) List(ctx context.Context, namespace, name string) ([]Summary, error) {
+	snapshots, err := m.list(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]Summary, 0, len(snapshots))
+	for _, s := range snapshots {
+		summaries = append(summaries, s.Summary)
+	}
+	return summaries, nil
+}
+
+// list returns the snapshots of the deployment, newest first
+func (m *Manager) list(ctx context.Context, namespace, name string) ([]Snapshot, error) {
+	all, err := m.snapshots.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	var snapshots []Snapshot
+	for _, s := range all {
+		if s.Namespace == namespace && s.Name == name {
+			snapshots = append(snapshots, s)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].CreatedAt.Equal(snapshots[j].CreatedAt) {
+			return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+		}
+		return snapshots[i].ID < snapshots[j].ID
+	})
+	return snapshots, nil
+}
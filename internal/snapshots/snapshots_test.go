@@ -0,0 +1,75 @@
+package snapshots
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/store"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func newDeployment(name string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "foo"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(replicas),
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:v1"}}}},
+		},
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	m := New(store.NewMemory(), 2)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+
+	var ids []string
+	for replicas := int32(1); replicas <= 3; replicas++ {
+		snapshot, err := m.Create(ctx, "alice", newDeployment("bar", replicas))
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		ids = append(ids, snapshot.ID)
+	}
+	if _, err := m.Create(ctx, "alice", newDeployment("baz", 1)); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Only the 2 newest snapshots of the deployment are kept
+	summaries, err := m.List(ctx, "foo", "bar")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(summaries) != 2 || summaries[0].ID != ids[2] || summaries[1].ID != ids[1] {
+		t.Fatalf("List() = %+v, want the snapshots %s and %s", summaries, ids[2], ids[1])
+	}
+	if *summaries[0].Replicas != 3 || summaries[0].Images["app"] != "app:v1" || summaries[0].CreatedBy != "alice" {
+		t.Errorf("List() = %+v, want the replicas, images and creator of the deployment", summaries[0])
+	}
+
+	snapshot, err := m.Get(ctx, "foo", "bar", ids[1])
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if *snapshot.Spec.Replicas != 2 {
+		t.Errorf("Get() replicas = %d, want 2", *snapshot.Spec.Replicas)
+	}
+	for _, id := range []string{ids[0], "missing"} {
+		if _, err := m.Get(ctx, "foo", "bar", id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%s) error = %v, want %v", id, err, ErrNotFound)
+		}
+	}
+	// Snapshots of other deployments aren't found
+	if _, err := m.Get(ctx, "foo", "baz", ids[2]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, ErrNotFound)
+	}
+}
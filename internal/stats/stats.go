@@ -0,0 +1,216 @@
+package stats
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/statusrecorder"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// UnmatchedRoute is the route of the requests which didn't match any endpoint
+	UnmatchedRoute = "unmatched"
+	// OtherIdentities groups the requests of new identities once the maximum number of series is reached, so that
+	// clients with ever changing identities can't grow the memory usage unbounded
+	OtherIdentities = "other"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_api_proxy_http_requests_total",
+		Help: "Number of requests served, by route, client identity and status class",
+	}, []string{"route", "identity", "code"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k8s_api_proxy_http_request_duration_seconds",
+		Help:    "Duration of the requests served, by route and client identity, with the trace ID of the requests as exemplars",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "identity"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(requestsTotal, requestDuration)
+}
+
+// Latency holds latency percentiles, in milliseconds
+type Latency struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// Entry is the statistics of the requests of a single identity to a single route
+type Entry struct {
+	Identity string `json:"identity"`
+	Route    string `json:"route"`
+	Count    int64  `json:"count"`
+	// Statuses counts the responses by status class, e.g. "2xx"
+	Statuses map[string]int64 `json:"statuses"`
+	// Latency is computed over the most recent requests only, as kept by the recorder's window
+	Latency  Latency   `json:"latencyMs"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+type seriesKey struct {
+	identity, route string
+}
+
+// series holds the statistics of a single identity and route. Latencies are kept in a ring buffer.
+type series struct {
+	count     int64
+	statuses  map[string]int64
+	latencies []time.Duration
+	next      int
+	lastSeen  time.Time
+}
+
+// Recorder keeps per-identity, per-route request statistics in memory
+type Recorder struct {
+	mu        sync.Mutex
+	window    int
+	maxSeries int
+	since     time.Time
+	series    map[seriesKey]*series
+	now       func() time.Time
+}
+
+// NewRecorder returns a new Recorder, computing the latency percentiles over the given number of most recent requests
+// of every identity and route, and tracking at most the given number of identity and route pairs
+func NewRecorder(window, maxSeries int) *Recorder {
+	return &Recorder{
+		window:    max(window, 1),
+		maxSeries: maxSeries,
+		since:     time.Now(),
+		series:    map[seriesKey]*series{},
+		now:       time.Now,
+	}
+}
+
+// Record records a request of the given identity to the given route, and returns the identity it was recorded under,
+// which is OtherIdentities once the maximum number of series is reached
+func (rec *Recorder) Record(identity, route string, status int, duration time.Duration) string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	key := seriesKey{identity: identity, route: route}
+	s, ok := rec.series[key]
+	if !ok && len(rec.series) >= rec.maxSeries {
+		key.identity = OtherIdentities
+		s, ok = rec.series[key]
+	}
+	if !ok {
+		s = &series{statuses: map[string]int64{}}
+		rec.series[key] = s
+	}
+	s.count++
+	s.statuses[statusClass(status)]++
+	if len(s.latencies) < rec.window {
+		s.latencies = append(s.latencies, duration)
+	} else {
+		s.latencies[s.next] = duration
+	}
+	s.next = (s.next + 1) % rec.window
+	s.lastSeen = rec.now()
+	return key.identity
+}
+
+// Since returns when the recorder started recording
+func (rec *Recorder) Since() time.Time {
+	return rec.since
+}
+
+// Window returns the number of most recent requests the latency percentiles are computed over
+func (rec *Recorder) Window() int {
+	return rec.window
+}
+
+// Entries returns the statistics of every identity and route, from the most to the least requested
+func (rec *Recorder) Entries() []Entry {
+	rec.mu.Lock()
+	entries := make([]Entry, 0, len(rec.series))
+	for key, s := range rec.series {
+		statuses := make(map[string]int64, len(s.statuses))
+		for class, count := range s.statuses {
+			statuses[class] = count
+		}
+		entries = append(entries, Entry{
+			Identity: key.identity,
+			Route:    key.route,
+			Count:    s.count,
+			Statuses: statuses,
+			Latency:  percentiles(slices.Clone(s.latencies)),
+			LastSeen: s.lastSeen,
+		})
+	}
+	rec.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		if entries[i].Identity != entries[j].Identity {
+			return entries[i].Identity < entries[j].Identity
+		}
+		return entries[i].Route < entries[j].Route
+	})
+	return entries
+}
+
+// percentiles returns the latency percentiles of the given samples, using the nearest-rank method
+func percentiles(samples []time.Duration) Latency {
+	if len(samples) == 0 {
+		return Latency{}
+	}
+	slices.Sort(samples)
+	rank := func(p float64) float64 {
+		i := int(p*float64(len(samples))+0.5) - 1
+		return milliseconds(samples[min(max(i, 0), len(samples)-1)])
+	}
+	return Latency{P50: rank(0.5), P90: rank(0.9), P99: rank(0.99), Max: milliseconds(samples[len(samples)-1])}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func statusClass(status int) string {
+	return string(rune('0'+status/100)) + "xx"
+}
+
+// Middleware returns a new http.Handler which records the requests served by the provided mux, and counts them in the
+// Prometheus metrics. Requests are recorded under the pattern of the endpoint they matched, and the ones part of a
+// trace (as per their traceparent header) are the exemplars of the duration histogram.
+func (rec *Recorder) Middleware(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusrecorder.Recorder{ResponseWriter: w}
+		mux.ServeHTTP(sw, r)
+
+		// The mux sets the pattern of the matched endpoint on the request
+		route := r.Pattern
+		if route == "" {
+			route = UnmatchedRoute
+		}
+		status := sw.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		duration := time.Since(start)
+		// The identities of the metrics are capped along with the series of the recorder
+		identity := rec.Record(auth.Identity(r), route, status, duration)
+		requestsTotal.WithLabelValues(route, identity, statusClass(status)).Inc()
+		observer := requestDuration.WithLabelValues(route, identity)
+		if traceID, ok := logging.TraceID(r); ok {
+			observer.(prometheus.ExemplarObserver).ObserveWithExemplar(duration.Seconds(), prometheus.Labels{"trace_id": traceID})
+		} else {
+			observer.Observe(duration.Seconds())
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder(100, 10)
+	for i := 1; i <= 100; i++ {
+		rec.Record("ci-bot", "GET /deployments", http.StatusOK, time.Duration(i)*time.Millisecond)
+	}
+	rec.Record("ci-bot", "GET /deployments", http.StatusInternalServerError, time.Second)
+	rec.Record("portal", "PUT /deployments/{namespace}/{deployment}/replicas", http.StatusConflict, 5*time.Millisecond)
+
+	entries := rec.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries() = %+v, want 2 entries", entries)
+	}
+	// The most requested route comes first
+	first := entries[0]
+	if first.Identity != "ci-bot" || first.Count != 101 {
+		t.Errorf("first entry = %+v, want the 101 requests of ci-bot", first)
+	}
+	if expected := map[string]int64{"2xx": 100, "5xx": 1}; !reflect.DeepEqual(first.Statuses, expected) {
+		t.Errorf("Statuses = %v, want %v", first.Statuses, expected)
+	}
+	// The window only holds the last 100 requests, so the 1ms request was overwritten
+	if expected := (Latency{P50: 51, P90: 91, P99: 100, Max: 1000}); first.Latency != expected {
+		t.Errorf("Latency = %+v, want %+v", first.Latency, expected)
+	}
+	if expected := (Latency{P50: 5, P90: 5, P99: 5, Max: 5}); entries[1].Latency != expected {
+		t.Errorf("Latency = %+v, want %+v", entries[1].Latency, expected)
+	}
+}
+
+func TestRecorderMaxSeries(t *testing.T) {
+	rec := NewRecorder(10, 2)
+	for _, identity := range []string{"a", "b", "c", "d", "a"} {
+		rec.Record(identity, "GET /deployments", http.StatusOK, time.Millisecond)
+	}
+	counts := map[string]int64{}
+	for _, entry := range rec.Entries() {
+		counts[entry.Identity] = entry.Count
+	}
+	if expected := map[string]int64{"a": 2, "b": 1, OtherIdentities: 2}; !reflect.DeepEqual(counts, expected) {
+		t.Errorf("counts = %v, want %v", counts, expected)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	rec := NewRecorder(10, 10)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /deployments/{namespace}/{deployment}/replicas", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := rec.Middleware(mux)
+
+	r := httptest.NewRequest(http.MethodGet, "/deployments/foo/bar/replicas", nil)
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "portal"}}}}}
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	entries := rec.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries() = %+v, want 2 entries", entries)
+	}
+	routes := map[string]Entry{}
+	for _, entry := range entries {
+		routes[entry.Route] = entry
+	}
+	matched := routes["GET /deployments/{namespace}/{deployment}/replicas"]
+	if matched.Identity != "portal" || matched.Statuses["4xx"] != 1 {
+		t.Errorf("matched entry = %+v, want a 4xx of portal", matched)
+	}
+	if _, ok := routes[UnmatchedRoute]; !ok {
+		t.Errorf("entries = %+v, want the unknown path to be recorded as %s", entries, UnmatchedRoute)
+	}
+}
+
+func TestMiddleware_Exemplars(t *testing.T) {
+	rec := NewRecorder(10, 10)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /apps", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := rec.Middleware(mux)
+
+	r := httptest.NewRequest(http.MethodGet, "/apps", nil)
+	r.Header.Set(logging.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	metric := &dto.Metric{}
+	if err := requestDuration.WithLabelValues("GET /apps", "").(prometheus.Metric).Write(metric); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	var traceIDs []string
+	for _, bucket := range metric.GetHistogram().GetBucket() {
+		for _, label := range bucket.GetExemplar().GetLabel() {
+			if label.GetName() == "trace_id" {
+				traceIDs = append(traceIDs, label.GetValue())
+			}
+		}
+	}
+	if len(traceIDs) != 1 || traceIDs[0] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("exemplar trace IDs = %v, want the trace ID of the request", traceIDs)
+	}
+	if count := testutil.ToFloat64(requestsTotal.WithLabelValues("GET /apps", "", "2xx")); count != 1 {
+		t.Errorf("requests total = %v, want 1", count)
+	}
+}
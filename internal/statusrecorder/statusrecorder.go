@@ -0,0 +1,31 @@
+// Package statusrecorder records the status code of the responses passed through the middlewares, e.g. to count them
+// by status.
+package statusrecorder
+
+import "net/http"
+
+// Recorder passes the response through, while keeping its status code
+type Recorder struct {
+	http.ResponseWriter
+	// Status is the status code of the response, or 0 until it's written
+	Status int
+}
+
+func (r *Recorder) WriteHeader(status int) {
+	if r.Status == 0 {
+		r.Status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so that http.ResponseController can reach it
+func (r *Recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
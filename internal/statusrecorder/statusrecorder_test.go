@@ -0,0 +1,47 @@
+package statusrecorder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		expectedStatus int
+	}{
+		{name: "nothing written", handler: func(w http.ResponseWriter, r *http.Request) {}, expectedStatus: 0},
+		{name: "implicit status", handler: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}, expectedStatus: http.StatusOK},
+		{name: "first status written", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("not found"))
+		}, expectedStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &Recorder{ResponseWriter: httptest.NewRecorder()}
+			tt.handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Status != tt.expectedStatus {
+				t.Errorf("Status = %d, want %d", rec.Status, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestRecorder_Unwrap(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := &Recorder{ResponseWriter: w}
+
+	// The wrapped writer is reached through the recorder, e.g. to flush it
+	if err := http.NewResponseController(rec).Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if !w.Flushed {
+		t.Errorf("the wrapped writer wasn't flushed")
+	}
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigMap keeps the values in a ConfigMap, holding one value per key. Keys must be valid ConfigMap keys.
+type ConfigMap struct {
+	// Client must read directly from the API server, so that the values saved by other replicas are visible
+	Client    client.Client
+	Namespace string
+	Name      string
+}
+
+// Get returns the value of the given key, and whether it was found
+func (s *ConfigMap) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := s.data(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := data[key]
+	return []byte(value), ok, nil
+}
+
+// Put creates or updates the value of the given key, creating the ConfigMap if needed
+func (s *ConfigMap) Put(ctx context.Context, key string, value []byte) error {
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return fmt.Errorf("invalid key %q: %s", key, strings.Join(errs, ", "))
+	}
+	return s.modify(ctx, func(data map[string]string) { data[key] = string(value) })
+}
+
+// Delete removes the given key from the ConfigMap
+func (s *ConfigMap) Delete(ctx context.Context, key string) error {
+	return s.modify(ctx, func(data map[string]string) { delete(data, key) })
+}
+
+// List returns all the values held by the ConfigMap
+func (s *ConfigMap) List(ctx context.Context) (map[string][]byte, error) {
+	data, err := s.data(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte, len(data))
+	for key, value := range data {
+		values[key] = []byte(value)
+	}
+	return values, nil
+}
+
+// data returns the data of the ConfigMap, which is empty if it doesn't exist yet
+func (s *ConfigMap) data(ctx context.Context) (map[string]string, error) {
+	cm := &corev1.ConfigMap{}
+	err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: s.Name}, cm)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap %s/%s: %w", s.Namespace, s.Name, err)
+	}
+	return cm.Data, nil
+}
+
+// modify applies the given change to the data of the ConfigMap, retrying on conflicts with other replicas
+func (s *ConfigMap) modify(ctx context.Context, change func(data map[string]string)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: s.Name}, cm)
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: s.Namespace, Name: s.Name}, Data: map[string]string{}}
+			change(cm.Data)
+			return s.Client.Create(ctx, cm)
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		change(cm.Data)
+		return s.Client.Update(ctx, cm)
+	})
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RecordGVK is the kind of the custom resources holding the records of the CRD store, as defined in
+// helm/crds/records.yaml
+var RecordGVK = schema.GroupVersionKind{Group: "go-k8s-http-api.io", Version: "v1alpha1", Kind: "Record"}
+
+// BucketLabel is the label holding the bucket of a record
+const BucketLabel = "go-k8s-http-api.io/bucket"
+
+// CRD keeps every value in its own Record custom resource, so that the size of a bucket isn't limited by the size of
+// a single object. Records are named after the hash of their key, which is kept in the record.
+type CRD struct {
+	// Client must read directly from the API server, so that the values saved by other replicas are visible
+	Client    client.Client
+	Namespace string
+	Bucket    string
+}
+
+// Get returns the value of the given key, and whether it was found
+func (s *CRD) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	record := newRecord()
+	err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: s.recordName(key)}, record)
+	if apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get record %s: %w", key, err)
+	}
+	value, _, _ := unstructured.NestedString(record.Object, "spec", "value")
+	return []byte(value), true, nil
+}
+
+// Put creates or updates the record of the given key
+func (s *CRD) Put(ctx context.Context, key string, value []byte) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		record := newRecord()
+		err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: s.recordName(key)}, record)
+		if apierrors.IsNotFound(err) {
+			record = newRecord()
+			record.SetNamespace(s.Namespace)
+			record.SetName(s.recordName(key))
+			record.SetLabels(map[string]string{BucketLabel: s.Bucket})
+			record.Object["spec"] = map[string]any{"key": key, "value": string(value)}
+			return s.Client.Create(ctx, record)
+		}
+		if err != nil {
+			return err
+		}
+		record.Object["spec"] = map[string]any{"key": key, "value": string(value)}
+		return s.Client.Update(ctx, record)
+	})
+}
+
+// Delete removes the record of the given key
+func (s *CRD) Delete(ctx context.Context, key string) error {
+	record := newRecord()
+	record.SetNamespace(s.Namespace)
+	record.SetName(s.recordName(key))
+	return client.IgnoreNotFound(s.Client.Delete(ctx, record))
+}
+
+// List returns all the values of the bucket
+func (s *CRD) List(ctx context.Context) (map[string][]byte, error) {
+	records := &unstructured.UnstructuredList{}
+	records.SetGroupVersionKind(RecordGVK.GroupVersion().WithKind(RecordGVK.Kind + "List"))
+	if err := s.Client.List(ctx, records, client.InNamespace(s.Namespace), client.MatchingLabels{BucketLabel: s.Bucket}); err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+	values := make(map[string][]byte, len(records.Items))
+	for _, record := range records.Items {
+		key, _, _ := unstructured.NestedString(record.Object, "spec", "key")
+		value, _, _ := unstructured.NestedString(record.Object, "spec", "value")
+		values[key] = []byte(value)
+	}
+	return values, nil
+}
+
+// recordName returns the name of the record of the given key, which is prefixed with the bucket so that the records
+// of a bucket are easy to tell apart
+func (s *CRD) recordName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return s.Bucket + "-" + hex.EncodeToString(hash[:8])
+}
+
+func newRecord() *unstructured.Unstructured {
+	record := &unstructured.Unstructured{}
+	record.SetGroupVersionKind(RecordGVK)
+	return record
+}
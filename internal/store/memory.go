@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Memory keeps the values in the memory of the process
+type Memory struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+// NewMemory returns an empty Memory store
+func NewMemory() *Memory {
+	return &Memory{values: map[string][]byte{}}
+}
+
+// Get returns the value of the given key, and whether it was found
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.values[key]
+	return slices.Clone(value), ok, nil
+}
+
+// Put creates or updates the value of the given key
+func (m *Memory) Put(_ context.Context, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = slices.Clone(value)
+	return nil
+}
+
+// Delete removes the given key
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// List returns all the values, by key
+func (m *Memory) List(_ context.Context) (map[string][]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	values := make(map[string][]byte, len(m.values))
+	for key, value := range m.values {
+		values[key] = slices.Clone(value)
+	}
+	return values, nil
+}
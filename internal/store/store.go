@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Backends of the store, set via the --store flag
+const (
+	// BackendMemory keeps the state in the memory of the process, so it's lost on restarts and isn't shared between
+	// replicas
+	BackendMemory = "memory"
+	// BackendConfigMap keeps the state of each bucket in a ConfigMap, which is limited to 1MiB
+	BackendConfigMap = "configmap"
+	// BackendCRD keeps every record in a Record custom resource, which requires the CRD to be installed
+	BackendCRD = "crd"
+)
+
+// Store is a key-value store of the state owned by the server (e.g. async operations), shared by all the subsystems
+// needing persistence. Each subsystem uses its own bucket, i.e. its own Store.
+type Store interface {
+	// Get returns the value of the given key, and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Put creates or updates the value of the given key
+	Put(ctx context.Context, key string, value []byte) error
+	// Delete removes the given key. Removing a missing key isn't an error.
+	Delete(ctx context.Context, key string) error
+	// List returns all the values, by key
+	List(ctx context.Context) (map[string][]byte, error)
+}
+
+// ValidateBackend validates the given backend
+func ValidateBackend(backend string) error {
+	switch backend {
+	case BackendMemory, BackendConfigMap, BackendCRD:
+		return nil
+	default:
+		return fmt.Errorf("invalid store backend %q, must be one of %s, %s or %s", backend, BackendMemory, BackendConfigMap, BackendCRD)
+	}
+}
+
+// New returns a store of the given backend for the given bucket. The client must read directly from the API server,
+// so that the state saved by other replicas is visible. It's unused by the memory backend.
+func New(backend string, c client.Client, namespace, bucket string) (Store, error) {
+	if errs := validation.IsDNS1123Label(bucket); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid bucket name %q: %v", bucket, errs)
+	}
+	switch backend {
+	case BackendMemory:
+		return NewMemory(), nil
+	case BackendConfigMap:
+		return &ConfigMap{Client: c, Namespace: namespace, Name: bucket}, nil
+	case BackendCRD:
+		return &CRD{Client: c, Namespace: namespace, Bucket: bucket}, nil
+	default:
+		return nil, ValidateBackend(backend)
+	}
+}
+
+// Typed stores values of type T, encoded as JSON
+type Typed[T any] struct {
+	Store Store
+}
+
+// Get returns the value of the given key, and whether it was found
+func (t Typed[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	var value T
+	encoded, found, err := t.Store.Get(ctx, key)
+	if err != nil || !found {
+		return value, false, err
+	}
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		return value, false, fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// Put creates or updates the value of the given key
+func (t Typed[T]) Put(ctx context.Context, key string, value T) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	return t.Store.Put(ctx, key, encoded)
+}
+
+// Delete removes the given key
+func (t Typed[T]) Delete(ctx context.Context, key string) error {
+	return t.Store.Delete(ctx, key)
+}
+
+// List returns all the values, by key
+func (t Typed[T]) List(ctx context.Context) (map[string]T, error) {
+	encoded, err := t.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]T, len(encoded))
+	for key, raw := range encoded {
+		var value T
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", key, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeClient() *fake.ClientBuilder {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(RecordGVK, meta.RESTScopeNamespace)
+	return fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper)
+}
+
+// TestStores runs the same scenario against every backend
+func TestStores(t *testing.T) {
+	for _, backend := range []string{BackendMemory, BackendConfigMap, BackendCRD} {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			c := newFakeClient().Build()
+			s, err := New(backend, c, "default", "operations")
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			// Another bucket of the same backend doesn't see the values of the first one
+			other, err := New(backend, c, "default", "schedules")
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if _, found, err := s.Get(ctx, "a"); err != nil || found {
+				t.Fatalf("Get() of a missing key = %v, %v, want not found", found, err)
+			}
+			if err := s.Put(ctx, "a", []byte(`{"n":1}`)); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if err := s.Put(ctx, "b", []byte(`{"n":2}`)); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if err := s.Put(ctx, "a", []byte(`{"n":3}`)); err != nil {
+				t.Fatalf("Put() of an existing key error = %v", err)
+			}
+			if err := other.Put(ctx, "c", []byte(`{}`)); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+
+			value, found, err := s.Get(ctx, "a")
+			if err != nil || !found || string(value) != `{"n":3}` {
+				t.Errorf("Get() = %s, %v, %v, want the updated value", value, found, err)
+			}
+			if err := s.Delete(ctx, "b"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := s.Delete(ctx, "missing"); err != nil {
+				t.Fatalf("Delete() of a missing key error = %v", err)
+			}
+
+			values, err := s.List(ctx)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if expected := map[string][]byte{"a": []byte(`{"n":3}`)}; !reflect.DeepEqual(values, expected) {
+				t.Errorf("List() = %s, want %s", values, expected)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("etcd", nil, "default", "operations"); err == nil {
+		t.Errorf("expected an error for an unknown backend")
+	}
+	if _, err := New(BackendMemory, nil, "default", "Not_A_Label"); err == nil {
+		t.Errorf("expected an error for an invalid bucket name")
+	}
+}
+
+func TestTyped(t *testing.T) {
+	type record struct {
+		N int `json:"n"`
+	}
+	ctx := context.Background()
+	typed := Typed[record]{Store: NewMemory()}
+	if err := typed.Put(ctx, "a", record{N: 1}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got, found, err := typed.Get(ctx, "a")
+	if err != nil || !found || got.N != 1 {
+		t.Errorf("Get() = %+v, %v, %v, want the stored record", got, found, err)
+	}
+	if err := typed.Store.Put(ctx, "corrupt", []byte("{")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := typed.List(ctx); err == nil {
+		t.Errorf("expected an error listing a corrupt record")
+	}
+}
@@ -0,0 +1,121 @@
+package tenancy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// AllNamespaces grants a tenant access to all namespaces, e.g. for platform administrators
+const AllNamespaces = "*"
+
+// Tenant maps a set of client identities to the namespaces they may access
+type Tenant struct {
+	Name       string   `json:"name"`
+	Identities []string `json:"identities"`
+	Namespaces []string `json:"namespaces"`
+}
+
+// Config is the tenants configuration file
+type Config struct {
+	Tenants []Tenant `json:"tenants"`
+}
+
+// Scope is the set of namespaces a client may access
+type Scope struct {
+	// All is set if the client may access all namespaces
+	All        bool
+	Namespaces map[string]bool
+}
+
+// Allows returns whether the scope includes the given namespace
+func (s Scope) Allows(namespace string) bool {
+	return s.All || s.Namespaces[namespace]
+}
+
+// Tenancy resolves the scope of client identities
+type Tenancy struct {
+	scopes map[string]Scope
+}
+
+// New returns a new Tenancy from the given configuration. An identity listed by multiple tenants may access the
+// namespaces of all of them.
+func New(config Config) (*Tenancy, error) {
+	scopes := map[string]Scope{}
+	for _, tenant := range config.Tenants {
+		if tenant.Name == "" {
+			return nil, fmt.Errorf("tenant name is required")
+		}
+		for _, identity := range tenant.Identities {
+			scope, ok := scopes[identity]
+			if !ok {
+				scope = Scope{Namespaces: map[string]bool{}}
+			}
+			for _, namespace := range tenant.Namespaces {
+				if namespace == AllNamespaces {
+					scope.All = true
+					continue
+				}
+				scope.Namespaces[namespace] = true
+			}
+			scopes[identity] = scope
+		}
+	}
+	return &Tenancy{scopes: scopes}, nil
+}
+
+// LoadFile returns a new Tenancy from the given YAML or JSON configuration file
+func LoadFile(path string) (*Tenancy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants file: %w", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants file %s: %w", path, err)
+	}
+	return New(config)
+}
+
+// ScopeOf returns the scope of the given identity. Identities which aren't part of any tenant may not access any namespace.
+func (t *Tenancy) ScopeOf(identity string) Scope {
+	if scope, ok := t.scopes[identity]; ok {
+		return scope
+	}
+	return Scope{}
+}
+
+type scopeKey struct{}
+
+// WithScope returns a copy of the context holding the given scope
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom returns the scope held by the context. It returns false if tenancy isn't enabled.
+func ScopeFrom(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	return scope, ok
+}
+
+// Middleware returns a new http.HandlerFunc that resolves the scope of the client, and passes it to the provided
+// handler through the request context. Requests to a namespace outside of the scope (via the namespace path wildcard)
+// get a 403 Forbidden.
+func Middleware(t *Tenancy, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity := auth.Identity(r)
+		scope := t.ScopeOf(identity)
+		if namespace := r.PathValue("namespace"); namespace != "" && !scope.Allows(namespace) {
+			logger := klog.FromContext(r.Context())
+			logger.Info("Forbidden, namespace is outside of the tenant's namespaces", "identity", identity, "namespace", namespace)
+			auth.WriteForbidden(w, logger)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithScope(r.Context(), scope)))
+	}
+}
@@ -0,0 +1,111 @@
+package tenancy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `
+tenants:
+  - name: team-a
+    identities: [team-a-portal, alice]
+    namespaces: [team-a, team-a-staging]
+  - name: shared
+    identities: [alice]
+    namespaces: [shared]
+  - name: platform
+    identities: [platform-admin]
+    namespaces: ["*"]
+`
+
+func newTenancy(t *testing.T) *Tenancy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tenants.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o600); err != nil {
+		t.Fatalf("failed to write tenants file: %v", err)
+	}
+	tenancy, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return tenancy
+}
+
+func TestTenancy_ScopeOf(t *testing.T) {
+	tenancy := newTenancy(t)
+	tests := []struct {
+		identity  string
+		namespace string
+		allowed   bool
+	}{
+		{"team-a-portal", "team-a", true},
+		{"team-a-portal", "team-a-staging", true},
+		{"team-a-portal", "shared", false},
+		{"alice", "shared", true},
+		{"alice", "team-a", true},
+		{"platform-admin", "anything", true},
+		{"unknown", "team-a", false},
+		{"", "team-a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.identity+"/"+tt.namespace, func(t *testing.T) {
+			if allowed := tenancy.ScopeOf(tt.identity).Allows(tt.namespace); allowed != tt.allowed {
+				t.Errorf("ScopeOf(%q).Allows(%q) = %v, want %v", tt.identity, tt.namespace, allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.yaml")
+	if err := os.WriteFile(path, []byte("tenants:\n  - name: a\n    identity: [b]\n"), 0o600); err != nil {
+		t.Fatalf("failed to write tenants file: %v", err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Errorf("expected an error for the unknown identity field")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tenancy := newTenancy(t)
+	tests := []struct {
+		name           string
+		identity       string
+		path           string
+		expectedStatus int
+	}{
+		{"Test Middleware Allowed Namespace", "team-a-portal", "/deployments/team-a/foo/replicas", http.StatusOK},
+		{"Test Middleware Other Namespace", "team-a-portal", "/deployments/shared/foo/replicas", http.StatusForbidden},
+		{"Test Middleware Unknown Identity", "bob", "/deployments/team-a/foo/replicas", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scope Scope
+			var hasScope bool
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /deployments/{namespace}/{deployment}/replicas", Middleware(tenancy, func(w http.ResponseWriter, r *http.Request) {
+				scope, hasScope = ScopeFrom(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest("GET", tt.path, nil)
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: tt.identity}}
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Middleware() status code = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus == http.StatusOK && (!hasScope || !scope.Allows("team-a")) {
+				t.Errorf("expected the scope to be passed to the handler, got %+v", scope)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package testenv
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Certificates is a CA along with a server certificate it issued for localhost, written to files the same way
+// hack/generate-certs.sh does, so that they can be passed to the server flags. Client certificates are issued on demand
+// for the identities the tests act as.
+type Certificates struct {
+	// CACert, ServerCert and ServerKey are the paths of the PEM files, for --ca-cert, --server-cert and --cert-key
+	CACert     string
+	ServerCert string
+	ServerKey  string
+
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	caPool *x509.CertPool
+	server tls.Certificate
+}
+
+// NewCertificates generates a new CA and server certificate in a temporary directory of the test
+func NewCertificates(t testing.TB) *Certificates {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey := newKey(t)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "testenv-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Error creating the CA certificate: %v", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("Error parsing the CA certificate: %v", err)
+	}
+	c := &Certificates{
+		CACert:     filepath.Join(dir, "ca.crt"),
+		ServerCert: filepath.Join(dir, "server.crt"),
+		ServerKey:  filepath.Join(dir, "server.key"),
+		ca:         ca,
+		caKey:      caKey,
+		caPool:     x509.NewCertPool(),
+	}
+	c.caPool.AddCert(ca)
+	writePEM(t, c.CACert, "CERTIFICATE", caDER)
+
+	c.server = c.issue(t, "localhost", x509.ExtKeyUsageServerAuth, c.ServerCert, c.ServerKey)
+	return c
+}
+
+// ServerTLSConfig returns the TLS configuration of the main server: the server certificate, and client certificates
+// issued by the CA required
+func (c *Certificates) ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{c.server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    c.caPool,
+		MinVersion:   tls.VersionTLS13,
+	}
+}
+
+// Client returns an HTTP client authenticating with a new client certificate for the given identity, trusting the
+// server certificate
+func (c *Certificates) Client(t testing.TB, identity string) *http.Client {
+	t.Helper()
+	cert := c.issue(t, identity, x509.ExtKeyUsageClientAuth, "", "")
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				RootCAs:      c.caPool,
+				MinVersion:   tls.VersionTLS13,
+			},
+		},
+	}
+}
+
+// NewServer starts a server for the handler with the TLS configuration of the main server, which is closed at the end
+// of the test, e.g. to exercise the handlers with client certificates before the whole server is
+func (c *Certificates) NewServer(t testing.TB, handler http.Handler) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = c.ServerTLSConfig()
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// issue issues a certificate for the given common name, also valid for localhost, and writes it to the given paths
+// unless empty
+func (c *Certificates) issue(t testing.TB, commonName string, usage x509.ExtKeyUsage, certPath, keyPath string) tls.Certificate {
+	t.Helper()
+	key := newKey(t)
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		t.Fatalf("Error generating a serial number: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, c.ca, &key.PublicKey, c.caKey)
+	if err != nil {
+		t.Fatalf("Error issuing a certificate for %s: %v", commonName, err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Error encoding the key of %s: %v", commonName, err)
+	}
+	if certPath != "" {
+		writePEM(t, certPath, "CERTIFICATE", der)
+		writePEM(t, keyPath, "EC PRIVATE KEY", keyDER)
+	}
+
+	cert, err := tls.X509KeyPair(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	if err != nil {
+		t.Fatalf("Error loading the certificate of %s: %v", commonName, err)
+	}
+	return cert
+}
+
+// FreePort returns a TCP port which is free on the loopback interface, for the servers started by the tests
+func FreePort(t testing.TB) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error finding a free port: %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return port
+}
+
+func newKey(t testing.TB) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating a key: %v", err)
+	}
+	return key
+}
+
+func writePEM(t testing.TB, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Error writing %s: %v", path, err)
+	}
+}
@@ -0,0 +1,35 @@
+package testenv
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+)
+
+func TestCertificates(t *testing.T) {
+	certs := NewCertificates(t)
+	server := certs.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, auth.Identity(r))
+	}))
+
+	// Clients authenticate as the identity of their certificate
+	resp, err := certs.Client(t, "alice").Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "alice" {
+		t.Errorf("identity = %q, want %q", body, "alice")
+	}
+
+	// Clients without a certificate are rejected during the handshake
+	anonymous := certs.Client(t, "bob")
+	anonymous.Transport.(*http.Transport).TLSClientConfig.Certificates = nil
+	if resp, err := anonymous.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Errorf("Get() without a client certificate succeeded, want an error")
+	}
+}
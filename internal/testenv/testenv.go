@@ -0,0 +1,84 @@
+// Package testenv runs integration tests against a real API server and etcd started with envtest, so that the handlers
+// and the full HTTP stack of the server (TLS, authentication, routing) can be exercised beyond the fake client unit
+// tests.
+//
+// The API server and etcd binaries are looked up in KUBEBUILDER_ASSETS, as set by `make test-integration`. The tests
+// starting an environment are skipped when it isn't set, so that `go test ./...` keeps running without them.
+package testenv
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// AssetsEnv is the environment variable pointing at the directory of the envtest binaries
+const AssetsEnv = "KUBEBUILDER_ASSETS"
+
+// Environment is a running API server and etcd, along with the means to connect to it as a cluster admin
+type Environment struct {
+	// Config connects to the API server as a cluster admin
+	Config *rest.Config
+	// Client is a client for the API server, with the built-in types registered
+	Client client.Client
+	// Kubeconfig is the path of a kubeconfig file connecting to the API server as a cluster admin, e.g. for --kubeconfig
+	Kubeconfig string
+}
+
+// Start starts a new API server and etcd with the CRDs of the Helm chart installed, which are stopped at the end of
+// the test. The test is skipped if the envtest binaries aren't available.
+func Start(t testing.TB) *Environment {
+	t.Helper()
+	if os.Getenv(AssetsEnv) == "" {
+		t.Skipf("%s is not set, skipping the integration test (run it with make test-integration)", AssetsEnv)
+	}
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join(RepoRoot(t), "helm", "crds")},
+		ErrorIfCRDPathMissing: true,
+	}
+	config, err := env.Start()
+	if err != nil {
+		t.Fatalf("Error starting the API server: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := env.Stop(); err != nil {
+			t.Errorf("Error stopping the API server: %v", err)
+		}
+	})
+
+	user, err := env.AddUser(envtest.User{Name: "testenv-admin", Groups: []string{"system:masters"}}, nil)
+	if err != nil {
+		t.Fatalf("Error adding the admin user: %v", err)
+	}
+	kubeconfig, err := user.KubeConfig()
+	if err != nil {
+		t.Fatalf("Error generating the kubeconfig: %v", err)
+	}
+	kubeconfigPath := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(kubeconfigPath, kubeconfig, 0o600); err != nil {
+		t.Fatalf("Error writing the kubeconfig: %v", err)
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme.Scheme})
+	if err != nil {
+		t.Fatalf("Error creating the client: %v", err)
+	}
+	return &Environment{Config: config, Client: c, Kubeconfig: kubeconfigPath}
+}
+
+// RepoRoot returns the root directory of the repository, to find the manifests the tests depend on
+func RepoRoot(t testing.TB) string {
+	t.Helper()
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatalf("Error finding the repository root")
+	}
+	return filepath.Join(filepath.Dir(file), "..", "..")
+}
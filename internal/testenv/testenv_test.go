@@ -0,0 +1,79 @@
+package testenv_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/handlers"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/testenv"
+)
+
+func TestDeploymentsHandler(t *testing.T) {
+	env := testenv.Start(t)
+	ctx := context.Background()
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(int32(2)),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "foo"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "foo", Image: "nginx"}}},
+			},
+		},
+	}
+	if err := env.Client.Create(ctx, deployment); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// The handlers read from and write to the API server over TLS, as the server does
+	h := &handlers.DeploymentsHandler{Client: env.Client}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /deployments/{namespace}/{deployment}/replicas", h.GetDeploymentReplicas)
+	mux.HandleFunc("PUT /deployments/{namespace}/{deployment}/replicas", h.SetDeploymentReplicas)
+	certs := testenv.NewCertificates(t)
+	server := certs.NewServer(t, mux)
+	httpClient := certs.Client(t, "alice")
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/deployments/default/foo/replicas", strings.NewReader(`{"replicas": 5}`))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d: %s", resp.StatusCode, http.StatusOK, body)
+	}
+
+	if err := env.Client.Get(ctx, client.ObjectKeyFromObject(deployment), deployment); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if *deployment.Spec.Replicas != 5 {
+		t.Errorf("replicas = %d, want 5", *deployment.Spec.Replicas)
+	}
+
+	resp, err = httpClient.Get(server.URL + "/deployments/default/foo/replicas")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	var replicas handlers.DeploymentResponseWithReplicas
+	if err := json.NewDecoder(resp.Body).Decode(&replicas); err != nil {
+		t.Fatalf("Error decoding the response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || replicas.Replicas.Replicas == nil || *replicas.Replicas.Replicas != 5 {
+		t.Errorf("GET = %d %+v, want 5 replicas", resp.StatusCode, replicas)
+	}
+}
@@ -0,0 +1,70 @@
+package timing
+
+import (
+	"context"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Client attributes the time spent in the calls of the wrapped client to the request of their context: reads to the
+// Reads phase (e.g. PhaseCache for the manager's client), and writes to PhaseAPIServer.
+type Client struct {
+	client.Client
+	Reads Phase
+}
+
+// Get retrieves an obj for the given object key
+func (c *Client) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	defer track(ctx, c.Reads, time.Now())
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+// List retrieves a list of objects for the given options
+func (c *Client) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	defer track(ctx, c.Reads, time.Now())
+	return c.Client.List(ctx, list, opts...)
+}
+
+// Create saves the object obj in the Kubernetes cluster
+func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	defer track(ctx, PhaseAPIServer, time.Now())
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// Update updates the given obj in the Kubernetes cluster
+func (c *Client) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	defer track(ctx, PhaseAPIServer, time.Now())
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// Patch patches the given obj in the Kubernetes cluster
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	defer track(ctx, PhaseAPIServer, time.Now())
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// Delete deletes the given obj from the Kubernetes cluster
+func (c *Client) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	defer track(ctx, PhaseAPIServer, time.Now())
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+// Reader attributes the time spent in the reads of the wrapped reader to the given phase of the request of their
+// context, e.g. PhaseAPIServer for the manager's API reader
+type Reader struct {
+	client.Reader
+	Phase Phase
+}
+
+// Get retrieves an obj for the given object key
+func (r *Reader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	defer track(ctx, r.Phase, time.Now())
+	return r.Reader.Get(ctx, key, obj, opts...)
+}
+
+// List retrieves a list of objects for the given options
+func (r *Reader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	defer track(ctx, r.Phase, time.Now())
+	return r.Reader.List(ctx, list, opts...)
+}
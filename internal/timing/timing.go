@@ -0,0 +1,138 @@
+package timing
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/auth"
+	"github.com/moshevayner/go-k8s-http-api-interface/internal/logging"
+	"k8s.io/klog/v2"
+)
+
+// Phase is a part of serving a request that its latency is attributed to
+type Phase string
+
+const (
+	// PhaseCache is the time spent reading from the manager's cache
+	PhaseCache Phase = "cache"
+	// PhaseAPIServer is the time spent in calls to the API server, i.e. live reads and writes
+	PhaseAPIServer Phase = "apiserver"
+	// PhaseEncode is the time spent encoding and writing the response body, from the status line to the last write
+	PhaseEncode Phase = "encode"
+)
+
+// Breakdown accumulates the time spent in every phase while serving a request
+type Breakdown struct {
+	mu        sync.Mutex
+	durations map[Phase]time.Duration
+	calls     map[Phase]int
+}
+
+// Add attributes the given duration to the given phase. It is a no-op on a nil Breakdown, so that callers don't need
+// to check whether the request is timed.
+func (b *Breakdown) Add(phase Phase, d time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.durations[phase] += d
+	b.calls[phase]++
+}
+
+// Duration returns the total time spent in the given phase
+func (b *Breakdown) Duration(phase Phase) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.durations[phase]
+}
+
+// Calls returns the number of times time was attributed to the given phase, e.g. the number of cache reads
+func (b *Breakdown) Calls(phase Phase) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls[phase]
+}
+
+type breakdownKey struct{}
+
+// WithBreakdown returns a copy of the context holding a new Breakdown, along with the Breakdown
+func WithBreakdown(ctx context.Context) (context.Context, *Breakdown) {
+	b := &Breakdown{durations: map[Phase]time.Duration{}, calls: map[Phase]int{}}
+	return context.WithValue(ctx, breakdownKey{}, b), b
+}
+
+// FromContext returns the Breakdown held by the context, or nil if the request isn't timed
+func FromContext(ctx context.Context) *Breakdown {
+	b, _ := ctx.Value(breakdownKey{}).(*Breakdown)
+	return b
+}
+
+// track attributes the time elapsed since start to the given phase of the request of the context
+func track(ctx context.Context, phase Phase, start time.Time) {
+	FromContext(ctx).Add(phase, time.Since(start))
+}
+
+// recorder passes the response through, while keeping its status code and timing the writing of its body
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	headerAt    time.Time
+	lastWriteAt time.Time
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.headerAt = time.Now()
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.lastWriteAt = time.Now()
+	return n, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so that http.ResponseController can reach it
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// SlowRequests returns a new http.Handler which times the requests served by the provided handler, and logs the
+// requests slower than the given threshold along with a breakdown of where the time went: cache reads, API server
+// calls, encoding the response, and the rest (e.g. waiting on locks or admission checks).
+// The clients of the handlers must be wrapped in a Client or Reader to attribute their calls.
+func SlowRequests(threshold time.Duration, next http.Handler) http.Handler {
+	if threshold <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, breakdown := WithBreakdown(r.Context())
+		rec := &recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		duration := time.Since(start)
+		if duration < threshold {
+			return
+		}
+		if !rec.lastWriteAt.IsZero() {
+			breakdown.Add(PhaseEncode, rec.lastWriteAt.Sub(rec.headerAt))
+		}
+		cache, apiserver, encode := breakdown.Duration(PhaseCache), breakdown.Duration(PhaseAPIServer), breakdown.Duration(PhaseEncode)
+		klog.FromContext(r.Context()).Info("Slow request",
+			"requestID", w.Header().Get(logging.RequestIDHeader), "user", auth.Identity(r),
+			"method", r.Method, "path", r.URL.Path, "route", r.Pattern, "status", rec.status,
+			"duration", duration, "threshold", threshold,
+			"cache", cache, "cacheReads", breakdown.Calls(PhaseCache),
+			"apiserver", apiserver, "apiserverCalls", breakdown.Calls(PhaseAPIServer),
+			"encode", encode, "other", max(duration-cache-apiserver-encode, 0))
+	})
+}
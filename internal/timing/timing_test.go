@@ -0,0 +1,95 @@
+package timing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClient(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(d).Build()
+	cached := &Client{Client: c, Reads: PhaseCache}
+	live := &Reader{Reader: c, Phase: PhaseAPIServer}
+
+	ctx, breakdown := WithBreakdown(context.Background())
+	if err := cached.Get(ctx, client.ObjectKeyFromObject(d), &appsv1.Deployment{}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if err := cached.List(ctx, &appsv1.DeploymentList{}); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if err := live.Get(ctx, client.ObjectKeyFromObject(d), &appsv1.Deployment{}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if err := cached.Patch(ctx, d, client.MergeFrom(d.DeepCopy())); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if calls := breakdown.Calls(PhaseCache); calls != 2 {
+		t.Errorf("cache calls = %d, want 2", calls)
+	}
+	if calls := breakdown.Calls(PhaseAPIServer); calls != 2 {
+		t.Errorf("apiserver calls = %d, want 2", calls)
+	}
+
+	// Requests which aren't timed are served as usual
+	if err := cached.Get(context.Background(), client.ObjectKeyFromObject(d), &appsv1.Deployment{}); err != nil {
+		t.Fatalf("Get() without a breakdown error = %v", err)
+	}
+}
+
+func TestSlowRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		delay     time.Duration
+		threshold time.Duration
+		wantLog   bool
+	}{
+		{name: "slow request", delay: 20 * time.Millisecond, threshold: 10 * time.Millisecond, wantLog: true},
+		{name: "fast request", threshold: time.Minute, wantLog: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logged []string
+			logger := funcr.New(func(prefix, args string) { logged = append(logged, args) }, funcr.Options{})
+			handler := SlowRequests(tt.threshold, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				FromContext(r.Context()).Add(PhaseAPIServer, tt.delay)
+				time.Sleep(tt.delay)
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte("{}"))
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/deployments", nil)
+			r = r.WithContext(klog.NewContext(r.Context(), logger))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			if !tt.wantLog {
+				if len(logged) != 0 {
+					t.Errorf("logged %v, want nothing", logged)
+				}
+				return
+			}
+			if len(logged) != 1 || !strings.Contains(logged[0], `"msg"="Slow request"`) || !strings.Contains(logged[0], `"apiserverCalls"=1`) ||
+				!strings.Contains(logged[0], `"status"=200`) {
+				t.Errorf("logged %v, want a slow request with its breakdown", logged)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is the version of the binary. It is set at build time via
+// -ldflags "-X github.com/moshevayner/go-k8s-http-api-interface/internal/version.Version=v1.2.3"
+var Version = "dev"
+
+// Info holds the version information of the running binary
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit,omitempty"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// Get returns the version information of the running binary. The git commit is read from the build info embedded
+// by the go toolchain, when available.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			if setting.Key == "vcs.revision" {
+				info.GitCommit = setting.Value
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,23 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	original := Version
+	defer func() { Version = original }()
+	Version = "v1.2.3"
+
+	info := Get()
+	if info.Version != "v1.2.3" {
+		t.Errorf("expected version v1.2.3, got %s", info.Version)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("expected go version %s, got %s", runtime.Version(), info.GoVersion)
+	}
+	if info.Platform != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("unexpected platform %s", info.Platform)
+	}
+}